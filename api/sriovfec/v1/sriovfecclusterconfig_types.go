@@ -109,7 +109,7 @@ type BBDevConfig struct {
 // PhysicalFunctionConfig defines a possible configuration of a single Physical Function (PF), i.e. card
 type PhysicalFunctionConfig struct {
 	// PCIAdress is a Physical Functions's PCI address that will be configured according to this spec
-	// +kubebuilder:validation:Pattern=`^[a-fA-F0-9]{4}:[a-fA-F0-9]{2}:[01][a-fA-F0-9]\.[0-7]$`
+	// +kubebuilder:validation:Pattern=`^([a-fA-F0-9]{4,8}:)?[a-fA-F0-9]{2}:[01][a-fA-F0-9]\.[0-7]$`
 	PCIAddress string `json:"pciAddress"`
 	// PFDriver to bound the PFs to
 	PFDriver string `json:"pfDriver"`
@@ -0,0 +1,40 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package v2
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAutoRebootPolicyDefaults(t *testing.T) {
+	g := NewWithT(t)
+	policy := &AutoRebootPolicy{Enabled: true}
+
+	g.Expect(policy.EffectiveThreshold()).To(Equal(DefaultAutoRebootThreshold))
+	g.Expect(policy.EffectiveCooldown()).To(Equal(DefaultAutoRebootCooldown))
+
+	policy.Threshold, policy.Cooldown = 5, &metav1.Duration{Duration: time.Hour}
+	g.Expect(policy.EffectiveThreshold()).To(Equal(5))
+	g.Expect(policy.EffectiveCooldown()).To(Equal(time.Hour))
+}
+
+func TestMergeAutoRebootPolicies(t *testing.T) {
+	g := NewWithT(t)
+	disabled := &AutoRebootPolicy{Threshold: 10}
+	eager := &AutoRebootPolicy{Enabled: true, Threshold: 2, Cooldown: &metav1.Duration{Duration: 48 * time.Hour}}
+	defaults := &AutoRebootPolicy{Enabled: true}
+
+	g.Expect(MergeAutoRebootPolicies(nil, nil)).To(BeNil())
+	g.Expect(MergeAutoRebootPolicies(nil, disabled)).To(BeNil())
+	g.Expect(MergeAutoRebootPolicies(disabled, eager)).To(Equal(eager))
+	g.Expect(MergeAutoRebootPolicies(eager, disabled)).To(Equal(eager))
+	g.Expect(MergeAutoRebootPolicies(eager, defaults)).To(Equal(&AutoRebootPolicy{
+		Enabled: true, Threshold: DefaultAutoRebootThreshold, Cooldown: &metav1.Duration{Duration: 48 * time.Hour},
+	}))
+	g.Expect(eager.Threshold).To(Equal(2), "merged policies are not modified")
+}
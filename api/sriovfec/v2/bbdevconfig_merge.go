@@ -0,0 +1,92 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package v2
+
+// EffectiveBBDevConfig returns physicalFunction.bbDevConfig merged with defaults.bbDevConfig. Defaults are not applied
+// when config is read from bbDevConfigFrom
+func (in *SriovFecClusterConfigSpec) EffectiveBBDevConfig() BBDevConfig {
+	if in.Defaults == nil || in.Defaults.BBDevConfig == nil || in.PhysicalFunction.BBDevConfigFrom != nil {
+		return *in.PhysicalFunction.BBDevConfig.DeepCopy()
+	}
+	return MergeBBDevConfig(*in.Defaults.BBDevConfig, in.PhysicalFunction.BBDevConfig)
+}
+
+// MergeBBDevConfig merges entry into defaults; values specified in entry win.
+// When entry does not contain config for any accelerator, defaults are used as they are. Otherwise only the accelerator
+// configured by entry is merged with its defaults. Queue groups and uplink/downlink settings are merged as a whole,
+// because zero is a valid value for some of their fields (e.g. numQueueGroups) and cannot be told apart from unset one.
+func MergeBBDevConfig(defaults, entry BBDevConfig) BBDevConfig {
+	if entry.N3000 == nil && entry.ACC100 == nil && entry.ACC200 == nil {
+		return *defaults.DeepCopy()
+	}
+
+	merged := *entry.DeepCopy()
+	if merged.N3000 != nil && defaults.N3000 != nil {
+		mergeN3000BBDevConfig(merged.N3000, defaults.N3000)
+	}
+	if merged.ACC100 != nil && defaults.ACC100 != nil {
+		mergeACC100BBDevConfig(merged.ACC100, defaults.ACC100)
+	}
+	if merged.ACC200 != nil && defaults.ACC200 != nil {
+		mergeACC200BBDevConfig(merged.ACC200, defaults.ACC200)
+	}
+	return merged
+}
+
+func mergeN3000BBDevConfig(dst, defaults *N3000BBDevConfig) {
+	if dst.NetworkType == "" {
+		dst.NetworkType = defaults.NetworkType
+	}
+	// pfMode can only be false for N3000, so there is nothing to override with
+	dst.PFMode = dst.PFMode || defaults.PFMode
+	if dst.FLRTimeOut == nil && defaults.FLRTimeOut != nil {
+		flrTimeOut := *defaults.FLRTimeOut
+		dst.FLRTimeOut = &flrTimeOut
+	}
+	mergeUplinkDownlink(&dst.Downlink, defaults.Downlink)
+	mergeUplinkDownlink(&dst.Uplink, defaults.Uplink)
+}
+
+func mergeUplinkDownlink(dst *UplinkDownlink, defaults UplinkDownlink) {
+	if *dst == (UplinkDownlink{}) {
+		*dst = defaults
+	}
+}
+
+func mergeACC100BBDevConfig(dst, defaults *ACC100BBDevConfig) {
+	// pfMode can only be false for ACC100, so there is nothing to override with
+	dst.PFMode = dst.PFMode || defaults.PFMode
+	if dst.NumVfBundles == 0 {
+		dst.NumVfBundles = defaults.NumVfBundles
+	}
+	if dst.MaxQueueSize == 0 {
+		dst.MaxQueueSize = defaults.MaxQueueSize
+	}
+	mergeQueueGroupConfig(&dst.Uplink4G, defaults.Uplink4G)
+	mergeQueueGroupConfig(&dst.Downlink4G, defaults.Downlink4G)
+	mergeQueueGroupConfig(&dst.Uplink5G, defaults.Uplink5G)
+	mergeQueueGroupConfig(&dst.Downlink5G, defaults.Downlink5G)
+	if dst.FFTLutFrom == nil {
+		dst.FFTLutFrom = defaults.FFTLutFrom.DeepCopy()
+	}
+	if dst.InterruptMode == "" {
+		dst.InterruptMode = defaults.InterruptMode
+	}
+}
+
+func mergeACC200BBDevConfig(dst, defaults *ACC200BBDevConfig) {
+	mergeACC100BBDevConfig(&dst.ACC100BBDevConfig, &defaults.ACC100BBDevConfig)
+	mergeQueueGroupConfig(&dst.QFFT, defaults.QFFT)
+	if dst.FFTLut == (FFTLutParam{}) {
+		dst.FFTLut = defaults.FFTLut
+	}
+}
+
+// mergeQueueGroupConfig takes group from defaults when group is not specified at all; specified group always has
+// numAqsPerGroups and aqDepthLog2 set
+func mergeQueueGroupConfig(dst *QueueGroupConfig, defaults QueueGroupConfig) {
+	if *dst == (QueueGroupConfig{}) {
+		*dst = defaults
+	}
+}
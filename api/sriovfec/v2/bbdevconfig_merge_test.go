@@ -0,0 +1,210 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package v2
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/yaml"
+)
+
+func defaultACC200BBDevConfig() ACC200BBDevConfig {
+	return ACC200BBDevConfig{
+		ACC100BBDevConfig: ACC100BBDevConfig{
+			NumVfBundles: 16,
+			MaxQueueSize: 1024,
+			Uplink4G:     QueueGroupConfig{NumQueueGroups: 0, NumAqsPerGroups: 16, AqDepthLog2: 4},
+			Downlink4G:   QueueGroupConfig{NumQueueGroups: 0, NumAqsPerGroups: 16, AqDepthLog2: 4},
+			Uplink5G:     QueueGroupConfig{NumQueueGroups: 4, NumAqsPerGroups: 16, AqDepthLog2: 4},
+			Downlink5G:   QueueGroupConfig{NumQueueGroups: 4, NumAqsPerGroups: 16, AqDepthLog2: 4},
+		},
+		QFFT: QueueGroupConfig{NumQueueGroups: 4, NumAqsPerGroups: 16, AqDepthLog2: 4},
+	}
+}
+
+func TestMergeBBDevConfigTakesDefaultsForEmptyEntry(t *testing.T) {
+	g := NewWithT(t)
+	acc200 := defaultACC200BBDevConfig()
+	defaults := BBDevConfig{ACC200: &acc200}
+
+	merged := MergeBBDevConfig(defaults, BBDevConfig{})
+
+	g.Expect(merged).To(Equal(defaults))
+	g.Expect(merged.ACC200).ToNot(BeIdenticalTo(defaults.ACC200))
+}
+
+func TestMergeBBDevConfigEntryWins(t *testing.T) {
+	g := NewWithT(t)
+	acc200 := defaultACC200BBDevConfig()
+	defaults := BBDevConfig{ACC200: &acc200}
+	entry := BBDevConfig{ACC200: &ACC200BBDevConfig{
+		ACC100BBDevConfig: ACC100BBDevConfig{
+			NumVfBundles: 2,
+			Uplink5G:     QueueGroupConfig{NumQueueGroups: 8, NumAqsPerGroups: 16, AqDepthLog2: 4},
+		},
+		// explicitly specified group with zero queue groups has to be kept
+		QFFT: QueueGroupConfig{NumQueueGroups: 0, NumAqsPerGroups: 1, AqDepthLog2: 1},
+	}}
+
+	merged := MergeBBDevConfig(defaults, entry)
+
+	expected := defaultACC200BBDevConfig()
+	expected.NumVfBundles = 2
+	expected.Uplink5G.NumQueueGroups = 8
+	expected.QFFT = QueueGroupConfig{NumQueueGroups: 0, NumAqsPerGroups: 1, AqDepthLog2: 1}
+	g.Expect(merged).To(Equal(BBDevConfig{ACC200: &expected}))
+	g.Expect(defaults.ACC200).To(Equal(&acc200), "defaults should not be modified")
+}
+
+func TestMergeBBDevConfigMergesOnlyConfiguredAccelerator(t *testing.T) {
+	g := NewWithT(t)
+	acc200 := defaultACC200BBDevConfig()
+	defaults := BBDevConfig{
+		ACC100: &ACC100BBDevConfig{NumVfBundles: 16, MaxQueueSize: 1024},
+		ACC200: &acc200,
+	}
+	entry := BBDevConfig{ACC100: &ACC100BBDevConfig{NumVfBundles: 4}}
+
+	merged := MergeBBDevConfig(defaults, entry)
+
+	g.Expect(merged.ACC200).To(BeNil())
+	g.Expect(merged.ACC100).To(Equal(&ACC100BBDevConfig{NumVfBundles: 4, MaxQueueSize: 1024}))
+}
+
+func TestMergeBBDevConfigN3000FLRTimeOut(t *testing.T) {
+	g := NewWithT(t)
+	link := UplinkDownlink{Bandwidth: 3, LoadBalance: 128, Queues: UplinkDownlinkQueues{VF0: 16, VF1: 16}}
+	defaults := BBDevConfig{N3000: &N3000BBDevConfig{
+		NetworkType: "FPGA_5GNR",
+		FLRTimeOut:  pointer.Int(610),
+		Downlink:    link,
+		Uplink:      link,
+	}}
+
+	merged := MergeBBDevConfig(defaults, BBDevConfig{N3000: &N3000BBDevConfig{FLRTimeOut: pointer.Int(0)}})
+	g.Expect(merged.N3000.FLRTimeOut).To(Equal(pointer.Int(0)))
+	g.Expect(merged.N3000.NetworkType).To(Equal("FPGA_5GNR"))
+	g.Expect(merged.N3000.Downlink).To(Equal(link))
+	g.Expect(merged.N3000.Uplink).To(Equal(link))
+
+	merged = MergeBBDevConfig(defaults, BBDevConfig{N3000: &N3000BBDevConfig{NetworkType: "FPGA_LTE"}})
+	g.Expect(merged.N3000.FLRTimeOut).To(Equal(pointer.Int(610)))
+	g.Expect(merged.N3000.FLRTimeOut).ToNot(BeIdenticalTo(defaults.N3000.FLRTimeOut))
+	g.Expect(merged.N3000.NetworkType).To(Equal("FPGA_LTE"))
+}
+
+func TestEffectiveBBDevConfigWithoutDefaults(t *testing.T) {
+	g := NewWithT(t)
+	acc200 := defaultACC200BBDevConfig()
+	spec := SriovFecClusterConfigSpec{PhysicalFunction: PhysicalFunctionConfig{BBDevConfig: BBDevConfig{ACC200: &acc200}}}
+
+	g.Expect(spec.EffectiveBBDevConfig()).To(Equal(spec.PhysicalFunction.BBDevConfig))
+}
+
+func TestValidateMergedBBDevConfig(t *testing.T) {
+	g := NewWithT(t)
+	acc200 := defaultACC200BBDevConfig()
+	spec := SriovFecClusterConfigSpec{
+		PhysicalFunction: PhysicalFunctionConfig{
+			VFAmount:    4,
+			BBDevConfig: BBDevConfig{ACC200: &ACC200BBDevConfig{ACC100BBDevConfig: ACC100BBDevConfig{NumVfBundles: 4}}},
+		},
+		Defaults: &ClusterConfigDefaults{BBDevConfig: &BBDevConfig{ACC200: &acc200}},
+	}
+	g.Expect(validate(spec)).To(BeEmpty())
+
+	spec.Defaults = nil
+	g.Expect(validate(spec)).To(HaveLen(5))
+}
+
+func TestValidateBBDevConfigFrom(t *testing.T) {
+	g := NewWithT(t)
+	acc200 := defaultACC200BBDevConfig()
+	spec := SriovFecClusterConfigSpec{
+		PhysicalFunction: PhysicalFunctionConfig{
+			VFAmount: 4,
+			BBDevConfigFrom: &BBDevConfigFromSource{
+				ConfigMapRef: ConfigMapKeyReference{Name: "acc-config", Key: "acc200.cfg"},
+			},
+		},
+		// defaults are not applied to config read from ConfigMap
+		Defaults: &ClusterConfigDefaults{BBDevConfig: &BBDevConfig{ACC200: &acc200}},
+	}
+	g.Expect(validate(spec)).To(BeEmpty())
+
+	spec.PhysicalFunction.BBDevConfig = BBDevConfig{ACC200: &acc200}
+	g.Expect(validate(spec)).To(ContainElement(HaveField("Field", "spec.physicalFunction.bbDevConfigFrom")))
+}
+
+func TestMergeBBDevConfigTakesFFTLutFromDefaults(t *testing.T) {
+	g := NewWithT(t)
+	fftLut := &FFTLutSource{ConfigMapRef: &LocalObjectReference{Name: "fft-lut"}, Key: "srs_fft.bin", Checksum: strings.Repeat("a", 64)}
+	defaults := BBDevConfig{ACC100: &ACC100BBDevConfig{NumVfBundles: 16, FFTLutFrom: fftLut}}
+
+	merged := MergeBBDevConfig(defaults, BBDevConfig{ACC100: &ACC100BBDevConfig{NumVfBundles: 2}})
+
+	g.Expect(merged.ACC100.FFTLutFrom).To(Equal(fftLut))
+	g.Expect(merged.ACC100.FFTLutFrom).ToNot(BeIdenticalTo(fftLut))
+}
+
+func TestMergeBBDevConfigTakesInterruptModeFromDefaults(t *testing.T) {
+	g := NewWithT(t)
+	defaults := BBDevConfig{ACC100: &ACC100BBDevConfig{NumVfBundles: 16, InterruptMode: InterruptModeMSI}}
+
+	g.Expect(MergeBBDevConfig(defaults, BBDevConfig{ACC100: &ACC100BBDevConfig{NumVfBundles: 2}}).ACC100.InterruptMode).
+		To(Equal(InterruptModeMSI))
+	g.Expect(MergeBBDevConfig(defaults, BBDevConfig{ACC100: &ACC100BBDevConfig{InterruptMode: InterruptModePolling}}).ACC100.InterruptMode).
+		To(Equal(InterruptModePolling))
+}
+
+func TestACC100BBDevConfigRequiresSingleFFTLutSource(t *testing.T) {
+	g := NewWithT(t)
+	config := ACC100BBDevConfig{FFTLutFrom: &FFTLutSource{Key: "srs_fft.bin", Checksum: strings.Repeat("a", 64)}}
+	g.Expect(config.Validate()).To(MatchError(ContainSubstring("exactly one of configMapRef and secretRef")))
+
+	config.FFTLutFrom.ConfigMapRef = &LocalObjectReference{Name: "fft-lut"}
+	g.Expect(config.Validate()).To(Succeed())
+
+	config.FFTLutFrom.SecretRef = &LocalObjectReference{Name: "fft-lut"}
+	g.Expect(config.Validate()).To(MatchError(ContainSubstring("exactly one of configMapRef and secretRef")))
+}
+
+func TestACC200FFTLutRoundTrips(t *testing.T) {
+	g := NewWithT(t)
+	const cr = `
+apiVersion: sriovfec.intel.com/v2
+kind: SriovFecClusterConfig
+metadata:
+  name: config
+spec:
+  acceleratorSelector:
+    deviceID: "57c0"
+  physicalFunction:
+    pfDriver: vfio-pci
+    vfDriver: vfio-pci
+    vfAmount: 16
+    bbDevConfig:
+      acc200:
+        numVfBundles: 16
+        fftLut:
+          fftUrl: http://example.com/fft.tar.gz
+          fftChecksum: 0123456789abcdef0123456789abcdef01234567
+`
+	config := &SriovFecClusterConfig{}
+	g.Expect(yaml.UnmarshalStrict([]byte(cr), config)).To(Succeed())
+
+	acc200 := config.Spec.PhysicalFunction.BBDevConfig.ACC200
+	g.Expect(acc200.FFTLut).To(Equal(FFTLutParam{FftUrl: "http://example.com/fft.tar.gz", FftChecksum: "0123456789abcdef0123456789abcdef01234567"}))
+	g.Expect(acc200.FFTLutFrom).To(BeNil(), "fftLut of ACC200 is not taken for fftLutFrom inlined from ACC100")
+
+	data, err := yaml.Marshal(config)
+	g.Expect(err).ToNot(HaveOccurred())
+	roundTripped := &SriovFecClusterConfig{}
+	g.Expect(yaml.UnmarshalStrict(data, roundTripped)).To(Succeed())
+	g.Expect(roundTripped.Spec.PhysicalFunction.BBDevConfig.ACC200).To(Equal(acc200))
+	g.Expect(string(data)).ToNot(ContainSubstring("fftLutFrom"))
+}
@@ -0,0 +1,188 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package v2
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func inventoryValidationScheme(g *WithT) *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	g.Expect(AddToScheme(scheme)).To(Succeed())
+	return scheme
+}
+
+func inventoryValidationReader(g *WithT) client.Reader {
+	node := func(name string, labels map[string]string) *corev1.Node {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+	}
+	nodeConfig := func(name, deviceID string) *SriovFecNodeConfig {
+		return &SriovFecNodeConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Status: SriovFecNodeConfigStatus{Inventory: NodeInventory{SriovAccelerators: []SriovAccelerator{
+				{PCIAddress: "0000:f7:00.0", VendorID: "8086", DeviceID: deviceID},
+			}}},
+		}
+	}
+	return fake.NewClientBuilder().WithScheme(inventoryValidationScheme(g)).WithObjects(
+		node("worker-0", map[string]string{"fec": "acc100"}), nodeConfig("worker-0", "0d5c"),
+		node("worker-1", map[string]string{"fec": "n3000"}), nodeConfig("worker-1", "0b32"),
+	).Build()
+}
+
+func TestInventoryMismatchIsReportedOnlyForExplicitSelectors(t *testing.T) {
+	g := NewWithT(t)
+	reader := inventoryValidationReader(g)
+
+	cc := &SriovFecClusterConfig{ObjectMeta: metav1.ObjectMeta{Name: "cc", Namespace: "default"}}
+	g.Expect(inventoryMismatch(context.TODO(), reader, cc)).To(BeEmpty())
+
+	cc.Spec.AcceleratorSelector.DeviceID = "0d5c"
+	g.Expect(inventoryMismatch(context.TODO(), reader, cc)).To(BeEmpty())
+
+	cc.Spec.NodeSelector = map[string]string{"fec": "n3000"}
+	warning, err := inventoryMismatch(context.TODO(), reader, cc)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(warning).To(Equal("none of the nodes selected by nodeSelector reports accelerator matching acceleratorSelector " +
+		"(deviceID: 0d5c) - config is not applied until such accelerator appears; accelerators reported: 0000:f7:00.0(0b32) on worker-1"))
+
+	cc.Spec.AcceleratorSelector = AcceleratorSelector{PCIAddress: "0000:f7:00.0"}
+	g.Expect(inventoryMismatch(context.TODO(), reader, cc)).To(BeEmpty())
+	// address without domain selects accelerator of domain 0000
+	cc.Spec.AcceleratorSelector = AcceleratorSelector{PCIAddress: "F7:00.0"}
+	g.Expect(inventoryMismatch(context.TODO(), reader, cc)).To(BeEmpty())
+	cc.Spec.AcceleratorSelector = AcceleratorSelector{PCIAddress: "10000:f7:00.0"}
+	g.Expect(inventoryMismatch(context.TODO(), reader, cc)).ToNot(BeEmpty())
+
+	cc.Spec.NodeSelector = map[string]string{"fec": "vrb"}
+	g.Expect(inventoryMismatch(context.TODO(), reader, cc)).To(HaveSuffix("accelerators reported: none"))
+}
+
+func TestInventoryValidatingHandlerWarnsOrRejects(t *testing.T) {
+	g := NewWithT(t)
+	scheme := inventoryValidationScheme(g)
+	decoder, err := admission.NewDecoder(scheme)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	handler := &inventoryValidatingHandler{
+		syntax: admission.HandlerFunc(func(context.Context, admission.Request) admission.Response {
+			return admission.Allowed("")
+		}),
+		reader: inventoryValidationReader(g),
+	}
+	g.Expect(handler.InjectDecoder(decoder)).To(Succeed())
+
+	request := func(cc *SriovFecClusterConfig) admission.Request {
+		raw, err := json.Marshal(cc)
+		g.Expect(err).ToNot(HaveOccurred())
+		return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{Operation: admissionv1.Create, Object: runtime.RawExtension{Raw: raw}}}
+	}
+
+	cc := &SriovFecClusterConfig{
+		TypeMeta:   metav1.TypeMeta{APIVersion: GroupVersion.String(), Kind: "SriovFecClusterConfig"},
+		ObjectMeta: metav1.ObjectMeta{Name: "cc", Namespace: "default"},
+		Spec: SriovFecClusterConfigSpec{
+			NodeSelector:        map[string]string{"fec": "n3000"},
+			AcceleratorSelector: AcceleratorSelector{DeviceID: "0d5c"},
+		},
+	}
+	response := handler.Handle(context.TODO(), request(cc))
+	g.Expect(response.Allowed).To(BeTrue())
+	g.Expect(response.Warnings).To(ConsistOf(ContainSubstring("deviceID: 0d5c")))
+
+	cc.Annotations = map[string]string{StrictValidationAnnotation: "true"}
+	response = handler.Handle(context.TODO(), request(cc))
+	g.Expect(response.Allowed).To(BeFalse())
+	g.Expect(response.Result.Message).To(ContainSubstring("deviceID: 0d5c"))
+
+	cc.Spec.NodeSelector = map[string]string{"fec": "acc100"}
+	response = handler.Handle(context.TODO(), request(cc))
+	g.Expect(response.Allowed).To(BeTrue())
+	g.Expect(response.Warnings).To(BeEmpty())
+}
+
+func TestInventoryValidatingHandlerRejectsVFDriverUnsupportedOnNodeKernel(t *testing.T) {
+	g := NewWithT(t)
+	scheme := inventoryValidationScheme(g)
+	decoder, err := admission.NewDecoder(scheme)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	node := func(name, kernel string) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"fec": "n3000", "kubernetes.io/hostname": name}},
+			Status:     corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{KernelVersion: kernel}},
+		}
+	}
+	nodeConfig := func(name string) *SriovFecNodeConfig {
+		return &SriovFecNodeConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Status: SriovFecNodeConfigStatus{Inventory: NodeInventory{SriovAccelerators: []SriovAccelerator{
+				{PCIAddress: "0000:1b:00.0", VendorID: "8086", DeviceID: "0d8f"},
+			}}},
+		}
+	}
+	discoveryConfig := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "supported-accelerators", Namespace: "default"},
+		Data:       map[string]string{"accelerators.json": `{"VFDrivers": {"0d8f": [{"Driver": "vfio-pci"}, {"Driver": "igb_uio", "MaxKernelVersion": "5.15"}]}}`},
+	}
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		node("worker-0", "5.14.0-284.el9.x86_64"), nodeConfig("worker-0"),
+		node("worker-1", "5.15.0-1023-realtime"), nodeConfig("worker-1"),
+		node("worker-2", "5.15.0-1023-realtime"), nodeConfig("worker-2"),
+		discoveryConfig,
+	).Build()
+
+	handler := &inventoryValidatingHandler{
+		syntax: admission.HandlerFunc(func(context.Context, admission.Request) admission.Response {
+			return admission.Allowed("")
+		}),
+		reader: reader,
+	}
+	g.Expect(handler.InjectDecoder(decoder)).To(Succeed())
+
+	request := func(cc *SriovFecClusterConfig) admission.Request {
+		raw, err := json.Marshal(cc)
+		g.Expect(err).ToNot(HaveOccurred())
+		return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{Operation: admissionv1.Create, Object: runtime.RawExtension{Raw: raw}}}
+	}
+
+	cc := &SriovFecClusterConfig{
+		TypeMeta:   metav1.TypeMeta{APIVersion: GroupVersion.String(), Kind: "SriovFecClusterConfig"},
+		ObjectMeta: metav1.ObjectMeta{Name: "cc", Namespace: "default"},
+		Spec: SriovFecClusterConfigSpec{
+			NodeSelector:     map[string]string{"fec": "n3000"},
+			PhysicalFunction: PhysicalFunctionConfig{VFDriver: "igb_uio"},
+		},
+	}
+	// violation is reported once, although both nodes with newer kernel violate it
+	response := handler.Handle(context.TODO(), request(cc))
+	g.Expect(response.Allowed).To(BeFalse())
+	g.Expect(response.Result.Message).To(Equal(`spec.physicalFunction.vfDriver: Invalid value: "igb_uio": vfDriver igb_uio not supported for device 0d8f on kernel >= 5.15`))
+
+	cc.Spec.PhysicalFunction.VFDriver = "vfio-pci"
+	g.Expect(handler.Handle(context.TODO(), request(cc)).Allowed).To(BeTrue())
+
+	// nodes with newer kernel are not selected
+	cc.Spec.PhysicalFunction.VFDriver = "igb_uio"
+	cc.Spec.NodeSelector = map[string]string{"kubernetes.io/hostname": "worker-0"}
+	g.Expect(vfDriverViolations(context.TODO(), reader, cc)).To(BeEmpty())
+
+	// restrictions are not known without discovery config
+	cc.Spec.NodeSelector = map[string]string{"fec": "n3000"}
+	g.Expect(reader.Delete(context.TODO(), discoveryConfig)).To(Succeed())
+	g.Expect(vfDriverViolations(context.TODO(), reader, cc)).To(BeEmpty())
+}
@@ -0,0 +1,70 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package v2
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAssignLogicalNames(t *testing.T) {
+	g := NewWithT(t)
+	pfs := []PhysicalFunctionConfigExt{
+		{PCIAddress: "0000:f8:00.0", LogicalNamePrefix: "fec-acc100"},
+		{PCIAddress: "0000:f7:00.0", LogicalNamePrefix: "fec-acc100"},
+		{PCIAddress: "0000:1b:00.0", LogicalNamePrefix: "fec-n3000"},
+		{PCIAddress: "0000:1c:00.0"},
+	}
+	vfs := map[string][]string{
+		"0000:f8:00.0": {"0000:f8:00.2", "0000:f8:00.1"},
+		"0000:f7:00.0": {"0000:f7:00.1", "0000:f7:00.2"},
+		"0000:1c:00.0": {"0000:1c:00.1"},
+	}
+
+	g.Expect(AssignLogicalNames(pfs, vfs)).To(Equal([]PFLogicalNames{
+		{PCIAddress: "0000:f7:00.0", VFs: map[string]string{"fec-acc100-0-vf0": "0000:f7:00.1", "fec-acc100-0-vf1": "0000:f7:00.2"}},
+		{PCIAddress: "0000:f8:00.0", VFs: map[string]string{"fec-acc100-1-vf0": "0000:f8:00.1", "fec-acc100-1-vf1": "0000:f8:00.2"}},
+	}))
+	g.Expect(vfs["0000:f8:00.0"]).To(Equal([]string{"0000:f8:00.2", "0000:f8:00.1"}), "VFs are not sorted in place")
+
+	// PF without VFs keeps its index, so names of the other PFs do not change
+	delete(vfs, "0000:f7:00.0")
+	g.Expect(AssignLogicalNames(pfs, vfs)).To(Equal([]PFLogicalNames{
+		{PCIAddress: "0000:f8:00.0", VFs: map[string]string{"fec-acc100-1-vf0": "0000:f8:00.1", "fec-acc100-1-vf1": "0000:f8:00.2"}},
+	}))
+	g.Expect(AssignLogicalNames(nil, vfs)).To(BeEmpty())
+}
+
+func TestResolveLogicalName(t *testing.T) {
+	g := NewWithT(t)
+	nc := &SriovFecNodeConfig{Status: SriovFecNodeConfigStatus{LogicalNames: []PFLogicalNames{
+		{PCIAddress: "0000:f7:00.0", VFs: map[string]string{"fec-acc100-0-vf3": "0000:f7:00.4"}},
+	}}}
+
+	pciAddress, found := nc.ResolveLogicalName("fec-acc100-0-vf3")
+	g.Expect(found).To(BeTrue())
+	g.Expect(pciAddress).To(Equal("0000:f7:00.4"))
+	_, found = nc.ResolveLogicalName("fec-acc100-0-vf4")
+	g.Expect(found).To(BeFalse())
+}
+
+func TestResolveNodeLogicalName(t *testing.T) {
+	g := NewWithT(t)
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-0", Annotations: map[string]string{
+		LogicalNamesAnnotation: `{"fec-acc100-0-vf3":"0000:f7:00.4"}`,
+	}}}
+
+	g.Expect(ResolveNodeLogicalName(node, "fec-acc100-0-vf3")).To(Equal("0000:f7:00.4"))
+	_, err := ResolveNodeLogicalName(node, "fec-acc100-0-vf4")
+	g.Expect(err).To(MatchError("logical name fec-acc100-0-vf4 is not known on node worker-0"))
+
+	node.Annotations[LogicalNamesAnnotation] = "{"
+	_, err = ResolveNodeLogicalName(node, "fec-acc100-0-vf3")
+	g.Expect(err).To(MatchError(ContainSubstring("invalid sriovfec.intel.com/logical-names annotation of node worker-0")))
+
+	g.Expect(NodeLogicalNames(&corev1.Node{})).To(BeEmpty())
+}
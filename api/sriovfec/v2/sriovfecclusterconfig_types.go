@@ -67,19 +67,22 @@ type UplinkDownlink struct {
 	Queues      UplinkDownlinkQueues `json:"queues"`
 }
 
-
 // N3000BBDevConfig specifies variables to configure N3000 with
 type N3000BBDevConfig struct {
+	// +kubebuilder:validation:Optional
 	// +kubebuilder:validation:Enum=FPGA_5GNR;FPGA_LTE
 	NetworkType string `json:"networkType"`
 	// +kubebuilder:validation:Optional
 	// +kubebuilder:default:false
 	// +kubebuilder:validation:Enum=false
 	PFMode bool `json:"pfMode,omitempty"`
+	// +kubebuilder:validation:Optional
 	// +kubebuilder:validation:Minimum=0
-	FLRTimeOut int            `json:"flrTimeout"`
-	Downlink   UplinkDownlink `json:"downlink"`
-	Uplink     UplinkDownlink `json:"uplink"`
+	FLRTimeOut *int `json:"flrTimeout,omitempty"`
+	// +kubebuilder:validation:Optional
+	Downlink UplinkDownlink `json:"downlink"`
+	// +kubebuilder:validation:Optional
+	Uplink UplinkDownlink `json:"uplink"`
 }
 
 type QueueGroupConfig struct {
@@ -100,6 +103,7 @@ type ACC100BBDevConfig struct {
 	// +kubebuilder:default:false
 	// +kubebuilder:validation:Enum=false
 	PFMode bool `json:"pfMode,omitempty"`
+	// +kubebuilder:validation:Optional
 	// +kubebuilder:validation:Minimum=1
 	// +kubebuilder:validation:Maximum=16
 	NumVfBundles int `json:"numVfBundles"`
@@ -107,21 +111,67 @@ type ACC100BBDevConfig struct {
 	// +kubebuilder:validation:Maximum=1024
 	// +kubebuilder:default:1024
 	// +kubebuilder:validation:Optional
-	MaxQueueSize int              `json:"maxQueueSize,omitempty"`
-	Uplink4G     QueueGroupConfig `json:"uplink4G"`
-	Downlink4G   QueueGroupConfig `json:"downlink4G"`
-	Uplink5G     QueueGroupConfig `json:"uplink5G"`
-	Downlink5G   QueueGroupConfig `json:"downlink5G"`
+	MaxQueueSize int `json:"maxQueueSize,omitempty"`
+	// +kubebuilder:validation:Optional
+	Uplink4G QueueGroupConfig `json:"uplink4G"`
+	// +kubebuilder:validation:Optional
+	Downlink4G QueueGroupConfig `json:"downlink4G"`
+	// +kubebuilder:validation:Optional
+	Uplink5G QueueGroupConfig `json:"uplink5G"`
+	// +kubebuilder:validation:Optional
+	Downlink5G QueueGroupConfig `json:"downlink5G"`
+	// FFTLutFrom references SRS FFT LUT binary passed to pf-bb-config, required for SRS processing; ACC200 uses its
+	// own fftLut instead. It is not named fftLut, as ACC200 inlines this config and its fftLut would clash with it.
+	// +kubebuilder:validation:Optional
+	FFTLutFrom *FFTLutSource `json:"fftLutFrom,omitempty"`
+	// InterruptMode of the PF set up with pf-bb-config; msi requires vfio-pci pfDriver. pf-bb-config default is kept
+	// when not specified
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=polling;msi
+	InterruptMode InterruptMode `json:"interruptMode,omitempty"`
 }
 
+// InterruptMode tells how the device signals completions - by interrupts (MSI) or not at all, being polled
+type InterruptMode string
+
+const (
+	InterruptModePolling InterruptMode = "polling"
+	InterruptModeMSI     InterruptMode = "msi"
+)
+
 func (in *ACC100BBDevConfig) Validate() error {
 	totalQueueGroups := in.Uplink4G.NumQueueGroups + in.Downlink4G.NumQueueGroups + in.Uplink5G.NumQueueGroups + in.Downlink5G.NumQueueGroups
 	if totalQueueGroups > acc100maxQueueGroups {
 		return fmt.Errorf("total number of requested queue groups (4G/5G) %v exceeds the maximum (%d)", totalQueueGroups, acc100maxQueueGroups)
 	}
+	if in.FFTLutFrom != nil && (in.FFTLutFrom.ConfigMapRef == nil) == (in.FFTLutFrom.SecretRef == nil) {
+		return fmt.Errorf("fftLutFrom has to reference exactly one of configMapRef and secretRef")
+	}
 	return nil
 }
 
+// FFTLutSource references SRS FFT LUT binary kept in ConfigMap or Secret in operator's namespace
+type FFTLutSource struct {
+	// ConfigMapRef selects ConfigMap holding the binary in binaryData (or data)
+	// +kubebuilder:validation:Optional
+	ConfigMapRef *LocalObjectReference `json:"configMapRef,omitempty"`
+	// SecretRef selects Secret holding the binary
+	// +kubebuilder:validation:Optional
+	SecretRef *LocalObjectReference `json:"secretRef,omitempty"`
+	// Key of the binary in ConfigMap or Secret
+	// +kubebuilder:validation:MinLength=1
+	Key string `json:"key"`
+	// SHA-256 checksum of the binary
+	// +kubebuilder:validation:Pattern=`^[a-fA-F0-9]{64}$`
+	Checksum string `json:"checksum"`
+}
+
+// LocalObjectReference references object in operator's namespace by name
+type LocalObjectReference struct {
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
 // FFTLutParam specifies variables required to use custom fft bin file
 type FFTLutParam struct {
 	// Path to .tar.gz SRS-FFT file
@@ -135,8 +185,9 @@ type FFTLutParam struct {
 // ACC200BBDevConfig specifies variables to configure ACC200 with
 type ACC200BBDevConfig struct {
 	ACC100BBDevConfig `json:",inline"`
-	QFFT              QueueGroupConfig `json:"qfft"`
-	FFTLut            FFTLutParam      `json:"fftLut,omitempty"`
+	// +kubebuilder:validation:Optional
+	QFFT   QueueGroupConfig `json:"qfft"`
+	FFTLut FFTLutParam      `json:"fftLut,omitempty"`
 }
 
 func (in *ACC200BBDevConfig) Validate() error {
@@ -185,13 +236,43 @@ type PhysicalFunctionConfig struct {
 	// VFAmount is an amount of VFs to be created
 	// +kubebuilder:validation:Minimum=1
 	VFAmount int `json:"vfAmount"`
-	// BBDevConfig is a config for PF's queues
+	// BBDevConfig is a config for PF's queues; it can be partial when defaults.bbDevConfig is specified
+	// +kubebuilder:validation:Optional
 	BBDevConfig BBDevConfig `json:"bbDevConfig"`
+	// BBDevConfigFrom references pf-bb-config file used instead of bbDevConfig
+	// +kubebuilder:validation:Optional
+	BBDevConfigFrom *BBDevConfigFromSource `json:"bbDevConfigFrom,omitempty"`
+	// LogicalNamePrefix enables deterministic logical names of PF's VFs, <prefix>-<pfIndex>-vf<vfIndex>; PFs sharing
+	// the prefix are indexed in order of their PCI addresses, VFs of PF in order of theirs
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MaxLength=40
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	LogicalNamePrefix string `json:"logicalNamePrefix,omitempty"`
+}
+
+// BBDevConfigFromSource defines where pf-bb-config file is kept
+type BBDevConfigFromSource struct {
+	// ConfigMapRef selects key of ConfigMap (in operator's namespace) holding pf-bb-config file
+	ConfigMapRef ConfigMapKeyReference `json:"configMapRef"`
+}
+
+// ConfigMapKeyReference selects key of ConfigMap
+type ConfigMapKeyReference struct {
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// +kubebuilder:validation:MinLength=1
+	Key string `json:"key"`
+}
+
+// ClusterConfigDefaults defines values shared by physical function configs of the cluster config
+type ClusterConfigDefaults struct {
+	// BBDevConfig is merged into physicalFunction.bbDevConfig; values specified in physicalFunction take precedence
+	BBDevConfig *BBDevConfig `json:"bbDevConfig,omitempty"`
 }
 
 type PhysicalFunctionConfigExt struct {
 	// PCIAdress is a Physical Functions's PCI address that will be configured according to this spec
-	// +kubebuilder:validation:Pattern=`^[a-fA-F0-9]{4}:[a-fA-F0-9]{2}:[01][a-fA-F0-9]\.[0-7]$`
+	// +kubebuilder:validation:Pattern=`^([a-fA-F0-9]{4,8}:)?[a-fA-F0-9]{2}:[01][a-fA-F0-9]\.[0-7]$`
 	PCIAddress string `json:"pciAddress"`
 
 	// PFDriver to bound the PFs to
@@ -206,7 +287,19 @@ type PhysicalFunctionConfigExt struct {
 	VFAmount int `json:"vfAmount"`
 
 	// BBDevConfig is a config for PF's queues
+	// +kubebuilder:validation:Optional
 	BBDevConfig BBDevConfig `json:"bbDevConfig"`
+
+	// BBDevConfigFrom references pf-bb-config file used instead of bbDevConfig
+	// +kubebuilder:validation:Optional
+	BBDevConfigFrom *BBDevConfigFromSource `json:"bbDevConfigFrom,omitempty"`
+
+	// LogicalNamePrefix enables deterministic logical names of PF's VFs, <prefix>-<pfIndex>-vf<vfIndex>; PFs sharing
+	// the prefix are indexed in order of their PCI addresses, VFs of PF in order of theirs
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MaxLength=40
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	LogicalNamePrefix string `json:"logicalNamePrefix,omitempty"`
 }
 
 // SriovFecClusterConfigSpec defines the desired state of SriovFecClusterConfig
@@ -224,6 +317,10 @@ type SriovFecClusterConfigSpec struct {
 	// Physical function (card) config
 	PhysicalFunction PhysicalFunctionConfig `json:"physicalFunction"`
 
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// Defaults applied to physicalFunction config before it is propagated to nodes
+	Defaults *ClusterConfigDefaults `json:"defaults,omitempty"`
+
 	// +operator-sdk:csv:customresourcedefinitions:type=spec
 	// Higher priority policies can override lower ones.
 	Priority int `json:"priority,omitempty"`
@@ -231,13 +328,90 @@ type SriovFecClusterConfigSpec struct {
 	// +operator-sdk:csv:customresourcedefinitions:type=spec
 	// Skips drain process when true; default false. Should be true if operator is running on SNO
 	DrainSkip bool `json:"drainSkip,omitempty"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// Reconfigures the node with drain skipped even when VFs to be removed or rebound are allocated to running pods;
+	// default false, configuration is refused with DeviceInUse reason then
+	ForceVfRemoval bool `json:"forceVfRemoval,omitempty"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// How pods are removed from the node during drain: evict (default) uses the eviction subresource, respecting
+	// PodDisruptionBudgets; delete deletes pods directly, which is faster, e.g. in CI clusters. When more configs are
+	// applied to the node, delete is used if any of them requests it
+	// +optional
+	DrainEvictionMode DrainEvictionMode `json:"drainEvictionMode,omitempty"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// Grace period of pods deleted in delete drainEvictionMode; terminationGracePeriodSeconds of the pod when not set.
+	// The longest one of configs applied to the node is used
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	DrainGracePeriodSeconds *int64 `json:"drainGracePeriodSeconds,omitempty"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// Number of times failed configuration of the same node config generation is retried before the daemon gives up
+	// with ConfigurationGivenUp reason; unlimited when not set. The lowest one of configs applied to the node is used
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxConfigurationRetries *int `json:"maxConfigurationRetries,omitempty"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// CPUs (list format, e.g. "0-1,32-33") pf-bb-config and other child processes of the daemon run on, so that they do
+	// not perturb workloads on isolated CPUs. Detected from isolcpus/nohz_full kernel params when not set; no affinity
+	// is applied on nodes without isolated CPUs. The one of the config with the highest priority is used
+	// +optional
+	HousekeepingCpuSet string `json:"housekeepingCpuSet,omitempty"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// Halts all configuration activity in the cluster when true (emergency stop); default false.
+	// Applies to all nodes regardless of nodeSelector; configurations already in progress are finished
+	Disabled bool `json:"disabled,omitempty"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// Refuses configuration when compatibility checks from discovery config fail; default true.
+	// When false, violations are only reported as warnings in the Configured condition message
+	EnforceCompatibilityChecks *bool `json:"enforceCompatibilityChecks,omitempty"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// Time to wait after spec change before node is configured, restarted by each further change; default 0.
+	// Allows changes applied in several steps to be configured (and nodes drained) once, with the final spec
+	ConfigurationDebounce *metav1.Duration `json:"configurationDebounce,omitempty"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// Executable run on the host within the drain, before accelerators are configured. Node-wide - when more
+	// configs applied to the node specify it, the one of the config with the highest priority (then name) is used
+	PreConfigureHook *ConfigurationHook `json:"preConfigureHook,omitempty"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// Executable run on the host within the drain, after accelerators are configured and the device plugin restarted.
+	// Node-wide, like preConfigureHook
+	PostConfigureHook *ConfigurationHook `json:"postConfigureHook,omitempty"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// Seconds to wait after accelerators are configured and the device plugin restarted, before the node is uncordoned
+	// and workloads return (e.g. pf-bb-config warm-up); default 0. The longest one of configs applied to the node is used
+	// +kubebuilder:validation:Minimum=0
+	PostConfigureSoakSeconds int `json:"postConfigureSoakSeconds,omitempty"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// Adopts configuration found on the node (e.g. VFs and pf-bb-config set up manually) when it already matches the
+	// spec, instead of tearing it down; default false. PFs which differ are reconfigured as usual. Node-wide - any
+	// config applied to the node enabling it enables it for the whole node
+	AdoptExistingConfig bool `json:"adoptExistingConfig,omitempty"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// Reboots the node after repeated pf-bb-config failures of the same PF; disabled when not set. Node-wide - any
+	// config applied to the node enabling it enables it for the whole node, with the highest threshold and the longest
+	// cooldown of such configs
+	// +optional
+	AutoRebootOnPersistentFailure *AutoRebootPolicy `json:"autoRebootOnPersistentFailure,omitempty"`
 }
 
 type AcceleratorSelector struct {
 	VendorID string `json:"vendorID,omitempty"`
 	DeviceID string `json:"deviceID,omitempty"`
 	// +kubebuilder:validation:Optional
-	// +kubebuilder:validation:Pattern=`^[a-fA-F0-9]{4}:[a-fA-F0-9]{2}:[01][a-fA-F0-9]\.[0-7]$`
+	// +kubebuilder:validation:Pattern=`^([a-fA-F0-9]{4,8}:)?[a-fA-F0-9]{2}:[01][a-fA-F0-9]\.[0-7]$`
 	PCIAddress string `json:"pciAddress,omitempty"`
 	//+kubebuilder:validation:Pattern=`(pci-pf-stub|pci_pf_stub|igb_uio|vfio-pci)`
 	PFDriver string `json:"driver,omitempty"`
@@ -250,6 +424,66 @@ type SriovFecClusterConfigStatus struct {
 	// +operator-sdk:csv:customresourcedefinitions:type=status
 	SyncStatus    SyncStatus `json:"syncStatus,omitempty"`
 	LastSyncError string     `json:"lastSyncError,omitempty"`
+	// Explains why accelerated nodes were not selected (or were selected only partially) by this config.
+	// Only non-matching and conflicting nodes are listed, recomputed on each sync.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MaxItems=50
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	NodeDecisions []NodeDecision `json:"nodeDecisions,omitempty"`
+	// Conditions of the config; DryRun condition is set while the config is previewed with DryRunAnnotation
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// Specs of SriovFecNodeConfigs the config would result in on the nodes it selects, together with the applied configs.
+	// Listed only while the config is previewed with DryRunAnnotation, the first nodes by name.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MaxItems=10
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	DryRunNodeConfigs []DryRunNodeConfig `json:"dryRunNodeConfigs,omitempty"`
+	// Cluster-wide number of VFs and of VFs allocated to pods, aggregated from SriovFecNodeConfigs and nodes. It is the same
+	// in every config.
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	VFCapacity *VFCapacity `json:"vfCapacity,omitempty"`
+}
+
+// VFCapacity is number of VFs of all accelerated nodes and of VFs allocated to pods
+type VFCapacity struct {
+	// Number of VFs of all accelerated nodes
+	TotalVFs int `json:"totalVFs"`
+	// Number of VFs allocated to pods, as reported by daemons with VFPodUsage feature gate; VFs of nodes which do not
+	// report usage are not counted
+	AllocatedVFs int `json:"allocatedVFs"`
+	// Number of nodes which VFs are counted from node allocatable, as their inventory reports no VFs
+	NodesWithoutInventory int `json:"nodesWithoutInventory,omitempty"`
+	// Number of nodes which do not report VFs allocated to pods
+	NodesWithoutUsage int `json:"nodesWithoutUsage,omitempty"`
+	// Breakdown by device plugin resource; VFs not exposed as any resource are counted only in totals
+	Resources []ResourceVFCapacity `json:"resources,omitempty"`
+	// Time the capacity last changed
+	LastUpdated metav1.Time `json:"lastUpdated"`
+}
+
+// ResourceVFCapacity is number of VFs exposed as device plugin resource and of the ones allocated to pods
+type ResourceVFCapacity struct {
+	// Name of the resource, as in node allocatable, e.g. intel.com/intel_fec_acc100
+	ResourceName string `json:"resourceName"`
+	TotalVFs     int    `json:"totalVFs"`
+	AllocatedVFs int    `json:"allocatedVFs"`
+}
+
+// DryRunNodeConfig is spec of SriovFecNodeConfig previewed config would result in on the node
+type DryRunNodeConfig struct {
+	NodeName string                 `json:"nodeName"`
+	Spec     SriovFecNodeConfigSpec `json:"spec"`
+}
+
+// NodeDecision describes outcome of matching SriovFecClusterConfig against single node
+type NodeDecision struct {
+	NodeName string `json:"nodeName"`
+	// Indicates whether config is applied to at least one accelerator of the node
+	Matched bool   `json:"matched"`
+	Reason  string `json:"reason"`
 }
 
 // +kubebuilder:object:root=true
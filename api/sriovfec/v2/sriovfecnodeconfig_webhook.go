@@ -0,0 +1,141 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package v2
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var sriovfecnodeconfiglog = utils.NewLogger()
+
+func (in *SriovFecNodeConfig) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	mgr.GetWebhookServer().Register("/mutate-sriovfec-intel-com-v2-sriovfecnodeconfig", admission.DefaultingWebhookFor(in))
+	mgr.GetWebhookServer().Register("/validate-sriovfec-intel-com-v2-sriovfecnodeconfig", &webhook.Admission{
+		Handler: &nodeConfigValidatingHandler{reader: mgr.GetClient()},
+	})
+	return nil
+}
+
+//+kubebuilder:webhook:path=/mutate-sriovfec-intel-com-v2-sriovfecnodeconfig,mutating=true,failurePolicy=fail,sideEffects=None,groups=sriovfec.intel.com,resources=sriovfecnodeconfigs,verbs=create;update,versions=v2,name=msriovfecnodeconfig.kb.io,admissionReviewVersions={v1}
+
+var _ webhook.Defaulter = &SriovFecNodeConfig{}
+
+// Default implements webhook.Defaulter; PCI addresses of PFs are brought to canonical form (lowercase, zero-padded,
+// with domain), so that what is stored matches addresses of the inventory exactly
+func (in *SriovFecNodeConfig) Default() {
+	for i := range in.Spec.PhysicalFunctions {
+		in.Spec.PhysicalFunctions[i].PCIAddress = utils.CanonicalPCIAddress(in.Spec.PhysicalFunctions[i].PCIAddress)
+	}
+}
+
+//+kubebuilder:webhook:path=/validate-sriovfec-intel-com-v2-sriovfecnodeconfig,mutating=false,failurePolicy=fail,sideEffects=None,groups=sriovfec.intel.com,resources=sriovfecnodeconfigs,verbs=create;update,versions=v2,name=vsriovfecnodeconfig.kb.io,admissionReviewVersions={v1}
+
+// nodeConfigValidatingHandler checks that node configs of the same node configure disjoint sets of accelerators, as
+// the daemon configures accelerators of each of them separately
+type nodeConfigValidatingHandler struct {
+	reader  client.Reader
+	decoder *admission.Decoder
+}
+
+var _ admission.DecoderInjector = &nodeConfigValidatingHandler{}
+
+func (h *nodeConfigValidatingHandler) InjectDecoder(d *admission.Decoder) error {
+	h.decoder = d
+	return nil
+}
+
+func (h *nodeConfigValidatingHandler) Handle(ctx context.Context, req admission.Request) admission.Response {
+	nc := &SriovFecNodeConfig{}
+	if err := h.decoder.DecodeRaw(req.Object, nc); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	var old *SriovFecNodeConfig
+	if req.Operation == admissionv1.Update {
+		old = &SriovFecNodeConfig{}
+		if err := h.decoder.DecodeRaw(req.OldObject, old); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+	}
+
+	errs, err := validateNodeConfig(ctx, h.reader, nc, old)
+	if err != nil {
+		sriovfecnodeconfiglog.WithError(err).WithField("name", nc.Name).Error("failed to validate against other node configs")
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if len(errs) != 0 {
+		message := apierrors.NewInvalid(schema.GroupKind{Group: "sriovfec.intel.com", Kind: "SriovFecNodeConfig"}, nc.Name, errs).Error()
+		response := admission.Denied(message)
+		// apiserver shows message, not reason of the denial
+		response.Result.Message = message
+		return response
+	}
+	return admission.Allowed("")
+}
+
+// validateNodeConfig checks the node targeted by the node config and that none of its accelerators is configured by
+// another node config of the node, nor any of its logical name prefixes is used by one; old is nil on create
+func validateNodeConfig(ctx context.Context, reader client.Reader, nc, old *SriovFecNodeConfig) (field.ErrorList, error) {
+	var errs field.ErrorList
+	nodeNamePath := field.NewPath("spec", "nodeName")
+	if nc.IsSecondary() && !strings.HasPrefix(nc.Name, nc.Spec.NodeName+"-") {
+		errs = append(errs, field.Invalid(nodeNamePath, nc.Spec.NodeName, "node config targeting another node has to be named <nodeName>-<suffix>"))
+	}
+	if old != nil && old.TargetNodeName() != nc.TargetNodeName() {
+		errs = append(errs, field.Forbidden(nodeNamePath, "node targeted by the node config cannot be changed"))
+	}
+	if len(errs) != 0 {
+		return errs, nil
+	}
+
+	nodeConfigs := &SriovFecNodeConfigList{}
+	if err := reader.List(ctx, nodeConfigs, client.InNamespace(nc.Namespace)); err != nil {
+		return nil, err
+	}
+	owners, prefixOwners := map[string]string{}, map[string]string{}
+	for _, other := range nodeConfigs.Items {
+		if other.Name == nc.Name || other.TargetNodeName() != nc.TargetNodeName() {
+			continue
+		}
+		for _, pf := range other.Spec.PhysicalFunctions {
+			owners[utils.CanonicalPCIAddress(pf.PCIAddress)] = other.Name
+			if pf.LogicalNamePrefix != "" {
+				prefixOwners[pf.LogicalNamePrefix] = other.Name
+			}
+		}
+	}
+	for i, pf := range nc.Spec.PhysicalFunctions {
+		// PFs sharing the prefix are indexed within the node config, so names of VFs would collide
+		if owner, found := prefixOwners[pf.LogicalNamePrefix]; found {
+			errs = append(errs, field.Forbidden(field.NewPath("spec", "physicalFunctions").Index(i).Child("logicalNamePrefix"),
+				"logical name prefix "+pf.LogicalNamePrefix+" is already used by SriovFecNodeConfig "+owner))
+		}
+		pciAddressPath := field.NewPath("spec", "physicalFunctions").Index(i).Child("pciAddress")
+		pciAddress, err := utils.NormalizePCIAddress(pf.PCIAddress)
+		if err != nil {
+			errs = append(errs, field.Invalid(pciAddressPath, pf.PCIAddress, err.Error()))
+			continue
+		}
+		if owner, found := owners[pciAddress]; found {
+			errs = append(errs, field.Forbidden(pciAddressPath, "accelerator "+pf.PCIAddress+" is already configured by SriovFecNodeConfig "+owner))
+			continue
+		}
+		if err := validatePCIAddress(pciAddressPath, pf.PCIAddress); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs, nil
+}
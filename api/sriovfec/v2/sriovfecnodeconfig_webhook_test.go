@@ -0,0 +1,125 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package v2
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestNodeConfigsOfNodeConfigureDisjointAccelerators(t *testing.T) {
+	g := NewWithT(t)
+	nodeConfig := func(name, nodeName string, pciAddresses ...string) *SriovFecNodeConfig {
+		nc := &SriovFecNodeConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       SriovFecNodeConfigSpec{NodeName: nodeName},
+		}
+		for _, pciAddress := range pciAddresses {
+			nc.Spec.PhysicalFunctions = append(nc.Spec.PhysicalFunctions, PhysicalFunctionConfigExt{PCIAddress: pciAddress})
+		}
+		return nc
+	}
+	reader := fake.NewClientBuilder().WithScheme(inventoryValidationScheme(g)).WithObjects(
+		nodeConfig("worker-0", "", "0000:1b:00.0"),
+		nodeConfig("worker-0-acc100", "worker-0", "0000:f7:00.0"),
+		nodeConfig("worker-1", "", "0000:f7:00.0"),
+	).Build()
+
+	g.Expect(validateNodeConfig(context.TODO(), reader, nodeConfig("worker-0-acc200", "worker-0", "0000:f8:00.0"), nil)).To(BeEmpty())
+	// the same address on another node
+	g.Expect(validateNodeConfig(context.TODO(), reader, nodeConfig("worker-1-n3000", "worker-1", "0000:1b:00.0"), nil)).To(BeEmpty())
+	// node config is not in conflict with its previous version
+	g.Expect(validateNodeConfig(context.TODO(), reader, nodeConfig("worker-0-acc100", "worker-0", "0000:f7:00.0", "0000:f8:00.0"),
+		nodeConfig("worker-0-acc100", "worker-0", "0000:f7:00.0"))).To(BeEmpty())
+
+	errs, err := validateNodeConfig(context.TODO(), reader, nodeConfig("worker-0-acc200", "worker-0", "0000:f8:00.0", "0000:f7:00.0"), nil)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(errs.ToAggregate().Error()).To(Equal(
+		"spec.physicalFunctions[1].pciAddress: Forbidden: accelerator 0000:f7:00.0 is already configured by SriovFecNodeConfig worker-0-acc100"))
+
+	// the same accelerator in another form of its address
+	errs, err = validateNodeConfig(context.TODO(), reader, nodeConfig("worker-0-acc200", "worker-0", "F7:00.0"), nil)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(errs).To(HaveLen(1))
+	// accelerator behind VMD domain
+	g.Expect(validateNodeConfig(context.TODO(), reader, nodeConfig("worker-0-vmd", "worker-0", "10000:f7:00.0"), nil)).To(BeEmpty())
+
+	errs, err = validateNodeConfig(context.TODO(), reader, nodeConfig("worker-0-acc200", "worker-0", "0000:f7:00"), nil)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(errs.ToAggregate().Error()).To(ContainSubstring("invalid PCI address '0000:f7:00'"))
+
+	// primary node config cannot take over accelerator of the secondary one
+	errs, err = validateNodeConfig(context.TODO(), reader, nodeConfig("worker-0", "", "0000:1b:00.0", "0000:f7:00.0"), nodeConfig("worker-0", "", "0000:1b:00.0"))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(errs).To(HaveLen(1))
+}
+
+func TestNodeConfigTargetingAnotherNodeIsNamedAfterIt(t *testing.T) {
+	g := NewWithT(t)
+	reader := fake.NewClientBuilder().WithScheme(inventoryValidationScheme(g)).Build()
+	nodeConfig := func(name, nodeName string) *SriovFecNodeConfig {
+		return &SriovFecNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}, Spec: SriovFecNodeConfigSpec{NodeName: nodeName}}
+	}
+
+	g.Expect(validateNodeConfig(context.TODO(), reader, nodeConfig("worker-0", "worker-0"), nil)).To(BeEmpty())
+
+	errs, err := validateNodeConfig(context.TODO(), reader, nodeConfig("acc100", "worker-0"), nil)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(errs.ToAggregate().Error()).To(Equal(
+		`spec.nodeName: Invalid value: "worker-0": node config targeting another node has to be named <nodeName>-<suffix>`))
+
+	errs, err = validateNodeConfig(context.TODO(), reader, nodeConfig("worker-0-acc100", "worker-0"), nodeConfig("worker-0-acc100", ""))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(errs.ToAggregate().Error()).To(Equal("spec.nodeName: Forbidden: node targeted by the node config cannot be changed"))
+}
+
+func TestLogicalNamePrefixIsNotSharedByNodeConfigsOfNode(t *testing.T) {
+	g := NewWithT(t)
+	nodeConfig := func(name, nodeName, pciAddress, prefix string) *SriovFecNodeConfig {
+		return &SriovFecNodeConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: SriovFecNodeConfigSpec{NodeName: nodeName, PhysicalFunctions: []PhysicalFunctionConfigExt{
+				{PCIAddress: pciAddress, LogicalNamePrefix: prefix},
+			}},
+		}
+	}
+	reader := fake.NewClientBuilder().WithScheme(inventoryValidationScheme(g)).WithObjects(
+		nodeConfig("worker-0", "", "0000:1b:00.0", "fec"),
+		nodeConfig("worker-1", "", "0000:1b:00.0", "acc100"),
+	).Build()
+
+	g.Expect(validateNodeConfig(context.TODO(), reader, nodeConfig("worker-0-acc100", "worker-0", "0000:f7:00.0", "acc100"), nil)).To(BeEmpty())
+	g.Expect(validateNodeConfig(context.TODO(), reader, nodeConfig("worker-0-acc100", "worker-0", "0000:f7:00.0", ""), nil)).To(BeEmpty())
+
+	errs, err := validateNodeConfig(context.TODO(), reader, nodeConfig("worker-0-acc100", "worker-0", "0000:f7:00.0", "fec"), nil)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(errs.ToAggregate().Error()).To(Equal(
+		"spec.physicalFunctions[0].logicalNamePrefix: Forbidden: logical name prefix fec is already used by SriovFecNodeConfig worker-0"))
+}
+
+func TestPCIAddressesOfNodeConfigAreNormalizedAndValidated(t *testing.T) {
+	g := NewWithT(t)
+	reader := fake.NewClientBuilder().WithScheme(inventoryValidationScheme(g)).Build()
+	nc := &SriovFecNodeConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker-0", Namespace: "default"},
+		Spec: SriovFecNodeConfigSpec{PhysicalFunctions: []PhysicalFunctionConfigExt{
+			{PCIAddress: "F7:00.0"}, {PCIAddress: " 0000:1B:0.0"}, {PCIAddress: "0000:f8:00.0"},
+		}},
+	}
+
+	errs, err := validateNodeConfig(context.TODO(), reader, nc, nil)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(errs.ToAggregate().Error()).To(Equal(`[spec.physicalFunctions[0].pciAddress: Invalid value: "F7:00.0": PCI address has to be in canonical form 0000:f7:00.0, ` +
+		`spec.physicalFunctions[1].pciAddress: Invalid value: " 0000:1B:0.0": PCI address has to be in canonical form 0000:1b:00.0]`))
+
+	nc.Default()
+	g.Expect(nc.Spec.PhysicalFunctions).To(Equal([]PhysicalFunctionConfigExt{
+		{PCIAddress: "0000:f7:00.0"}, {PCIAddress: "0000:1b:00.0"}, {PCIAddress: "0000:f8:00.0"},
+	}))
+	g.Expect(validateNodeConfig(context.TODO(), reader, nc, nil)).To(BeEmpty())
+}
@@ -0,0 +1,113 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package v2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// OperatorConfigName is the name of the only SriovFecOperatorConfig read by the operator and the daemons
+const OperatorConfigName = "default"
+
+// SriovFecOperatorConfigSpec defines operational settings of the operator and its daemons. Settings which are not
+// specified keep their defaults. Environment variables of the operator and daemon containers take precedence over
+// the settings they correspond to.
+type SriovFecOperatorConfigSpec struct {
+	// Verbosity of operator and daemon logs; applied without restart
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=panic;fatal;error;warning;info;debug;trace
+	LogLevel string `json:"logLevel,omitempty"`
+	// Period of daemon's NodeConfig reconciliation; applied without restart
+	// +kubebuilder:validation:Optional
+	ResyncPeriod *metav1.Duration `json:"resyncPeriod,omitempty"`
+	// Maximum number of ClusterConfigs reconciled at once (SRIOV_FEC_MAX_CONCURRENT_RECONCILES); operator is restarted
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	MaxConcurrentReconciles *int `json:"maxConcurrentReconciles,omitempty"`
+	// Maximum age of inventory which accelerator selectors are resolved against, 0 disables the check
+	// (SRIOV_FEC_INVENTORY_STALENESS_BOUND); operator is restarted
+	// +kubebuilder:validation:Optional
+	InventoryStalenessBound *metav1.Duration `json:"inventoryStalenessBound,omitempty"`
+	// Interval of telemetry updates (SRIOV_FEC_METRIC_GATHER_INTERVAL); daemons are restarted
+	// +kubebuilder:validation:Optional
+	MetricGatherInterval *metav1.Duration `json:"metricGatherInterval,omitempty"`
+	// Duration of operator's cordon after which it is reported as overdue (SRIOV_FEC_CORDON_OVERDUE_THRESHOLD);
+	// daemons are restarted
+	// +kubebuilder:validation:Optional
+	CordonOverdueThreshold *metav1.Duration `json:"cordonOverdueThreshold,omitempty"`
+	// Interval of reconciliation of node config while no accelerators are discovered on the node although its spec
+	// requests PFs (SRIOV_FEC_DEGRADED_REQUEUE_INTERVAL); daemons are restarted
+	// +kubebuilder:validation:Optional
+	DegradedRequeueInterval *metav1.Duration `json:"degradedRequeueInterval,omitempty"`
+	// Timeout of node drain, rounded to seconds (DRAIN_TIMEOUT_SECONDS); daemons are restarted
+	// +kubebuilder:validation:Optional
+	DrainTimeout *metav1.Duration `json:"drainTimeout,omitempty"`
+	// Timeout of rescheduling verification after uncordon, rounded to seconds (RESCHEDULE_TIMEOUT_SECONDS); daemons
+	// are restarted
+	// +kubebuilder:validation:Optional
+	RescheduleTimeout *metav1.Duration `json:"rescheduleTimeout,omitempty"`
+	// Time the drain lease is held for after the node was left cordoned for reboot, so that other nodes are not
+	// drained meanwhile; rounded to seconds, 0 releases it right away (REBOOT_LEASE_DURATION_SECONDS). Reboots are
+	// serialized with the reboot lock, see RebootLockName. Daemons are restarted
+	// +kubebuilder:validation:Optional
+	RebootLeaseDuration *metav1.Duration `json:"rebootLeaseDuration,omitempty"`
+	// Feature gates of daemons in "Gate=true,Other=false" format (FEATURE_GATES); daemons are restarted
+	// +kubebuilder:validation:Optional
+	FeatureGates string `json:"featureGates,omitempty"`
+	// Size of pf_bb_config output retained for Configured condition, in KB (SRIOV_FEC_PF_BB_CONFIG_OUTPUT_LIMIT_KB);
+	// daemons are restarted
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=16
+	PfBBConfigOutputLimitKB *int `json:"pfBBConfigOutputLimitKB,omitempty"`
+	// Directory on the host which preConfigureHook and postConfigureHook executables have to be located in, default
+	// /etc/sriov-fec/hooks/ (SRIOV_FEC_CONFIGURATION_HOOK_PATH_PREFIX); operator and daemons are restarted
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Pattern=`^/`
+	ConfigurationHookPathPrefix string `json:"configurationHookPathPrefix,omitempty"`
+	// Maximum size of condition messages in bytes, default 1024 (SRIOV_FEC_CONDITION_MESSAGE_LIMIT_BYTES); longer
+	// messages are truncated and their full text is emitted as event. Operator and daemons are restarted
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=256
+	// +kubebuilder:validation:Maximum=32768
+	ConditionMessageLimitBytes *int `json:"conditionMessageLimitBytes,omitempty"`
+	// Name of Lease in daemons' namespace which is held while the node reboots on persistent pf-bb-config failure,
+	// default sriov-fec-reboot-lock (SRIOV_FEC_REBOOT_LOCK_NAME); other agents rebooting nodes may share it. Daemons
+	// are restarted
+	// +kubebuilder:validation:Optional
+	RebootLockName string `json:"rebootLockName,omitempty"`
+	// Time the reboot lock held by other holder is waited for before the reboot is deferred
+	// (SRIOV_FEC_REBOOT_LOCK_TIMEOUT); daemons are restarted
+	// +kubebuilder:validation:Optional
+	RebootLockTimeout *metav1.Duration `json:"rebootLockTimeout,omitempty"`
+	// Time the reboot lock is held for without renewal, i.e. how long the node may take to reboot before other
+	// holders take the lock over (SRIOV_FEC_REBOOT_LOCK_DURATION); daemons are restarted
+	// +kubebuilder:validation:Optional
+	RebootLockDuration *metav1.Duration `json:"rebootLockDuration,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,shortName=sfoc
+
+// SriovFecOperatorConfig is the Schema for the sriovfecoperatorconfigs API; only the one named "default" is read
+// +operator-sdk:csv:customresourcedefinitions:displayName="SriovFecOperatorConfig"
+type SriovFecOperatorConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec SriovFecOperatorConfigSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// SriovFecOperatorConfigList contains a list of SriovFecOperatorConfig
+type SriovFecOperatorConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SriovFecOperatorConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SriovFecOperatorConfig{}, &SriovFecOperatorConfigList{})
+}
@@ -0,0 +1,207 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package v2
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+const n3000MaxQueues = 32
+
+// ValidatePhysicalFunction validates config of a single PF located at path (e.g. spec.physicalFunction of cluster
+// config). When device reported by node inventory is given, config is also validated against it. The webhook and
+// the daemon (through ValidateNodePhysicalFunction) share it, so both report the same errors.
+func ValidatePhysicalFunction(path *field.Path, pf PhysicalFunctionConfig, device *SriovAccelerator) field.ErrorList {
+	return validatePhysicalFunction(path, pf, device, true)
+}
+
+// ValidateNodePhysicalFunction validates PF of node config (e.g. spec.physicalFunctions[0]) like
+// ValidatePhysicalFunction does, except that bbDevConfig may be empty - the daemon then only creates VFs
+func ValidateNodePhysicalFunction(path *field.Path, pf PhysicalFunctionConfigExt, device *SriovAccelerator) field.ErrorList {
+	return validatePhysicalFunction(path, PhysicalFunctionConfig{
+		PFDriver:        pf.PFDriver,
+		VFDriver:        pf.VFDriver,
+		VFAmount:        pf.VFAmount,
+		BBDevConfig:     pf.BBDevConfig,
+		BBDevConfigFrom: pf.BBDevConfigFrom,
+	}, device, false)
+}
+
+func validatePhysicalFunction(path *field.Path, pf PhysicalFunctionConfig, device *SriovAccelerator, requireBBDevConfig bool) (errs field.ErrorList) {
+	if device != nil && pf.VFAmount > device.MaxVFs {
+		errs = append(errs, field.Invalid(path.Child("vfAmount"), pf.VFAmount,
+			fmt.Sprintf("value should not be greater than %d supported by %s", device.MaxVFs, device.PCIAddress)))
+	}
+
+	if presenceErrs := validateBBDevConfigPresence(path, pf, requireBBDevConfig); len(presenceErrs) != 0 || pf.BBDevConfigFrom != nil {
+		return append(errs, presenceErrs...)
+	}
+
+	bbDevConfigPath := path.Child("bbDevConfig")
+	if n3000 := pf.BBDevConfig.N3000; n3000 != nil {
+		errs = append(errs, validateN3000(bbDevConfigPath.Child("n3000"), n3000)...)
+	}
+	if acc100 := pf.BBDevConfig.ACC100; acc100 != nil {
+		errs = append(errs, validateACC100(bbDevConfigPath.Child("acc100"), acc100, pf.VFAmount, pf.PFDriver)...)
+	}
+	if acc200 := pf.BBDevConfig.ACC200; acc200 != nil {
+		errs = append(errs, validateACC200(bbDevConfigPath.Child("acc200"), acc200, pf.VFAmount)...)
+	}
+	return errs
+}
+
+func validateBBDevConfigPresence(path *field.Path, pf PhysicalFunctionConfig, required bool) field.ErrorList {
+	var configs int
+	for _, config := range []interface{}{pf.BBDevConfig.N3000, pf.BBDevConfig.ACC100, pf.BBDevConfig.ACC200} {
+		if !isNil(config) {
+			configs++
+		}
+	}
+
+	switch {
+	case configs > 1:
+		return field.ErrorList{field.Forbidden(path.Child("bbDevConfig"), "specified bbDevConfig cannot contain multiple configurations")}
+	case pf.BBDevConfigFrom != nil && configs != 0:
+		return field.ErrorList{field.Forbidden(path.Child("bbDevConfigFrom"), "bbDevConfig and bbDevConfigFrom are mutually exclusive")}
+	case required && pf.BBDevConfigFrom == nil && configs == 0:
+		return field.ErrorList{field.Forbidden(path.Child("bbDevConfig"), "bbDevConfig section cannot be empty")}
+	}
+	return nil
+}
+
+func validateN3000(path *field.Path, config *N3000BBDevConfig) (errs field.ErrorList) {
+	if config.NetworkType == "" {
+		errs = append(errs, field.Required(path.Child("networkType"), "networkType has to be specified in physicalFunction or defaults"))
+	}
+
+	sum := func(q UplinkDownlinkQueues) int {
+		return q.VF0 + q.VF1 + q.VF2 + q.VF3 + q.VF4 + q.VF5 + q.VF6 + q.VF7
+	}
+	if total := sum(config.Uplink.Queues); total > n3000MaxQueues {
+		errs = append(errs, field.Invalid(path.Child("uplink", "queues"), total,
+			fmt.Sprintf("sum of all specified queues must be no more than %d", n3000MaxQueues)))
+	}
+	if total := sum(config.Downlink.Queues); total > n3000MaxQueues {
+		errs = append(errs, field.Invalid(path.Child("downlink", "queues"), total,
+			fmt.Sprintf("sum of all specified queues must be no more than %d", n3000MaxQueues)))
+	}
+	return errs
+}
+
+type queueGroup struct {
+	name   string
+	config QueueGroupConfig
+}
+
+func acc100QueueGroups(config *ACC100BBDevConfig) []queueGroup {
+	return []queueGroup{
+		{"uplink4G", config.Uplink4G},
+		{"downlink4G", config.Downlink4G},
+		{"uplink5G", config.Uplink5G},
+		{"downlink5G", config.Downlink5G},
+	}
+}
+
+func validateQueueGroups(path *field.Path, groups []queueGroup, maxQueueGroups int) (errs field.ErrorList) {
+	var total int
+	for _, group := range groups {
+		if group.config.NumAqsPerGroups == 0 || group.config.AqDepthLog2 == 0 {
+			errs = append(errs, field.Required(path.Child(group.name), "queue group has to be specified in physicalFunction or defaults"))
+		}
+		total += group.config.NumQueueGroups
+	}
+	if total > maxQueueGroups {
+		errs = append(errs, field.Invalid(path, total, fmt.Sprintf("sum of all numQueueGroups should not be greater than %d", maxQueueGroups)))
+	}
+	return errs
+}
+
+func validateNumVfBundles(path *field.Path, numVfBundles, vfAmount int) field.ErrorList {
+	if numVfBundles != vfAmount {
+		return field.ErrorList{field.Invalid(path.Child("numVfBundles"), numVfBundles, "value should be the same as physicalFunction.vfAmount")}
+	}
+	return nil
+}
+
+func validateACC100(path *field.Path, config *ACC100BBDevConfig, vfAmount int, pfDriver string) (errs field.ErrorList) {
+	errs = append(errs, validateNumVfBundles(path, config.NumVfBundles, vfAmount)...)
+	errs = append(errs, validateQueueGroups(path, acc100QueueGroups(config), acc100maxQueueGroups)...)
+	if config.FFTLutFrom != nil && (config.FFTLutFrom.ConfigMapRef == nil) == (config.FFTLutFrom.SecretRef == nil) {
+		errs = append(errs, field.Forbidden(path.Child("fftLutFrom"), "fftLutFrom has to reference exactly one of configMapRef and secretRef"))
+	}
+	// MSI of the PF is delivered only through vfio-pci, which the daemon enables MSI of the PF for
+	if config.InterruptMode == InterruptModeMSI && !strings.EqualFold(pfDriver, utils.VFIO_PCI) {
+		errs = append(errs, field.Invalid(path.Child("interruptMode"), config.InterruptMode,
+			fmt.Sprintf("msi interrupt mode requires %s pfDriver, not %s", utils.VFIO_PCI, pfDriver)))
+	}
+	return errs
+}
+
+func validateACC200(path *field.Path, config *ACC200BBDevConfig, vfAmount int) (errs field.ErrorList) {
+	errs = append(errs, validateNumVfBundles(path, config.NumVfBundles, vfAmount)...)
+	groups := append(acc100QueueGroups(&config.ACC100BBDevConfig), queueGroup{"qfft", config.QFFT})
+	errs = append(errs, validateQueueGroups(path, groups, acc200maxQueueGroups)...)
+	if config.InterruptMode != "" {
+		errs = append(errs, field.Forbidden(path.Child("interruptMode"), "interruptMode is supported only by ACC100"))
+	}
+	return errs
+}
+
+// validatePCIAddress requires PCI address located at path in canonical form, which the defaulting webhook brings
+// addresses to; the daemon matches addresses of the spec against sysfs
+func validatePCIAddress(path *field.Path, address string) *field.Error {
+	normalized, err := utils.NormalizePCIAddress(address)
+	if err != nil {
+		return field.Invalid(path, address, err.Error())
+	}
+	if normalized != address {
+		return field.Invalid(path, address, "PCI address has to be in canonical form "+normalized)
+	}
+	return nil
+}
+
+// validateDrainGracePeriod validates grace period located at path, which applies only to pods deleted in delete mode
+func validateDrainGracePeriod(path *field.Path, mode DrainEvictionMode, gracePeriod *int64) (errs field.ErrorList) {
+	if gracePeriod == nil {
+		return nil
+	}
+	if *gracePeriod < 0 {
+		errs = append(errs, field.Invalid(path, *gracePeriod, "must be greater than or equal to 0"))
+	}
+	if mode != DrainEvictionModeDelete {
+		errs = append(errs, field.Forbidden(path, "drainGracePeriodSeconds is supported only by delete drainEvictionMode"))
+	}
+	return errs
+}
+
+// ValidateConfigurationHook validates hook located at path (e.g. spec.preConfigureHook); nil hook is valid. Executable
+// of the hook has to be located under the prefix allowed by the operator, so that arbitrary host binaries cannot be run.
+func ValidateConfigurationHook(path *field.Path, hook *ConfigurationHook) (errs field.ErrorList) {
+	if hook == nil {
+		return nil
+	}
+	if prefix := utils.ConfigurationHookPathPrefix(); !utils.IsAllowedHookPath(hook.Path, prefix) {
+		errs = append(errs, field.Invalid(path.Child("path"), hook.Path,
+			fmt.Sprintf("value should be a clean absolute path located under %s", prefix)))
+	}
+	if hook.Timeout != nil && hook.Timeout.Duration <= 0 {
+		errs = append(errs, field.Invalid(path.Child("timeout"), hook.Timeout.Duration.String(), "value should be positive"))
+	}
+	return errs
+}
+
+// ValidateVFDriver validates vfDriver of PF located at path against drivers the discovery config (supported-accelerators
+// ConfigMap) allows for VFs of the device, on given kernel release of the node. Kernel bounds are not validated when
+// kernel is empty. The webhook and the daemon share it, so both report the same errors.
+func ValidateVFDriver(path *field.Path, vfDriver, deviceID, kernel string, allowed map[string][]utils.VFDriverSupport) field.ErrorList {
+	if err := utils.CheckVFDriver(allowed, deviceID, vfDriver, kernel); err != nil {
+		return field.ErrorList{field.Invalid(path.Child("vfDriver"), vfDriver, err.Error())}
+	}
+	return nil
+}
@@ -0,0 +1,358 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package v2
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+func validACC100PhysicalFunction() PhysicalFunctionConfig {
+	queueGroup := QueueGroupConfig{NumQueueGroups: 2, NumAqsPerGroups: 16, AqDepthLog2: 4}
+	return PhysicalFunctionConfig{
+		PFDriver: "vfio-pci",
+		VFDriver: "vfio-pci",
+		VFAmount: 2,
+		BBDevConfig: BBDevConfig{ACC100: &ACC100BBDevConfig{
+			NumVfBundles: 2,
+			MaxQueueSize: 1024,
+			Uplink4G:     queueGroup,
+			Downlink4G:   queueGroup,
+			Uplink5G:     queueGroup,
+			Downlink5G:   queueGroup,
+		}},
+	}
+}
+
+// physicalFunctionCorpus is shared by admission (cluster config) and pre-drain (node config) validation; every entry
+// lists fields (relative to PF) of expected errors
+var physicalFunctionCorpus = []struct {
+	name   string
+	pf     func() PhysicalFunctionConfig
+	device *SriovAccelerator
+	fields []string
+}{
+	{
+		name: "valid acc100",
+		pf:   validACC100PhysicalFunction,
+	},
+	{
+		name: "valid bbDevConfigFrom",
+		pf: func() PhysicalFunctionConfig {
+			pf := validACC100PhysicalFunction()
+			pf.BBDevConfig = BBDevConfig{}
+			pf.BBDevConfigFrom = &BBDevConfigFromSource{ConfigMapRef: ConfigMapKeyReference{Name: "acc-config", Key: "acc100.cfg"}}
+			return pf
+		},
+	},
+	{
+		name: "valid n3000",
+		pf: func() PhysicalFunctionConfig {
+			pf := validACC100PhysicalFunction()
+			pf.BBDevConfig = BBDevConfig{N3000: &N3000BBDevConfig{
+				NetworkType: "FPGA_5GNR",
+				Uplink:      UplinkDownlink{Queues: UplinkDownlinkQueues{VF0: 16, VF1: 16}},
+				Downlink:    UplinkDownlink{Queues: UplinkDownlinkQueues{VF0: 16, VF5: 16}},
+			}}
+			return pf
+		},
+	},
+	{
+		name:   "vfAmount within device limit",
+		pf:     validACC100PhysicalFunction,
+		device: &SriovAccelerator{PCIAddress: "0000:af:00.0", MaxVFs: 2},
+	},
+	{
+		name:   "vfAmount over device limit",
+		pf:     validACC100PhysicalFunction,
+		device: &SriovAccelerator{PCIAddress: "0000:af:00.0", MaxVFs: 1},
+		fields: []string{"vfAmount"},
+	},
+	{
+		name: "multiple configurations",
+		pf: func() PhysicalFunctionConfig {
+			pf := validACC100PhysicalFunction()
+			pf.BBDevConfig.N3000 = &N3000BBDevConfig{NetworkType: "FPGA_LTE"}
+			return pf
+		},
+		fields: []string{"bbDevConfig"},
+	},
+	{
+		name: "bbDevConfig with bbDevConfigFrom",
+		pf: func() PhysicalFunctionConfig {
+			pf := validACC100PhysicalFunction()
+			pf.BBDevConfigFrom = &BBDevConfigFromSource{ConfigMapRef: ConfigMapKeyReference{Name: "acc-config", Key: "acc100.cfg"}}
+			return pf
+		},
+		fields: []string{"bbDevConfigFrom"},
+	},
+	{
+		name: "n3000 without networkType and with too many queues",
+		pf: func() PhysicalFunctionConfig {
+			pf := validACC100PhysicalFunction()
+			pf.BBDevConfig = BBDevConfig{N3000: &N3000BBDevConfig{
+				Uplink:   UplinkDownlink{Queues: UplinkDownlinkQueues{VF0: 32, VF7: 1}},
+				Downlink: UplinkDownlink{Queues: UplinkDownlinkQueues{VF5: 17, VF6: 16}},
+			}}
+			return pf
+		},
+		fields: []string{"bbDevConfig.n3000.networkType", "bbDevConfig.n3000.uplink.queues", "bbDevConfig.n3000.downlink.queues"},
+	},
+	{
+		name: "acc100 numVfBundles different than vfAmount",
+		pf: func() PhysicalFunctionConfig {
+			pf := validACC100PhysicalFunction()
+			pf.BBDevConfig.ACC100.NumVfBundles = 4
+			return pf
+		},
+		fields: []string{"bbDevConfig.acc100.numVfBundles"},
+	},
+	{
+		name: "acc100 with too many queue groups",
+		pf: func() PhysicalFunctionConfig {
+			pf := validACC100PhysicalFunction()
+			pf.BBDevConfig.ACC100.Uplink5G.NumQueueGroups = 3
+			return pf
+		},
+		fields: []string{"bbDevConfig.acc100"},
+	},
+	{
+		name: "acc100 with incomplete queue group",
+		pf: func() PhysicalFunctionConfig {
+			pf := validACC100PhysicalFunction()
+			pf.BBDevConfig.ACC100.Downlink4G = QueueGroupConfig{}
+			return pf
+		},
+		fields: []string{"bbDevConfig.acc100.downlink4G"},
+	},
+	{
+		name: "acc100 fftLutFrom without source",
+		pf: func() PhysicalFunctionConfig {
+			pf := validACC100PhysicalFunction()
+			pf.BBDevConfig.ACC100.FFTLutFrom = &FFTLutSource{Key: "srs_fft.bin"}
+			return pf
+		},
+		fields: []string{"bbDevConfig.acc100.fftLutFrom"},
+	},
+	{
+		name: "acc100 msi interrupt mode with vfio-pci",
+		pf: func() PhysicalFunctionConfig {
+			pf := validACC100PhysicalFunction()
+			pf.BBDevConfig.ACC100.InterruptMode = InterruptModeMSI
+			return pf
+		},
+	},
+	{
+		name: "acc100 msi interrupt mode with pci-pf-stub",
+		pf: func() PhysicalFunctionConfig {
+			pf := validACC100PhysicalFunction()
+			pf.PFDriver = utils.PCI_PF_STUB_DASH
+			pf.BBDevConfig.ACC100.InterruptMode = InterruptModeMSI
+			return pf
+		},
+		fields: []string{"bbDevConfig.acc100.interruptMode"},
+	},
+	{
+		name: "acc100 polling interrupt mode with pci-pf-stub",
+		pf: func() PhysicalFunctionConfig {
+			pf := validACC100PhysicalFunction()
+			pf.PFDriver = utils.PCI_PF_STUB_DASH
+			pf.BBDevConfig.ACC100.InterruptMode = InterruptModePolling
+			return pf
+		},
+	},
+	{
+		name: "acc200 with interrupt mode",
+		pf: func() PhysicalFunctionConfig {
+			pf := validACC100PhysicalFunction()
+			pf.BBDevConfig.ACC100.InterruptMode = InterruptModePolling
+			pf.BBDevConfig = BBDevConfig{ACC200: &ACC200BBDevConfig{
+				ACC100BBDevConfig: *pf.BBDevConfig.ACC100,
+				QFFT:              QueueGroupConfig{NumQueueGroups: 1, NumAqsPerGroups: 16, AqDepthLog2: 4},
+			}}
+			return pf
+		},
+		fields: []string{"bbDevConfig.acc200.interruptMode"},
+	},
+	{
+		name: "acc200 with too many queue groups",
+		pf: func() PhysicalFunctionConfig {
+			pf := validACC100PhysicalFunction()
+			pf.BBDevConfig = BBDevConfig{ACC200: &ACC200BBDevConfig{
+				ACC100BBDevConfig: *pf.BBDevConfig.ACC100,
+				QFFT:              QueueGroupConfig{NumQueueGroups: 9, NumAqsPerGroups: 16, AqDepthLog2: 4},
+			}}
+			return pf
+		},
+		fields: []string{"bbDevConfig.acc200"},
+	},
+}
+
+func corpusErrorFields(errs field.ErrorList, root *field.Path) []string {
+	fields := []string{}
+	for _, err := range errs {
+		fields = append(fields, err.Field[len(root.String())+1:])
+	}
+	return fields
+}
+
+func TestValidatePhysicalFunctionCorpus(t *testing.T) {
+	root := field.NewPath("spec", "physicalFunction")
+	for _, tc := range physicalFunctionCorpus {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			expected := append([]string{}, tc.fields...)
+			g.Expect(corpusErrorFields(ValidatePhysicalFunction(root, tc.pf(), tc.device), root)).To(ConsistOf(expected))
+		})
+	}
+}
+
+func TestValidateNodePhysicalFunctionCorpus(t *testing.T) {
+	root := field.NewPath("spec", "physicalFunctions").Index(0)
+	for _, tc := range physicalFunctionCorpus {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			pf := tc.pf()
+			ext := PhysicalFunctionConfigExt{
+				PCIAddress:      "0000:af:00.0",
+				PFDriver:        pf.PFDriver,
+				VFDriver:        pf.VFDriver,
+				VFAmount:        pf.VFAmount,
+				BBDevConfig:     pf.BBDevConfig,
+				BBDevConfigFrom: pf.BBDevConfigFrom,
+			}
+			expected := append([]string{}, tc.fields...)
+			g.Expect(corpusErrorFields(ValidateNodePhysicalFunction(root, ext, tc.device), root)).To(ConsistOf(expected))
+		})
+	}
+}
+
+func TestValidatePhysicalFunctionRequiresBBDevConfigOnlyInClusterConfig(t *testing.T) {
+	g := NewWithT(t)
+	pf := validACC100PhysicalFunction()
+	pf.BBDevConfig = BBDevConfig{}
+
+	g.Expect(ValidatePhysicalFunction(field.NewPath("spec", "physicalFunction"), pf, nil)).
+		To(ConsistOf(HaveField("Field", "spec.physicalFunction.bbDevConfig")))
+	g.Expect(ValidateNodePhysicalFunction(field.NewPath("spec", "physicalFunctions").Index(0),
+		PhysicalFunctionConfigExt{PFDriver: pf.PFDriver, VFAmount: pf.VFAmount}, nil)).To(BeEmpty())
+}
+
+func TestValidatePhysicalFunctionReportsSchemaPaths(t *testing.T) {
+	g := NewWithT(t)
+	pf := validACC100PhysicalFunction()
+	pf.BBDevConfig.ACC100.NumVfBundles = 4
+
+	errs := validate(SriovFecClusterConfigSpec{PhysicalFunction: pf})
+
+	g.Expect(errs).To(ConsistOf(HaveField("Field", "spec.physicalFunction.bbDevConfig.acc100.numVfBundles")))
+	g.Expect(errs.ToAggregate()).To(MatchError(ContainSubstring("value should be the same as physicalFunction.vfAmount")))
+}
+
+func TestValidateConfigurationHookRestrictsPathToAllowedPrefix(t *testing.T) {
+	g := NewWithT(t)
+	pf := validACC100PhysicalFunction()
+	spec := SriovFecClusterConfigSpec{
+		PhysicalFunction:  pf,
+		PreConfigureHook:  &ConfigurationHook{Path: "/etc/sriov-fec/hooks/quiesce.sh", FailurePolicy: HookFailurePolicyAbort},
+		PostConfigureHook: &ConfigurationHook{Path: "/etc/sriov-fec/hooks/../../../usr/bin/reboot"},
+	}
+
+	g.Expect(validate(spec)).To(ConsistOf(HaveField("Field", "spec.postConfigureHook.path")))
+
+	t.Setenv(utils.CONFIGURATION_HOOK_PATH_PREFIX, "/opt/du/hooks")
+	spec.PostConfigureHook = &ConfigurationHook{Path: "/opt/du/hooks/resume", Timeout: &metav1.Duration{}}
+	g.Expect(validate(spec)).To(ConsistOf(
+		HaveField("Field", "spec.preConfigureHook.path"),
+		HaveField("Field", "spec.postConfigureHook.timeout"),
+	))
+}
+
+func TestValidateDrainGracePeriodRequiresDeleteEvictionMode(t *testing.T) {
+	g := NewWithT(t)
+	gracePeriod := int64(10)
+	spec := SriovFecClusterConfigSpec{PhysicalFunction: validACC100PhysicalFunction(), DrainGracePeriodSeconds: &gracePeriod}
+
+	g.Expect(validate(spec)).To(ConsistOf(HaveField("Type", field.ErrorTypeForbidden)))
+
+	spec.DrainEvictionMode = DrainEvictionModeDelete
+	g.Expect(validate(spec)).To(BeEmpty())
+
+	gracePeriod = -1
+	g.Expect(validate(spec)).To(ConsistOf(HaveField("Field", "spec.drainGracePeriodSeconds")))
+}
+
+func TestValidateMaxConfigurationRetries(t *testing.T) {
+	g := NewWithT(t)
+	maxRetries := 0
+	spec := SriovFecClusterConfigSpec{PhysicalFunction: validACC100PhysicalFunction(), MaxConfigurationRetries: &maxRetries}
+	g.Expect(validate(spec)).To(BeEmpty())
+
+	maxRetries = -1
+	g.Expect(validate(spec)).To(ConsistOf(HaveField("Field", "spec.maxConfigurationRetries")))
+}
+
+func TestValidateAutoRebootOnPersistentFailure(t *testing.T) {
+	g := NewWithT(t)
+	policy := &AutoRebootPolicy{Enabled: true, Threshold: 3, Cooldown: &metav1.Duration{Duration: time.Hour}}
+	spec := SriovFecClusterConfigSpec{PhysicalFunction: validACC100PhysicalFunction(), AutoRebootOnPersistentFailure: policy}
+	g.Expect(validate(spec)).To(BeEmpty())
+
+	policy.Threshold, policy.Cooldown.Duration = -1, -time.Hour
+	g.Expect(validate(spec)).To(ConsistOf(
+		HaveField("Field", "spec.autoRebootOnPersistentFailure.threshold"),
+		HaveField("Field", "spec.autoRebootOnPersistentFailure.cooldown"),
+	))
+}
+
+func TestValidateHousekeepingCpuSet(t *testing.T) {
+	g := NewWithT(t)
+	spec := SriovFecClusterConfigSpec{PhysicalFunction: validACC100PhysicalFunction(), HousekeepingCpuSet: "0-1,32-33"}
+	g.Expect(validate(spec)).To(BeEmpty())
+
+	spec.HousekeepingCpuSet = "1-0"
+	g.Expect(validate(spec)).To(ConsistOf(HaveField("Field", "spec.housekeepingCpuSet")))
+}
+
+func TestAcceleratorSelectorPCIAddressIsNormalizedAndValidated(t *testing.T) {
+	for input, expected := range map[string]string{
+		"0000:af:00.0":   "0000:af:00.0",
+		"AF:00.0":        "0000:af:00.0",
+		" 0000:AF:0.0\n": "0000:af:00.0",
+		"3b:1.7":         "0000:3b:01.7",
+		"10000:A:F.1":    "10000:0a:0f.1",
+	} {
+		t.Run(input, func(t *testing.T) {
+			g := NewWithT(t)
+			cc := &SriovFecClusterConfig{Spec: SriovFecClusterConfigSpec{
+				PhysicalFunction:    validACC100PhysicalFunction(),
+				AcceleratorSelector: AcceleratorSelector{PCIAddress: input},
+			}}
+			g.Expect(validate(cc.Spec)).To(HaveLen(map[bool]int{true: 0, false: 1}[input == expected]))
+
+			cc.Default()
+			g.Expect(cc.Spec.AcceleratorSelector.PCIAddress).To(Equal(expected))
+			g.Expect(validate(cc.Spec)).To(BeEmpty())
+		})
+	}
+
+	for _, input := range []string{"0000:af:00", "0000:af:00.8", "0000:af:20.0", "0000:xx:00.0", "af00.0", "0000:af:00.0:0"} {
+		t.Run(input, func(t *testing.T) {
+			g := NewWithT(t)
+			cc := &SriovFecClusterConfig{Spec: SriovFecClusterConfigSpec{
+				PhysicalFunction:    validACC100PhysicalFunction(),
+				AcceleratorSelector: AcceleratorSelector{PCIAddress: input},
+			}}
+			cc.Default()
+			g.Expect(cc.Spec.AcceleratorSelector.PCIAddress).To(Equal(input), "invalid address is left to the validation")
+			g.Expect(validate(cc.Spec)).To(ConsistOf(HaveField("Field", "spec.acceleratorSelector.pciAddress")))
+		})
+	}
+}
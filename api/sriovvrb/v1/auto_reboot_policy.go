@@ -0,0 +1,53 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package v1
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// DefaultAutoRebootThreshold is number of consecutive pf-bb-config failures triggering the reboot, when
+	// AutoRebootPolicy does not set it
+	DefaultAutoRebootThreshold = 3
+	// DefaultAutoRebootCooldown is minimum time between reboots, when AutoRebootPolicy does not set it
+	DefaultAutoRebootCooldown = 24 * time.Hour
+)
+
+// EffectiveThreshold returns number of consecutive pf-bb-config failures of the same PF which triggers the reboot
+func (in *AutoRebootPolicy) EffectiveThreshold() int {
+	if in.Threshold <= 0 {
+		return DefaultAutoRebootThreshold
+	}
+	return in.Threshold
+}
+
+// EffectiveCooldown returns minimum time between reboots triggered by the policy
+func (in *AutoRebootPolicy) EffectiveCooldown() time.Duration {
+	if in.Cooldown == nil {
+		return DefaultAutoRebootCooldown
+	}
+	return in.Cooldown.Duration
+}
+
+// MergeAutoRebootPolicies returns policy applied to the node by configs with policies a and b: it is enabled when
+// either of them is, with the highest threshold and the longest cooldown of the enabled ones
+func MergeAutoRebootPolicies(a, b *AutoRebootPolicy) *AutoRebootPolicy {
+	if b == nil || !b.Enabled {
+		return a
+	}
+	if a == nil || !a.Enabled {
+		return b.DeepCopy()
+	}
+	merged := a.DeepCopy()
+	if b.EffectiveThreshold() > merged.EffectiveThreshold() {
+		merged.Threshold = b.EffectiveThreshold()
+	}
+	if b.EffectiveCooldown() > merged.EffectiveCooldown() {
+		merged.Cooldown = &metav1.Duration{Duration: b.EffectiveCooldown()}
+	}
+	return merged
+}
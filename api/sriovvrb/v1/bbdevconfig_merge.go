@@ -0,0 +1,66 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package v1
+
+// EffectiveBBDevConfig returns physicalFunction.bbDevConfig merged with defaults.bbDevConfig. Defaults are not applied
+// when config is read from bbDevConfigFrom
+func (in *SriovVrbClusterConfigSpec) EffectiveBBDevConfig() BBDevConfig {
+	if in.Defaults == nil || in.Defaults.BBDevConfig == nil || in.PhysicalFunction.BBDevConfigFrom != nil {
+		return *in.PhysicalFunction.BBDevConfig.DeepCopy()
+	}
+	return MergeBBDevConfig(*in.Defaults.BBDevConfig, in.PhysicalFunction.BBDevConfig)
+}
+
+// MergeBBDevConfig merges entry into defaults; values specified in entry win.
+// When entry does not contain config for any accelerator, defaults are used as they are. Otherwise only the accelerator
+// configured by entry is merged with its defaults. Queue groups are merged as a whole, because zero is a valid value
+// of numQueueGroups and cannot be told apart from unset one.
+func MergeBBDevConfig(defaults, entry BBDevConfig) BBDevConfig {
+	if entry.VRB1 == nil && entry.VRB2 == nil {
+		return *defaults.DeepCopy()
+	}
+
+	merged := *entry.DeepCopy()
+	if merged.VRB1 != nil && defaults.VRB1 != nil {
+		mergeACC100BBDevConfig(&merged.VRB1.ACC100BBDevConfig, &defaults.VRB1.ACC100BBDevConfig)
+		mergeQueueGroupConfig(&merged.VRB1.QFFT, defaults.VRB1.QFFT)
+		mergeFFTLutParam(&merged.VRB1.FFTLut, defaults.VRB1.FFTLut)
+	}
+	if merged.VRB2 != nil && defaults.VRB2 != nil {
+		mergeACC100BBDevConfig(&merged.VRB2.ACC100BBDevConfig, &defaults.VRB2.ACC100BBDevConfig)
+		mergeQueueGroupConfig(&merged.VRB2.QFFT, defaults.VRB2.QFFT)
+		mergeQueueGroupConfig(&merged.VRB2.QMLD, defaults.VRB2.QMLD)
+		mergeFFTLutParam(&merged.VRB2.FFTLut, defaults.VRB2.FFTLut)
+	}
+	return merged
+}
+
+func mergeACC100BBDevConfig(dst, defaults *ACC100BBDevConfig) {
+	// pfMode can only be false, so there is nothing to override with
+	dst.PFMode = dst.PFMode || defaults.PFMode
+	if dst.NumVfBundles == 0 {
+		dst.NumVfBundles = defaults.NumVfBundles
+	}
+	if dst.MaxQueueSize == 0 {
+		dst.MaxQueueSize = defaults.MaxQueueSize
+	}
+	mergeQueueGroupConfig(&dst.Uplink4G, defaults.Uplink4G)
+	mergeQueueGroupConfig(&dst.Downlink4G, defaults.Downlink4G)
+	mergeQueueGroupConfig(&dst.Uplink5G, defaults.Uplink5G)
+	mergeQueueGroupConfig(&dst.Downlink5G, defaults.Downlink5G)
+}
+
+func mergeFFTLutParam(dst *FFTLutParam, defaults FFTLutParam) {
+	if *dst == (FFTLutParam{}) {
+		*dst = defaults
+	}
+}
+
+// mergeQueueGroupConfig takes group from defaults when group is not specified at all; specified group always has
+// numAqsPerGroups and aqDepthLog2 set
+func mergeQueueGroupConfig(dst *QueueGroupConfig, defaults QueueGroupConfig) {
+	if *dst == (QueueGroupConfig{}) {
+		*dst = defaults
+	}
+}
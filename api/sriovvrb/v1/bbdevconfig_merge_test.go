@@ -0,0 +1,107 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package v1
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func defaultVRB2BBDevConfig() VRB2BBDevConfig {
+	return VRB2BBDevConfig{
+		ACC100BBDevConfig: ACC100BBDevConfig{
+			NumVfBundles: 16,
+			MaxQueueSize: 1024,
+			Uplink4G:     QueueGroupConfig{NumQueueGroups: 0, NumAqsPerGroups: 16, AqDepthLog2: 4},
+			Downlink4G:   QueueGroupConfig{NumQueueGroups: 0, NumAqsPerGroups: 16, AqDepthLog2: 4},
+			Uplink5G:     QueueGroupConfig{NumQueueGroups: 4, NumAqsPerGroups: 16, AqDepthLog2: 4},
+			Downlink5G:   QueueGroupConfig{NumQueueGroups: 4, NumAqsPerGroups: 16, AqDepthLog2: 4},
+		},
+		QFFT: QueueGroupConfig{NumQueueGroups: 4, NumAqsPerGroups: 16, AqDepthLog2: 4},
+		QMLD: QueueGroupConfig{NumQueueGroups: 4, NumAqsPerGroups: 16, AqDepthLog2: 4},
+	}
+}
+
+func TestMergeBBDevConfigTakesDefaultsForEmptyEntry(t *testing.T) {
+	g := NewWithT(t)
+	vrb2 := defaultVRB2BBDevConfig()
+	defaults := BBDevConfig{VRB2: &vrb2}
+
+	merged := MergeBBDevConfig(defaults, BBDevConfig{})
+
+	g.Expect(merged).To(Equal(defaults))
+	g.Expect(merged.VRB2).ToNot(BeIdenticalTo(defaults.VRB2))
+}
+
+func TestMergeBBDevConfigEntryWins(t *testing.T) {
+	g := NewWithT(t)
+	vrb2 := defaultVRB2BBDevConfig()
+	defaults := BBDevConfig{VRB2: &vrb2}
+	entry := BBDevConfig{VRB2: &VRB2BBDevConfig{
+		ACC100BBDevConfig: ACC100BBDevConfig{NumVfBundles: 2},
+		// explicitly specified group with zero queue groups has to be kept
+		QMLD:   QueueGroupConfig{NumQueueGroups: 0, NumAqsPerGroups: 1, AqDepthLog2: 1},
+		FFTLut: FFTLutParam{FftUrl: "http://example.com/fft.tar.gz"},
+	}}
+
+	merged := MergeBBDevConfig(defaults, entry)
+
+	expected := defaultVRB2BBDevConfig()
+	expected.NumVfBundles = 2
+	expected.QMLD = QueueGroupConfig{NumQueueGroups: 0, NumAqsPerGroups: 1, AqDepthLog2: 1}
+	expected.FFTLut = FFTLutParam{FftUrl: "http://example.com/fft.tar.gz"}
+	g.Expect(merged).To(Equal(BBDevConfig{VRB2: &expected}))
+	g.Expect(defaults.VRB2).To(Equal(&vrb2), "defaults should not be modified")
+}
+
+func TestMergeBBDevConfigMergesOnlyConfiguredAccelerator(t *testing.T) {
+	g := NewWithT(t)
+	vrb2 := defaultVRB2BBDevConfig()
+	defaults := BBDevConfig{
+		VRB1: &VRB1BBDevConfig{ACC100BBDevConfig: ACC100BBDevConfig{NumVfBundles: 16, MaxQueueSize: 1024}},
+		VRB2: &vrb2,
+	}
+	entry := BBDevConfig{VRB1: &VRB1BBDevConfig{ACC100BBDevConfig: ACC100BBDevConfig{NumVfBundles: 4}}}
+
+	merged := MergeBBDevConfig(defaults, entry)
+
+	g.Expect(merged.VRB2).To(BeNil())
+	g.Expect(merged.VRB1).To(Equal(&VRB1BBDevConfig{ACC100BBDevConfig: ACC100BBDevConfig{NumVfBundles: 4, MaxQueueSize: 1024}}))
+}
+
+func TestValidateMergedBBDevConfig(t *testing.T) {
+	g := NewWithT(t)
+	vrb2 := defaultVRB2BBDevConfig()
+	spec := SriovVrbClusterConfigSpec{
+		PhysicalFunction: PhysicalFunctionConfig{
+			VFAmount:    4,
+			BBDevConfig: BBDevConfig{VRB2: &VRB2BBDevConfig{ACC100BBDevConfig: ACC100BBDevConfig{NumVfBundles: 4}}},
+		},
+		Defaults: &ClusterConfigDefaults{BBDevConfig: &BBDevConfig{VRB2: &vrb2}},
+	}
+	g.Expect(validate(spec)).To(BeEmpty())
+
+	spec.Defaults = nil
+	g.Expect(validate(spec)).To(HaveLen(6))
+}
+
+func TestValidateBBDevConfigFrom(t *testing.T) {
+	g := NewWithT(t)
+	vrb2 := defaultVRB2BBDevConfig()
+	spec := SriovVrbClusterConfigSpec{
+		PhysicalFunction: PhysicalFunctionConfig{
+			VFAmount: 4,
+			BBDevConfigFrom: &BBDevConfigFromSource{
+				ConfigMapRef: ConfigMapKeyReference{Name: "acc-config", Key: "vrb2.cfg"},
+			},
+		},
+		// defaults are not applied to config read from ConfigMap
+		Defaults: &ClusterConfigDefaults{BBDevConfig: &BBDevConfig{VRB2: &vrb2}},
+	}
+	g.Expect(validate(spec)).To(BeEmpty())
+
+	spec.PhysicalFunction.BBDevConfig = BBDevConfig{VRB2: &vrb2}
+	g.Expect(validate(spec)).To(ContainElement(HaveField("Field", "spec.physicalFunction.bbDevConfigFrom")))
+}
@@ -4,12 +4,38 @@
 package v1
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"reflect"
 
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
 )
 
+// ConfigurationHaltedAnnotation is set on SriovVrbNodeConfig by cluster controller when any SriovVrbClusterConfig
+// requests cluster-wide emergency stop (spec.disabled)
+const ConfigurationHaltedAnnotation = "sriovvrb.intel.com/configuration-halted"
+
+// WrittenByVersionAnnotation is set on SriovVrbNodeConfig by cluster controller to version of the operator which wrote it
+// (utils.OperatorVersion); daemons of older version refuse to act on its spec, which they could misinterpret
+const WrittenByVersionAnnotation = "sriovvrb.intel.com/written-by-version"
+
+// IgnoreWriterVersionAnnotation set to "true" on SriovVrbNodeConfig makes daemons act on it even when it was written by
+// newer version of the operator, see WrittenByVersionAnnotation
+const IgnoreWriterVersionAnnotation = "sriovvrb.intel.com/ignore-writer-version"
+
+// ForceReconfigureAnnotation changed on SriovVrbNodeConfig makes the daemon retry configuration it gave up on, see
+// maxConfigurationRetries; any new value does
+const ForceReconfigureAnnotation = "sriovvrb.intel.com/force-reconfigure"
+
+// AdoptedByNodeAnnotation is set on SriovVrbNodeConfig by daemon to name of the node, when the daemon adopted node config
+// named differently than the node (matching it by hostname only); cluster controller writes spec of the node into it
+const AdoptedByNodeAnnotation = "sriovvrb.intel.com/adopted-by-node"
+
 type ByPriority []SriovVrbClusterConfig
 
 func (a ByPriority) Len() int {
@@ -37,7 +63,7 @@ func (s AcceleratorSelector) isVendorMatching(a SriovAccelerator) bool {
 }
 
 func (s AcceleratorSelector) isPciAddressMatching(a SriovAccelerator) bool {
-	return s.PCIAddress == "" || s.PCIAddress == a.PCIAddress
+	return s.PCIAddress == "" || utils.SamePCIAddress(s.PCIAddress, a.PCIAddress)
 }
 
 func (s AcceleratorSelector) isPFDriverMatching(a SriovAccelerator) bool {
@@ -59,3 +85,89 @@ func (in *SriovVrbNodeConfig) FindCondition(conditionType string) *metav1.Condit
 func isNil(v interface{}) bool {
 	return v == nil || (reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil())
 }
+
+// IsConfigurationHalted returns true when cluster-wide emergency stop has been propagated into node config
+func (in *SriovVrbNodeConfig) IsConfigurationHalted() bool {
+	return in.GetAnnotations()[ConfigurationHaltedAnnotation] == "true"
+}
+
+// IsConfigurationHalted returns true if any of given cluster configs requests cluster-wide emergency stop
+func IsConfigurationHalted(configs []SriovVrbClusterConfig) bool {
+	for _, cc := range configs {
+		if cc.Spec.Disabled {
+			return true
+		}
+	}
+	return false
+}
+
+// DryRunAnnotation set to "true" on SriovVrbClusterConfig makes cluster controller only preview specs of
+// SriovVrbNodeConfigs the config would result in, in its status, instead of applying it to the nodes
+const DryRunAnnotation = "sriovvrb.intel.com/dry-run"
+
+// IsDryRun returns true when the config is only previewed, see DryRunAnnotation
+func (in *SriovVrbClusterConfig) IsDryRun() bool {
+	return in.GetAnnotations()[DryRunAnnotation] == "true"
+}
+
+// LastAppliedSpecAnnotation is set on SriovVrbClusterConfig by cluster controller to JSON of the spec it applied to the nodes
+// last time, so that the spec stays in effect while the config is previewed with DryRunAnnotation
+const LastAppliedSpecAnnotation = "sriovvrb.intel.com/last-applied-spec"
+
+// LastApplied returns the config with spec it was applied with last time, see LastAppliedSpecAnnotation; false when
+// the config was not applied yet
+func (in *SriovVrbClusterConfig) LastApplied() (*SriovVrbClusterConfig, bool) {
+	spec, ok := in.GetAnnotations()[LastAppliedSpecAnnotation]
+	if !ok {
+		return nil, false
+	}
+	lastApplied := in.DeepCopy()
+	lastApplied.Spec = SriovVrbClusterConfigSpec{}
+	if err := json.Unmarshal([]byte(spec), &lastApplied.Spec); err != nil {
+		return nil, false
+	}
+	return lastApplied, true
+}
+
+// AdoptExistingNodeConfigsAnnotation set to "true" on SriovVrbClusterConfig makes cluster controller take over SriovVrbNodeConfigs
+// written by hand instead of overwriting them: the ones with spec equivalent to the stamped one are only labeled with
+// ManagedByLabel, so that they are not reconfigured; the ones with different spec are left untouched and differences are
+// reported in node decisions of the config
+const AdoptExistingNodeConfigsAnnotation = "sriovvrb.intel.com/adopt-existing-nodeconfigs"
+
+// AdoptsExistingNodeConfigs returns true when the config takes over hand-written node configs, see
+// AdoptExistingNodeConfigsAnnotation
+func (in *SriovVrbClusterConfig) AdoptsExistingNodeConfigs() bool {
+	return in.GetAnnotations()[AdoptExistingNodeConfigsAnnotation] == "true"
+}
+
+// ManagedByLabel is set on SriovVrbNodeConfig by cluster controller to ManagedByClusterController whenever it writes it
+const ManagedByLabel = "sriovvrb.intel.com/managed-by"
+
+// ManagedByClusterController is value of ManagedByLabel of node configs stamped by cluster controller
+const ManagedByClusterController = "cluster-controller"
+
+// IsManaged returns true when the node config was written by cluster controller; node configs written by older versions
+// of the controller are not labeled, but carry WrittenByVersionAnnotation
+func (in *SriovVrbNodeConfig) IsManaged() bool {
+	if in.GetLabels()[ManagedByLabel] == ManagedByClusterController {
+		return true
+	}
+	_, written := in.GetAnnotations()[WrittenByVersionAnnotation]
+	return written
+}
+
+// GetPredictedVFs fetches PCI addresses of VFs of the PF, published in SriovVrbNodeConfig of the node as soon as the daemon
+// enables the VFs; Verified field tells whether the VFs were probed at these addresses
+func GetPredictedVFs(ctx context.Context, c client.Reader, namespace, nodeName, pfPCIAddress string) (PredictedVFs, error) {
+	nc := &SriovVrbNodeConfig{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: nodeName}, nc); err != nil {
+		return PredictedVFs{}, err
+	}
+	for _, predicted := range nc.Status.PredictedVFs {
+		if utils.SamePCIAddress(predicted.PCIAddress, pfPCIAddress) {
+			return predicted, nil
+		}
+	}
+	return PredictedVFs{}, fmt.Errorf("VFs of PF %s are not published in SriovVrbNodeConfig %s/%s", pfPCIAddress, namespace, nodeName)
+}
@@ -0,0 +1,199 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+// discovery config of the accelerators, shared with the daemon and the labeler
+const (
+	discoveryConfigMapName = "supported-accelerators"
+	discoveryConfigKey     = "accelerators_vrb.json"
+)
+
+// StrictValidationAnnotation set to "true" on SriovVrbClusterConfig turns inventory validation warning into rejection
+const StrictValidationAnnotation = "sriovvrb.intel.com/strict-validation"
+
+// inventoryValidatingHandler extends syntax validation of SriovVrbClusterConfig with the check of accelerators reported
+// by SriovVrbNodeConfigs. Mismatch is returned as a warning, as hardware may appear later, unless strict validation is
+// requested with StrictValidationAnnotation.
+type inventoryValidatingHandler struct {
+	syntax  admission.Handler
+	reader  client.Reader
+	decoder *admission.Decoder
+}
+
+var _ admission.DecoderInjector = &inventoryValidatingHandler{}
+
+func (h *inventoryValidatingHandler) InjectDecoder(d *admission.Decoder) error {
+	h.decoder = d
+	_, err := admission.InjectDecoderInto(d, h.syntax)
+	return err
+}
+
+func (h *inventoryValidatingHandler) Handle(ctx context.Context, req admission.Request) admission.Response {
+	response := h.syntax.Handle(ctx, req)
+	if !response.Allowed || (req.Operation != admissionv1.Create && req.Operation != admissionv1.Update) {
+		return response
+	}
+
+	cc := &SriovVrbClusterConfig{}
+	if err := h.decoder.DecodeRaw(req.Object, cc); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	if errs, err := vfDriverViolations(ctx, h.reader, cc); err != nil {
+		vrbclusterconfiglog.WithError(err).WithField("name", cc.Name).Error("failed to validate vfDriver against discovery config")
+	} else if len(errs) != 0 {
+		message := errs.ToAggregate().Error()
+		response = admission.Denied(message)
+		response.Result.Message = message
+		return response
+	}
+
+	warning, err := inventoryMismatch(ctx, h.reader, cc)
+	if err != nil {
+		vrbclusterconfiglog.WithError(err).WithField("name", cc.Name).Error("failed to validate against node inventories")
+		return response
+	}
+	if warning == "" {
+		return response
+	}
+	if cc.GetAnnotations()[StrictValidationAnnotation] == "true" {
+		response = admission.Denied(warning)
+		// apiserver shows message, not reason of the denial
+		response.Result.Message = warning
+		return response
+	}
+	return response.WithWarnings(warning)
+}
+
+// inventoryMismatch returns warning when SriovVrbClusterConfig selects accelerators with explicit PCI address or
+// device ID, but none of the nodes matching its nodeSelector reports such accelerator
+func inventoryMismatch(ctx context.Context, reader client.Reader, cc *SriovVrbClusterConfig) (string, error) {
+	selector := cc.Spec.AcceleratorSelector
+	if selector.PCIAddress == "" && selector.DeviceID == "" {
+		return "", nil
+	}
+
+	nodes := &corev1.NodeList{}
+	if err := reader.List(ctx, nodes, client.MatchingLabelsSelector{Selector: labels.SelectorFromSet(cc.Spec.NodeSelector)}); err != nil {
+		return "", err
+	}
+	nodeConfigs := &SriovVrbNodeConfigList{}
+	if err := reader.List(ctx, nodeConfigs, client.InNamespace(cc.Namespace)); err != nil {
+		return "", err
+	}
+
+	selected := map[string]bool{}
+	for _, node := range nodes.Items {
+		selected[node.Name] = true
+	}
+
+	found := map[string]bool{}
+	for _, nc := range nodeConfigs.Items {
+		if !selected[nc.Name] {
+			continue
+		}
+		for _, acc := range nc.Status.Inventory.SriovAccelerators {
+			if selector.Matches(acc) {
+				return "", nil
+			}
+			found[fmt.Sprintf("%s(%s) on %s", acc.PCIAddress, acc.DeviceID, nc.Name)] = true
+		}
+	}
+
+	var available []string
+	for acc := range found {
+		available = append(available, acc)
+	}
+	sort.Strings(available)
+	if len(available) == 0 {
+		available = []string{"none"}
+	}
+	return fmt.Sprintf("none of the nodes selected by nodeSelector reports accelerator matching acceleratorSelector (%s) - config is not applied until such accelerator appears; accelerators reported: %s",
+		describeAcceleratorSelector(selector), strings.Join(available, ", ")), nil
+}
+
+// vfDriverViolations validates vfDriver of SriovVrbClusterConfig against drivers the discovery config allows for VFs of
+// accelerators it selects, on kernels of nodes reporting them. Validation is skipped when the discovery config is not
+// found; unlike inventory mismatch, violation is always an error, as configuration would fail on such node.
+func vfDriverViolations(ctx context.Context, reader client.Reader, cc *SriovVrbClusterConfig) (field.ErrorList, error) {
+	cm := &corev1.ConfigMap{}
+	if err := reader.Get(ctx, client.ObjectKey{Name: discoveryConfigMapName, Namespace: cc.Namespace}, cm); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	data, ok := cm.Data[discoveryConfigKey]
+	if !ok {
+		return nil, nil
+	}
+	var discoveryConfig utils.AcceleratorDiscoveryConfig
+	if err := json.Unmarshal([]byte(data), &discoveryConfig); err != nil {
+		return nil, fmt.Errorf("failed to parse %s of %s ConfigMap - %v", discoveryConfigKey, discoveryConfigMapName, err)
+	}
+	if len(discoveryConfig.VFDrivers) == 0 {
+		return nil, nil
+	}
+
+	nodes := &corev1.NodeList{}
+	if err := reader.List(ctx, nodes, client.MatchingLabelsSelector{Selector: labels.SelectorFromSet(cc.Spec.NodeSelector)}); err != nil {
+		return nil, err
+	}
+	kernels := map[string]string{}
+	for _, node := range nodes.Items {
+		kernels[node.Name] = node.Status.NodeInfo.KernelVersion
+	}
+	nodeConfigs := &SriovVrbNodeConfigList{}
+	if err := reader.List(ctx, nodeConfigs, client.InNamespace(cc.Namespace)); err != nil {
+		return nil, err
+	}
+
+	var errs field.ErrorList
+	reported := map[string]bool{}
+	for _, nc := range nodeConfigs.Items {
+		kernel, selected := kernels[nc.Name]
+		if !selected {
+			continue
+		}
+		for _, acc := range nc.Status.Inventory.SriovAccelerators {
+			if !cc.Spec.AcceleratorSelector.Matches(acc) {
+				continue
+			}
+			for _, err := range ValidateVFDriver(field.NewPath("spec", "physicalFunction"), cc.Spec.PhysicalFunction.VFDriver,
+				acc.DeviceID, kernel, discoveryConfig.VFDrivers) {
+				if !reported[err.Error()] {
+					reported[err.Error()] = true
+					errs = append(errs, err)
+				}
+			}
+		}
+	}
+	return errs, nil
+}
+
+func describeAcceleratorSelector(selector AcceleratorSelector) string {
+	var fields []string
+	if selector.PCIAddress != "" {
+		fields = append(fields, "pciAddress: "+selector.PCIAddress)
+	}
+	if selector.DeviceID != "" {
+		fields = append(fields, "deviceID: "+selector.DeviceID)
+	}
+	return strings.Join(fields, ", ")
+}
@@ -0,0 +1,86 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package v1
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestInventoryMismatchIsReportedForNodesWithoutMatchingAccelerator(t *testing.T) {
+	g := NewWithT(t)
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	g.Expect(AddToScheme(scheme)).To(Succeed())
+
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-0", Labels: map[string]string{"vrb": "true"}}},
+		&SriovVrbNodeConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker-0", Namespace: "default"},
+			Status: SriovVrbNodeConfigStatus{Inventory: NodeInventory{SriovAccelerators: []SriovAccelerator{
+				{PCIAddress: "0000:f7:00.0", VendorID: "8086", DeviceID: "57c0"},
+			}}},
+		},
+	).Build()
+
+	cc := &SriovVrbClusterConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "cc", Namespace: "default"},
+		Spec: SriovVrbClusterConfigSpec{
+			NodeSelector:        map[string]string{"vrb": "true"},
+			AcceleratorSelector: AcceleratorSelector{DeviceID: "57c0"},
+		},
+	}
+	g.Expect(inventoryMismatch(context.TODO(), reader, cc)).To(BeEmpty())
+
+	cc.Spec.AcceleratorSelector = AcceleratorSelector{DeviceID: "57c2", PCIAddress: "0000:f7:00.0"}
+	warning, err := inventoryMismatch(context.TODO(), reader, cc)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(warning).To(ContainSubstring("(pciAddress: 0000:f7:00.0, deviceID: 57c2)"))
+	g.Expect(warning).To(HaveSuffix("accelerators reported: 0000:f7:00.0(57c0) on worker-0"))
+}
+
+func TestVFDriverViolationsAreReportedForAcceleratorsOfSelectedNodes(t *testing.T) {
+	g := NewWithT(t)
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	g.Expect(AddToScheme(scheme)).To(Succeed())
+
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker-0", Labels: map[string]string{"vrb": "true"}},
+			Status:     corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{KernelVersion: "4.18.0-372.9.1.el8.x86_64"}},
+		},
+		&SriovVrbNodeConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker-0", Namespace: "default"},
+			Status: SriovVrbNodeConfigStatus{Inventory: NodeInventory{SriovAccelerators: []SriovAccelerator{
+				{PCIAddress: "0000:f7:00.0", VendorID: "8086", DeviceID: "57c0"},
+			}}},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "supported-accelerators", Namespace: "default"},
+			Data:       map[string]string{"accelerators_vrb.json": `{"VFDrivers": {"57c0": [{"Driver": "vfio-pci", "MinKernelVersion": "5.14"}]}}`},
+		},
+	).Build()
+
+	cc := &SriovVrbClusterConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "cc", Namespace: "default"},
+		Spec: SriovVrbClusterConfigSpec{
+			NodeSelector:     map[string]string{"vrb": "true"},
+			PhysicalFunction: PhysicalFunctionConfig{VFDriver: "vfio-pci"},
+		},
+	}
+	errs, err := vfDriverViolations(context.TODO(), reader, cc)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(errs.ToAggregate()).To(MatchError(`spec.physicalFunction.vfDriver: Invalid value: "vfio-pci": vfDriver vfio-pci not supported for device 57c0 on kernel < 5.14`))
+
+	cc.Spec.AcceleratorSelector = AcceleratorSelector{DeviceID: "57c2"}
+	g.Expect(vfDriverViolations(context.TODO(), reader, cc)).To(BeEmpty())
+}
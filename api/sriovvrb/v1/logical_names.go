@@ -0,0 +1,89 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// LogicalNamesAnnotation is set on the node by the daemon; it holds JSON object which maps logical names of VFs
+// configured by SriovVrbNodeConfigs of the node to their PCI addresses
+const LogicalNamesAnnotation = "sriovvrb.intel.com/logical-names"
+
+// LogicalName returns logical name of VF vfIndex of PF pfIndex among PFs sharing the prefix, e.g. "fec-acc100-0-vf3"
+func LogicalName(prefix string, pfIndex, vfIndex int) string {
+	return fmt.Sprintf("%s-%d-vf%d", prefix, pfIndex, vfIndex)
+}
+
+// AssignLogicalNames assigns logical names to VFs of PFs with logicalNamePrefix. PFs sharing the prefix are indexed in
+// order of their PCI addresses and VFs of PF in order of theirs, so names depend neither on order of PFs in the spec
+// nor on order VFs were probed in, and they survive reboots. vfs holds PCI addresses of VFs by PCI address of their PF.
+func AssignLogicalNames(pfs []PhysicalFunctionConfigExt, vfs map[string][]string) []PFLogicalNames {
+	byPrefix := map[string][]string{}
+	for _, pf := range pfs {
+		if pf.LogicalNamePrefix != "" {
+			byPrefix[pf.LogicalNamePrefix] = append(byPrefix[pf.LogicalNamePrefix], pf.PCIAddress)
+		}
+	}
+
+	var assigned []PFLogicalNames
+	for prefix, pfAddresses := range byPrefix {
+		sort.Strings(pfAddresses)
+		for pfIndex, pfAddress := range pfAddresses {
+			vfAddresses := append([]string(nil), vfs[pfAddress]...)
+			if len(vfAddresses) == 0 {
+				continue
+			}
+			sort.Strings(vfAddresses)
+			names := PFLogicalNames{PCIAddress: pfAddress, VFs: map[string]string{}}
+			for vfIndex, vfAddress := range vfAddresses {
+				names.VFs[LogicalName(prefix, pfIndex, vfIndex)] = vfAddress
+			}
+			assigned = append(assigned, names)
+		}
+	}
+	sort.Slice(assigned, func(i, j int) bool { return assigned[i].PCIAddress < assigned[j].PCIAddress })
+	return assigned
+}
+
+// ResolveLogicalName returns PCI address of VF with the logical name, as published in status of the node config
+func (in *SriovVrbNodeConfig) ResolveLogicalName(name string) (string, bool) {
+	for _, pf := range in.Status.LogicalNames {
+		if pciAddress, found := pf.VFs[name]; found {
+			return pciAddress, true
+		}
+	}
+	return "", false
+}
+
+// NodeLogicalNames returns PCI addresses of VFs of the node by their logical names, see LogicalNamesAnnotation
+func NodeLogicalNames(node *corev1.Node) (map[string]string, error) {
+	names := map[string]string{}
+	value, found := node.GetAnnotations()[LogicalNamesAnnotation]
+	if !found {
+		return names, nil
+	}
+	if err := json.Unmarshal([]byte(value), &names); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation of node %s - %v", LogicalNamesAnnotation, node.Name, err)
+	}
+	return names, nil
+}
+
+// ResolveNodeLogicalName returns PCI address of VF of the node with the logical name; consumers which identify VFs by
+// PCI address use it to find VF by its stable logical name
+func ResolveNodeLogicalName(node *corev1.Node, name string) (string, error) {
+	names, err := NodeLogicalNames(node)
+	if err != nil {
+		return "", err
+	}
+	pciAddress, found := names[name]
+	if !found {
+		return "", fmt.Errorf("logical name %s is not known on node %s", name, node.Name)
+	}
+	return pciAddress, nil
+}
@@ -11,6 +11,9 @@ type VF struct {
 	PCIAddress string `json:"pciAddress"`
 	Driver     string `json:"driver"`
 	DeviceID   string `json:"deviceID"`
+	// Pod (namespace/name) the VF is allocated to by the device plugin, "unknown" when it could not be determined
+	// +optional
+	UsedBy string `json:"usedBy,omitempty"`
 }
 
 type SriovAccelerator struct {
@@ -20,10 +23,138 @@ type SriovAccelerator struct {
 	PFDriver   string `json:"driver"`
 	MaxVFs     int    `json:"maxVirtualFunctions"`
 	VFs        []VF   `json:"virtualFunctions"`
+	// Extra information about the accelerator provided by inventory enrichers (e.g. numaNode, firmwareVersion)
+	// +optional
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// PredictedVFs lists PCI addresses of PF's VFs computed from SR-IOV First VF Offset and VF Stride of the PF as soon as
+// VFs are enabled, i.e. before the VFs are probed and bound to their driver
+type PredictedVFs struct {
+	// PCI address of the PF
+	PCIAddress string `json:"pciAddress"`
+	// PCI addresses of the VFs in virtfn order
+	VFs []string `json:"vfs,omitempty"`
+	// Indicates that probed VFs were found at predicted addresses
+	Verified bool `json:"verified,omitempty"`
+	// Difference between predicted and probed addresses, empty when they match or VFs were not probed yet
+	Mismatch string `json:"mismatch,omitempty"`
+}
+
+// PFLogicalNames lists logical names of PF's VFs, see PhysicalFunctionConfigExt.LogicalNamePrefix
+type PFLogicalNames struct {
+	// PCI address of the PF
+	PCIAddress string `json:"pciAddress"`
+	// PCI addresses of the VFs by their logical names
+	VFs map[string]string `json:"vfs,omitempty"`
+}
+
+// HookFailurePolicy tells how failure of a configuration hook affects the configuration
+// +kubebuilder:validation:Enum=Abort;Continue
+type HookFailurePolicy string
+
+const (
+	// HookFailurePolicyAbort fails the configuration; accelerators are not configured when preConfigure hook fails
+	HookFailurePolicyAbort HookFailurePolicy = "Abort"
+	// HookFailurePolicyContinue only records failure of the hook in status
+	HookFailurePolicyContinue HookFailurePolicy = "Continue"
+)
+
+// DrainEvictionMode tells how pods are removed from the node during drain
+// +kubebuilder:validation:Enum=evict;delete
+type DrainEvictionMode string
+
+const (
+	// DrainEvictionModeEvict removes pods with the eviction subresource, respecting PodDisruptionBudgets
+	DrainEvictionModeEvict DrainEvictionMode = "evict"
+	// DrainEvictionModeDelete deletes pods directly; it is faster, but PodDisruptionBudgets are not respected
+	DrainEvictionModeDelete DrainEvictionMode = "delete"
+)
+
+// ConfigurationHook is an executable on the host, run by the daemon within the drain of the node
+type ConfigurationHook struct {
+	// Absolute path of the executable on the host; it has to be located under the prefix allowed by the operator
+	// (SRIOV_FEC_CONFIGURATION_HOOK_PATH_PREFIX, /etc/sriov-fec/hooks/ by default)
+	Path string `json:"path"`
+	// Arguments the executable is run with
+	// +optional
+	Args []string `json:"args,omitempty"`
+	// Time after which the hook is killed and considered failed; default 60s
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+	// Handling of hook's failure (non-zero exit code, timeout); default Abort
+	// +optional
+	FailurePolicy HookFailurePolicy `json:"failurePolicy,omitempty"`
+}
+
+// HookResult describes execution of a configuration hook during the last configuration of the node
+type HookResult struct {
+	// Hook which was run, preConfigureHook or postConfigureHook
+	Hook string `json:"hook"`
+	// Path of the executable on the host
+	Path string `json:"path"`
+	// Exit code of the executable, -1 when it did not exit on its own (e.g. it was killed on timeout) or was not run
+	ExitCode int `json:"exitCode"`
+	// Time the hook was running for, e.g. "1.5s"
+	Duration string `json:"duration,omitempty"`
+	// Reason of hook's failure, empty when it succeeded
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+// ConfigurationRetries counts failed configurations of the node config; the count restarts when the generation or
+// the force-reconfigure annotation of the node config changes, and is dropped once configuration succeeds
+type ConfigurationRetries struct {
+	// Generation of the node config the failures are counted for
+	Generation int64 `json:"generation"`
+	// Number of failed configurations of the generation
+	Failures int `json:"failures"`
+	// Value of the force-reconfigure annotation the failures are counted for
+	// +optional
+	ForceReconfigure string `json:"forceReconfigure,omitempty"`
+	// Error of the last failed configuration
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+}
+
+// PFTransition is a transition of the Configured condition of the node config, recorded for each PF of its spec
+type PFTransition struct {
+	// Time of the transition
+	Time metav1.Time `json:"time"`
+	// Reason of the Configured condition after the transition
+	Reason string `json:"reason"`
+	// Generation of the node config the condition was observed for
+	// +optional
+	Generation int64 `json:"generation,omitempty"`
+}
+
+// AutoRebootPolicy makes the daemon reboot the node when pf-bb-config keeps failing on the same PF, as some error
+// states of the device are cleared only by power cycle of the node
+type AutoRebootPolicy struct {
+	// Enables the policy; default false
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+	// Number of consecutive pf-bb-config failures of the same PF, across reconciles, which triggers the reboot; default 3
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Threshold int `json:"threshold,omitempty"`
+	// Minimum time between reboots triggered by the policy; default 24h
+	// +optional
+	Cooldown *metav1.Duration `json:"cooldown,omitempty"`
+}
+
+// UnsupportedDevice is SR-IOV accelerator present on the node which device ID is missing in the discovery config
+type UnsupportedDevice struct {
+	PCIAddress string `json:"pciAddress"`
+	VendorID   string `json:"vendorID"`
+	DeviceID   string `json:"deviceID"`
 }
 
 type NodeInventory struct {
 	SriovAccelerators []SriovAccelerator `json:"sriovAccelerators,omitempty"`
+	// UnsupportedDevices are found with the same scan as SriovAccelerators, they are exposed in
+	// status.unsupportedDevices
+	UnsupportedDevices []UnsupportedDevice `json:"-"`
 }
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -38,20 +169,178 @@ type SriovVrbNodeConfigSpec struct {
 	// +operator-sdk:csv:customresourcedefinitions:type=spec
 	// Skips drain process when true; default false. Should be true if operator is running on SNO
 	DrainSkip bool `json:"drainSkip,omitempty"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// Reconfigures the node with drain skipped even when VFs to be removed or rebound are allocated to running pods;
+	// default false, configuration is refused with DeviceInUse reason then
+	ForceVfRemoval bool `json:"forceVfRemoval,omitempty"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// How pods are removed from the node during drain, evict (default) or delete
+	// +optional
+	DrainEvictionMode DrainEvictionMode `json:"drainEvictionMode,omitempty"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// Grace period of pods deleted in delete drainEvictionMode; terminationGracePeriodSeconds of the pod when not set
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	DrainGracePeriodSeconds *int64 `json:"drainGracePeriodSeconds,omitempty"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// Number of times failed configuration of the same generation is retried before the daemon gives up with
+	// ConfigurationGivenUp reason; unlimited when not set
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxConfigurationRetries *int `json:"maxConfigurationRetries,omitempty"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// CPUs (list format, e.g. "0-1,32-33") pf-bb-config and other child processes of the daemon run on; detected from
+	// isolcpus/nohz_full kernel params when not set
+	// +optional
+	HousekeepingCpuSet string `json:"housekeepingCpuSet,omitempty"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// Refuses configuration when compatibility checks from discovery config fail; default true.
+	// When false, violations are only reported as warnings in the Configured condition message
+	EnforceCompatibilityChecks *bool `json:"enforceCompatibilityChecks,omitempty"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// Time to wait after spec change before node is configured, restarted by each further change; default 0
+	ConfigurationDebounce *metav1.Duration `json:"configurationDebounce,omitempty"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// Executable run on the host within the drain, before accelerators are configured
+	PreConfigureHook *ConfigurationHook `json:"preConfigureHook,omitempty"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// Executable run on the host within the drain, after accelerators are configured and the device plugin restarted
+	PostConfigureHook *ConfigurationHook `json:"postConfigureHook,omitempty"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// Seconds to wait after accelerators are configured and the device plugin restarted, before the node is uncordoned
+	// and configuration is reported as succeeded; default 0
+	// +kubebuilder:validation:Minimum=0
+	PostConfigureSoakSeconds int `json:"postConfigureSoakSeconds,omitempty"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// Adopts configuration found on the node when it already matches the spec, instead of tearing it down; default false
+	AdoptExistingConfig bool `json:"adoptExistingConfig,omitempty"`
+
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	// Reboots the node after repeated pf-bb-config failures of the same PF; disabled when not set
+	// +optional
+	AutoRebootOnPersistentFailure *AutoRebootPolicy `json:"autoRebootOnPersistentFailure,omitempty"`
 }
 
 // SriovVrbNodeConfigStatus defines the observed state of SriovVrbNodeConfig
 type SriovVrbNodeConfigStatus struct {
-	// Provides information about device update status
+	// Provides information about device update status. Conventional Available, Progressing and Degraded conditions are
+	// derived from the Configured condition, which is deprecated and kept for backwards compatibility
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
 	// Provides information about FPGA inventory on the node
 	// +operator-sdk:csv:customresourcedefinitions:type=status
 	Inventory NodeInventory `json:"inventory,omitempty"`
+	// Time when the inventory was last collected by the daemon; the cluster controller does not resolve accelerator
+	// selectors against inventory older than its staleness bound
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	InventoryCollectedAt *metav1.Time `json:"inventoryCollectedAt,omitempty"`
+	// Sequence number of the inventory, incremented by the daemon on every scan
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	InventorySequence int64 `json:"inventorySequence,omitempty"`
+	// Summary of pods evicted during the last drain and their rescheduling, e.g. "7 pods evicted, 7 rescheduled, 0 pending"
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	EvictionSummary string `json:"evictionSummary,omitempty"`
+	// SHA-256 of pf-bb-config files referenced with bbDevConfigFrom and applied to PFs, by PF's PCI address
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	BBDevConfigHashes map[string]string `json:"bbDevConfigHashes,omitempty"`
+	// SHA-256 of cfg files pf-bb-config was last started with, by PF's PCI address
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	AppliedBBDevConfigHashes map[string]string `json:"appliedBBDevConfigHashes,omitempty"`
+	// Duration of the last pf-bb-config startup, by PF's PCI address; startup exceeding threshold of the device family
+	// from the discovery config marks health of the PF as degraded
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	PfBBConfigDurations map[string]metav1.Duration `json:"pfBBConfigDurations,omitempty"`
+	// CPUs (list format) pf-bb-config was last started on, by PF's PCI address; PFs which pf-bb-config was started
+	// without CPU affinity for are not listed
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	PfBBConfigCpuSets map[string]string `json:"pfBBConfigCpuSets,omitempty"`
+	// SHA-256 of the spec which was last applied to the hardware successfully
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	AppliedSpecHash string `json:"appliedSpecHash,omitempty"`
+	// SR-IOV accelerators present on the node, which are not supported as their device IDs are missing in the
+	// discovery config (supported-accelerators ConfigMap)
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	UnsupportedDevices []UnsupportedDevice `json:"unsupportedDevices,omitempty"`
+	// PCI addresses of VFs predicted for configured PFs, published before the device plugin is restarted
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	PredictedVFs []PredictedVFs `json:"predictedVFs,omitempty"`
+	// Results of configuration hooks run during the last configuration
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	HookResults []HookResult `json:"hookResults,omitempty"`
+	// Failures of best-effort configuration steps of the last configuration; they do not fail the configuration
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	Warnings []string `json:"warnings,omitempty"`
+	// PCI addresses of PFs whose pre-existing configuration matched the spec and was adopted by the last configuration,
+	// without being torn down
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	AdoptedPFs []string `json:"adoptedPFs,omitempty"`
+	// Generation of the node config which was in effect when PF was last configured successfully, by PF's PCI address;
+	// PFs are at different generations when configuration failed partially
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	AppliedGenerations map[string]int64 `json:"appliedGenerations,omitempty"`
+	// Generation of the node config which was last configured successfully; observedGeneration of the Configured
+	// condition is the generation being configured while configuration is in progress
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	SucceededGeneration int64 `json:"succeededGeneration,omitempty"`
+	// Failed configurations counted against maxConfigurationRetries; nil when the last configuration succeeded
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	ConfigurationRetries *ConfigurationRetries `json:"configurationRetries,omitempty"`
+	// Last transitions of the Configured condition, by PF's PCI address; up to 5 latest transitions are kept per PF,
+	// oldest first
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	PfTransitions map[string][]PFTransition `json:"pfTransitions,omitempty"`
+	// Consecutive pf-bb-config failures by PF's PCI address, counted across reconciles for autoRebootOnPersistentFailure;
+	// count of the PF is dropped once its pf-bb-config starts successfully
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	PfBBConfigFailures map[string]int `json:"pfBBConfigFailures,omitempty"`
+	// SHA-256 of the accelerators discovery config (supported-accelerators ConfigMap) the daemon uses; nodes reporting
+	// different hashes discover and configure accelerators differently
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	DiscoveryConfigHash string `json:"discoveryConfigHash,omitempty"`
+	// Logical names of VFs of PFs with logicalNamePrefix, by PF; names of VFs of the whole node are published in
+	// sriovvrb.intel.com/logical-names node annotation as well
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	LogicalNames []PFLogicalNames `json:"logicalNames,omitempty"`
+	// Human-readable one-line summary of the node, e.g. "2/2 PFs configured, 32 VFs, vfio-pci, last change 2023-06-01T10:00:00Z";
+	// it is computed whenever the daemon writes the status
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	// +optional
+	Summary string `json:"summary,omitempty"`
+	// Version of the status schema; status written by older versions of the operator is migrated when it is lower
+	// than the current one
+	// +optional
+	SchemaVersion int `json:"schemaVersion,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="Configured",type=string,JSONPath=`.status.conditions[?(@.type=="Configured")].reason`
+// +kubebuilder:printcolumn:name="Summary",type=string,JSONPath=`.status.summary`,priority=1
 // +kubebuilder:storageversion
 // +kubebuilder:resource:shortName=vrbnc
 
@@ -0,0 +1,29 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package v1
+
+import (
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+func (in *SriovVrbNodeConfig) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	mgr.GetWebhookServer().Register("/mutate-sriovvrb-intel-com-v1-sriovvrbnodeconfig", admission.DefaultingWebhookFor(in))
+	return nil
+}
+
+//+kubebuilder:webhook:path=/mutate-sriovvrb-intel-com-v1-sriovvrbnodeconfig,mutating=true,failurePolicy=fail,sideEffects=None,groups=sriovvrb.intel.com,resources=sriovvrbnodeconfigs,verbs=create;update,versions=v1,name=msriovvrbnodeconfig.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Defaulter = &SriovVrbNodeConfig{}
+
+// Default implements webhook.Defaulter; PCI addresses of PFs are brought to canonical form (lowercase, zero-padded,
+// with domain), so that what is stored matches addresses of the inventory exactly
+func (in *SriovVrbNodeConfig) Default() {
+	for i := range in.Spec.PhysicalFunctions {
+		in.Spec.PhysicalFunctions[i].PCIAddress = utils.CanonicalPCIAddress(in.Spec.PhysicalFunctions[i].PCIAddress)
+	}
+}
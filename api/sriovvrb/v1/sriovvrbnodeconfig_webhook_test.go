@@ -0,0 +1,23 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package v1
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestPCIAddressesOfNodeConfigAreNormalized(t *testing.T) {
+	g := NewWithT(t)
+	nc := &SriovVrbNodeConfig{Spec: SriovVrbNodeConfigSpec{PhysicalFunctions: []PhysicalFunctionConfigExt{
+		{PCIAddress: "F7:00.0"}, {PCIAddress: " 0000:1B:0.0"}, {PCIAddress: "0000:f8:00.0"},
+	}}}
+
+	nc.Default()
+
+	g.Expect(nc.Spec.PhysicalFunctions).To(Equal([]PhysicalFunctionConfigExt{
+		{PCIAddress: "0000:f7:00.0"}, {PCIAddress: "0000:1b:00.0"}, {PCIAddress: "0000:f8:00.0"},
+	}))
+}
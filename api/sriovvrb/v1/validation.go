@@ -0,0 +1,183 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package v1
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+// ValidatePhysicalFunction validates config of a single PF located at path (e.g. spec.physicalFunction of cluster
+// config). When device reported by node inventory is given, config is also validated against it. The webhook and
+// the daemon (through ValidateNodePhysicalFunction) share it, so both report the same errors.
+func ValidatePhysicalFunction(path *field.Path, pf PhysicalFunctionConfig, device *SriovAccelerator) field.ErrorList {
+	return validatePhysicalFunction(path, pf, device, true)
+}
+
+// ValidateNodePhysicalFunction validates PF of node config (e.g. spec.physicalFunctions[0]) like
+// ValidatePhysicalFunction does, except that bbDevConfig may be empty - the daemon then only creates VFs
+func ValidateNodePhysicalFunction(path *field.Path, pf PhysicalFunctionConfigExt, device *SriovAccelerator) field.ErrorList {
+	return validatePhysicalFunction(path, PhysicalFunctionConfig{
+		PFDriver:        pf.PFDriver,
+		VFDriver:        pf.VFDriver,
+		VFAmount:        pf.VFAmount,
+		BBDevConfig:     pf.BBDevConfig,
+		BBDevConfigFrom: pf.BBDevConfigFrom,
+	}, device, false)
+}
+
+func validatePhysicalFunction(path *field.Path, pf PhysicalFunctionConfig, device *SriovAccelerator, requireBBDevConfig bool) (errs field.ErrorList) {
+	if device != nil && pf.VFAmount > device.MaxVFs {
+		errs = append(errs, field.Invalid(path.Child("vfAmount"), pf.VFAmount,
+			fmt.Sprintf("value should not be greater than %d supported by %s", device.MaxVFs, device.PCIAddress)))
+	}
+
+	if presenceErrs := validateBBDevConfigPresence(path, pf, requireBBDevConfig); len(presenceErrs) != 0 || pf.BBDevConfigFrom != nil {
+		return append(errs, presenceErrs...)
+	}
+
+	bbDevConfigPath := path.Child("bbDevConfig")
+	if vrb1 := pf.BBDevConfig.VRB1; vrb1 != nil {
+		errs = append(errs, validateVRB1(bbDevConfigPath.Child("vrb1"), vrb1, pf.VFAmount)...)
+	}
+	if vrb2 := pf.BBDevConfig.VRB2; vrb2 != nil {
+		errs = append(errs, validateVRB2(bbDevConfigPath.Child("vrb2"), vrb2, pf.VFAmount)...)
+	}
+	return errs
+}
+
+func validateBBDevConfigPresence(path *field.Path, pf PhysicalFunctionConfig, required bool) field.ErrorList {
+	var configs int
+	for _, config := range []interface{}{pf.BBDevConfig.VRB1, pf.BBDevConfig.VRB2} {
+		if !isNil(config) {
+			configs++
+		}
+	}
+
+	switch {
+	case configs > 1:
+		return field.ErrorList{field.Forbidden(path.Child("bbDevConfig"), "specified bbDevConfig cannot contain multiple configurations")}
+	case pf.BBDevConfigFrom != nil && configs != 0:
+		return field.ErrorList{field.Forbidden(path.Child("bbDevConfigFrom"), "bbDevConfig and bbDevConfigFrom are mutually exclusive")}
+	case required && pf.BBDevConfigFrom == nil && configs == 0:
+		return field.ErrorList{field.Forbidden(path.Child("bbDevConfig"), "bbDevConfig section cannot be empty")}
+	}
+	return nil
+}
+
+type queueGroup struct {
+	name   string
+	config QueueGroupConfig
+}
+
+func acc100QueueGroups(config *ACC100BBDevConfig) []queueGroup {
+	return []queueGroup{
+		{"uplink4G", config.Uplink4G},
+		{"downlink4G", config.Downlink4G},
+		{"uplink5G", config.Uplink5G},
+		{"downlink5G", config.Downlink5G},
+	}
+}
+
+func validateQueueGroups(path *field.Path, groups []queueGroup, maxQueueGroups int) (errs field.ErrorList) {
+	var total int
+	for _, group := range groups {
+		if group.config.NumAqsPerGroups == 0 || group.config.AqDepthLog2 == 0 {
+			errs = append(errs, field.Required(path.Child(group.name), "queue group has to be specified in physicalFunction or defaults"))
+		}
+		total += group.config.NumQueueGroups
+	}
+	if total > maxQueueGroups {
+		errs = append(errs, field.Invalid(path, total, fmt.Sprintf("sum of all numQueueGroups should not be greater than %d", maxQueueGroups)))
+	}
+	return errs
+}
+
+func validateNumVfBundles(path *field.Path, numVfBundles, vfAmount int) field.ErrorList {
+	if numVfBundles != vfAmount {
+		return field.ErrorList{field.Invalid(path.Child("numVfBundles"), numVfBundles, "value should be the same as physicalFunction.vfAmount")}
+	}
+	return nil
+}
+
+func validateVRB1(path *field.Path, config *VRB1BBDevConfig, vfAmount int) (errs field.ErrorList) {
+	if config.NumVfBundles > vrb1maxVfNums {
+		errs = append(errs, field.Invalid(path.Child("numVfBundles"), config.NumVfBundles,
+			fmt.Sprintf("value should not be greater than %d", vrb1maxVfNums)))
+	} else {
+		errs = append(errs, validateNumVfBundles(path, config.NumVfBundles, vfAmount)...)
+	}
+
+	groups := append(acc100QueueGroups(&config.ACC100BBDevConfig), queueGroup{"qfft", config.QFFT})
+	errs = append(errs, validateQueueGroups(path, groups, vrb1maxQueueGroups)...)
+	for _, group := range groups {
+		if group.config.NumAqsPerGroups > vrb1maxQueueGroups {
+			errs = append(errs, field.Invalid(path.Child(group.name, "numAqsPerGroups"), group.config.NumAqsPerGroups,
+				fmt.Sprintf("value should not be greater than %d", vrb1maxQueueGroups)))
+		}
+	}
+	return errs
+}
+
+func validateVRB2(path *field.Path, config *VRB2BBDevConfig, vfAmount int) (errs field.ErrorList) {
+	errs = append(errs, validateNumVfBundles(path, config.NumVfBundles, vfAmount)...)
+	groups := append(acc100QueueGroups(&config.ACC100BBDevConfig), queueGroup{"qfft", config.QFFT}, queueGroup{"qmld", config.QMLD})
+	return append(errs, validateQueueGroups(path, groups, vrb2maxQueueGroups)...)
+}
+
+// validatePCIAddress requires PCI address located at path in canonical form, which the defaulting webhook brings
+// addresses to; the daemon matches addresses of the spec against sysfs
+func validatePCIAddress(path *field.Path, address string) *field.Error {
+	normalized, err := utils.NormalizePCIAddress(address)
+	if err != nil {
+		return field.Invalid(path, address, err.Error())
+	}
+	if normalized != address {
+		return field.Invalid(path, address, "PCI address has to be in canonical form "+normalized)
+	}
+	return nil
+}
+
+// validateDrainGracePeriod validates grace period located at path, which applies only to pods deleted in delete mode
+func validateDrainGracePeriod(path *field.Path, mode DrainEvictionMode, gracePeriod *int64) (errs field.ErrorList) {
+	if gracePeriod == nil {
+		return nil
+	}
+	if *gracePeriod < 0 {
+		errs = append(errs, field.Invalid(path, *gracePeriod, "must be greater than or equal to 0"))
+	}
+	if mode != DrainEvictionModeDelete {
+		errs = append(errs, field.Forbidden(path, "drainGracePeriodSeconds is supported only by delete drainEvictionMode"))
+	}
+	return errs
+}
+
+// ValidateConfigurationHook validates hook located at path (e.g. spec.preConfigureHook); nil hook is valid. Executable
+// of the hook has to be located under the prefix allowed by the operator, so that arbitrary host binaries cannot be run.
+func ValidateConfigurationHook(path *field.Path, hook *ConfigurationHook) (errs field.ErrorList) {
+	if hook == nil {
+		return nil
+	}
+	if prefix := utils.ConfigurationHookPathPrefix(); !utils.IsAllowedHookPath(hook.Path, prefix) {
+		errs = append(errs, field.Invalid(path.Child("path"), hook.Path,
+			fmt.Sprintf("value should be a clean absolute path located under %s", prefix)))
+	}
+	if hook.Timeout != nil && hook.Timeout.Duration <= 0 {
+		errs = append(errs, field.Invalid(path.Child("timeout"), hook.Timeout.Duration.String(), "value should be positive"))
+	}
+	return errs
+}
+
+// ValidateVFDriver validates vfDriver of PF located at path against drivers the discovery config (supported-accelerators
+// ConfigMap) allows for VFs of the device, on given kernel release of the node. Kernel bounds are not validated when
+// kernel is empty. The webhook and the daemon share it, so both report the same errors.
+func ValidateVFDriver(path *field.Path, vfDriver, deviceID, kernel string, allowed map[string][]utils.VFDriverSupport) field.ErrorList {
+	if err := utils.CheckVFDriver(allowed, deviceID, vfDriver, kernel); err != nil {
+		return field.ErrorList{field.Invalid(path.Child("vfDriver"), vfDriver, err.Error())}
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package v1
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+func validVRB1PhysicalFunction() PhysicalFunctionConfig {
+	queueGroup := QueueGroupConfig{NumQueueGroups: 2, NumAqsPerGroups: 16, AqDepthLog2: 4}
+	return PhysicalFunctionConfig{
+		PFDriver: "vfio-pci",
+		VFDriver: "vfio-pci",
+		VFAmount: 2,
+		BBDevConfig: BBDevConfig{VRB1: &VRB1BBDevConfig{
+			ACC100BBDevConfig: ACC100BBDevConfig{
+				NumVfBundles: 2,
+				MaxQueueSize: 1024,
+				Uplink4G:     queueGroup,
+				Downlink4G:   queueGroup,
+				Uplink5G:     queueGroup,
+				Downlink5G:   queueGroup,
+			},
+			QFFT: queueGroup,
+		}},
+	}
+}
+
+// physicalFunctionCorpus is shared by admission (cluster config) and pre-drain (node config) validation; every entry
+// lists fields (relative to PF) of expected errors
+var physicalFunctionCorpus = []struct {
+	name   string
+	pf     func() PhysicalFunctionConfig
+	device *SriovAccelerator
+	fields []string
+}{
+	{
+		name: "valid vrb1",
+		pf:   validVRB1PhysicalFunction,
+	},
+	{
+		name: "valid vrb2",
+		pf: func() PhysicalFunctionConfig {
+			pf := validVRB1PhysicalFunction()
+			pf.BBDevConfig = BBDevConfig{VRB2: &VRB2BBDevConfig{
+				ACC100BBDevConfig: pf.BBDevConfig.VRB1.ACC100BBDevConfig,
+				QFFT:              pf.BBDevConfig.VRB1.QFFT,
+				QMLD:              QueueGroupConfig{NumQueueGroups: 20, NumAqsPerGroups: 16, AqDepthLog2: 4},
+			}}
+			return pf
+		},
+	},
+	{
+		name:   "vfAmount over device limit",
+		pf:     validVRB1PhysicalFunction,
+		device: &SriovAccelerator{PCIAddress: "0000:f7:00.0", MaxVFs: 1},
+		fields: []string{"vfAmount"},
+	},
+	{
+		name: "multiple configurations",
+		pf: func() PhysicalFunctionConfig {
+			pf := validVRB1PhysicalFunction()
+			pf.BBDevConfig.VRB2 = &VRB2BBDevConfig{}
+			return pf
+		},
+		fields: []string{"bbDevConfig"},
+	},
+	{
+		name: "vrb1 numVfBundles over the maximum",
+		pf: func() PhysicalFunctionConfig {
+			pf := validVRB1PhysicalFunction()
+			pf.VFAmount, pf.BBDevConfig.VRB1.NumVfBundles = 17, 17
+			return pf
+		},
+		fields: []string{"bbDevConfig.vrb1.numVfBundles"},
+	},
+	{
+		name: "vrb1 with too many queue groups and queues per group",
+		pf: func() PhysicalFunctionConfig {
+			pf := validVRB1PhysicalFunction()
+			pf.BBDevConfig.VRB1.QFFT = QueueGroupConfig{NumQueueGroups: 9, NumAqsPerGroups: 17, AqDepthLog2: 4}
+			return pf
+		},
+		fields: []string{"bbDevConfig.vrb1", "bbDevConfig.vrb1.qfft.numAqsPerGroups"},
+	},
+	{
+		name: "vrb2 numVfBundles different than vfAmount with incomplete queue group",
+		pf: func() PhysicalFunctionConfig {
+			pf := validVRB1PhysicalFunction()
+			pf.BBDevConfig = BBDevConfig{VRB2: &VRB2BBDevConfig{
+				ACC100BBDevConfig: pf.BBDevConfig.VRB1.ACC100BBDevConfig,
+				QFFT:              pf.BBDevConfig.VRB1.QFFT,
+			}}
+			pf.BBDevConfig.VRB2.NumVfBundles = 4
+			return pf
+		},
+		fields: []string{"bbDevConfig.vrb2.numVfBundles", "bbDevConfig.vrb2.qmld"},
+	},
+}
+
+func corpusErrorFields(errs field.ErrorList, root *field.Path) []string {
+	fields := []string{}
+	for _, err := range errs {
+		fields = append(fields, err.Field[len(root.String())+1:])
+	}
+	return fields
+}
+
+func TestValidatePhysicalFunctionCorpus(t *testing.T) {
+	root := field.NewPath("spec", "physicalFunction")
+	for _, tc := range physicalFunctionCorpus {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			expected := append([]string{}, tc.fields...)
+			g.Expect(corpusErrorFields(ValidatePhysicalFunction(root, tc.pf(), tc.device), root)).To(ConsistOf(expected))
+		})
+	}
+}
+
+func TestValidateNodePhysicalFunctionCorpus(t *testing.T) {
+	root := field.NewPath("spec", "physicalFunctions").Index(0)
+	for _, tc := range physicalFunctionCorpus {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			pf := tc.pf()
+			ext := PhysicalFunctionConfigExt{
+				PCIAddress:      "0000:f7:00.0",
+				PFDriver:        pf.PFDriver,
+				VFDriver:        pf.VFDriver,
+				VFAmount:        pf.VFAmount,
+				BBDevConfig:     pf.BBDevConfig,
+				BBDevConfigFrom: pf.BBDevConfigFrom,
+			}
+			expected := append([]string{}, tc.fields...)
+			g.Expect(corpusErrorFields(ValidateNodePhysicalFunction(root, ext, tc.device), root)).To(ConsistOf(expected))
+		})
+	}
+}
+
+func TestValidateConfigurationHookRestrictsPathToAllowedPrefix(t *testing.T) {
+	g := NewWithT(t)
+	spec := SriovVrbClusterConfigSpec{
+		PhysicalFunction:  validVRB1PhysicalFunction(),
+		PreConfigureHook:  &ConfigurationHook{Path: "/etc/sriov-fec/hooks/quiesce.sh", Timeout: &metav1.Duration{Duration: -1}},
+		PostConfigureHook: &ConfigurationHook{Path: "/usr/bin/reboot"},
+	}
+
+	g.Expect(validate(spec)).To(ConsistOf(
+		HaveField("Field", "spec.preConfigureHook.timeout"),
+		HaveField("Field", "spec.postConfigureHook.path"),
+	))
+
+	t.Setenv(utils.CONFIGURATION_HOOK_PATH_PREFIX, "/usr/bin")
+	spec.PreConfigureHook.Timeout = nil
+	g.Expect(validate(spec)).To(ConsistOf(HaveField("Field", "spec.preConfigureHook.path")))
+}
+
+func TestValidateDrainGracePeriodRequiresDeleteEvictionMode(t *testing.T) {
+	g := NewWithT(t)
+	gracePeriod := int64(10)
+	spec := SriovVrbClusterConfigSpec{PhysicalFunction: validVRB1PhysicalFunction(), DrainGracePeriodSeconds: &gracePeriod}
+
+	g.Expect(validate(spec)).To(ConsistOf(HaveField("Type", field.ErrorTypeForbidden)))
+
+	spec.DrainEvictionMode = DrainEvictionModeDelete
+	g.Expect(validate(spec)).To(BeEmpty())
+
+	gracePeriod = -1
+	g.Expect(validate(spec)).To(ConsistOf(HaveField("Field", "spec.drainGracePeriodSeconds")))
+}
+
+func TestValidateMaxConfigurationRetries(t *testing.T) {
+	g := NewWithT(t)
+	maxRetries := 0
+	spec := SriovVrbClusterConfigSpec{PhysicalFunction: validVRB1PhysicalFunction(), MaxConfigurationRetries: &maxRetries}
+	g.Expect(validate(spec)).To(BeEmpty())
+
+	maxRetries = -1
+	g.Expect(validate(spec)).To(ConsistOf(HaveField("Field", "spec.maxConfigurationRetries")))
+}
+
+func TestValidateHousekeepingCpuSet(t *testing.T) {
+	g := NewWithT(t)
+	spec := SriovVrbClusterConfigSpec{PhysicalFunction: validVRB1PhysicalFunction(), HousekeepingCpuSet: "0-1,32-33"}
+	g.Expect(validate(spec)).To(BeEmpty())
+
+	spec.HousekeepingCpuSet = "1-0"
+	g.Expect(validate(spec)).To(ConsistOf(HaveField("Field", "spec.housekeepingCpuSet")))
+}
+
+func TestAcceleratorSelectorPCIAddressIsNormalizedAndValidated(t *testing.T) {
+	g := NewWithT(t)
+	cc := &SriovVrbClusterConfig{Spec: SriovVrbClusterConfigSpec{
+		PhysicalFunction:    validVRB1PhysicalFunction(),
+		AcceleratorSelector: AcceleratorSelector{PCIAddress: "F7:0.0"},
+	}}
+	g.Expect(validate(cc.Spec)).To(ConsistOf(HaveField("Field", "spec.acceleratorSelector.pciAddress")))
+
+	cc.Default()
+	g.Expect(cc.Spec.AcceleratorSelector.PCIAddress).To(Equal("0000:f7:00.0"))
+	g.Expect(validate(cc.Spec)).To(BeEmpty())
+
+	cc.Spec.AcceleratorSelector.PCIAddress = "0000:f7:00.9"
+	cc.Default()
+	g.Expect(validate(cc.Spec)).To(ConsistOf(HaveField("Field", "spec.acceleratorSelector.pciAddress")))
+}
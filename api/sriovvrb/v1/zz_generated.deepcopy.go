@@ -47,6 +47,26 @@ func (in *AcceleratorSelector) DeepCopy() *AcceleratorSelector {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AutoRebootPolicy) DeepCopyInto(out *AutoRebootPolicy) {
+	*out = *in
+	if in.Cooldown != nil {
+		in, out := &in.Cooldown, &out.Cooldown
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutoRebootPolicy.
+func (in *AutoRebootPolicy) DeepCopy() *AutoRebootPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(AutoRebootPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BBDevConfig) DeepCopyInto(out *BBDevConfig) {
 	*out = *in
@@ -55,6 +75,11 @@ func (in *BBDevConfig) DeepCopyInto(out *BBDevConfig) {
 		*out = new(VRB1BBDevConfig)
 		**out = **in
 	}
+	if in.VRB2 != nil {
+		in, out := &in.VRB2, &out.VRB2
+		*out = new(VRB2BBDevConfig)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BBDevConfig.
@@ -67,6 +92,22 @@ func (in *BBDevConfig) DeepCopy() *BBDevConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BBDevConfigFromSource) DeepCopyInto(out *BBDevConfigFromSource) {
+	*out = *in
+	out.ConfigMapRef = in.ConfigMapRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BBDevConfigFromSource.
+func (in *BBDevConfigFromSource) DeepCopy() *BBDevConfigFromSource {
+	if in == nil {
+		return nil
+	}
+	out := new(BBDevConfigFromSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in ByPriority) DeepCopyInto(out *ByPriority) {
 	{
@@ -88,6 +129,127 @@ func (in ByPriority) DeepCopy() ByPriority {
 	return *out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterConfigDefaults) DeepCopyInto(out *ClusterConfigDefaults) {
+	*out = *in
+	if in.BBDevConfig != nil {
+		in, out := &in.BBDevConfig, &out.BBDevConfig
+		*out = new(BBDevConfig)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterConfigDefaults.
+func (in *ClusterConfigDefaults) DeepCopy() *ClusterConfigDefaults {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterConfigDefaults)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigMapKeyReference) DeepCopyInto(out *ConfigMapKeyReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigMapKeyReference.
+func (in *ConfigMapKeyReference) DeepCopy() *ConfigMapKeyReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigMapKeyReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigurationHook) DeepCopyInto(out *ConfigurationHook) {
+	*out = *in
+	if in.Args != nil {
+		in, out := &in.Args, &out.Args
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigurationHook.
+func (in *ConfigurationHook) DeepCopy() *ConfigurationHook {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigurationHook)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DryRunNodeConfig) DeepCopyInto(out *DryRunNodeConfig) {
+	*out = *in
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DryRunNodeConfig.
+func (in *DryRunNodeConfig) DeepCopy() *DryRunNodeConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(DryRunNodeConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigurationRetries) DeepCopyInto(out *ConfigurationRetries) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigurationRetries.
+func (in *ConfigurationRetries) DeepCopy() *ConfigurationRetries {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigurationRetries)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HookResult) DeepCopyInto(out *HookResult) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HookResult.
+func (in *HookResult) DeepCopy() *HookResult {
+	if in == nil {
+		return nil
+	}
+	out := new(HookResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeDecision) DeepCopyInto(out *NodeDecision) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeDecision.
+func (in *NodeDecision) DeepCopy() *NodeDecision {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeDecision)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NodeInventory) DeepCopyInto(out *NodeInventory) {
 	*out = *in
@@ -98,6 +260,11 @@ func (in *NodeInventory) DeepCopyInto(out *NodeInventory) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.UnsupportedDevices != nil {
+		in, out := &in.UnsupportedDevices, &out.UnsupportedDevices
+		*out = make([]UnsupportedDevice, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeInventory.
@@ -110,10 +277,53 @@ func (in *NodeInventory) DeepCopy() *NodeInventory {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PFLogicalNames) DeepCopyInto(out *PFLogicalNames) {
+	*out = *in
+	if in.VFs != nil {
+		in, out := &in.VFs, &out.VFs
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PFLogicalNames.
+func (in *PFLogicalNames) DeepCopy() *PFLogicalNames {
+	if in == nil {
+		return nil
+	}
+	out := new(PFLogicalNames)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PFTransition) DeepCopyInto(out *PFTransition) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PFTransition.
+func (in *PFTransition) DeepCopy() *PFTransition {
+	if in == nil {
+		return nil
+	}
+	out := new(PFTransition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PhysicalFunctionConfig) DeepCopyInto(out *PhysicalFunctionConfig) {
 	*out = *in
 	in.BBDevConfig.DeepCopyInto(&out.BBDevConfig)
+	if in.BBDevConfigFrom != nil {
+		in, out := &in.BBDevConfigFrom, &out.BBDevConfigFrom
+		*out = new(BBDevConfigFromSource)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PhysicalFunctionConfig.
@@ -130,6 +340,11 @@ func (in *PhysicalFunctionConfig) DeepCopy() *PhysicalFunctionConfig {
 func (in *PhysicalFunctionConfigExt) DeepCopyInto(out *PhysicalFunctionConfigExt) {
 	*out = *in
 	in.BBDevConfig.DeepCopyInto(&out.BBDevConfig)
+	if in.BBDevConfigFrom != nil {
+		in, out := &in.BBDevConfigFrom, &out.BBDevConfigFrom
+		*out = new(BBDevConfigFromSource)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PhysicalFunctionConfigExt.
@@ -157,6 +372,41 @@ func (in *QueueGroupConfig) DeepCopy() *QueueGroupConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PredictedVFs) DeepCopyInto(out *PredictedVFs) {
+	*out = *in
+	if in.VFs != nil {
+		in, out := &in.VFs, &out.VFs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PredictedVFs.
+func (in *PredictedVFs) DeepCopy() *PredictedVFs {
+	if in == nil {
+		return nil
+	}
+	out := new(PredictedVFs)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceVFCapacity) DeepCopyInto(out *ResourceVFCapacity) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceVFCapacity.
+func (in *ResourceVFCapacity) DeepCopy() *ResourceVFCapacity {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceVFCapacity)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SriovAccelerator) DeepCopyInto(out *SriovAccelerator) {
 	*out = *in
@@ -165,6 +415,13 @@ func (in *SriovAccelerator) DeepCopyInto(out *SriovAccelerator) {
 		*out = make([]VF, len(*in))
 		copy(*out, *in)
 	}
+	if in.Attributes != nil {
+		in, out := &in.Attributes, &out.Attributes
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SriovAccelerator.
@@ -177,6 +434,21 @@ func (in *SriovAccelerator) DeepCopy() *SriovAccelerator {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UnsupportedDevice) DeepCopyInto(out *UnsupportedDevice) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UnsupportedDevice.
+func (in *UnsupportedDevice) DeepCopy() *UnsupportedDevice {
+	if in == nil {
+		return nil
+	}
+	out := new(UnsupportedDevice)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VF) DeepCopyInto(out *VF) {
 	*out = *in
@@ -192,6 +464,27 @@ func (in *VF) DeepCopy() *VF {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VFCapacity) DeepCopyInto(out *VFCapacity) {
+	*out = *in
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]ResourceVFCapacity, len(*in))
+		copy(*out, *in)
+	}
+	in.LastUpdated.DeepCopyInto(&out.LastUpdated)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VFCapacity.
+func (in *VFCapacity) DeepCopy() *VFCapacity {
+	if in == nil {
+		return nil
+	}
+	out := new(VFCapacity)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VRB1BBDevConfig) DeepCopyInto(out *VRB1BBDevConfig) {
 	*out = *in
@@ -232,7 +525,7 @@ func (in *SriovVrbClusterConfig) DeepCopyInto(out *SriovVrbClusterConfig) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SriovVrbClusterConfig.
@@ -297,6 +590,46 @@ func (in *SriovVrbClusterConfigSpec) DeepCopyInto(out *SriovVrbClusterConfigSpec
 	}
 	out.AcceleratorSelector = in.AcceleratorSelector
 	in.PhysicalFunction.DeepCopyInto(&out.PhysicalFunction)
+	if in.Defaults != nil {
+		in, out := &in.Defaults, &out.Defaults
+		*out = new(ClusterConfigDefaults)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.EnforceCompatibilityChecks != nil {
+		in, out := &in.EnforceCompatibilityChecks, &out.EnforceCompatibilityChecks
+		*out = new(bool)
+		**out = **in
+	}
+	if in.DrainGracePeriodSeconds != nil {
+		in, out := &in.DrainGracePeriodSeconds, &out.DrainGracePeriodSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.MaxConfigurationRetries != nil {
+		in, out := &in.MaxConfigurationRetries, &out.MaxConfigurationRetries
+		*out = new(int)
+		**out = **in
+	}
+	if in.ConfigurationDebounce != nil {
+		in, out := &in.ConfigurationDebounce, &out.ConfigurationDebounce
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.PreConfigureHook != nil {
+		in, out := &in.PreConfigureHook, &out.PreConfigureHook
+		*out = new(ConfigurationHook)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PostConfigureHook != nil {
+		in, out := &in.PostConfigureHook, &out.PostConfigureHook
+		*out = new(ConfigurationHook)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AutoRebootOnPersistentFailure != nil {
+		in, out := &in.AutoRebootOnPersistentFailure, &out.AutoRebootOnPersistentFailure
+		*out = new(AutoRebootPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SriovVrbClusterConfigSpec.
@@ -312,6 +645,30 @@ func (in *SriovVrbClusterConfigSpec) DeepCopy() *SriovVrbClusterConfigSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SriovVrbClusterConfigStatus) DeepCopyInto(out *SriovVrbClusterConfigStatus) {
 	*out = *in
+	if in.NodeDecisions != nil {
+		in, out := &in.NodeDecisions, &out.NodeDecisions
+		*out = make([]NodeDecision, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DryRunNodeConfigs != nil {
+		in, out := &in.DryRunNodeConfigs, &out.DryRunNodeConfigs
+		*out = make([]DryRunNodeConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.VFCapacity != nil {
+		in, out := &in.VFCapacity, &out.VFCapacity
+		*out = new(VFCapacity)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SriovVrbClusterConfigStatus.
@@ -393,6 +750,41 @@ func (in *SriovVrbNodeConfigSpec) DeepCopyInto(out *SriovVrbNodeConfigSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.EnforceCompatibilityChecks != nil {
+		in, out := &in.EnforceCompatibilityChecks, &out.EnforceCompatibilityChecks
+		*out = new(bool)
+		**out = **in
+	}
+	if in.DrainGracePeriodSeconds != nil {
+		in, out := &in.DrainGracePeriodSeconds, &out.DrainGracePeriodSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.MaxConfigurationRetries != nil {
+		in, out := &in.MaxConfigurationRetries, &out.MaxConfigurationRetries
+		*out = new(int)
+		**out = **in
+	}
+	if in.ConfigurationDebounce != nil {
+		in, out := &in.ConfigurationDebounce, &out.ConfigurationDebounce
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.PreConfigureHook != nil {
+		in, out := &in.PreConfigureHook, &out.PreConfigureHook
+		*out = new(ConfigurationHook)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PostConfigureHook != nil {
+		in, out := &in.PostConfigureHook, &out.PostConfigureHook
+		*out = new(ConfigurationHook)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AutoRebootOnPersistentFailure != nil {
+		in, out := &in.AutoRebootOnPersistentFailure, &out.AutoRebootOnPersistentFailure
+		*out = new(AutoRebootPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SriovVrbNodeConfigSpec.
@@ -416,6 +808,108 @@ func (in *SriovVrbNodeConfigStatus) DeepCopyInto(out *SriovVrbNodeConfigStatus)
 		}
 	}
 	in.Inventory.DeepCopyInto(&out.Inventory)
+	if in.InventoryCollectedAt != nil {
+		in, out := &in.InventoryCollectedAt, &out.InventoryCollectedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.BBDevConfigHashes != nil {
+		in, out := &in.BBDevConfigHashes, &out.BBDevConfigHashes
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.AppliedBBDevConfigHashes != nil {
+		in, out := &in.AppliedBBDevConfigHashes, &out.AppliedBBDevConfigHashes
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.PfBBConfigDurations != nil {
+		in, out := &in.PfBBConfigDurations, &out.PfBBConfigDurations
+		*out = make(map[string]metav1.Duration, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.PfBBConfigCpuSets != nil {
+		in, out := &in.PfBBConfigCpuSets, &out.PfBBConfigCpuSets
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.UnsupportedDevices != nil {
+		in, out := &in.UnsupportedDevices, &out.UnsupportedDevices
+		*out = make([]UnsupportedDevice, len(*in))
+		copy(*out, *in)
+	}
+	if in.PredictedVFs != nil {
+		in, out := &in.PredictedVFs, &out.PredictedVFs
+		*out = make([]PredictedVFs, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.HookResults != nil {
+		in, out := &in.HookResults, &out.HookResults
+		*out = make([]HookResult, len(*in))
+		copy(*out, *in)
+	}
+	if in.Warnings != nil {
+		in, out := &in.Warnings, &out.Warnings
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AdoptedPFs != nil {
+		in, out := &in.AdoptedPFs, &out.AdoptedPFs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AppliedGenerations != nil {
+		in, out := &in.AppliedGenerations, &out.AppliedGenerations
+		*out = make(map[string]int64, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ConfigurationRetries != nil {
+		in, out := &in.ConfigurationRetries, &out.ConfigurationRetries
+		*out = new(ConfigurationRetries)
+		**out = **in
+	}
+	if in.PfTransitions != nil {
+		in, out := &in.PfTransitions, &out.PfTransitions
+		*out = make(map[string][]PFTransition, len(*in))
+		for key, val := range *in {
+			var outVal []PFTransition
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = make([]PFTransition, len(*in))
+				for i := range *in {
+					(*in)[i].DeepCopyInto(&(*out)[i])
+				}
+			}
+			(*out)[key] = outVal
+		}
+	}
+	if in.PfBBConfigFailures != nil {
+		in, out := &in.PfBBConfigFailures, &out.PfBBConfigFailures
+		*out = make(map[string]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.LogicalNames != nil {
+		in, out := &in.LogicalNames, &out.LogicalNames
+		*out = make([]PFLogicalNames, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SriovVrbNodeConfigStatus.
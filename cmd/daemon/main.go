@@ -4,13 +4,17 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
 	"syscall"
 
 	"github.com/go-logr/logr"
 	"github.com/google/uuid"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/conditions"
 	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/drainhelper"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/faultinjection"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/operatorconfig"
 	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
 
 	"k8s.io/apimachinery/pkg/types"
@@ -43,6 +47,22 @@ func main() {
 
 	ctrl.SetLogger(logr.New(utils.NewLogWrapper()))
 
+	pfBbConfigCliCmd := flag.String("C", "", "CLI command string")
+	bbDevConfigDiff := flag.String("bbdev-config-diff", "", "print diff between the last two pf_bb_config cfg files of PF with given PCI address")
+	standalone := flag.Bool("standalone", false, "configure the node once outside of a pod and print resulting status instead of updating node configs")
+	localSpec := flag.String("local-spec", "", "YAML file with node configs used in standalone mode instead of the ones in API server")
+	flag.Usage = func() {
+		daemon.ShowHelp()
+	}
+	flag.Parse()
+	if *standalone {
+		os.Exit(runStandalone(*localSpec))
+	}
+	if *localSpec != "" {
+		setupLog.Error("-local-spec is supported only in standalone mode")
+		os.Exit(1)
+	}
+
 	nodeName := getNodeNameFromEnvOrDie()
 	ns := getSriovFecNameSpaceFromEnvOrDie()
 
@@ -53,17 +73,29 @@ func main() {
 		os.Exit(1)
 	}
 
-	pfBbConfigCliCmd := flag.String("C", "", "CLI command string")
-	flag.Usage = func() {
-		daemon.ShowHelp()
-	}
-	flag.Parse()
 	if *pfBbConfigCliCmd != "" {
 		// Get the additional arguments after CLI command
 		args := flag.Args()
 		daemon.StartPfBbConfigCli(nodeName, ns, directClient, *pfBbConfigCliCmd, args, setupLog)
 		return
 	}
+	if *bbDevConfigDiff != "" {
+		if _, err := daemon.InitStateDir(setupLog); err != nil {
+			setupLog.WithError(err).Error("invalid state directory")
+			os.Exit(1)
+		}
+		if err := daemon.PrintBBDevConfigDiff(os.Stdout, *bbDevConfigDiff); err != nil {
+			setupLog.WithError(err).Error("failed to diff pf_bb_config cfg files")
+			os.Exit(1)
+		}
+		return
+	}
+
+	operatorConfig, err := operatorconfig.Load(directClient, operatorconfig.DaemonSettings, setupLog)
+	if err != nil {
+		setupLog.WithError(err).Error("failed to apply operator configuration")
+		os.Exit(1)
+	}
 
 	cset, err := clientset.NewForConfig(config)
 	if err != nil {
@@ -76,7 +108,44 @@ func main() {
 		os.Exit(1)
 	}
 
-	daemon.StartTelemetryDaemon(mgr, nodeName, ns, directClient, setupLog)
+	if _, err := daemon.InitStateDir(setupLog); err != nil {
+		setupLog.WithError(err).Error("invalid state directory")
+		os.Exit(1)
+	}
+
+	conditions.InitMessageLimit(setupLog)
+
+	if _, err := daemon.InitHostProcPath(setupLog); err != nil {
+		setupLog.WithError(err).Warn("process discovery is limited to daemon container - pf_bb_config started outside of it is not found")
+	}
+
+	if err := daemon.PinToHousekeepingCPUs(setupLog); err != nil {
+		setupLog.WithError(err).Warn("failed to pin the daemon to housekeeping CPUs - it runs on all CPUs available to it")
+	}
+
+	if enabled, err := faultinjection.LoadFromEnv(); err != nil {
+		setupLog.WithError(err).Error("invalid fault injection file")
+		os.Exit(1)
+	} else if enabled {
+		setupLog.WithField("path", os.Getenv(faultinjection.EnvVarName)).Warn("fault injection is enabled - it is meant for testing only")
+	}
+
+	featureGates, err := daemon.FeatureGatesFromEnv()
+	if err != nil {
+		setupLog.WithError(err).Error("invalid feature gates")
+		os.Exit(1)
+	}
+	featureGates.Report(setupLog)
+
+	nodeNameMatching, err := daemon.NodeNameMatchingFromEnv()
+	if err != nil {
+		setupLog.WithError(err).Error("invalid node name matching")
+		os.Exit(1)
+	}
+
+	if featureGates.Enabled(daemon.Telemetry) {
+		daemon.StartTelemetryDaemon(mgr, nodeName, ns, directClient, setupLog)
+	}
 
 	vfioTokenBytes, err := os.ReadFile("/sriov_config/vfiotoken")
 	if err != nil {
@@ -98,15 +167,39 @@ func main() {
 
 	nodeNameRef := types.NamespacedName{Namespace: ns, Name: nodeName}
 	drainHelper := drainhelper.NewDrainHelper(utils.NewLogger(), cset, nodeName, ns, isSingleNodeCluster)
+	drainHelper.SetClock(daemon.WallTime)
+	effectiveConfig := daemon.NewEffectiveConfig(drainHelper.Settings(), isSingleNodeCluster, featureGates, setupLog)
+	if err := daemon.PublishEffectiveConfig(directClient, ns, nodeName, effectiveConfig, setupLog); err != nil {
+		setupLog.WithError(err).Error("failed to publish effective configuration")
+	}
+
+	daemon.RegisterBuiltinInventoryEnrichers(featureGates)
+	daemon.SetInventoryInfoNodeName(nodeName)
+
+	var auditSink *daemon.AuditSink
+	if featureGates.Enabled(daemon.AuditLog) {
+		auditSink = daemon.NewAuditSink(directClient, nodeNameRef, utils.NewLogger())
+	}
+
 	pfBBConfigController := daemon.NewPfBBConfigController(utils.NewLogger(), vfioToken.String())
-	nodeConfigurer := daemon.NewNodeConfigurator(utils.NewLogger(), pfBBConfigController, mgr.GetClient(), nodeNameRef)
-	devicePluginController := daemon.NewDevicePluginController(mgr.GetClient(), utils.NewLogger(), nodeNameRef)
+	if featureGates.Enabled(daemon.BBDevConfigMirror) {
+		pfBBConfigController.MirrorBBDevConfigs(directClient, nodeNameRef)
+	}
+	nodeConfigurer := daemon.NewNodeConfigurator(utils.NewLogger(), pfBBConfigController, mgr.GetClient(), nodeNameRef, featureGates, auditSink)
+	nodeConfigurer.SetKernelLogSource(daemon.KernelLogSourceFromEnv())
+	devicePluginController := daemon.NewDevicePluginController(mgr.GetClient(), utils.NewLogger(), nodeNameRef, mgr.GetEventRecorderFor("sriov-fec-daemon"))
 
-	reconciler, err := daemon.NewNodeConfigReconciler(mgr.GetClient(), drainHelper.Run, nodeNameRef, nodeConfigurer, nodeConfigurer, devicePluginController.RestartDevicePlugin)
+	reconciler, err := daemon.NewNodeConfigReconciler(mgr.GetClient(), drainHelper, nodeNameRef, nodeConfigurer, nodeConfigurer,
+		devicePluginController.RestartDevicePlugin, mgr.GetEventRecorderFor("sriov-fec-daemon"), auditSink)
 	if err != nil {
 		setupLog.WithError(err).Error("unable to create reconciler")
 		os.Exit(1)
 	}
+	reconciler.OnVfioTokenChange(pfBBConfigController.SetVfioToken)
+	reconciler.SetNodeNameMatching(nodeNameMatching)
+	nodeConfigurer.SetNodeConfigKey(reconciler.NodeConfigKey)
+	reconciler.ProbePrivileges()
+	reconciler.DetectPfBBConfigCapabilities(context.Background())
 
 	if err := reconciler.SetupWithManager(mgr); err != nil {
 		setupLog.WithError(err).Error("unable to create controller for SrionvFecNodeConfig")
@@ -118,12 +211,49 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err := reconciler.CreateEmptyNodeConfigIfNeeded(directClient); err != nil {
+	if err := mgr.Add(daemon.NewCordonMonitor(directClient, mgr.GetEventRecorderFor("sriov-fec-daemon"), nodeNameRef, reconciler.NodeConfigKey, utils.NewLogger())); err != nil {
+		setupLog.WithError(err).Error("unable to add cordon monitor")
+		os.Exit(1)
+	}
+
+	if err := mgr.Add(daemon.NewResourceConsistencyChecker(directClient, mgr.GetEventRecorderFor("sriov-fec-daemon"), nodeNameRef, reconciler.NodeConfigKey, reconciler.IsConfigurationInProgress, utils.NewLogger())); err != nil {
+		setupLog.WithError(err).Error("unable to add resource consistency checker")
+		os.Exit(1)
+	}
+
+	if featureGates.Enabled(daemon.AERMonitoring) {
+		if err := mgr.Add(daemon.NewAERCollector(directClient, mgr.GetEventRecorderFor("sriov-fec-daemon"), reconciler.NodeConfigKey, utils.NewLogger())); err != nil {
+			setupLog.WithError(err).Error("unable to add AER collector")
+			os.Exit(1)
+		}
+	}
+
+	if featureGates.Enabled(daemon.DebugEndpoint) {
+		if err := mgr.Add(daemon.NewDebugServer(reconciler, effectiveConfig, utils.NewLogger())); err != nil {
+			setupLog.WithError(err).Error("unable to add debug endpoint")
+			os.Exit(1)
+		}
+	}
+
+	ctx, restart := context.WithCancel(ctrl.SetupSignalHandler())
+	if err := (&operatorconfig.Reconciler{
+		Client:          mgr.GetClient(),
+		Log:             utils.NewLogger(),
+		Startup:         operatorConfig,
+		SetResyncPeriod: daemon.SetResyncPeriod,
+		Busy:            reconciler.IsConfigurationInProgress,
+		Restart:         restart,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.WithError(err).Error("unable to create controller for SriovFecOperatorConfig")
+		os.Exit(1)
+	}
+
+	if err := reconciler.CreateEmptyNodeConfigIfNeeded(ctx, directClient); err != nil {
 		setupLog.WithError(err).Error("failed to create initial NodeConfig CR")
 		os.Exit(1)
 	}
 
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	if err := mgr.Start(ctx); err != nil {
 		setupLog.WithError(err).Error("problem running manager")
 		os.Exit(1)
 	}
@@ -0,0 +1,131 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package main
+
+import (
+	"os"
+
+	"github.com/google/uuid"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/conditions"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/faultinjection"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/daemon"
+)
+
+// standaloneNamespace is namespace of node configs in standalone mode when SRIOV_FEC_NAMESPACE is not set
+const standaloneNamespace = "vran-acceleration-operators"
+
+// runStandalone configures the node once, outside of a pod, and prints node configs with resulting status to stdout.
+// Node configs are read from localSpec file when given, from API server otherwise; API server is not written to.
+// Exit code of the daemon is returned.
+func runStandalone(localSpec string) int {
+	nodeNameRef, err := standaloneNodeNameRef()
+	if err != nil {
+		setupLog.WithError(err).Error("failed to determine name of the node")
+		return 1
+	}
+
+	var apiClient client.Client
+	if config, err := ctrl.GetConfig(); err != nil {
+		if localSpec == "" {
+			setupLog.WithError(err).Error("kubeconfig is required in standalone mode without -local-spec")
+			return 1
+		}
+		setupLog.WithError(err).Info("no kubeconfig - BBDevConfig ConfigMaps and other objects referenced by local spec are not found")
+	} else if apiClient, err = client.New(config, client.Options{Scheme: scheme}); err != nil {
+		setupLog.WithError(err).Error("failed to create direct client")
+		return 1
+	}
+
+	ctx := ctrl.SetupSignalHandler()
+	var nodeConfigs []client.Object
+	if localSpec != "" {
+		nodeConfigs, err = daemon.LoadLocalSpec(localSpec, nodeNameRef)
+	} else {
+		nodeConfigs, err = daemon.ReadNodeConfigs(ctx, apiClient, nodeNameRef)
+	}
+	if err != nil {
+		setupLog.WithError(err).Error("failed to read node configs")
+		return 1
+	}
+	standaloneClient, err := daemon.NewStandaloneClient(apiClient, scheme, nodeConfigs...)
+	if err != nil {
+		setupLog.WithError(err).Error("failed to create standalone client")
+		return 1
+	}
+
+	if _, err := daemon.InitStateDir(setupLog); err != nil {
+		setupLog.WithError(err).Error("invalid state directory")
+		return 1
+	}
+	conditions.InitMessageLimit(setupLog)
+	if _, err := daemon.InitHostProcPath(setupLog); err != nil {
+		setupLog.WithError(err).Warn("process discovery is limited to the daemon - pf_bb_config started outside of it is not found")
+	}
+	if enabled, err := faultinjection.LoadFromEnv(); err != nil {
+		setupLog.WithError(err).Error("invalid fault injection file")
+		return 1
+	} else if enabled {
+		setupLog.WithField("path", os.Getenv(faultinjection.EnvVarName)).Warn("fault injection is enabled - it is meant for testing only")
+	}
+	featureGates, err := daemon.FeatureGatesFromEnv()
+	if err != nil {
+		setupLog.WithError(err).Error("invalid feature gates")
+		return 1
+	}
+	featureGates.Report(setupLog)
+
+	// outside of a pod the token is not mounted from the Secret; VF users have to be given the generated one then
+	vfioToken := uuid.New()
+	if vfioTokenBytes, err := os.ReadFile("/sriov_config/vfiotoken"); err != nil {
+		setupLog.WithError(err).WithField("vfioToken", vfioToken.String()).Warn("VFIO token not found - using generated one")
+	} else if vfioToken, err = uuid.ParseBytes(vfioTokenBytes); err != nil {
+		setupLog.Errorf("provided vfioToken(%s) is not in UUID format: %s", vfioTokenBytes, err)
+		return 1
+	}
+
+	daemon.RegisterBuiltinInventoryEnrichers(featureGates)
+	daemon.SetInventoryInfoNodeName(nodeNameRef.Name)
+
+	pfBBConfigController := daemon.NewPfBBConfigController(utils.NewLogger(), vfioToken.String())
+	nodeConfigurer := daemon.NewNodeConfigurator(utils.NewLogger(), pfBBConfigController, standaloneClient, nodeNameRef, featureGates, nil)
+	nodeConfigurer.SetKernelLogSource(daemon.KernelLogSourceFromEnv())
+
+	reconciler, err := daemon.NewNodeConfigReconciler(standaloneClient, daemon.NewStandaloneDrainer(), nodeNameRef, nodeConfigurer, nodeConfigurer,
+		daemon.RestartDevicePluginInStandalone(setupLog), daemon.NewStandaloneRecorder(utils.NewLogger()), nil)
+	if err != nil {
+		setupLog.WithError(err).Error("unable to create reconciler")
+		return 1
+	}
+	reconciler.ProbePrivileges()
+	reconciler.DetectPfBBConfigCapabilities(ctx)
+
+	if err := reconciler.RunStandalone(ctx, os.Stdout); err != nil {
+		setupLog.WithError(err).Error("configuration failed")
+		return 1
+	}
+	return 0
+}
+
+// standaloneNodeNameRef returns reference of node configs of the node; outside of a pod NODENAME and
+// SRIOV_FEC_NAMESPACE default to hostname and standaloneNamespace
+func standaloneNodeNameRef() (types.NamespacedName, error) {
+	nodeName := os.Getenv("NODENAME")
+	if nodeName == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return types.NamespacedName{}, err
+		}
+		nodeName = hostname
+	}
+	ns := os.Getenv("SRIOV_FEC_NAMESPACE")
+	if ns == "" {
+		ns = standaloneNamespace
+	}
+	return types.NamespacedName{Namespace: ns, Name: nodeName}, nil
+}
@@ -0,0 +1,178 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package sriovfec
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sriovfecv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+)
+
+const (
+	stressNodes   = 200
+	stressWorkers = 4
+	stressTimeout = 30 * time.Second
+)
+
+func stressNodeName(i int) string {
+	return fmt.Sprintf("node-%03d", i)
+}
+
+// stressAccelerator is reported by the node; even nodes have ACC100, odd ones ACC200
+func stressAccelerator(i int) sriovfecv2.SriovAccelerator {
+	deviceID := "0d5c"
+	if i%2 == 1 {
+		deviceID = "57c0"
+	}
+	return sriovfecv2.SriovAccelerator{VendorID: "8086", DeviceID: deviceID, PCIAddress: fmt.Sprintf("0000:%02x:00.0", i%256), MaxVFs: 16}
+}
+
+func stressClusterConfig(name, deviceID string, vfAmount int) *sriovfecv2.SriovFecClusterConfig {
+	return &sriovfecv2.SriovFecClusterConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: NAMESPACE},
+		Spec: sriovfecv2.SriovFecClusterConfigSpec{
+			AcceleratorSelector: sriovfecv2.AcceleratorSelector{DeviceID: deviceID},
+			PhysicalFunction: sriovfecv2.PhysicalFunctionConfig{
+				PFDriver: "vfio-pci",
+				VFDriver: "vfio-pci",
+				VFAmount: vfAmount,
+			},
+		},
+	}
+}
+
+// TestConcurrentReconcilesStress feeds status updates of many NodeConfigs (as reported by daemons after a rollout)
+// to reconciles running concurrently the same way as controller's workers do, and checks that all NodeConfigs and
+// node decisions aggregated into ClusterConfigs end up correct
+func TestConcurrentReconcilesStress(t *testing.T) {
+	g := NewWithT(t)
+
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	g.Expect(sriovfecv2.AddToScheme(scheme)).To(Succeed())
+
+	objects := []client.Object{
+		stressClusterConfig("acc100", "0d5c", 2),
+		stressClusterConfig("acc200", "57c0", 4),
+	}
+	for i := 0; i < stressNodes; i++ {
+		objects = append(objects,
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{
+				Name:   stressNodeName(i),
+				Labels: map[string]string{"fpga.intel.com/intel-accelerator-present": ""},
+			}},
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "daemon-" + stressNodeName(i), Namespace: NAMESPACE, Labels: daemonPodLabels},
+				Spec:       corev1.PodSpec{NodeName: stressNodeName(i)},
+				Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+			},
+			&sriovfecv2.SriovFecNodeConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: stressNodeName(i), Namespace: NAMESPACE},
+				Spec:       sriovfecv2.SriovFecNodeConfigSpec{PhysicalFunctions: []sriovfecv2.PhysicalFunctionConfigExt{}},
+			},
+		)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	reconciler := &SriovFecClusterConfigReconciler{Client: c, Log: log, MaxConcurrentReconciles: stressWorkers}
+
+	// workqueue of controller-runtime never hands the same key to concurrent workers
+	queue := workqueue.New()
+	var workers sync.WaitGroup
+	var reconcileErrs []error
+	var errsLock sync.Mutex
+	for w := 0; w < reconciler.MaxConcurrentReconciles; w++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for {
+				item, shutdown := queue.Get()
+				if shutdown {
+					return
+				}
+				for {
+					result, err := reconciler.Reconcile(context.TODO(), item.(ctrl.Request))
+					if err != nil {
+						errsLock.Lock()
+						reconcileErrs = append(reconcileErrs, err)
+						errsLock.Unlock()
+					}
+					if err != nil || !result.Requeue {
+						break
+					}
+				}
+				queue.Done(item)
+			}
+		}()
+	}
+
+	start := time.Now()
+	for i := 0; i < stressNodes; i++ {
+		err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+			nc := &sriovfecv2.SriovFecNodeConfig{}
+			if err := c.Get(context.TODO(), types.NamespacedName{Namespace: NAMESPACE, Name: stressNodeName(i)}, nc); err != nil {
+				return err
+			}
+			nc.Status.Inventory.SriovAccelerators = []sriovfecv2.SriovAccelerator{stressAccelerator(i)}
+			return c.Status().Update(context.TODO(), nc)
+		})
+		g.Expect(err).ToNot(HaveOccurred())
+		for _, cc := range []string{"acc100", "acc200"} {
+			queue.Add(ctrl.Request{NamespacedName: types.NamespacedName{Namespace: NAMESPACE, Name: cc}})
+		}
+	}
+	queue.ShutDownWithDrain()
+	workers.Wait()
+	g.Expect(time.Since(start)).To(BeNumerically("<", stressTimeout))
+	g.Expect(reconcileErrs).To(BeEmpty())
+
+	for i := 0; i < stressNodes; i++ {
+		nc := &sriovfecv2.SriovFecNodeConfig{}
+		g.Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: NAMESPACE, Name: stressNodeName(i)}, nc)).To(Succeed())
+		vfAmount := 2
+		if i%2 == 1 {
+			vfAmount = 4
+		}
+		g.Expect(nc.Spec.PhysicalFunctions).To(HaveLen(1), nc.Name)
+		g.Expect(nc.Spec.PhysicalFunctions[0].PCIAddress).To(Equal(stressAccelerator(i).PCIAddress), nc.Name)
+		g.Expect(nc.Spec.PhysicalFunctions[0].VFAmount).To(Equal(vfAmount), nc.Name)
+		g.Expect(nc.Status.Inventory.SriovAccelerators).To(ConsistOf(stressAccelerator(i)), "reported status has to be kept")
+	}
+
+	for cc, parity := range map[string]int{"acc100": 1, "acc200": 0} {
+		var unmatched []string
+		for i := parity; i < stressNodes; i += 2 {
+			unmatched = append(unmatched, stressNodeName(i))
+		}
+		sort.Strings(unmatched)
+
+		config := &sriovfecv2.SriovFecClusterConfig{}
+		g.Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: NAMESPACE, Name: cc}, config)).To(Succeed())
+		g.Expect(config.Status.NodeDecisions).To(HaveLen(maxNodeDecisions))
+		for j, decision := range config.Status.NodeDecisions {
+			g.Expect(decision).To(Equal(sriovfecv2.NodeDecision{NodeName: unmatched[j], Reason: "no accelerator matched accelerator selector"}))
+		}
+	}
+}
@@ -0,0 +1,38 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package sriovfec
+
+import (
+	"testing"
+
+	"github.com/elliotchance/orderedmap/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/utils/pointer"
+
+	sriovfecv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+)
+
+// TestBuildNodeConfigUsesFewestConfigurationRetries checks that the node gives up after the fewest retries allowed by
+// matching configs, that configs without the limit do not lift it, and that the limit is kept when no accelerator matches
+func TestBuildNodeConfigUsesFewestConfigurationRetries(t *testing.T) {
+	g := NewWithT(t)
+
+	configs := orderedmap.NewOrderedMap[string, sriovfecv2.SriovFecClusterConfig]()
+	configs.Set("0000:f7:00.0", sriovfecv2.SriovFecClusterConfig{Spec: sriovfecv2.SriovFecClusterConfigSpec{MaxConfigurationRetries: pointer.Int(5)}})
+	configs.Set("0000:f8:00.0", sriovfecv2.SriovFecClusterConfig{})
+	configs.Set("0000:f9:00.0", sriovfecv2.SriovFecClusterConfig{Spec: sriovfecv2.SriovFecClusterConfigSpec{MaxConfigurationRetries: pointer.Int(2)}})
+
+	nc := buildNodeConfig(NodeConfigurationCtx{AcceleratorConfigContext: configs}, false)
+	g.Expect(nc.Spec.MaxConfigurationRetries).To(Equal(pointer.Int(2)))
+
+	nc = buildNodeConfig(NodeConfigurationCtx{
+		SriovFecNodeConfig:       *nc,
+		AcceleratorConfigContext: orderedmap.NewOrderedMap[string, sriovfecv2.SriovFecClusterConfig](),
+	}, false)
+	g.Expect(nc.Spec.MaxConfigurationRetries).To(Equal(pointer.Int(2)))
+
+	configs = orderedmap.NewOrderedMap[string, sriovfecv2.SriovFecClusterConfig]()
+	configs.Set("0000:f7:00.0", sriovfecv2.SriovFecClusterConfig{})
+	g.Expect(buildNodeConfig(NodeConfigurationCtx{AcceleratorConfigContext: configs}, false).Spec.MaxConfigurationRetries).To(BeNil())
+}
@@ -0,0 +1,91 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package sriovfec
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sriovfecv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/conditions"
+)
+
+// TestDiscoveryConfigConsistency checks that ClusterConfigs are flagged while NodeConfigs report different discovery
+// config hashes
+func TestDiscoveryConfigConsistency(t *testing.T) {
+	g := NewWithT(t)
+
+	scheme := runtime.NewScheme()
+	g.Expect(sriovfecv2.AddToScheme(scheme)).To(Succeed())
+
+	nodeConfig := func(i int, hash string) *sriovfecv2.SriovFecNodeConfig {
+		return &sriovfecv2.SriovFecNodeConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: stressNodeName(i), Namespace: NAMESPACE},
+			Status:     sriovfecv2.SriovFecNodeConfigStatus{DiscoveryConfigHash: hash},
+		}
+	}
+	cc := stressClusterConfig("acc100", "0d5c", 2)
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cc,
+		nodeConfig(0, ""), nodeConfig(1, ""),
+	).Build()
+
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &SriovFecClusterConfigReconciler{Client: c, Log: log, Recorder: recorder}
+
+	getCondition := func() *metav1.Condition {
+		stored := &sriovfecv2.SriovFecClusterConfig{}
+		g.Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: NAMESPACE, Name: cc.Name}, stored)).To(Succeed())
+		return meta.FindStatusCondition(stored.Status.Conditions, conditions.TypeDiscoveryConfigConsistent)
+	}
+	setHash := func(i int, hash string) {
+		nc := &sriovfecv2.SriovFecNodeConfig{}
+		g.Expect(c.Get(context.TODO(), client.ObjectKey{Namespace: NAMESPACE, Name: stressNodeName(i)}, nc)).To(Succeed())
+		nc.Status.DiscoveryConfigHash = hash
+		g.Expect(c.Status().Update(context.TODO(), nc)).To(Succeed())
+	}
+	update := func() {
+		list := &sriovfecv2.SriovFecClusterConfigList{}
+		g.Expect(c.List(context.TODO(), list)).To(Succeed())
+		reconciler.updateDiscoveryConfigCondition(context.TODO(), list.Items)
+	}
+
+	// daemons do not report the hash yet
+	update()
+	g.Expect(getCondition()).To(BeNil())
+
+	setHash(0, "aaaaaaaaaaaaaaaa")
+	update()
+	g.Expect(getCondition()).ToNot(BeNil())
+	g.Expect(getCondition().Status).To(Equal(metav1.ConditionTrue))
+
+	setHash(1, "bbbbbbbbbbbbbbbb")
+	update()
+	condition := getCondition()
+	g.Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+	g.Expect(condition.Reason).To(Equal(string(conditions.ReasonInconsistent)))
+	g.Expect(condition.Message).To(Equal("nodes report 2 different discovery configs: aaaaaaaaaaaa (node-000); bbbbbbbbbbbb (node-001)"))
+	g.Expect(recorder.Events).To(Receive(ContainSubstring(condition.Message)))
+
+	// drift is not re-reported while it lasts
+	update()
+	g.Expect(recorder.Events).ToNot(Receive())
+
+	setHash(1, "aaaaaaaaaaaaaaaa")
+	update()
+	g.Expect(getCondition().Status).To(Equal(metav1.ConditionTrue))
+	g.Expect(recorder.Events).ToNot(Receive())
+}
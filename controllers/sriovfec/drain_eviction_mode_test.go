@@ -0,0 +1,42 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package sriovfec
+
+import (
+	"testing"
+
+	"github.com/elliotchance/orderedmap/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/utils/pointer"
+
+	sriovfecv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+)
+
+// TestBuildNodeConfigMergesDrainEvictionMode checks that any matching config requesting deletion of pods makes the
+// node drained by deletion with the longest grace period, and that these are kept when no accelerator matches
+func TestBuildNodeConfigMergesDrainEvictionMode(t *testing.T) {
+	g := NewWithT(t)
+
+	configs := orderedmap.NewOrderedMap[string, sriovfecv2.SriovFecClusterConfig]()
+	configs.Set("0000:f7:00.0", sriovfecv2.SriovFecClusterConfig{Spec: sriovfecv2.SriovFecClusterConfigSpec{
+		DrainEvictionMode: sriovfecv2.DrainEvictionModeEvict,
+	}})
+	configs.Set("0000:f8:00.0", sriovfecv2.SriovFecClusterConfig{Spec: sriovfecv2.SriovFecClusterConfigSpec{
+		DrainEvictionMode: sriovfecv2.DrainEvictionModeDelete, DrainGracePeriodSeconds: pointer.Int64(5),
+	}})
+	configs.Set("0000:f9:00.0", sriovfecv2.SriovFecClusterConfig{Spec: sriovfecv2.SriovFecClusterConfigSpec{
+		DrainEvictionMode: sriovfecv2.DrainEvictionModeDelete, DrainGracePeriodSeconds: pointer.Int64(30),
+	}})
+
+	nc := buildNodeConfig(NodeConfigurationCtx{AcceleratorConfigContext: configs}, false)
+	g.Expect(nc.Spec.DrainEvictionMode).To(Equal(sriovfecv2.DrainEvictionModeDelete))
+	g.Expect(nc.Spec.DrainGracePeriodSeconds).To(Equal(pointer.Int64(30)))
+
+	nc = buildNodeConfig(NodeConfigurationCtx{
+		SriovFecNodeConfig:       *nc,
+		AcceleratorConfigContext: orderedmap.NewOrderedMap[string, sriovfecv2.SriovFecClusterConfig](),
+	}, false)
+	g.Expect(nc.Spec.DrainEvictionMode).To(Equal(sriovfecv2.DrainEvictionModeDelete))
+	g.Expect(nc.Spec.DrainGracePeriodSeconds).To(Equal(pointer.Int64(30)))
+}
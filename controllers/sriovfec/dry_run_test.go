@@ -0,0 +1,246 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package sriovfec
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sriovfecv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/conditions"
+)
+
+// TestDryRun checks that config with dry-run annotation is only previewed in its status, and that removing the
+// annotation stamps exactly the previewed specs
+func TestDryRun(t *testing.T) {
+	g := NewWithT(t)
+
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	g.Expect(sriovfecv2.AddToScheme(scheme)).To(Succeed())
+
+	dryRun := func(cc *sriovfecv2.SriovFecClusterConfig) *sriovfecv2.SriovFecClusterConfig {
+		cc.Annotations = map[string]string{sriovfecv2.DryRunAnnotation: "true"}
+		return cc
+	}
+	override := dryRun(stressClusterConfig("acc100-override", "0d5c", 8))
+	override.Spec.Priority = 1
+
+	objects := []client.Object{
+		stressClusterConfig("acc100", "0d5c", 2),
+		dryRun(stressClusterConfig("acc200", "57c0", 4)),
+		override,
+	}
+	for i := 0; i < 3; i++ {
+		objects = append(objects,
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{
+				Name:   stressNodeName(i),
+				Labels: map[string]string{"fpga.intel.com/intel-accelerator-present": ""},
+			}},
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "daemon-" + stressNodeName(i), Namespace: NAMESPACE, Labels: daemonPodLabels},
+				Spec:       corev1.PodSpec{NodeName: stressNodeName(i)},
+				Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+			},
+			&sriovfecv2.SriovFecNodeConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: stressNodeName(i), Namespace: NAMESPACE},
+				Spec:       sriovfecv2.SriovFecNodeConfigSpec{PhysicalFunctions: []sriovfecv2.PhysicalFunctionConfigExt{}},
+				Status: sriovfecv2.SriovFecNodeConfigStatus{
+					Inventory: sriovfecv2.NodeInventory{SriovAccelerators: []sriovfecv2.SriovAccelerator{stressAccelerator(i)}},
+				},
+			},
+		)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	reconciler := &SriovFecClusterConfigReconciler{Client: c, Log: log}
+	reconcile := func() {
+		_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{Namespace: NAMESPACE, Name: "acc200"}})
+		g.Expect(err).ToNot(HaveOccurred())
+	}
+	getNodeConfig := func(name string) *sriovfecv2.SriovFecNodeConfig {
+		nc := &sriovfecv2.SriovFecNodeConfig{}
+		g.Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: NAMESPACE, Name: name}, nc)).To(Succeed())
+		return nc
+	}
+	getClusterConfig := func(name string) *sriovfecv2.SriovFecClusterConfig {
+		cc := &sriovfecv2.SriovFecClusterConfig{}
+		g.Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: NAMESPACE, Name: name}, cc)).To(Succeed())
+		return cc
+	}
+
+	reconcile()
+
+	// only the applied config is stamped
+	for _, i := range []int{0, 2} {
+		pfs := getNodeConfig(stressNodeName(i)).Spec.PhysicalFunctions
+		g.Expect(pfs).To(HaveLen(1))
+		g.Expect(pfs[0].VFAmount).To(Equal(2))
+	}
+	g.Expect(getNodeConfig(stressNodeName(1)).Spec.PhysicalFunctions).To(BeEmpty())
+
+	acc200 := getClusterConfig("acc200")
+	condition := meta.FindStatusCondition(acc200.Status.Conditions, conditions.TypeDryRun)
+	g.Expect(condition).ToNot(BeNil())
+	g.Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+	g.Expect(condition.Reason).To(Equal(string(conditions.ReasonPreviewed)))
+	g.Expect(condition.Message).To(Equal("config would change NodeConfigs of 1 node(s); remove sriovfec.intel.com/dry-run annotation to apply it"))
+	g.Expect(acc200.Status.DryRunNodeConfigs).To(HaveLen(1))
+	g.Expect(acc200.Status.DryRunNodeConfigs[0].NodeName).To(Equal(stressNodeName(1)))
+	g.Expect(acc200.Status.DryRunNodeConfigs[0].Spec.PhysicalFunctions).To(HaveLen(1))
+	g.Expect(acc200.Status.DryRunNodeConfigs[0].Spec.PhysicalFunctions[0].VFAmount).To(Equal(4))
+
+	// each dry-run config is previewed against applied configs only
+	previews := getClusterConfig("acc100-override").Status.DryRunNodeConfigs
+	g.Expect(previews).To(HaveLen(2))
+	for i, preview := range previews {
+		g.Expect(preview.NodeName).To(Equal(stressNodeName(2 * i)))
+		g.Expect(preview.Spec.PhysicalFunctions).To(HaveLen(1))
+		g.Expect(preview.Spec.PhysicalFunctions[0].VFAmount).To(Equal(8))
+	}
+	g.Expect(getClusterConfig("acc100").Status.Conditions).To(BeEmpty())
+	g.Expect(getClusterConfig("acc100").Status.DryRunNodeConfigs).To(BeEmpty())
+
+	// removing the annotation stamps the previewed spec
+	acc200.Annotations = nil
+	g.Expect(c.Update(context.TODO(), acc200)).To(Succeed())
+
+	reconcile()
+
+	g.Expect(getNodeConfig(stressNodeName(1)).Spec).To(Equal(acc200.Status.DryRunNodeConfigs[0].Spec))
+	acc200 = getClusterConfig("acc200")
+	g.Expect(acc200.Status.Conditions).To(BeEmpty())
+	g.Expect(acc200.Status.DryRunNodeConfigs).To(BeEmpty())
+	g.Expect(acc200.Annotations).To(HaveKey(sriovfecv2.LastAppliedSpecAnnotation))
+
+	// previewing a change of applied config keeps its last applied spec in effect
+	acc200.Annotations[sriovfecv2.DryRunAnnotation] = "true"
+	acc200.Spec.PhysicalFunction.VFAmount = 6
+	g.Expect(c.Update(context.TODO(), acc200)).To(Succeed())
+
+	reconcile()
+
+	g.Expect(getNodeConfig(stressNodeName(1)).Spec.PhysicalFunctions[0].VFAmount).To(Equal(4))
+	acc200 = getClusterConfig("acc200")
+	g.Expect(acc200.Status.DryRunNodeConfigs).To(HaveLen(1))
+	g.Expect(acc200.Status.DryRunNodeConfigs[0].Spec.PhysicalFunctions[0].VFAmount).To(Equal(6))
+
+	// nodes which NodeConfig would not change are not counted
+	acc200.Spec.PhysicalFunction.VFAmount = 4
+	g.Expect(c.Update(context.TODO(), acc200)).To(Succeed())
+
+	reconcile()
+
+	acc200 = getClusterConfig("acc200")
+	g.Expect(acc200.Status.DryRunNodeConfigs).To(BeEmpty())
+	condition = meta.FindStatusCondition(acc200.Status.Conditions, conditions.TypeDryRun)
+	g.Expect(condition).ToNot(BeNil())
+	g.Expect(condition.Message).To(HavePrefix("config would change NodeConfigs of 0 node(s)"))
+}
+
+// TestDryRunOfAdoptingConfig checks that hand-written NodeConfigs which config adopting them would leave untouched are
+// not previewed, but reported in node decisions
+func TestDryRunOfAdoptingConfig(t *testing.T) {
+	g := NewWithT(t)
+
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	g.Expect(sriovfecv2.AddToScheme(scheme)).To(Succeed())
+
+	acc100 := stressClusterConfig("acc100", "0d5c", 2)
+	acc100.Annotations = map[string]string{sriovfecv2.DryRunAnnotation: "true", sriovfecv2.AdoptExistingNodeConfigsAnnotation: "true"}
+	// applied adopting config keeps the hand-written NodeConfig from being overwritten
+	acc200 := stressClusterConfig("acc200", "57c0", 4)
+	acc200.Annotations = map[string]string{sriovfecv2.AdoptExistingNodeConfigsAnnotation: "true"}
+	objects := []client.Object{acc100, acc200}
+	for _, i := range []int{0, 2} {
+		objects = append(objects,
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{
+				Name:   stressNodeName(i),
+				Labels: map[string]string{"fpga.intel.com/intel-accelerator-present": ""},
+			}},
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "daemon-" + stressNodeName(i), Namespace: NAMESPACE, Labels: daemonPodLabels},
+				Spec:       corev1.PodSpec{NodeName: stressNodeName(i)},
+				Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+			},
+		)
+	}
+	// hand-written on node 0, created empty by the daemon on node 2
+	objects = append(objects,
+		&sriovfecv2.SriovFecNodeConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: stressNodeName(0), Namespace: NAMESPACE},
+			Spec: sriovfecv2.SriovFecNodeConfigSpec{PhysicalFunctions: []sriovfecv2.PhysicalFunctionConfigExt{
+				{PCIAddress: stressAccelerator(0).PCIAddress, PFDriver: "pci-pf-stub", VFDriver: "vfio-pci", VFAmount: 8},
+			}},
+			Status: sriovfecv2.SriovFecNodeConfigStatus{
+				Inventory: sriovfecv2.NodeInventory{SriovAccelerators: []sriovfecv2.SriovAccelerator{stressAccelerator(0)}},
+			},
+		},
+		&sriovfecv2.SriovFecNodeConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: stressNodeName(2), Namespace: NAMESPACE},
+			Spec:       sriovfecv2.SriovFecNodeConfigSpec{PhysicalFunctions: []sriovfecv2.PhysicalFunctionConfigExt{}},
+			Status: sriovfecv2.SriovFecNodeConfigStatus{
+				Inventory: sriovfecv2.NodeInventory{SriovAccelerators: []sriovfecv2.SriovAccelerator{stressAccelerator(2)}},
+			},
+		},
+	)
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	reconciler := &SriovFecClusterConfigReconciler{Client: c, Log: log}
+	_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{Namespace: NAMESPACE, Name: "acc100"}})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	cc := &sriovfecv2.SriovFecClusterConfig{}
+	g.Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: NAMESPACE, Name: "acc100"}, cc)).To(Succeed())
+	g.Expect(cc.Status.DryRunNodeConfigs).To(HaveLen(1))
+	g.Expect(cc.Status.DryRunNodeConfigs[0].NodeName).To(Equal(stressNodeName(2)))
+	g.Expect(cc.Status.NodeDecisions).To(ContainElement(And(
+		HaveField("NodeName", stressNodeName(0)),
+		HaveField("Reason", HavePrefix("NotAdopted: spec of hand-written NodeConfig differs")),
+	)))
+}
+
+func TestDryRunStatusIsCapped(t *testing.T) {
+	g := NewWithT(t)
+
+	cc := sriovfecv2.SriovFecClusterConfig{ObjectMeta: metav1.ObjectMeta{
+		Generation:  3,
+		Annotations: map[string]string{sriovfecv2.DryRunAnnotation: "true"},
+	}}
+	var dryRunNodeConfigs []sriovfecv2.DryRunNodeConfig
+	for i := 0; i < maxDryRunNodeConfigs+2; i++ {
+		dryRunNodeConfigs = append(dryRunNodeConfigs, sriovfecv2.DryRunNodeConfig{NodeName: stressNodeName(i)})
+	}
+
+	status := sriovfecv2.SriovFecClusterConfigStatus{}
+	setDryRunStatus(&status, cc, dryRunNodeConfigs)
+	g.Expect(status.DryRunNodeConfigs).To(Equal(dryRunNodeConfigs[:maxDryRunNodeConfigs]))
+	g.Expect(status.Conditions).To(HaveLen(1))
+	g.Expect(status.Conditions[0].ObservedGeneration).To(Equal(int64(3)))
+	g.Expect(status.Conditions[0].Message).To(HaveSuffix("; only first 10 of them are listed"))
+	g.Expect(status.Conditions[0].Message).To(HavePrefix("config would change NodeConfigs of 12 node(s)"))
+
+	cc.Annotations = nil
+	setDryRunStatus(&status, cc, nil)
+	g.Expect(status.DryRunNodeConfigs).To(BeNil())
+	g.Expect(status.Conditions).To(BeEmpty())
+}
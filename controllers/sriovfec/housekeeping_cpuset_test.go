@@ -0,0 +1,40 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package sriovfec
+
+import (
+	"testing"
+
+	"github.com/elliotchance/orderedmap/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	sriovfecv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+)
+
+// TestBuildNodeConfigUsesHousekeepingCpuSetOfPreferredConfig checks that housekeeping cpuset is taken from the config
+// with the highest priority which sets it, and that it is kept when no accelerator matches
+func TestBuildNodeConfigUsesHousekeepingCpuSetOfPreferredConfig(t *testing.T) {
+	g := NewWithT(t)
+
+	config := func(name string, priority int, cpuset string) sriovfecv2.SriovFecClusterConfig {
+		return sriovfecv2.SriovFecClusterConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       sriovfecv2.SriovFecClusterConfigSpec{Priority: priority, HousekeepingCpuSet: cpuset},
+		}
+	}
+	configs := orderedmap.NewOrderedMap[string, sriovfecv2.SriovFecClusterConfig]()
+	configs.Set("0000:f7:00.0", config("low", 1, "0-1"))
+	configs.Set("0000:f8:00.0", config("high", 5, "2-3"))
+	configs.Set("0000:f9:00.0", config("highest", 10, ""))
+
+	nc := buildNodeConfig(NodeConfigurationCtx{AcceleratorConfigContext: configs}, false)
+	g.Expect(nc.Spec.HousekeepingCpuSet).To(Equal("2-3"))
+
+	nc = buildNodeConfig(NodeConfigurationCtx{
+		SriovFecNodeConfig:       *nc,
+		AcceleratorConfigContext: orderedmap.NewOrderedMap[string, sriovfecv2.SriovFecClusterConfig](),
+	}, false)
+	g.Expect(nc.Spec.HousekeepingCpuSet).To(Equal("2-3"))
+}
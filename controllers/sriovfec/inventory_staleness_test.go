@@ -0,0 +1,124 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package sriovfec
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sriovfecv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+)
+
+// TestInventoryStaleness checks that selectors are resolved only against inventory younger than the staleness bound
+// and that NodeConfig with stale inventory keeps its spec until the daemon reports fresh inventory
+func TestInventoryStaleness(t *testing.T) {
+	g := NewWithT(t)
+
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	g.Expect(sriovfecv2.AddToScheme(scheme)).To(Succeed())
+
+	collectedAt := map[string]time.Time{
+		"fresh":  time.Now().Add(-time.Minute),
+		"stale":  time.Now().Add(-10 * time.Minute),
+		"legacy": {},
+	}
+	objects := []client.Object{stressClusterConfig("acc100", "0d5c", 2)}
+	for name, at := range collectedAt {
+		nc := &sriovfecv2.SriovFecNodeConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: NAMESPACE},
+			Spec:       sriovfecv2.SriovFecNodeConfigSpec{PhysicalFunctions: []sriovfecv2.PhysicalFunctionConfigExt{}},
+			Status: sriovfecv2.SriovFecNodeConfigStatus{
+				Inventory:         sriovfecv2.NodeInventory{SriovAccelerators: []sriovfecv2.SriovAccelerator{stressAccelerator(0)}},
+				InventorySequence: 7,
+			},
+		}
+		if !at.IsZero() {
+			nc.Status.InventoryCollectedAt = &metav1.Time{Time: at}
+		}
+		objects = append(objects,
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: map[string]string{"fpga.intel.com/intel-accelerator-present": ""},
+			}},
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "daemon-" + name, Namespace: NAMESPACE, Labels: daemonPodLabels},
+				Spec:       corev1.PodSpec{NodeName: name},
+				Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+			},
+			nc,
+		)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	reconciler := &SriovFecClusterConfigReconciler{Client: c, Log: log, InventoryStalenessBound: 5 * time.Minute}
+	reconcile := func() {
+		_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{Namespace: NAMESPACE, Name: "acc100"}})
+		g.Expect(err).ToNot(HaveOccurred())
+	}
+	getNodeConfig := func(name string) *sriovfecv2.SriovFecNodeConfig {
+		nc := &sriovfecv2.SriovFecNodeConfig{}
+		g.Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: NAMESPACE, Name: name}, nc)).To(Succeed())
+		return nc
+	}
+
+	reconcile()
+
+	for _, name := range []string{"fresh", "legacy"} {
+		g.Expect(getNodeConfig(name).Spec.PhysicalFunctions).To(HaveLen(1), name)
+	}
+	g.Expect(getNodeConfig("stale").Spec.PhysicalFunctions).To(BeEmpty(), "selectors must not be resolved against stale inventory")
+
+	cc := &sriovfecv2.SriovFecClusterConfig{}
+	g.Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: NAMESPACE, Name: "acc100"}, cc)).To(Succeed())
+	g.Expect(cc.Status.NodeDecisions).To(HaveLen(1))
+	g.Expect(cc.Status.NodeDecisions[0].NodeName).To(Equal("stale"))
+	g.Expect(cc.Status.NodeDecisions[0].Matched).To(BeFalse())
+	g.Expect(cc.Status.NodeDecisions[0].Reason).To(HavePrefix("WaitingForFreshInventory: inventory #7 collected at "))
+	g.Expect(cc.Status.NodeDecisions[0].Reason).To(HaveSuffix(" is older than 5m0s"))
+
+	// daemon reports fresh inventory
+	stale := getNodeConfig("stale")
+	stale.Status.InventoryCollectedAt = &metav1.Time{Time: time.Now()}
+	stale.Status.InventorySequence++
+	g.Expect(c.Status().Update(context.TODO(), stale)).To(Succeed())
+
+	reconcile()
+
+	g.Expect(getNodeConfig("stale").Spec.PhysicalFunctions).To(HaveLen(1))
+	g.Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: NAMESPACE, Name: "acc100"}, cc)).To(Succeed())
+	g.Expect(cc.Status.NodeDecisions).To(BeEmpty())
+}
+
+func TestInventoryStalenessBound(t *testing.T) {
+	g := NewWithT(t)
+
+	nc := &sriovfecv2.SriovFecNodeConfig{Status: sriovfecv2.SriovFecNodeConfigStatus{
+		InventoryCollectedAt: &metav1.Time{Time: time.Now().Add(-time.Hour)},
+	}}
+
+	_, stale := createClusterConfigMatcher(nil, nil, nil, 0, logrus.New()).inventoryStaleness(nc)
+	g.Expect(stale).To(BeFalse())
+
+	_, stale = createClusterConfigMatcher(nil, nil, nil, time.Hour+time.Minute, logrus.New()).inventoryStaleness(nc)
+	g.Expect(stale).To(BeFalse())
+
+	_, stale = createClusterConfigMatcher(nil, nil, nil, time.Minute, logrus.New()).inventoryStaleness(nc)
+	g.Expect(stale).To(BeTrue())
+}
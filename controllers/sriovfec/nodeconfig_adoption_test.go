@@ -0,0 +1,312 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package sriovfec
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/elliotchance/orderedmap/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sriovfecv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+)
+
+func TestNodeConfigSpecDifferences(t *testing.T) {
+	pf := func(pciAddress string, vfAmount int) sriovfecv2.PhysicalFunctionConfigExt {
+		return sriovfecv2.PhysicalFunctionConfigExt{PCIAddress: pciAddress, PFDriver: "vfio-pci", VFDriver: "vfio-pci", VFAmount: vfAmount}
+	}
+	withBBDevConfig := func(pf sriovfecv2.PhysicalFunctionConfigExt, maxQueueSize int) sriovfecv2.PhysicalFunctionConfigExt {
+		pf.BBDevConfig = sriovfecv2.BBDevConfig{ACC100: &sriovfecv2.ACC100BBDevConfig{NumVfBundles: 2, MaxQueueSize: maxQueueSize}}
+		return pf
+	}
+	gracePeriod := func(seconds int64) *int64 {
+		return &seconds
+	}
+	stamped := sriovfecv2.SriovFecNodeConfigSpec{
+		PhysicalFunctions:       []sriovfecv2.PhysicalFunctionConfigExt{withBBDevConfig(pf("0000:14:00.0", 2), 1024), pf("0000:15:00.0", 4)},
+		DrainSkip:               true,
+		DrainGracePeriodSeconds: gracePeriod(30),
+	}
+
+	for name, tc := range map[string]struct {
+		existing    sriovfecv2.SriovFecNodeConfigSpec
+		stamped     sriovfecv2.SriovFecNodeConfigSpec
+		differences []string
+	}{
+		"equal specs": {
+			existing: *stamped.DeepCopy(),
+			stamped:  stamped,
+		},
+		"PFs in different order": {
+			existing: sriovfecv2.SriovFecNodeConfigSpec{
+				PhysicalFunctions:       []sriovfecv2.PhysicalFunctionConfigExt{pf("0000:15:00.0", 4), withBBDevConfig(pf("0000:14:00.0", 2), 1024)},
+				DrainSkip:               true,
+				DrainGracePeriodSeconds: gracePeriod(30),
+			},
+			stamped: stamped,
+		},
+		"nil and empty PFs": {
+			existing: sriovfecv2.SriovFecNodeConfigSpec{},
+			stamped:  sriovfecv2.SriovFecNodeConfigSpec{PhysicalFunctions: []sriovfecv2.PhysicalFunctionConfigExt{}},
+		},
+		"node-wide settings": {
+			existing: sriovfecv2.SriovFecNodeConfigSpec{
+				PhysicalFunctions:       stamped.PhysicalFunctions,
+				DrainGracePeriodSeconds: gracePeriod(60),
+			},
+			stamped:     stamped,
+			differences: []string{"node-wide drainSkip, drainGracePeriodSeconds differ"},
+		},
+		"PF settings": {
+			existing: sriovfecv2.SriovFecNodeConfigSpec{
+				PhysicalFunctions: []sriovfecv2.PhysicalFunctionConfigExt{
+					withBBDevConfig(pf("0000:14:00.0", 2), 512),
+					{PCIAddress: "0000:15:00.0", PFDriver: "vfio-pci", VFDriver: "igb_uio", VFAmount: 8},
+				},
+				DrainSkip:               true,
+				DrainGracePeriodSeconds: gracePeriod(30),
+			},
+			stamped: stamped,
+			differences: []string{
+				"PF 0000:14:00.0 differs in bbDevConfig",
+				"PF 0000:15:00.0 differs in vfDriver, vfAmount",
+			},
+		},
+		"PFs configured by one spec only": {
+			existing: sriovfecv2.SriovFecNodeConfigSpec{
+				PhysicalFunctions: []sriovfecv2.PhysicalFunctionConfigExt{pf("0000:16:00.0", 2), pf("0000:15:00.0", 4)},
+			},
+			stamped: sriovfecv2.SriovFecNodeConfigSpec{
+				PhysicalFunctions: []sriovfecv2.PhysicalFunctionConfigExt{pf("0000:14:00.0", 2), pf("0000:15:00.0", 4)},
+			},
+			differences: []string{
+				"PF 0000:14:00.0 is not configured",
+				"PF 0000:16:00.0 is not selected by any ClusterConfig",
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(nodeConfigSpecDifferences(tc.existing, tc.stamped)).To(Equal(tc.differences))
+		})
+	}
+}
+
+// TestAdoptExistingNodeConfigs checks that config with adopt-existing-nodeconfigs annotation takes over hand-written
+// NodeConfigs equivalent to the stamped ones without changing their spec, and reports the others instead of
+// overwriting them
+func TestAdoptExistingNodeConfigs(t *testing.T) {
+	g := NewWithT(t)
+
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	g.Expect(sriovfecv2.AddToScheme(scheme)).To(Succeed())
+
+	acc100 := stressClusterConfig("acc100", "0d5c", 2)
+	acc100.Annotations = map[string]string{sriovfecv2.AdoptExistingNodeConfigsAnnotation: "true"}
+	handWritten := func(i int, vfAmount int) sriovfecv2.SriovFecNodeConfigSpec {
+		return sriovfecv2.SriovFecNodeConfigSpec{PhysicalFunctions: []sriovfecv2.PhysicalFunctionConfigExt{
+			{PCIAddress: stressAccelerator(i).PCIAddress, PFDriver: "vfio-pci", VFDriver: "vfio-pci", VFAmount: vfAmount},
+		}}
+	}
+	nodeConfigs := map[string]*sriovfecv2.SriovFecNodeConfig{
+		// hand-written, equivalent to the stamped spec
+		stressNodeName(0): {Spec: handWritten(0, 2)},
+		// hand-written, differs from the stamped spec
+		stressNodeName(1): {Spec: handWritten(1, 8)},
+		// created empty by the daemon
+		stressNodeName(2): {Spec: sriovfecv2.SriovFecNodeConfigSpec{PhysicalFunctions: []sriovfecv2.PhysicalFunctionConfigExt{}}},
+		// written by cluster controller of older version, which did not label NodeConfigs
+		stressNodeName(3): {
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{sriovfecv2.WrittenByVersionAnnotation: "2.7.0"}},
+			Spec:       handWritten(3, 8),
+		},
+	}
+
+	objects := []client.Object{acc100, stressClusterConfig("acc200", "57c0", 4)}
+	for i := 0; i < len(nodeConfigs); i++ {
+		nc := nodeConfigs[stressNodeName(i)]
+		nc.Name, nc.Namespace = stressNodeName(i), NAMESPACE
+		nc.Labels = map[string]string{"team": "ran"}
+		nc.Status.Inventory = sriovfecv2.NodeInventory{SriovAccelerators: []sriovfecv2.SriovAccelerator{stressAccelerator(i)}}
+		objects = append(objects,
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{
+				Name:   stressNodeName(i),
+				Labels: map[string]string{"fpga.intel.com/intel-accelerator-present": ""},
+			}},
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "daemon-" + stressNodeName(i), Namespace: NAMESPACE, Labels: daemonPodLabels},
+				Spec:       corev1.PodSpec{NodeName: stressNodeName(i)},
+				Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+			},
+			nc,
+		)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	reconciler := &SriovFecClusterConfigReconciler{Client: c, Log: log}
+	reconcile := func() {
+		_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{Namespace: NAMESPACE, Name: "acc100"}})
+		g.Expect(err).ToNot(HaveOccurred())
+	}
+	getNodeConfig := func(name string) *sriovfecv2.SriovFecNodeConfig {
+		nc := &sriovfecv2.SriovFecNodeConfig{}
+		g.Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: NAMESPACE, Name: name}, nc)).To(Succeed())
+		return nc
+	}
+	getClusterConfig := func(name string) *sriovfecv2.SriovFecClusterConfig {
+		cc := &sriovfecv2.SriovFecClusterConfig{}
+		g.Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: NAMESPACE, Name: name}, cc)).To(Succeed())
+		return cc
+	}
+
+	reconcile()
+
+	// equivalent NodeConfig is taken over without changing its spec
+	adopted := getNodeConfig(stressNodeName(0))
+	g.Expect(adopted.Spec).To(Equal(handWritten(0, 2)))
+	g.Expect(adopted.IsManaged()).To(BeTrue())
+	g.Expect(adopted.Labels).To(HaveKeyWithValue("team", "ran"))
+
+	// different NodeConfig is left untouched, differences are reported in decisions of the adopting config
+	notAdopted := getNodeConfig(stressNodeName(1))
+	g.Expect(notAdopted.Spec).To(Equal(handWritten(1, 8)))
+	g.Expect(notAdopted.IsManaged()).To(BeFalse())
+	g.Expect(getClusterConfig("acc100").Status.NodeDecisions).To(ConsistOf(
+		sriovfecv2.NodeDecision{
+			NodeName: stressNodeName(1),
+			Reason: "no accelerator matched accelerator selector; NotAdopted: spec of hand-written NodeConfig differs - " +
+				"PF 0000:01:00.0 differs in vfAmount",
+		},
+		sriovfecv2.NodeDecision{NodeName: stressNodeName(3), Reason: "no accelerator matched accelerator selector"},
+	))
+	g.Expect(getClusterConfig("acc200").Status.NodeDecisions).To(HaveLen(2))
+
+	// empty and managed NodeConfigs are stamped as usual
+	g.Expect(getNodeConfig(stressNodeName(2)).Spec.PhysicalFunctions).To(ConsistOf(
+		WithTransform(func(pf sriovfecv2.PhysicalFunctionConfigExt) int { return pf.VFAmount }, Equal(2))))
+	g.Expect(getNodeConfig(stressNodeName(2)).IsManaged()).To(BeTrue())
+	g.Expect(getNodeConfig(stressNodeName(3)).Spec.PhysicalFunctions).To(ConsistOf(
+		WithTransform(func(pf sriovfecv2.PhysicalFunctionConfigExt) int { return pf.VFAmount }, Equal(4))))
+	g.Expect(getNodeConfig(stressNodeName(3)).Labels).To(HaveKeyWithValue(sriovfecv2.ManagedByLabel, sriovfecv2.ManagedByClusterController))
+
+	// adopted NodeConfig is not rewritten anymore
+	resourceVersion := adopted.ResourceVersion
+	reconcile()
+	g.Expect(getNodeConfig(stressNodeName(0)).ResourceVersion).To(Equal(resourceVersion))
+
+	// without the annotation, hand-written NodeConfigs are overwritten
+	acc100 = getClusterConfig("acc100")
+	acc100.Annotations = nil
+	g.Expect(c.Update(context.TODO(), acc100)).To(Succeed())
+
+	reconcile()
+
+	g.Expect(getNodeConfig(stressNodeName(1)).Spec.PhysicalFunctions).To(ConsistOf(
+		WithTransform(func(pf sriovfecv2.PhysicalFunctionConfigExt) int { return pf.VFAmount }, Equal(4))))
+	g.Expect(getClusterConfig("acc100").Status.NodeDecisions).To(ConsistOf(
+		sriovfecv2.NodeDecision{NodeName: stressNodeName(1), Reason: "no accelerator matched accelerator selector"},
+		sriovfecv2.NodeDecision{NodeName: stressNodeName(3), Reason: "no accelerator matched accelerator selector"},
+	))
+}
+
+// TestNodeConfigAdoptedByDaemon checks that spec of the node is written into NodeConfig adopted by daemon of the node
+// (matching it by hostname) rather than into NodeConfig named after the node
+func TestNodeConfigAdoptedByDaemon(t *testing.T) {
+	g := NewWithT(t)
+
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	g.Expect(sriovfecv2.AddToScheme(scheme)).To(Succeed())
+
+	nodeName := "worker.example.com"
+	adopted := &sriovfecv2.SriovFecNodeConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "worker",
+			Namespace:   NAMESPACE,
+			Annotations: map[string]string{sriovfecv2.AdoptedByNodeAnnotation: nodeName},
+		},
+		Spec: sriovfecv2.SriovFecNodeConfigSpec{PhysicalFunctions: []sriovfecv2.PhysicalFunctionConfigExt{}},
+	}
+	adopted.Status.Inventory = sriovfecv2.NodeInventory{SriovAccelerators: []sriovfecv2.SriovAccelerator{stressAccelerator(0)}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		stressClusterConfig("acc100", "0d5c", 2),
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{
+			Name:   nodeName,
+			Labels: map[string]string{"fpga.intel.com/intel-accelerator-present": ""},
+		}},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "daemon-worker", Namespace: NAMESPACE, Labels: daemonPodLabels},
+			Spec:       corev1.PodSpec{NodeName: nodeName},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		},
+		adopted,
+	).Build()
+
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	reconciler := &SriovFecClusterConfigReconciler{Client: c, Log: log}
+	_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{Namespace: NAMESPACE, Name: "acc100"}})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	nc := &sriovfecv2.SriovFecNodeConfig{}
+	g.Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: NAMESPACE, Name: "worker"}, nc)).To(Succeed())
+	g.Expect(nc.Spec.PhysicalFunctions).To(ConsistOf(
+		WithTransform(func(pf sriovfecv2.PhysicalFunctionConfigExt) int { return pf.VFAmount }, Equal(2))))
+	g.Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: NAMESPACE, Name: nodeName}, &sriovfecv2.SriovFecNodeConfig{})).
+		ToNot(Succeed())
+}
+
+// TestNodeConfigsStampedBeforeAdoptionAreNotHandWritten covers upgrade from controller versions which neither labeled
+// nor annotated NodeConfigs: NodeConfigs of nodes selected by configs created before the adopting ones were stamped
+func TestNodeConfigsStampedBeforeAdoptionAreNotHandWritten(t *testing.T) {
+	g := NewWithT(t)
+
+	now := time.Now()
+	clusterConfig := func(name string, created time.Time, adopts bool) sriovfecv2.SriovFecClusterConfig {
+		cc := *stressClusterConfig(name, "0d5c", 2)
+		cc.CreationTimestamp = metav1.NewTime(created)
+		if adopts {
+			cc.Annotations = map[string]string{sriovfecv2.AdoptExistingNodeConfigsAnnotation: "true"}
+		}
+		return cc
+	}
+	selecting := func(configs ...sriovfecv2.SriovFecClusterConfig) *orderedmap.OrderedMap[string, sriovfecv2.SriovFecClusterConfig] {
+		acceleratorConfigContext := orderedmap.NewOrderedMap[string, sriovfecv2.SriovFecClusterConfig]()
+		for i, cc := range configs {
+			acceleratorConfigContext.Set(stressAccelerator(2*i).PCIAddress, cc)
+		}
+		return acceleratorConfigContext
+	}
+	// unlabeled NodeConfig with spec, as written by hand or by a controller of old version
+	nc := &sriovfecv2.SriovFecNodeConfig{Spec: sriovfecv2.SriovFecNodeConfigSpec{PhysicalFunctions: []sriovfecv2.PhysicalFunctionConfigExt{
+		{PCIAddress: stressAccelerator(0).PCIAddress, VFAmount: 2},
+	}}}
+	adopting := clusterConfig("adopting", now, true)
+	old := clusterConfig("old", now.Add(-time.Hour), false)
+	recent := clusterConfig("recent", now.Add(time.Hour), false)
+
+	g.Expect(isHandWritten(nc, []sriovfecv2.SriovFecClusterConfig{adopting}, selecting(adopting))).To(BeTrue())
+	g.Expect(isHandWritten(nc, []sriovfecv2.SriovFecClusterConfig{adopting}, selecting(adopting, recent))).To(BeTrue())
+	g.Expect(isHandWritten(nc, []sriovfecv2.SriovFecClusterConfig{adopting}, selecting(adopting, old))).To(BeFalse())
+	// config created before adopting ones adopts NodeConfigs itself
+	oldAdopting := clusterConfig("old-adopting", now.Add(-time.Hour), true)
+	g.Expect(isHandWritten(nc, []sriovfecv2.SriovFecClusterConfig{adopting, oldAdopting}, selecting(oldAdopting))).To(BeTrue())
+
+	g.Expect(isHandWritten(&sriovfecv2.SriovFecNodeConfig{}, []sriovfecv2.SriovFecClusterConfig{adopting}, selecting(adopting))).To(BeFalse())
+}
@@ -0,0 +1,84 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package sriovfec
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sriovfecv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+)
+
+// TestAcceleratorsOfSecondaryNodeConfigsAreNotStamped checks that accelerators configured by secondary node configs
+// of the node are left out of the node config stamped from ClusterConfigs
+func TestAcceleratorsOfSecondaryNodeConfigsAreNotStamped(t *testing.T) {
+	g := NewWithT(t)
+
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	g.Expect(sriovfecv2.AddToScheme(scheme)).To(Succeed())
+
+	nodeName := "worker"
+	primary := &sriovfecv2.SriovFecNodeConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: nodeName, Namespace: NAMESPACE},
+		Spec:       sriovfecv2.SriovFecNodeConfigSpec{PhysicalFunctions: []sriovfecv2.PhysicalFunctionConfigExt{}},
+	}
+	// primary node config reports inventory of the whole node
+	primary.Status.Inventory = sriovfecv2.NodeInventory{SriovAccelerators: []sriovfecv2.SriovAccelerator{stressAccelerator(0), stressAccelerator(2)}}
+	secondary := &sriovfecv2.SriovFecNodeConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: nodeName + "-acc100", Namespace: NAMESPACE},
+		Spec: sriovfecv2.SriovFecNodeConfigSpec{
+			NodeName: nodeName,
+			PhysicalFunctions: []sriovfecv2.PhysicalFunctionConfigExt{
+				{PCIAddress: stressAccelerator(2).PCIAddress, PFDriver: "vfio-pci", VFDriver: "vfio-pci", VFAmount: 8},
+			},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		stressClusterConfig("acc100", "0d5c", 2),
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{
+			Name:   nodeName,
+			Labels: map[string]string{"fpga.intel.com/intel-accelerator-present": ""},
+		}},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "daemon-worker", Namespace: NAMESPACE, Labels: daemonPodLabels},
+			Spec:       corev1.PodSpec{NodeName: nodeName},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		},
+		primary,
+		secondary,
+	).Build()
+
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	reconciler := &SriovFecClusterConfigReconciler{Client: c, Log: log}
+	_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{Namespace: NAMESPACE, Name: "acc100"}})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	nc := &sriovfecv2.SriovFecNodeConfig{}
+	g.Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: NAMESPACE, Name: nodeName}, nc)).To(Succeed())
+	g.Expect(nc.Spec.PhysicalFunctions).To(ConsistOf(HaveField("PCIAddress", stressAccelerator(0).PCIAddress)))
+
+	cc := &sriovfecv2.SriovFecClusterConfig{}
+	g.Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: NAMESPACE, Name: "acc100"}, cc)).To(Succeed())
+	g.Expect(cc.Status.NodeDecisions).To(ConsistOf(sriovfecv2.NodeDecision{
+		NodeName: nodeName,
+		Matched:  true,
+		Reason:   "device 0000:02:00.0 configured by SriovFecNodeConfig 'worker-acc100'",
+	}))
+
+	g.Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: NAMESPACE, Name: secondary.Name}, nc)).To(Succeed())
+	g.Expect(nc.Spec).To(Equal(secondary.Spec))
+}
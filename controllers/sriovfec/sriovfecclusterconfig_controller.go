@@ -21,9 +21,12 @@ package sriovfec
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/elliotchance/orderedmap/v2"
@@ -35,12 +38,23 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	sriovfecv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/conditions"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/syncmetrics"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/vfcapacity"
 )
 
 var NAMESPACE = os.Getenv("SRIOV_FEC_NAMESPACE")
@@ -49,6 +63,26 @@ var NAMESPACE = os.Getenv("SRIOV_FEC_NAMESPACE")
 type SriovFecClusterConfigReconciler struct {
 	client.Client
 	Log *logrus.Logger
+	// MaxConcurrentReconciles is the maximum number of ClusterConfigs reconciled at once; 1 when not set
+	MaxConcurrentReconciles int
+	// InventoryStalenessBound is the maximum age of NodeConfig's inventory which accelerator selectors are resolved
+	// against; NodeConfigs with older inventory are not synchronized until the daemon reports fresh one. 0 disables
+	// the check.
+	InventoryStalenessBound time.Duration
+	// Recorder emits full text of truncated condition messages; events are not emitted when not set
+	Recorder record.EventRecorder
+	// APIReader reads NodeConfigs synchronized under per-node lock bypassing the cache; Client is used when not set
+	APIReader client.Reader
+	// VFCapacityDebounce is quiet period after reconciles the cluster-wide VF capacity is recomputed after;
+	// vfcapacity.DefaultDebounce when not set
+	VFCapacityDebounce time.Duration
+
+	// nodeLocks serializes synchronization of the same NodeConfig by concurrent reconciles
+	nodeLocks utils.KeyedMutex
+	// statusLock serializes writes of node decisions aggregated into ClusterConfigs' status
+	statusLock sync.Mutex
+	// vfCapacity coalesces recomputes of VF capacity requested by reconciles
+	vfCapacity vfcapacity.Debouncer
 }
 
 // +kubebuilder:rbac:groups=sriovfec.intel.com,resources=sriovfecclusterconfigs,verbs=get;list;watch;create;update;patch;delete
@@ -57,64 +91,523 @@ type SriovFecClusterConfigReconciler struct {
 // +kubebuilder:rbac:groups=sriovfec.intel.com,resources=sriovfecnodeconfigs/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups="",resources=nodes,verbs=list;get;watch;update;patch
 // +kubebuilder:rbac:groups="",resources=namespaces;serviceaccounts;secrets;configmaps,verbs=get;list;create;update
+// +kubebuilder:rbac:groups="",resources=configmaps;secrets,verbs=watch
 // +kubebuilder:rbac:groups=apps,resources=daemonsets;deployments;deployments/finalizers,verbs=get;list;create;update
 // +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles;rolebindings;clusterroles;clusterrolebindings,verbs=get;list;create;update
 // +kubebuilder:rbac:groups=security.openshift.io,resources=securitycontextconstraints,verbs=use,resourceNames=privileged
+// +kubebuilder:rbac:groups=machineconfiguration.openshift.io,resources=machineconfigs,verbs=get
 // +kubebuilder:rbac:groups="",resources=pods/eviction,verbs=create
 // +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;create;update;patch;delete
 
-func (r *SriovFecClusterConfigReconciler) Reconcile(_ context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *SriovFecClusterConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	r.Log.Infof("Reconcile(...) triggered by %s", req.NamespacedName.String())
 
 	clusterConfigList := new(sriovfecv2.SriovFecClusterConfigList)
-	if err := r.List(context.TODO(), clusterConfigList, client.InNamespace(NAMESPACE)); err != nil {
+	if err := r.List(ctx, clusterConfigList, client.InNamespace(NAMESPACE)); err != nil {
 		r.Log.WithError(err).Error("cannot obtain list of SriovFecClusterConfig, rescheduling rescheduling reconcile call")
 		return ctrl.Result{}, err
 	}
 
-	nodes, err := r.getAcceleratedNodes()
+	nodes, err := r.getAcceleratedNodes(ctx)
 	if err != nil {
 		r.Log.WithError(err).Info("cannot obtain list of accelerated nodes, rescheduling rescheduling reconcile call")
 		return reconcile.Result{}, err
 	}
 
-	clusterConfigurationMatcher := createClusterConfigMatcher(r.getOrInitializeSriovFecNodeConfig, r.Log)
+	// configs previewed with dry-run annotation are not applied to nodes, the spec they were applied with before stays
+	// in effect
+	appliedConfigs, dryRunConfigs := splitDryRunConfigs(clusterConfigList.Items)
+
+	halted := sriovfecv2.IsConfigurationHalted(appliedConfigs)
+	if halted {
+		r.Log.Info("configuration is halted cluster-wide by SriovFecClusterConfig with spec.disabled")
+	}
+
+	daemonPods, err := r.getDaemonPods(ctx)
+	if err != nil {
+		r.Log.WithError(err).Error("cannot obtain list of daemon pods, nodes without running daemon will not be reported")
+	}
+
+	getNodeConfig := func(nodeName string) (*sriovfecv2.SriovFecNodeConfig, error) {
+		return r.getOrInitializeSriovFecNodeConfig(ctx, nodeName)
+	}
+	getSecondaryNodeConfigs := func(nodeName string) ([]sriovfecv2.SriovFecNodeConfig, error) {
+		return r.getSecondarySriovFecNodeConfigs(ctx, nodeName)
+	}
+	clusterConfigurationMatcher := createClusterConfigMatcher(getNodeConfig, getSecondaryNodeConfigs, daemonPods, r.InventoryStalenessBound, r.Log)
+	requeue := false
 	for _, node := range nodes {
-		configurationContextProvider, err := clusterConfigurationMatcher.match(node, clusterConfigList.Items)
+		if r.synchronizeNode(ctx, clusterConfigurationMatcher, node, appliedConfigs, halted) {
+			requeue = true
+		}
+	}
+	r.recordLastAppliedSpecs(ctx, clusterConfigList.Items)
+
+	nodeDecisions := clusterConfigurationMatcher.nodeDecisions
+	dryRunNodeConfigs := map[string][]sriovfecv2.DryRunNodeConfig{}
+	for _, cc := range dryRunConfigs {
+		dryRunNodeConfigs[cc.Name], nodeDecisions[cc.Name] = r.previewDryRun(ctx, cc, appliedConfigs, nodes, daemonPods, halted)
+	}
+
+	r.updateNodeDecisions(ctx, clusterConfigList.Items, nodeDecisions)
+	r.updateDryRunStatus(ctx, clusterConfigList.Items, dryRunNodeConfigs)
+	r.updateDiscoveryConfigCondition(ctx, clusterConfigList.Items)
+	r.vfCapacity.Trigger(r.VFCapacityDebounce, r.updateVFCapacity)
+
+	if requeue {
+		return ctrl.Result{Requeue: true}, nil
+	}
+	return r.requeueIfClusterConfigExists(ctx, req.NamespacedName)
+}
+
+// synchronizeNode propagates matching ClusterConfigs into NodeConfig of the node; returns true when NodeConfig was
+// modified concurrently and reconcile has to be repeated
+func (r *SriovFecClusterConfigReconciler) synchronizeNode(ctx context.Context, clusterConfigurationMatcher *clusterConfigMatcher, node corev1.Node, clusterConfigs []sriovfecv2.SriovFecClusterConfig, halted bool) bool {
+	unlock := r.nodeLocks.Lock(node.Name)
+	defer unlock()
+
+	configurationContextProvider, err := clusterConfigurationMatcher.match(node, clusterConfigs)
+	if err != nil {
+		r.Log.WithField("node", node.Name).WithField("error", err).Info("Error when matching SriovFecClusterConfigs")
+		syncmetrics.Record(syncmetrics.KindSriovFec, node.Name, err)
+		return false
+	}
+
+	if configurationContextProvider.WaitingForFreshInventory {
+		// existing spec is kept until selectors can be resolved against fresh inventory
+		return false
+	}
+
+	// cache may not reflect the write of the reconcile which held the lock before, spec is built from and written over
+	// the latest NodeConfig
+	if err := r.readLatestNodeConfig(ctx, &configurationContextProvider.SriovFecNodeConfig); err != nil {
+		r.Log.WithField("node", node.Name).WithField("error", err).Info("failed to read the latest SriovFecNodeConfig")
+		syncmetrics.Record(syncmetrics.KindSriovFec, node.Name, err)
+		return false
+	}
+
+	// hand-written NodeConfigs are not overwritten while configs adopting them exist; they are adopted only when their spec
+	// is equivalent to the stamped one
+	if adopting := adoptingConfigs(clusterConfigs); len(adopting) > 0 &&
+		isHandWritten(&configurationContextProvider.SriovFecNodeConfig, adopting, configurationContextProvider.AcceleratorConfigContext) {
+		stamped := buildNodeConfig(*configurationContextProvider, halted)
+		if differences := nodeConfigSpecDifferences(configurationContextProvider.Spec, stamped.Spec); len(differences) > 0 {
+			r.Log.WithField("name", node.Name).WithField("differences", differences).
+				Info("hand-written SriovFecNodeConfig differs from ClusterConfigs - not adopted")
+			clusterConfigurationMatcher.recordNotAdopted(node.Name, adopting, configurationContextProvider.AcceleratorConfigContext, differences)
+			return false
+		}
+		r.Log.WithField("name", node.Name).Info("adopting hand-written SriovFecNodeConfig")
+	}
+
+	if err := r.synchronizeNodeConfigSpec(ctx, *configurationContextProvider, halted); err != nil {
+		if errors.IsConflict(err) {
+			r.Log.WithField("name", node.Name).Info("SriovFecNodeConfig modified concurrently - requeueing")
+			return true
+		}
+		r.Log.WithField("name", node.Name).WithField("error", err).Info("failed to propagate configuration into SriovFecNodeConfig")
+		syncmetrics.Record(syncmetrics.KindSriovFec, node.Name, err)
+
+		err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+			snc := new(sriovfecv2.SriovFecNodeConfig)
+			if err := r.Get(ctx, types.NamespacedName{Namespace: NAMESPACE, Name: configurationContextProvider.Name}, snc); err != nil {
+				return err
+			}
+
+			changed, fullMessage := setConfigurationPropagationConditionFailed(&snc.Status.Conditions, snc.GetGeneration(), err.Error())
+			if !changed {
+				return nil
+			}
+			r.Log.
+				WithField("sfnc", snc).
+				Info("updating svnc status")
+			if err := r.Status().Update(ctx, snc); err != nil {
+				return err
+			}
+			if fullMessage != "" && r.Recorder != nil {
+				r.Recorder.Event(snc, corev1.EventTypeWarning, conditions.TypeConfigurationPropagation, fullMessage)
+			}
+			return nil
+		})
+
 		if err != nil {
-			r.Log.WithField("node", node.Name).WithField("error", err).Info("Error when matching SriovFecClusterConfigs")
+			r.Log.WithError(err).WithField("name", node.Name).Error("failed to set ConfigurationPropagationCondition for SriovFecNodeConfig")
+		}
+		return false
+	}
+	syncmetrics.Record(syncmetrics.KindSriovFec, node.Name, nil)
+	return false
+}
+
+const (
+	maxNodeDecisions         = 50
+	maxDryRunNodeConfigs     = 10
+	daemonNotRunning         = "DaemonNotRunning"
+	waitingForFreshInventory = "WaitingForFreshInventory"
+)
+
+var daemonPodLabels = map[string]string{"app": "sriov-fec-daemonset"}
+
+// updateNodeDecisions writes recomputed node decisions into status of each ClusterConfig
+func (r *SriovFecClusterConfigReconciler) updateNodeDecisions(ctx context.Context, clusterConfigs []sriovfecv2.SriovFecClusterConfig, nodeDecisions map[string][]sriovfecv2.NodeDecision) {
+	r.statusLock.Lock()
+	defer r.statusLock.Unlock()
+
+	for _, cc := range clusterConfigs {
+		decisions := nodeDecisions[cc.Name]
+		sort.Slice(decisions, func(i, j int) bool {
+			return decisions[i].NodeName < decisions[j].NodeName
+		})
+		if len(decisions) > maxNodeDecisions {
+			decisions = decisions[:maxNodeDecisions]
+		}
+
+		if equality.Semantic.DeepEqual(cc.Status.NodeDecisions, decisions) {
 			continue
 		}
 
-		if err := r.synchronizeNodeConfigSpec(*configurationContextProvider); err != nil {
-			r.Log.WithField("name", node.Name).WithField("error", err).Info("failed to propagate configuration into SriovFecNodeConfig")
+		err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+			latest := new(sriovfecv2.SriovFecClusterConfig)
+			if err := r.Get(ctx, client.ObjectKeyFromObject(&cc), latest); err != nil {
+				return err
+			}
+			latest.Status.NodeDecisions = decisions
+			return r.Status().Update(ctx, latest)
+		})
+		if err != nil {
+			r.Log.WithError(err).WithField("name", cc.Name).Error("failed to update node decisions of ClusterConfig")
+		}
+	}
+}
+
+// splitDryRunConfigs separates configs previewed with dry-run annotation from the ones applied to nodes; dry-run configs
+// applied before are applied with their last applied spec, see LastAppliedSpecAnnotation
+func splitDryRunConfigs(clusterConfigs []sriovfecv2.SriovFecClusterConfig) (applied, dryRun []sriovfecv2.SriovFecClusterConfig) {
+	for _, cc := range clusterConfigs {
+		if cc.IsDryRun() {
+			dryRun = append(dryRun, cc)
+			if lastApplied, ok := cc.LastApplied(); ok {
+				applied = append(applied, *lastApplied)
+			}
+		} else {
+			applied = append(applied, cc)
+		}
+	}
+	return applied, dryRun
+}
 
-			err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
-				snc := new(sriovfecv2.SriovFecNodeConfig)
-				if err := r.Get(context.TODO(), types.NamespacedName{Namespace: NAMESPACE, Name: node.Name}, snc); err != nil {
-					return err
-				}
+// recordLastAppliedSpecs stores spec of configs applied to the nodes into their LastAppliedSpecAnnotation
+func (r *SriovFecClusterConfigReconciler) recordLastAppliedSpecs(ctx context.Context, clusterConfigs []sriovfecv2.SriovFecClusterConfig) {
+	for i := range clusterConfigs {
+		cc := &clusterConfigs[i]
+		if cc.IsDryRun() {
+			continue
+		}
+		spec, err := json.Marshal(cc.Spec)
+		if err != nil {
+			r.Log.WithError(err).WithField("name", cc.Name).Error("failed to marshal spec of ClusterConfig")
+			continue
+		}
+		if cc.GetAnnotations()[sriovfecv2.LastAppliedSpecAnnotation] == string(spec) {
+			continue
+		}
+		patch := client.MergeFrom(cc.DeepCopy())
+		if cc.Annotations == nil {
+			cc.Annotations = map[string]string{}
+		}
+		cc.Annotations[sriovfecv2.LastAppliedSpecAnnotation] = string(spec)
+		if err := r.Patch(ctx, cc, patch); err != nil {
+			r.Log.WithError(err).WithField("name", cc.Name).Error("failed to record last applied spec of ClusterConfig")
+		}
+	}
+}
 
-				setConfigurationPropagationConditionFailed(&snc.Status.Conditions, snc.GetGeneration(), err.Error())
-				r.Log.
-					WithField("sfnc", snc).
-					Info("updating svnc status")
-				return r.Status().Update(context.TODO(), snc)
-			})
+// previewDryRun resolves NodeConfigs which dry-run config would result in, if it was applied together with applied
+// configs instead of its last applied spec. Matching, merging and adoption of hand-written NodeConfigs are the same as
+// in synchronization of nodes, but nothing is written into NodeConfigs. Returns NodeConfigs of nodes the config selects
+// accelerators of and which would change, sorted by node name, and node decisions of the config.
+func (r *SriovFecClusterConfigReconciler) previewDryRun(ctx context.Context, cc sriovfecv2.SriovFecClusterConfig, appliedConfigs []sriovfecv2.SriovFecClusterConfig,
+	nodes []corev1.Node, daemonPods map[string]corev1.Pod, halted bool) ([]sriovfecv2.DryRunNodeConfig, []sriovfecv2.NodeDecision) {
 
-			if err != nil {
-				r.Log.WithError(err).WithField("name", node.Name).Error("failed to set ConfigurationPropagationCondition for SriovFecNodeConfig")
+	getNodeConfig := func(nodeName string) (*sriovfecv2.SriovFecNodeConfig, error) {
+		return r.getOrInitializeSriovFecNodeConfig(ctx, nodeName)
+	}
+	getSecondaryNodeConfigs := func(nodeName string) ([]sriovfecv2.SriovFecNodeConfig, error) {
+		return r.getSecondarySriovFecNodeConfigs(ctx, nodeName)
+	}
+	matcher := createClusterConfigMatcher(getNodeConfig, getSecondaryNodeConfigs, daemonPods, r.InventoryStalenessBound, r.Log)
+	var configs []sriovfecv2.SriovFecClusterConfig
+	for _, applied := range appliedConfigs {
+		if applied.Name != cc.Name {
+			configs = append(configs, applied)
+		}
+	}
+	configs = append(configs, cc)
+	adopting := adoptingConfigs(configs)
+
+	var dryRunNodeConfigs []sriovfecv2.DryRunNodeConfig
+	for _, node := range nodes {
+		ncc, err := matcher.match(node, configs)
+		if err != nil {
+			r.Log.WithField("node", node.Name).WithField("error", err).Info("Error when matching SriovFecClusterConfigs for dry-run")
+			continue
+		}
+		if ncc.WaitingForFreshInventory || !selectsAccelerators(ncc.AcceleratorConfigContext, cc.Name) {
+			continue
+		}
+		stamped := buildNodeConfig(*ncc, halted)
+		differences := nodeConfigSpecDifferences(ncc.Spec, stamped.Spec)
+		if len(differences) == 0 {
+			continue
+		}
+		if len(adopting) > 0 && isHandWritten(&ncc.SriovFecNodeConfig, adopting, ncc.AcceleratorConfigContext) {
+			// hand-written NodeConfig which differs would be left untouched
+			matcher.recordNotAdopted(node.Name, adopting, ncc.AcceleratorConfigContext, differences)
+			continue
+		}
+		dryRunNodeConfigs = append(dryRunNodeConfigs, sriovfecv2.DryRunNodeConfig{NodeName: node.Name, Spec: stamped.Spec})
+	}
+	sort.Slice(dryRunNodeConfigs, func(i, j int) bool {
+		return dryRunNodeConfigs[i].NodeName < dryRunNodeConfigs[j].NodeName
+	})
+	return dryRunNodeConfigs, matcher.nodeDecisions[cc.Name]
+}
+
+// selectsAccelerators tells whether config of given name owns any accelerator of the node
+func selectsAccelerators(acceleratorConfigContext *orderedmap.OrderedMap[string, sriovfecv2.SriovFecClusterConfig], name string) bool {
+	for el := acceleratorConfigContext.Front(); el != nil; el = el.Next() {
+		if el.Value.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// updateDryRunStatus writes previewed NodeConfigs and DryRun condition into status of configs with dry-run annotation;
+// both are removed from status of configs without it
+func (r *SriovFecClusterConfigReconciler) updateDryRunStatus(ctx context.Context, clusterConfigs []sriovfecv2.SriovFecClusterConfig, dryRunNodeConfigs map[string][]sriovfecv2.DryRunNodeConfig) {
+	r.statusLock.Lock()
+	defer r.statusLock.Unlock()
+
+	for _, cc := range clusterConfigs {
+		status := cc.Status.DeepCopy()
+		setDryRunStatus(status, cc, dryRunNodeConfigs[cc.Name])
+		if equality.Semantic.DeepEqual(status.DryRunNodeConfigs, cc.Status.DryRunNodeConfigs) &&
+			equality.Semantic.DeepEqual(status.Conditions, cc.Status.Conditions) {
+			continue
+		}
+
+		err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+			latest := new(sriovfecv2.SriovFecClusterConfig)
+			if err := r.Get(ctx, client.ObjectKeyFromObject(&cc), latest); err != nil {
+				return err
 			}
+			setDryRunStatus(&latest.Status, cc, dryRunNodeConfigs[cc.Name])
+			return r.Status().Update(ctx, latest)
+		})
+		if err != nil {
+			r.Log.WithError(err).WithField("name", cc.Name).Error("failed to update dry-run status of ClusterConfig")
+		}
+	}
+}
+
+// setDryRunStatus sets previewed NodeConfigs (at most maxDryRunNodeConfigs) and DryRun condition of dry-run config cc
+// into status, or removes them when cc is not a dry-run config
+func setDryRunStatus(status *sriovfecv2.SriovFecClusterConfigStatus, cc sriovfecv2.SriovFecClusterConfig, dryRunNodeConfigs []sriovfecv2.DryRunNodeConfig) {
+	if !cc.IsDryRun() {
+		status.DryRunNodeConfigs = nil
+		meta.RemoveStatusCondition(&status.Conditions, conditions.TypeDryRun)
+		return
+	}
+
+	msg := fmt.Sprintf("config would change NodeConfigs of %d node(s); remove %s annotation to apply it", len(dryRunNodeConfigs), sriovfecv2.DryRunAnnotation)
+	if len(dryRunNodeConfigs) > maxDryRunNodeConfigs {
+		msg += fmt.Sprintf("; only first %d of them are listed", maxDryRunNodeConfigs)
+		dryRunNodeConfigs = dryRunNodeConfigs[:maxDryRunNodeConfigs]
+	}
+	status.DryRunNodeConfigs = dryRunNodeConfigs
+	conditions.SetIfChanged(&status.Conditions, conditions.DryRun(metav1.ConditionTrue, conditions.ReasonPreviewed, msg, cc.GetGeneration()))
+}
+
+// updateDiscoveryConfigCondition sets DiscoveryConfigConsistent condition of every ClusterConfig, which is false when
+// SriovFecNodeConfigs report different discovery config hashes, e.g. while supported-accelerators ConfigMap is rolled out
+// or when some daemons run with stale config; warning event is emitted when it becomes false
+func (r *SriovFecClusterConfigReconciler) updateDiscoveryConfigCondition(ctx context.Context, clusterConfigs []sriovfecv2.SriovFecClusterConfig) {
+	nodeConfigList := new(sriovfecv2.SriovFecNodeConfigList)
+	if err := r.List(ctx, nodeConfigList, client.InNamespace(NAMESPACE)); err != nil {
+		r.Log.WithError(err).Error("cannot obtain list of SriovFecNodeConfig, discovery config consistency is not updated")
+		return
+	}
+	hashes := map[string]string{}
+	reported := false
+	for _, nc := range nodeConfigList.Items {
+		hashes[nc.Name] = nc.Status.DiscoveryConfigHash
+		reported = reported || nc.Status.DiscoveryConfigHash != ""
+	}
+	drift := utils.DiscoveryConfigDrift(hashes)
+
+	r.statusLock.Lock()
+	defer r.statusLock.Unlock()
+
+	for _, cc := range clusterConfigs {
+		status := cc.Status.DeepCopy()
+		if !setDiscoveryConfigCondition(status, cc.GetGeneration(), reported, drift) {
 			continue
 		}
+
+		err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+			latest := new(sriovfecv2.SriovFecClusterConfig)
+			if err := r.Get(ctx, client.ObjectKeyFromObject(&cc), latest); err != nil {
+				return err
+			}
+			setDiscoveryConfigCondition(&latest.Status, latest.GetGeneration(), reported, drift)
+			return r.Status().Update(ctx, latest)
+		})
+		if err != nil {
+			r.Log.WithError(err).WithField("name", cc.Name).Error("failed to update discovery config consistency of ClusterConfig")
+			continue
+		}
+		if drift != "" && r.Recorder != nil {
+			r.Recorder.Event(&cc, corev1.EventTypeWarning, conditions.TypeDiscoveryConfigConsistent, drift)
+		}
+	}
+}
+
+// setDiscoveryConfigCondition sets DiscoveryConfigConsistent condition into status by drift of discovery configs
+// described by utils.DiscoveryConfigDrift; the condition is removed while no node reports the hash. Returns false when
+// status is left as it was.
+func setDiscoveryConfigCondition(status *sriovfecv2.SriovFecClusterConfigStatus, generation int64, reported bool, drift string) bool {
+	if !reported {
+		if meta.FindStatusCondition(status.Conditions, conditions.TypeDiscoveryConfigConsistent) == nil {
+			return false
+		}
+		meta.RemoveStatusCondition(&status.Conditions, conditions.TypeDiscoveryConfigConsistent)
+		return true
 	}
+	if drift != "" {
+		return conditions.SetIfChanged(&status.Conditions,
+			conditions.DiscoveryConfigConsistent(metav1.ConditionFalse, conditions.ReasonInconsistent, drift, generation))
+	}
+	return conditions.SetIfChanged(&status.Conditions, conditions.DiscoveryConfigConsistent(metav1.ConditionTrue,
+		conditions.ReasonConsistent, "all nodes report the same discovery config", generation))
+}
+
+// updateVFCapacity aggregates VFs of accelerated nodes and the ones allocated to pods into metrics and status of each
+// ClusterConfig; status is written only when the capacity changes
+func (r *SriovFecClusterConfigReconciler) updateVFCapacity() {
+	capacity, err := r.aggregateVFCapacity(context.TODO())
+	if err != nil {
+		r.Log.WithError(err).Error("failed to aggregate VF capacity")
+		return
+	}
+	vfcapacity.Record(syncmetrics.KindSriovFec, capacity)
 
-	return r.requeueIfClusterConfigExists(req.NamespacedName)
+	r.statusLock.Lock()
+	defer r.statusLock.Unlock()
+
+	clusterConfigList := new(sriovfecv2.SriovFecClusterConfigList)
+	if err := r.List(context.TODO(), clusterConfigList, client.InNamespace(NAMESPACE)); err != nil {
+		r.Log.WithError(err).Error("cannot obtain list of SriovFecClusterConfig, VF capacity is not updated")
+		return
+	}
+
+	vfCapacity := toVFCapacity(capacity, metav1.Now())
+	for _, cc := range clusterConfigList.Items {
+		if sameVFCapacity(cc.Status.VFCapacity, vfCapacity) {
+			continue
+		}
+
+		err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+			latest := new(sriovfecv2.SriovFecClusterConfig)
+			if err := r.Get(context.TODO(), client.ObjectKeyFromObject(&cc), latest); err != nil {
+				return err
+			}
+			latest.Status.VFCapacity = vfCapacity.DeepCopy()
+			return r.Status().Update(context.TODO(), latest)
+		})
+		if err != nil {
+			r.Log.WithError(err).WithField("name", cc.Name).Error("failed to update VF capacity of ClusterConfig")
+		}
+	}
 }
 
-func (r *SriovFecClusterConfigReconciler) requeueIfClusterConfigExists(cc types.NamespacedName) (ctrl.Result, error) {
+// aggregateVFCapacity counts VFs of accelerated nodes from inventory of their NodeConfigs, VFs of nodes without
+// inventory from node allocatable
+func (r *SriovFecClusterConfigReconciler) aggregateVFCapacity(ctx context.Context) (vfcapacity.Capacity, error) {
+	nodes, err := r.getAcceleratedNodes(ctx)
+	if err != nil {
+		return vfcapacity.Capacity{}, fmt.Errorf("cannot obtain list of accelerated nodes - %v", err)
+	}
+
+	nodeConfigList := new(sriovfecv2.SriovFecNodeConfigList)
+	if err := r.List(ctx, nodeConfigList, client.InNamespace(NAMESPACE)); err != nil {
+		return vfcapacity.Capacity{}, fmt.Errorf("cannot obtain list of SriovFecNodeConfig - %v", err)
+	}
+	inventories := map[string]sriovfecv2.NodeInventory{}
+	for _, nc := range nodeConfigList.Items {
+		inventories[nc.Name] = nc.Status.Inventory
+	}
+
+	resources, err := r.getDevicePluginResources(ctx)
+	if err != nil {
+		r.Log.WithError(err).Warn("failed to read device plugin config - VF capacity is not broken down by resource")
+	}
+
+	capacityNodes := make([]vfcapacity.Node, 0, len(nodes))
+	for _, node := range nodes {
+		capacityNode := vfcapacity.Node{Name: node.Name, Allocatable: node.Status.Allocatable}
+		for _, acc := range inventories[node.Name].SriovAccelerators {
+			for _, vf := range acc.VFs {
+				capacityNode.VFs = append(capacityNode.VFs, vfcapacity.VF{
+					Resource: utils.ResourceOfVF(resources, acc.VendorID, vf.DeviceID, vf.Driver),
+					UsedBy:   vf.UsedBy,
+				})
+			}
+		}
+		capacityNodes = append(capacityNodes, capacityNode)
+	}
+	return vfcapacity.Aggregate(capacityNodes), nil
+}
+
+func (r *SriovFecClusterConfigReconciler) getDevicePluginResources(ctx context.Context) ([]utils.DevicePluginResource, error) {
+	cm := new(corev1.ConfigMap)
+	if err := r.Get(ctx, client.ObjectKey{Namespace: NAMESPACE, Name: utils.DevicePluginConfigMapName}, cm); err != nil {
+		return nil, err
+	}
+	return utils.ParseDevicePluginResources(cm.Data)
+}
+
+func toVFCapacity(capacity vfcapacity.Capacity, now metav1.Time) *sriovfecv2.VFCapacity {
+	vfCapacity := &sriovfecv2.VFCapacity{
+		TotalVFs:              capacity.Total,
+		AllocatedVFs:          capacity.Allocated,
+		NodesWithoutInventory: capacity.NodesWithoutInventory,
+		NodesWithoutUsage:     capacity.NodesWithoutUsage,
+		LastUpdated:           now,
+	}
+	for _, resource := range capacity.Resources {
+		vfCapacity.Resources = append(vfCapacity.Resources, sriovfecv2.ResourceVFCapacity{
+			ResourceName: resource.Name,
+			TotalVFs:     resource.Total,
+			AllocatedVFs: resource.Allocated,
+		})
+	}
+	return vfCapacity
+}
+
+// sameVFCapacity compares capacities regardless of the time they were aggregated at
+func sameVFCapacity(current, updated *sriovfecv2.VFCapacity) bool {
+	if current == nil {
+		return false
+	}
+	c := current.DeepCopy()
+	c.LastUpdated = updated.LastUpdated
+	return equality.Semantic.DeepEqual(c, updated)
+}
+
+func (r *SriovFecClusterConfigReconciler) requeueIfClusterConfigExists(ctx context.Context, cc types.NamespacedName) (ctrl.Result, error) {
 	sfcc := &sriovfecv2.SriovFecClusterConfig{}
-	err := r.Get(context.TODO(), cc, sfcc)
+	err := r.Get(ctx, cc, sfcc)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			return ctrl.Result{}, nil
@@ -125,7 +618,27 @@ func (r *SriovFecClusterConfigReconciler) requeueIfClusterConfigExists(cc types.
 	return ctrl.Result{RequeueAfter: time.Minute}, nil
 }
 
-func (r *SriovFecClusterConfigReconciler) synchronizeNodeConfigSpec(ncc NodeConfigurationCtx) error {
+func (r *SriovFecClusterConfigReconciler) synchronizeNodeConfigSpec(ctx context.Context, ncc NodeConfigurationCtx, halted bool) error {
+	currentNodeConfig := ncc.SriovFecNodeConfig
+	newNodeConfig := buildNodeConfig(ncc, halted)
+	// equivalent spec is not rewritten (e.g. with PFs reordered), so that generation is not bumped and the node is not
+	// reconfigured; NodeConfigs are adopted this way too
+	if len(nodeConfigSpecDifferences(currentNodeConfig.Spec, newNodeConfig.Spec)) == 0 {
+		newNodeConfig.Spec = currentNodeConfig.Spec
+	}
+
+	if !equality.Semantic.DeepEqual(newNodeConfig.Spec, currentNodeConfig.Spec) ||
+		!equality.Semantic.DeepEqual(newNodeConfig.GetAnnotations(), currentNodeConfig.GetAnnotations()) ||
+		!equality.Semantic.DeepEqual(newNodeConfig.GetLabels(), currentNodeConfig.GetLabels()) {
+		r.Log.Info("Node Config Changed")
+		return r.Update(ctx, newNodeConfig)
+	}
+	return nil
+}
+
+// buildNodeConfig merges configs matched to accelerators of the node into its NodeConfig, as it has to be stamped.
+// Both synchronization and dry-run preview use it, so the preview cannot diverge from the applied spec.
+func buildNodeConfig(ncc NodeConfigurationCtx, halted bool) *sriovfecv2.SriovFecNodeConfig {
 	copyWithEmptySpec := func(nc sriovfecv2.SriovFecNodeConfig) *sriovfecv2.SriovFecNodeConfig {
 		newNC := nc.DeepCopy()
 		newNC.Spec = sriovfecv2.SriovFecNodeConfigSpec{
@@ -134,54 +647,195 @@ func (r *SriovFecClusterConfigReconciler) synchronizeNodeConfigSpec(ncc NodeConf
 		return newNC
 	}
 
-	currentNodeConfig := ncc.SriovFecNodeConfig
 	acceleratorConfigContext := ncc.AcceleratorConfigContext
 
 	newNodeConfig := copyWithEmptySpec(ncc.SriovFecNodeConfig)
 
+	// configs which hooks of the node are taken from
+	var preConfigureHookOwner, postConfigureHookOwner *sriovfecv2.SriovFecClusterConfig
+	// config which housekeeping cpuset of the node is taken from; it is node-wide too
+	var housekeepingCpuSetOwner *sriovfecv2.SriovFecClusterConfig
+
 	// Use orederedmap for iteration
 	for _, pciAddress := range acceleratorConfigContext.Keys() {
 		cc, _ := acceleratorConfigContext.Get(pciAddress)
 		pf := sriovfecv2.PhysicalFunctionConfigExt{
-			PCIAddress:  pciAddress,
-			PFDriver:    cc.Spec.PhysicalFunction.PFDriver,
-			VFDriver:    cc.Spec.PhysicalFunction.VFDriver,
-			VFAmount:    cc.Spec.PhysicalFunction.VFAmount,
-			BBDevConfig: cc.Spec.PhysicalFunction.BBDevConfig,
+			PCIAddress:        pciAddress,
+			PFDriver:          cc.Spec.PhysicalFunction.PFDriver,
+			VFDriver:          cc.Spec.PhysicalFunction.VFDriver,
+			VFAmount:          cc.Spec.PhysicalFunction.VFAmount,
+			BBDevConfig:       cc.Spec.EffectiveBBDevConfig(),
+			BBDevConfigFrom:   cc.Spec.PhysicalFunction.BBDevConfigFrom.DeepCopy(),
+			LogicalNamePrefix: cc.Spec.PhysicalFunction.LogicalNamePrefix,
 		}
 		newNodeConfig.Spec.DrainSkip = newNodeConfig.Spec.DrainSkip || cc.Spec.DrainSkip
+		newNodeConfig.Spec.ForceVfRemoval = newNodeConfig.Spec.ForceVfRemoval || cc.Spec.ForceVfRemoval
+		newNodeConfig.Spec.AdoptExistingConfig = newNodeConfig.Spec.AdoptExistingConfig || cc.Spec.AdoptExistingConfig
+		newNodeConfig.Spec.AutoRebootOnPersistentFailure = sriovfecv2.MergeAutoRebootPolicies(newNodeConfig.Spec.AutoRebootOnPersistentFailure, cc.Spec.AutoRebootOnPersistentFailure)
+		// any matching config relaxing compatibility checks relaxes them for the whole node
+		if cc.Spec.EnforceCompatibilityChecks != nil && !*cc.Spec.EnforceCompatibilityChecks {
+			newNodeConfig.Spec.EnforceCompatibilityChecks = cc.Spec.EnforceCompatibilityChecks
+		}
+		// the longest debounce of matching configs is used for the whole node
+		if cc.Spec.ConfigurationDebounce != nil && (newNodeConfig.Spec.ConfigurationDebounce == nil ||
+			cc.Spec.ConfigurationDebounce.Duration > newNodeConfig.Spec.ConfigurationDebounce.Duration) {
+			newNodeConfig.Spec.ConfigurationDebounce = cc.Spec.ConfigurationDebounce.DeepCopy()
+		}
+		// any matching config requesting deletion of pods drains the node by deletion, with the longest grace period
+		if cc.Spec.DrainEvictionMode == sriovfecv2.DrainEvictionModeDelete {
+			newNodeConfig.Spec.DrainEvictionMode = cc.Spec.DrainEvictionMode
+		}
+		if cc.Spec.DrainGracePeriodSeconds != nil && (newNodeConfig.Spec.DrainGracePeriodSeconds == nil ||
+			*cc.Spec.DrainGracePeriodSeconds > *newNodeConfig.Spec.DrainGracePeriodSeconds) {
+			gracePeriod := *cc.Spec.DrainGracePeriodSeconds
+			newNodeConfig.Spec.DrainGracePeriodSeconds = &gracePeriod
+		}
+		// the node gives up configuration after the fewest retries any matching config allows
+		if cc.Spec.MaxConfigurationRetries != nil && (newNodeConfig.Spec.MaxConfigurationRetries == nil ||
+			*cc.Spec.MaxConfigurationRetries < *newNodeConfig.Spec.MaxConfigurationRetries) {
+			maxRetries := *cc.Spec.MaxConfigurationRetries
+			newNodeConfig.Spec.MaxConfigurationRetries = &maxRetries
+		}
+		// so is the longest soak
+		if cc.Spec.PostConfigureSoakSeconds > newNodeConfig.Spec.PostConfigureSoakSeconds {
+			newNodeConfig.Spec.PostConfigureSoakSeconds = cc.Spec.PostConfigureSoakSeconds
+		}
+		if cc.Spec.HousekeepingCpuSet != "" && prefersHookOf(cc, housekeepingCpuSetOwner) {
+			owner := cc
+			housekeepingCpuSetOwner = &owner
+			newNodeConfig.Spec.HousekeepingCpuSet = cc.Spec.HousekeepingCpuSet
+		}
+		if cc.Spec.PreConfigureHook != nil && prefersHookOf(cc, preConfigureHookOwner) {
+			owner := cc
+			preConfigureHookOwner = &owner
+			newNodeConfig.Spec.PreConfigureHook = cc.Spec.PreConfigureHook.DeepCopy()
+		}
+		if cc.Spec.PostConfigureHook != nil && prefersHookOf(cc, postConfigureHookOwner) {
+			owner := cc
+			postConfigureHookOwner = &owner
+			newNodeConfig.Spec.PostConfigureHook = cc.Spec.PostConfigureHook.DeepCopy()
+		}
 		newNodeConfig.Spec.PhysicalFunctions = append(newNodeConfig.Spec.PhysicalFunctions, pf)
 	}
 
 	// copy latest known drainSkip from NodeConfig for cleanup
 	if acceleratorConfigContext.Len() == 0 {
 		newNodeConfig.Spec.DrainSkip = ncc.Spec.DrainSkip
+		newNodeConfig.Spec.ForceVfRemoval = ncc.Spec.ForceVfRemoval
+		newNodeConfig.Spec.DrainEvictionMode = ncc.Spec.DrainEvictionMode
+		newNodeConfig.Spec.DrainGracePeriodSeconds = ncc.Spec.DrainGracePeriodSeconds
+		newNodeConfig.Spec.MaxConfigurationRetries = ncc.Spec.MaxConfigurationRetries
+		newNodeConfig.Spec.HousekeepingCpuSet = ncc.Spec.HousekeepingCpuSet
+		newNodeConfig.Spec.EnforceCompatibilityChecks = ncc.Spec.EnforceCompatibilityChecks
+		newNodeConfig.Spec.ConfigurationDebounce = ncc.Spec.ConfigurationDebounce
+		newNodeConfig.Spec.PostConfigureSoakSeconds = ncc.Spec.PostConfigureSoakSeconds
+		newNodeConfig.Spec.AdoptExistingConfig = ncc.Spec.AdoptExistingConfig
+		newNodeConfig.Spec.AutoRebootOnPersistentFailure = ncc.Spec.AutoRebootOnPersistentFailure
+		newNodeConfig.Spec.PreConfigureHook = ncc.Spec.PreConfigureHook
+		newNodeConfig.Spec.PostConfigureHook = ncc.Spec.PostConfigureHook
 	}
 
-	if !equality.Semantic.DeepEqual(newNodeConfig.Spec, currentNodeConfig.Spec) {
-		r.Log.Info("Node Config Changed")
-		return r.Update(context.TODO(), newNodeConfig)
+	setConfigurationHaltedAnnotation(newNodeConfig, halted)
+	setWrittenByVersionAnnotation(newNodeConfig)
+	setManagedByLabel(newNodeConfig)
+	return newNodeConfig
+}
+
+// prefersHookOf tells whether hook of cc takes precedence over the one of owner; nil owner defines no hook. Hooks are
+// node-wide, so the config with the highest priority wins and ties are resolved by name, independently of PF order.
+// The same applies to other node-wide settings which cannot be merged, e.g. housekeeping cpuset.
+func prefersHookOf(cc sriovfecv2.SriovFecClusterConfig, owner *sriovfecv2.SriovFecClusterConfig) bool {
+	return owner == nil || cc.Spec.Priority > owner.Spec.Priority ||
+		cc.Spec.Priority == owner.Spec.Priority && cc.Name < owner.Name
+}
+
+func setConfigurationHaltedAnnotation(nc *sriovfecv2.SriovFecNodeConfig, halted bool) {
+	annotations := nc.GetAnnotations()
+	if halted {
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[sriovfecv2.ConfigurationHaltedAnnotation] = "true"
+	} else {
+		delete(annotations, sriovfecv2.ConfigurationHaltedAnnotation)
 	}
-	return nil
+	nc.SetAnnotations(annotations)
 }
 
-func (r *SriovFecClusterConfigReconciler) getAcceleratedNodes() ([]corev1.Node, error) {
+// setWrittenByVersionAnnotation records version of the operator writing the node config, so that daemons of older
+// version (e.g. after rollback) do not act on its spec
+func setWrittenByVersionAnnotation(nc *sriovfecv2.SriovFecNodeConfig) {
+	annotations := nc.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[sriovfecv2.WrittenByVersionAnnotation] = utils.OperatorVersion
+	nc.SetAnnotations(annotations)
+}
+
+// getDaemonPods returns daemon pods by name of the node they are scheduled on; running pod is preferred when there are
+// more of them on single node (e.g. during DaemonSet update)
+func (r *SriovFecClusterConfigReconciler) getDaemonPods(ctx context.Context) (map[string]corev1.Pod, error) {
+	pl := new(corev1.PodList)
+	if err := r.List(ctx, pl, client.InNamespace(NAMESPACE), client.MatchingLabels(daemonPodLabels)); err != nil {
+		return nil, err
+	}
+
+	pods := map[string]corev1.Pod{}
+	for _, pod := range pl.Items {
+		if pod.Spec.NodeName == "" {
+			continue
+		}
+		if current, ok := pods[pod.Spec.NodeName]; !ok || current.Status.Phase != corev1.PodRunning {
+			pods[pod.Spec.NodeName] = pod
+		}
+	}
+	return pods, nil
+}
+
+func (r *SriovFecClusterConfigReconciler) getAcceleratedNodes(ctx context.Context) ([]corev1.Node, error) {
 	nl := new(corev1.NodeList)
 	labelsToMatch := &client.MatchingLabels{
 		"fpga.intel.com/intel-accelerator-present": "",
 	}
-	if err := r.List(context.TODO(), nl, labelsToMatch); err != nil {
+	if err := r.List(ctx, nl, labelsToMatch); err != nil {
 		return nil, err
 	}
 	return nl.Items, nil
 }
 
-func (r *SriovFecClusterConfigReconciler) getOrInitializeSriovFecNodeConfig(name string) (*sriovfecv2.SriovFecNodeConfig, error) {
+// readLatestNodeConfig re-reads NodeConfig from API server; NodeConfig which does not exist is left as it is
+func (r *SriovFecClusterConfigReconciler) readLatestNodeConfig(ctx context.Context, nc *sriovfecv2.SriovFecNodeConfig) error {
+	reader := r.APIReader
+	if reader == nil {
+		reader = r.Client
+	}
+	latest := new(sriovfecv2.SriovFecNodeConfig)
+	if err := reader.Get(ctx, client.ObjectKeyFromObject(nc), latest); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	*nc = *latest
+	return nil
+}
+
+// getOrInitializeSriovFecNodeConfig returns SriovFecNodeConfig of the node; node config adopted by daemon of the node
+// (see sriovfecv2.AdoptedByNodeAnnotation) is returned when there is none named after the node
+func (r *SriovFecClusterConfigReconciler) getOrInitializeSriovFecNodeConfig(ctx context.Context, name string) (*sriovfecv2.SriovFecNodeConfig, error) {
 	nc := new(sriovfecv2.SriovFecNodeConfig)
-	if err := r.Get(context.TODO(), client.ObjectKey{Name: name, Namespace: NAMESPACE}, nc); err != nil {
+	if err := r.Get(ctx, client.ObjectKey{Name: name, Namespace: NAMESPACE}, nc); err != nil {
 		if !errors.IsNotFound(err) {
 			return nil, err
 		}
+		adopted, err := r.getAdoptedSriovFecNodeConfig(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if adopted != nil {
+			return adopted, nil
+		}
 		nc.Name = name
 		nc.Namespace = NAMESPACE
 		nc.Spec.PhysicalFunctions = []sriovfecv2.PhysicalFunctionConfigExt{}
@@ -189,53 +843,168 @@ func (r *SriovFecClusterConfigReconciler) getOrInitializeSriovFecNodeConfig(name
 	return nc, nil
 }
 
+// getSecondarySriovFecNodeConfigs returns secondary SriovFecNodeConfigs targeting the node
+func (r *SriovFecClusterConfigReconciler) getSecondarySriovFecNodeConfigs(ctx context.Context, nodeName string) ([]sriovfecv2.SriovFecNodeConfig, error) {
+	ncl := new(sriovfecv2.SriovFecNodeConfigList)
+	if err := r.List(ctx, ncl, client.InNamespace(NAMESPACE)); err != nil {
+		return nil, err
+	}
+	var secondary []sriovfecv2.SriovFecNodeConfig
+	for _, nc := range ncl.Items {
+		if nc.IsSecondary() && nc.Spec.NodeName == nodeName {
+			secondary = append(secondary, nc)
+		}
+	}
+	return secondary, nil
+}
+
+// getAdoptedSriovFecNodeConfig returns SriovFecNodeConfig adopted by daemon of the node, nil when there is none
+func (r *SriovFecClusterConfigReconciler) getAdoptedSriovFecNodeConfig(ctx context.Context, nodeName string) (*sriovfecv2.SriovFecNodeConfig, error) {
+	ncl := new(sriovfecv2.SriovFecNodeConfigList)
+	if err := r.List(ctx, ncl, client.InNamespace(NAMESPACE)); err != nil {
+		return nil, err
+	}
+	for i := range ncl.Items {
+		if ncl.Items[i].GetAnnotations()[sriovfecv2.AdoptedByNodeAnnotation] == nodeName {
+			return &ncl.Items[i], nil
+		}
+	}
+	return nil, nil
+}
+
 func (r *SriovFecClusterConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	// Add NodeConfigs & DaemonSet
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&sriovfecv2.SriovFecClusterConfig{}).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
+		Watches(&source.Kind{Type: &sriovfecv2.SriovFecNodeConfig{}}, handler.EnqueueRequestsFromMapFunc(r.triggerVFCapacityUpdate),
+			builder.WithPredicates(predicate.Funcs{UpdateFunc: func(e event.UpdateEvent) bool {
+				return !equality.Semantic.DeepEqual(e.ObjectOld.(*sriovfecv2.SriovFecNodeConfig).Status.Inventory, e.ObjectNew.(*sriovfecv2.SriovFecNodeConfig).Status.Inventory)
+			}})).
+		// discovery config consistency is re-evaluated when daemons report hash of the discovery config they use
+		Watches(&source.Kind{Type: &sriovfecv2.SriovFecNodeConfig{}}, &handler.EnqueueRequestForObject{},
+			builder.WithPredicates(predicate.Funcs{
+				CreateFunc: func(event.CreateEvent) bool { return false },
+				UpdateFunc: func(e event.UpdateEvent) bool {
+					return e.ObjectOld.(*sriovfecv2.SriovFecNodeConfig).Status.DiscoveryConfigHash != e.ObjectNew.(*sriovfecv2.SriovFecNodeConfig).Status.DiscoveryConfigHash
+				},
+				GenericFunc: func(event.GenericEvent) bool { return false },
+			})).
+		Watches(&source.Kind{Type: &corev1.Node{}}, handler.EnqueueRequestsFromMapFunc(r.triggerVFCapacityUpdate),
+			builder.WithPredicates(predicate.Funcs{UpdateFunc: func(e event.UpdateEvent) bool {
+				oldNode, newNode := e.ObjectOld.(*corev1.Node), e.ObjectNew.(*corev1.Node)
+				return !equality.Semantic.DeepEqual(oldNode.Labels, newNode.Labels) ||
+					!equality.Semantic.DeepEqual(oldNode.Status.Allocatable, newNode.Status.Allocatable)
+			}})).
 		Complete(r)
 }
 
+// triggerVFCapacityUpdate recomputes VF capacity when inventory of NodeConfigs or allocatable of nodes change in between
+// reconciles of ClusterConfigs; nothing is enqueued
+func (r *SriovFecClusterConfigReconciler) triggerVFCapacityUpdate(client.Object) []reconcile.Request {
+	r.vfCapacity.Trigger(r.VFCapacityDebounce, r.updateVFCapacity)
+	return nil
+}
+
 // key: accelerator pciAddress
 type NodeConfigurationCtx struct {
 	sriovfecv2.SriovFecNodeConfig
 	AcceleratorConfigContext *orderedmap.OrderedMap[string, sriovfecv2.SriovFecClusterConfig]
+	// WaitingForFreshInventory is set when inventory of the NodeConfig is too old to resolve accelerator selectors,
+	// AcceleratorConfigContext is empty then
+	WaitingForFreshInventory bool
 }
 
-func createClusterConfigMatcher(ncp nodeConfigProvider, l *logrus.Logger) *clusterConfigMatcher {
+func createClusterConfigMatcher(ncp nodeConfigProvider, sncp secondaryNodeConfigsProvider, daemonPods map[string]corev1.Pod, stalenessBound time.Duration, l *logrus.Logger) *clusterConfigMatcher {
 	return &clusterConfigMatcher{
-		getNodeConfig: ncp,
-		log:           l,
+		getNodeConfig:           ncp,
+		getSecondaryNodeConfigs: sncp,
+		daemonPods:              daemonPods,
+		stalenessBound:          stalenessBound,
+		now:                     time.Now,
+		log:                     l,
+		nodeDecisions:           map[string][]sriovfecv2.NodeDecision{},
 	}
 }
 
 type nodeConfigProvider func(nodeName string) (*sriovfecv2.SriovFecNodeConfig, error)
 
+// secondaryNodeConfigsProvider returns secondary node configs targeting the node, see SriovFecNodeConfig.IsSecondary
+type secondaryNodeConfigsProvider func(nodeName string) ([]sriovfecv2.SriovFecNodeConfig, error)
+
 type clusterConfigMatcher struct {
 	getNodeConfig nodeConfigProvider
-	log           *logrus.Logger
+	// accelerators configured by secondary node configs are left out of the node config; nil when not checked
+	getSecondaryNodeConfigs secondaryNodeConfigsProvider
+	// key: node name; nil when daemon pods are unknown
+	daemonPods map[string]corev1.Pod
+	// maximum age of inventory; 0 when not checked
+	stalenessBound time.Duration
+	now            func() time.Time
+	log            *logrus.Logger
+	// key: ClusterConfig name
+	nodeDecisions map[string][]sriovfecv2.NodeDecision
 }
 
 func (pm *clusterConfigMatcher) match(node corev1.Node, allConfigs []sriovfecv2.SriovFecClusterConfig) (*NodeConfigurationCtx, error) {
 
 	matchingClusterConfigs := matchConfigsForNode(&node, allConfigs)
+	// node configs are stamped under canonical name of the node object, unless daemon of the node adopted one named
+	// differently (matching it by hostname)
 	nodeConfig, err := pm.getNodeConfig(node.Name)
 	if err != nil {
 		return nil, fmt.Errorf("error occurred when reading SriovFecNodeConfig: %s", err.Error())
 	}
 
-	acceleratorConfigContext := pm.prepareAcceleratorConfigContext(nodeConfig, matchingClusterConfigs)
+	if reason, stale := pm.inventoryStaleness(nodeConfig); stale {
+		pm.log.WithField("node", node.Name).Info(reason)
+		pm.recordWaitingForFreshInventory(nodeConfig, allConfigs, matchingClusterConfigs, reason)
+		return &NodeConfigurationCtx{
+			SriovFecNodeConfig:       *nodeConfig,
+			AcceleratorConfigContext: orderedmap.NewOrderedMap[string, sriovfecv2.SriovFecClusterConfig](),
+			WaitingForFreshInventory: true,
+		}, nil
+	}
+
+	claimed, err := pm.claimedAccelerators(node.Name)
+	if err != nil {
+		return nil, fmt.Errorf("error occurred when reading secondary SriovFecNodeConfigs: %s", err.Error())
+	}
+
+	acceleratorConfigContext := pm.prepareAcceleratorConfigContext(nodeConfig, matchingClusterConfigs, claimed)
 	if acceleratorConfigContext == nil {
-		return nil, fmt.Errorf("error occurred when preparing acceleratorConfig: %s", err.Error())
+		return nil, fmt.Errorf("error occurred when preparing acceleratorConfig")
+	}
+	pm.recordNodeDecisions(nodeConfig, allConfigs, matchingClusterConfigs, acceleratorConfigContext, claimed)
+	return &NodeConfigurationCtx{SriovFecNodeConfig: *nodeConfig, AcceleratorConfigContext: acceleratorConfigContext}, nil
+}
+
+// claimedAccelerators returns names of secondary node configs of the node by PCI addresses of accelerators they configure
+func (pm *clusterConfigMatcher) claimedAccelerators(nodeName string) (map[string]string, error) {
+	claimed := map[string]string{}
+	if pm.getSecondaryNodeConfigs == nil {
+		return claimed, nil
+	}
+	secondary, err := pm.getSecondaryNodeConfigs(nodeName)
+	if err != nil {
+		return nil, err
+	}
+	for _, nc := range secondary {
+		for _, pf := range nc.Spec.PhysicalFunctions {
+			claimed[utils.CanonicalPCIAddress(pf.PCIAddress)] = nc.Name
+		}
 	}
-	return &NodeConfigurationCtx{*nodeConfig, acceleratorConfigContext}, nil
+	return claimed, nil
 }
 
-// Use orderedmap to save SriovFecCluster configurations
-func (pm *clusterConfigMatcher) prepareAcceleratorConfigContext(nodeConfig *sriovfecv2.SriovFecNodeConfig, configs []sriovfecv2.SriovFecClusterConfig) *orderedmap.OrderedMap[string, sriovfecv2.SriovFecClusterConfig] {
+// Use orderedmap to save SriovFecCluster configurations; accelerators claimed by secondary node configs are skipped
+func (pm *clusterConfigMatcher) prepareAcceleratorConfigContext(nodeConfig *sriovfecv2.SriovFecNodeConfig, configs []sriovfecv2.SriovFecClusterConfig, claimed map[string]string) *orderedmap.OrderedMap[string, sriovfecv2.SriovFecClusterConfig] {
 	acceleratorConfigContext := orderedmap.NewOrderedMap[string, sriovfecv2.SriovFecClusterConfig]()
 	for _, current := range configs {
 		for _, accelerator := range nodeConfig.Status.Inventory.SriovAccelerators {
+			if _, found := claimed[accelerator.PCIAddress]; found {
+				continue
+			}
 			if current.Spec.AcceleratorSelector.Matches(accelerator) {
 
 				if _, ok := acceleratorConfigContext.Get(accelerator.PCIAddress); !ok {
@@ -273,6 +1042,102 @@ func (pm *clusterConfigMatcher) prepareAcceleratorConfigContext(nodeConfig *srio
 	return acceleratorConfigContext
 }
 
+// recordNodeDecisions explains why configs were not selected for the node: either node labels did not match, none of
+// the accelerators matched or accelerators were taken by another config. Fully applied configs are not recorded.
+func (pm *clusterConfigMatcher) recordNodeDecisions(nodeConfig *sriovfecv2.SriovFecNodeConfig, allConfigs, matchingConfigs []sriovfecv2.SriovFecClusterConfig,
+	acceleratorConfigContext *orderedmap.OrderedMap[string, sriovfecv2.SriovFecClusterConfig], claimed map[string]string) {
+
+	labelsMatched := map[string]bool{}
+	for _, cc := range matchingConfigs {
+		labelsMatched[cc.Name] = true
+	}
+
+	for _, cc := range allConfigs {
+		decision := sriovfecv2.NodeDecision{NodeName: nodeConfig.Name}
+		if !labelsMatched[cc.Name] {
+			decision.Reason = "label selector did not match"
+			pm.nodeDecisions[cc.Name] = append(pm.nodeDecisions[cc.Name], decision)
+			continue
+		}
+
+		var exclusions []string
+		selected := false
+		for _, accelerator := range nodeConfig.Status.Inventory.SriovAccelerators {
+			if !cc.Spec.AcceleratorSelector.Matches(accelerator) {
+				continue
+			}
+			owner, _ := acceleratorConfigContext.Get(accelerator.PCIAddress)
+			switch secondary, found := claimed[accelerator.PCIAddress]; {
+			case found:
+				exclusions = append(exclusions, fmt.Sprintf("device %s configured by SriovFecNodeConfig '%s'", accelerator.PCIAddress, secondary))
+			case owner.Name == cc.Name:
+				selected = true
+			case owner.Spec.Priority == cc.Spec.Priority:
+				exclusions = append(exclusions, fmt.Sprintf("device %s excluded by newer CC '%s' with same priority", accelerator.PCIAddress, owner.Name))
+			default:
+				exclusions = append(exclusions, fmt.Sprintf("device %s excluded by higher priority CC '%s'", accelerator.PCIAddress, owner.Name))
+			}
+		}
+
+		switch {
+		case !pm.isDaemonRunning(nodeConfig.Name):
+			// inventory is not (or no longer) reported, so accelerator matching can't be trusted
+			decision.Matched = selected
+			decision.Reason = pm.daemonNotRunningReason(nodeConfig.Name)
+		case len(exclusions) > 0:
+			decision.Matched = selected
+			decision.Reason = strings.Join(exclusions, "; ")
+		case !selected:
+			decision.Reason = "no accelerator matched accelerator selector"
+		default:
+			continue
+		}
+		pm.nodeDecisions[cc.Name] = append(pm.nodeDecisions[cc.Name], decision)
+	}
+}
+
+// inventoryStaleness tells whether inventory of the NodeConfig is older than the staleness bound. Inventory without
+// collection time (reported by daemon of older version) is not checked.
+func (pm *clusterConfigMatcher) inventoryStaleness(nodeConfig *sriovfecv2.SriovFecNodeConfig) (string, bool) {
+	collectedAt := nodeConfig.Status.InventoryCollectedAt
+	if pm.stalenessBound <= 0 || collectedAt == nil || pm.now().Sub(collectedAt.Time) <= pm.stalenessBound {
+		return "", false
+	}
+	return fmt.Sprintf("%s: inventory #%d collected at %s is older than %s", waitingForFreshInventory,
+		nodeConfig.Status.InventorySequence, collectedAt.UTC().Format(time.RFC3339), pm.stalenessBound), true
+}
+
+// recordWaitingForFreshInventory records that configs selecting the node are not resolved until its inventory is fresh
+func (pm *clusterConfigMatcher) recordWaitingForFreshInventory(nodeConfig *sriovfecv2.SriovFecNodeConfig, allConfigs, matchingConfigs []sriovfecv2.SriovFecClusterConfig, reason string) {
+	labelsMatched := map[string]bool{}
+	for _, cc := range matchingConfigs {
+		labelsMatched[cc.Name] = true
+	}
+
+	for _, cc := range allConfigs {
+		decision := sriovfecv2.NodeDecision{NodeName: nodeConfig.Name, Reason: reason}
+		if !labelsMatched[cc.Name] {
+			decision.Reason = "label selector did not match"
+		}
+		pm.nodeDecisions[cc.Name] = append(pm.nodeDecisions[cc.Name], decision)
+	}
+}
+
+func (pm *clusterConfigMatcher) isDaemonRunning(nodeName string) bool {
+	if pm.daemonPods == nil {
+		return true
+	}
+	pod, ok := pm.daemonPods[nodeName]
+	return ok && pod.Status.Phase == corev1.PodRunning
+}
+
+func (pm *clusterConfigMatcher) daemonNotRunningReason(nodeName string) string {
+	if pod, ok := pm.daemonPods[nodeName]; ok {
+		return fmt.Sprintf("%s: daemon pod %s is %s", daemonNotRunning, pod.Name, pod.Status.Phase)
+	}
+	return fmt.Sprintf("%s: no daemon pod on the node", daemonNotRunning)
+}
+
 func matchConfigsForNode(node *corev1.Node, allConfigs []sriovfecv2.SriovFecClusterConfig) (nodeConfigs []sriovfecv2.SriovFecClusterConfig) {
 	nodeLabels := labels.Set(node.Labels)
 	for _, config := range allConfigs {
@@ -290,12 +1155,10 @@ func matchConfigsForNode(node *corev1.Node, allConfigs []sriovfecv2.SriovFecClus
 	return
 }
 
-func setConfigurationPropagationConditionFailed(conditions *[]metav1.Condition, generation int64, msg string) {
-	meta.SetStatusCondition(conditions, metav1.Condition{
-		Type:               "ConfigurationPropagationCondition",
-		Status:             metav1.ConditionFalse,
-		ObservedGeneration: generation,
-		Reason:             "Failed",
-		Message:            msg,
-	})
+// setConfigurationPropagationConditionFailed returns false when the condition was already set and status does not
+// need to be updated; fullMessage is set when msg had to be truncated and has to be emitted as event
+func setConfigurationPropagationConditionFailed(ncConditions *[]metav1.Condition, generation int64, msg string) (changed bool, fullMessage string) {
+	condition, fullMessage := conditions.Bounded(
+		conditions.ConfigurationPropagation(metav1.ConditionFalse, conditions.ReasonFailed, msg, generation))
+	return conditions.SetIfChanged(ncConditions, condition), fullMessage
 }
@@ -33,6 +33,7 @@ import (
 	"github.com/onsi/gomega/gstruct"
 	"github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/utils/pointer"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
@@ -75,7 +76,7 @@ var (
 					N3000: &sriovv2.N3000BBDevConfig{
 						NetworkType: "FPGA_LTE",
 						PFMode:      false,
-						FLRTimeOut:  10,
+						FLRTimeOut:  pointer.Int(10),
 						Downlink: sriovv2.UplinkDownlink{
 							Bandwidth:   3,
 							LoadBalance: 3,
@@ -154,12 +155,29 @@ var _ = Describe("SriovControllerTest", func() {
 			return cc
 		}
 
+		createDaemonPod := func(nodeName string, phase corev1.PodPhase) {
+			pod := &corev1.Pod{
+				ObjectMeta: v1.ObjectMeta{
+					Name:      "sriov-fec-daemonset-" + nodeName,
+					Namespace: NAMESPACE,
+					Labels:    map[string]string{"app": "sriov-fec-daemonset"},
+				},
+				Spec: corev1.PodSpec{
+					NodeName:   nodeName,
+					Containers: []corev1.Container{{Name: "sriov-fec-daemon", Image: "daemon"}},
+				},
+			}
+			Expect(k8sClient.Create(context.TODO(), pod)).ToNot(HaveOccurred())
+			pod.Status.Phase = phase
+			Expect(k8sClient.Status().Update(context.TODO(), pod)).ToNot(HaveOccurred())
+		}
+
 		createDummyReconcileRequest := func(ccName string) ctrl.Request {
 			return ctrl.Request{NamespacedName: types.NamespacedName{Namespace: NAMESPACE, Name: ccName}}
 		}
 
 		reconcile := func(ccName string) *SriovFecClusterConfigReconciler {
-			reconciler := SriovFecClusterConfigReconciler{k8sClient, log}
+			reconciler := SriovFecClusterConfigReconciler{Client: k8sClient, Log: log}
 			_, err := reconciler.Reconcile(context.TODO(), createDummyReconcileRequest(ccName))
 			Expect(err).ToNot(HaveOccurred())
 			return &reconciler
@@ -179,6 +197,7 @@ var _ = Describe("SriovControllerTest", func() {
 			}
 
 			Expect(k8sClient.DeleteAllOf(context.TODO(), &corev1.Node{})).ToNot(HaveOccurred())
+			Expect(k8sClient.DeleteAllOf(context.TODO(), &corev1.Pod{}, client.InNamespace(NAMESPACE), client.GracePeriodSeconds(0))).ToNot(HaveOccurred())
 		})
 
 		When("Error occurs during SriovFecClusterConfig->SriovFecNodeConfig propagation", func() {
@@ -419,7 +438,7 @@ var _ = Describe("SriovControllerTest", func() {
 					}
 				})
 
-				reconciler := SriovFecClusterConfigReconciler{k8sClient, log}
+				reconciler := SriovFecClusterConfigReconciler{Client: k8sClient, Log: log}
 
 				_, err := reconciler.Reconcile(context.TODO(), createDummyReconcileRequest("cc1"))
 				Expect(err).ToNot(HaveOccurred())
@@ -467,7 +486,7 @@ var _ = Describe("SriovControllerTest", func() {
 					}
 				})
 
-				reconciler := SriovFecClusterConfigReconciler{k8sClient, log}
+				reconciler := SriovFecClusterConfigReconciler{Client: k8sClient, Log: log}
 				ccs := []string{"cc1", "cc2"}
 				for i := 0; i < 100; i++ {
 					cc := ccs[i%len(ccs)]
@@ -648,6 +667,88 @@ var _ = Describe("SriovControllerTest", func() {
 
 		})
 
+		When("ccs are not selected for some nodes", func() {
+			It("node decisions should explain why in cc status", func() {
+				n1 := createNode("n1", func(n *corev1.Node) { n.Labels["kubernetes.io/hostname"] = "n1" })
+				n2 := createNode("n2")
+
+				for _, n := range []*corev1.Node{n1, n2} {
+					createNodeInventory(n.Name, []sriovv2.SriovAccelerator{
+						{
+							PCIAddress: "0000:15:00.1",
+							VendorID:   "testvendor",
+							VFs:        []sriovv2.VF{},
+						},
+					})
+					createDaemonPod(n.Name, corev1.PodRunning)
+				}
+
+				createAcceleratorConfig("high", func(cc *sriovv2.SriovFecClusterConfig) {
+					cc.Spec.NodeSelector = map[string]string{"kubernetes.io/hostname": "n1"}
+					cc.Spec.AcceleratorSelector = sriovv2.AcceleratorSelector{PCIAddress: "0000:15:00.1"}
+					cc.Spec.Priority = 2
+				})
+				createAcceleratorConfig("low", func(cc *sriovv2.SriovFecClusterConfig) {
+					cc.Spec.AcceleratorSelector = sriovv2.AcceleratorSelector{VendorID: "testvendor"}
+					cc.Spec.Priority = 1
+				})
+				createAcceleratorConfig("other", func(cc *sriovv2.SriovFecClusterConfig) {
+					cc.Spec.AcceleratorSelector = sriovv2.AcceleratorSelector{VendorID: "otherVendor"}
+				})
+
+				reconcile("high")
+
+				nodeDecisions := func(ccName string) []sriovv2.NodeDecision {
+					cc := new(sriovv2.SriovFecClusterConfig)
+					Expect(k8sClient.Get(context.TODO(), client.ObjectKey{Name: ccName, Namespace: NAMESPACE}, cc)).ToNot(HaveOccurred())
+					return cc.Status.NodeDecisions
+				}
+
+				Expect(nodeDecisions("high")).To(Equal([]sriovv2.NodeDecision{
+					{NodeName: "n2", Matched: false, Reason: "label selector did not match"},
+				}))
+				Expect(nodeDecisions("low")).To(Equal([]sriovv2.NodeDecision{
+					{NodeName: "n1", Matched: false, Reason: "device 0000:15:00.1 excluded by higher priority CC 'high'"},
+				}))
+				Expect(nodeDecisions("other")).To(Equal([]sriovv2.NodeDecision{
+					{NodeName: "n1", Matched: false, Reason: "no accelerator matched accelerator selector"},
+					{NodeName: "n2", Matched: false, Reason: "no accelerator matched accelerator selector"},
+				}))
+			})
+		})
+
+		When("daemon is not running on matching node", func() {
+			It("node decision should report DaemonNotRunning", func() {
+				n1 := createNode("n1")
+				n2 := createNode("n2")
+				n3 := createNode("n3")
+				for _, n := range []*corev1.Node{n1, n2, n3} {
+					createNodeInventory(n.Name, []sriovv2.SriovAccelerator{
+						{
+							PCIAddress: "0000:15:00.1",
+							VendorID:   "testvendor",
+							VFs:        []sriovv2.VF{},
+						},
+					})
+				}
+				createDaemonPod(n1.Name, corev1.PodRunning)
+				createDaemonPod(n2.Name, corev1.PodPending)
+
+				createAcceleratorConfig("cc", func(cc *sriovv2.SriovFecClusterConfig) {
+					cc.Spec.AcceleratorSelector = sriovv2.AcceleratorSelector{VendorID: "testvendor"}
+				})
+
+				reconcile("cc")
+
+				cc := new(sriovv2.SriovFecClusterConfig)
+				Expect(k8sClient.Get(context.TODO(), client.ObjectKey{Name: "cc", Namespace: NAMESPACE}, cc)).ToNot(HaveOccurred())
+				Expect(cc.Status.NodeDecisions).To(Equal([]sriovv2.NodeDecision{
+					{NodeName: "n2", Matched: true, Reason: "DaemonNotRunning: daemon pod sriov-fec-daemonset-n2 is Pending"},
+					{NodeName: "n3", Matched: true, Reason: "DaemonNotRunning: no daemon pod on the node"},
+				}))
+			})
+		})
+
 		When("cc has no node selector", func() {
 			It("cc.spec should be propagated to all nodes having matching accelerator", func() {
 				n1 := createNode("n1")
@@ -680,7 +781,7 @@ var _ = Describe("SriovControllerTest", func() {
 					}
 				})
 
-				reconciler := SriovFecClusterConfigReconciler{k8sClient, log}
+				reconciler := SriovFecClusterConfigReconciler{Client: k8sClient, Log: log}
 				_, err := reconciler.Reconcile(context.TODO(), createDummyReconcileRequest("cc"))
 				Expect(err).ToNot(HaveOccurred())
 
@@ -716,7 +817,7 @@ var _ = Describe("SriovControllerTest", func() {
 						}
 					})
 
-					reconciler := SriovFecClusterConfigReconciler{k8sClient, log}
+					reconciler := SriovFecClusterConfigReconciler{Client: k8sClient, Log: log}
 					_, err := reconciler.Reconcile(context.TODO(), createDummyReconcileRequest("config"))
 					Expect(err).ToNot(HaveOccurred())
 
@@ -771,7 +872,7 @@ var _ = Describe("SriovControllerTest", func() {
 						}
 					})
 
-					reconciler := SriovFecClusterConfigReconciler{k8sClient, log}
+					reconciler := SriovFecClusterConfigReconciler{Client: k8sClient, Log: log}
 					_, err := reconciler.Reconcile(context.TODO(), createDummyReconcileRequest("config"))
 					Expect(err).ToNot(HaveOccurred())
 
@@ -820,7 +921,7 @@ var _ = Describe("SriovControllerTest", func() {
 					cc.Spec.DrainSkip = true
 				})
 
-				reconciler := SriovFecClusterConfigReconciler{k8sClient, log}
+				reconciler := SriovFecClusterConfigReconciler{Client: k8sClient, Log: log}
 				_, err := reconciler.Reconcile(context.TODO(), createDummyReconcileRequest("config"))
 				Expect(err).ToNot(HaveOccurred())
 
@@ -830,6 +931,201 @@ var _ = Describe("SriovControllerTest", func() {
 			})
 		})
 
+		When("configurationDebounce is specified on CC level", func() {
+			It("should rewrite the longest one to matching NC", func() {
+				n1 := createNode("first-node", func(n *corev1.Node) {
+					n.Labels["kubernetes.io/hostname"] = n.Name
+				})
+
+				createNodeInventory(n1.Name, []sriovv2.SriovAccelerator{
+					{DeviceID: n1.Name, PCIAddress: "0000:15:00.1", VFs: []sriovv2.VF{}},
+					{DeviceID: n1.Name, PCIAddress: "0000:15:00.2", VFs: []sriovv2.VF{}},
+				})
+
+				for name, debounce := range map[string]time.Duration{"config1": 30 * time.Second, "config2": 2 * time.Minute} {
+					debounce := debounce
+					pciAddress := map[string]string{"config1": "0000:15:00.1", "config2": "0000:15:00.2"}[name]
+					createAcceleratorConfig(name, func(cc *sriovv2.SriovFecClusterConfig) {
+						cc.Spec.NodeSelector["kubernetes.io/hostname"] = n1.Name
+						cc.Spec.AcceleratorSelector = sriovv2.AcceleratorSelector{PCIAddress: pciAddress}
+						cc.Spec.ConfigurationDebounce = &v1.Duration{Duration: debounce}
+					})
+				}
+
+				reconcile("config1")
+
+				nodeConfig := new(sriovv2.SriovFecNodeConfig)
+				Expect(k8sClient.Get(context.TODO(), client.ObjectKey{Name: n1.Name, Namespace: NAMESPACE}, nodeConfig)).ToNot(HaveOccurred())
+				Expect(nodeConfig.Spec.PhysicalFunctions).To(HaveLen(2))
+				Expect(nodeConfig.Spec.ConfigurationDebounce).To(Equal(&v1.Duration{Duration: 2 * time.Minute}))
+			})
+		})
+
+		When("postConfigureSoakSeconds is specified on CC level", func() {
+			It("should rewrite the longest one to matching NC", func() {
+				n1 := createNode("first-node", func(n *corev1.Node) {
+					n.Labels["kubernetes.io/hostname"] = n.Name
+				})
+
+				createNodeInventory(n1.Name, []sriovv2.SriovAccelerator{
+					{DeviceID: n1.Name, PCIAddress: "0000:15:00.1", VFs: []sriovv2.VF{}},
+					{DeviceID: n1.Name, PCIAddress: "0000:15:00.2", VFs: []sriovv2.VF{}},
+				})
+
+				for name, soak := range map[string]int{"config1": 120, "config2": 30} {
+					soak := soak
+					pciAddress := map[string]string{"config1": "0000:15:00.1", "config2": "0000:15:00.2"}[name]
+					createAcceleratorConfig(name, func(cc *sriovv2.SriovFecClusterConfig) {
+						cc.Spec.NodeSelector["kubernetes.io/hostname"] = n1.Name
+						cc.Spec.AcceleratorSelector = sriovv2.AcceleratorSelector{PCIAddress: pciAddress}
+						cc.Spec.PostConfigureSoakSeconds = soak
+					})
+				}
+
+				reconcile("config1")
+
+				nodeConfig := new(sriovv2.SriovFecNodeConfig)
+				Expect(k8sClient.Get(context.TODO(), client.ObjectKey{Name: n1.Name, Namespace: NAMESPACE}, nodeConfig)).ToNot(HaveOccurred())
+				Expect(nodeConfig.Spec.PhysicalFunctions).To(HaveLen(2))
+				Expect(nodeConfig.Spec.PostConfigureSoakSeconds).To(Equal(120))
+			})
+		})
+
+		When("adoptExistingConfig is specified on CC level", func() {
+			It("should enable adoption for the whole matching NC", func() {
+				n1 := createNode("first-node", func(n *corev1.Node) {
+					n.Labels["kubernetes.io/hostname"] = n.Name
+				})
+
+				createNodeInventory(n1.Name, []sriovv2.SriovAccelerator{
+					{DeviceID: n1.Name, PCIAddress: "0000:15:00.1", VFs: []sriovv2.VF{}},
+					{DeviceID: n1.Name, PCIAddress: "0000:15:00.2", VFs: []sriovv2.VF{}},
+				})
+
+				for name, adopt := range map[string]bool{"config1": false, "config2": true} {
+					adopt := adopt
+					pciAddress := map[string]string{"config1": "0000:15:00.1", "config2": "0000:15:00.2"}[name]
+					createAcceleratorConfig(name, func(cc *sriovv2.SriovFecClusterConfig) {
+						cc.Spec.NodeSelector["kubernetes.io/hostname"] = n1.Name
+						cc.Spec.AcceleratorSelector = sriovv2.AcceleratorSelector{PCIAddress: pciAddress}
+						cc.Spec.AdoptExistingConfig = adopt
+					})
+				}
+
+				reconcile("config1")
+
+				nodeConfig := new(sriovv2.SriovFecNodeConfig)
+				Expect(k8sClient.Get(context.TODO(), client.ObjectKey{Name: n1.Name, Namespace: NAMESPACE}, nodeConfig)).ToNot(HaveOccurred())
+				Expect(nodeConfig.Spec.PhysicalFunctions).To(HaveLen(2))
+				Expect(nodeConfig.Spec.AdoptExistingConfig).To(BeTrue())
+			})
+		})
+
+		When("autoRebootOnPersistentFailure is specified on CC level", func() {
+			It("should merge enabled policies of all matching CCs to NC", func() {
+				n1 := createNode("first-node", func(n *corev1.Node) {
+					n.Labels["kubernetes.io/hostname"] = n.Name
+				})
+
+				createNodeInventory(n1.Name, []sriovv2.SriovAccelerator{
+					{DeviceID: n1.Name, PCIAddress: "0000:15:00.1", VFs: []sriovv2.VF{}},
+					{DeviceID: n1.Name, PCIAddress: "0000:15:00.2", VFs: []sriovv2.VF{}},
+				})
+
+				policies := map[string]*sriovv2.AutoRebootPolicy{
+					"config1": {Enabled: true, Threshold: 5},
+					"config2": {Enabled: true, Cooldown: &v1.Duration{Duration: 48 * time.Hour}},
+				}
+				for name, policy := range policies {
+					policy := policy
+					pciAddress := map[string]string{"config1": "0000:15:00.1", "config2": "0000:15:00.2"}[name]
+					createAcceleratorConfig(name, func(cc *sriovv2.SriovFecClusterConfig) {
+						cc.Spec.NodeSelector["kubernetes.io/hostname"] = n1.Name
+						cc.Spec.AcceleratorSelector = sriovv2.AcceleratorSelector{PCIAddress: pciAddress}
+						cc.Spec.AutoRebootOnPersistentFailure = policy
+					})
+				}
+
+				reconcile("config1")
+
+				nodeConfig := new(sriovv2.SriovFecNodeConfig)
+				Expect(k8sClient.Get(context.TODO(), client.ObjectKey{Name: n1.Name, Namespace: NAMESPACE}, nodeConfig)).ToNot(HaveOccurred())
+				Expect(nodeConfig.Spec.AutoRebootOnPersistentFailure).To(Equal(&sriovv2.AutoRebootPolicy{
+					Enabled: true, Threshold: 5, Cooldown: &v1.Duration{Duration: 48 * time.Hour}}))
+			})
+		})
+
+		When("configuration hooks are specified on CC level", func() {
+			It("should rewrite hooks of the highest prioritized config to matching NC", func() {
+				n1 := createNode("first-node", func(n *corev1.Node) {
+					n.Labels["kubernetes.io/hostname"] = n.Name
+				})
+
+				createNodeInventory(n1.Name, []sriovv2.SriovAccelerator{
+					{DeviceID: n1.Name, PCIAddress: "0000:15:00.1", VFs: []sriovv2.VF{}},
+					{DeviceID: n1.Name, PCIAddress: "0000:15:00.2", VFs: []sriovv2.VF{}},
+				})
+
+				createAcceleratorConfig("config1", func(cc *sriovv2.SriovFecClusterConfig) {
+					cc.Spec.NodeSelector["kubernetes.io/hostname"] = n1.Name
+					cc.Spec.AcceleratorSelector = sriovv2.AcceleratorSelector{PCIAddress: "0000:15:00.1"}
+					cc.Spec.PreConfigureHook = &sriovv2.ConfigurationHook{Path: "/etc/sriov-fec/hooks/quiesce-low"}
+					cc.Spec.PostConfigureHook = &sriovv2.ConfigurationHook{Path: "/etc/sriov-fec/hooks/resume"}
+				})
+				createAcceleratorConfig("config2", func(cc *sriovv2.SriovFecClusterConfig) {
+					cc.Spec.NodeSelector["kubernetes.io/hostname"] = n1.Name
+					cc.Spec.AcceleratorSelector = sriovv2.AcceleratorSelector{PCIAddress: "0000:15:00.2"}
+					cc.Spec.Priority = 1
+					cc.Spec.PreConfigureHook = &sriovv2.ConfigurationHook{Path: "/etc/sriov-fec/hooks/quiesce",
+						Args: []string{"--du"}, FailurePolicy: sriovv2.HookFailurePolicyContinue}
+				})
+
+				reconcile("config1")
+
+				nodeConfig := new(sriovv2.SriovFecNodeConfig)
+				Expect(k8sClient.Get(context.TODO(), client.ObjectKey{Name: n1.Name, Namespace: NAMESPACE}, nodeConfig)).ToNot(HaveOccurred())
+				Expect(nodeConfig.Spec.PreConfigureHook).To(Equal(&sriovv2.ConfigurationHook{Path: "/etc/sriov-fec/hooks/quiesce",
+					Args: []string{"--du"}, FailurePolicy: sriovv2.HookFailurePolicyContinue}))
+				Expect(nodeConfig.Spec.PostConfigureHook).To(Equal(&sriovv2.ConfigurationHook{Path: "/etc/sriov-fec/hooks/resume"}))
+			})
+		})
+
+		When("cc requests cluster-wide emergency stop", func() {
+			It("should be propagated as annotation to all nc and removed once cleared", func() {
+				n1 := createNode("first-node", func(n *corev1.Node) {
+					n.Labels["kubernetes.io/hostname"] = n.Name
+				})
+				n2 := createNode("second-node", func(n *corev1.Node) {
+					n.Labels["kubernetes.io/hostname"] = n.Name
+				})
+
+				cc := createAcceleratorConfig("config", func(cc *sriovv2.SriovFecClusterConfig) {
+					cc.Spec.NodeSelector["kubernetes.io/hostname"] = n1.Name
+					cc.Spec.Disabled = true
+				})
+
+				reconcile("config")
+
+				for _, name := range []string{n1.Name, n2.Name} {
+					nodeConfig := new(sriovv2.SriovFecNodeConfig)
+					Expect(k8sClient.Get(context.TODO(), client.ObjectKey{Name: name, Namespace: NAMESPACE}, nodeConfig)).ToNot(HaveOccurred())
+					Expect(nodeConfig.IsConfigurationHalted()).To(BeTrue(), name)
+				}
+
+				Expect(k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(cc), cc)).ToNot(HaveOccurred())
+				cc.Spec.Disabled = false
+				Expect(k8sClient.Update(context.TODO(), cc)).ToNot(HaveOccurred())
+
+				reconcile("config")
+
+				for _, name := range []string{n1.Name, n2.Name} {
+					nodeConfig := new(sriovv2.SriovFecNodeConfig)
+					Expect(k8sClient.Get(context.TODO(), client.ObjectKey{Name: name, Namespace: NAMESPACE}, nodeConfig)).ToNot(HaveOccurred())
+					Expect(nodeConfig.IsConfigurationHalted()).To(BeFalse(), name)
+				}
+			})
+		})
+
 		When("cc has been created outside of sriov-fec operator namespace", func() {
 			It("should not be reflected in any existing nc", func() {
 				node := nodePrototype.DeepCopy()
@@ -839,7 +1135,7 @@ var _ = Describe("SriovControllerTest", func() {
 				cc.Namespace = v1.NamespaceSystem
 				Expect(k8sClient.Create(context.TODO(), cc)).ToNot(HaveOccurred())
 
-				reconciler := SriovFecClusterConfigReconciler{k8sClient, log}
+				reconciler := SriovFecClusterConfigReconciler{Client: k8sClient, Log: log}
 				_, err := reconciler.Reconcile(context.TODO(), createDummyReconcileRequest(clusterConfigPrototype.Name))
 				Expect(err).ToNot(HaveOccurred())
 
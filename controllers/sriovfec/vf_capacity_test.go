@@ -0,0 +1,112 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package sriovfec
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sriovfecv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+// TestVFCapacity checks that VFs of NodeConfigs' inventory and of allocatable of nodes without inventory are
+// aggregated into status of every ClusterConfig
+func TestVFCapacity(t *testing.T) {
+	g := NewWithT(t)
+
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	g.Expect(sriovfecv2.AddToScheme(scheme)).To(Succeed())
+
+	accelerator := func(i int, usedBy ...string) sriovfecv2.SriovAccelerator {
+		acc := stressAccelerator(i)
+		for _, user := range usedBy {
+			acc.VFs = append(acc.VFs, sriovfecv2.VF{DeviceID: "0d5d", Driver: "vfio-pci", UsedBy: user})
+		}
+		return acc
+	}
+	node := func(i int, allocatable corev1.ResourceList) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: stressNodeName(i), Labels: map[string]string{"fpga.intel.com/intel-accelerator-present": ""}},
+			Status:     corev1.NodeStatus{Allocatable: allocatable},
+		}
+	}
+	nodeConfig := func(i int, accelerators ...sriovfecv2.SriovAccelerator) *sriovfecv2.SriovFecNodeConfig {
+		return &sriovfecv2.SriovFecNodeConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: stressNodeName(i), Namespace: NAMESPACE},
+			Status:     sriovfecv2.SriovFecNodeConfigStatus{Inventory: sriovfecv2.NodeInventory{SriovAccelerators: accelerators}},
+		}
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		stressClusterConfig("acc100", "0d5c", 2),
+		stressClusterConfig("acc200", "57c0", 4),
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: utils.DevicePluginConfigMapName, Namespace: NAMESPACE},
+			Data: map[string]string{utils.DevicePluginConfigKey: `{"resourceList": [
+				{"resourceName": "intel_fec_acc100", "selectors": {"vendors": ["8086"], "devices": ["0d5d"]}}
+			]}`},
+		},
+		// usage is reported
+		node(0, nil), nodeConfig(0, accelerator(0, "ns/pod-a", "", "ns/pod-b")),
+		// usage is not reported
+		node(1, nil), nodeConfig(1, accelerator(1, "", "")),
+		// inventory is not reported yet, VFs are counted from allocatable
+		node(2, corev1.ResourceList{"intel.com/intel_fec_acc100": resource.MustParse("4")}), nodeConfig(2),
+		// NodeConfig is not created yet and no VFs are allocatable
+		node(3, nil),
+	).Build()
+
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	reconciler := &SriovFecClusterConfigReconciler{Client: c, Log: log}
+
+	getVFCapacity := func(name string) *sriovfecv2.VFCapacity {
+		cc := &sriovfecv2.SriovFecClusterConfig{}
+		g.Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: NAMESPACE, Name: name}, cc)).To(Succeed())
+		return cc.Status.VFCapacity
+	}
+
+	reconciler.updateVFCapacity()
+
+	capacity := getVFCapacity("acc100")
+	g.Expect(capacity).ToNot(BeNil())
+	g.Expect(capacity.TotalVFs).To(Equal(9))
+	g.Expect(capacity.AllocatedVFs).To(Equal(2))
+	g.Expect(capacity.NodesWithoutInventory).To(Equal(1))
+	g.Expect(capacity.NodesWithoutUsage).To(Equal(2))
+	g.Expect(capacity.Resources).To(Equal([]sriovfecv2.ResourceVFCapacity{
+		{ResourceName: "intel.com/intel_fec_acc100", TotalVFs: 9, AllocatedVFs: 2},
+	}))
+	g.Expect(getVFCapacity("acc200")).To(Equal(capacity), "capacity is cluster-wide")
+
+	// status is not rewritten while capacity is the same
+	reconciler.updateVFCapacity()
+	g.Expect(getVFCapacity("acc100").LastUpdated).To(Equal(capacity.LastUpdated))
+
+	nc := &sriovfecv2.SriovFecNodeConfig{}
+	g.Expect(c.Get(context.TODO(), client.ObjectKey{Namespace: NAMESPACE, Name: stressNodeName(1)}, nc)).To(Succeed())
+	nc.Status.Inventory.SriovAccelerators[0].VFs[0].UsedBy = "ns/pod-c"
+	g.Expect(c.Status().Update(context.TODO(), nc)).To(Succeed())
+
+	// change of inventory triggers recompute without reconcile of ClusterConfigs
+	reconciler.VFCapacityDebounce = 10 * time.Millisecond
+	g.Expect(reconciler.triggerVFCapacityUpdate(nc)).To(BeEmpty())
+	g.Eventually(func() int { return getVFCapacity("acc100").AllocatedVFs }).Should(Equal(3))
+	g.Expect(getVFCapacity("acc100").NodesWithoutUsage).To(Equal(1))
+}
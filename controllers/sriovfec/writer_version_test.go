@@ -0,0 +1,35 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package sriovfec
+
+import (
+	"testing"
+
+	"github.com/elliotchance/orderedmap/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	sriovfecv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+// TestBuildNodeConfigRecordsOperatorVersion checks that NodeConfigs carry version of the operator which wrote them,
+// replacing version of the previous writer and keeping other annotations
+func TestBuildNodeConfigRecordsOperatorVersion(t *testing.T) {
+	g := NewWithT(t)
+
+	nc := buildNodeConfig(NodeConfigurationCtx{
+		SriovFecNodeConfig: sriovfecv2.SriovFecNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: "node", Namespace: NAMESPACE,
+			Annotations: map[string]string{
+				sriovfecv2.WrittenByVersionAnnotation:    "99.0.0",
+				sriovfecv2.IgnoreWriterVersionAnnotation: "true",
+			}}},
+		AcceleratorConfigContext: orderedmap.NewOrderedMap[string, sriovfecv2.SriovFecClusterConfig](),
+	}, false)
+
+	g.Expect(nc.GetAnnotations()).To(Equal(map[string]string{
+		sriovfecv2.WrittenByVersionAnnotation:    utils.OperatorVersion,
+		sriovfecv2.IgnoreWriterVersionAnnotation: "true",
+	}))
+}
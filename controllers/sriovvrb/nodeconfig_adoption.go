@@ -0,0 +1,134 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package sriovvrb
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/elliotchance/orderedmap/v2"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+const notAdopted = "NotAdopted"
+
+// adoptingConfigs returns configs which take over hand-written NodeConfigs, see
+// vrbv1.AdoptExistingNodeConfigsAnnotation
+func adoptingConfigs(clusterConfigs []vrbv1.SriovVrbClusterConfig) []vrbv1.SriovVrbClusterConfig {
+	return utils.Filter(clusterConfigs, func(cc vrbv1.SriovVrbClusterConfig) bool {
+		return cc.AdoptsExistingNodeConfigs()
+	})
+}
+
+// isHandWritten tells whether NodeConfig was written other way than by the cluster controller, e.g. created by hand
+// before the node was managed with ClusterConfigs; empty NodeConfigs created by daemons are not considered hand-written,
+// nor are the ones of nodes stamped before the adopting configs were created, see stampedBeforeAdoption
+func isHandWritten(nc *vrbv1.SriovVrbNodeConfig, adopting []vrbv1.SriovVrbClusterConfig,
+	acceleratorConfigContext *orderedmap.OrderedMap[string, vrbv1.SriovVrbClusterConfig]) bool {
+	return !nc.IsManaged() && !equality.Semantic.DeepEqual(nc.Spec, vrbv1.SriovVrbNodeConfigSpec{}) &&
+		!stampedBeforeAdoption(adopting, acceleratorConfigContext)
+}
+
+// stampedBeforeAdoption tells whether accelerators of the node are selected by a config which does not adopt NodeConfigs
+// and was created before all the adopting ones; such config matched the node before adoption was requested, so its
+// NodeConfig was stamped by the cluster controller, even if it is not labeled (e.g. written by a controller of version
+// which neither labeled nor annotated NodeConfigs)
+func stampedBeforeAdoption(adopting []vrbv1.SriovVrbClusterConfig, acceleratorConfigContext *orderedmap.OrderedMap[string, vrbv1.SriovVrbClusterConfig]) bool {
+	var earliest *metav1.Time
+	for i := range adopting {
+		if earliest == nil || adopting[i].CreationTimestamp.Before(earliest) {
+			earliest = &adopting[i].CreationTimestamp
+		}
+	}
+	if earliest == nil {
+		return false
+	}
+	for _, pciAddress := range acceleratorConfigContext.Keys() {
+		cc, _ := acceleratorConfigContext.Get(pciAddress)
+		if !cc.AdoptsExistingNodeConfigs() && cc.CreationTimestamp.Before(earliest) {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeConfigSpecDifferences describes how existing spec of NodeConfig differs from the stamped one; specs are
+// equivalent when nothing is returned. PFs are compared by PCI address regardless of their order, nil and empty
+// lists are equal.
+func nodeConfigSpecDifferences(existing, stamped vrbv1.SriovVrbNodeConfigSpec) []string {
+	var differences []string
+	if fields := utils.DifferingFields(existing, stamped, "physicalFunctions"); len(fields) > 0 {
+		differences = append(differences, fmt.Sprintf("node-wide %s differ", strings.Join(fields, ", ")))
+	}
+
+	existingPFs, stampedPFs := map[string]vrbv1.PhysicalFunctionConfigExt{}, map[string]vrbv1.PhysicalFunctionConfigExt{}
+	var pciAddresses []string
+	for _, pf := range existing.PhysicalFunctions {
+		existingPFs[pf.PCIAddress] = pf
+		pciAddresses = append(pciAddresses, pf.PCIAddress)
+	}
+	for _, pf := range stamped.PhysicalFunctions {
+		if _, ok := existingPFs[pf.PCIAddress]; !ok {
+			pciAddresses = append(pciAddresses, pf.PCIAddress)
+		}
+		stampedPFs[pf.PCIAddress] = pf
+	}
+	sort.Strings(pciAddresses)
+
+	for _, pciAddress := range pciAddresses {
+		existingPF, inExisting := existingPFs[pciAddress]
+		stampedPF, inStamped := stampedPFs[pciAddress]
+		switch {
+		case !inStamped:
+			differences = append(differences, fmt.Sprintf("PF %s is not selected by any ClusterConfig", pciAddress))
+		case !inExisting:
+			differences = append(differences, fmt.Sprintf("PF %s is not configured", pciAddress))
+		default:
+			if fields := utils.DifferingFields(existingPF, stampedPF); len(fields) > 0 {
+				differences = append(differences, fmt.Sprintf("PF %s differs in %s", pciAddress, strings.Join(fields, ", ")))
+			}
+		}
+	}
+	return differences
+}
+
+// recordNotAdopted explains in decisions of adopting configs that hand-written NodeConfig of the node was not adopted,
+// as its spec differs from the stamped one
+func (pm *clusterConfigMatcher) recordNotAdopted(nodeName string, adopting []vrbv1.SriovVrbClusterConfig,
+	acceleratorConfigContext *orderedmap.OrderedMap[string, vrbv1.SriovVrbClusterConfig], differences []string) {
+
+	reason := fmt.Sprintf("%s: spec of hand-written NodeConfig differs - %s", notAdopted, strings.Join(differences, "; "))
+	for _, cc := range adopting {
+		decisions := pm.nodeDecisions[cc.Name]
+		recorded := false
+		for i := range decisions {
+			if decisions[i].NodeName == nodeName {
+				decisions[i].Reason += "; " + reason
+				recorded = true
+			}
+		}
+		if !recorded {
+			decisions = append(decisions, vrbv1.NodeDecision{
+				NodeName: nodeName,
+				Matched:  selectsAccelerators(acceleratorConfigContext, cc.Name),
+				Reason:   reason,
+			})
+		}
+		pm.nodeDecisions[cc.Name] = decisions
+	}
+}
+
+func setManagedByLabel(nc *vrbv1.SriovVrbNodeConfig) {
+	labels := nc.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[vrbv1.ManagedByLabel] = vrbv1.ManagedByClusterController
+	nc.SetLabels(labels)
+}
@@ -0,0 +1,293 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package sriovvrb
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/elliotchance/orderedmap/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+)
+
+func adoptionNodeName(i int) string {
+	return fmt.Sprintf("node-%03d", i)
+}
+
+// adoptionAccelerator is reported by the node; even nodes have VRB1, odd ones VRB2
+func adoptionAccelerator(i int) vrbv1.SriovAccelerator {
+	deviceID := "57c0"
+	if i%2 == 1 {
+		deviceID = "57c2"
+	}
+	return vrbv1.SriovAccelerator{VendorID: "8086", DeviceID: deviceID, PCIAddress: fmt.Sprintf("0000:%02x:00.0", i%256), MaxVFs: 16}
+}
+
+func adoptionClusterConfig(name, deviceID string, vfAmount int) *vrbv1.SriovVrbClusterConfig {
+	return &vrbv1.SriovVrbClusterConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: NAMESPACE},
+		Spec: vrbv1.SriovVrbClusterConfigSpec{
+			AcceleratorSelector: vrbv1.AcceleratorSelector{DeviceID: deviceID},
+			PhysicalFunction: vrbv1.PhysicalFunctionConfig{
+				PFDriver: "vfio-pci",
+				VFDriver: "vfio-pci",
+				VFAmount: vfAmount,
+			},
+		},
+	}
+}
+
+func TestNodeConfigSpecDifferences(t *testing.T) {
+	pf := func(pciAddress string, vfAmount int) vrbv1.PhysicalFunctionConfigExt {
+		return vrbv1.PhysicalFunctionConfigExt{PCIAddress: pciAddress, PFDriver: "vfio-pci", VFDriver: "vfio-pci", VFAmount: vfAmount}
+	}
+	withBBDevConfig := func(pf vrbv1.PhysicalFunctionConfigExt, maxQueueSize int) vrbv1.PhysicalFunctionConfigExt {
+		pf.BBDevConfig = vrbv1.BBDevConfig{VRB1: &vrbv1.VRB1BBDevConfig{ACC100BBDevConfig: vrbv1.ACC100BBDevConfig{NumVfBundles: 2, MaxQueueSize: maxQueueSize}}}
+		return pf
+	}
+	gracePeriod := func(seconds int64) *int64 {
+		return &seconds
+	}
+	stamped := vrbv1.SriovVrbNodeConfigSpec{
+		PhysicalFunctions:       []vrbv1.PhysicalFunctionConfigExt{withBBDevConfig(pf("0000:14:00.0", 2), 1024), pf("0000:15:00.0", 4)},
+		DrainSkip:               true,
+		DrainGracePeriodSeconds: gracePeriod(30),
+	}
+
+	for name, tc := range map[string]struct {
+		existing    vrbv1.SriovVrbNodeConfigSpec
+		stamped     vrbv1.SriovVrbNodeConfigSpec
+		differences []string
+	}{
+		"equal specs": {
+			existing: *stamped.DeepCopy(),
+			stamped:  stamped,
+		},
+		"PFs in different order": {
+			existing: vrbv1.SriovVrbNodeConfigSpec{
+				PhysicalFunctions:       []vrbv1.PhysicalFunctionConfigExt{pf("0000:15:00.0", 4), withBBDevConfig(pf("0000:14:00.0", 2), 1024)},
+				DrainSkip:               true,
+				DrainGracePeriodSeconds: gracePeriod(30),
+			},
+			stamped: stamped,
+		},
+		"nil and empty PFs": {
+			existing: vrbv1.SriovVrbNodeConfigSpec{},
+			stamped:  vrbv1.SriovVrbNodeConfigSpec{PhysicalFunctions: []vrbv1.PhysicalFunctionConfigExt{}},
+		},
+		"node-wide settings": {
+			existing: vrbv1.SriovVrbNodeConfigSpec{
+				PhysicalFunctions:       stamped.PhysicalFunctions,
+				DrainGracePeriodSeconds: gracePeriod(60),
+			},
+			stamped:     stamped,
+			differences: []string{"node-wide drainSkip, drainGracePeriodSeconds differ"},
+		},
+		"PF settings": {
+			existing: vrbv1.SriovVrbNodeConfigSpec{
+				PhysicalFunctions: []vrbv1.PhysicalFunctionConfigExt{
+					withBBDevConfig(pf("0000:14:00.0", 2), 512),
+					{PCIAddress: "0000:15:00.0", PFDriver: "vfio-pci", VFDriver: "igb_uio", VFAmount: 8},
+				},
+				DrainSkip:               true,
+				DrainGracePeriodSeconds: gracePeriod(30),
+			},
+			stamped: stamped,
+			differences: []string{
+				"PF 0000:14:00.0 differs in bbDevConfig",
+				"PF 0000:15:00.0 differs in vfDriver, vfAmount",
+			},
+		},
+		"PFs configured by one spec only": {
+			existing: vrbv1.SriovVrbNodeConfigSpec{
+				PhysicalFunctions: []vrbv1.PhysicalFunctionConfigExt{pf("0000:16:00.0", 2), pf("0000:15:00.0", 4)},
+			},
+			stamped: vrbv1.SriovVrbNodeConfigSpec{
+				PhysicalFunctions: []vrbv1.PhysicalFunctionConfigExt{pf("0000:14:00.0", 2), pf("0000:15:00.0", 4)},
+			},
+			differences: []string{
+				"PF 0000:14:00.0 is not configured",
+				"PF 0000:16:00.0 is not selected by any ClusterConfig",
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(nodeConfigSpecDifferences(tc.existing, tc.stamped)).To(Equal(tc.differences))
+		})
+	}
+}
+
+// TestAdoptExistingNodeConfigs checks that config with adopt-existing-nodeconfigs annotation takes over hand-written
+// NodeConfigs equivalent to the stamped ones without changing their spec, and reports the others instead of
+// overwriting them
+func TestAdoptExistingNodeConfigs(t *testing.T) {
+	g := NewWithT(t)
+
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	g.Expect(vrbv1.AddToScheme(scheme)).To(Succeed())
+
+	vrb1 := adoptionClusterConfig("vrb1", "57c0", 2)
+	vrb1.Annotations = map[string]string{vrbv1.AdoptExistingNodeConfigsAnnotation: "true"}
+	handWritten := func(i int, vfAmount int) vrbv1.SriovVrbNodeConfigSpec {
+		return vrbv1.SriovVrbNodeConfigSpec{PhysicalFunctions: []vrbv1.PhysicalFunctionConfigExt{
+			{PCIAddress: adoptionAccelerator(i).PCIAddress, PFDriver: "vfio-pci", VFDriver: "vfio-pci", VFAmount: vfAmount},
+		}}
+	}
+	nodeConfigs := map[string]*vrbv1.SriovVrbNodeConfig{
+		// hand-written, equivalent to the stamped spec
+		adoptionNodeName(0): {Spec: handWritten(0, 2)},
+		// hand-written, differs from the stamped spec
+		adoptionNodeName(1): {Spec: handWritten(1, 8)},
+		// created empty by the daemon
+		adoptionNodeName(2): {Spec: vrbv1.SriovVrbNodeConfigSpec{PhysicalFunctions: []vrbv1.PhysicalFunctionConfigExt{}}},
+		// written by cluster controller of older version, which did not label NodeConfigs
+		adoptionNodeName(3): {
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{vrbv1.WrittenByVersionAnnotation: "2.7.0"}},
+			Spec:       handWritten(3, 8),
+		},
+	}
+
+	objects := []client.Object{vrb1, adoptionClusterConfig("vrb2", "57c2", 4)}
+	for i := 0; i < len(nodeConfigs); i++ {
+		nc := nodeConfigs[adoptionNodeName(i)]
+		nc.Name, nc.Namespace = adoptionNodeName(i), NAMESPACE
+		nc.Labels = map[string]string{"team": "ran"}
+		nc.Status.Inventory = vrbv1.NodeInventory{SriovAccelerators: []vrbv1.SriovAccelerator{adoptionAccelerator(i)}}
+		objects = append(objects,
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{
+				Name:   adoptionNodeName(i),
+				Labels: map[string]string{"fpga.intel.com/intel-accelerator-present": ""},
+			}},
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "daemon-" + adoptionNodeName(i), Namespace: NAMESPACE, Labels: daemonPodLabels},
+				Spec:       corev1.PodSpec{NodeName: adoptionNodeName(i)},
+				Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+			},
+			nc,
+		)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	reconciler := &SriovVrbClusterConfigReconciler{Client: c, Log: log}
+	reconcile := func() {
+		_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{Namespace: NAMESPACE, Name: "vrb1"}})
+		g.Expect(err).ToNot(HaveOccurred())
+	}
+	getNodeConfig := func(name string) *vrbv1.SriovVrbNodeConfig {
+		nc := &vrbv1.SriovVrbNodeConfig{}
+		g.Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: NAMESPACE, Name: name}, nc)).To(Succeed())
+		return nc
+	}
+	getClusterConfig := func(name string) *vrbv1.SriovVrbClusterConfig {
+		cc := &vrbv1.SriovVrbClusterConfig{}
+		g.Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: NAMESPACE, Name: name}, cc)).To(Succeed())
+		return cc
+	}
+	vfAmount := func(vfAmount int) OmegaMatcher {
+		return ConsistOf(WithTransform(func(pf vrbv1.PhysicalFunctionConfigExt) int { return pf.VFAmount }, Equal(vfAmount)))
+	}
+
+	reconcile()
+
+	// equivalent NodeConfig is taken over without changing its spec
+	adopted := getNodeConfig(adoptionNodeName(0))
+	g.Expect(adopted.Spec).To(Equal(handWritten(0, 2)))
+	g.Expect(adopted.IsManaged()).To(BeTrue())
+	g.Expect(adopted.Labels).To(HaveKeyWithValue("team", "ran"))
+
+	// different NodeConfig is left untouched, differences are reported in decisions of the adopting config
+	notAdopted := getNodeConfig(adoptionNodeName(1))
+	g.Expect(notAdopted.Spec).To(Equal(handWritten(1, 8)))
+	g.Expect(notAdopted.IsManaged()).To(BeFalse())
+	g.Expect(getClusterConfig("vrb1").Status.NodeDecisions).To(ConsistOf(
+		vrbv1.NodeDecision{
+			NodeName: adoptionNodeName(1),
+			Reason: "no accelerator matched accelerator selector; NotAdopted: spec of hand-written NodeConfig differs - " +
+				"PF 0000:01:00.0 differs in vfAmount",
+		},
+		vrbv1.NodeDecision{NodeName: adoptionNodeName(3), Reason: "no accelerator matched accelerator selector"},
+	))
+	g.Expect(getClusterConfig("vrb2").Status.NodeDecisions).To(HaveLen(2))
+
+	// empty and managed NodeConfigs are stamped as usual
+	g.Expect(getNodeConfig(adoptionNodeName(2)).Spec.PhysicalFunctions).To(vfAmount(2))
+	g.Expect(getNodeConfig(adoptionNodeName(2)).IsManaged()).To(BeTrue())
+	g.Expect(getNodeConfig(adoptionNodeName(3)).Spec.PhysicalFunctions).To(vfAmount(4))
+	g.Expect(getNodeConfig(adoptionNodeName(3)).Labels).To(HaveKeyWithValue(vrbv1.ManagedByLabel, vrbv1.ManagedByClusterController))
+
+	// adopted NodeConfig is not rewritten anymore
+	resourceVersion := adopted.ResourceVersion
+	reconcile()
+	g.Expect(getNodeConfig(adoptionNodeName(0)).ResourceVersion).To(Equal(resourceVersion))
+
+	// without the annotation, hand-written NodeConfigs are overwritten
+	vrb1 = getClusterConfig("vrb1")
+	vrb1.Annotations = nil
+	g.Expect(c.Update(context.TODO(), vrb1)).To(Succeed())
+
+	reconcile()
+
+	g.Expect(getNodeConfig(adoptionNodeName(1)).Spec.PhysicalFunctions).To(vfAmount(4))
+	g.Expect(getClusterConfig("vrb1").Status.NodeDecisions).To(ConsistOf(
+		vrbv1.NodeDecision{NodeName: adoptionNodeName(1), Reason: "no accelerator matched accelerator selector"},
+		vrbv1.NodeDecision{NodeName: adoptionNodeName(3), Reason: "no accelerator matched accelerator selector"},
+	))
+}
+
+// TestNodeConfigsStampedBeforeAdoptionAreNotHandWritten covers upgrade from controller versions which neither labeled
+// nor annotated NodeConfigs: NodeConfigs of nodes selected by configs created before the adopting ones were stamped
+func TestNodeConfigsStampedBeforeAdoptionAreNotHandWritten(t *testing.T) {
+	g := NewWithT(t)
+
+	now := time.Now()
+	clusterConfig := func(name string, created time.Time, adopts bool) vrbv1.SriovVrbClusterConfig {
+		cc := *adoptionClusterConfig(name, "57c0", 2)
+		cc.CreationTimestamp = metav1.NewTime(created)
+		if adopts {
+			cc.Annotations = map[string]string{vrbv1.AdoptExistingNodeConfigsAnnotation: "true"}
+		}
+		return cc
+	}
+	selecting := func(configs ...vrbv1.SriovVrbClusterConfig) *orderedmap.OrderedMap[string, vrbv1.SriovVrbClusterConfig] {
+		acceleratorConfigContext := orderedmap.NewOrderedMap[string, vrbv1.SriovVrbClusterConfig]()
+		for i, cc := range configs {
+			acceleratorConfigContext.Set(adoptionAccelerator(2*i).PCIAddress, cc)
+		}
+		return acceleratorConfigContext
+	}
+	// unlabeled NodeConfig with spec, as written by hand or by a controller of old version
+	nc := &vrbv1.SriovVrbNodeConfig{Spec: vrbv1.SriovVrbNodeConfigSpec{PhysicalFunctions: []vrbv1.PhysicalFunctionConfigExt{
+		{PCIAddress: adoptionAccelerator(0).PCIAddress, VFAmount: 2},
+	}}}
+	adopting := clusterConfig("adopting", now, true)
+	old := clusterConfig("old", now.Add(-time.Hour), false)
+	recent := clusterConfig("recent", now.Add(time.Hour), false)
+
+	g.Expect(isHandWritten(nc, []vrbv1.SriovVrbClusterConfig{adopting}, selecting(adopting))).To(BeTrue())
+	g.Expect(isHandWritten(nc, []vrbv1.SriovVrbClusterConfig{adopting}, selecting(adopting, recent))).To(BeTrue())
+	g.Expect(isHandWritten(nc, []vrbv1.SriovVrbClusterConfig{adopting}, selecting(adopting, old))).To(BeFalse())
+	// config created before adopting ones adopts NodeConfigs itself
+	oldAdopting := clusterConfig("old-adopting", now.Add(-time.Hour), true)
+	g.Expect(isHandWritten(nc, []vrbv1.SriovVrbClusterConfig{adopting, oldAdopting}, selecting(oldAdopting))).To(BeTrue())
+
+	g.Expect(isHandWritten(&vrbv1.SriovVrbNodeConfig{}, []vrbv1.SriovVrbClusterConfig{adopting}, selecting(adopting))).To(BeFalse())
+}
@@ -25,6 +25,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -35,7 +36,9 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/assets"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/conditions"
 	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/drainhelper"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/operatorconfig"
 	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
 
 	secv1 "github.com/openshift/api/security/v1"
@@ -83,13 +86,32 @@ func main() {
 	ctrl.SetLogger(logr.New(utils.NewLogWrapper()))
 
 	config := ctrl.GetConfigOrDie()
+	c := createClient(config)
+
+	operatorConfig, err := operatorconfig.Load(c, operatorconfig.OperatorSettings, setupLog)
+	if err != nil {
+		setupLog.WithError(err).Error("failed to apply operator configuration")
+		os.Exit(1)
+	}
+
+	conditions.InitMessageLimit(setupLog)
+
 	mgr := createAndConfigureManager(config, metricsAddr, healthProbeAddr, enableLeaderElection)
 
 	initializeSriovFecClusterConfigReconciler(mgr)
 	initializeVrbClusterConfigReconciler(mgr)
 	// +kubebuilder:scaffold:builder
 
-	c := createClient(config)
+	ctx, restart := context.WithCancel(ctrl.SetupSignalHandler())
+	if err := (&operatorconfig.Reconciler{
+		Client:  mgr.GetClient(),
+		Log:     utils.NewLogger(),
+		Startup: operatorConfig,
+		Restart: restart,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.WithField("controller", "SriovFecOperatorConfig").WithError(err).Error("unable to create controller")
+		os.Exit(1)
+	}
 
 	operatorDeployment := assets.FetchOperatorDeployment(c, setupLog)
 
@@ -112,7 +134,7 @@ func main() {
 	}
 
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	if err := mgr.Start(ctx); err != nil {
 		setupLog.WithError(err).Error("problem running manager")
 		os.Exit(1)
 	}
@@ -171,11 +193,52 @@ func createClient(config *rest.Config) client.Client {
 	return c
 }
 
+const maxConcurrentReconcilesEnvVarName = utils.SRIOV_PREFIX + "MAX_CONCURRENT_RECONCILES"
+
+// maxConcurrentReconciles returns how many ClusterConfigs of each kind are reconciled at once; it is configured with
+// MAX_CONCURRENT_RECONCILES env variable and defaults to 1
+func maxConcurrentReconciles() int {
+	value := os.Getenv(maxConcurrentReconcilesEnvVarName)
+	if value == "" {
+		return 1
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit <= 0 {
+		setupLog.WithError(err).WithField("value", value).
+			Error("user-provided value is incorrect number of concurrent reconciles, using 1 instead")
+		return 1
+	}
+	return limit
+}
+
+const inventoryStalenessBoundEnvVarName = utils.SRIOV_PREFIX + "INVENTORY_STALENESS_BOUND"
+
+// inventoryStalenessBound returns maximum age of NodeConfig's inventory which ClusterConfigs are resolved against; it
+// is configured with INVENTORY_STALENESS_BOUND env variable (e.g. "10m", "0" disables the check) and defaults to 5m
+func inventoryStalenessBound() time.Duration {
+	const defaultBound = 5 * time.Minute
+	value := os.Getenv(inventoryStalenessBoundEnvVarName)
+	if value == "" {
+		return defaultBound
+	}
+	bound, err := time.ParseDuration(value)
+	if err != nil || bound < 0 {
+		setupLog.WithError(err).WithField("value", value).
+			Error("user-provided value is incorrect inventory staleness bound, using default instead")
+		return defaultBound
+	}
+	return bound
+}
+
 func initializeSriovFecClusterConfigReconciler(mgr manager.Manager) {
 	log := utils.NewLogger()
 	if err := (&controllers.SriovFecClusterConfigReconciler{
-		Client: mgr.GetClient(),
-		Log:    log,
+		Client:                  mgr.GetClient(),
+		APIReader:               mgr.GetAPIReader(),
+		Log:                     log,
+		MaxConcurrentReconciles: maxConcurrentReconciles(),
+		InventoryStalenessBound: inventoryStalenessBound(),
+		Recorder:                mgr.GetEventRecorderFor("sriov-fec-controller-manager"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.WithField("controller", "SriovFecClusterConfig").WithError(err).Error("unable to create controller")
 		os.Exit(1)
@@ -184,13 +247,21 @@ func initializeSriovFecClusterConfigReconciler(mgr manager.Manager) {
 		setupLog.WithError(err).WithField("webhook", "SriovFecClusterConfig").Error("unable to create webhook")
 		os.Exit(1)
 	}
+	if err := (&sriovfecv2.SriovFecNodeConfig{}).SetupWebhookWithManager(mgr); err != nil {
+		setupLog.WithError(err).WithField("webhook", "SriovFecNodeConfig").Error("unable to create webhook")
+		os.Exit(1)
+	}
 }
 
 func initializeVrbClusterConfigReconciler(mgr manager.Manager) {
 	log := utils.NewLogger()
 	if err := (&vrbcontrollers.SriovVrbClusterConfigReconciler{
-		Client: mgr.GetClient(),
-		Log:    log,
+		Client:                  mgr.GetClient(),
+		APIReader:               mgr.GetAPIReader(),
+		Log:                     log,
+		MaxConcurrentReconciles: maxConcurrentReconciles(),
+		InventoryStalenessBound: inventoryStalenessBound(),
+		Recorder:                mgr.GetEventRecorderFor("sriov-fec-controller-manager"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.WithField("controller", "SriovVrbClusterConfig").WithError(err).Error("unable to create controller")
 		os.Exit(1)
@@ -199,6 +270,10 @@ func initializeVrbClusterConfigReconciler(mgr manager.Manager) {
 		setupLog.WithError(err).WithField("webhook", "SriovVrbClusterConfig").Error("unable to create webhook")
 		os.Exit(1)
 	}
+	if err := (&sriovvrbv1.SriovVrbNodeConfig{}).SetupWebhookWithManager(mgr); err != nil {
+		setupLog.WithError(err).WithField("webhook", "SriovVrbNodeConfig").Error("unable to create webhook")
+		os.Exit(1)
+	}
 }
 
 func createAndConfigureManager(config *rest.Config, metricsAddr string, healthProbeAddr string, enableLeaderElection bool) manager.Manager {
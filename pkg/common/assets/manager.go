@@ -88,6 +88,13 @@ func (m *Manager) buildTemplateVars(ctx context.Context, setKernelVar bool) (map
 		tp[resourceNameVRB2] = "intel_vrb_vrb2"
 	}
 
+	// proxy settings of the operator (injected by OLM from cluster-wide proxy) are passed to the daemons
+	for _, name := range []string{"HTTPS_PROXY", "HTTP_PROXY", "NO_PROXY"} {
+		if tp[m.EnvPrefix+name] == "" {
+			tp[m.EnvPrefix+name] = os.Getenv(name)
+		}
+	}
+
 	if !setKernelVar {
 		return tp, nil
 	}
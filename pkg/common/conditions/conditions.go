@@ -0,0 +1,284 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+// Package conditions contains condition types and reasons used by node and cluster configs, together with helpers
+// setting them, so that daemon and cluster controllers report them consistently
+package conditions
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+const (
+	// TypeConfigured is managed by daemon and reflects configuration of accelerators on the node
+	TypeConfigured = "Configured"
+	// TypeConfigurationPropagation is managed by cluster controller and reflects propagation of cluster configs into
+	// node config
+	TypeConfigurationPropagation = "ConfigurationPropagationCondition"
+	// TypeResourceConsistency is managed by daemon and reflects whether VF counts requested in spec, exposed in sysfs,
+	// reported in inventory and allocatable on the node agree
+	TypeResourceConsistency = "ResourceConsistency"
+	// TypeDryRun is managed by cluster controller and reflects preview of cluster config with dry-run annotation
+	TypeDryRun = "DryRun"
+	// TypeDiscoveryConfigConsistent is managed by cluster controller and reflects whether all nodes report the same
+	// discovery config hash
+	TypeDiscoveryConfigConsistent = "DiscoveryConfigConsistent"
+	// typePFHealthyPrefix is followed by PCI address of physical function, see PFHealthyType
+	typePFHealthyPrefix = "PFHealthy-"
+)
+
+type Reason string
+
+const (
+	ReasonInProgress   Reason = "InProgress"
+	ReasonFailed       Reason = "Failed"
+	ReasonNotRequested Reason = "NotRequested"
+	ReasonSucceeded    Reason = "Succeeded"
+	// ReasonIncompatibleEnvironment indicates that kernel or firmware violates compatibility checks
+	ReasonIncompatibleEnvironment Reason = "IncompatibleEnvironment"
+	// ReasonConfigurationHalted indicates that configuration is postponed due to cluster-wide emergency stop
+	ReasonConfigurationHalted Reason = "ConfigurationHalted"
+	// ReasonUnsupportedDevice indicates that requested accelerator is present, but missing in the discovery config
+	ReasonUnsupportedDevice Reason = "UnsupportedDevice"
+	ReasonHealthy           Reason = "Healthy"
+	// ReasonDegraded indicates that device reports errors
+	ReasonDegraded   Reason = "Degraded"
+	ReasonConsistent Reason = "Consistent"
+	// ReasonInconsistent indicates that VF counts of PF differ between spec, sysfs, inventory and node allocatable, or
+	// that nodes report different discovery configs
+	ReasonInconsistent Reason = "Inconsistent"
+	// ReasonInsufficientPrivileges indicates that the daemon container lacks capabilities or host mounts needed to configure accelerators
+	ReasonInsufficientPrivileges Reason = "InsufficientPrivileges"
+	// ReasonDeviceInUse indicates that configuration without drain would remove or rebind VFs allocated to running pods
+	ReasonDeviceInUse Reason = "DeviceInUse"
+	// ReasonConfigurationDeferred indicates that configuration waits for the node to be rebooted with required kernel
+	// params by Machine Config Operator, or for node config written by operator version the daemon knows
+	ReasonConfigurationDeferred Reason = "ConfigurationDeferred"
+	// ReasonNoAcceleratorsDiscovered indicates that spec requests PFs, but no supported accelerators are discovered on
+	// the node, e.g. when device died or its driver is blacklisted
+	ReasonNoAcceleratorsDiscovered Reason = "NoAcceleratorsDiscovered"
+	// ReasonConfigurationGivenUp indicates that configuration failed more times than maxConfigurationRetries allows and
+	// is not retried until the spec or the force-reconfigure annotation of the node config changes
+	ReasonConfigurationGivenUp Reason = "ConfigurationGivenUp"
+	// ReasonRebootRequested indicates that the daemon reboots the node, as pf-bb-config failed on the same PF more times
+	// in a row than autoRebootOnPersistentFailure allows
+	ReasonRebootRequested Reason = "RebootRequested"
+	// ReasonPreviewed indicates that cluster config is only previewed, NodeConfigs are not modified by it
+	ReasonPreviewed Reason = "Previewed"
+)
+
+// Configured returns Configured condition; generation is the spec generation reflected by the condition
+func Configured(status metav1.ConditionStatus, reason Reason, msg string, generation int64) metav1.Condition {
+	return newCondition(TypeConfigured, status, reason, msg, generation)
+}
+
+// ConfigurationPropagation returns ConfigurationPropagationCondition condition
+func ConfigurationPropagation(status metav1.ConditionStatus, reason Reason, msg string, generation int64) metav1.Condition {
+	return newCondition(TypeConfigurationPropagation, status, reason, msg, generation)
+}
+
+// DryRun returns DryRun condition of cluster config
+func DryRun(status metav1.ConditionStatus, reason Reason, msg string, generation int64) metav1.Condition {
+	return newCondition(TypeDryRun, status, reason, msg, generation)
+}
+
+// DiscoveryConfigConsistent returns condition reflecting whether nodes report the same discovery config
+func DiscoveryConfigConsistent(status metav1.ConditionStatus, reason Reason, msg string, generation int64) metav1.Condition {
+	return newCondition(TypeDiscoveryConfigConsistent, status, reason, msg, generation)
+}
+
+// PFHealthyType returns type of health condition of physical function; ':' is not allowed in condition type, so it is
+// replaced with '-', e.g. PFHealthy-0000-f7-00.0
+func PFHealthyType(pciAddress string) string {
+	return typePFHealthyPrefix + strings.ReplaceAll(pciAddress, ":", "-")
+}
+
+// PFHealthy returns health condition of physical function identified by pciAddress
+func PFHealthy(pciAddress string, status metav1.ConditionStatus, reason Reason, msg string, generation int64) metav1.Condition {
+	return newCondition(PFHealthyType(pciAddress), status, reason, msg, generation)
+}
+
+// ResourceConsistency returns ResourceConsistency condition of node config
+func ResourceConsistency(status metav1.ConditionStatus, reason Reason, msg string, generation int64) metav1.Condition {
+	return newCondition(TypeResourceConsistency, status, reason, msg, generation)
+}
+
+func newCondition(conditionType string, status metav1.ConditionStatus, reason Reason, msg string, generation int64) metav1.Condition {
+	return metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             string(reason),
+		Message:            msg,
+		ObservedGeneration: generation,
+	}
+}
+
+const (
+	// MessageLimitEnvVarName configures maximum size of condition messages in bytes, see InitMessageLimit
+	MessageLimitEnvVarName = utils.SRIOV_PREFIX + "CONDITION_MESSAGE_LIMIT_BYTES"
+	DefaultMessageLimit    = 1024
+	// truncated message has to leave room for the pointer to full details
+	minMessageLimit = 256
+	// metav1.Condition.Message is validated to be at most 32768 bytes long
+	maxMessageLimit = 32768
+	ellipsis        = "…"
+)
+
+// messageLimit is maximum size of condition message in bytes, see Bounded
+var messageLimit = DefaultMessageLimit
+
+// InitMessageLimit reads maximum size of condition messages from MessageLimitEnvVarName env variable; default is used
+// when the variable is not set or its value is out of range
+func InitMessageLimit(log *logrus.Logger) int {
+	messageLimit = DefaultMessageLimit
+	if limitEnv := os.Getenv(MessageLimitEnvVarName); limitEnv != "" {
+		limit, err := strconv.Atoi(limitEnv)
+		if err != nil || limit < minMessageLimit || limit > maxMessageLimit {
+			log.WithError(err).WithField("default", DefaultMessageLimit).WithField("min", minMessageLimit).
+				WithField("max", maxMessageLimit).Error("user-provided value is incorrect number of bytes, using default value instead")
+		} else {
+			messageLimit = limit
+		}
+	}
+	return messageLimit
+}
+
+// EventDetails returns pointer to event of given reason, which holds full text of truncated message
+func EventDetails(reason string) string {
+	return "event " + reason
+}
+
+// TruncateMessage returns msg when it fits into limit bytes; otherwise msg is cut on rune boundary and followed by an
+// ellipsis and pointer to full details, e.g. "… (full details in event Configured)", so that the result fits into limit
+func TruncateMessage(msg string, limit int, details string) string {
+	if len(msg) <= limit {
+		return msg
+	}
+	suffix := fmt.Sprintf("%s (full details in %s)", ellipsis, details)
+	cut := limit - len(suffix)
+	if cut < 0 {
+		cut = 0
+	}
+	for cut > 0 && !utf8.RuneStart(msg[cut]) {
+		cut--
+	}
+	return msg[:cut] + suffix
+}
+
+// Bounded returns condition with message truncated to the configured limit. When the message is truncated, it points
+// to event whose reason is type of the condition, and the full message is returned as well - caller is responsible
+// for emitting that event. Empty full is returned when the message fits.
+func Bounded(condition metav1.Condition) (bounded metav1.Condition, full string) {
+	if len(condition.Message) <= messageLimit {
+		return condition, ""
+	}
+	full = condition.Message
+	condition.Message = TruncateMessage(full, messageLimit, EventDetails(condition.Type))
+	return condition, full
+}
+
+// Equivalent compares conditions ignoring lastTransitionTime
+func Equivalent(a, b metav1.Condition) bool {
+	a.LastTransitionTime, b.LastTransitionTime = metav1.Time{}, metav1.Time{}
+	return a == b
+}
+
+// IsSet returns true when conditions contain condition equal to given one (ignoring lastTransitionTime)
+func IsSet(conditions []metav1.Condition, condition metav1.Condition) bool {
+	existing := meta.FindStatusCondition(conditions, condition.Type)
+	return existing != nil && Equivalent(*existing, condition)
+}
+
+// SetIfChanged sets condition in conditions and reports whether they were modified, i.e. whether the status
+// needs to be written
+func SetIfChanged(conditions *[]metav1.Condition, condition metav1.Condition) bool {
+	if IsSet(*conditions, condition) {
+		return false
+	}
+	meta.SetStatusCondition(conditions, condition)
+	return true
+}
+
+// CurrentSchemaVersion is version of node config status written by current daemon; status of lower version is migrated
+// with MigrateLegacy once
+const CurrentSchemaVersion = 1
+
+var (
+	// legacyTypes are condition types owned by older versions of the operator, which are not reported anymore
+	legacyTypes = map[string]bool{
+		// N3000 flashing is not supported since v2 API
+		"flashed": true,
+	}
+
+	// legacyReasons maps lowercase reasons used by older versions of the operator to current ones
+	legacyReasons = map[string]Reason{
+		"configurationinprogress":   ReasonInProgress,
+		"configurationfailed":       ReasonFailed,
+		"configurationnotrequested": ReasonNotRequested,
+		"configurationsucceeded":    ReasonSucceeded,
+	}
+
+	currentTypes = []string{TypeConfigured, TypeConfigurationPropagation, TypeResourceConsistency, TypeAvailable,
+		TypeProgressing, TypeDegraded}
+
+	currentReasons = []Reason{ReasonInProgress, ReasonFailed, ReasonNotRequested, ReasonSucceeded,
+		ReasonIncompatibleEnvironment, ReasonConfigurationHalted, ReasonUnsupportedDevice, ReasonHealthy, ReasonDegraded,
+		ReasonConsistent, ReasonInconsistent, ReasonInsufficientPrivileges, ReasonDeviceInUse}
+)
+
+// MigrateLegacy normalizes conditions written by older versions of the operator: types and reasons are matched
+// case-insensitively and renamed to current constants, legacy types are dropped and from conditions which end up with
+// the same type only the most recently transitioned one is kept. Conditions of unknown types are kept untouched.
+func MigrateLegacy(conditions []metav1.Condition) []metav1.Condition {
+	var migrated []metav1.Condition
+	for _, condition := range conditions {
+		if legacyTypes[strings.ToLower(condition.Type)] {
+			continue
+		}
+		condition.Type = normalizeType(condition.Type)
+		condition.Reason = normalizeReason(condition.Reason)
+
+		if existing := meta.FindStatusCondition(migrated, condition.Type); existing != nil {
+			if condition.LastTransitionTime.After(existing.LastTransitionTime.Time) {
+				*existing = condition
+			}
+			continue
+		}
+		migrated = append(migrated, condition)
+	}
+	return migrated
+}
+
+func normalizeType(conditionType string) string {
+	for _, current := range currentTypes {
+		if strings.EqualFold(conditionType, current) {
+			return current
+		}
+	}
+	if len(conditionType) > len(typePFHealthyPrefix) && strings.EqualFold(conditionType[:len(typePFHealthyPrefix)], typePFHealthyPrefix) {
+		return typePFHealthyPrefix + conditionType[len(typePFHealthyPrefix):]
+	}
+	return conditionType
+}
+
+func normalizeReason(reason string) string {
+	if current, ok := legacyReasons[strings.ToLower(reason)]; ok {
+		return string(current)
+	}
+	for _, current := range currentReasons {
+		if strings.EqualFold(reason, string(current)) {
+			return string(current)
+		}
+	}
+	return reason
+}
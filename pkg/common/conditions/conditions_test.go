@@ -0,0 +1,196 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package conditions
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestConditions(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Conditions suite")
+}
+
+var _ = Describe("Conditions", func() {
+	It("should construct typed conditions", func() {
+		Expect(Configured(metav1.ConditionTrue, ReasonSucceeded, "Configured successfully", 3)).To(Equal(metav1.Condition{
+			Type:               TypeConfigured,
+			Status:             metav1.ConditionTrue,
+			Reason:             "Succeeded",
+			Message:            "Configured successfully",
+			ObservedGeneration: 3,
+		}))
+		Expect(ConfigurationPropagation(metav1.ConditionFalse, ReasonFailed, "", 1).Type).
+			To(Equal("ConfigurationPropagationCondition"))
+		Expect(PFHealthy("0000:f7:00.0", metav1.ConditionFalse, ReasonDegraded, "", 1).Type).
+			To(Equal("PFHealthy-0000-f7-00.0"))
+		Expect(ResourceConsistency(metav1.ConditionFalse, ReasonInconsistent, "", 1).Type).
+			To(Equal("ResourceConsistency"))
+	})
+
+	It("should compare conditions ignoring lastTransitionTime", func() {
+		a := Configured(metav1.ConditionFalse, ReasonFailed, "failed", 1)
+		b := a
+		b.LastTransitionTime = metav1.NewTime(time.Now())
+		Expect(Equivalent(a, b)).To(BeTrue())
+
+		b.Message = "failed again"
+		Expect(Equivalent(a, b)).To(BeFalse())
+	})
+
+	It("should report whether condition was changed", func() {
+		var conditions []metav1.Condition
+		inProgress := Configured(metav1.ConditionFalse, ReasonInProgress, "Configuration started", 1)
+
+		Expect(SetIfChanged(&conditions, inProgress)).To(BeTrue())
+		transitionTime := meta.FindStatusCondition(conditions, TypeConfigured).LastTransitionTime
+		Expect(transitionTime.IsZero()).To(BeFalse())
+
+		Expect(SetIfChanged(&conditions, inProgress)).To(BeFalse())
+		Expect(IsSet(conditions, inProgress)).To(BeTrue())
+
+		Expect(SetIfChanged(&conditions, Configured(metav1.ConditionFalse, ReasonFailed, "error", 1))).To(BeTrue())
+		Expect(conditions).To(HaveLen(1))
+		// status did not change, so transition time is kept
+		Expect(meta.FindStatusCondition(conditions, TypeConfigured).LastTransitionTime).To(Equal(transitionTime))
+
+		Expect(SetIfChanged(&conditions, ConfigurationPropagation(metav1.ConditionFalse, ReasonFailed, "error", 1))).To(BeTrue())
+		Expect(conditions).To(HaveLen(2))
+	})
+})
+
+var _ = Describe("Message limit", func() {
+	AfterEach(func() {
+		Expect(os.Unsetenv(MessageLimitEnvVarName)).To(Succeed())
+		messageLimit = DefaultMessageLimit
+	})
+
+	It("should keep message which fits into the limit", func() {
+		Expect(TruncateMessage("pf_bb_config failed", 19, EventDetails(TypeConfigured))).To(Equal("pf_bb_config failed"))
+	})
+
+	It("should end truncated message with ellipsis and pointer to full details", func() {
+		truncated := TruncateMessage(strings.Repeat("a", 100), 60, EventDetails(TypeConfigured))
+		Expect(truncated).To(Equal(strings.Repeat("a", 22) + "… (full details in event Configured)"))
+		Expect(truncated).To(HaveLen(60))
+	})
+
+	It("should not split multi-byte characters", func() {
+		// "ł" takes 2 bytes and "€" 3 bytes
+		for _, msg := range []string{strings.Repeat("ł", 50), strings.Repeat("€", 50), "a" + strings.Repeat("€", 50)} {
+			for limit := 40; limit < 48; limit++ {
+				truncated := TruncateMessage(msg, limit, EventDetails(TypeConfigured))
+				Expect(utf8.ValidString(truncated)).To(BeTrue(), "%q truncated to %d bytes", msg, limit)
+				Expect(len(truncated)).To(BeNumerically("<=", limit))
+				Expect(len(truncated)).To(BeNumerically(">", limit-3))
+				Expect(truncated).To(HaveSuffix("… (full details in event Configured)"))
+			}
+		}
+	})
+
+	It("should truncate message of condition and return the full one", func() {
+		msg := "pf_bb_config failed: " + strings.Repeat("error ", 200)
+		bounded, full := Bounded(Configured(metav1.ConditionFalse, ReasonFailed, msg, 2))
+		Expect(full).To(Equal(msg))
+		Expect(bounded.Message).To(HaveLen(DefaultMessageLimit))
+		Expect(bounded.Message).To(HavePrefix("pf_bb_config failed: error "))
+		Expect(bounded.Message).To(HaveSuffix("… (full details in event Configured)"))
+		Expect(bounded.Reason).To(Equal(string(ReasonFailed)))
+		Expect(bounded.ObservedGeneration).To(Equal(int64(2)))
+
+		bounded, full = Bounded(PFHealthy("0000:f7:00.0", metav1.ConditionFalse, ReasonDegraded, msg, 1))
+		Expect(full).To(Equal(msg))
+		Expect(bounded.Message).To(HaveSuffix("… (full details in event PFHealthy-0000-f7-00.0)"))
+
+		short := Configured(metav1.ConditionTrue, ReasonSucceeded, "Configured successfully", 2)
+		bounded, full = Bounded(short)
+		Expect(full).To(BeEmpty())
+		Expect(bounded).To(Equal(short))
+	})
+
+	It("should read the limit from env variable", func() {
+		log := logrus.New()
+		log.SetOutput(io.Discard)
+		Expect(InitMessageLimit(log)).To(Equal(DefaultMessageLimit))
+
+		Expect(os.Setenv(MessageLimitEnvVarName, "2048")).To(Succeed())
+		Expect(InitMessageLimit(log)).To(Equal(2048))
+		_, full := Bounded(Configured(metav1.ConditionFalse, ReasonFailed, strings.Repeat("a", 2000), 1))
+		Expect(full).To(BeEmpty())
+
+		for _, invalid := range []string{"1kB", "10", "65536"} {
+			Expect(os.Setenv(MessageLimitEnvVarName, invalid)).To(Succeed())
+			Expect(InitMessageLimit(log)).To(Equal(DefaultMessageLimit), invalid)
+		}
+	})
+})
+
+var _ = Describe("MigrateLegacy", func() {
+	parse := func(payload string) []metav1.Condition {
+		var parsed []metav1.Condition
+		Expect(json.Unmarshal([]byte(payload), &parsed)).To(Succeed())
+		return parsed
+	}
+
+	It("should keep current conditions untouched", func() {
+		current := parse(`[
+			{"type": "Configured", "status": "True", "reason": "Succeeded", "message": "Configured successfully", "observedGeneration": 2, "lastTransitionTime": "2023-03-01T10:00:00Z"},
+			{"type": "ConfigurationPropagationCondition", "status": "True", "reason": "Succeeded", "message": "", "lastTransitionTime": "2023-03-01T09:00:00Z"},
+			{"type": "PFHealthy-0000-f7-00.0", "status": "True", "reason": "Healthy", "message": "", "lastTransitionTime": "2023-03-01T09:00:00Z"}
+		]`)
+		Expect(MigrateLegacy(current)).To(Equal(current))
+	})
+
+	It("should rename legacy reasons and types differing in casing", func() {
+		migrated := MigrateLegacy(parse(`[
+			{"type": "configured", "status": "False", "reason": "ConfigurationInProgress", "message": "Configuration started", "lastTransitionTime": "2022-06-01T10:00:00Z"},
+			{"type": "resourceconsistency", "status": "True", "reason": "consistent", "message": "", "lastTransitionTime": "2022-06-01T10:00:00Z"},
+			{"type": "pfhealthy-0000-f7-00.0", "status": "False", "reason": "DEGRADED", "message": "", "lastTransitionTime": "2022-06-01T10:00:00Z"}
+		]`))
+		Expect(migrated).To(HaveLen(3))
+		Expect(migrated[0].Type).To(Equal(TypeConfigured))
+		Expect(migrated[0].Reason).To(Equal(string(ReasonInProgress)))
+		Expect(migrated[0].Message).To(Equal("Configuration started"))
+		Expect(migrated[1].Type).To(Equal(TypeResourceConsistency))
+		Expect(migrated[1].Reason).To(Equal(string(ReasonConsistent)))
+		Expect(migrated[2].Type).To(Equal("PFHealthy-0000-f7-00.0"))
+		Expect(migrated[2].Reason).To(Equal(string(ReasonDegraded)))
+	})
+
+	It("should keep the most recent of duplicated conditions", func() {
+		migrated := MigrateLegacy(parse(`[
+			{"type": "CONFIGURED", "status": "False", "reason": "ConfigurationFailed", "message": "old failure", "lastTransitionTime": "2021-01-01T10:00:00Z"},
+			{"type": "Configured", "status": "True", "reason": "ConfigurationSucceeded", "message": "Configured successfully", "lastTransitionTime": "2022-01-01T10:00:00Z"},
+			{"type": "configured", "status": "False", "reason": "InProgress", "message": "older", "lastTransitionTime": "2020-01-01T10:00:00Z"}
+		]`))
+		Expect(migrated).To(HaveLen(1))
+		Expect(migrated[0].Type).To(Equal(TypeConfigured))
+		Expect(migrated[0].Status).To(Equal(metav1.ConditionTrue))
+		Expect(migrated[0].Reason).To(Equal(string(ReasonSucceeded)))
+		Expect(migrated[0].Message).To(Equal("Configured successfully"))
+	})
+
+	It("should drop legacy types and keep unknown ones", func() {
+		migrated := MigrateLegacy(parse(`[
+			{"type": "Flashed", "status": "True", "reason": "Succeeded", "message": "", "lastTransitionTime": "2020-01-01T10:00:00Z"},
+			{"type": "Configured", "status": "True", "reason": "Succeeded", "message": "", "lastTransitionTime": "2022-01-01T10:00:00Z"},
+			{"type": "example.com/Audited", "status": "True", "reason": "Done", "message": "", "lastTransitionTime": "2022-01-01T10:00:00Z"}
+		]`))
+		Expect(migrated).To(HaveLen(2))
+		Expect(meta.FindStatusCondition(migrated, "Flashed")).To(BeNil())
+		Expect(meta.FindStatusCondition(migrated, "example.com/Audited").Reason).To(Equal("Done"))
+	})
+})
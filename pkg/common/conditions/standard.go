@@ -0,0 +1,116 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package conditions
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Conventional condition types of Kubernetes, derived from Configured condition with Standard; Configured is kept for
+// backwards compatibility and is deprecated in favor of them
+const (
+	// TypeAvailable reflects whether the last configuration succeeded and consistency checks pass
+	TypeAvailable = "Available"
+	// TypeProgressing reflects whether configuration, drain or reboot is in flight
+	TypeProgressing = "Progressing"
+	// TypeDegraded reflects whether configuration failed, or applied configuration drifted or degraded
+	TypeDegraded = "Degraded"
+)
+
+// standardState are statuses of Available, Progressing and Degraded conditions in given state of configuration
+type standardState struct {
+	available   metav1.ConditionStatus
+	progressing metav1.ConditionStatus
+	degraded    metav1.ConditionStatus
+}
+
+var (
+	configurationSucceeded = standardState{metav1.ConditionTrue, metav1.ConditionFalse, metav1.ConditionFalse}
+	configurationFailed    = standardState{metav1.ConditionFalse, metav1.ConditionFalse, metav1.ConditionTrue}
+	configurationWaiting   = standardState{metav1.ConditionFalse, metav1.ConditionTrue, metav1.ConditionFalse}
+	configurationUnknown   = standardState{metav1.ConditionUnknown, metav1.ConditionUnknown, metav1.ConditionUnknown}
+
+	// standardStates are states of configuration by reason of Configured condition
+	standardStates = map[Reason]standardState{
+		ReasonSucceeded: configurationSucceeded,
+		// node config without PFs requests nothing, which is available
+		ReasonNotRequested: configurationSucceeded,
+		// InProgress is reported from the moment new spec is queued until the drain and configuration complete
+		ReasonInProgress:            configurationWaiting,
+		ReasonConfigurationDeferred: configurationWaiting,
+		// node is rebooted to recover device which failed repeatedly
+		ReasonRebootRequested:          {metav1.ConditionFalse, metav1.ConditionTrue, metav1.ConditionTrue},
+		ReasonFailed:                   configurationFailed,
+		ReasonConfigurationGivenUp:     configurationFailed,
+		ReasonIncompatibleEnvironment:  configurationFailed,
+		ReasonUnsupportedDevice:        configurationFailed,
+		ReasonInsufficientPrivileges:   configurationFailed,
+		ReasonDeviceInUse:              configurationFailed,
+		ReasonNoAcceleratorsDiscovered: configurationFailed,
+		// emergency stop is intended by the admin, the node is not degraded by it
+		ReasonConfigurationHalted: {metav1.ConditionFalse, metav1.ConditionFalse, metav1.ConditionFalse},
+	}
+)
+
+// Standard returns Available, Progressing and Degraded conditions of node config of given generation, derived from its
+// Configured condition. Succeeded configuration is not Available, but Degraded, when ResourceConsistency condition of
+// the generation reports inconsistent VF counts; it is Degraded as well while health condition of any PF is false.
+// Reason and message are the ones of the condition which determines the status. Conditions of unknown status are
+// returned for unknown reason of Configured condition and none when Configured condition is not set.
+func Standard(nodeConditions []metav1.Condition, generation int64) []metav1.Condition {
+	configured := meta.FindStatusCondition(nodeConditions, TypeConfigured)
+	if configured == nil {
+		return nil
+	}
+
+	state, ok := standardStates[Reason(configured.Reason)]
+	if !ok {
+		return newStandard(configurationUnknown, *configured, *configured, *configured, generation)
+	}
+	available, degraded := *configured, *configured
+	if state == configurationSucceeded {
+		if consistency := meta.FindStatusCondition(nodeConditions, TypeResourceConsistency); consistency != nil &&
+			consistency.Status == metav1.ConditionFalse && consistency.ObservedGeneration == generation {
+			state = configurationFailed
+			available, degraded = *consistency, *consistency
+		} else if health := unhealthyPF(nodeConditions); health != nil {
+			state.degraded = metav1.ConditionTrue
+			degraded = *health
+		}
+	}
+	return newStandard(state, available, *configured, degraded, generation)
+}
+
+// unhealthyPF returns the first health condition of PF which is false
+func unhealthyPF(nodeConditions []metav1.Condition) *metav1.Condition {
+	for i := range nodeConditions {
+		if strings.HasPrefix(nodeConditions[i].Type, typePFHealthyPrefix) && nodeConditions[i].Status == metav1.ConditionFalse {
+			return &nodeConditions[i]
+		}
+	}
+	return nil
+}
+
+func newStandard(state standardState, available, progressing, degraded metav1.Condition, generation int64) []metav1.Condition {
+	return []metav1.Condition{
+		newCondition(TypeAvailable, state.available, Reason(available.Reason), available.Message, generation),
+		newCondition(TypeProgressing, state.progressing, Reason(progressing.Reason), progressing.Message, generation),
+		newCondition(TypeDegraded, state.degraded, Reason(degraded.Reason), degraded.Message, generation),
+	}
+}
+
+// SetStandard sets conditions derived with Standard into conditions of node config and reports whether they were
+// modified
+func SetStandard(nodeConditions *[]metav1.Condition, generation int64) bool {
+	changed := false
+	for _, condition := range Standard(*nodeConditions, generation) {
+		if SetIfChanged(nodeConditions, condition) {
+			changed = true
+		}
+	}
+	return changed
+}
@@ -0,0 +1,108 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package conditions
+
+import (
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Standard conditions", func() {
+	const generation = 3
+
+	type trio struct {
+		available, progressing, degraded metav1.ConditionStatus
+		// reason of Degraded condition when it is true, of Available condition otherwise; reason of Progressing is the one
+		// of Configured
+		reason Reason
+	}
+
+	var (
+		succeeded = Configured(metav1.ConditionTrue, ReasonSucceeded, "Configured successfully", generation)
+		healthy   = PFHealthy("0000:f7:00.0", metav1.ConditionTrue, ReasonHealthy, "", generation)
+		degraded  = PFHealthy("0000:f7:00.0", metav1.ConditionFalse, ReasonDegraded, "uncorrectable AER errors", generation)
+		// VF counts are checked only in steady state, i.e. once the generation is configured successfully
+		consistent   = ResourceConsistency(metav1.ConditionTrue, ReasonConsistent, "", generation)
+		inconsistent = ResourceConsistency(metav1.ConditionFalse, ReasonInconsistent, "VF counts differ", generation)
+		// staleInconsistent was reported for the previous generation
+		staleInconsistent = ResourceConsistency(metav1.ConditionFalse, ReasonInconsistent, "VF counts differ", generation-1)
+	)
+
+	configured := func(status metav1.ConditionStatus, reason Reason) metav1.Condition {
+		return Configured(status, reason, "message of "+string(reason), generation)
+	}
+
+	table.DescribeTable("are derived from state of configuration",
+		func(expected trio, nodeConditions ...metav1.Condition) {
+			derived := Standard(nodeConditions, generation)
+
+			Expect(derived).To(HaveLen(3))
+			Expect(derived[0]).To(And(HaveField("Type", TypeAvailable), HaveField("Status", expected.available),
+				HaveField("ObservedGeneration", int64(generation))))
+			Expect(derived[1]).To(And(HaveField("Type", TypeProgressing), HaveField("Status", expected.progressing),
+				HaveField("Reason", nodeConditions[0].Reason), HaveField("Message", nodeConditions[0].Message)))
+			Expect(derived[2]).To(And(HaveField("Type", TypeDegraded), HaveField("Status", expected.degraded)))
+			if expected.degraded == metav1.ConditionTrue {
+				Expect(derived[2].Reason).To(Equal(string(expected.reason)))
+			} else {
+				Expect(derived[0].Reason).To(Equal(string(expected.reason)))
+			}
+		},
+		table.Entry("succeeded", trio{metav1.ConditionTrue, metav1.ConditionFalse, metav1.ConditionFalse, ReasonSucceeded}, succeeded),
+		table.Entry("succeeded, consistent and healthy", trio{metav1.ConditionTrue, metav1.ConditionFalse, metav1.ConditionFalse, ReasonSucceeded},
+			succeeded, consistent, healthy),
+		table.Entry("succeeded, drifted", trio{metav1.ConditionFalse, metav1.ConditionFalse, metav1.ConditionTrue, ReasonInconsistent},
+			succeeded, inconsistent),
+		table.Entry("succeeded, drifted before reconfiguration", trio{metav1.ConditionTrue, metav1.ConditionFalse, metav1.ConditionFalse, ReasonSucceeded},
+			succeeded, staleInconsistent),
+		table.Entry("succeeded, PF degraded", trio{metav1.ConditionTrue, metav1.ConditionFalse, metav1.ConditionTrue, ReasonDegraded},
+			succeeded, degraded),
+		table.Entry("not requested", trio{metav1.ConditionTrue, metav1.ConditionFalse, metav1.ConditionFalse, ReasonNotRequested},
+			configured(metav1.ConditionFalse, ReasonNotRequested)),
+		table.Entry("in progress", trio{metav1.ConditionFalse, metav1.ConditionTrue, metav1.ConditionFalse, ReasonInProgress},
+			configured(metav1.ConditionFalse, ReasonInProgress), inconsistent),
+		table.Entry("deferred", trio{metav1.ConditionFalse, metav1.ConditionTrue, metav1.ConditionFalse, ReasonConfigurationDeferred},
+			configured(metav1.ConditionFalse, ReasonConfigurationDeferred)),
+		table.Entry("reboot requested", trio{metav1.ConditionFalse, metav1.ConditionTrue, metav1.ConditionTrue, ReasonRebootRequested},
+			configured(metav1.ConditionFalse, ReasonRebootRequested)),
+		table.Entry("halted", trio{metav1.ConditionFalse, metav1.ConditionFalse, metav1.ConditionFalse, ReasonConfigurationHalted},
+			configured(metav1.ConditionFalse, ReasonConfigurationHalted)),
+		table.Entry("failed", trio{metav1.ConditionFalse, metav1.ConditionFalse, metav1.ConditionTrue, ReasonFailed},
+			configured(metav1.ConditionFalse, ReasonFailed), degraded),
+		table.Entry("given up", trio{metav1.ConditionFalse, metav1.ConditionFalse, metav1.ConditionTrue, ReasonConfigurationGivenUp},
+			configured(metav1.ConditionFalse, ReasonConfigurationGivenUp)),
+		table.Entry("incompatible environment", trio{metav1.ConditionFalse, metav1.ConditionFalse, metav1.ConditionTrue, ReasonIncompatibleEnvironment},
+			configured(metav1.ConditionFalse, ReasonIncompatibleEnvironment)),
+		table.Entry("unsupported device", trio{metav1.ConditionFalse, metav1.ConditionFalse, metav1.ConditionTrue, ReasonUnsupportedDevice},
+			configured(metav1.ConditionFalse, ReasonUnsupportedDevice)),
+		table.Entry("insufficient privileges", trio{metav1.ConditionFalse, metav1.ConditionFalse, metav1.ConditionTrue, ReasonInsufficientPrivileges},
+			configured(metav1.ConditionFalse, ReasonInsufficientPrivileges)),
+		table.Entry("device in use", trio{metav1.ConditionFalse, metav1.ConditionFalse, metav1.ConditionTrue, ReasonDeviceInUse},
+			configured(metav1.ConditionFalse, ReasonDeviceInUse)),
+		table.Entry("no accelerators discovered", trio{metav1.ConditionFalse, metav1.ConditionFalse, metav1.ConditionTrue, ReasonNoAcceleratorsDiscovered},
+			configured(metav1.ConditionFalse, ReasonNoAcceleratorsDiscovered)),
+		table.Entry("unknown reason", trio{metav1.ConditionUnknown, metav1.ConditionUnknown, metav1.ConditionUnknown, "Flashing"},
+			configured(metav1.ConditionFalse, "Flashing")),
+	)
+
+	It("derives nothing without Configured condition", func() {
+		Expect(Standard([]metav1.Condition{inconsistent}, generation)).To(BeEmpty())
+	})
+
+	It("sets derived conditions only when they change", func() {
+		nodeConditions := []metav1.Condition{configured(metav1.ConditionFalse, ReasonInProgress)}
+
+		Expect(SetStandard(&nodeConditions, generation)).To(BeTrue())
+		Expect(nodeConditions).To(HaveLen(4))
+		Expect(SetStandard(&nodeConditions, generation)).To(BeFalse())
+
+		meta.SetStatusCondition(&nodeConditions, succeeded)
+		Expect(SetStandard(&nodeConditions, generation)).To(BeTrue())
+		Expect(meta.IsStatusConditionTrue(nodeConditions, TypeAvailable)).To(BeTrue())
+		Expect(meta.IsStatusConditionFalse(nodeConditions, TypeProgressing)).To(BeTrue())
+	})
+})
@@ -0,0 +1,22 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package drainhelper
+
+import "context"
+
+// Drainer takes the node out of service for the time of a worker function. DrainHelper is the implementation used by
+// the daemon, FakeDrainer is meant for unit tests of its users.
+type Drainer interface {
+	// Run executes f while holding the drain lease of the cluster; node is cordoned and drained before f is executed
+	// when drain is set. f returns whether node should be uncordoned afterwards (only applicable if drain is set).
+	// Context passed to f carries progress hooks: steps reported with ReportProgress renew the lease and notify
+	// reporters registered with WithProgressReporter on the parent context. Pods are removed as requested by
+	// DrainOptions set on ctx with WithDrainOptions.
+	Run(ctx context.Context, f func(context.Context) bool, drain bool) error
+	// VerifyRescheduling checks whether pods evicted during the last Run are running again; returns nil if nothing was
+	// evicted
+	VerifyRescheduling(ctx context.Context) *ReschedulingSummary
+}
+
+var _ Drainer = &DrainHelper{}
@@ -5,20 +5,26 @@ package drainhelper
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/sirupsen/logrus"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
 	clientset "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/leaderelection"
 	"k8s.io/client-go/tools/leaderelection/resourcelock"
 	"k8s.io/kubectl/pkg/drain"
+
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/faultinjection"
 )
 
 const (
@@ -26,6 +32,19 @@ const (
 	drainHelperTimeoutDefault    = int64(90)
 	LeaseDurationEnvVarName      = "LEASE_DURATION_SECONDS"
 	LeaseDurationDefault         = int64(137)
+	// RebootLeaseDurationEnvVarName sets for how many seconds the lease is held by the node after Run, which left it
+	// cordoned for reboot; 0 releases the lease right away
+	RebootLeaseDurationEnvVarName = "REBOOT_LEASE_DURATION_SECONDS"
+	RebootLeaseDurationDefault    = int64(900)
+
+	// CordonedByAnnotation is set on the node cordoned by DrainHelper; it tells operator cordon apart from admin one
+	CordonedByAnnotation = "sriovfec.intel.com/cordoned-by"
+	// CordonedAtAnnotation holds RFC3339 timestamp of the cordon applied by DrainHelper
+	CordonedAtAnnotation = "sriovfec.intel.com/cordoned-at"
+	// RebootPendingAnnotation is set when worker function asked to keep the node cordoned until reboot
+	RebootPendingAnnotation = "sriovfec.intel.com/reboot-pending"
+	// CordonOwner is the value of CordonedByAnnotation
+	CordonOwner = "sriov-fec-daemon"
 )
 
 // logWriter is a wrapper around logrus log.Info() to allow drain.Helper logging
@@ -46,6 +65,26 @@ type DrainHelper struct {
 	drainer              *drain.Helper
 	leaseLock            *resourcelock.LeaseLock
 	leaderElectionConfig leaderelection.LeaderElectionConfig
+
+	rescheduleTimeout time.Duration
+	evictedPodsMutex  sync.Mutex
+	evictedPods       []EvictedPod
+
+	settings Settings
+
+	// now returns wall time the cordon is stamped with, see SetClock
+	now func() time.Time
+}
+
+// Settings describes effective drain configuration resolved from environment variables and cluster type
+type Settings struct {
+	DrainTimeout      time.Duration
+	LeaseDuration     time.Duration
+	RenewDeadline     time.Duration
+	RetryPeriod       time.Duration
+	RescheduleTimeout time.Duration
+	// RebootLeaseDuration is how long the lease is held after Run, which left the node cordoned for reboot
+	RebootLeaseDuration time.Duration
 }
 
 func NewDrainHelper(log *logrus.Logger, cs *clientset.Clientset, nodeName, namespace string, isSingleNodeCluster bool) *DrainHelper {
@@ -74,6 +113,30 @@ func NewDrainHelper(log *logrus.Logger, cs *clientset.Clientset, nodeName, names
 	}
 	log.WithField("duration seconds", leaseDur).Info("lease settings")
 
+	rebootLeaseDur := RebootLeaseDurationDefault
+	rebootLeaseDurStr := os.Getenv(RebootLeaseDurationEnvVarName)
+	if rebootLeaseDurStr != "" {
+		val, err := strconv.ParseInt(rebootLeaseDurStr, 10, 64)
+		if err != nil || val < 0 {
+			log.WithError(err).WithField("variable", RebootLeaseDurationEnvVarName).Error("failed to parse env variable to non-negative int64 - using default value")
+		} else {
+			rebootLeaseDur = val
+		}
+	}
+	log.WithField("duration seconds", rebootLeaseDur).Info("reboot lease settings")
+
+	rescheduleTimeout := rescheduleTimeoutDefault
+	rescheduleTimeoutStr := os.Getenv(rescheduleTimeoutEnvVarName)
+	if rescheduleTimeoutStr != "" {
+		val, err := strconv.ParseInt(rescheduleTimeoutStr, 10, 64)
+		if err != nil {
+			log.WithError(err).WithField("variable", rescheduleTimeoutEnvVarName).Error("failed to parse env variable to int64 - using default value")
+		} else {
+			rescheduleTimeout = val
+		}
+	}
+	log.WithField("timeout seconds", rescheduleTimeout).Info("rescheduling verification settings")
+
 	lock := &resourcelock.LeaseLock{
 		LeaseMeta: metav1.ObjectMeta{
 			Name:      "n3000-daemon-lease",
@@ -85,34 +148,61 @@ func NewDrainHelper(log *logrus.Logger, cs *clientset.Clientset, nodeName, names
 		},
 	}
 
-	return &DrainHelper{
-		log:       log,
-		clientSet: cs,
-		nodeName:  nodeName,
-
-		drainer: &drain.Helper{
-			Ctx:                 context.Background(),
-			Client:              cs,
-			Force:               true,
-			IgnoreAllDaemonSets: true,
-			DeleteEmptyDirData:  true,
-			GracePeriodSeconds:  -1,
-			Timeout:             time.Duration(drainTimeout) * time.Second,
-			OnPodDeletedOrEvicted: func(pod *corev1.Pod, usingEviction bool) {
-				act := "Deleted"
-				if usingEviction {
-					act = "Evicted"
-				}
-				log.WithField("action", act).WithField("pod", fmt.Sprintf("%s/%s", pod.Name, pod.Namespace)).
-					Info("pod evicted or deleted")
-			},
-			Out:    logWriter{log},
-			ErrOut: logWriter{log},
-		},
+	lec := CustomizedLeaderElectionConfig(lock, leaseDur, isSingleNodeCluster)
+	dh := &DrainHelper{
+		log:               log,
+		clientSet:         cs,
+		nodeName:          nodeName,
+		rescheduleTimeout: time.Duration(rescheduleTimeout) * time.Second,
 
 		leaseLock:            lock,
-		leaderElectionConfig: CustomizedLeaderElectionConfig(lock, leaseDur, isSingleNodeCluster),
+		leaderElectionConfig: lec,
+
+		settings: Settings{
+			DrainTimeout:        time.Duration(drainTimeout) * time.Second,
+			LeaseDuration:       lec.LeaseDuration,
+			RenewDeadline:       lec.RenewDeadline,
+			RetryPeriod:         lec.RetryPeriod,
+			RescheduleTimeout:   time.Duration(rescheduleTimeout) * time.Second,
+			RebootLeaseDuration: time.Duration(rebootLeaseDur) * time.Second,
+		},
+
+		now: time.Now,
 	}
+
+	dh.drainer = &drain.Helper{
+		Ctx:                 context.Background(),
+		Client:              cs,
+		Force:               true,
+		IgnoreAllDaemonSets: true,
+		DeleteEmptyDirData:  true,
+		GracePeriodSeconds:  -1,
+		Timeout:             time.Duration(drainTimeout) * time.Second,
+		OnPodDeletedOrEvicted: func(pod *corev1.Pod, usingEviction bool) {
+			act := "Deleted"
+			if usingEviction {
+				act = "Evicted"
+			}
+			log.WithField("action", act).WithField("pod", fmt.Sprintf("%s/%s", pod.Name, pod.Namespace)).
+				Info("pod evicted or deleted")
+			dh.recordEvictedPod(pod)
+		},
+		Out:    logWriter{log},
+		ErrOut: logWriter{log},
+	}
+
+	return dh
+}
+
+// Settings returns effective drain configuration used by this DrainHelper
+func (dh *DrainHelper) Settings() Settings {
+	return dh.settings
+}
+
+// SetClock replaces source of wall time the cordon is stamped with (see CordonedAtAnnotation), so that the stamp is
+// taken from the same clock as the age of the cordon is compared with (see CordonedFor)
+func (dh *DrainHelper) SetClock(now func() time.Time) {
+	dh.now = now
 }
 
 // More details about values are available here:
@@ -138,8 +228,16 @@ func CustomizedLeaderElectionConfig(lock *resourcelock.LeaseLock, leaseDur int64
 // f is a function that takes a context and returns a bool.
 // It should return true if uncordon should be performed(Only applicable if drain is set to true).
 // If `f` returns false, the uncordon does not take place. This is useful in 2-step scenario like sriov-fec-daemon where
-// reboot must be performed without loosing the leadership and without the uncordon.
-func (dh *DrainHelper) Run(f func(context.Context) bool, drain bool) error {
+// reboot must be performed without loosing the leadership and without the uncordon. Lease is kept held by the node for
+// RebootLeaseDuration of Settings after Run then, so that other nodes are not drained while it reboots. Reboots
+// themselves are not coordinated with the lease; callers serialize them with a lock of their own.
+//
+// Leadership is given up when parent ctx is done; context passed to f is cancelled then as well and Run returns once f
+// has returned. Node is uncordoned even if ctx is done meanwhile, so that cancelled reconcile does not leave it cordoned.
+func (dh *DrainHelper) Run(parent context.Context, f func(context.Context) bool, drain bool) error {
+	// set when f asked to keep the node cordoned until reboot; the lease stays held by the node then, unless
+	// RebootLeaseDuration is 0
+	var rebootPending bool
 	defer func() {
 		// Following mitigation is needed because of the bug in the leader election's release functionality
 		// Release fails because the input (leader election record) is created incomplete (missing fields):
@@ -150,6 +248,11 @@ func (dh *DrainHelper) Run(f func(context.Context) bool, drain bool) error {
 		// This however is not critical - if the leader will not refresh the lease,
 		// another node will take it after some time.
 
+		if rebootPending && dh.settings.RebootLeaseDuration > 0 {
+			dh.holdLeaseForReboot()
+			return
+		}
+
 		dh.log.Info("releasing the lock (bug mitigation)")
 
 		leaderElectionRecord, _, err := dh.leaseLock.Get(context.Background())
@@ -163,14 +266,35 @@ func (dh *DrainHelper) Run(f func(context.Context) bool, drain bool) error {
 		}
 	}()
 
-	ctx, cancel := context.WithCancel(context.Background())
+	dh.resetEvictedPods()
+
+	ctx, cancel := context.WithCancel(parent)
 	defer cancel()
 
 	var innerErr error
 
+	// leader elector starts OnStartedLeading in a goroutine and does not wait for it, so Run has to. Callback which
+	// starts after le.Run has already returned (ctx done right after the lease was acquired) does nothing.
+	var (
+		mu       sync.Mutex
+		returned bool
+		leading  sync.WaitGroup
+	)
+
 	lec := dh.leaderElectionConfig
+	// lease is released by the deferred function, unless it is held for pending reboot
+	lec.ReleaseOnCancel = false
 	lec.Callbacks = leaderelection.LeaderCallbacks{
 		OnStartedLeading: func(ctx context.Context) {
+			mu.Lock()
+			if returned {
+				mu.Unlock()
+				return
+			}
+			leading.Add(1)
+			mu.Unlock()
+			defer leading.Done()
+
 			defer func() {
 				dh.log.Info("cancelling the context to finish the leadership")
 				cancel()
@@ -182,14 +306,14 @@ func (dh *DrainHelper) Run(f func(context.Context) bool, drain bool) error {
 				// always try to uncordon the node
 				// e.g. when cordoning succeeds, but draining fails
 				dh.log.Info("uncordoning node")
-				if err := dh.uncordon(ctx); err != nil {
+				if err := dh.uncordon(context.WithoutCancel(ctx)); err != nil {
 					dh.log.WithError(err).Error("uncordon failed")
 					innerErr = err
 				}
 			}
 
 			if drain {
-				dh.log.Info("cordoning & draining node")
+				dh.log.WithField("mode", DrainOptionsFrom(ctx).String()).Info("cordoning & draining node")
 				if err := dh.cordonAndDrain(ctx); err != nil {
 					dh.log.WithError(err).Error("cordonAndDrain failed")
 					innerErr = err
@@ -199,10 +323,19 @@ func (dh *DrainHelper) Run(f func(context.Context) bool, drain bool) error {
 			}
 
 			dh.log.Info("worker function - start")
-			performUncordon := f(ctx)
+			performUncordon := f(WithProgressReporter(ctx, func(step string) {
+				if err := dh.renewLease(ctx); err != nil {
+					dh.log.WithError(err).WithField("step", step).Warn("failed to renew the lease")
+					return
+				}
+				dh.log.WithField("step", step).Info("lease renewed")
+			}))
 			dh.log.WithField("performUncordon", performUncordon).Info("worker function - end")
 			if drain && performUncordon {
 				uncordon()
+			} else if drain {
+				rebootPending = true
+				dh.annotateNode(context.WithoutCancel(ctx), map[string]*string{RebootPendingAnnotation: stringPtr("true")})
 			}
 		},
 		OnStoppedLeading: func() {
@@ -219,6 +352,11 @@ func (dh *DrainHelper) Run(f func(context.Context) bool, drain bool) error {
 
 	le.Run(ctx)
 
+	mu.Lock()
+	returned = true
+	mu.Unlock()
+	leading.Wait()
+
 	if innerErr != nil {
 		dh.log.WithError(innerErr).Error("error during (un)cordon or drain actions")
 	}
@@ -226,30 +364,76 @@ func (dh *DrainHelper) Run(f func(context.Context) bool, drain bool) error {
 	return innerErr
 }
 
+// holdLeaseForReboot keeps the lease held by the node for RebootLeaseDuration of Settings, so that other nodes are not
+// drained while the node reboots. Lease is taken over by the node on its next Run, or by other node once it expires.
+func (dh *DrainHelper) holdLeaseForReboot() {
+	record, _, err := dh.leaseLock.Get(context.Background())
+	if err != nil {
+		dh.log.WithError(err).Error("failed to get the LeaderElectionRecord")
+		return
+	}
+	if record.HolderIdentity != dh.nodeName {
+		dh.log.WithField("holder", record.HolderIdentity).Warn("lease is not held by the node - it is not kept for pending reboot")
+		return
+	}
+	record.RenewTime = metav1.Now()
+	record.LeaseDurationSeconds = int(dh.settings.RebootLeaseDuration.Seconds())
+	if err := dh.leaseLock.Update(context.Background(), *record); err != nil {
+		dh.log.WithError(err).Error("failed to keep the lease for pending reboot")
+		return
+	}
+	dh.log.WithField("duration", dh.settings.RebootLeaseDuration).Info("lease is kept for pending reboot")
+}
+
 func (dh *DrainHelper) onNewLeaderFunction(id string) {
 	if id != dh.nodeName {
 		dh.log.WithField("this", dh.nodeName).WithField("leader", id).Info("new leader elected")
 	}
 }
 
+// cordonAndDrain removes pods from the node as requested by DrainOptions of ctx
 func (dh *DrainHelper) cordonAndDrain(ctx context.Context) error {
+	if err := faultinjection.Check(faultinjection.Drain, "", dh.nodeName); err != nil {
+		dh.log.WithError(err).Error("failed to drain node - injected failure")
+		return err
+	}
+
 	node, nodeGetErr := dh.clientSet.CoreV1().Nodes().Get(ctx, dh.nodeName, metav1.GetOptions{})
 	if nodeGetErr != nil {
 		dh.log.WithError(nodeGetErr).Error("failed to get the node object")
 		return nodeGetErr
 	}
 
+	// node which is already unschedulable is either cordoned by admin (left unmarked, so it is never reported as
+	// operator's one) or by previous run (original timestamp is kept)
+	markCordon := !node.Spec.Unschedulable
+
+	options := DrainOptionsFrom(ctx)
+	drainer := drainerFor(dh.drainer, options)
+
 	var e error
 	backoff := wait.Backoff{Steps: 5, Duration: 15 * time.Second, Factor: 2}
 	f := func() (bool, error) {
-		if err := drain.RunCordonOrUncordon(dh.drainer, node, true); err != nil {
+		if err := dh.setUnschedulable(ctx, true); err != nil {
+			if apierrors.IsForbidden(err) {
+				// missing permission is not going to be granted by retrying
+				return false, err
+			}
 			dh.log.WithField("nodeName", dh.nodeName).WithField("reason", err.Error()).
 				Info("failed to cordon the node - retrying")
 			e = err
 			return false, nil
 		}
 
-		if err := drain.RunNodeDrain(dh.drainer, dh.nodeName); err != nil {
+		if markCordon {
+			dh.annotateNode(ctx, map[string]*string{
+				CordonedByAnnotation: stringPtr(CordonOwner),
+				CordonedAtAnnotation: stringPtr(dh.now().UTC().Format(time.RFC3339)),
+			})
+			markCordon = false
+		}
+
+		if err := drain.RunNodeDrain(drainer, dh.nodeName); err != nil {
 			dh.log.WithField("nodeName", dh.nodeName).WithField("reason", err.Error()).
 				Info("failed to drain the node - retrying")
 			e = err
@@ -269,13 +453,12 @@ func (dh *DrainHelper) cordonAndDrain(ctx context.Context) error {
 		return err
 	}
 
-	dh.log.Info("node drained")
+	dh.log.WithField("mode", options.String()).Info("node drained")
 	return nil
 }
 
 func (dh *DrainHelper) uncordon(ctx context.Context) error {
-	node, err := dh.clientSet.CoreV1().Nodes().Get(ctx, dh.nodeName, metav1.GetOptions{})
-	if err != nil {
+	if _, err := dh.clientSet.CoreV1().Nodes().Get(ctx, dh.nodeName, metav1.GetOptions{}); err != nil {
 		dh.log.WithError(err).Error("failed to get the node object")
 		return err
 	}
@@ -283,7 +466,10 @@ func (dh *DrainHelper) uncordon(ctx context.Context) error {
 	var e error
 	backoff := wait.Backoff{Steps: 5, Duration: 15 * time.Second, Factor: 2}
 	f := func() (bool, error) {
-		if err := drain.RunCordonOrUncordon(dh.drainer, node, false); err != nil {
+		if err := dh.setUnschedulable(ctx, false); err != nil {
+			if apierrors.IsForbidden(err) {
+				return false, err
+			}
 			dh.log.WithField("nodeName", dh.nodeName).WithError(err).Error("failed to uncordon the node - retrying")
 			e = err
 			return false, nil
@@ -303,5 +489,74 @@ func (dh *DrainHelper) uncordon(ctx context.Context) error {
 	}
 	dh.log.Info("node uncordoned")
 
+	dh.annotateNode(ctx, map[string]*string{
+		CordonedByAnnotation:    nil,
+		CordonedAtAnnotation:    nil,
+		RebootPendingAnnotation: nil,
+	})
+
 	return nil
 }
+
+// setUnschedulable cordons or uncordons the node. Merge patch is used instead of kubectl's cordon helper, which falls
+// back to update of the whole node - daemon is granted only patch on nodes.
+func (dh *DrainHelper) setUnschedulable(ctx context.Context, unschedulable bool) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"unschedulable": unschedulable,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = dh.clientSet.CoreV1().Nodes().Patch(ctx, dh.nodeName, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// annotateNode sets (or removes when value is nil) given annotations of the node. Failure is only logged, because
+// annotations are informative and must not break the drain flow.
+func (dh *DrainHelper) annotateNode(ctx context.Context, annotations map[string]*string) {
+	patch, err := annotationsPatch(annotations)
+	if err != nil {
+		dh.log.WithError(err).Error("failed to build node annotations patch")
+		return
+	}
+	if _, err := dh.clientSet.CoreV1().Nodes().Patch(ctx, dh.nodeName, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		dh.log.WithError(err).WithField("nodeName", dh.nodeName).Error("failed to annotate the node")
+	}
+}
+
+func annotationsPatch(annotations map[string]*string) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": annotations,
+		},
+	})
+}
+
+// IsCordonedByAdmin returns true if node is unschedulable, but it was not cordoned by DrainHelper
+func IsCordonedByAdmin(node *corev1.Node) bool {
+	return node.Spec.Unschedulable && node.GetAnnotations()[CordonedByAnnotation] != CordonOwner
+}
+
+// CordonedFor returns how long the node has been cordoned by DrainHelper; zero is returned if node is schedulable or
+// it was cordoned by admin. Zero is returned as well when the cordon timestamp is ahead of now, i.e. the clock was
+// stepped back since the node was cordoned.
+func CordonedFor(node *corev1.Node, now time.Time) (time.Duration, error) {
+	if !node.Spec.Unschedulable || IsCordonedByAdmin(node) {
+		return 0, nil
+	}
+
+	cordonedAt, err := time.Parse(time.RFC3339, node.GetAnnotations()[CordonedAtAnnotation])
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s annotation - %v", CordonedAtAnnotation, err)
+	}
+	if now.Before(cordonedAt) {
+		return 0, nil
+	}
+	return now.Sub(cordonedAt), nil
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -5,6 +5,7 @@ package drainhelper
 
 import (
 	"context"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/faultinjection"
 	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
 	"os"
 	"strconv"
@@ -17,6 +18,7 @@ import (
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clientset "k8s.io/client-go/kubernetes"
 	restclient "k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 var _ = Describe("DrainHelper Tests", func() {
@@ -80,7 +82,7 @@ var _ = Describe("DrainHelper Tests", func() {
 			dh := NewDrainHelper(log, cset, "node", "namespace", false)
 			Expect(dh).ToNot(Equal(nil))
 
-			err = dh.Run(func(c context.Context) bool { return true }, true)
+			err = dh.Run(context.TODO(), func(c context.Context) bool { return true }, true)
 			Expect(err).To(HaveOccurred())
 		})
 
@@ -98,6 +100,23 @@ var _ = Describe("DrainHelper Tests", func() {
 			Expect(err).To(HaveOccurred())
 		})
 
+		var _ = It("Fail DrainHelper.cordonAndDrain because of injected fault", func() {
+			faults, err := os.CreateTemp("", "faults-*.json")
+			Expect(err).ToNot(HaveOccurred())
+			defer os.Remove(faults.Name())
+			_, err = faults.WriteString(`{"faults": [{"operation": "drain", "device": "dummy", "message": "drain timed out"}]}`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(faults.Close()).To(Succeed())
+			Expect(faultinjection.Load(faults.Name())).To(Succeed())
+			defer faultinjection.Reset()
+
+			cset, err := clientset.NewForConfig(cfg)
+			Expect(err).ToNot(HaveOccurred())
+
+			dh := NewDrainHelper(log, cset, "dummy", "namespace", false)
+			Expect(dh.cordonAndDrain(context.Background())).To(MatchError("drain timed out"))
+		})
+
 		var _ = It("Fail DrainHelper.uncordon because of no nodes", func() {
 			var err error
 
@@ -228,7 +247,7 @@ var _ = Describe("DrainHelper Tests", func() {
 			dh := NewDrainHelper(log, cset, "dummy", "default", false)
 			Expect(dh).ToNot(Equal(nil))
 
-			err = dh.Run(func(c context.Context) bool { return true }, true)
+			err = dh.Run(context.TODO(), func(c context.Context) bool { return true }, true)
 			Expect(err).ToNot(HaveOccurred())
 
 			// Cleanup
@@ -260,12 +279,90 @@ var _ = Describe("DrainHelper Tests", func() {
 			dh := NewDrainHelper(log, cset, "dummy", "default", false)
 			Expect(dh).ToNot(Equal(nil))
 
-			err = dh.Run(func(c context.Context) bool { return true }, false)
+			err = dh.Run(context.TODO(), func(c context.Context) bool { return true }, false)
 			Expect(err).ToNot(HaveOccurred())
 
 			// Cleanup
 			err = k8sClient.Delete(context.TODO(), node)
 			Expect(err).ToNot(HaveOccurred())
 		})
+
+		var _ = It("Mark node cordoned by DrainHelper and clear marks on uncordon", func() {
+			node := &corev1.Node{ObjectMeta: v1.ObjectMeta{Name: "dummy"}}
+			Expect(k8sClient.Create(context.Background(), node)).To(Succeed())
+			defer func() { Expect(k8sClient.Delete(context.TODO(), node)).To(Succeed()) }()
+
+			cset, err := clientset.NewForConfig(cfg)
+			Expect(err).ToNot(HaveOccurred())
+			dh := NewDrainHelper(log, cset, "dummy", "namespace", false)
+			cordonedAt := time.Date(2023, 5, 1, 10, 30, 0, 0, time.UTC)
+			dh.SetClock(func() time.Time { return cordonedAt })
+
+			Expect(dh.cordonAndDrain(context.Background())).To(Succeed())
+			Expect(k8sClient.Get(context.Background(), client.ObjectKeyFromObject(node), node)).To(Succeed())
+			Expect(node.Annotations).To(HaveKeyWithValue(CordonedByAnnotation, CordonOwner))
+			Expect(node.Annotations).To(HaveKeyWithValue(CordonedAtAnnotation, "2023-05-01T10:30:00Z"))
+			Expect(IsCordonedByAdmin(node)).To(BeFalse())
+			Expect(CordonedFor(node, cordonedAt.Add(time.Hour))).To(Equal(time.Hour))
+			// clock stepped back since the cordon
+			Expect(CordonedFor(node, cordonedAt.Add(-time.Hour))).To(BeZero())
+
+			Expect(dh.uncordon(context.Background())).To(Succeed())
+			Expect(k8sClient.Get(context.Background(), client.ObjectKeyFromObject(node), node)).To(Succeed())
+			Expect(node.Annotations).ToNot(HaveKey(CordonedByAnnotation))
+			Expect(node.Annotations).ToNot(HaveKey(CordonedAtAnnotation))
+		})
+
+		var _ = It("Do not mark node cordoned by admin", func() {
+			node := &corev1.Node{ObjectMeta: v1.ObjectMeta{Name: "dummy"}, Spec: corev1.NodeSpec{Unschedulable: true}}
+			Expect(k8sClient.Create(context.Background(), node)).To(Succeed())
+			defer func() { Expect(k8sClient.Delete(context.TODO(), node)).To(Succeed()) }()
+
+			cset, err := clientset.NewForConfig(cfg)
+			Expect(err).ToNot(HaveOccurred())
+			dh := NewDrainHelper(log, cset, "dummy", "namespace", false)
+
+			Expect(dh.cordonAndDrain(context.Background())).To(Succeed())
+			Expect(k8sClient.Get(context.Background(), client.ObjectKeyFromObject(node), node)).To(Succeed())
+			Expect(node.Annotations).ToNot(HaveKey(CordonedByAnnotation))
+			Expect(IsCordonedByAdmin(node)).To(BeTrue())
+		})
+	})
+
+	var _ = Describe("CordonedFor", func() {
+		now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+
+		cordonedNode := func(unschedulable bool, annotations map[string]string) *corev1.Node {
+			return &corev1.Node{
+				ObjectMeta: v1.ObjectMeta{Name: "dummy", Annotations: annotations},
+				Spec:       corev1.NodeSpec{Unschedulable: unschedulable},
+			}
+		}
+
+		var _ = It("returns time since cordon applied by DrainHelper", func() {
+			node := cordonedNode(true, map[string]string{
+				CordonedByAnnotation: CordonOwner,
+				CordonedAtAnnotation: "2023-05-01T10:30:00Z",
+			})
+			Expect(CordonedFor(node, now)).To(Equal(90 * time.Minute))
+		})
+
+		var _ = It("returns zero for schedulable node", func() {
+			node := cordonedNode(false, map[string]string{
+				CordonedByAnnotation: CordonOwner,
+				CordonedAtAnnotation: "2023-05-01T10:30:00Z",
+			})
+			Expect(CordonedFor(node, now)).To(BeZero())
+		})
+
+		var _ = It("returns zero for node cordoned by admin", func() {
+			Expect(CordonedFor(cordonedNode(true, nil), now)).To(BeZero())
+		})
+
+		var _ = It("fails for invalid timestamp", func() {
+			node := cordonedNode(true, map[string]string{CordonedByAnnotation: CordonOwner, CordonedAtAnnotation: "yesterday"})
+			_, err := CordonedFor(node, now)
+			Expect(err).To(HaveOccurred())
+		})
 	})
 })
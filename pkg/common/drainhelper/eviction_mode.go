@@ -0,0 +1,65 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package drainhelper
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/kubectl/pkg/drain"
+)
+
+// EvictionMode tells how pods are removed from the node during drain
+type EvictionMode string
+
+const (
+	// EvictionModeEvict removes pods with the eviction subresource, respecting PodDisruptionBudgets (default)
+	EvictionModeEvict EvictionMode = "evict"
+	// EvictionModeDelete deletes pods directly, without waiting for PodDisruptionBudgets
+	EvictionModeDelete EvictionMode = "delete"
+)
+
+// DrainOptions customize drain performed by DrainHelper.Run; zero value evicts pods with their own grace periods
+type DrainOptions struct {
+	EvictionMode EvictionMode
+	// GracePeriodSeconds overrides terminationGracePeriodSeconds of pods deleted in EvictionModeDelete
+	GracePeriodSeconds *int64
+}
+
+// String describes the options for drain progress reporting and events, e.g. "pod deletion with 0s grace period"
+func (o DrainOptions) String() string {
+	if o.EvictionMode != EvictionModeDelete {
+		return "pod eviction"
+	}
+	if o.GracePeriodSeconds == nil {
+		return "pod deletion"
+	}
+	return fmt.Sprintf("pod deletion with %ds grace period", *o.GracePeriodSeconds)
+}
+
+type drainOptionsKey struct{}
+
+// WithDrainOptions returns context which makes DrainHelper.Run drain the node according to options
+func WithDrainOptions(ctx context.Context, options DrainOptions) context.Context {
+	return context.WithValue(ctx, drainOptionsKey{}, options)
+}
+
+// DrainOptionsFrom returns drain options carried by the context; zero value when there are none
+func DrainOptionsFrom(ctx context.Context) DrainOptions {
+	options, _ := ctx.Value(drainOptionsKey{}).(DrainOptions)
+	return options
+}
+
+// drainerFor returns copy of the drainer removing pods as options request; selection of pods is the same for both
+// modes, only the way selected pods are removed differs
+func drainerFor(drainer *drain.Helper, options DrainOptions) *drain.Helper {
+	customized := *drainer
+	if options.EvictionMode == EvictionModeDelete {
+		customized.DisableEviction = true
+		if options.GracePeriodSeconds != nil {
+			customized.GracePeriodSeconds = int(*options.GracePeriodSeconds)
+		}
+	}
+	return &customized
+}
@@ -0,0 +1,56 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package drainhelper
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/kubectl/pkg/drain"
+)
+
+var _ = Describe("DrainOptions", func() {
+	gracePeriod := int64(5)
+	drainer := &drain.Helper{Force: true, IgnoreAllDaemonSets: true, DeleteEmptyDirData: true, GracePeriodSeconds: -1}
+
+	It("evicts pods by default", func() {
+		customized := drainerFor(drainer, DrainOptionsFrom(context.TODO()))
+		Expect(customized.DisableEviction).To(BeFalse())
+		Expect(customized.GracePeriodSeconds).To(Equal(-1))
+
+		// grace period applies only to deleted pods
+		customized = drainerFor(drainer, DrainOptions{EvictionMode: EvictionModeEvict, GracePeriodSeconds: &gracePeriod})
+		Expect(customized.DisableEviction).To(BeFalse())
+		Expect(customized.GracePeriodSeconds).To(Equal(-1))
+	})
+
+	It("deletes pods with configured grace period", func() {
+		ctx := WithDrainOptions(context.TODO(), DrainOptions{EvictionMode: EvictionModeDelete, GracePeriodSeconds: &gracePeriod})
+		customized := drainerFor(drainer, DrainOptionsFrom(ctx))
+		Expect(customized.DisableEviction).To(BeTrue())
+		Expect(customized.GracePeriodSeconds).To(Equal(5))
+		// pods are selected the same way in both modes
+		Expect(customized.Force).To(BeTrue())
+		Expect(customized.IgnoreAllDaemonSets).To(BeTrue())
+		Expect(customized.DeleteEmptyDirData).To(BeTrue())
+
+		Expect(drainerFor(drainer, DrainOptions{EvictionMode: EvictionModeDelete}).GracePeriodSeconds).To(Equal(-1))
+		Expect(drainer.DisableEviction).To(BeFalse(), "shared drainer is not modified")
+	})
+
+	It("describes the mode", func() {
+		Expect(DrainOptions{}.String()).To(Equal("pod eviction"))
+		Expect(DrainOptions{EvictionMode: EvictionModeDelete}.String()).To(Equal("pod deletion"))
+		Expect(DrainOptions{EvictionMode: EvictionModeDelete, GracePeriodSeconds: &gracePeriod}.String()).
+			To(Equal("pod deletion with 5s grace period"))
+	})
+
+	It("are recorded by FakeDrainer", func() {
+		fake := &FakeDrainer{}
+		options := DrainOptions{EvictionMode: EvictionModeDelete}
+		Expect(fake.Run(WithDrainOptions(context.TODO(), options), func(context.Context) bool { return true }, true)).To(Succeed())
+		Expect(fake.Runs()[0].Options).To(Equal(options))
+	})
+})
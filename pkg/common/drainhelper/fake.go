@@ -0,0 +1,79 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package drainhelper
+
+import (
+	"context"
+	"sync"
+)
+
+// FakeDrainer is in-memory Drainer which mimics outcomes of DrainHelper.Run without touching the cluster. Zero value
+// executes worker function with successful drain and uncordon.
+type FakeDrainer struct {
+	// DrainErr is returned by Run when drain is requested; worker function is not executed then, as when cordon or
+	// drain of the node fails
+	DrainErr error
+	// UncordonErr is returned by Run when node is uncordoned after the worker function
+	UncordonErr error
+	// Rescheduling is returned by VerifyRescheduling
+	Rescheduling *ReschedulingSummary
+
+	mu   sync.Mutex
+	runs []FakeRun
+}
+
+// FakeRun records single call of FakeDrainer.Run
+type FakeRun struct {
+	Drain bool
+	// Options are drain options carried by the context passed to Run
+	Options DrainOptions
+	// Executed is set when worker function was called
+	Executed bool
+	// Uncordoned is set when node was uncordoned after the worker function
+	Uncordoned bool
+	// RebootPending is set when worker function asked to keep the node cordoned
+	RebootPending bool
+	// Steps are reported by worker function with ReportProgress
+	Steps []string
+}
+
+var _ Drainer = &FakeDrainer{}
+
+func (f *FakeDrainer) Run(ctx context.Context, worker func(context.Context) bool, drain bool) error {
+	run := FakeRun{Drain: drain, Options: DrainOptionsFrom(ctx)}
+	defer func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.runs = append(f.runs, run)
+	}()
+
+	if drain && f.DrainErr != nil {
+		return f.DrainErr
+	}
+
+	run.Executed = true
+	performUncordon := worker(WithProgressReporter(ctx, func(step string) {
+		run.Steps = append(run.Steps, step)
+	}))
+	if !drain {
+		return nil
+	}
+	if !performUncordon {
+		run.RebootPending = true
+		return nil
+	}
+	run.Uncordoned = f.UncordonErr == nil
+	return f.UncordonErr
+}
+
+func (f *FakeDrainer) VerifyRescheduling(context.Context) *ReschedulingSummary {
+	return f.Rescheduling
+}
+
+// Runs returns calls of Run in order
+func (f *FakeDrainer) Runs() []FakeRun {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]FakeRun(nil), f.runs...)
+}
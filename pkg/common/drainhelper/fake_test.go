@@ -0,0 +1,58 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package drainhelper
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FakeDrainer", func() {
+	worker := func(performUncordon bool) func(context.Context) bool {
+		return func(ctx context.Context) bool {
+			ReportProgress(ctx, "configured")
+			return performUncordon
+		}
+	}
+
+	It("records drain, uncordon and progress of the worker function", func() {
+		var reported []string
+		ctx := WithProgressReporter(context.TODO(), func(step string) { reported = append(reported, step) })
+		fake := &FakeDrainer{}
+
+		Expect(fake.Run(ctx, worker(true), true)).To(Succeed())
+		Expect(fake.Run(ctx, worker(false), true)).To(Succeed())
+		Expect(fake.Run(ctx, worker(false), false)).To(Succeed())
+
+		Expect(fake.Runs()).To(Equal([]FakeRun{
+			{Drain: true, Executed: true, Uncordoned: true, Steps: []string{"configured"}},
+			{Drain: true, Executed: true, RebootPending: true, Steps: []string{"configured"}},
+			{Drain: false, Executed: true, Steps: []string{"configured"}},
+		}))
+		Expect(reported).To(HaveLen(3), "reporters of the parent context are notified")
+	})
+
+	It("does not execute worker function when drain fails", func() {
+		fake := &FakeDrainer{DrainErr: errors.New("drain failed")}
+
+		Expect(fake.Run(context.TODO(), worker(true), true)).To(MatchError("drain failed"))
+		Expect(fake.Run(context.TODO(), worker(true), false)).To(Succeed())
+
+		Expect(fake.Runs()).To(Equal([]FakeRun{
+			{Drain: true},
+			{Drain: false, Executed: true, Steps: []string{"configured"}},
+		}))
+	})
+
+	It("returns uncordon error after the worker function", func() {
+		fake := &FakeDrainer{UncordonErr: errors.New("uncordon failed")}
+
+		Expect(fake.Run(context.TODO(), worker(true), true)).To(MatchError("uncordon failed"))
+		Expect(fake.Runs()[0].Executed).To(BeTrue())
+		Expect(fake.Runs()[0].Uncordoned).To(BeFalse())
+	})
+})
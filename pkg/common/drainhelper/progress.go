@@ -0,0 +1,52 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package drainhelper
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ProgressReporter is notified about steps completed by the worker function
+type ProgressReporter func(step string)
+
+type progressReporterKey struct{}
+
+// WithProgressReporter returns context which notifies reporter about progress reported with ReportProgress; reporters
+// of the parent context are notified as well
+func WithProgressReporter(ctx context.Context, reporter ProgressReporter) context.Context {
+	parent, _ := ctx.Value(progressReporterKey{}).(ProgressReporter)
+	return context.WithValue(ctx, progressReporterKey{}, ProgressReporter(func(step string) {
+		if parent != nil {
+			parent(step)
+		}
+		reporter(step)
+	}))
+}
+
+// ReportProgress tells reporters of the context that the worker function completed given step. Context passed to
+// the worker function by DrainHelper.Run renews the lease, so long-running work does not lose leadership between steps.
+func ReportProgress(ctx context.Context, step string) {
+	if reporter, ok := ctx.Value(progressReporterKey{}).(ProgressReporter); ok {
+		reporter(step)
+	}
+}
+
+// renewLease renews the lease held by this node out of the leader elector's renew loop
+func (dh *DrainHelper) renewLease(ctx context.Context) error {
+	record, _, err := dh.leaseLock.Get(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get the LeaderElectionRecord - %v", err)
+	}
+	if record.HolderIdentity != dh.nodeName {
+		return fmt.Errorf("lease is held by %q", record.HolderIdentity)
+	}
+	record.RenewTime = metav1.Now()
+	if err := dh.leaseLock.Update(ctx, *record); err != nil {
+		return fmt.Errorf("failed to update the LeaderElectionRecord - %v", err)
+	}
+	return nil
+}
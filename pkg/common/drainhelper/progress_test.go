@@ -0,0 +1,99 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package drainhelper
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("Progress reporting", func() {
+	It("notifies reporters of the context and of its parents", func() {
+		var steps []string
+		ctx := WithProgressReporter(context.TODO(), func(step string) { steps = append(steps, "parent: "+step) })
+		ctx = WithProgressReporter(ctx, func(step string) { steps = append(steps, "child: "+step) })
+
+		ReportProgress(ctx, "0000:f7:00.0: cleaned")
+
+		Expect(steps).To(Equal([]string{"parent: 0000:f7:00.0: cleaned", "child: 0000:f7:00.0: cleaned"}))
+	})
+
+	It("ignores progress reported with context without reporters", func() {
+		Expect(func() { ReportProgress(context.TODO(), "step") }).ToNot(Panic())
+	})
+
+	Describe("renewLease", func() {
+		var (
+			dh          *DrainHelper
+			clientSet   *fake.Clientset
+			renewedLong = metav1.NewMicroTime(time.Now().Add(-time.Hour))
+		)
+
+		BeforeEach(func() {
+			holder := "node"
+			clientSet = fake.NewSimpleClientset(&coordinationv1.Lease{
+				ObjectMeta: metav1.ObjectMeta{Name: "n3000-daemon-lease", Namespace: "namespace"},
+				Spec:       coordinationv1.LeaseSpec{HolderIdentity: &holder, RenewTime: &renewedLong},
+			})
+			dh = &DrainHelper{
+				log:      utils.NewLogger(),
+				nodeName: "node",
+				settings: Settings{RebootLeaseDuration: 20 * time.Minute},
+				leaseLock: &resourcelock.LeaseLock{
+					LeaseMeta:  metav1.ObjectMeta{Name: "n3000-daemon-lease", Namespace: "namespace"},
+					Client:     clientSet.CoordinationV1(),
+					LockConfig: resourcelock.ResourceLockConfig{Identity: "node"},
+				},
+			}
+		})
+
+		It("renews the lease held by the node", func() {
+			Expect(dh.renewLease(context.TODO())).To(Succeed())
+
+			lease, err := clientSet.CoordinationV1().Leases("namespace").Get(context.TODO(), "n3000-daemon-lease", metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lease.Spec.RenewTime.Time).To(BeTemporally("~", time.Now(), time.Minute))
+		})
+
+		It("does not take over the lease held by another node", func() {
+			dh.nodeName = "other-node"
+
+			Expect(dh.renewLease(context.TODO())).To(MatchError(ContainSubstring(`lease is held by "node"`)))
+
+			lease, err := clientSet.CoordinationV1().Leases("namespace").Get(context.TODO(), "n3000-daemon-lease", metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lease.Spec.RenewTime.Time).To(BeTemporally("~", renewedLong.Time, time.Second))
+		})
+
+		It("keeps the lease held by the node for pending reboot", func() {
+			dh.holdLeaseForReboot()
+
+			lease, err := clientSet.CoordinationV1().Leases("namespace").Get(context.TODO(), "n3000-daemon-lease", metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*lease.Spec.HolderIdentity).To(Equal("node"))
+			Expect(*lease.Spec.LeaseDurationSeconds).To(BeEquivalentTo(1200))
+			Expect(lease.Spec.RenewTime.Time).To(BeTemporally("~", time.Now(), time.Minute))
+		})
+
+		It("does not keep the lease held by another node for pending reboot", func() {
+			dh.nodeName = "other-node"
+
+			dh.holdLeaseForReboot()
+
+			lease, err := clientSet.CoordinationV1().Leases("namespace").Get(context.TODO(), "n3000-daemon-lease", metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*lease.Spec.HolderIdentity).To(Equal("node"))
+			Expect(lease.Spec.LeaseDurationSeconds).To(BeNil())
+		})
+	})
+})
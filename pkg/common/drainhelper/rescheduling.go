@@ -0,0 +1,165 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package drainhelper
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	rescheduleTimeoutEnvVarName = "RESCHEDULE_TIMEOUT_SECONDS"
+	rescheduleTimeoutDefault    = int64(120)
+)
+
+var reschedulePollInterval = 5 * time.Second
+
+// EvictedPod identifies pod evicted (or deleted) during node drain
+type EvictedPod struct {
+	Namespace string
+	Name      string
+	UID       types.UID
+	// OwnerUID is UID of pod's controller; empty for pods which are not managed by any controller
+	OwnerUID  types.UID
+	EvictedAt metav1.Time
+}
+
+func newEvictedPod(pod *corev1.Pod) EvictedPod {
+	ep := EvictedPod{
+		Namespace: pod.Namespace,
+		Name:      pod.Name,
+		UID:       pod.UID,
+		EvictedAt: metav1.Now(),
+	}
+	if owner := metav1.GetControllerOf(pod); owner != nil {
+		ep.OwnerUID = owner.UID
+	}
+	return ep
+}
+
+// ReschedulingSummary describes whether pods evicted during drain are running again
+type ReschedulingSummary struct {
+	Evicted     int
+	Rescheduled int
+	// Pending contains namespace/name of evicted pods without running replacement
+	Pending []string
+}
+
+func (s ReschedulingSummary) String() string {
+	return fmt.Sprintf("%d pods evicted, %d rescheduled, %d pending", s.Evicted, s.Rescheduled, len(s.Pending))
+}
+
+// EvictedPods returns pods evicted during the last Run
+func (dh *DrainHelper) EvictedPods() []EvictedPod {
+	dh.evictedPodsMutex.Lock()
+	defer dh.evictedPodsMutex.Unlock()
+	return append([]EvictedPod(nil), dh.evictedPods...)
+}
+
+func (dh *DrainHelper) recordEvictedPod(pod *corev1.Pod) {
+	dh.evictedPodsMutex.Lock()
+	defer dh.evictedPodsMutex.Unlock()
+	dh.evictedPods = append(dh.evictedPods, newEvictedPod(pod))
+}
+
+func (dh *DrainHelper) resetEvictedPods() {
+	dh.evictedPodsMutex.Lock()
+	defer dh.evictedPodsMutex.Unlock()
+	dh.evictedPods = nil
+}
+
+// VerifyRescheduling waits (up to RESCHEDULE_TIMEOUT_SECONDS) until pods evicted during the last Run are running again
+// on any node. Returns nil if no pods were evicted.
+func (dh *DrainHelper) VerifyRescheduling(ctx context.Context) *ReschedulingSummary {
+	evicted := dh.EvictedPods()
+	if len(evicted) == 0 {
+		return nil
+	}
+	summary := checkRescheduling(ctx, dh.clientSet, evicted, dh.rescheduleTimeout, dh.log)
+	return &summary
+}
+
+// checkRescheduling polls pods until each evicted pod has running replacement or timeout is reached.
+// Replacement is a running pod having the same controller as evicted one, created after the eviction.
+// Pods without controller are never recreated, so they are reported as pending.
+func checkRescheduling(ctx context.Context, cs kubernetes.Interface, evicted []EvictedPod, timeout time.Duration, log *logrus.Logger) ReschedulingSummary {
+	summary := ReschedulingSummary{Evicted: len(evicted)}
+
+	check := func() (bool, error) {
+		s, err := summarizeRescheduling(ctx, cs, evicted)
+		if err != nil {
+			log.WithError(err).Info("failed to verify rescheduling of evicted pods - retrying")
+			return false, nil
+		}
+		summary = s
+		return len(summary.Pending) == 0, nil
+	}
+
+	if err := wait.PollImmediate(reschedulePollInterval, timeout, check); err != nil {
+		log.WithField("summary", summary.String()).WithField("pending", summary.Pending).
+			Info("not all evicted pods have been rescheduled in time")
+	}
+	return summary
+}
+
+func summarizeRescheduling(ctx context.Context, cs kubernetes.Interface, evicted []EvictedPod) (ReschedulingSummary, error) {
+	summary := ReschedulingSummary{Evicted: len(evicted)}
+
+	// replacements which were already accounted, so that single replacement is not matched with two evicted pods
+	used := map[types.UID]bool{}
+	podsByNamespace := map[string][]corev1.Pod{}
+
+	for _, ep := range evicted {
+		key := fmt.Sprintf("%s/%s", ep.Namespace, ep.Name)
+		if ep.OwnerUID == "" {
+			summary.Pending = append(summary.Pending, key)
+			continue
+		}
+
+		pods, ok := podsByNamespace[ep.Namespace]
+		if !ok {
+			podList, err := cs.CoreV1().Pods(ep.Namespace).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return summary, err
+			}
+			pods = podList.Items
+			podsByNamespace[ep.Namespace] = pods
+		}
+
+		if replacement := findReplacement(ep, pods, used); replacement != nil {
+			used[replacement.UID] = true
+			summary.Rescheduled++
+		} else {
+			summary.Pending = append(summary.Pending, key)
+		}
+	}
+	return summary, nil
+}
+
+func findReplacement(ep EvictedPod, pods []corev1.Pod, used map[types.UID]bool) *corev1.Pod {
+	// creationTimestamp has seconds precision
+	evictedAt := ep.EvictedAt.Rfc3339Copy()
+	for i := range pods {
+		pod := &pods[i]
+		if pod.UID == ep.UID || used[pod.UID] || pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		if owner := metav1.GetControllerOf(pod); owner == nil || owner.UID != ep.OwnerUID {
+			continue
+		}
+		if pod.CreationTimestamp.Before(&evictedAt) {
+			continue
+		}
+		return pod
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package drainhelper
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Rescheduling verification", func() {
+	log := utils.NewLogger()
+	evictedAt := metav1.NewTime(time.Now().Add(-time.Minute))
+	isController := true
+
+	pod := func(name string, uid, ownerUID types.UID, phase corev1.PodPhase, created metav1.Time) *corev1.Pod {
+		p := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "workloads", UID: uid, CreationTimestamp: created},
+			Status:     corev1.PodStatus{Phase: phase},
+		}
+		if ownerUID != "" {
+			p.OwnerReferences = []metav1.OwnerReference{{UID: ownerUID, Controller: &isController}}
+		}
+		return p
+	}
+
+	evicted := []EvictedPod{
+		{Namespace: "workloads", Name: "rs-a", UID: "a", OwnerUID: "rs", EvictedAt: evictedAt},
+		{Namespace: "workloads", Name: "rs-b", UID: "b", OwnerUID: "rs", EvictedAt: evictedAt},
+		{Namespace: "workloads", Name: "bare", UID: "c", EvictedAt: evictedAt},
+	}
+
+	BeforeEach(func() {
+		reschedulePollInterval = 10 * time.Millisecond
+	})
+
+	It("counts only running pods of the same controller created after eviction", func() {
+		cs := fake.NewSimpleClientset(
+			pod("rs-old", "old", "rs", corev1.PodRunning, metav1.NewTime(evictedAt.Add(-time.Hour))),
+			pod("rs-new", "new", "rs", corev1.PodRunning, metav1.NewTime(evictedAt.Add(time.Second))),
+			pod("rs-pending", "pending", "rs", corev1.PodPending, metav1.NewTime(evictedAt.Add(time.Second))),
+			pod("other-new", "other", "other-rs", corev1.PodRunning, metav1.NewTime(evictedAt.Add(time.Second))),
+		)
+
+		summary := checkRescheduling(context.TODO(), cs, evicted, 50*time.Millisecond, log)
+		Expect(summary.Evicted).To(Equal(3))
+		Expect(summary.Rescheduled).To(Equal(1))
+		Expect(summary.Pending).To(ConsistOf("workloads/rs-b", "workloads/bare"))
+		Expect(summary.String()).To(Equal("3 pods evicted, 1 rescheduled, 2 pending"))
+	})
+
+	It("reports all pods as rescheduled when replacements are running", func() {
+		cs := fake.NewSimpleClientset(
+			pod("rs-x", "x", "rs", corev1.PodRunning, metav1.NewTime(evictedAt.Add(time.Second))),
+			pod("rs-y", "y", "rs", corev1.PodRunning, metav1.NewTime(evictedAt.Add(time.Second))),
+		)
+
+		summary := checkRescheduling(context.TODO(), cs, evicted[:2], time.Second, log)
+		Expect(summary.String()).To(Equal("2 pods evicted, 2 rescheduled, 0 pending"))
+	})
+})
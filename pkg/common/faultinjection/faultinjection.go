@@ -0,0 +1,163 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+// Package faultinjection deterministically fails chosen operations (e.g. drain, pf_bb_config execution, sysfs writes),
+// so that behavior of the operator under failures can be tested without special hardware. Faults are read from JSON
+// file pointed to by SRIOV_FEC_FAULT_INJECTION env variable, which is meant for testing only.
+package faultinjection
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+const EnvVarName = utils.SRIOV_PREFIX + "FAULT_INJECTION"
+
+type Operation string
+
+const (
+	// Exec fails execution of command which base name is equal to Fault.Target
+	Exec Operation = "exec"
+	// SysfsWrite fails write to sysfs file which base name is equal to Fault.Target
+	SysfsWrite Operation = "sysfsWrite"
+	// Drain fails cordon & drain of the node
+	Drain Operation = "drain"
+)
+
+// Fault describes operation to be failed
+type Fault struct {
+	Operation Operation `json:"operation"`
+	// Target is command (Exec) or sysfs file (SysfsWrite) name; empty matches any
+	Target string `json:"target,omitempty"`
+	// Device is PCI address (node name for Drain) operation has to refer to; empty matches any
+	Device string `json:"device,omitempty"`
+	// Errno is name of error number returned by the operation (e.g. EBUSY); Message is returned when not set
+	Errno   string `json:"errno,omitempty"`
+	Message string `json:"message,omitempty"`
+	// Times is number of times the fault is injected; 0 means every time
+	Times int `json:"times,omitempty"`
+}
+
+type faultsFile struct {
+	Faults []Fault `json:"faults"`
+}
+
+type activeFault struct {
+	Fault
+	injected int
+}
+
+var (
+	errnos = map[string]syscall.Errno{
+		"EAGAIN":    syscall.EAGAIN,
+		"EBUSY":     syscall.EBUSY,
+		"EINVAL":    syscall.EINVAL,
+		"EIO":       syscall.EIO,
+		"ENODEV":    syscall.ENODEV,
+		"ENOENT":    syscall.ENOENT,
+		"EPERM":     syscall.EPERM,
+		"ETIMEDOUT": syscall.ETIMEDOUT,
+	}
+
+	mu     sync.Mutex
+	faults []*activeFault
+)
+
+// LoadFromEnv loads faults from file pointed to by SRIOV_FEC_FAULT_INJECTION env variable; returns false when
+// the variable is not set
+func LoadFromEnv() (bool, error) {
+	path := os.Getenv(EnvVarName)
+	if path == "" {
+		return false, nil
+	}
+	return true, Load(path)
+}
+
+// Load replaces injected faults with the ones defined in given file
+func Load(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read fault injection file - %v", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	var file faultsFile
+	if err := decoder.Decode(&file); err != nil {
+		return fmt.Errorf("failed to parse fault injection file %s - %v", path, err)
+	}
+
+	var loaded []*activeFault
+	for i, fault := range file.Faults {
+		switch fault.Operation {
+		case Exec, SysfsWrite, Drain:
+		default:
+			return fmt.Errorf("fault %d: unknown operation %q, supported are %s, %s and %s", i, fault.Operation, Exec, SysfsWrite, Drain)
+		}
+		if _, ok := errnos[fault.Errno]; fault.Errno != "" && !ok {
+			return fmt.Errorf("fault %d: unsupported errno %q", i, fault.Errno)
+		}
+		loaded = append(loaded, &activeFault{Fault: fault})
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	faults = loaded
+	return nil
+}
+
+// Reset removes all injected faults
+func Reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	faults = nil
+}
+
+// Check returns error of the first fault matching the operation, nil when the operation has to proceed. Device of
+// the fault is looked up in args (e.g. command arguments, sysfs path).
+func Check(operation Operation, target string, args ...string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, fault := range faults {
+		if fault.Operation != operation || (fault.Target != "" && fault.Target != target) {
+			continue
+		}
+		if fault.Device != "" && !refersTo(args, fault.Device) {
+			continue
+		}
+		if fault.Times > 0 && fault.injected >= fault.Times {
+			continue
+		}
+		fault.injected++
+		return fault.err()
+	}
+	return nil
+}
+
+func refersTo(args []string, device string) bool {
+	for _, arg := range args {
+		if strings.Contains(arg, device) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *activeFault) err() error {
+	if f.Errno != "" {
+		return errnos[f.Errno]
+	}
+	if f.Message != "" {
+		return errors.New(f.Message)
+	}
+	return fmt.Errorf("injected %s failure", f.Operation)
+}
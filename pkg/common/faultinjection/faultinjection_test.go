@@ -0,0 +1,79 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package faultinjection
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestFaultInjection(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "FaultInjection suite")
+}
+
+var _ = Describe("Check", func() {
+	var dir string
+
+	load := func(content string) error {
+		path := filepath.Join(dir, "faults.json")
+		Expect(os.WriteFile(path, []byte(content), 0644)).To(Succeed())
+		return Load(path)
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "faultinjection")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Reset()
+		Expect(os.Unsetenv(EnvVarName)).To(Succeed())
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("should not inject anything when env variable is not set", func() {
+		Expect(LoadFromEnv()).To(BeFalse())
+		Expect(Check(Drain, "", "worker")).To(Succeed())
+	})
+
+	It("should fail matching operations on matching devices only", func() {
+		Expect(load(`{"faults": [
+			{"operation": "sysfsWrite", "target": "sriov_numvfs", "device": "0000:f7:00.0", "errno": "EBUSY"},
+			{"operation": "exec", "target": "pf_bb_config", "message": "pf_bb_config crashed", "times": 2}
+		]}`)).To(Succeed())
+
+		Expect(Check(SysfsWrite, "sriov_numvfs", "/sys/bus/pci/devices/0000:f7:00.0/sriov_numvfs")).To(MatchError(syscall.EBUSY))
+		Expect(Check(SysfsWrite, "sriov_numvfs", "/sys/bus/pci/devices/0000:f8:00.0/sriov_numvfs")).To(Succeed())
+		Expect(Check(SysfsWrite, "driver_override", "/sys/bus/pci/devices/0000:f7:00.0/driver_override")).To(Succeed())
+		Expect(Check(Drain, "", "worker")).To(Succeed())
+
+		for i := 0; i < 2; i++ {
+			Expect(Check(Exec, "pf_bb_config", "/sriov_workdir/pf_bb_config", "ACC100", "-p", "0000:f7:00.0")).To(MatchError("pf_bb_config crashed"))
+		}
+		Expect(Check(Exec, "pf_bb_config", "/sriov_workdir/pf_bb_config", "ACC100", "-p", "0000:f7:00.0")).To(Succeed())
+	})
+
+	It("should load faults from file pointed to by env variable", func() {
+		path := filepath.Join(dir, "faults.json")
+		Expect(os.WriteFile(path, []byte(`{"faults": [{"operation": "drain"}]}`), 0644)).To(Succeed())
+		Expect(os.Setenv(EnvVarName, path)).To(Succeed())
+
+		Expect(LoadFromEnv()).To(BeTrue())
+		Expect(Check(Drain, "", "worker")).To(MatchError("injected drain failure"))
+	})
+
+	It("should reject invalid faults", func() {
+		Expect(load(`{"faults": [{"operation": "reboot"}]}`)).To(MatchError(ContainSubstring(`unknown operation "reboot"`)))
+		Expect(load(`{"faults": [{"operation": "exec", "errno": "EFOO"}]}`)).To(MatchError(ContainSubstring(`unsupported errno "EFOO"`)))
+		Expect(load(`{"faults": [{"operation": "exec", "command": "modprobe"}]}`)).To(MatchError(ContainSubstring("unknown field")))
+		Expect(Load(filepath.Join(dir, "missing.json"))).To(MatchError(ContainSubstring("failed to read fault injection file")))
+	})
+})
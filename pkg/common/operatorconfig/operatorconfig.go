@@ -0,0 +1,209 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package operatorconfig
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	sriovfecv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+// Setting binds field of SriovFecOperatorConfigSpec to environment variable which the component reads on startup
+type Setting struct {
+	EnvVarName string
+	// value returns the field formatted the way the reader of the variable parses it; "" when field is not specified
+	value func(spec *sriovfecv2.SriovFecOperatorConfigSpec) string
+}
+
+var (
+	MaxConcurrentReconciles = Setting{utils.SRIOV_PREFIX + "MAX_CONCURRENT_RECONCILES", func(spec *sriovfecv2.SriovFecOperatorConfigSpec) string {
+		return formatInt(spec.MaxConcurrentReconciles)
+	}}
+	InventoryStalenessBound = Setting{utils.SRIOV_PREFIX + "INVENTORY_STALENESS_BOUND", func(spec *sriovfecv2.SriovFecOperatorConfigSpec) string {
+		return formatDuration(spec.InventoryStalenessBound)
+	}}
+	MetricGatherInterval = Setting{utils.SRIOV_PREFIX + "METRIC_GATHER_INTERVAL", func(spec *sriovfecv2.SriovFecOperatorConfigSpec) string {
+		return formatDuration(spec.MetricGatherInterval)
+	}}
+	CordonOverdueThreshold = Setting{utils.SRIOV_PREFIX + "CORDON_OVERDUE_THRESHOLD", func(spec *sriovfecv2.SriovFecOperatorConfigSpec) string {
+		return formatDuration(spec.CordonOverdueThreshold)
+	}}
+	DegradedRequeueInterval = Setting{utils.SRIOV_PREFIX + "DEGRADED_REQUEUE_INTERVAL", func(spec *sriovfecv2.SriovFecOperatorConfigSpec) string {
+		return formatDuration(spec.DegradedRequeueInterval)
+	}}
+	DrainTimeout = Setting{"DRAIN_TIMEOUT_SECONDS", func(spec *sriovfecv2.SriovFecOperatorConfigSpec) string {
+		return formatSeconds(spec.DrainTimeout)
+	}}
+	RescheduleTimeout = Setting{"RESCHEDULE_TIMEOUT_SECONDS", func(spec *sriovfecv2.SriovFecOperatorConfigSpec) string {
+		return formatSeconds(spec.RescheduleTimeout)
+	}}
+	RebootLeaseDuration = Setting{"REBOOT_LEASE_DURATION_SECONDS", func(spec *sriovfecv2.SriovFecOperatorConfigSpec) string {
+		return formatSeconds(spec.RebootLeaseDuration)
+	}}
+	FeatureGates = Setting{"FEATURE_GATES", func(spec *sriovfecv2.SriovFecOperatorConfigSpec) string {
+		return spec.FeatureGates
+	}}
+	PfBBConfigOutputLimitKB = Setting{utils.SRIOV_PREFIX + "PF_BB_CONFIG_OUTPUT_LIMIT_KB", func(spec *sriovfecv2.SriovFecOperatorConfigSpec) string {
+		return formatInt(spec.PfBBConfigOutputLimitKB)
+	}}
+	// ConfigurationHookPathPrefix is read by the webhook and the daemon, both have to restrict hooks the same way
+	ConfigurationHookPathPrefix = Setting{utils.CONFIGURATION_HOOK_PATH_PREFIX, func(spec *sriovfecv2.SriovFecOperatorConfigSpec) string {
+		return spec.ConfigurationHookPathPrefix
+	}}
+	ConditionMessageLimitBytes = Setting{utils.SRIOV_PREFIX + "CONDITION_MESSAGE_LIMIT_BYTES", func(spec *sriovfecv2.SriovFecOperatorConfigSpec) string {
+		return formatInt(spec.ConditionMessageLimitBytes)
+	}}
+	RebootLockName = Setting{utils.SRIOV_PREFIX + "REBOOT_LOCK_NAME", func(spec *sriovfecv2.SriovFecOperatorConfigSpec) string {
+		return spec.RebootLockName
+	}}
+	RebootLockTimeout = Setting{utils.SRIOV_PREFIX + "REBOOT_LOCK_TIMEOUT", func(spec *sriovfecv2.SriovFecOperatorConfigSpec) string {
+		return formatDuration(spec.RebootLockTimeout)
+	}}
+	RebootLockDuration = Setting{utils.SRIOV_PREFIX + "REBOOT_LOCK_DURATION", func(spec *sriovfecv2.SriovFecOperatorConfigSpec) string {
+		return formatDuration(spec.RebootLockDuration)
+	}}
+
+	// OperatorSettings are read by the operator on startup
+	OperatorSettings = []Setting{MaxConcurrentReconciles, InventoryStalenessBound, ConfigurationHookPathPrefix,
+		ConditionMessageLimitBytes}
+	// DaemonSettings are read by the daemon on startup
+	DaemonSettings = []Setting{MetricGatherInterval, CordonOverdueThreshold, DegradedRequeueInterval, DrainTimeout, RescheduleTimeout,
+		RebootLeaseDuration, FeatureGates, PfBBConfigOutputLimitKB, ConfigurationHookPathPrefix, ConditionMessageLimitBytes, RebootLockName,
+		RebootLockTimeout, RebootLockDuration}
+)
+
+func formatInt(value *int) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.Itoa(*value)
+}
+
+func formatDuration(value *metav1.Duration) string {
+	if value == nil {
+		return ""
+	}
+	return value.Duration.String()
+}
+
+func formatSeconds(value *metav1.Duration) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatInt(int64(value.Seconds()), 10)
+}
+
+// Get returns SriovFecOperatorConfig named OperatorConfigName; nil is returned when it does not exist
+func Get(ctx context.Context, c client.Reader) (*sriovfecv2.SriovFecOperatorConfig, error) {
+	config := &sriovfecv2.SriovFecOperatorConfig{}
+	err := c.Get(ctx, client.ObjectKey{Name: sriovfecv2.OperatorConfigName}, config)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get SriovFecOperatorConfig - %v", err)
+	}
+	return config, nil
+}
+
+// Startup is the operator configuration which the component has started with
+type Startup struct {
+	settings []Setting
+	// env variables set in the container; they take precedence over SriovFecOperatorConfig
+	overridden map[string]bool
+	// values taken from SriovFecOperatorConfig, by env variable name
+	values map[string]string
+}
+
+// Apply exports settings specified by config as env variables, so that they are picked up by readers of the variables.
+// Variables already set in the container are left untouched - they take precedence over config. Nil config leaves
+// defaults of all settings which are not overridden.
+func Apply(config *sriovfecv2.SriovFecOperatorConfig, settings []Setting, log *logrus.Logger) (*Startup, error) {
+	startup := &Startup{settings: settings, overridden: map[string]bool{}, values: map[string]string{}}
+	spec := specOf(config)
+	for _, setting := range settings {
+		if envValue := os.Getenv(setting.EnvVarName); envValue != "" {
+			startup.overridden[setting.EnvVarName] = true
+			if setting.value(spec) != "" {
+				log.WithField("variable", setting.EnvVarName).WithField("value", envValue).
+					Warn("env variable overrides SriovFecOperatorConfig")
+			}
+			continue
+		}
+		value := setting.value(spec)
+		startup.values[setting.EnvVarName] = value
+		if value == "" {
+			continue
+		}
+		if err := os.Setenv(setting.EnvVarName, value); err != nil {
+			return nil, fmt.Errorf("failed to set %s env variable - %v", setting.EnvVarName, err)
+		}
+		log.WithField("variable", setting.EnvVarName).WithField("value", value).Info("applied SriovFecOperatorConfig")
+	}
+	return startup, nil
+}
+
+// Changed returns env variables of settings which config sets to different values than the component has started
+// with; overridden settings are never changed
+func (s *Startup) Changed(config *sriovfecv2.SriovFecOperatorConfig) []string {
+	spec := specOf(config)
+	var changed []string
+	for _, setting := range s.settings {
+		if s.overridden[setting.EnvVarName] {
+			continue
+		}
+		if setting.value(spec) != s.values[setting.EnvVarName] {
+			changed = append(changed, setting.EnvVarName)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+func specOf(config *sriovfecv2.SriovFecOperatorConfig) *sriovfecv2.SriovFecOperatorConfigSpec {
+	if config == nil {
+		return &sriovfecv2.SriovFecOperatorConfigSpec{}
+	}
+	return &config.Spec
+}
+
+// LogLevel returns log level set by config; Info when config does not set it or sets invalid one
+func LogLevel(config *sriovfecv2.SriovFecOperatorConfig) (logrus.Level, error) {
+	spec := specOf(config)
+	if spec.LogLevel == "" {
+		return logrus.InfoLevel, nil
+	}
+	level, err := logrus.ParseLevel(spec.LogLevel)
+	if err != nil {
+		return logrus.InfoLevel, err
+	}
+	return level, nil
+}
+
+// Load applies SriovFecOperatorConfig on startup of the component: log level is set and settings are exported as env
+// variables. Defaults are kept when SriovFecOperatorConfig cannot be read.
+func Load(c client.Reader, settings []Setting, log *logrus.Logger) (*Startup, error) {
+	config, err := Get(context.TODO(), c)
+	if err != nil {
+		log.WithError(err).Warn("using default operator configuration")
+	}
+
+	level, err := LogLevel(config)
+	if err != nil {
+		log.WithError(err).Error("invalid log level in SriovFecOperatorConfig, using info")
+	}
+	utils.SetLogLevel(level)
+
+	return Apply(config, settings, log)
+}
@@ -0,0 +1,168 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package operatorconfig
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sriovfecv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+func TestOperatorConfig(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "OperatorConfig suite")
+}
+
+func operatorConfig(spec sriovfecv2.SriovFecOperatorConfigSpec) *sriovfecv2.SriovFecOperatorConfig {
+	return &sriovfecv2.SriovFecOperatorConfig{ObjectMeta: metav1.ObjectMeta{Name: sriovfecv2.OperatorConfigName}, Spec: spec}
+}
+
+var _ = Describe("Apply", func() {
+	settings := []Setting{DrainTimeout, CordonOverdueThreshold, FeatureGates}
+
+	AfterEach(func() {
+		for _, setting := range settings {
+			Expect(os.Unsetenv(setting.EnvVarName)).To(Succeed())
+		}
+	})
+
+	It("should export settings which are not overridden by env variables", func() {
+		Expect(os.Setenv(CordonOverdueThreshold.EnvVarName, "2h")).To(Succeed())
+		config := operatorConfig(sriovfecv2.SriovFecOperatorConfigSpec{
+			DrainTimeout:           &metav1.Duration{Duration: 3 * time.Minute},
+			CordonOverdueThreshold: &metav1.Duration{Duration: 30 * time.Minute},
+		})
+
+		_, err := Apply(config, settings, logrus.New())
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(os.Getenv(DrainTimeout.EnvVarName)).To(Equal("180"))
+		Expect(os.Getenv(CordonOverdueThreshold.EnvVarName)).To(Equal("2h"))
+		Expect(os.Getenv(FeatureGates.EnvVarName)).To(BeEmpty())
+	})
+
+	It("should report changed settings which are not overridden", func() {
+		Expect(os.Setenv(CordonOverdueThreshold.EnvVarName, "2h")).To(Succeed())
+		startup, err := Apply(operatorConfig(sriovfecv2.SriovFecOperatorConfigSpec{
+			DrainTimeout: &metav1.Duration{Duration: 3 * time.Minute},
+		}), settings, logrus.New())
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(startup.Changed(operatorConfig(sriovfecv2.SriovFecOperatorConfigSpec{
+			DrainTimeout:           &metav1.Duration{Duration: 3*time.Minute + 100*time.Millisecond},
+			CordonOverdueThreshold: &metav1.Duration{Duration: 30 * time.Minute},
+			LogLevel:               "debug",
+		}))).To(BeEmpty())
+
+		Expect(startup.Changed(nil)).To(Equal([]string{DrainTimeout.EnvVarName}))
+		Expect(startup.Changed(operatorConfig(sriovfecv2.SriovFecOperatorConfigSpec{
+			DrainTimeout: &metav1.Duration{Duration: 3 * time.Minute},
+			FeatureGates: "AuditLog=true",
+		}))).To(Equal([]string{FeatureGates.EnvVarName}))
+	})
+})
+
+var _ = Describe("Reconciler", func() {
+	var (
+		c          client.Client
+		reconciler *Reconciler
+		restarted  bool
+		busy       bool
+		resync     time.Duration
+	)
+
+	reconcile := func() ctrl.Result {
+		result, err := reconciler.Reconcile(context.TODO(), ctrl.Request{})
+		Expect(err).ToNot(HaveOccurred())
+		return result
+	}
+
+	update := func(spec sriovfecv2.SriovFecOperatorConfigSpec) {
+		config := &sriovfecv2.SriovFecOperatorConfig{}
+		Expect(c.Get(context.TODO(), client.ObjectKey{Name: sriovfecv2.OperatorConfigName}, config)).To(Succeed())
+		config.Spec = spec
+		Expect(c.Update(context.TODO(), config)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(sriovfecv2.AddToScheme(scheme)).To(Succeed())
+		config := operatorConfig(sriovfecv2.SriovFecOperatorConfigSpec{MaxConcurrentReconciles: new(int)})
+		*config.Spec.MaxConcurrentReconciles = 2
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(config).Build()
+
+		startup, err := Apply(config, OperatorSettings, logrus.New())
+		Expect(err).ToNot(HaveOccurred())
+		restarted, busy, resync = false, false, -1
+		reconciler = &Reconciler{
+			Client:          c,
+			Log:             logrus.New(),
+			Startup:         startup,
+			SetResyncPeriod: func(period time.Duration) { resync = period },
+			Busy:            func() bool { return busy },
+			Restart:         func() { restarted = true },
+		}
+	})
+
+	AfterEach(func() {
+		for _, setting := range OperatorSettings {
+			Expect(os.Unsetenv(setting.EnvVarName)).To(Succeed())
+		}
+		utils.SetLogLevel(logrus.InfoLevel)
+	})
+
+	It("should apply log level and resync period without restart", func() {
+		log := utils.NewLogger()
+		maxConcurrentReconciles := 2
+		update(sriovfecv2.SriovFecOperatorConfigSpec{
+			MaxConcurrentReconciles: &maxConcurrentReconciles,
+			LogLevel:                "debug",
+			ResyncPeriod:            &metav1.Duration{Duration: 5 * time.Minute},
+		})
+
+		Expect(reconcile()).To(Equal(ctrl.Result{}))
+
+		Expect(log.IsLevelEnabled(logrus.DebugLevel)).To(BeTrue(), "level of existing loggers has to be changed")
+		Expect(utils.NewLogger().IsLevelEnabled(logrus.DebugLevel)).To(BeTrue())
+		Expect(resync).To(Equal(5 * time.Minute))
+		Expect(restarted).To(BeFalse())
+	})
+
+	It("should restore defaults when SriovFecOperatorConfig is deleted", func() {
+		utils.SetLogLevel(logrus.DebugLevel)
+		Expect(c.Delete(context.TODO(), operatorConfig(sriovfecv2.SriovFecOperatorConfigSpec{}))).To(Succeed())
+
+		reconcile()
+
+		Expect(utils.NewLogger().IsLevelEnabled(logrus.DebugLevel)).To(BeFalse())
+		Expect(resync).To(BeZero())
+		Expect(restarted).To(BeTrue(), "maxConcurrentReconciles changed back to default")
+	})
+
+	It("should restart once the component is idle when setting read on startup changed", func() {
+		maxConcurrentReconciles := 4
+		update(sriovfecv2.SriovFecOperatorConfigSpec{MaxConcurrentReconciles: &maxConcurrentReconciles})
+		busy = true
+
+		Expect(reconcile().RequeueAfter).To(Equal(restartRetryPeriod))
+		Expect(restarted).To(BeFalse())
+
+		busy = false
+		reconcile()
+		Expect(restarted).To(BeTrue())
+	})
+})
@@ -0,0 +1,81 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package operatorconfig
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	sriovfecv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+// restartRetryPeriod is how often restart postponed by busy component is retried
+const restartRetryPeriod = 10 * time.Second
+
+// Reconciler applies changes of SriovFecOperatorConfig to the running component. Log level and resync period are
+// applied in place; change of any setting which is read on startup makes the component restart.
+type Reconciler struct {
+	client.Client
+	Log     *logrus.Logger
+	Startup *Startup
+	// SetResyncPeriod is called with resync period set by SriovFecOperatorConfig, 0 when it is not set; nil for
+	// components which do not resync
+	SetResyncPeriod func(time.Duration)
+	// Busy postpones the restart while it returns true; optional
+	Busy func() bool
+	// Restart stops the component, so that it is started again by kubelet with new configuration
+	Restart func()
+}
+
+// +kubebuilder:rbac:groups=sriovfec.intel.com,resources=sriovfecoperatorconfigs,verbs=get;list;watch
+
+func (r *Reconciler) Reconcile(ctx context.Context, _ ctrl.Request) (ctrl.Result, error) {
+	config, err := Get(ctx, r)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	level, err := LogLevel(config)
+	if err != nil {
+		r.Log.WithError(err).Error("invalid log level in SriovFecOperatorConfig, using info")
+	}
+	utils.SetLogLevel(level)
+
+	if r.SetResyncPeriod != nil {
+		var period time.Duration
+		if config != nil && config.Spec.ResyncPeriod != nil {
+			period = config.Spec.ResyncPeriod.Duration
+		}
+		r.SetResyncPeriod(period)
+	}
+
+	changed := r.Startup.Changed(config)
+	if len(changed) == 0 {
+		return ctrl.Result{}, nil
+	}
+	if r.Busy != nil && r.Busy() {
+		r.Log.WithField("variables", changed).Info("SriovFecOperatorConfig changed, restart postponed until component is idle")
+		return ctrl.Result{RequeueAfter: restartRetryPeriod}, nil
+	}
+	r.Log.WithField("variables", changed).Info("SriovFecOperatorConfig changed settings read on startup, restarting")
+	r.Restart()
+	return ctrl.Result{}, nil
+}
+
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	isOperatorConfig := predicate.NewPredicateFuncs(func(o client.Object) bool {
+		return o.GetName() == sriovfecv2.OperatorConfigName
+	})
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("sriovfecoperatorconfig").
+		For(&sriovfecv2.SriovFecOperatorConfig{}, builder.WithPredicates(isOperatorConfig)).
+		Complete(r)
+}
@@ -0,0 +1,63 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package syncmetrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	// KindSriovFec and KindSriovVrb label metrics of SriovFecNodeConfigs and SriovVrbNodeConfigs
+	KindSriovFec = "sriovfec"
+	KindSriovVrb = "sriovvrb"
+
+	reasonUnknown = "Unknown"
+)
+
+var (
+	lastSyncTimestamp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "node_config_last_sync_timestamp_seconds",
+		Help: `unix time when cluster controller last synchronized node config with cluster configs successfully. 'kind' - represents node config kind. Available values: 'sriovfec', 'sriovvrb'`,
+	}, []string{"kind", "node"})
+
+	lastSyncError = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "node_config_last_sync_error_info",
+		Help: `set to 1 when the last synchronization of node config failed; 'reason' is the reason of the failure reported by API server or 'Unknown'. Removed once synchronization succeeds`,
+	}, []string{"kind", "node", "reason"})
+
+	now = time.Now
+
+	// recordLock makes replacement of node's metrics atomic, as node configs are synchronized by concurrent reconciles
+	recordLock sync.Mutex
+)
+
+func init() {
+	metrics.Registry.MustRegister(lastSyncTimestamp, lastSyncError)
+}
+
+// Record updates metrics with the result of synchronization of node config of given kind with cluster configs
+func Record(kind, node string, err error) {
+	recordLock.Lock()
+	defer recordLock.Unlock()
+
+	lastSyncError.DeletePartialMatch(prometheus.Labels{"kind": kind, "node": node})
+	if err != nil {
+		lastSyncError.WithLabelValues(kind, node, reason(err)).Set(1)
+		return
+	}
+	lastSyncTimestamp.WithLabelValues(kind, node).Set(float64(now().Unix()))
+}
+
+func reason(err error) string {
+	if reason := apierrors.ReasonForError(err); reason != metav1.StatusReasonUnknown {
+		return string(reason)
+	}
+	return reasonUnknown
+}
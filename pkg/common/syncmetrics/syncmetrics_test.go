@@ -0,0 +1,61 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package syncmetrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestSyncMetrics(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "SyncMetrics suite")
+}
+
+var _ = Describe("Record", func() {
+	BeforeEach(func() {
+		lastSyncTimestamp.Reset()
+		lastSyncError.Reset()
+		now = func() time.Time { return time.Unix(1700000000, 0) }
+	})
+
+	AfterEach(func() {
+		now = time.Now
+	})
+
+	It("should stamp node on success", func() {
+		Record(KindSriovFec, "worker-0", nil)
+		Expect(testutil.ToFloat64(lastSyncTimestamp.WithLabelValues(KindSriovFec, "worker-0"))).To(Equal(float64(1700000000)))
+		Expect(testutil.CollectAndCount(lastSyncError)).To(BeZero())
+	})
+
+	It("should report the last error with its reason and keep the last success", func() {
+		Record(KindSriovVrb, "worker-0", nil)
+		conflict := apierrors.NewConflict(schema.GroupResource{Group: "sriovvrb.intel.com", Resource: "sriovvrbnodeconfigs"}, "worker-0", errors.New("modified"))
+
+		now = func() time.Time { return time.Unix(1700000060, 0) }
+		Record(KindSriovVrb, "worker-0", conflict)
+		Expect(testutil.ToFloat64(lastSyncError.WithLabelValues(KindSriovVrb, "worker-0", "Conflict"))).To(Equal(float64(1)))
+		Expect(testutil.ToFloat64(lastSyncTimestamp.WithLabelValues(KindSriovVrb, "worker-0"))).To(Equal(float64(1700000000)))
+
+		Record(KindSriovVrb, "worker-0", errors.New("no accelerators"))
+		Expect(testutil.CollectAndCount(lastSyncError)).To(Equal(1))
+		Expect(testutil.ToFloat64(lastSyncError.WithLabelValues(KindSriovVrb, "worker-0", "Unknown"))).To(Equal(float64(1)))
+	})
+
+	It("should clear the error of the node once synchronization succeeds", func() {
+		Record(KindSriovFec, "worker-0", errors.New("failed"))
+		Record(KindSriovFec, "worker-1", errors.New("failed"))
+		Record(KindSriovFec, "worker-0", nil)
+		Expect(testutil.CollectAndCount(lastSyncError)).To(Equal(1))
+		Expect(testutil.ToFloat64(lastSyncError.WithLabelValues(KindSriovFec, "worker-1", "Unknown"))).To(Equal(float64(1)))
+	})
+})
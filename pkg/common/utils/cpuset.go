@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxCPUs bounds CPU IDs accepted in cpusets; the kernel does not support more CPUs by default either
+const maxCPUs = 8192
+
+// ParseCPUSet parses cpuset in Linux list format (e.g. "0-3,8,10-11", as in /sys/devices/system/cpu/online or kubelet's
+// reservedSystemCPUs) and returns sorted, unique CPU IDs. Empty cpuset results in no CPUs.
+func ParseCPUSet(cpuset string) ([]int, error) {
+	cpuset = strings.TrimSpace(cpuset)
+	if cpuset == "" {
+		return nil, nil
+	}
+	unique := map[int]bool{}
+	for _, r := range strings.Split(cpuset, ",") {
+		first, last, isRange := strings.Cut(strings.TrimSpace(r), "-")
+		start, err := parseCPU(first)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cpuset '%s' - %v", cpuset, err)
+		}
+		end := start
+		if isRange {
+			if end, err = parseCPU(last); err != nil {
+				return nil, fmt.Errorf("invalid cpuset '%s' - %v", cpuset, err)
+			}
+			if end < start {
+				return nil, fmt.Errorf("invalid cpuset '%s' - range %s is reversed", cpuset, r)
+			}
+		}
+		for cpu := start; cpu <= end; cpu++ {
+			unique[cpu] = true
+		}
+	}
+	cpus := make([]int, 0, len(unique))
+	for cpu := range unique {
+		cpus = append(cpus, cpu)
+	}
+	sort.Ints(cpus)
+	return cpus, nil
+}
+
+func parseCPU(s string) (int, error) {
+	cpu, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || cpu < 0 || cpu >= maxCPUs {
+		return 0, fmt.Errorf("'%s' is not a CPU ID between 0 and %d", s, maxCPUs-1)
+	}
+	return cpu, nil
+}
+
+// FormatCPUSet returns sorted CPU IDs in Linux list format, with consecutive CPUs collapsed into ranges
+func FormatCPUSet(cpus []int) string {
+	var parts []string
+	for i := 0; i < len(cpus); {
+		j := i
+		for j+1 < len(cpus) && cpus[j+1] == cpus[j]+1 {
+			j++
+		}
+		if i == j {
+			parts = append(parts, strconv.Itoa(cpus[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", cpus[i], cpus[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,46 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package utils
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ParseCPUSet", func() {
+	It("parses list format into sorted unique CPUs", func() {
+		for cpuset, expected := range map[string][]int{
+			"0":              {0},
+			"0-3":            {0, 1, 2, 3},
+			"8,0-1, 4":       {0, 1, 4, 8},
+			"2-3,3,1-2\n":    {1, 2, 3},
+			" 10 - 11 ,5-5 ": {5, 10, 11},
+		} {
+			cpus, err := ParseCPUSet(cpuset)
+			Expect(err).ToNot(HaveOccurred(), cpuset)
+			Expect(cpus).To(Equal(expected), cpuset)
+		}
+	})
+
+	It("returns no CPUs for empty cpuset", func() {
+		cpus, err := ParseCPUSet(" ")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cpus).To(BeEmpty())
+	})
+
+	It("rejects malformed cpusets", func() {
+		for _, cpuset := range []string{"a", "0-", "-1", "3-1", "0,,1", "1-2-3", "8192", "0x1"} {
+			_, err := ParseCPUSet(cpuset)
+			Expect(err).To(MatchError(ContainSubstring("invalid cpuset")), cpuset)
+		}
+	})
+})
+
+var _ = Describe("FormatCPUSet", func() {
+	It("collapses consecutive CPUs into ranges", func() {
+		Expect(FormatCPUSet(nil)).To(BeEmpty())
+		Expect(FormatCPUSet([]int{3})).To(Equal("3"))
+		Expect(FormatCPUSet([]int{0, 1, 2, 4, 6, 7})).To(Equal("0-2,4,6-7"))
+	})
+})
@@ -0,0 +1,82 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	DevicePluginConfigMapName = "sriovdp-config"
+	DevicePluginConfigKey     = "config.json"
+	// DefaultResourcePrefix is used by sriov-device-plugin when neither resource nor command line defines the prefix
+	DefaultResourcePrefix = "intel.com"
+)
+
+// devicePluginConfig is a subset of sriov-device-plugin config required to map VFs to resources
+type devicePluginConfig struct {
+	ResourceList []struct {
+		ResourcePrefix string                      `json:"resourcePrefix,omitempty"`
+		ResourceName   string                      `json:"resourceName"`
+		Selectors      DevicePluginDeviceSelectors `json:"selectors,omitempty"`
+	} `json:"resourceList"`
+}
+
+type DevicePluginDeviceSelectors struct {
+	Vendors []string `json:"vendors,omitempty"`
+	Devices []string `json:"devices,omitempty"`
+	Drivers []string `json:"drivers,omitempty"`
+}
+
+// DevicePluginResource is a resource exposed by sriov-device-plugin, as defined in its config
+type DevicePluginResource struct {
+	// Name is prefixed like in node's allocatable, e.g. intel.com/intel_fec_acc100
+	Name      string
+	Selectors DevicePluginDeviceSelectors
+}
+
+// Matches tells whether VF of given vendor, device and driver is exposed as the resource
+func (r DevicePluginResource) Matches(vendorID, deviceID, driver string) bool {
+	contains := func(values []string, value string) bool {
+		if len(values) == 0 {
+			return true
+		}
+		for _, v := range values {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	}
+	return contains(r.Selectors.Vendors, vendorID) && contains(r.Selectors.Devices, deviceID) && contains(r.Selectors.Drivers, driver)
+}
+
+// ParseDevicePluginResources returns resources defined in data of sriov-device-plugin ConfigMap
+func ParseDevicePluginResources(data map[string]string) ([]DevicePluginResource, error) {
+	config := devicePluginConfig{}
+	if err := json.Unmarshal([]byte(data[DevicePluginConfigKey]), &config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s of %s ConfigMap - %v", DevicePluginConfigKey, DevicePluginConfigMapName, err)
+	}
+
+	var resources []DevicePluginResource
+	for _, resource := range config.ResourceList {
+		prefix := resource.ResourcePrefix
+		if prefix == "" {
+			prefix = DefaultResourcePrefix
+		}
+		resources = append(resources, DevicePluginResource{Name: prefix + "/" + resource.ResourceName, Selectors: resource.Selectors})
+	}
+	return resources, nil
+}
+
+// ResourceOfVF returns name of device plugin resource which exposes VF; empty when there is no such resource
+func ResourceOfVF(resources []DevicePluginResource, vendorID, deviceID, driver string) string {
+	for _, resource := range resources {
+		if resource.Matches(vendorID, deviceID, driver) {
+			return resource.Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,37 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package utils
+
+import (
+	"reflect"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+// DifferingFields returns JSON names of fields of structs a and b (of the same type) which are not semantically equal,
+// in order of declaration; fields named in skip are not compared
+func DifferingFields(a, b interface{}, skip ...string) []string {
+	va, vb := reflect.Indirect(reflect.ValueOf(a)), reflect.Indirect(reflect.ValueOf(b))
+	skipped := map[string]bool{}
+	for _, name := range skip {
+		skipped[name] = true
+	}
+
+	var differing []string
+	for i := 0; i < va.NumField(); i++ {
+		field := va.Type().Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" {
+			name = field.Name
+		}
+		if !field.IsExported() || skipped[name] {
+			continue
+		}
+		if !equality.Semantic.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			differing = append(differing, name)
+		}
+	}
+	return differing
+}
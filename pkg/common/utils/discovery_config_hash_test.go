@@ -0,0 +1,48 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package utils
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DiscoveryConfigHash", func() {
+	It("should not depend on formatting of the config", func() {
+		compact, err := ParseDiscoveryConfig([]byte(`{"VendorID":{"8086":"Intel"},"Devices":{"0d5c":"FPGA_5GNR","57c0":"ACC200"}}`))
+		Expect(err).ToNot(HaveOccurred())
+		indented, err := ParseDiscoveryConfig([]byte(`{
+			"Devices": {"57c0": "ACC200", "0d5c": "FPGA_5GNR"},
+			"VendorID": {"8086": "Intel"}
+		}`))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(DiscoveryConfigHash(compact)).To(HaveLen(64))
+		Expect(DiscoveryConfigHash(compact)).To(Equal(DiscoveryConfigHash(indented)))
+	})
+
+	It("should change with the config", func() {
+		cfg := AcceleratorDiscoveryConfig{Devices: map[string]string{"0d5c": "FPGA_5GNR"}}
+		hash := DiscoveryConfigHash(cfg)
+		cfg.Devices["57c0"] = "ACC200"
+		Expect(DiscoveryConfigHash(cfg)).ToNot(Equal(hash))
+	})
+})
+
+var _ = Describe("DiscoveryConfigDrift", func() {
+	It("should report nothing when nodes report the same hash", func() {
+		Expect(DiscoveryConfigDrift(map[string]string{"node-a": "aaa", "node-b": "aaa", "old-daemon": ""})).To(BeEmpty())
+		Expect(DiscoveryConfigDrift(nil)).To(BeEmpty())
+	})
+
+	It("should list nodes by hash, the most common one first", func() {
+		Expect(DiscoveryConfigDrift(map[string]string{
+			"node-e": "bbbbbbbbbbbbbbbbbbbb",
+			"node-a": "aaaaaaaaaaaaaaaaaaaa", "node-d": "aaaaaaaaaaaaaaaaaaaa", "node-b": "aaaaaaaaaaaaaaaaaaaa",
+			"node-c":     "aaaaaaaaaaaaaaaaaaaa",
+			"old-daemon": "",
+		})).To(Equal("nodes report 2 different discovery configs: aaaaaaaaaaaa (node-a, node-b, node-c and 1 more); " +
+			"bbbbbbbbbbbb (node-e)"))
+	})
+})
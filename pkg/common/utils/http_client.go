@@ -0,0 +1,66 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package utils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/http/httpproxy"
+)
+
+// TrustedCABundlePath is where CA bundle of cluster-wide proxy is mounted; on OpenShift the bundle is injected into
+// ConfigMap labeled with config.openshift.io/inject-trusted-cabundle
+var TrustedCABundlePath = "/etc/sriov-fec/trusted-ca/ca-bundle.crt"
+
+// NewHTTPClient returns client which has to be used for all outbound HTTP(S) calls. Requests are sent through proxy
+// defined with HTTPS_PROXY, HTTP_PROXY and NO_PROXY env variables. Server certificates are verified with system CAs,
+// CA bundle of cluster-wide proxy (if mounted) and given certificates.
+func NewHTTPClient(log *logrus.Logger, certs ...*x509.Certificate) (*http.Client, error) {
+	rootCAs, err := x509.SystemCertPool()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get syscerts - %v", err)
+	}
+
+	bundle, err := os.ReadFile(TrustedCABundlePath)
+	switch {
+	case err == nil:
+		if !rootCAs.AppendCertsFromPEM(bundle) {
+			log.WithField("path", TrustedCABundlePath).Warn("no certificates found in trusted CA bundle")
+		}
+	case !os.IsNotExist(err):
+		log.WithError(err).WithField("path", TrustedCABundlePath).Warn("failed to read trusted CA bundle")
+	}
+
+	for _, cert := range certs {
+		rootCAs.AddCert(cert)
+	}
+
+	proxyConfig := httpproxy.FromEnvironment()
+	if proxyConfig.HTTPSProxy == "" && proxyConfig.HTTPProxy == "" {
+		log.Info("no proxy configured - outbound calls are made directly and fail if there is no egress")
+	} else {
+		log.WithField("httpsProxy", proxyConfig.HTTPSProxy).
+			WithField("httpProxy", proxyConfig.HTTPProxy).
+			WithField("noProxy", proxyConfig.NoProxy).
+			Info("using proxy for outbound calls")
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxyFunc(proxyConfig)
+	transport.TLSClientConfig = &tls.Config{RootCAs: rootCAs}
+	return &http.Client{Transport: transport}, nil
+}
+
+func proxyFunc(config *httpproxy.Config) func(*http.Request) (*url.URL, error) {
+	selectProxy := config.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return selectProxy(req.URL)
+	}
+}
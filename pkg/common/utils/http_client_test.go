@@ -0,0 +1,87 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package utils
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NewHTTPClient", func() {
+	var originalBundlePath string
+
+	BeforeEach(func() {
+		originalBundlePath = TrustedCABundlePath
+		TrustedCABundlePath = filepath.Join(os.TempDir(), "not-existing-ca-bundle.crt")
+		for _, name := range []string{"HTTPS_PROXY", "HTTP_PROXY", "NO_PROXY", "https_proxy", "http_proxy", "no_proxy"} {
+			Expect(os.Unsetenv(name)).To(Succeed())
+		}
+	})
+
+	AfterEach(func() {
+		TrustedCABundlePath = originalBundlePath
+		for _, name := range []string{"HTTPS_PROXY", "HTTP_PROXY", "NO_PROXY"} {
+			Expect(os.Unsetenv(name)).To(Succeed())
+		}
+	})
+
+	proxyOf := func(client *http.Client, url string) string {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		Expect(err).ToNot(HaveOccurred())
+		proxy, err := client.Transport.(*http.Transport).Proxy(req)
+		Expect(err).ToNot(HaveOccurred())
+		if proxy == nil {
+			return ""
+		}
+		return proxy.String()
+	}
+
+	It("should select proxy according to HTTPS_PROXY, HTTP_PROXY and NO_PROXY", func() {
+		Expect(os.Setenv("HTTPS_PROXY", "http://secure-proxy:3128")).To(Succeed())
+		Expect(os.Setenv("HTTP_PROXY", "http://proxy:3128")).To(Succeed())
+		Expect(os.Setenv("NO_PROXY", ".cluster.local,10.0.0.0/8")).To(Succeed())
+
+		client, err := NewHTTPClient(NewLogger())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(proxyOf(client, "https://artifacts.example.com/fft.tar.gz")).To(Equal("http://secure-proxy:3128"))
+		Expect(proxyOf(client, "http://artifacts.example.com/fft.tar.gz")).To(Equal("http://proxy:3128"))
+		Expect(proxyOf(client, "https://artifacts.svc.cluster.local/fft.tar.gz")).To(BeEmpty())
+		Expect(proxyOf(client, "https://10.1.2.3/fft.tar.gz")).To(BeEmpty())
+	})
+
+	It("should connect directly when no proxy is configured", func() {
+		client, err := NewHTTPClient(NewLogger())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(proxyOf(client, "https://artifacts.example.com/fft.tar.gz")).To(BeEmpty())
+	})
+
+	It("should trust CA bundle of cluster-wide proxy", func() {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer server.Close()
+
+		client, err := NewHTTPClient(NewLogger())
+		Expect(err).ToNot(HaveOccurred())
+		_, err = client.Get(server.URL)
+		Expect(err).To(HaveOccurred())
+
+		dir, err := os.MkdirTemp("", "trusted-ca")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+		TrustedCABundlePath = filepath.Join(dir, "ca-bundle.crt")
+		bundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+		Expect(os.WriteFile(TrustedCABundlePath, bundle, 0644)).To(Succeed())
+
+		client, err = NewHTTPClient(NewLogger())
+		Expect(err).ToNot(HaveOccurred())
+		resp, err := client.Get(server.URL)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.Body.Close()).To(Succeed())
+	})
+})
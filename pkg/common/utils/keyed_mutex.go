@@ -0,0 +1,20 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package utils
+
+import "sync"
+
+// KeyedMutex provides mutual exclusion of operations on the same key (e.g. node name), while operations on different
+// keys run in parallel. Zero value is ready to use.
+type KeyedMutex struct {
+	locks sync.Map
+}
+
+// Lock locks mutex of the key and returns function which unlocks it
+func (k *KeyedMutex) Lock(key string) (unlock func()) {
+	m, _ := k.locks.LoadOrStore(key, &sync.Mutex{})
+	mutex := m.(*sync.Mutex)
+	mutex.Lock()
+	return mutex.Unlock
+}
@@ -4,10 +4,19 @@
 package utils
 
 import (
+	"sync"
+
 	"github.com/go-logr/logr"
 	"github.com/sirupsen/logrus"
 )
 
+var (
+	loggersMutex sync.Mutex
+	// loggers created with NewLogger, kept so that SetLogLevel reaches loggers which were already handed out
+	loggers  []*logrus.Logger
+	logLevel = logrus.InfoLevel
+)
+
 type logrusWrapper struct {
 	log       *logrus.Logger
 	lastEntry *logrus.Entry
@@ -74,13 +83,34 @@ func (l *logrusWrapper) WithName(name string) logr.LogSink {
 	return l
 }
 
+// NewLogger returns logger with level set by SetLogLevel. Loggers are retained for the lifetime of the process, so
+// they are meant to be created once per component, not per operation.
 func NewLogger() *logrus.Logger {
 	log := logrus.New()
 	log.SetReportCaller(true)
 	log.SetFormatter(&logrus.JSONFormatter{})
+
+	loggersMutex.Lock()
+	defer loggersMutex.Unlock()
+	log.SetLevel(logLevel)
+	loggers = append(loggers, log)
 	return log
 }
 
+// SetLogLevel changes level of all loggers created with NewLogger and of the standard logger
+func SetLogLevel(level logrus.Level) {
+	loggersMutex.Lock()
+	defer loggersMutex.Unlock()
+	if level == logLevel {
+		return
+	}
+	logLevel = level
+	logrus.SetLevel(level)
+	for _, log := range loggers {
+		log.SetLevel(level)
+	}
+}
+
 func NewLogWrapper() *logrusWrapper {
 	return &logrusWrapper{
 		log: NewLogger(),
@@ -0,0 +1,54 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// defaultPCIDomain is assumed for PCI addresses provided without domain
+const defaultPCIDomain = "0000"
+
+// pciAddressRegexp matches [domain:]bus:device.function address. Domain is not limited to 4 hex digits - devices
+// behind VMD (Volume Management Device) are exposed in domains starting at 10000. Bus and device may be given
+// without leading zero.
+var pciAddressRegexp = regexp.MustCompile(`^(?:([0-9a-fA-F]{4,8}):)?([0-9a-fA-F]{1,2}):([0-9a-fA-F]|[01][0-9a-fA-F])\.([0-7])$`)
+
+// NormalizePCIAddress returns PCI address in the form used by sysfs (lowercase, domain with at least 4 hex digits,
+// zero-padded bus and device), e.g. "AF:00.0" becomes "0000:af:00.0", "10000:AF:0.0" becomes "10000:af:00.0".
+// Surrounding whitespace is ignored.
+func NormalizePCIAddress(address string) (string, error) {
+	parts := pciAddressRegexp.FindStringSubmatch(strings.TrimSpace(address))
+	if parts == nil {
+		return "", fmt.Errorf("invalid PCI address '%s' - expected [domain:]bus:device.function, e.g. 0000:af:00.0", address)
+	}
+	domain := parts[1]
+	if domain == "" {
+		domain = defaultPCIDomain
+	}
+	// leading zeros above 4 digits are dropped, as sysfs does
+	if trimmed := strings.TrimLeft(domain, "0"); len(trimmed) > len(defaultPCIDomain) {
+		domain = trimmed
+	} else {
+		domain = strings.Repeat("0", len(defaultPCIDomain)-len(trimmed)) + trimmed
+	}
+	pad := func(part string) string { return strings.Repeat("0", 2-len(part)) + part }
+	return strings.ToLower(fmt.Sprintf("%s:%s:%s.%s", domain, pad(parts[2]), pad(parts[3]), parts[4])), nil
+}
+
+// CanonicalPCIAddress returns normalized PCI address; invalid address is returned unchanged, so that it is reported
+// as not found rather than misinterpreted
+func CanonicalPCIAddress(address string) string {
+	if normalized, err := NormalizePCIAddress(address); err == nil {
+		return normalized
+	}
+	return address
+}
+
+// SamePCIAddress tells whether both addresses refer to the same device, regardless of their form
+func SamePCIAddress(a, b string) bool {
+	return CanonicalPCIAddress(a) == CanonicalPCIAddress(b)
+}
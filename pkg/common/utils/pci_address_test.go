@@ -0,0 +1,46 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package utils
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NormalizePCIAddress", func() {
+	It("completes domain and lowercases the address", func() {
+		for address, expected := range map[string]string{
+			"0000:af:00.0":     "0000:af:00.0",
+			"af:00.0":          "0000:af:00.0",
+			"AF:1F.7":          "0000:af:1f.7",
+			"0001:3b:00.0":     "0001:3b:00.0",
+			"10000:af:00.0":    "10000:af:00.0",
+			"1000A:AF:00.0":    "1000a:af:00.0",
+			"00010000:af:00.0": "10000:af:00.0",
+			" 0000:AF:00.0 \n": "0000:af:00.0",
+			"\taf:00.0":        "0000:af:00.0",
+			"3b:0.1":           "0000:3b:00.1",
+			"0000:3:00.0":      "0000:03:00.0",
+			"10000:A:F.7":      "10000:0a:0f.7",
+		} {
+			normalized, err := NormalizePCIAddress(address)
+			Expect(err).ToNot(HaveOccurred(), address)
+			Expect(normalized).To(Equal(expected), address)
+		}
+	})
+
+	It("rejects malformed addresses", func() {
+		for _, address := range []string{"", "000:af:00.0", "123456789:af:00.0", "0000:af:20.0", "0000:af:00.8", "0000:af:00", "0000:af.00.0", "xyzw:af:00.0",
+			"0000:af:00.0:0", "0000::00.0", "0000:af:.0", "0000:af:000.0", "0000:1af:00.0", "0000:af:00.0 garbage", "0000 :af:00.0"} {
+			_, err := NormalizePCIAddress(address)
+			Expect(err).To(MatchError(ContainSubstring("invalid PCI address")), address)
+			Expect(CanonicalPCIAddress(address)).To(Equal(address))
+		}
+	})
+
+	It("compares addresses regardless of their form", func() {
+		Expect(SamePCIAddress("af:00.0", "0000:AF:00.0")).To(BeTrue())
+		Expect(SamePCIAddress("10000:af:00.0", "0000:af:00.0")).To(BeFalse())
+	})
+})
@@ -5,6 +5,8 @@ package utils
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"github.com/go-logr/logr"
@@ -12,14 +14,42 @@ import (
 	"os"
 	"path/filepath"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sort"
+	"strings"
+	"time"
 )
 
 type AcceleratorDiscoveryConfig struct {
-	VendorID  map[string]string
-	Class     string
-	SubClass  string
-	Devices   map[string]string
-	NodeLabel string
+	VendorID            map[string]string
+	Class               string
+	SubClass            string
+	Devices             map[string]string
+	NodeLabel           string
+	CompatibilityChecks *CompatibilityChecks `json:",omitempty"`
+	// KernelParams maps architecture (GOARCH, e.g. "amd64", "arm64") to kernel params required to configure the
+	// accelerators; built-in defaults are used for architectures which are not listed
+	KernelParams map[string][]string `json:",omitempty"`
+	// VFDrivers maps device ID to drivers its VFs can be bound to; devices which are not listed allow any driver
+	VFDrivers map[string][]VFDriverSupport `json:",omitempty"`
+	// PfBBConfigStartupThresholds maps device ID to duration (e.g. "30s") pf-bb-config is expected to configure the
+	// device within; longer startup marks health of the PF as degraded. Devices which are not listed are not checked.
+	PfBBConfigStartupThresholds map[string]string `json:",omitempty"`
+}
+
+// CompatibilityChecks describes environments in which accelerators must not be configured
+type CompatibilityChecks struct {
+	// MinKernelVersion is the lowest kernel release (e.g. "4.18.0") the accelerators can be configured on
+	MinKernelVersion string `json:",omitempty"`
+	// BlacklistedKernelRanges lists kernel releases with known issues; From is inclusive, To is exclusive
+	BlacklistedKernelRanges []KernelRange `json:",omitempty"`
+	// MinFirmwareVersion maps device ID to the lowest firmware revision (e.g. "0x01") that can be configured
+	MinFirmwareVersion map[string]string `json:",omitempty"`
+}
+
+type KernelRange struct {
+	From   string
+	To     string
+	Reason string `json:",omitempty"`
 }
 
 const (
@@ -30,6 +60,9 @@ const (
 	VFIO_PCI                        = "vfio-pci"
 	VFIO_PCI_UNDERSCORE             = "vfio_pci"
 	IGB_UIO                         = "igb_uio"
+	// CONFIGURATION_HOOK_PATH_PREFIX is env variable with the directory configuration hooks have to be located in
+	CONFIGURATION_HOOK_PATH_PREFIX         = SRIOV_PREFIX + "CONFIGURATION_HOOK_PATH_PREFIX"
+	DEFAULT_CONFIGURATION_HOOK_PATH_PREFIX = "/etc/sriov-fec/hooks/"
 )
 
 func LoadDiscoveryConfig(cfgPath string) (AcceleratorDiscoveryConfig, error) {
@@ -58,12 +91,91 @@ func LoadDiscoveryConfig(cfgPath string) (AcceleratorDiscoveryConfig, error) {
 		return cfg, fmt.Errorf("Unable to read config: %s", filepath.Clean(cfgPath))
 	}
 
-	if err = json.Unmarshal(cfgData, &cfg); err != nil {
+	return ParseDiscoveryConfig(cfgData)
+}
+
+// ParseDiscoveryConfig parses content of accelerators discovery config, e.g. taken directly from ConfigMap
+func ParseDiscoveryConfig(cfgData []byte) (AcceleratorDiscoveryConfig, error) {
+	var cfg AcceleratorDiscoveryConfig
+	if len(cfgData) > CONFIG_FILE_SIZE_LIMIT_IN_BYTES {
+		return cfg, fmt.Errorf("Config size %d, exceeds limit %d bytes", len(cfgData), CONFIG_FILE_SIZE_LIMIT_IN_BYTES)
+	}
+	if err := json.Unmarshal(cfgData, &cfg); err != nil {
 		return cfg, fmt.Errorf("Failed to unmarshal config: %v", err)
 	}
 	return cfg, nil
 }
 
+// PfBBConfigStartupThreshold returns threshold of pf-bb-config startup of the device; ok is false when the device
+// is not listed in PfBBConfigStartupThresholds
+func (cfg AcceleratorDiscoveryConfig) PfBBConfigStartupThreshold(deviceID string) (threshold time.Duration, ok bool, err error) {
+	value, ok := cfg.PfBBConfigStartupThresholds[deviceID]
+	if !ok {
+		return 0, false, nil
+	}
+	threshold, err = time.ParseDuration(value)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid pf-bb-config startup threshold of device %s - %v", deviceID, err)
+	}
+	if threshold <= 0 {
+		return 0, false, fmt.Errorf("pf-bb-config startup threshold of device %s has to be positive, got %s", deviceID, value)
+	}
+	return threshold, true, nil
+}
+
+// DiscoveryConfigHash returns SHA-256 of the discovery config; it is computed from the parsed config, so that formatting
+// of the file or ConfigMap it was loaded from does not change it
+func DiscoveryConfigHash(cfg AcceleratorDiscoveryConfig) string {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// maxDriftNodes is the number of nodes listed for each discovery config hash by DiscoveryConfigDrift
+const maxDriftNodes = 3
+
+// DiscoveryConfigDrift describes discovery config hashes reported by nodes (by node name) and the nodes reporting
+// them, when nodes report more than one hash; empty when they all report the same one. Nodes which report no hash,
+// e.g. the ones running older daemon, are ignored.
+func DiscoveryConfigDrift(hashes map[string]string) string {
+	nodesByHash := map[string][]string{}
+	for node, hash := range hashes {
+		if hash != "" {
+			nodesByHash[hash] = append(nodesByHash[hash], node)
+		}
+	}
+	if len(nodesByHash) < 2 {
+		return ""
+	}
+
+	distinct := make([]string, 0, len(nodesByHash))
+	for hash, nodes := range nodesByHash {
+		sort.Strings(nodes)
+		distinct = append(distinct, hash)
+	}
+	// the most common config goes first, it is likely the expected one
+	sort.Slice(distinct, func(i, j int) bool {
+		if len(nodesByHash[distinct[i]]) != len(nodesByHash[distinct[j]]) {
+			return len(nodesByHash[distinct[i]]) > len(nodesByHash[distinct[j]])
+		}
+		return distinct[i] < distinct[j]
+	})
+
+	groups := make([]string, 0, len(distinct))
+	for _, hash := range distinct {
+		nodes := nodesByHash[hash]
+		listed := strings.Join(nodes, ", ")
+		if len(nodes) > maxDriftNodes {
+			listed = fmt.Sprintf("%s and %d more", strings.Join(nodes[:maxDriftNodes], ", "), len(nodes)-maxDriftNodes)
+		}
+		groups = append(groups, fmt.Sprintf("%.12s (%s)", hash, listed))
+	}
+	return fmt.Sprintf("nodes report %d different discovery configs: %s", len(distinct), strings.Join(groups, "; "))
+}
+
 func SetOsEnvIfNotSet(key, value string, logger logr.Logger) error {
 	if osValue := os.Getenv(key); osValue != "" {
 		logger.Info("skipping ENV because it is already set", "key", key, "value", osValue)
@@ -84,3 +196,24 @@ func IsSingleNodeCluster(c client.Client) (bool, error) {
 	}
 	return false, nil
 }
+
+// ConfigurationHookPathPrefix returns the directory on the host configuration hooks are allowed to be located in
+func ConfigurationHookPathPrefix() string {
+	if prefix := os.Getenv(CONFIGURATION_HOOK_PATH_PREFIX); prefix != "" {
+		return prefix
+	}
+	return DEFAULT_CONFIGURATION_HOOK_PATH_PREFIX
+}
+
+// IsAllowedHookPath tells whether path is an absolute path located under prefix directory. Path has to be clean,
+// so that it cannot escape the directory with ".." elements.
+func IsAllowedHookPath(path, prefix string) bool {
+	if !filepath.IsAbs(path) || filepath.Clean(path) != path {
+		return false
+	}
+	dir := filepath.Clean(prefix)
+	if dir != "/" {
+		dir += "/"
+	}
+	return strings.HasPrefix(path, dir) && path != dir
+}
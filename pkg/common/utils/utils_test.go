@@ -6,6 +6,7 @@ import (
 	"github.com/go-logr/logr"
 	"os"
 	"testing"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -68,3 +69,57 @@ var _ = Describe("Utils", func() {
 		})
 	})
 })
+
+var _ = Describe("Utils", func() {
+	var _ = Describe("IsAllowedHookPath", func() {
+		var _ = It("should allow only clean absolute paths located under the prefix", func() {
+			prefix := DEFAULT_CONFIGURATION_HOOK_PATH_PREFIX
+			Expect(IsAllowedHookPath("/etc/sriov-fec/hooks/quiesce.sh", prefix)).To(BeTrue())
+			Expect(IsAllowedHookPath("/etc/sriov-fec/hooks/du/resume", "/etc/sriov-fec/hooks")).To(BeTrue())
+
+			Expect(IsAllowedHookPath("/etc/sriov-fec/hooks/", prefix)).To(BeFalse())
+			Expect(IsAllowedHookPath("/etc/sriov-fec/hooks-other/run", prefix)).To(BeFalse())
+			Expect(IsAllowedHookPath("/etc/sriov-fec/hooks/../../../bin/sh", prefix)).To(BeFalse())
+			Expect(IsAllowedHookPath("etc/sriov-fec/hooks/quiesce.sh", prefix)).To(BeFalse())
+			Expect(IsAllowedHookPath("/usr/bin/reboot", prefix)).To(BeFalse())
+		})
+	})
+})
+
+var _ = Describe("Utils", func() {
+	var _ = Describe("PfBBConfigStartupThreshold", func() {
+		cfg := AcceleratorDiscoveryConfig{PfBBConfigStartupThresholds: map[string]string{
+			"0d5c": "30s",
+			"57c0": "1m",
+			"0d8f": "fast",
+			"5052": "0s",
+		}}
+
+		var _ = It("should return threshold of listed device", func() {
+			threshold, ok, err := cfg.PfBBConfigStartupThreshold("0d5c")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(threshold).To(Equal(30 * time.Second))
+
+			threshold, _, _ = cfg.PfBBConfigStartupThreshold("57c0")
+			Expect(threshold).To(Equal(time.Minute))
+		})
+
+		var _ = It("should not check devices which are not listed", func() {
+			_, ok, err := cfg.PfBBConfigStartupThreshold("0b32")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeFalse())
+
+			_, ok, err = AcceleratorDiscoveryConfig{}.PfBBConfigStartupThreshold("0d5c")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeFalse())
+		})
+
+		var _ = It("should fail on invalid threshold", func() {
+			_, _, err := cfg.PfBBConfigStartupThreshold("0d8f")
+			Expect(err).To(MatchError(ContainSubstring("invalid pf-bb-config startup threshold of device 0d8f")))
+			_, _, err = cfg.PfBBConfigStartupThreshold("5052")
+			Expect(err).To(MatchError("pf-bb-config startup threshold of device 5052 has to be positive, got 0s"))
+		})
+	})
+})
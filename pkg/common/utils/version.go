@@ -0,0 +1,85 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// OperatorVersion is version of the operator, injected from VERSION of Makefile at build time with
+// -ldflags "-X github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils.OperatorVersion=<version>"; builds without
+// it report 0.0.0-dev. Cluster controllers write it into node configs, so that daemons of older version can refuse to act
+// on spec they could misinterpret, e.g. during rollback.
+var OperatorVersion = "0.0.0-dev"
+
+// IsNewerOperatorVersion tells whether operator version written into a resource (e.g. "2.9.0" or "v2.9.0") is newer
+// than own one. Empty written version, i.e. resource written by operator which does not record its version, is not.
+func IsNewerOperatorVersion(written, own string) (bool, error) {
+	if written == "" {
+		return false, nil
+	}
+	cmp, err := CompareVersions(strings.TrimPrefix(written, "v"), strings.TrimPrefix(own, "v"))
+	if err != nil {
+		return false, err
+	}
+	return cmp > 0, nil
+}
+
+// CompareVersions compares version strings like kernel releases ("5.14.0-284.el9.x86_64") or
+// firmware revisions ("0x01"). Only the leading numeric components are compared; the first
+// non-numeric component and everything after it is ignored. Missing components are treated as 0.
+// Returns -1 when a < b, 0 when a == b and 1 when a > b.
+func CompareVersions(a, b string) (int, error) {
+	va, err := parseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := parseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := 0; i < len(va) || i < len(vb); i++ {
+		var ca, cb uint64
+		if i < len(va) {
+			ca = va[i]
+		}
+		if i < len(vb) {
+			cb = vb[i]
+		}
+		switch {
+		case ca < cb:
+			return -1, nil
+		case ca > cb:
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func parseVersion(v string) ([]uint64, error) {
+	fields := strings.FieldsFunc(strings.TrimSpace(v), func(r rune) bool {
+		return r == '.' || r == '-' || r == '+' || r == '_'
+	})
+
+	var components []uint64
+	for _, f := range fields {
+		base := 10
+		if strings.HasPrefix(strings.ToLower(f), "0x") {
+			f, base = f[2:], 16
+		}
+		c, err := strconv.ParseUint(f, base, 64)
+		if err != nil {
+			break
+		}
+		components = append(components, c)
+	}
+
+	if len(components) == 0 {
+		return nil, fmt.Errorf("'%s' is not a valid version", v)
+	}
+	return components, nil
+}
@@ -0,0 +1,46 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package utils
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CompareVersions", func() {
+	var _ = It("will compare kernel releases", func() {
+		Expect(CompareVersions("5.14.0-284.el9.x86_64", "5.14.0-284.el9.x86_64")).To(Equal(0))
+		Expect(CompareVersions("5.14.0-284.el9.x86_64", "5.14.1")).To(Equal(-1))
+		Expect(CompareVersions("6.1", "5.14.0-284")).To(Equal(1))
+		Expect(CompareVersions("5.14", "5.14.0")).To(Equal(0))
+	})
+	var _ = It("will compare hexadecimal firmware revisions", func() {
+		Expect(CompareVersions("0x01", "0x0a")).To(Equal(-1))
+		Expect(CompareVersions("0x10", "0x0F")).To(Equal(1))
+	})
+	var _ = It("will fail for non numeric versions", func() {
+		_, err := CompareVersions("abc", "1.0")
+		Expect(err).To(HaveOccurred())
+		_, err = CompareVersions("1.0", "")
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("IsNewerOperatorVersion", func() {
+	var _ = It("will tell whether written version is newer than own one", func() {
+		Expect(IsNewerOperatorVersion("2.9.0", "2.8.0")).To(BeTrue())
+		Expect(IsNewerOperatorVersion("v2.8.1", "2.8.0")).To(BeTrue())
+		Expect(IsNewerOperatorVersion("3.0.0-rc1", "v2.8.0")).To(BeTrue())
+		Expect(IsNewerOperatorVersion("2.8.0", "2.8.0")).To(BeFalse())
+		Expect(IsNewerOperatorVersion("2.8", "2.8.0")).To(BeFalse())
+		Expect(IsNewerOperatorVersion("2.7.3", "2.8.0")).To(BeFalse())
+	})
+	var _ = It("will not consider missing version newer", func() {
+		Expect(IsNewerOperatorVersion("", "2.8.0")).To(BeFalse())
+	})
+	var _ = It("will fail for invalid version", func() {
+		_, err := IsNewerOperatorVersion("latest", "2.8.0")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,71 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// VFDriverSupport allows VFs of a device to be bound to the driver on kernel releases of given range
+type VFDriverSupport struct {
+	Driver string
+	// MinKernelVersion is the lowest kernel release the driver can be used on; any release when empty
+	MinKernelVersion string `json:",omitempty"`
+	// MaxKernelVersion is the kernel release from which on the driver cannot be used (exclusive); any release when empty
+	MaxKernelVersion string `json:",omitempty"`
+}
+
+// SameDriver compares driver names regardless of dash and underscore, as modules are reported with underscores
+func SameDriver(a, b string) bool {
+	return strings.ReplaceAll(a, "_", "-") == strings.ReplaceAll(b, "_", "-")
+}
+
+// CheckVFDriver checks that VFs of device can be bound to driver on given kernel release, according to VF drivers
+// allowed by the discovery config. Devices which are not listed there allow any driver. Kernel bounds are not checked
+// when kernel is empty, e.g. when release of the node is not known yet.
+func CheckVFDriver(allowed map[string][]VFDriverSupport, deviceID, driver, kernel string) error {
+	supports, ok := allowed[deviceID]
+	if !ok {
+		return nil
+	}
+
+	var drivers []string
+	var violation string
+	for _, support := range supports {
+		drivers = append(drivers, support.Driver)
+		if !SameDriver(support.Driver, driver) {
+			continue
+		}
+		if kernel == "" {
+			return nil
+		}
+		if support.MinKernelVersion != "" {
+			cmp, err := CompareVersions(kernel, support.MinKernelVersion)
+			if err != nil {
+				return err
+			}
+			if cmp < 0 {
+				violation = "on kernel < " + support.MinKernelVersion
+				continue
+			}
+		}
+		if support.MaxKernelVersion != "" {
+			cmp, err := CompareVersions(kernel, support.MaxKernelVersion)
+			if err != nil {
+				return err
+			}
+			if cmp >= 0 {
+				violation = "on kernel >= " + support.MaxKernelVersion
+				continue
+			}
+		}
+		return nil
+	}
+
+	if violation == "" {
+		return fmt.Errorf("vfDriver %s not supported for device %s, supported: %s", driver, deviceID, strings.Join(drivers, ", "))
+	}
+	return fmt.Errorf("vfDriver %s not supported for device %s %s", driver, deviceID, violation)
+}
@@ -0,0 +1,49 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package utils
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CheckVFDriver", func() {
+	allowed := map[string][]VFDriverSupport{
+		"0d8f": {{Driver: VFIO_PCI}, {Driver: IGB_UIO, MaxKernelVersion: "5.15"}},
+		"0d5c": {{Driver: VFIO_PCI}, {Driver: IGB_UIO}},
+		"57c0": {{Driver: VFIO_PCI, MinKernelVersion: "5.14"}},
+	}
+
+	It("will allow any driver for devices which are not listed", func() {
+		Expect(CheckVFDriver(allowed, "0b32", "foo", "6.1")).To(Succeed())
+		Expect(CheckVFDriver(nil, "0d8f", IGB_UIO, "6.1")).To(Succeed())
+	})
+
+	It("will allow only listed drivers", func() {
+		Expect(CheckVFDriver(allowed, "0d5c", IGB_UIO, "6.1")).To(Succeed())
+		Expect(CheckVFDriver(allowed, "0d5c", VFIO_PCI_UNDERSCORE, "6.1")).To(Succeed())
+		Expect(CheckVFDriver(allowed, "0d5c", PCI_PF_STUB_DASH, "6.1")).
+			To(MatchError("vfDriver pci-pf-stub not supported for device 0d5c, supported: vfio-pci, igb_uio"))
+	})
+
+	It("will respect kernel bounds of the driver", func() {
+		Expect(CheckVFDriver(allowed, "0d8f", IGB_UIO, "5.14.0-284.el9.x86_64")).To(Succeed())
+		Expect(CheckVFDriver(allowed, "0d8f", IGB_UIO, "5.15.0-1023-realtime")).
+			To(MatchError("vfDriver igb_uio not supported for device 0d8f on kernel >= 5.15"))
+		Expect(CheckVFDriver(allowed, "0d8f", VFIO_PCI, "5.15.0")).To(Succeed())
+		Expect(CheckVFDriver(allowed, "57c0", VFIO_PCI, "4.18.0-372")).
+			To(MatchError("vfDriver vfio-pci not supported for device 57c0 on kernel < 5.14"))
+	})
+
+	It("will not check kernel bounds when kernel is not known", func() {
+		Expect(CheckVFDriver(allowed, "0d8f", IGB_UIO, "")).To(Succeed())
+		Expect(CheckVFDriver(allowed, "0d8f", "foo", "")).To(HaveOccurred())
+	})
+
+	It("will fail for malformed kernel release", func() {
+		err := CheckVFDriver(allowed, "0d8f", IGB_UIO, "unknown")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).ToNot(HavePrefix("vfDriver"))
+	})
+})
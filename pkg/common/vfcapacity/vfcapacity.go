@@ -0,0 +1,237 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package vfcapacity
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// usageUnknown is reported by the daemon as user of every VF of the node when pods using VFs could not be determined
+const usageUnknown = "unknown"
+
+// VF is a VF reported in inventory of node config
+type VF struct {
+	// Resource is name of device plugin resource exposing the VF; empty when it is not exposed as any resource
+	Resource string
+	// UsedBy is pod the VF is allocated to, reported by daemons with VFPodUsage feature gate
+	UsedBy string
+}
+
+// Node is data of single accelerated node the capacity is aggregated from
+type Node struct {
+	Name string
+	// VFs reported in inventory of node's node config; none when there is no node config or inventory yet
+	VFs []VF
+	// Allocatable of the node; VFs are counted from it when inventory reports none
+	Allocatable corev1.ResourceList
+}
+
+// Resource is number of VFs exposed as single device plugin resource and of the ones allocated to pods
+type Resource struct {
+	Name      string
+	Total     int
+	Allocated int
+}
+
+// Capacity is number of VFs of all accelerated nodes and of VFs allocated to pods
+type Capacity struct {
+	Total     int
+	Allocated int
+	// NodesWithoutInventory is the number of nodes which VFs are counted from node allocatable
+	NodesWithoutInventory int
+	// NodesWithoutUsage is the number of nodes which VFs are counted in totals, but not in allocated
+	NodesWithoutUsage int
+	// Resources are sorted by name
+	Resources []Resource
+}
+
+// reportsUsage tells whether VFs of the node report pods they are allocated to. Usage is reported by daemons with
+// VFPodUsage feature gate only, so when none of the VFs is in use, nodes with the gate are indistinguishable from
+// nodes without it and are considered not reporting usage.
+func reportsUsage(vfs []VF) bool {
+	used := false
+	for _, vf := range vfs {
+		if vf.UsedBy == usageUnknown {
+			return false
+		}
+		used = used || vf.UsedBy != ""
+	}
+	return used
+}
+
+// Aggregate sums VFs of the nodes. VFs are counted from node config inventory; node allocatable is used for nodes
+// which inventory reports no VFs, only for resources VFs of other nodes are exposed as. Allocated VFs are counted
+// only for nodes which report usage of their VFs.
+func Aggregate(nodes []Node) Capacity {
+	known := map[string]bool{}
+	for _, node := range nodes {
+		for _, vf := range node.VFs {
+			if vf.Resource != "" {
+				known[vf.Resource] = true
+			}
+		}
+	}
+
+	capacity := Capacity{}
+	resources := map[string]*Resource{}
+	add := func(name string, total, allocated int) {
+		capacity.Total += total
+		capacity.Allocated += allocated
+		if name == "" {
+			return
+		}
+		if _, ok := resources[name]; !ok {
+			resources[name] = &Resource{Name: name}
+		}
+		resources[name].Total += total
+		resources[name].Allocated += allocated
+	}
+
+	for _, node := range nodes {
+		if len(node.VFs) == 0 {
+			counted := false
+			for name := range known {
+				quantity, ok := node.Allocatable[corev1.ResourceName(name)]
+				if !ok || quantity.Value() <= 0 {
+					continue
+				}
+				add(name, int(quantity.Value()), 0)
+				counted = true
+			}
+			if counted {
+				capacity.NodesWithoutInventory++
+				capacity.NodesWithoutUsage++
+			}
+			continue
+		}
+
+		usage := reportsUsage(node.VFs)
+		if !usage {
+			capacity.NodesWithoutUsage++
+		}
+		for _, vf := range node.VFs {
+			allocated := 0
+			if usage && vf.UsedBy != "" {
+				allocated = 1
+			}
+			add(vf.Resource, 1, allocated)
+		}
+	}
+
+	for _, resource := range resources {
+		capacity.Resources = append(capacity.Resources, *resource)
+	}
+	sort.Slice(capacity.Resources, func(i, j int) bool {
+		return capacity.Resources[i].Name < capacity.Resources[j].Name
+	})
+	return capacity
+}
+
+var (
+	totalVFs = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cluster_vfs_total",
+		Help: `number of VFs of all accelerated nodes. 'kind' - represents node config kind. Available values: 'sriovfec', 'sriovvrb'`,
+	}, []string{"kind"})
+
+	allocatedVFs = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cluster_vfs_allocated",
+		Help: `number of VFs allocated to pods, on nodes which report usage of their VFs (VFPodUsage feature gate)`,
+	}, []string{"kind"})
+
+	nodesWithoutUsage = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cluster_vfs_nodes_without_usage",
+		Help: `number of nodes which VFs are not counted in cluster_vfs_allocated, as they do not report usage of their VFs`,
+	}, []string{"kind"})
+
+	resourceTotalVFs = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cluster_resource_vfs_total",
+		Help: `number of VFs exposed as device plugin resource on all accelerated nodes. 'resource' - name of the resource`,
+	}, []string{"kind", "resource"})
+
+	resourceAllocatedVFs = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cluster_resource_vfs_allocated",
+		Help: `number of VFs exposed as device plugin resource, which are allocated to pods. 'resource' - name of the resource`,
+	}, []string{"kind", "resource"})
+
+	// recordLock makes replacement of kind's metrics atomic
+	recordLock sync.Mutex
+)
+
+func init() {
+	metrics.Registry.MustRegister(totalVFs, allocatedVFs, nodesWithoutUsage, resourceTotalVFs, resourceAllocatedVFs)
+}
+
+// Record replaces metrics of given node config kind with the capacity
+func Record(kind string, capacity Capacity) {
+	recordLock.Lock()
+	defer recordLock.Unlock()
+
+	totalVFs.WithLabelValues(kind).Set(float64(capacity.Total))
+	allocatedVFs.WithLabelValues(kind).Set(float64(capacity.Allocated))
+	nodesWithoutUsage.WithLabelValues(kind).Set(float64(capacity.NodesWithoutUsage))
+
+	resourceTotalVFs.DeletePartialMatch(prometheus.Labels{"kind": kind})
+	resourceAllocatedVFs.DeletePartialMatch(prometheus.Labels{"kind": kind})
+	for _, resource := range capacity.Resources {
+		resourceTotalVFs.WithLabelValues(kind, resource.Name).Set(float64(resource.Total))
+		resourceAllocatedVFs.WithLabelValues(kind, resource.Name).Set(float64(resource.Allocated))
+	}
+}
+
+const (
+	// DefaultDebounce is quiet period after the last request the capacity is recomputed after
+	DefaultDebounce = 10 * time.Second
+	// maxDelayFactor bounds delay of the recompute, as requests may keep coming more often than the debounce
+	maxDelayFactor = 6
+)
+
+// Debouncer coalesces requests to recompute the capacity, e.g. from reconciles of all cluster configs, into a single
+// recompute. It runs once no request came for the debounce, but at latest 6 debounces after the first request.
+type Debouncer struct {
+	mu      sync.Mutex
+	timer   *time.Timer
+	pending time.Time
+}
+
+// Trigger (re)starts the debounce; recompute is run by the last trigger's call when it expires
+func (d *Debouncer) Trigger(debounce time.Duration, recompute func()) {
+	if debounce <= 0 {
+		debounce = DefaultDebounce
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	if d.timer != nil {
+		d.timer.Stop()
+	} else {
+		d.pending = now
+	}
+
+	delay := debounce
+	if deadline := d.pending.Add(maxDelayFactor * debounce); now.Add(delay).After(deadline) {
+		delay = deadline.Sub(now)
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		d.mu.Lock()
+		// timer which already fired cannot be stopped, the one which replaced it runs the recompute
+		superseded := d.timer != timer
+		if !superseded {
+			d.timer = nil
+		}
+		d.mu.Unlock()
+		if !superseded {
+			recompute()
+		}
+	})
+	d.timer = timer
+}
@@ -0,0 +1,150 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package vfcapacity
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestVFCapacity(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "VFCapacity suite")
+}
+
+const (
+	acc100 = "intel.com/intel_fec_acc100"
+	acc200 = "intel.com/intel_fec_acc200"
+)
+
+func vfs(resource string, usedBy ...string) []VF {
+	result := make([]VF, 0, len(usedBy))
+	for _, user := range usedBy {
+		result = append(result, VF{Resource: resource, UsedBy: user})
+	}
+	return result
+}
+
+var _ = Describe("Aggregate", func() {
+	It("should sum VFs and allocated VFs by resource", func() {
+		capacity := Aggregate([]Node{
+			{Name: "node-0", VFs: vfs(acc100, "ns/pod-a", "", "", "ns/pod-b")},
+			{Name: "node-1", VFs: append(vfs(acc100, "", ""), vfs(acc200, "ns/pod-c")...)},
+		})
+		Expect(capacity).To(Equal(Capacity{
+			Total:     7,
+			Allocated: 3,
+			// node-1 has an allocated VF, so it reports usage of all its VFs
+			NodesWithoutUsage: 0,
+			Resources: []Resource{
+				{Name: acc100, Total: 6, Allocated: 2},
+				{Name: acc200, Total: 1, Allocated: 1},
+			},
+		}))
+	})
+
+	It("should not count allocated VFs of nodes which do not report usage", func() {
+		capacity := Aggregate([]Node{
+			{Name: "node-0", VFs: vfs(acc100, "ns/pod-a", "")},
+			{Name: "usage-not-reported", VFs: vfs(acc100, "", "")},
+			{Name: "usage-failed", VFs: vfs(acc100, "unknown", "unknown")},
+		})
+		Expect(capacity).To(Equal(Capacity{
+			Total:             6,
+			Allocated:         1,
+			NodesWithoutUsage: 2,
+			Resources:         []Resource{{Name: acc100, Total: 6, Allocated: 1}},
+		}))
+	})
+
+	It("should count VFs of nodes without inventory from node allocatable of known resources", func() {
+		capacity := Aggregate([]Node{
+			{Name: "node-0", VFs: vfs(acc100, "ns/pod-a")},
+			{Name: "no-inventory", Allocatable: corev1.ResourceList{
+				acc100:                resource.MustParse("4"),
+				"intel.com/intel_vrb": resource.MustParse("2"),
+				corev1.ResourceCPU:    resource.MustParse("8"),
+			}},
+			{Name: "nothing-reported"},
+		})
+		Expect(capacity).To(Equal(Capacity{
+			Total:                 5,
+			Allocated:             1,
+			NodesWithoutInventory: 1,
+			NodesWithoutUsage:     1,
+			Resources:             []Resource{{Name: acc100, Total: 5, Allocated: 1}},
+		}))
+	})
+
+	It("should count VFs not exposed as any resource in totals only", func() {
+		capacity := Aggregate([]Node{{Name: "node-0", VFs: append(vfs("", "ns/pod-a", ""), vfs(acc200, "")...)}})
+		Expect(capacity.Total).To(Equal(3))
+		Expect(capacity.Allocated).To(Equal(1))
+		Expect(capacity.Resources).To(Equal([]Resource{{Name: acc200, Total: 1}}))
+	})
+
+	It("should report nothing for no nodes", func() {
+		Expect(Aggregate(nil)).To(Equal(Capacity{}))
+	})
+})
+
+var _ = Describe("Record", func() {
+	BeforeEach(func() {
+		for _, gauge := range []interface{ Reset() }{totalVFs, allocatedVFs, nodesWithoutUsage, resourceTotalVFs, resourceAllocatedVFs} {
+			gauge.Reset()
+		}
+	})
+
+	It("should replace resources of the kind", func() {
+		Record("sriovfec", Capacity{Total: 3, Allocated: 1, Resources: []Resource{{Name: acc100, Total: 2, Allocated: 1}, {Name: acc200, Total: 1}}})
+		Record("sriovvrb", Capacity{Total: 1, Resources: []Resource{{Name: "intel.com/intel_vrb_vrb2", Total: 1}}})
+		Record("sriovfec", Capacity{Total: 2, Allocated: 2, NodesWithoutUsage: 1, Resources: []Resource{{Name: acc100, Total: 2, Allocated: 2}}})
+
+		Expect(testutil.ToFloat64(totalVFs.WithLabelValues("sriovfec"))).To(Equal(float64(2)))
+		Expect(testutil.ToFloat64(allocatedVFs.WithLabelValues("sriovfec"))).To(Equal(float64(2)))
+		Expect(testutil.ToFloat64(nodesWithoutUsage.WithLabelValues("sriovfec"))).To(Equal(float64(1)))
+		Expect(testutil.ToFloat64(resourceAllocatedVFs.WithLabelValues("sriovfec", acc100))).To(Equal(float64(2)))
+		Expect(testutil.CollectAndCount(resourceTotalVFs)).To(Equal(2), "acc200 is removed, sriovvrb resource is kept")
+	})
+})
+
+var _ = Describe("Debouncer", func() {
+	It("should recompute once after triggers stop", func() {
+		var recomputes int32
+		var recomputedAt atomic.Value
+		recompute := func() {
+			atomic.AddInt32(&recomputes, 1)
+			recomputedAt.Store(time.Now())
+		}
+
+		d := &Debouncer{}
+		firstTrigger := time.Now()
+		for i := 0; i < 5; i++ {
+			d.Trigger(50*time.Millisecond, recompute)
+		}
+		Eventually(func() int32 { return atomic.LoadInt32(&recomputes) }, time.Second, 10*time.Millisecond).Should(Equal(int32(1)))
+		Expect(recomputedAt.Load()).To(BeTemporally(">=", firstTrigger.Add(50*time.Millisecond)))
+		Consistently(func() int32 { return atomic.LoadInt32(&recomputes) }, 150*time.Millisecond, 10*time.Millisecond).Should(Equal(int32(1)))
+	})
+
+	It("should not delay recompute indefinitely by frequent triggers", func() {
+		var recomputes int32
+		recompute := func() { atomic.AddInt32(&recomputes, 1) }
+
+		d := &Debouncer{}
+		deadline := time.Now().Add(maxDelayFactor*20*time.Millisecond + 100*time.Millisecond)
+		for time.Now().Before(deadline) {
+			d.Trigger(20*time.Millisecond, recompute)
+			time.Sleep(5 * time.Millisecond)
+		}
+		Expect(atomic.LoadInt32(&recomputes)).To(BeNumerically(">=", 1))
+	})
+})
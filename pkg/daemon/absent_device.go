@@ -0,0 +1,34 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// isDeviceAbsent tells whether err of a step on the device is caused by the device being gone, e.g. failed card which
+// dropped off the PCI bus: the device is absent from sysfs, or the step failed with ENODEV. Errors of most steps are
+// formatted into their messages, so ENODEV is recognized by its text as well.
+func isDeviceAbsent(pciAddress string, err error) bool {
+	if errors.Is(err, syscall.ENODEV) || strings.Contains(err.Error(), syscall.ENODEV.Error()) {
+		return true
+	}
+	_, statErr := os.Stat(filepath.Join(sysBusPciDevices, pciAddress))
+	return os.IsNotExist(statErr)
+}
+
+// tolerateAbsentDevice turns failure of a teardown step of PF removed from the spec into success when the device is
+// absent (see isDeviceAbsent), unless disabled with TolerateAbsentDevices gate. There is nothing left to tear down
+// then, while failing would block teardown of the other PFs and configuration of the node.
+func (n *NodeConfigurator) tolerateAbsentDevice(pciAddress string, err error) error {
+	if err == nil || !n.featureGates.Enabled(TolerateAbsentDevices) || !isDeviceAbsent(pciAddress, err) {
+		return err
+	}
+	n.Log.WithError(err).WithField("pci", pciAddress).Warn("device is absent - considering its teardown step done")
+	return nil
+}
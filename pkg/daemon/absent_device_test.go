@@ -0,0 +1,132 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/faultinjection"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("Teardown of absent device", func() {
+	const pfPCIAddress = "0000:f7:00.0"
+
+	var (
+		nc                       *NodeConfigurator
+		root                     string
+		originalSysBusPciDevices = sysBusPciDevices
+		originalProcPath         = procPath
+		originalOwnProcPath      = ownProcPath
+		originalGetVFconfigured  = getVFconfigured
+		originalGetVFList        = getVFList
+		// inventory getter is restored as it was, other specs may rely on it
+		originalGetSriovInventory func(_ context.Context, log *logrus.Logger) (*sriovv2.NodeInventory, error)
+	)
+
+	removeDevice := func() {
+		Expect(os.RemoveAll(filepath.Join(sysBusPciDevices, pfPCIAddress))).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+		root, err = os.MkdirTemp(testTmpFolder, "absent-device")
+		Expect(err).ToNot(HaveOccurred())
+		sysBusPciDevices = filepath.Join(root, "devices")
+		procPath = filepath.Join(root, "proc")
+		ownProcPath = procPath
+		Expect(os.MkdirAll(procPath, 0755)).To(Succeed())
+		Expect(createFiles(filepath.Join(sysBusPciDevices, pfPCIAddress), vfNumFileDefault, "reset")).To(Succeed())
+
+		getVFconfigured = func(string) int { return 2 }
+		getVFList = func(string) ([]string, error) { return nil, nil }
+		originalGetSriovInventory = getSriovInventory
+		// PF is no longer requested by the spec, so its VFs are torn down
+		getSriovInventory = func(context.Context, *logrus.Logger) (*sriovv2.NodeInventory, error) {
+			return &sriovv2.NodeInventory{SriovAccelerators: []sriovv2.SriovAccelerator{{
+				VendorID: "8086", DeviceID: "0d5c", PCIAddress: pfPCIAddress, PFDriver: utils.PCI_PF_STUB_DASH, MaxVFs: 16,
+				VFs: []sriovv2.VF{{PCIAddress: "0000:f7:00.1"}, {PCIAddress: "0000:f7:00.2"}},
+			}}}, nil
+		}
+
+		nc = NewNodeConfigurator(utils.NewLogger(), &pfBBConfigController{log: utils.NewLogger()},
+			fake.NewClientBuilder().Build(), types.NamespacedName{Namespace: "default", Name: "worker"}, nil, nil)
+	})
+
+	AfterEach(func() {
+		faultinjection.Reset()
+		sysBusPciDevices = originalSysBusPciDevices
+		procPath = originalProcPath
+		ownProcPath = originalOwnProcPath
+		getVFconfigured = originalGetVFconfigured
+		getVFList = originalGetVFList
+		getSriovInventory = originalGetSriovInventory
+		Expect(os.RemoveAll(root)).To(Succeed())
+	})
+
+	It("should succeed when the device is absent from sysfs", func() {
+		removeDevice()
+		getVFList = func(pciAddress string) ([]string, error) {
+			return nil, fmt.Errorf("failed to read VFs of %s - no such file or directory", pciAddress)
+		}
+
+		Expect(nc.ApplySpec(context.TODO(), sriovv2.SriovFecNodeConfigSpec{})).To(Succeed())
+	})
+
+	It("should succeed when the device disappears halfway through teardown", func() {
+		// VFs are unbound, then the card drops off the bus before VFs are removed
+		getVFList = func(string) ([]string, error) {
+			removeDevice()
+			return nil, nil
+		}
+
+		Expect(nc.ApplySpec(context.TODO(), sriovv2.SriovFecNodeConfigSpec{})).To(Succeed())
+	})
+
+	It("should tolerate ENODEV of individual step", func() {
+		Expect(faultinjection.Load(writeFaults(root, fmt.Sprintf(`{"faults": [{"operation": "sysfsWrite", "target": %q, "errno": "ENODEV"}]}`, vfNumFileDefault)))).To(Succeed())
+
+		Expect(nc.ApplySpec(context.TODO(), sriovv2.SriovFecNodeConfigSpec{})).To(Succeed())
+		content, err := os.ReadFile(filepath.Join(sysBusPciDevices, pfPCIAddress, "reset"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(content)).To(Equal("1"), "following steps are run")
+	})
+
+	It("should fail when the device is present", func() {
+		Expect(faultinjection.Load(writeFaults(root, fmt.Sprintf(`{"faults": [{"operation": "sysfsWrite", "target": %q, "errno": "EBUSY"}]}`, vfNumFileDefault)))).To(Succeed())
+
+		Expect(nc.ApplySpec(context.TODO(), sriovv2.SriovFecNodeConfigSpec{})).To(MatchError(ContainSubstring("device or resource busy")))
+	})
+
+	It("should fail when disabled with feature gate", func() {
+		nc.featureGates = FeatureGates{TolerateAbsentDevices: false}
+		removeDevice()
+
+		Expect(nc.ApplySpec(context.TODO(), sriovv2.SriovFecNodeConfigSpec{})).ToNot(Succeed())
+	})
+
+	It("should not tolerate absent device when PF is configured", func() {
+		removeDevice()
+
+		Expect(nc.cleanAcceleratorConfig(context.TODO(), sriovv2.SriovAccelerator{PCIAddress: pfPCIAddress, PFDriver: utils.PCI_PF_STUB_DASH,
+			VFs: []sriovv2.VF{{PCIAddress: "0000:f7:00.1"}}}, false)).ToNot(Succeed())
+	})
+})
+
+// writeFaults writes fault injection file into dir and returns its path
+func writeFaults(dir, faults string) string {
+	path := filepath.Join(dir, "faults.json")
+	Expect(os.WriteFile(path, []byte(faults), 0644)).To(Succeed())
+	return path
+}
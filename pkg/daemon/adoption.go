@@ -0,0 +1,304 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+
+	"gopkg.in/ini.v1"
+
+	fec "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+// adoptedPF is PF whose configuration found on the node (e.g. set up manually before the operator was installed)
+// matches the spec, so that it is adopted instead of being torn down and configured again. bbDevConfig is content of
+// cfg file pf_bb_config serving the PF was started with; nil when the spec configures no queues.
+type adoptedPF struct {
+	pciAddress  string
+	bbDevConfig []byte
+}
+
+type adoptedPFsKey struct{}
+
+// withAdoptedPFs returns context with which configuration leaves adopted PFs untouched
+func withAdoptedPFs(ctx context.Context, pfs []adoptedPF) context.Context {
+	return context.WithValue(ctx, adoptedPFsKey{}, pfs)
+}
+
+// isAdoptedPF tells whether configuration of the PF found on the node was adopted, so it must not be applied again
+func isAdoptedPF(ctx context.Context, pciAddress string) bool {
+	pfs, _ := ctx.Value(adoptedPFsKey{}).([]adoptedPF)
+	for _, pf := range pfs {
+		if pf.pciAddress == pciAddress {
+			return true
+		}
+	}
+	return false
+}
+
+func adoptedPCIAddresses(pfs []adoptedPF) []string {
+	var pciAddresses []string
+	for _, pf := range pfs {
+		pciAddresses = append(pciAddresses, pf.pciAddress)
+	}
+	return pciAddresses
+}
+
+// recordAdoptedBBDevConfigs stores cfg files of adopted PFs in their history, as if the daemon started pf_bb_config
+// with them. Failure is only logged, as history must not fail the configuration.
+func (r *NodeConfigReconciler) recordAdoptedBBDevConfigs(pfs []adoptedPF) {
+	for _, pf := range pfs {
+		if pf.bbDevConfig == nil {
+			continue
+		}
+		if err := storeBBDevConfig(pf.pciAddress, pf.bbDevConfig); err != nil {
+			r.log.WithError(err).WithField("pci", pf.pciAddress).Error("failed to store adopted bbdev config file in history")
+		}
+	}
+}
+
+// fecAdoptablePFs returns PFs of the spec whose configuration found on the node can be adopted; nil when adoption is
+// not enabled by the spec
+func (r *NodeConfigReconciler) fecAdoptablePFs(ctx context.Context, spec fec.SriovFecNodeConfigSpec, inventory *fec.NodeInventory) []adoptedPF {
+	if !spec.AdoptExistingConfig {
+		return nil
+	}
+
+	stopped := r.pfBBConfigsStoppedForReboot(ctx)
+	var adopted []adoptedPF
+	for _, pf := range spec.PhysicalFunctions {
+		if stopped[pf.PCIAddress] {
+			r.log.WithField("pci", pf.PCIAddress).Info("pf-bb-config was stopped before the reboot - PF will be reconfigured")
+			continue
+		}
+		for _, acc := range inventory.SriovAccelerators {
+			if acc.PCIAddress != pf.PCIAddress {
+				continue
+			}
+			var vfDrivers []string
+			for _, vf := range acc.VFs {
+				vfDrivers = append(vfDrivers, vf.Driver)
+			}
+			bbDevConfig, err := r.adoptablePF(acc.PFDriver, vfDrivers, pf.PFDriver, pf.VFDriver, pf.VFAmount, pf.PCIAddress,
+				func() ([]byte, error) { return r.expectedFecBBDevConfig(ctx, pf) })
+			if err != nil {
+				r.log.WithField("pci", pf.PCIAddress).WithField("reason", err).Info("configuration found on the node differs from the spec - PF will be reconfigured")
+				continue
+			}
+			adopted = append(adopted, adoptedPF{pciAddress: pf.PCIAddress, bbDevConfig: bbDevConfig})
+		}
+	}
+	return adopted
+}
+
+// vrbAdoptablePFs is the VRB counterpart of fecAdoptablePFs
+func (r *NodeConfigReconciler) vrbAdoptablePFs(ctx context.Context, spec vrbv1.SriovVrbNodeConfigSpec, inventory *vrbv1.NodeInventory) []adoptedPF {
+	if !spec.AdoptExistingConfig {
+		return nil
+	}
+
+	stopped := r.pfBBConfigsStoppedForReboot(ctx)
+	var adopted []adoptedPF
+	for _, pf := range spec.PhysicalFunctions {
+		if stopped[pf.PCIAddress] {
+			r.log.WithField("pci", pf.PCIAddress).Info("pf-bb-config was stopped before the reboot - PF will be reconfigured")
+			continue
+		}
+		for _, acc := range inventory.SriovAccelerators {
+			if acc.PCIAddress != pf.PCIAddress {
+				continue
+			}
+			var vfDrivers []string
+			for _, vf := range acc.VFs {
+				vfDrivers = append(vfDrivers, vf.Driver)
+			}
+			bbDevConfig, err := r.adoptablePF(acc.PFDriver, vfDrivers, pf.PFDriver, pf.VFDriver, pf.VFAmount, pf.PCIAddress,
+				func() ([]byte, error) { return r.expectedVrbBBDevConfig(ctx, pf) })
+			if err != nil {
+				r.log.WithField("pci", pf.PCIAddress).WithField("reason", err).Info("configuration found on the node differs from the spec - PF will be reconfigured")
+				continue
+			}
+			adopted = append(adopted, adoptedPF{pciAddress: pf.PCIAddress, bbDevConfig: bbDevConfig})
+		}
+	}
+	return adopted
+}
+
+// fecCleanupRequired tells whether configuration would remove VFs of accelerators which are not requested by the spec
+func fecCleanupRequired(pfs []fec.PhysicalFunctionConfigExt, inventory *fec.NodeInventory) bool {
+	for _, acc := range inventory.SriovAccelerators {
+		if len(acc.VFs) > 0 && getMatchingConfiguration(acc.PCIAddress, pfs) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// vrbCleanupRequired is the VRB counterpart of fecCleanupRequired
+func vrbCleanupRequired(pfs []vrbv1.PhysicalFunctionConfigExt, inventory *vrbv1.NodeInventory) bool {
+	for _, acc := range inventory.SriovAccelerators {
+		if len(acc.VFs) > 0 && VrbgetMatchingConfiguration(acc.PCIAddress, pfs) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// adoptablePF checks that PF found on the node matches the spec - it is bound to requested PF driver and exposes
+// requested number of VFs bound to requested VF driver, and pf_bb_config serving it runs with cfg file equivalent to
+// the expected one. Returns content of the cfg file, or error describing the first difference.
+func (r *NodeConfigReconciler) adoptablePF(pfDriver string, vfDrivers []string, requestedPFDriver, requestedVFDriver string, vfAmount int,
+	pciAddress string, expectedBBDevConfig func() ([]byte, error)) ([]byte, error) {
+
+	if !utils.SameDriver(pfDriver, requestedPFDriver) {
+		return nil, fmt.Errorf("PF is bound to %q instead of %q", pfDriver, requestedPFDriver)
+	}
+	if len(vfDrivers) != vfAmount {
+		return nil, fmt.Errorf("%d VFs found instead of %d", len(vfDrivers), vfAmount)
+	}
+	for _, vfDriver := range vfDrivers {
+		if !utils.SameDriver(vfDriver, requestedVFDriver) {
+			return nil, fmt.Errorf("VF is bound to %q instead of %q", vfDriver, requestedVFDriver)
+		}
+	}
+
+	expected, err := expectedBBDevConfig()
+	if err != nil || expected == nil {
+		return nil, err
+	}
+	found, err := runningBBDevConfig(pciAddress)
+	if err != nil {
+		return nil, err
+	}
+	equivalent, err := equivalentBBDevConfigs(expected, found)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare cfg file of running pf_bb_config - %v", err)
+	}
+	if !equivalent {
+		return nil, fmt.Errorf("pf_bb_config runs with cfg file different from the requested one")
+	}
+	return found, nil
+}
+
+// expectedFecBBDevConfig returns cfg file pf_bb_config would be started with for the PF; nil when no queues are
+// configured. PFs with custom SRS FFT LUT are not adopted, as the LUT pf_bb_config runs with cannot be compared.
+func (r *NodeConfigReconciler) expectedFecBBDevConfig(ctx context.Context, pf fec.PhysicalFunctionConfigExt) ([]byte, error) {
+	if acc100FFTLut(&pf) != nil || (pf.BBDevConfig.ACC200 != nil && pf.BBDevConfig.ACC200.FFTLut.FftUrl != "") {
+		return nil, fmt.Errorf("custom SRS FFT LUT cannot be compared")
+	}
+	if pf.BBDevConfigFrom != nil {
+		ref := bbDevConfigRef{pf.PCIAddress, pf.BBDevConfigFrom.ConfigMapRef.Name, pf.BBDevConfigFrom.ConfigMapRef.Key}
+		return readBBDevConfigFrom(ctx, r, r.nodeNameRef.Namespace, ref)
+	}
+	if pf.BBDevConfig.N3000 == nil && pf.BBDevConfig.ACC100 == nil && pf.BBDevConfig.ACC200 == nil {
+		return nil, nil
+	}
+	return generatedBBDevConfig(func(file string) error { return generateBBDevConfigFile(pf.BBDevConfig, file) })
+}
+
+// expectedVrbBBDevConfig is the VRB counterpart of expectedFecBBDevConfig
+func (r *NodeConfigReconciler) expectedVrbBBDevConfig(ctx context.Context, pf vrbv1.PhysicalFunctionConfigExt) ([]byte, error) {
+	if (pf.BBDevConfig.VRB1 != nil && pf.BBDevConfig.VRB1.FFTLut.FftUrl != "") || (pf.BBDevConfig.VRB2 != nil && pf.BBDevConfig.VRB2.FFTLut.FftUrl != "") {
+		return nil, fmt.Errorf("custom SRS FFT LUT cannot be compared")
+	}
+	if pf.BBDevConfigFrom != nil {
+		ref := bbDevConfigRef{pf.PCIAddress, pf.BBDevConfigFrom.ConfigMapRef.Name, pf.BBDevConfigFrom.ConfigMapRef.Key}
+		return readBBDevConfigFrom(ctx, r, r.nodeNameRef.Namespace, ref)
+	}
+	if pf.BBDevConfig.VRB1 == nil && pf.BBDevConfig.VRB2 == nil {
+		return nil, nil
+	}
+	return generatedBBDevConfig(func(file string) error { return generateVrbBBDevConfigFile(pf.BBDevConfig, file) })
+}
+
+// generatedBBDevConfig returns content of cfg file created by generate in a temporary file
+func generatedBBDevConfig(generate func(file string) error) ([]byte, error) {
+	f, err := os.CreateTemp(workdir, "expected-*.ini")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary cfg file - %v", err)
+	}
+	_ = f.Close()
+	defer os.Remove(f.Name())
+
+	if err := generate(f.Name()); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(f.Name())
+}
+
+// runningBBDevConfig returns content of cfg file ("-c" argument) pf_bb_config serving the PF was started with. The file
+// is read through root (or working directory) of the process, as it may run in another mount namespace.
+func runningBBDevConfig(pciAddress string) ([]byte, error) {
+	entries, err := os.ReadDir(procPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processes - %v", err)
+	}
+
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err != nil {
+			continue
+		}
+		cmdline, err := os.ReadFile(filepath.Join(procPath, entry.Name(), "cmdline"))
+		if err != nil || !isPfBBConfigCmdline(cmdline, pciAddress) {
+			continue
+		}
+
+		cfgFile := pfBBConfigArg(cmdline, "-c")
+		if cfgFile == "" {
+			return nil, fmt.Errorf("pf_bb_config(%s) runs without cfg file", entry.Name())
+		}
+		base := "root"
+		if !filepath.IsAbs(cfgFile) {
+			base = "cwd"
+		}
+		content, err := os.ReadFile(filepath.Join(procPath, entry.Name(), base, cfgFile))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cfg file of pf_bb_config(%s) - %v", entry.Name(), err)
+		}
+		return content, nil
+	}
+	return nil, fmt.Errorf("pf_bb_config is not running")
+}
+
+// equivalentBBDevConfigs compares cfg files by content - sections and keys are compared regardless of their order,
+// whitespace and comments
+func equivalentBBDevConfigs(a, b []byte) (bool, error) {
+	contentA, err := bbDevConfigContent(a)
+	if err != nil {
+		return false, err
+	}
+	contentB, err := bbDevConfigContent(b)
+	if err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(contentA, contentB), nil
+}
+
+// bbDevConfigContent returns values of cfg file by section and key; sections without keys are left out
+func bbDevConfigContent(cfg []byte) (map[string]map[string]string, error) {
+	file, err := ini.Load(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	content := map[string]map[string]string{}
+	for _, section := range file.Sections() {
+		if len(section.Keys()) == 0 {
+			continue
+		}
+		values := map[string]string{}
+		for _, key := range section.Keys() {
+			values[key.Name()] = key.Value()
+		}
+		content[section.Name()] = values
+	}
+	return content, nil
+}
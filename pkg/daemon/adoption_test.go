@@ -0,0 +1,257 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+// adoptionRecordingConfigurer records which PFs of applied spec were adopted according to the context
+type adoptionRecordingConfigurer struct {
+	adopted map[string]bool
+}
+
+func (c *adoptionRecordingConfigurer) ApplySpec(ctx context.Context, spec sriovv2.SriovFecNodeConfigSpec) error {
+	c.adopted = map[string]bool{}
+	for _, pf := range spec.PhysicalFunctions {
+		c.adopted[pf.PCIAddress] = isAdoptedPF(ctx, pf.PCIAddress)
+	}
+	return nil
+}
+
+func (c *adoptionRecordingConfigurer) RestartPfBBConfig(context.Context, sriovv2.PhysicalFunctionConfigExt) error {
+	return fmt.Errorf("not implemented")
+}
+
+var _ = Describe("Adoption of existing configuration", func() {
+	Context("equivalentBBDevConfigs", func() {
+		const generated = "[MODE]\npf_mode_en = 0\n\n[UL]\nbandwidth    = 3\nload_balance = 128\n"
+
+		It("should ignore order, whitespace and comments", func() {
+			found := "; configured manually\n[UL]\n  load_balance=128\nbandwidth   =   3\n\n\n[MODE]\n# PF mode\npf_mode_en=0\n"
+			Expect(equivalentBBDevConfigs([]byte(generated), []byte(found))).To(BeTrue())
+		})
+
+		It("should detect different values, keys and sections", func() {
+			for _, found := range []string{
+				"[MODE]\npf_mode_en = 0\n\n[UL]\nbandwidth = 4\nload_balance = 128\n",
+				"[MODE]\npf_mode_en = 0\n\n[UL]\nbandwidth = 3\nload_balance = 128\nvfqmap = 16\n",
+				"[MODE]\npf_mode_en = 0\n\n[DL]\nbandwidth = 3\nload_balance = 128\n",
+			} {
+				Expect(equivalentBBDevConfigs([]byte(generated), []byte(found))).To(BeFalse(), found)
+			}
+		})
+
+		It("should fail for malformed cfg file", func() {
+			_, err := equivalentBBDevConfigs([]byte(generated), []byte("[MODE"))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("NodeConfigReconciler.Reconcile", func() {
+		const otherPCIAddress = "0000:15:00.1"
+
+		var (
+			fakeClient       client.Client
+			nodeNameRef      = types.NamespacedName{Name: "worker", Namespace: "testNamespace"}
+			reconciler       *NodeConfigReconciler
+			configurer       *adoptionRecordingConfigurer
+			drained          bool
+			originalWorkdir  = workdir
+			originalProcPath = procPath
+			bbDevConfig      = sriovv2.BBDevConfig{N3000: &sriovv2.N3000BBDevConfig{NetworkType: "FPGA_5GNR", FLRTimeOut: func(v int) *int { return &v }(10)}}
+		)
+
+		// writes cfg file of pf_bb_config started for the PF, as it would be found in /proc
+		runPfBBConfig := func(cfg []byte) {
+			process := filepath.Join(procPath, "4242")
+			Expect(os.MkdirAll(filepath.Join(process, "root", "etc"), 0755)).To(Succeed())
+			cmdline := strings.Join([]string{"/opt/pf_bb_config", "FPGA_5GNR", "-c", "/etc/fec.cfg", "-p", pciAddress}, "\x00")
+			Expect(os.WriteFile(filepath.Join(process, "cmdline"), []byte(cmdline+"\x00"), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(process, "root", "etc", "fec.cfg"), cfg, 0644)).To(Succeed())
+		}
+
+		// rewrites cfg file as admin setting it up manually could - sections reversed, different spacing and comments
+		manual := func(cfg []byte) []byte {
+			sections := strings.Split(strings.TrimSpace(string(cfg)), "\n\n")
+			for i, j := 0, len(sections)-1; i < j; i, j = i+1, j-1 {
+				sections[i], sections[j] = sections[j], sections[i]
+			}
+			return []byte("; set up manually\n" + strings.ReplaceAll(strings.Join(sections, "\n\n\n"), " = ", "=  ") + "\n")
+		}
+
+		updateSpec := func(update func(spec *sriovv2.SriovFecNodeConfigSpec)) {
+			sfnc := new(sriovv2.SriovFecNodeConfig)
+			Expect(fakeClient.Get(context.TODO(), nodeNameRef, sfnc)).To(Succeed())
+			update(&sfnc.Spec)
+			Expect(fakeClient.Update(context.TODO(), sfnc)).To(Succeed())
+		}
+
+		getNodeConfig := func() *sriovv2.SriovFecNodeConfig {
+			sfnc := new(sriovv2.SriovFecNodeConfig)
+			Expect(fakeClient.Get(context.TODO(), nodeNameRef, sfnc)).To(Succeed())
+			return sfnc
+		}
+
+		reconcile := func() {
+			_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: nodeNameRef})
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		BeforeEach(func() {
+			root, err := os.MkdirTemp(testTmpFolder, "adoption")
+			Expect(err).ToNot(HaveOccurred())
+			workdir = root
+			procPath = filepath.Join(root, "proc")
+			Expect(os.MkdirAll(procPath, 0755)).To(Succeed())
+
+			scheme := runtime.NewScheme()
+			Expect(sriovv2.AddToScheme(scheme)).To(Succeed())
+			Expect(vrbv1.AddToScheme(scheme)).To(Succeed())
+			Expect(corev1.AddToScheme(scheme)).To(Succeed())
+
+			procCmdlineFilePath = "testdata/cmdline_test"
+			sysLockdownFilePath = filepath.Join(testTmpFolder, "lockdown")
+			Expect(os.WriteFile(sysLockdownFilePath, []byte("[none] integrity confidentiality"), 0644)).To(Succeed())
+			drained = false
+
+			getSriovInventory = func(_ context.Context, log *logrus.Logger) (*sriovv2.NodeInventory, error) {
+				return &sriovv2.NodeInventory{
+					SriovAccelerators: []sriovv2.SriovAccelerator{
+						{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_UNDERSCORE, MaxVFs: 10, VFs: []sriovv2.VF{
+							{PCIAddress: "0000:14:00.2", Driver: utils.VFIO_PCI_UNDERSCORE},
+							{PCIAddress: "0000:14:00.3", Driver: utils.VFIO_PCI_UNDERSCORE},
+						}},
+						{PCIAddress: otherPCIAddress, PFDriver: utils.PCI_PF_STUB_DASH, MaxVFs: 10, VFs: []sriovv2.VF{
+							{PCIAddress: "0000:15:00.2", Driver: utils.IGB_UIO},
+						}},
+					},
+				}, nil
+			}
+			VrbgetSriovInventory = func(_ context.Context, log *logrus.Logger) (*vrbv1.NodeInventory, error) {
+				return &vrbv1.NodeInventory{}, nil
+			}
+
+			sfnc := &sriovv2.SriovFecNodeConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace, Generation: 1},
+				Spec: sriovv2.SriovFecNodeConfigSpec{
+					AdoptExistingConfig: true,
+					PhysicalFunctions: []sriovv2.PhysicalFunctionConfigExt{
+						{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, VFDriver: utils.VFIO_PCI, VFAmount: 2, BBDevConfig: bbDevConfig},
+						{PCIAddress: otherPCIAddress, PFDriver: utils.PCI_PF_STUB_DASH, VFDriver: utils.IGB_UIO, VFAmount: 1},
+					},
+				},
+			}
+			vrbnc := &vrbv1.SriovVrbNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace}}
+			fakeClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(sfnc, vrbnc).Build()
+
+			configurer = &adoptionRecordingConfigurer{}
+			reconciler = &NodeConfigReconciler{
+				Client:             fakeClient,
+				log:                utils.NewLogger(),
+				nodeNameRef:        nodeNameRef,
+				sriovfecconfigurer: configurer,
+				drainerAndExecute: func(ctx context.Context, configurer func(ctx context.Context) bool, drain bool) error {
+					drained = true
+					_ = configurer(ctx)
+					return nil
+				},
+				restartDevicePlugin: func(context.Context) error { return nil },
+			}
+
+			generated, err := reconciler.expectedFecBBDevConfig(context.TODO(), sfnc.Spec.PhysicalFunctions[0])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(manual(generated)).ToNot(Equal(generated))
+			runPfBBConfig(manual(generated))
+		})
+
+		AfterEach(func() {
+			workdir = originalWorkdir
+			procPath = originalProcPath
+			getSriovInventory = GetSriovInventory
+			VrbgetSriovInventory = VrbGetSriovInventory
+			sysLockdownFilePath = "/sys/kernel/security/lockdown"
+		})
+
+		It("should adopt configuration matching the spec without drain", func() {
+			reconcile()
+
+			Expect(drained).To(BeFalse())
+			Expect(configurer.adopted).To(BeNil())
+			sfnc := getNodeConfig()
+			Expect(sfnc.FindCondition(ConditionConfigured).Reason).To(Equal(string(ConfigurationSucceeded)))
+			Expect(sfnc.FindCondition(ConditionConfigured).Message).To(Equal("Configured successfully (adopted existing configuration)"))
+			Expect(sfnc.Status.AdoptedPFs).To(Equal([]string{pciAddress, otherPCIAddress}))
+			Expect(sfnc.Status.AppliedBBDevConfigHashes).To(HaveKey(pciAddress))
+
+			// adopted spec is the applied one
+			reconcile()
+			Expect(drained).To(BeFalse())
+		})
+
+		It("should reconfigure only PFs which differ from the spec", func() {
+			updateSpec(func(spec *sriovv2.SriovFecNodeConfigSpec) { spec.PhysicalFunctions[1].VFAmount = 4 })
+
+			reconcile()
+
+			Expect(drained).To(BeTrue())
+			Expect(configurer.adopted).To(Equal(map[string]bool{pciAddress: true, otherPCIAddress: false}))
+			sfnc := getNodeConfig()
+			Expect(sfnc.FindCondition(ConditionConfigured).Message).To(Equal("Configured successfully"))
+			Expect(sfnc.Status.AdoptedPFs).To(Equal([]string{pciAddress}))
+		})
+
+		It("should reconfigure PF which pf_bb_config was stopped before the reboot", func() {
+			Expect(fakeClient.Create(context.TODO(), &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name,
+				Annotations: map[string]string{AutoRebootStoppedPFsAnnotation: otherPCIAddress}}})).To(Succeed())
+
+			reconcile()
+
+			Expect(drained).To(BeTrue())
+			Expect(configurer.adopted).To(Equal(map[string]bool{pciAddress: true, otherPCIAddress: false}))
+		})
+
+		It("should reconfigure PF when pf_bb_config runs with different cfg file", func() {
+			different := bbDevConfig.DeepCopy()
+			*different.N3000.FLRTimeOut = 20
+			generated, err := generatedBBDevConfig(func(file string) error { return generateBBDevConfigFile(*different, file) })
+			Expect(err).ToNot(HaveOccurred())
+			runPfBBConfig(generated)
+
+			reconcile()
+
+			Expect(drained).To(BeTrue())
+			Expect(configurer.adopted).To(Equal(map[string]bool{pciAddress: false, otherPCIAddress: true}))
+		})
+
+		It("should reconfigure PFs when adoption is not enabled", func() {
+			updateSpec(func(spec *sriovv2.SriovFecNodeConfigSpec) { spec.AdoptExistingConfig = false })
+
+			reconcile()
+
+			Expect(drained).To(BeTrue())
+			Expect(configurer.adopted).To(Equal(map[string]bool{pciAddress: false, otherPCIAddress: false}))
+			Expect(getNodeConfig().Status.AdoptedPFs).To(BeEmpty())
+		})
+	})
+})
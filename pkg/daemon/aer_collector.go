@@ -0,0 +1,244 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	fec "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/conditions"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+const (
+	aerSeverityLabel       = "severity"
+	aerSeverityCorrectable = "correctable"
+	aerSeverityNonFatal    = "nonfatal"
+	aerSeverityFatal       = "fatal"
+	uncorrectableAERErrors = "UncorrectablePCIeErrors"
+	aerCorrectableFileName = "aer_dev_correctable"
+	aerNonFatalFileName    = "aer_dev_nonfatal"
+	aerFatalFileName       = "aer_dev_fatal"
+	aerTotalCorrectableKey = "TOTAL_ERR_COR"
+	aerTotalNonFatalKey    = "TOTAL_ERR_NONFATAL"
+	aerTotalFatalKey       = "TOTAL_ERR_FATAL"
+)
+
+var aerErrors = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "sriovfec_pcie_aer_errors",
+	Help: `number of PCIe errors reported by AER for configured PF. 'pci_address' - represents unique BDF for PF. 'severity' - available values: 'correctable', 'nonfatal', 'fatal'`,
+}, []string{pciAddressLabel, aerSeverityLabel})
+
+func init() {
+	metrics.Registry.MustRegister(aerErrors)
+}
+
+type aerCounters struct {
+	correctable, nonFatal, fatal uint64
+}
+
+func (c aerCounters) uncorrectable() uint64 {
+	return c.nonFatal + c.fatal
+}
+
+// readAERCounters reads AER counters of the device from sysfs; supported is false when kernel does not expose them
+func readAERCounters(pciAddress string) (counters aerCounters, supported bool, err error) {
+	for _, counter := range []struct {
+		file, key string
+		value     *uint64
+	}{
+		{aerCorrectableFileName, aerTotalCorrectableKey, &counters.correctable},
+		{aerNonFatalFileName, aerTotalNonFatalKey, &counters.nonFatal},
+		{aerFatalFileName, aerTotalFatalKey, &counters.fatal},
+	} {
+		value, err := readAERTotal(filepath.Join(sysBusPciDevices, pciAddress, counter.file), counter.key)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return aerCounters{}, false, nil
+			}
+			return aerCounters{}, true, err
+		}
+		*counter.value = value
+	}
+	return counters, true, nil
+}
+
+// readAERTotal returns value of key from AER counters file, which contains lines in '<name> <value>' format
+func readAERTotal(path, key string) (uint64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == key {
+			value, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value of %s in %s - %v", key, path, err)
+			}
+			return value, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("%s not found in %s", key, path)
+}
+
+// AERCollector periodically reads AER counters of configured PFs and exposes them as metrics. Increase of uncorrectable
+// errors is reported with warning event and marks PFHealthy condition of the PF as degraded; the condition becomes
+// healthy again when counters are reset (e.g. by device reset).
+type AERCollector struct {
+	client   client.Client
+	recorder record.EventRecorder
+	// nodeConfigKey returns key of node configs of the node, see NodeConfigReconciler.NodeConfigKey
+	nodeConfigKey func() types.NamespacedName
+	log           *logrus.Logger
+	last          map[string]aerCounters
+	unsupported   map[string]bool
+}
+
+// NewAERCollector creates collector of PFs configured by node configs with key returned by nodeConfigKey
+func NewAERCollector(c client.Client, recorder record.EventRecorder, nodeConfigKey func() types.NamespacedName, log *logrus.Logger) *AERCollector {
+	return &AERCollector{
+		client:        c,
+		recorder:      recorder,
+		nodeConfigKey: nodeConfigKey,
+		log:           log,
+		last:          map[string]aerCounters{},
+		unsupported:   map[string]bool{},
+	}
+}
+
+// Start implements manager.Runnable
+func (a *AERCollector) Start(ctx context.Context) error {
+	a.log.WithField("interval", resyncPeriod.Get()).Info("starting AER collector")
+	wait.UntilWithContext(ctx, a.collect, resyncPeriod.Get())
+	return nil
+}
+
+func (a *AERCollector) collect(ctx context.Context) {
+	aerErrors.Reset()
+	for _, nc := range []client.Object{&fec.SriovFecNodeConfig{}, &vrbv1.SriovVrbNodeConfig{}} {
+		if err := a.collectNodeConfig(ctx, nc); err != nil {
+			a.log.WithError(err).WithField("kind", fmt.Sprintf("%T", nc)).Error("failed to collect AER counters")
+		}
+	}
+}
+
+func (a *AERCollector) collectNodeConfig(ctx context.Context, nc client.Object) error {
+	if err := a.client.Get(ctx, a.nodeConfigKey(), nc); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	original := nc.DeepCopyObject().(client.Object)
+	var pciAddresses []string
+	var ncConditions *[]metav1.Condition
+	switch nodeConfig := nc.(type) {
+	case *fec.SriovFecNodeConfig:
+		for _, pf := range nodeConfig.Spec.PhysicalFunctions {
+			pciAddresses = append(pciAddresses, utils.CanonicalPCIAddress(pf.PCIAddress))
+		}
+		ncConditions = &nodeConfig.Status.Conditions
+	case *vrbv1.SriovVrbNodeConfig:
+		for _, pf := range nodeConfig.Spec.PhysicalFunctions {
+			pciAddresses = append(pciAddresses, utils.CanonicalPCIAddress(pf.PCIAddress))
+		}
+		ncConditions = &nodeConfig.Status.Conditions
+	default:
+		return fmt.Errorf("unsupported node config type %T", nc)
+	}
+
+	changed := false
+	for _, pciAddress := range pciAddresses {
+		collected := a.collectPF(pciAddress, nc.GetGeneration())
+		if collected == nil {
+			continue
+		}
+		condition, fullMessage := conditions.Bounded(*collected)
+		if condition.Status == metav1.ConditionTrue && meta.FindStatusCondition(*ncConditions, condition.Type) == nil {
+			// do not write healthy condition of PF which was never degraded
+			continue
+		}
+		if conditions.SetIfChanged(ncConditions, condition) {
+			changed = true
+			if condition.Reason == string(conditions.ReasonDegraded) && a.recorder != nil {
+				a.recorder.Event(nc, corev1.EventTypeWarning, uncorrectableAERErrors, condition.Message)
+			}
+			emitFullMessage(a.recorder, nc, condition, fullMessage)
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+	_, err := patchStatus(ctx, a.client, original, nc)
+	return err
+}
+
+// collectPF updates metrics of the PF and returns its health condition; nil when counters are not available
+func (a *AERCollector) collectPF(pciAddress string, generation int64) *metav1.Condition {
+	log := a.log.WithField("pci", pciAddress)
+	counters, supported, err := readAERCounters(pciAddress)
+	if err != nil {
+		log.WithError(err).Error("failed to read AER counters")
+		return nil
+	}
+	if !supported {
+		if !a.unsupported[pciAddress] {
+			log.Info("AER counters are not exposed by the kernel - skipping")
+			a.unsupported[pciAddress] = true
+		}
+		return nil
+	}
+
+	aerErrors.WithLabelValues(pciAddress, aerSeverityCorrectable).Set(float64(counters.correctable))
+	aerErrors.WithLabelValues(pciAddress, aerSeverityNonFatal).Set(float64(counters.nonFatal))
+	aerErrors.WithLabelValues(pciAddress, aerSeverityFatal).Set(float64(counters.fatal))
+
+	last, seen := a.last[pciAddress]
+	a.last[pciAddress] = counters
+	switch {
+	case !seen:
+		// counters observed for the first time are the baseline
+		return nil
+	case counters.uncorrectable() > last.uncorrectable():
+		log.WithField("previous", last.uncorrectable()).WithField("current", counters.uncorrectable()).
+			Warn("uncorrectable PCIe errors increased")
+		condition := conditions.PFHealthy(pciAddress, metav1.ConditionFalse, conditions.ReasonDegraded,
+			fmt.Sprintf("Uncorrectable PCIe errors of %s increased from %d to %d (nonfatal: %d, fatal: %d)",
+				pciAddress, last.uncorrectable(), counters.uncorrectable(), counters.nonFatal, counters.fatal), generation)
+		return &condition
+	case counters.uncorrectable() < last.uncorrectable():
+		condition := conditions.PFHealthy(pciAddress, metav1.ConditionTrue, conditions.ReasonHealthy,
+			"AER counters were reset", generation)
+		return &condition
+	}
+	return nil
+}
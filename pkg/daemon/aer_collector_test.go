@@ -0,0 +1,116 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	fec "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/conditions"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("AERCollector", func() {
+	const pciAddress = "0000:f7:00.0"
+	var (
+		originalSysBusPciDevices = sysBusPciDevices
+		nodeRef                  = types.NamespacedName{Name: "worker", Namespace: "default"}
+		c                        client.Client
+		recorder                 *record.FakeRecorder
+		collector                *AERCollector
+	)
+
+	writeCounters := func(correctable, nonFatal, fatal int) {
+		deviceDir := filepath.Join(sysBusPciDevices, pciAddress)
+		Expect(os.MkdirAll(deviceDir, 0755)).To(Succeed())
+		for file, content := range map[string]string{
+			aerCorrectableFileName: fmt.Sprintf("RxErr 0\nBadTLP %d\nTOTAL_ERR_COR %d\n", correctable, correctable),
+			aerNonFatalFileName:    fmt.Sprintf("Undefined 0\nCmpltTO %d\nTOTAL_ERR_NONFATAL %d\n", nonFatal, nonFatal),
+			aerFatalFileName:       fmt.Sprintf("Undefined 0\nSurpriseDown %d\nTOTAL_ERR_FATAL %d\n", fatal, fatal),
+		} {
+			Expect(os.WriteFile(filepath.Join(deviceDir, file), []byte(content), 0644)).To(Succeed())
+		}
+	}
+
+	healthCondition := func() *metav1.Condition {
+		nc := &vrbv1.SriovVrbNodeConfig{}
+		Expect(c.Get(context.TODO(), nodeRef, nc)).To(Succeed())
+		return meta.FindStatusCondition(nc.Status.Conditions, conditions.PFHealthyType(pciAddress))
+	}
+
+	BeforeEach(func() {
+		root, err := os.MkdirTemp(testTmpFolder, "aer")
+		Expect(err).ToNot(HaveOccurred())
+		sysBusPciDevices = filepath.Join(root, "devices")
+
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(fec.AddToScheme(scheme)).To(Succeed())
+		Expect(vrbv1.AddToScheme(scheme)).To(Succeed())
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&vrbv1.SriovVrbNodeConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: nodeRef.Name, Namespace: nodeRef.Namespace},
+				Spec: vrbv1.SriovVrbNodeConfigSpec{
+					PhysicalFunctions: []vrbv1.PhysicalFunctionConfigExt{{PCIAddress: pciAddress}},
+				},
+			},
+		).Build()
+		recorder = record.NewFakeRecorder(10)
+		collector = NewAERCollector(c, recorder, func() types.NamespacedName { return nodeRef }, utils.NewLogger())
+	})
+
+	AfterEach(func() {
+		sysBusPciDevices = originalSysBusPciDevices
+	})
+
+	It("should expose counters and report increase of uncorrectable errors", func() {
+		writeCounters(3, 1, 0)
+		collector.collect(context.TODO())
+		Expect(testutil.ToFloat64(aerErrors.WithLabelValues(pciAddress, aerSeverityCorrectable))).To(Equal(3.0))
+		Expect(testutil.ToFloat64(aerErrors.WithLabelValues(pciAddress, aerSeverityNonFatal))).To(Equal(1.0))
+		Expect(healthCondition()).To(BeNil(), "initial counters are the baseline")
+
+		writeCounters(10, 1, 0)
+		collector.collect(context.TODO())
+		Expect(healthCondition()).To(BeNil(), "correctable errors do not degrade PF")
+		Expect(recorder.Events).To(BeEmpty())
+
+		writeCounters(10, 2, 1)
+		collector.collect(context.TODO())
+		Expect(healthCondition().Status).To(Equal(metav1.ConditionFalse))
+		Expect(healthCondition().Reason).To(Equal(string(conditions.ReasonDegraded)))
+		Expect(recorder.Events).To(HaveLen(1))
+
+		collector.collect(context.TODO())
+		Expect(healthCondition().Status).To(Equal(metav1.ConditionFalse))
+		Expect(recorder.Events).To(HaveLen(1))
+
+		writeCounters(0, 0, 0)
+		collector.collect(context.TODO())
+		Expect(healthCondition().Status).To(Equal(metav1.ConditionTrue))
+	})
+
+	It("should skip devices without AER counters", func() {
+		collector.collect(context.TODO())
+		collector.collect(context.TODO())
+		Expect(testutil.CollectAndCount(aerErrors)).To(BeZero())
+		Expect(healthCondition()).To(BeNil())
+	})
+})
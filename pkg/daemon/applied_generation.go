@@ -0,0 +1,131 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+)
+
+// appliedPFs collects PFs configured successfully by single configuration, so that generation they run with is known
+// even when configuration of other PFs fails
+type appliedPFs struct {
+	mu           sync.Mutex
+	pciAddresses []string
+}
+
+func (a *appliedPFs) add(pciAddress string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.pciAddresses = append(a.pciAddresses, pciAddress)
+}
+
+func (a *appliedPFs) list() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]string(nil), a.pciAddresses...)
+}
+
+type appliedPFsKey struct{}
+
+// withAppliedPFs returns context with which configuration records PFs configured successfully to a
+func withAppliedPFs(ctx context.Context, a *appliedPFs) context.Context {
+	return context.WithValue(ctx, appliedPFsKey{}, a)
+}
+
+// recordAppliedPF records PF configured successfully in the context; it is no-op when context has no collector
+func recordAppliedPF(ctx context.Context, pciAddress string) {
+	if a, ok := ctx.Value(appliedPFsKey{}).(*appliedPFs); ok {
+		a.add(pciAddress)
+	}
+}
+
+// succeededGeneration returns generation node config was last configured with successfully, given its Configured
+// condition and generation recorded in status. ObservedGeneration of InProgress condition is the generation being
+// configured, so the recorded one is returned then; otherwise the condition tells, which covers node configs written
+// before the generation was recorded.
+func succeededGeneration(configured metav1.Condition, recorded int64) int64 {
+	if configured.Reason == string(ConfigurationInProgress) {
+		return recorded
+	}
+	return configured.ObservedGeneration
+}
+
+// configurationQueuedMessage is message of InProgress condition reported once configuration of new spec is required
+const configurationQueuedMessage = "queued for configuration"
+
+// inProgressReported tells whether Configured condition reports configuration of given generation in progress already
+func inProgressReported(configured metav1.Condition, generation int64) bool {
+	return configured.Reason == string(ConfigurationInProgress) && configured.ObservedGeneration == generation
+}
+
+// setAppliedGenerations returns applied generations with given PFs set to generation
+func setAppliedGenerations(applied map[string]int64, pciAddresses []string, generation int64) map[string]int64 {
+	if len(pciAddresses) == 0 {
+		return applied
+	}
+	updated := make(map[string]int64, len(applied)+len(pciAddresses))
+	for pciAddress, g := range applied {
+		updated[pciAddress] = g
+	}
+	for _, pciAddress := range pciAddresses {
+		updated[pciAddress] = generation
+	}
+	return updated
+}
+
+// pruneAppliedGenerations returns applied generations of requested PFs only; nil when there are none
+func pruneAppliedGenerations(applied map[string]int64, requested []string) map[string]int64 {
+	var pruned map[string]int64
+	for _, pciAddress := range requested {
+		if g, ok := applied[pciAddress]; ok {
+			if pruned == nil {
+				pruned = map[string]int64{}
+			}
+			pruned[pciAddress] = g
+		}
+	}
+	return pruned
+}
+
+// appliedGenerationsCurrent tells whether every requested PF was last configured with given generation of the spec,
+// i.e. whether node config is reconciled fully
+func appliedGenerationsCurrent(applied map[string]int64, requested []string, generation int64) bool {
+	for _, pciAddress := range requested {
+		if g, ok := applied[pciAddress]; !ok || g != generation {
+			return false
+		}
+	}
+	return true
+}
+
+// fecSpecAppliedGenerations returns applied generations of node config which spec needs no configuration. Spec applied
+// successfully before is in effect on every PF then, even when its generation changed since (e.g. by a field which
+// does not affect the hardware, or before applied generations were recorded).
+func fecSpecAppliedGenerations(nc *sriovv2.SriovFecNodeConfig) map[string]int64 {
+	if condition := nc.FindCondition(ConditionConfigured); condition == nil || condition.Reason != string(ConfigurationSucceeded) {
+		return nc.Status.AppliedGenerations
+	}
+	var pciAddresses []string
+	for _, pf := range nc.Spec.PhysicalFunctions {
+		pciAddresses = append(pciAddresses, pf.PCIAddress)
+	}
+	return setAppliedGenerations(pruneAppliedGenerations(nc.Status.AppliedGenerations, pciAddresses), pciAddresses, nc.GetGeneration())
+}
+
+func vrbSpecAppliedGenerations(nc *vrbv1.SriovVrbNodeConfig) map[string]int64 {
+	if condition := nc.FindCondition(ConditionConfigured); condition == nil || condition.Reason != string(ConfigurationSucceeded) {
+		return nc.Status.AppliedGenerations
+	}
+	var pciAddresses []string
+	for _, pf := range nc.Spec.PhysicalFunctions {
+		pciAddresses = append(pciAddresses, pf.PCIAddress)
+	}
+	return setAppliedGenerations(pruneAppliedGenerations(nc.Status.AppliedGenerations, pciAddresses), pciAddresses, nc.GetGeneration())
+}
@@ -0,0 +1,191 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+// partiallyFailingConfigurer configures PFs of the spec in order and fails on the one with failingPCIAddress
+type partiallyFailingConfigurer struct {
+	failingPCIAddress string
+}
+
+func (c *partiallyFailingConfigurer) ApplySpec(ctx context.Context, spec sriovv2.SriovFecNodeConfigSpec) error {
+	for _, pf := range spec.PhysicalFunctions {
+		if pf.PCIAddress == c.failingPCIAddress {
+			return fmt.Errorf("failed to configure %s", pf.PCIAddress)
+		}
+		recordAppliedPF(ctx, pf.PCIAddress)
+	}
+	return nil
+}
+
+func (c *partiallyFailingConfigurer) RestartPfBBConfig(context.Context, sriovv2.PhysicalFunctionConfigExt) error {
+	return fmt.Errorf("not implemented")
+}
+
+var _ = Describe("Applied generation", func() {
+	Context("helpers", func() {
+		It("should keep generations of requested PFs only", func() {
+			applied := map[string]int64{"0000:f7:00.0": 2, "0000:f8:00.0": 1}
+			Expect(pruneAppliedGenerations(applied, []string{"0000:f8:00.0", "0000:f9:00.0"})).To(Equal(map[string]int64{"0000:f8:00.0": 1}))
+			Expect(pruneAppliedGenerations(applied, nil)).To(BeNil())
+		})
+
+		It("should set generation of given PFs without modifying the original", func() {
+			applied := map[string]int64{"0000:f7:00.0": 1, "0000:f8:00.0": 1}
+			Expect(setAppliedGenerations(applied, []string{"0000:f8:00.0"}, 3)).To(Equal(map[string]int64{"0000:f7:00.0": 1, "0000:f8:00.0": 3}))
+			Expect(applied["0000:f8:00.0"]).To(Equal(int64(1)))
+		})
+
+		It("should consider node config reconciled only when every PF is at the generation", func() {
+			requested := []string{"0000:f7:00.0", "0000:f8:00.0"}
+			Expect(appliedGenerationsCurrent(map[string]int64{"0000:f7:00.0": 2, "0000:f8:00.0": 2}, requested, 2)).To(BeTrue())
+			Expect(appliedGenerationsCurrent(map[string]int64{"0000:f7:00.0": 2, "0000:f8:00.0": 1}, requested, 2)).To(BeFalse())
+			Expect(appliedGenerationsCurrent(map[string]int64{"0000:f7:00.0": 2}, requested, 2)).To(BeFalse())
+			Expect(appliedGenerationsCurrent(nil, nil, 2)).To(BeTrue())
+		})
+
+		It("should move every PF to current generation when spec needs no configuration", func() {
+			nc := &sriovv2.SriovFecNodeConfig{
+				ObjectMeta: metav1.ObjectMeta{Generation: 4},
+				Spec: sriovv2.SriovFecNodeConfigSpec{PhysicalFunctions: []sriovv2.PhysicalFunctionConfigExt{
+					{PCIAddress: "0000:f7:00.0"}, {PCIAddress: "0000:f8:00.0"},
+				}},
+				Status: sriovv2.SriovFecNodeConfigStatus{
+					AppliedGenerations: map[string]int64{"0000:f7:00.0": 3, "0000:f9:00.0": 1},
+					Conditions:         []metav1.Condition{{Type: ConditionConfigured, Reason: string(ConfigurationFailed)}},
+				},
+			}
+			Expect(fecSpecAppliedGenerations(nc)).To(Equal(nc.Status.AppliedGenerations), "generations are kept unless configuration succeeded")
+
+			nc.Status.Conditions[0].Reason = string(ConfigurationSucceeded)
+			Expect(fecSpecAppliedGenerations(nc)).To(Equal(map[string]int64{"0000:f7:00.0": 4, "0000:f8:00.0": 4}))
+		})
+	})
+
+	Context("NodeConfigReconciler.Reconcile", func() {
+		const otherPCIAddress = "0000:15:00.1"
+
+		var (
+			fakeClient      client.Client
+			nodeNameRef     = types.NamespacedName{Name: "worker", Namespace: "testNamespace"}
+			reconciler      *NodeConfigReconciler
+			configurer      *partiallyFailingConfigurer
+			originalWorkdir = workdir
+			// inventory getters are restored as they were, other specs may rely on them
+			originalGetSriovInventory    func(_ context.Context, log *logrus.Logger) (*sriovv2.NodeInventory, error)
+			originalVrbgetSriovInventory func(_ context.Context, log *logrus.Logger) (*vrbv1.NodeInventory, error)
+		)
+
+		getNodeConfig := func() *sriovv2.SriovFecNodeConfig {
+			sfnc := new(sriovv2.SriovFecNodeConfig)
+			Expect(fakeClient.Get(context.TODO(), nodeNameRef, sfnc)).To(Succeed())
+			return sfnc
+		}
+
+		BeforeEach(func() {
+			root, err := os.MkdirTemp(testTmpFolder, "applied-generation")
+			Expect(err).ToNot(HaveOccurred())
+			workdir = root
+
+			scheme := runtime.NewScheme()
+			Expect(sriovv2.AddToScheme(scheme)).To(Succeed())
+			Expect(vrbv1.AddToScheme(scheme)).To(Succeed())
+
+			procCmdlineFilePath = "testdata/cmdline_test"
+			sysLockdownFilePath = filepath.Join(testTmpFolder, "lockdown")
+			Expect(os.WriteFile(sysLockdownFilePath, []byte("[none] integrity confidentiality"), 0644)).To(Succeed())
+
+			originalGetSriovInventory, originalVrbgetSriovInventory = getSriovInventory, VrbgetSriovInventory
+			getSriovInventory = func(_ context.Context, log *logrus.Logger) (*sriovv2.NodeInventory, error) {
+				return &sriovv2.NodeInventory{
+					SriovAccelerators: []sriovv2.SriovAccelerator{
+						{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, MaxVFs: 10},
+						{PCIAddress: otherPCIAddress, PFDriver: utils.PCI_PF_STUB_DASH, MaxVFs: 10},
+					},
+				}, nil
+			}
+			VrbgetSriovInventory = func(_ context.Context, log *logrus.Logger) (*vrbv1.NodeInventory, error) {
+				return &vrbv1.NodeInventory{}, nil
+			}
+
+			sfnc := &sriovv2.SriovFecNodeConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace, Generation: 2},
+				Spec: sriovv2.SriovFecNodeConfigSpec{
+					PhysicalFunctions: []sriovv2.PhysicalFunctionConfigExt{
+						{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, VFDriver: utils.IGB_UIO, VFAmount: 1},
+						{PCIAddress: otherPCIAddress, PFDriver: utils.PCI_PF_STUB_DASH, VFDriver: utils.IGB_UIO, VFAmount: 1},
+					},
+				},
+				Status: sriovv2.SriovFecNodeConfigStatus{
+					AppliedGenerations: map[string]int64{pciAddress: 1, otherPCIAddress: 1, "0000:16:00.0": 1},
+				},
+			}
+			vrbnc := &vrbv1.SriovVrbNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace}}
+			fakeClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(sfnc, vrbnc).Build()
+
+			configurer = &partiallyFailingConfigurer{failingPCIAddress: otherPCIAddress}
+			reconciler = &NodeConfigReconciler{
+				Client:             fakeClient,
+				log:                utils.NewLogger(),
+				nodeNameRef:        nodeNameRef,
+				sriovfecconfigurer: configurer,
+				drainerAndExecute: func(ctx context.Context, configurer func(ctx context.Context) bool, drain bool) error {
+					_ = configurer(ctx)
+					return nil
+				},
+				restartDevicePlugin: func(context.Context) error { return nil },
+			}
+		})
+
+		AfterEach(func() {
+			workdir = originalWorkdir
+			getSriovInventory = originalGetSriovInventory
+			VrbgetSriovInventory = originalVrbgetSriovInventory
+			sysLockdownFilePath = "/sys/kernel/security/lockdown"
+		})
+
+		It("should record generation of PFs configured before the failure", func() {
+			_, _ = reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: nodeNameRef})
+
+			sfnc := getNodeConfig()
+			Expect(sfnc.FindCondition(ConditionConfigured).Reason).To(Equal(string(ConfigurationFailed)))
+			Expect(sfnc.FindCondition(ConditionConfigured).ObservedGeneration).To(BeZero())
+			Expect(sfnc.Status.AppliedGenerations).To(Equal(map[string]int64{pciAddress: 2, otherPCIAddress: 1}))
+			Expect(sfnc.Status.Summary).To(HavePrefix("0/2 PFs configured"))
+		})
+
+		It("should observe generation once every PF is configured with it", func() {
+			_, _ = reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: nodeNameRef})
+			configurer.failingPCIAddress = ""
+
+			_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: nodeNameRef})
+			Expect(err).ToNot(HaveOccurred())
+
+			sfnc := getNodeConfig()
+			Expect(sfnc.FindCondition(ConditionConfigured).Reason).To(Equal(string(ConfigurationSucceeded)))
+			Expect(sfnc.FindCondition(ConditionConfigured).ObservedGeneration).To(Equal(int64(2)))
+			Expect(sfnc.Status.AppliedGenerations).To(Equal(map[string]int64{pciAddress: 2, otherPCIAddress: 2}))
+		})
+	})
+})
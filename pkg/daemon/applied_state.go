@@ -0,0 +1,76 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// specHash returns SHA-256 of node config spec, which is stored in status once the spec is applied to the hardware
+func specHash(spec interface{}) (string, error) {
+	content, err := json.Marshal(spec)
+	if err != nil {
+		return "", fmt.Errorf("failed to calculate hash of node config spec - %v", err)
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// appliedSpecFile returns path of file in state directory which holds the last spec of given kind applied to the hardware
+func appliedSpecFile(kind string) string {
+	return filepath.Join(workdir, fmt.Sprintf("applied-spec-%s.json", kind))
+}
+
+// storeAppliedSpec stores spec applied to the hardware, so that the next spec can be compared with it
+func storeAppliedSpec(kind string, spec interface{}) error {
+	content, err := json.Marshal(spec)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(appliedSpecFile(kind), content, 0644)
+}
+
+// loadAppliedSpec reads spec stored with storeAppliedSpec into spec; returns false when there is no stored spec or
+// it is not the one with appliedHash (e.g. stored by another daemon instance before the node config was recreated)
+func loadAppliedSpec(kind, appliedHash string, spec interface{}) (bool, error) {
+	content, err := os.ReadFile(appliedSpecFile(kind))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	sum := sha256.Sum256(content)
+	if appliedHash == "" || hex.EncodeToString(sum[:]) != appliedHash {
+		return false, nil
+	}
+	return true, json.Unmarshal(content, spec)
+}
+
+// isAppliedSpecOutdated tells whether requested spec differs from the one applied to the hardware. Status written by
+// daemon which did not store applied spec hash yet is checked with observed generation instead.
+func (r *NodeConfigReconciler) isAppliedSpecOutdated(requestedHash, appliedHash string, generation, observedGeneration int64) bool {
+	if appliedHash == "" {
+		if generation != observedGeneration {
+			r.log.WithField("observed", observedGeneration).
+				WithField("requested", generation).
+				Info("Observed generation doesn't reflect requested one")
+			return true
+		}
+		return false
+	}
+
+	if requestedHash != appliedHash {
+		r.log.WithField("applied", appliedHash).
+			WithField("requested", requestedHash).
+			Info("Applied spec doesn't reflect requested one")
+		return true
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	fec "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("AppliedState", func() {
+	var reconciler *NodeConfigReconciler
+
+	nodeConfig := func(generation int64, annotations map[string]string) *fec.SriovFecNodeConfig {
+		return &fec.SriovFecNodeConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "default", Generation: generation, Annotations: annotations},
+		}
+	}
+
+	BeforeEach(func() {
+		reconciler = &NodeConfigReconciler{log: utils.NewLogger(), nodeNameRef: types.NamespacedName{Name: "worker", Namespace: "default"}}
+	})
+
+	Context("nodeConfigPredicate", func() {
+		It("should always pass create event", func() {
+			nc := nodeConfig(3, nil)
+			nc.Status.Conditions = []metav1.Condition{{Type: ConditionConfigured, Reason: string(ConfigurationSucceeded), ObservedGeneration: 3}}
+			Expect(reconciler.nodeConfigPredicate().Create(event.CreateEvent{Object: nc})).To(BeTrue())
+			Expect(reconciler.nodeConfigPredicate().Create(event.CreateEvent{Object: &fec.SriovFecNodeConfig{}})).To(BeFalse(),
+				"node config of another node")
+		})
+
+		It("should pass update event changing generation or control annotation only", func() {
+			update := func(old, new *fec.SriovFecNodeConfig) bool {
+				return reconciler.nodeConfigPredicate().Update(event.UpdateEvent{ObjectOld: old, ObjectNew: new})
+			}
+			halted := map[string]string{fec.ConfigurationHaltedAnnotation: "true"}
+
+			Expect(update(nodeConfig(1, nil), nodeConfig(2, nil))).To(BeTrue())
+			Expect(update(nodeConfig(1, nil), nodeConfig(1, halted))).To(BeTrue())
+			Expect(update(nodeConfig(1, halted), nodeConfig(1, nil))).To(BeTrue())
+			Expect(update(nodeConfig(1, nil), nodeConfig(1, map[string]string{"foo": "bar"}))).To(BeFalse())
+			Expect(update(nodeConfig(1, nil), nodeConfig(1, nil))).To(BeFalse())
+		})
+	})
+
+	Context("isAppliedSpecOutdated", func() {
+		It("should compare applied spec hash", func() {
+			spec := fec.SriovFecNodeConfigSpec{PhysicalFunctions: []fec.PhysicalFunctionConfigExt{{PCIAddress: "0000:f7:00.0", VFAmount: 2}}}
+			applied, err := specHash(spec)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(reconciler.isAppliedSpecOutdated(applied, applied, 2, 1)).To(BeFalse(), "generation is ignored")
+
+			spec.PhysicalFunctions[0].VFAmount = 4
+			requested, err := specHash(spec)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(reconciler.isAppliedSpecOutdated(requested, applied, 1, 1)).To(BeTrue())
+		})
+
+		It("should compare generation when status has no applied spec hash", func() {
+			Expect(reconciler.isAppliedSpecOutdated("hash", "", 1, 1)).To(BeFalse())
+			Expect(reconciler.isAppliedSpecOutdated("hash", "", 2, 1)).To(BeTrue())
+		})
+	})
+
+	It("should reconfigure restored node config with succeeded status when hardware is blank", func() {
+		nc := nodeConfig(1, nil)
+		nc.Spec.PhysicalFunctions = []fec.PhysicalFunctionConfigExt{{PCIAddress: "0000:f7:00.0", VFAmount: 2}}
+		hash, err := specHash(nc.Spec)
+		Expect(err).ToNot(HaveOccurred())
+		nc.Status.AppliedSpecHash = hash
+		nc.Status.Conditions = []metav1.Condition{{Type: ConditionConfigured, Reason: string(ConfigurationSucceeded), ObservedGeneration: 1}}
+
+		blank := &fec.NodeInventory{SriovAccelerators: []fec.SriovAccelerator{{PCIAddress: "0000:f7:00.0"}}}
+		Expect(reconciler.isCardUpdateRequired(context.TODO(), nc, blank, hash)).To(BeTrue())
+	})
+})
@@ -0,0 +1,155 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/drainhelper"
+)
+
+// steps of PF configuration, in order in which they are done
+const (
+	// applyStepCleaned - pristine state is stored, VFs are removed
+	applyStepCleaned = "cleaned"
+	// applyStepPFBound - drivers are loaded, PF is bound to requested driver
+	applyStepPFBound = "pf-bound"
+	// applyStepPfBBConfig - pf_bb_config is started (if requested)
+	applyStepPfBBConfig = "pf-bb-config"
+	// applyStepVFsCreated - requested amount of VFs is created
+	applyStepVFsCreated = "vfs-created"
+	// applyStepDone - VFs are bound to requested driver, or VFs of not requested PF are zeroed
+	applyStepDone = "done"
+)
+
+var (
+	applySteps     = []string{applyStepCleaned, applyStepPFBound, applyStepPfBBConfig, applyStepVFsCreated, applyStepDone}
+	bootIDFilePath = "/proc/sys/kernel/random/boot_id"
+)
+
+// applyCheckpoint records steps of applying the spec which were completed on the hardware, so that the work
+// interrupted by crash of the daemon or by lost drain lease is resumed instead of being started over
+type applyCheckpoint struct {
+	SpecHash string `json:"specHash"`
+	// BootID invalidates the checkpoint after reboot, which reverts all the steps
+	BootID string `json:"bootID"`
+	// Steps maps PCI address of PF to its last completed step
+	Steps map[string]string `json:"steps"`
+
+	kind string
+	log  *logrus.Logger
+}
+
+func applyCheckpointFile(kind string) string {
+	return filepath.Join(workdir, fmt.Sprintf("apply-checkpoint-%s.json", kind))
+}
+
+func readBootID() string {
+	content, err := os.ReadFile(bootIDFilePath)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(content))
+}
+
+// loadApplyCheckpoint returns checkpoint of applying spec of given kind; it is empty unless stored one was recorded
+// for the same spec since the last reboot
+func loadApplyCheckpoint(kind string, spec interface{}, log *logrus.Logger) (*applyCheckpoint, error) {
+	hash, err := specHash(spec)
+	if err != nil {
+		return nil, err
+	}
+	checkpoint := &applyCheckpoint{SpecHash: hash, BootID: readBootID(), Steps: map[string]string{}, kind: kind, log: log}
+
+	content, err := os.ReadFile(applyCheckpointFile(kind))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.WithError(err).Warn("failed to read apply checkpoint - starting over")
+		}
+		return checkpoint, nil
+	}
+	stored := applyCheckpoint{}
+	if err := json.Unmarshal(content, &stored); err != nil {
+		log.WithError(err).Warn("invalid apply checkpoint - starting over")
+		return checkpoint, nil
+	}
+	if stored.SpecHash != checkpoint.SpecHash || stored.BootID != checkpoint.BootID || checkpoint.BootID == "" {
+		log.Info("apply checkpoint is outdated - starting over")
+		return checkpoint, nil
+	}
+	if len(stored.Steps) > 0 {
+		log.WithField("steps", stored.Steps).Info("resuming interrupted configuration")
+		checkpoint.Steps = stored.Steps
+	}
+	return checkpoint, nil
+}
+
+func applyStepIndex(step string) int {
+	for i, s := range applySteps {
+		if s == step {
+			return i
+		}
+	}
+	return -1
+}
+
+// completed tells whether given step of PF was already completed
+func (c *applyCheckpoint) completed(pciAddress, step string) bool {
+	last, ok := c.Steps[pciAddress]
+	return ok && applyStepIndex(last) >= applyStepIndex(step)
+}
+
+// reset forgets completed steps of PF, so that it is configured from scratch
+func (c *applyCheckpoint) reset(pciAddress string) {
+	delete(c.Steps, pciAddress)
+}
+
+// complete records completed step of PF and reports it as progress. Failure of storing the checkpoint is only logged,
+// it just prevents resuming.
+func (c *applyCheckpoint) complete(ctx context.Context, pciAddress, step string) {
+	c.Steps[pciAddress] = step
+	if content, err := json.Marshal(c); err != nil {
+		c.log.WithError(err).Warn("failed to marshal apply checkpoint")
+	} else if err := os.WriteFile(applyCheckpointFile(c.kind), content, 0644); err != nil {
+		c.log.WithError(err).Warn("failed to store apply checkpoint")
+	}
+	drainhelper.ReportProgress(ctx, fmt.Sprintf("%s %s", pciAddress, step))
+}
+
+// interrupted returns error if work should not be continued, e.g. because drain lease was lost
+func (c *applyCheckpoint) interrupted(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("configuration interrupted, it will be resumed - %v", err)
+	}
+	return nil
+}
+
+// finish removes the checkpoint once the work is done, or failed (not interrupted) - next attempt starts over
+func (c *applyCheckpoint) finish(ctx context.Context, err error) {
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+	if err := os.Remove(applyCheckpointFile(c.kind)); err != nil && !os.IsNotExist(err) {
+		c.log.WithError(err).Warn("failed to remove apply checkpoint")
+	}
+}
+
+// resetIfPfBBConfigIsGone configures PF from scratch, when pf_bb_config started by interrupted work is not running
+// anymore (e.g. it was terminated together with the daemon container)
+func (c *applyCheckpoint) resetIfPfBBConfigIsGone(pciAddress string, pfBBConfigRequested bool) {
+	if !pfBBConfigRequested || !c.completed(pciAddress, applyStepPfBBConfig) {
+		return
+	}
+	if pids, err := findPfBBConfigProcesses(pciAddress); err != nil || len(pids) == 0 {
+		c.log.WithField("pci", pciAddress).Info("pf_bb_config is not running - configuring PF from scratch")
+		c.reset(pciAddress)
+	}
+}
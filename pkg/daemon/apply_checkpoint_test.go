@@ -0,0 +1,108 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("applyCheckpoint", func() {
+	const pf = "0000:f7:00.0"
+
+	var (
+		originalWorkdir        = workdir
+		originalBootIDFilePath = bootIDFilePath
+		originalProcPath       = procPath
+		spec                   sriovv2.SriovFecNodeConfigSpec
+	)
+
+	setBootID := func(bootID string) {
+		Expect(os.WriteFile(bootIDFilePath, []byte(bootID+"\n"), 0644)).To(Succeed())
+	}
+
+	interruptedCheckpoint := func() {
+		checkpoint, err := loadApplyCheckpoint(hitlessUpdateKindFec, spec, utils.NewLogger())
+		Expect(err).ToNot(HaveOccurred())
+		checkpoint.complete(context.TODO(), pf, applyStepPfBBConfig)
+	}
+
+	loadSteps := func() map[string]string {
+		checkpoint, err := loadApplyCheckpoint(hitlessUpdateKindFec, spec, utils.NewLogger())
+		Expect(err).ToNot(HaveOccurred())
+		return checkpoint.Steps
+	}
+
+	BeforeEach(func() {
+		root, err := os.MkdirTemp(testTmpFolder, "checkpoint")
+		Expect(err).ToNot(HaveOccurred())
+		workdir = root
+		bootIDFilePath = filepath.Join(root, "boot_id")
+		procPath = filepath.Join(root, "proc")
+		Expect(os.MkdirAll(procPath, 0755)).To(Succeed())
+		setBootID("boot-1")
+		spec = sriovv2.SriovFecNodeConfigSpec{PhysicalFunctions: []sriovv2.PhysicalFunctionConfigExt{{PCIAddress: pf, VFAmount: 2}}}
+	})
+
+	AfterEach(func() {
+		workdir = originalWorkdir
+		bootIDFilePath = originalBootIDFilePath
+		procPath = originalProcPath
+	})
+
+	It("should resume steps completed for the same spec since the last boot", func() {
+		interruptedCheckpoint()
+
+		Expect(loadSteps()).To(Equal(map[string]string{pf: applyStepPfBBConfig}))
+	})
+
+	It("should start over after reboot", func() {
+		interruptedCheckpoint()
+		setBootID("boot-2")
+
+		Expect(loadSteps()).To(BeEmpty())
+	})
+
+	It("should start over when spec was changed", func() {
+		interruptedCheckpoint()
+		spec.PhysicalFunctions[0].VFAmount = 4
+
+		Expect(loadSteps()).To(BeEmpty())
+	})
+
+	It("should be kept only when the work was interrupted", func() {
+		interruptedCheckpoint()
+		checkpoint, err := loadApplyCheckpoint(hitlessUpdateKindFec, spec, utils.NewLogger())
+		Expect(err).ToNot(HaveOccurred())
+
+		ctx, cancel := context.WithCancel(context.TODO())
+		cancel()
+		interrupted := checkpoint.interrupted(ctx)
+		Expect(interrupted).To(HaveOccurred())
+		checkpoint.finish(ctx, interrupted)
+		Expect(applyCheckpointFile(hitlessUpdateKindFec)).To(BeAnExistingFile())
+
+		checkpoint.finish(context.TODO(), os.ErrPermission)
+		Expect(applyCheckpointFile(hitlessUpdateKindFec)).ToNot(BeAnExistingFile())
+	})
+
+	It("should configure PF from scratch when pf_bb_config it started is not running anymore", func() {
+		interruptedCheckpoint()
+		checkpoint, err := loadApplyCheckpoint(hitlessUpdateKindFec, spec, utils.NewLogger())
+		Expect(err).ToNot(HaveOccurred())
+
+		checkpoint.resetIfPfBBConfigIsGone(pf, false)
+		Expect(checkpoint.completed(pf, applyStepPfBBConfig)).To(BeTrue(), "pf_bb_config is not used by PF")
+
+		checkpoint.resetIfPfBBConfigIsGone(pf, true)
+		Expect(checkpoint.completed(pf, applyStepCleaned)).To(BeFalse())
+	})
+})
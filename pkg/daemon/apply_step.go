@@ -0,0 +1,99 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// configurationWarningsReason is reason of event listing failures of best-effort configuration steps
+const configurationWarningsReason = "ConfigurationWarnings"
+
+// applyStepClass tells how failure of configuration step affects configuration of the node
+type applyStepClass int
+
+const (
+	// criticalStep - failure fails the configuration, as accelerator would not be usable
+	criticalStep applyStepClass = iota
+	// bestEffortStep - failure is reported as a warning and the configuration continues
+	bestEffortStep
+)
+
+// applyStep describes single step of applying the spec to a PF
+type applyStep struct {
+	name  string
+	class applyStepClass
+}
+
+// steps of applying the spec; class of each step is decided here, not at the place it is run
+var (
+	stepStorePristineState       = applyStep{name: "store pristine state", class: criticalStep}
+	stepCleanAcceleratorConfig   = applyStep{name: "clean accelerator config", class: criticalStep}
+	stepRestoreOriginalDriver    = applyStep{name: "restore original driver", class: criticalStep}
+	stepLoadDrivers              = applyStep{name: "load drivers", class: criticalStep}
+	stepSetInterruptMode         = applyStep{name: "set interrupt mode", class: criticalStep}
+	stepBindPF                   = applyStep{name: "bind PF to driver", class: criticalStep}
+	stepConfigureCommandRegister = applyStep{name: "configure PCI command register", class: criticalStep}
+	stepReadBBDevConfigFrom      = applyStep{name: "read bbDevConfigFrom", class: criticalStep}
+	stepProvisionFFTLut          = applyStep{name: "provision FFT LUT", class: criticalStep}
+	stepStartPfBBConfig          = applyStep{name: "start pf_bb_config", class: criticalStep}
+	stepCreateVFs                = applyStep{name: "create VFs", class: criticalStep}
+	stepPublishPredictedVFs      = applyStep{name: "publish predicted VF addresses", class: bestEffortStep}
+	stepBindVFs                  = applyStep{name: "bind VFs to driver", class: criticalStep}
+)
+
+// applyWarnings collects failures of best-effort steps of single configuration
+type applyWarnings struct {
+	mu       sync.Mutex
+	warnings []string
+}
+
+func (w *applyWarnings) add(warning string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.warnings = append(w.warnings, warning)
+}
+
+func (w *applyWarnings) list() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]string(nil), w.warnings...)
+}
+
+type applyWarningsKey struct{}
+
+// withApplyWarnings returns context which steps run with record failures of best-effort steps to w
+func withApplyWarnings(ctx context.Context, w *applyWarnings) context.Context {
+	return context.WithValue(ctx, applyWarningsKey{}, w)
+}
+
+// runStep runs step of configuration of the PF. Failure of critical step is returned; failure of best-effort step is
+// logged and recorded as a warning in the context, nil is returned then.
+func (n *NodeConfigurator) runStep(ctx context.Context, step applyStep, pciAddress string, run func() error) error {
+	err := run()
+	if err == nil || step.class == criticalStep {
+		return err
+	}
+
+	n.Log.WithError(err).WithField("pci", pciAddress).WithField("step", step.name).Warn("best-effort configuration step failed - continuing")
+	if w, ok := ctx.Value(applyWarningsKey{}).(*applyWarnings); ok {
+		w.add(fmt.Sprintf("%s of %s failed - %v", step.name, pciAddress, err))
+	}
+	return nil
+}
+
+// reportApplyWarnings emits event listing failures of best-effort steps of the configuration; returned warnings are
+// exposed in status of the node config
+func (r *NodeConfigReconciler) reportApplyWarnings(nodeConfig runtime.Object, warnings []string) []string {
+	if len(warnings) > 0 && r.recorder != nil {
+		r.recorder.Event(nodeConfig, corev1.EventTypeWarning, configurationWarningsReason, strings.Join(warnings, "; "))
+	}
+	return warnings
+}
@@ -0,0 +1,52 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("NodeConfigurator.runStep", func() {
+	const pf = "0000:f7:00.0"
+
+	var (
+		configurator = &NodeConfigurator{Log: utils.NewLogger()}
+		failure      = errors.New("write error")
+	)
+
+	It("returns failure of critical step without recording a warning", func() {
+		warnings := &applyWarnings{}
+		ctx := withApplyWarnings(context.TODO(), warnings)
+
+		Expect(configurator.runStep(ctx, stepBindPF, pf, func() error { return failure })).To(MatchError(failure))
+		Expect(warnings.list()).To(BeEmpty())
+	})
+
+	It("records failure of best-effort step as a warning and continues", func() {
+		warnings := &applyWarnings{}
+		ctx := withApplyWarnings(context.TODO(), warnings)
+
+		Expect(configurator.runStep(ctx, stepPublishPredictedVFs, pf, func() error { return failure })).To(Succeed())
+		Expect(configurator.runStep(ctx, stepStorePristineState, pf, func() error { return nil })).To(Succeed())
+		Expect(warnings.list()).To(Equal([]string{"publish predicted VF addresses of 0000:f7:00.0 failed - write error"}))
+
+		// without collector failure is only logged
+		Expect(configurator.runStep(context.TODO(), stepPublishPredictedVFs, pf, func() error { return failure })).To(Succeed())
+	})
+
+	It("classifies only steps which do not affect usability of the accelerator as best-effort", func() {
+		Expect(stepPublishPredictedVFs.class).To(Equal(bestEffortStep))
+		// original driver could not be restored on teardown without pristine state
+		for _, step := range []applyStep{stepStorePristineState, stepCleanAcceleratorConfig, stepRestoreOriginalDriver, stepLoadDrivers, stepSetInterruptMode, stepBindPF,
+			stepConfigureCommandRegister, stepReadBBDevConfigFrom, stepProvisionFFTLut, stepStartPfBBConfig, stepCreateVFs, stepBindVFs} {
+			Expect(step.class).To(Equal(criticalStep), step.name)
+		}
+	})
+})
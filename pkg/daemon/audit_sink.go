@@ -0,0 +1,193 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	auditLogConfigMapPrefix = "sriov-fec-audit-"
+	auditLogKey             = "audit.log"
+	// maxAuditLogEntries and maxAuditLogBytes cap the audit log; oldest entries are rotated out first
+	maxAuditLogEntries = 1000
+	maxAuditLogBytes   = 512 * 1024
+
+	auditActionDrain             = "drain"
+	auditActionBind              = "bind"
+	auditActionNumVFs            = "numvfs"
+	auditActionPfBBConfigRestart = "pf-bb-config-restart"
+	auditResultSuccess           = "success"
+
+	auditKindFec = "SriovFecNodeConfig"
+	auditKindVrb = "SriovVrbNodeConfig"
+)
+
+// auditEntry describes single hardware-affecting action; entries are stored one per line in JSON format
+type auditEntry struct {
+	Time       time.Time `json:"time"`
+	Kind       string    `json:"kind"`
+	Generation int64     `json:"generation"`
+	Action     string    `json:"action"`
+	Target     string    `json:"target"`
+	Value      string    `json:"value,omitempty"`
+	Result     string    `json:"result"`
+	Duration   string    `json:"duration"`
+}
+
+// AuditSink records hardware-affecting actions of the daemon in size-capped, append-only log kept in
+// sriov-fec-audit-<node> ConfigMap. Actions performed while applying node config are buffered and written, stamped with
+// kind and generation of the node config, once the configuration completes. Nil AuditSink records nothing.
+type AuditSink struct {
+	client      client.Client
+	nodeNameRef types.NamespacedName
+	log         *logrus.Logger
+
+	mu      sync.Mutex
+	pending []auditEntry
+}
+
+func NewAuditSink(c client.Client, nodeNameRef types.NamespacedName, log *logrus.Logger) *AuditSink {
+	return &AuditSink{client: c, nodeNameRef: nodeNameRef, log: log}
+}
+
+// observe buffers action which started at given time and finished with err
+func (a *AuditSink) observe(action, target, value string, started time.Time, err error) {
+	if a == nil {
+		return
+	}
+	result := auditResultSuccess
+	if err != nil {
+		result = err.Error()
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.pending = append(a.pending, auditEntry{
+		Time:     started.UTC(),
+		Action:   action,
+		Target:   target,
+		Value:    value,
+		Result:   result,
+		Duration: time.Since(started).Round(time.Millisecond).String(),
+	})
+}
+
+// commit writes buffered actions as performed for given generation of node config of given kind. Failure is only
+// logged, as audit must not fail the configuration.
+func (a *AuditSink) commit(ctx context.Context, kind string, generation int64) {
+	if a == nil {
+		return
+	}
+
+	a.mu.Lock()
+	entries := a.pending
+	a.pending = nil
+	a.mu.Unlock()
+
+	if len(entries) == 0 {
+		return
+	}
+
+	lines := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		entry.Kind, entry.Generation = kind, generation
+		line, err := json.Marshal(entry)
+		if err != nil {
+			a.log.WithError(err).Error("failed to marshal audit entry")
+			continue
+		}
+		lines = append(lines, string(line))
+	}
+
+	// actions were performed regardless of whether reconcile was cancelled meanwhile, so they are recorded anyway
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), apiCallTimeout)
+	defer cancel()
+	if err := a.append(ctx, lines); err != nil {
+		a.log.WithError(err).WithField("entries", len(lines)).Error("failed to write audit log")
+	}
+}
+
+func (a *AuditSink) append(ctx context.Context, lines []string) error {
+	name := auditLogName(a.nodeNameRef.Name)
+	retriable := func(err error) bool {
+		return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err)
+	}
+
+	return retry.OnError(retry.DefaultRetry, retriable, func() error {
+		cm := &corev1.ConfigMap{}
+		err := a.client.Get(ctx, client.ObjectKey{Namespace: a.nodeNameRef.Namespace, Name: name}, cm)
+		if apierrors.IsNotFound(err) {
+			return a.client.Create(ctx, &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: a.nodeNameRef.Namespace, Name: name},
+				Data:       map[string]string{auditLogKey: rotateAuditLog(nil, lines)},
+			})
+		}
+		if err != nil {
+			return err
+		}
+
+		var existing []string
+		if content := cm.Data[auditLogKey]; content != "" {
+			existing = strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+		}
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[auditLogKey] = rotateAuditLog(existing, lines)
+		return a.client.Update(ctx, cm)
+	})
+}
+
+// rotateAuditLog appends lines to existing ones and drops the oldest lines exceeding maxAuditLogEntries or
+// maxAuditLogBytes
+func rotateAuditLog(existing, lines []string) string {
+	all := append(existing, lines...)
+	size := 0
+	for _, line := range all {
+		size += len(line) + 1
+	}
+	for len(all) > 0 && (len(all) > maxAuditLogEntries || size > maxAuditLogBytes) {
+		size -= len(all[0]) + 1
+		all = all[1:]
+	}
+	if len(all) == 0 {
+		return ""
+	}
+	return strings.Join(all, "\n") + "\n"
+}
+
+// auditLogName returns name of ConfigMap holding audit log of the node
+func auditLogName(nodeName string) string {
+	return fmt.Sprintf("%s%s", auditLogConfigMapPrefix, nodeName)
+}
+
+// auditedDrainAndExecute runs drainerAndExecute and records the drain of the node in audit log
+func (r *NodeConfigReconciler) auditedDrainAndExecute(ctx context.Context, configurer func(ctx context.Context) bool, drain bool) error {
+	started, drained := time.Now(), false
+	err := r.drainerAndExecute(ctx, func(ctx context.Context) bool {
+		if drain {
+			drained = true
+			r.audit.observe(auditActionDrain, r.nodeNameRef.Name, "", started, nil)
+		}
+		return configurer(ctx)
+	}, drain)
+	if drain && !drained && err != nil {
+		r.audit.observe(auditActionDrain, r.nodeNameRef.Name, "", started, err)
+	}
+	return err
+}
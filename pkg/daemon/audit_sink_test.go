@@ -0,0 +1,133 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("AuditSink", func() {
+	var (
+		nodeRef = types.NamespacedName{Name: "worker", Namespace: "default"}
+		c       client.Client
+		sink    *AuditSink
+	)
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		c = fake.NewClientBuilder().WithScheme(scheme).Build()
+		sink = NewAuditSink(c, nodeRef, utils.NewLogger())
+	})
+
+	auditEntries := func() []auditEntry {
+		cm := &corev1.ConfigMap{}
+		Expect(c.Get(context.TODO(), client.ObjectKey{Namespace: nodeRef.Namespace, Name: "sriov-fec-audit-worker"}, cm)).To(Succeed())
+		var entries []auditEntry
+		for _, line := range strings.Split(strings.TrimSuffix(cm.Data[auditLogKey], "\n"), "\n") {
+			entry := auditEntry{}
+			Expect(json.Unmarshal([]byte(line), &entry)).To(Succeed())
+			entries = append(entries, entry)
+		}
+		return entries
+	}
+
+	It("should append observed actions stamped with node config generation once committed", func() {
+		started := time.Now()
+		sink.observe(auditActionBind, "0000:f7:00.0", utils.VFIO_PCI, started, nil)
+		sink.observe(auditActionNumVFs, "0000:f7:00.0", "16", started, errors.New("write error"))
+		sink.commit(context.TODO(), auditKindFec, 3)
+
+		entries := auditEntries()
+		Expect(entries).To(HaveLen(2))
+		Expect(entries[0].Kind).To(Equal("SriovFecNodeConfig"))
+		Expect(entries[0].Generation).To(BeEquivalentTo(3))
+		Expect(entries[0].Action).To(Equal("bind"))
+		Expect(entries[0].Target).To(Equal("0000:f7:00.0"))
+		Expect(entries[0].Value).To(Equal(utils.VFIO_PCI))
+		Expect(entries[0].Result).To(Equal("success"))
+		Expect(entries[1].Result).To(Equal("write error"))
+
+		sink.observe(auditActionPfBBConfigRestart, "0000:f7:00.0", "", started, nil)
+		sink.commit(context.TODO(), auditKindVrb, 1)
+		entries = auditEntries()
+		Expect(entries).To(HaveLen(3))
+		Expect(entries[2].Kind).To(Equal("SriovVrbNodeConfig"))
+
+		// nothing pending - nothing written
+		sink.commit(context.TODO(), auditKindFec, 4)
+		Expect(auditEntries()).To(HaveLen(3))
+	})
+
+	It("should record drain before actions performed on drained node", func() {
+		reconciler := &NodeConfigReconciler{
+			nodeNameRef: nodeRef,
+			audit:       sink,
+			drainerAndExecute: func(_ context.Context, configurer func(ctx context.Context) bool, drain bool) error {
+				_ = configurer(context.TODO())
+				return nil
+			},
+		}
+		Expect(reconciler.auditedDrainAndExecute(context.TODO(), func(ctx context.Context) bool {
+			sink.observe(auditActionNumVFs, "0000:f7:00.0", "16", time.Now(), nil)
+			return true
+		}, true)).To(Succeed())
+		sink.commit(context.TODO(), auditKindFec, 1)
+
+		entries := auditEntries()
+		Expect(entries).To(HaveLen(2))
+		Expect(entries[0].Action).To(Equal("drain"))
+		Expect(entries[0].Target).To(Equal("worker"))
+		Expect(entries[1].Action).To(Equal("numvfs"))
+
+		reconciler.drainerAndExecute = func(context.Context, func(ctx context.Context) bool, bool) error { return errors.New("drain failed") }
+		Expect(reconciler.auditedDrainAndExecute(context.TODO(), func(ctx context.Context) bool { return true }, true)).ToNot(Succeed())
+		sink.commit(context.TODO(), auditKindFec, 2)
+		Expect(auditEntries()[2].Result).To(Equal("drain failed"))
+	})
+
+	It("should not fail when audit log cannot be written", func() {
+		sink = NewAuditSink(fake.NewClientBuilder().WithScheme(runtime.NewScheme()).Build(), nodeRef, utils.NewLogger())
+		sink.observe(auditActionBind, "0000:f7:00.0", utils.VFIO_PCI, time.Now(), nil)
+		Expect(func() { sink.commit(context.TODO(), auditKindFec, 1) }).ToNot(Panic())
+		Expect(sink.pending).To(BeEmpty())
+	})
+
+	It("should record nothing when disabled", func() {
+		var disabled *AuditSink
+		disabled.observe(auditActionBind, "0000:f7:00.0", utils.VFIO_PCI, time.Now(), nil)
+		disabled.commit(context.TODO(), auditKindFec, 1)
+	})
+
+	It("should rotate the oldest entries out", func() {
+		var existing []string
+		for i := 0; i < maxAuditLogEntries; i++ {
+			existing = append(existing, fmt.Sprintf("entry-%d", i))
+		}
+		rotated := strings.Split(strings.TrimSuffix(rotateAuditLog(existing, []string{"new-1", "new-2"}), "\n"), "\n")
+		Expect(rotated).To(HaveLen(maxAuditLogEntries))
+		Expect(rotated[0]).To(Equal("entry-2"))
+		Expect(rotated[len(rotated)-1]).To(Equal("new-2"))
+
+		large := strings.Repeat("x", maxAuditLogBytes/2)
+		rotated = strings.Split(strings.TrimSuffix(rotateAuditLog([]string{large, large}, []string{"new"}), "\n"), "\n")
+		Expect(rotated).To(Equal([]string{large, "new"}))
+	})
+})
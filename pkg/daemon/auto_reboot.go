@@ -0,0 +1,356 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	fec "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/drainhelper"
+)
+
+const (
+	// AutoRebootRequestedAtAnnotation of the node is time of the last reboot requested by autoRebootOnPersistentFailure;
+	// it survives the reboot, so that the cooldown of the policy is kept
+	AutoRebootRequestedAtAnnotation = "sriovfec.intel.com/auto-reboot-requested-at"
+	// AutoRebootDeviceAnnotation of the node is PCI address of PF which triggered the last reboot
+	AutoRebootDeviceAnnotation = "sriovfec.intel.com/auto-reboot-device"
+	// AutoRebootBootIDAnnotation of the node is boot ID of the host which reboot was requested on; reboot is pending
+	// until the boot ID changes
+	AutoRebootBootIDAnnotation = "sriovfec.intel.com/auto-reboot-boot-id"
+	// AutoRebootStoppedPFsAnnotation of the node is comma-separated list of PCI addresses of PFs which pf-bb-config was
+	// stopped before the reboot; these PFs are reconfigured after it, see pfBBConfigsStoppedForReboot
+	AutoRebootStoppedPFsAnnotation = "sriovfec.intel.com/auto-reboot-stopped-pfs"
+
+	rebootRequestedEvent = "RebootRequested"
+	auditActionReboot    = "reboot"
+)
+
+var (
+	// rebootCommand reboots the host; it is replaced by tests
+	rebootCommand = []string{"chroot", hostRoot, "systemctl", "reboot"}
+	// stopPfBBConfigBeforeReboot stops pf-bb-config of the PF and returns PIDs which had to be killed; it is replaced
+	// by tests
+	stopPfBBConfigBeforeReboot = stopPfBBConfig
+)
+
+// rebootRequestedError is returned by configuration which requested reboot of the node
+type rebootRequestedError struct {
+	pciAddress string
+	failures   int
+	threshold  int
+}
+
+func (e *rebootRequestedError) Error() string {
+	return fmt.Sprintf("pf-bb-config of PF %s failed %d times in a row (threshold %d) - node reboot requested",
+		e.pciAddress, e.failures, e.threshold)
+}
+
+// rebootDeferredError is returned by configuration which did not reboot the node, as the reboot lock was held by
+// other holder; count of failures is kept, so that reboot is requested again by the next configuration
+type rebootDeferredError struct {
+	pciAddress string
+	err        error
+}
+
+func (e *rebootDeferredError) Error() string {
+	return fmt.Sprintf("reboot on repeated pf-bb-config failures of PF %s is deferred - %v", e.pciAddress, e.err)
+}
+
+// pfBBConfigOutcomes collects results of pf-bb-config started by single configuration, by PF
+type pfBBConfigOutcomes struct {
+	mu     sync.Mutex
+	failed map[string]bool
+}
+
+func (o *pfBBConfigOutcomes) add(pciAddress string, failed bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.failed == nil {
+		o.failed = map[string]bool{}
+	}
+	o.failed[pciAddress] = failed
+}
+
+func (o *pfBBConfigOutcomes) list() map[string]bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	outcomes := make(map[string]bool, len(o.failed))
+	for pciAddress, failed := range o.failed {
+		outcomes[pciAddress] = failed
+	}
+	return outcomes
+}
+
+type pfBBConfigOutcomesKey struct{}
+
+// withPfBBConfigOutcomes returns context with which configuration records results of pf-bb-config to o
+func withPfBBConfigOutcomes(ctx context.Context, o *pfBBConfigOutcomes) context.Context {
+	return context.WithValue(ctx, pfBBConfigOutcomesKey{}, o)
+}
+
+// recordPfBBConfigOutcome records result of pf-bb-config of PF in the context; it is no-op when context has no collector
+func recordPfBBConfigOutcome(ctx context.Context, pciAddress string, err error) {
+	if o, ok := ctx.Value(pfBBConfigOutcomesKey{}).(*pfBBConfigOutcomes); ok {
+		o.add(pciAddress, err != nil)
+	}
+}
+
+// countPfBBConfigFailures returns consecutive pf-bb-config failures of PFs with outcomes of the last configuration
+// counted; PFs which are not requested anymore are dropped. Nothing is counted while the policy is disabled.
+func countPfBBConfigFailures(policy *fec.AutoRebootPolicy, failures map[string]int, outcomes map[string]bool, requested []string) map[string]int {
+	if policy == nil || !policy.Enabled {
+		return nil
+	}
+	counted := map[string]int{}
+	for _, pciAddress := range requested {
+		if count, ok := failures[pciAddress]; ok {
+			counted[pciAddress] = count
+		}
+		if failed, ok := outcomes[pciAddress]; ok {
+			if failed {
+				counted[pciAddress]++
+			} else {
+				delete(counted, pciAddress)
+			}
+		}
+	}
+	if len(counted) == 0 {
+		return nil
+	}
+	return counted
+}
+
+// persistentlyFailingPF returns PF with the most consecutive pf-bb-config failures, if they reach the threshold
+func persistentlyFailingPF(failures map[string]int, threshold int) (string, bool) {
+	pciAddresses := make([]string, 0, len(failures))
+	for pciAddress := range failures {
+		pciAddresses = append(pciAddresses, pciAddress)
+	}
+	sort.Strings(pciAddresses)
+
+	var found string
+	for _, pciAddress := range pciAddresses {
+		if failures[pciAddress] >= threshold && (found == "" || failures[pciAddress] > failures[found]) {
+			found = pciAddress
+		}
+	}
+	return found, found != ""
+}
+
+// rebootOnPersistentFailure reboots the node, when pf-bb-config of a PF failed as many times in a row as the policy
+// allows. Reboot is requested at most once per cooldown of the policy - time of the request and boot ID of the host
+// are kept in node annotations, which are set before the reboot; reboot is not performed when they cannot be set.
+// Decision is logged, audited, emitted as event and reported with updateStatus before the reboot; count of the PF is
+// reset then. pf-bb-config of requested PFs is stopped before the reboot command, which runs within the drain lease
+// held by the caller; the PFs are recorded in AutoRebootStoppedPFsAnnotation, so that they are reconfigured after
+// the reboot. Reboot lock is acquired before the node is annotated and held until awaitingReboot observes
+// new boot ID; rebootDeferredError is returned when it is not acquired within its timeout. rebootRequestedError is
+// returned once reboot is requested; boot ID annotation is removed and the lock is released when the reboot command
+// fails, so that the node is not considered to be waiting for the reboot.
+func (r *NodeConfigReconciler) rebootOnPersistentFailure(ctx context.Context, nc client.Object, kind string,
+	policy *fec.AutoRebootPolicy, failures map[string]int, requested []string, updateStatus func(msg string) error) error {
+
+	if policy == nil || !policy.Enabled {
+		return nil
+	}
+	pciAddress, found := persistentlyFailingPF(failures, policy.EffectiveThreshold())
+	if !found {
+		return nil
+	}
+	log := r.log.WithField("pciAddress", pciAddress).WithField("failures", failures[pciAddress])
+
+	bootID := readBootID()
+	if bootID == "" {
+		log.Error("boot ID of the host is unknown - reboot on persistent pf-bb-config failure is not requested")
+		return nil
+	}
+	node := &corev1.Node{}
+	if err := r.Get(ctx, client.ObjectKey{Name: r.nodeNameRef.Name}, node); err != nil {
+		log.WithError(err).Error("failed to get the node - reboot on persistent pf-bb-config failure is not requested")
+		return nil
+	}
+	now := daemonClock.Now()
+	if requestedAt, err := time.Parse(time.RFC3339, node.GetAnnotations()[AutoRebootRequestedAtAnnotation]); err == nil {
+		if next := requestedAt.Add(policy.EffectiveCooldown()); now.Before(next) {
+			log.WithField("requestedAt", requestedAt).WithField("cooldownUntil", next).
+				Warn("pf-bb-config keeps failing, but reboot was requested recently - not rebooting until cooldown passes")
+			return nil
+		}
+	}
+
+	if err := r.rebootLock.acquire(ctx); err != nil {
+		log.WithError(err).Warn("pf-bb-config keeps failing, but reboot lock is not available - reboot is deferred")
+		return &rebootDeferredError{pciAddress: pciAddress, err: err}
+	}
+
+	original := node.DeepCopy()
+	if node.Annotations == nil {
+		node.Annotations = map[string]string{}
+	}
+	node.Annotations[AutoRebootRequestedAtAnnotation] = now.UTC().Format(time.RFC3339)
+	node.Annotations[AutoRebootDeviceAnnotation] = pciAddress
+	node.Annotations[AutoRebootBootIDAnnotation] = bootID
+	node.Annotations[AutoRebootStoppedPFsAnnotation] = strings.Join(sortedCopy(requested), ",")
+	if err := r.Patch(ctx, node, client.MergeFrom(original)); err != nil {
+		log.WithError(err).Error("failed to annotate the node - reboot on persistent pf-bb-config failure is not requested")
+		r.releaseRebootLock(ctx)
+		return nil
+	}
+
+	rebootRequested := &rebootRequestedError{pciAddress: pciAddress, failures: failures[pciAddress], threshold: policy.EffectiveThreshold()}
+	delete(failures, pciAddress)
+	log.Warn("rebooting the node: " + rebootRequested.Error())
+	if r.recorder != nil {
+		r.recorder.Event(nc, corev1.EventTypeWarning, rebootRequestedEvent, rebootRequested.Error())
+	}
+	if err := updateStatus(rebootRequested.Error()); err != nil {
+		log.WithError(err).Warn("failed to report requested reboot")
+	}
+	// reboot may terminate the daemon before configuration completes, so that the decision is written right away
+	r.audit.observe(auditActionReboot, pciAddress, strconv.Itoa(rebootRequested.failures)+" pf-bb-config failures", now, nil)
+	r.audit.commit(ctx, kind, nc.GetGeneration())
+
+	// devices are not left to pf-bb-config which is killed at an arbitrary point of the shutdown
+	for _, requestedPCIAddress := range requested {
+		killed, err := stopPfBBConfigBeforeReboot(requestedPCIAddress, r.log)
+		if err != nil {
+			log.WithError(err).WithField("stoppedPciAddress", requestedPCIAddress).Warn("failed to stop pf-bb-config before the reboot")
+		}
+		if len(killed) > 0 {
+			log.WithField("stoppedPciAddress", requestedPCIAddress).WithField("pids", killed).
+				Warn("pf-bb-config did not exit on SIGTERM before the reboot - killed")
+		}
+	}
+
+	if _, err := runExecCmd(ctx, rebootCommand, r.log); err != nil {
+		r.audit.observe(auditActionReboot, pciAddress, "", now, err)
+		patched := node.DeepCopy()
+		delete(patched.Annotations, AutoRebootBootIDAnnotation)
+		if err := r.Patch(context.WithoutCancel(ctx), patched, client.MergeFrom(node)); err != nil {
+			log.WithError(err).Error("failed to remove boot ID annotation of the node after failed reboot")
+		}
+		r.releaseRebootLock(context.WithoutCancel(ctx))
+		return fmt.Errorf("failed to reboot the node after repeated pf-bb-config failures of PF %s - %v", pciAddress, err)
+	}
+	return rebootRequested
+}
+
+// awaitingReboot tells whether reboot requested by rebootOnPersistentFailure has not happened yet, i.e. boot ID
+// of the host is still the annotated one. Once it has changed, the annotation and reboot-pending mark set by the
+// drain are removed from the node and the reboot lock is released. Node which cannot be read is not considered to be
+// waiting for reboot.
+func (r *NodeConfigReconciler) awaitingReboot(ctx context.Context) bool {
+	node := &corev1.Node{}
+	if err := r.Get(ctx, client.ObjectKey{Name: r.nodeNameRef.Name}, node); err != nil {
+		r.log.WithError(err).Warn("failed to get the node - requested reboot is not checked")
+		return false
+	}
+	requestedOn, ok := node.GetAnnotations()[AutoRebootBootIDAnnotation]
+	if !ok {
+		return false
+	}
+	bootID := readBootID()
+	if bootID == requestedOn {
+		r.log.WithField("bootID", bootID).Info("node reboot was requested and has not happened yet - configuration is postponed")
+		return true
+	}
+
+	original := node.DeepCopy()
+	delete(node.Annotations, AutoRebootBootIDAnnotation)
+	delete(node.Annotations, drainhelper.RebootPendingAnnotation)
+	if err := r.Patch(ctx, node, client.MergeFrom(original)); err != nil {
+		r.log.WithError(err).Warn("failed to clear pending reboot of the node")
+	}
+	r.releaseRebootLock(ctx)
+	r.log.WithField("bootID", bootID).Info("node rebooted as requested")
+	return false
+}
+
+// pfBBConfigsStoppedForReboot returns PFs recorded in AutoRebootStoppedPFsAnnotation of the node, i.e. PFs which
+// pf-bb-config was stopped before the reboot and has not been run since then. They are reconfigured even when
+// configuration found on the node matches the spec. Node which cannot be read has no such PFs.
+func (r *NodeConfigReconciler) pfBBConfigsStoppedForReboot(ctx context.Context) map[string]bool {
+	node := &corev1.Node{}
+	if err := r.Get(ctx, client.ObjectKey{Name: r.nodeNameRef.Name}, node); err != nil {
+		r.log.WithError(err).Warn("failed to get the node - PFs stopped before the reboot are not checked")
+		return nil
+	}
+	return stoppedPFsOf(node)
+}
+
+func stoppedPFsOf(node *corev1.Node) map[string]bool {
+	stopped := map[string]bool{}
+	for _, pciAddress := range strings.Split(node.GetAnnotations()[AutoRebootStoppedPFsAnnotation], ",") {
+		if pciAddress != "" {
+			stopped[pciAddress] = true
+		}
+	}
+	return stopped
+}
+
+// anyStoppedForReboot tells whether pf-bb-config of any of the PFs was stopped before the reboot
+func anyStoppedForReboot(stopped map[string]bool, pciAddresses []string) bool {
+	for _, pciAddress := range pciAddresses {
+		if stopped[pciAddress] {
+			return true
+		}
+	}
+	return false
+}
+
+// reconfiguredAfterReboot removes PFs which pf-bb-config has run for from AutoRebootStoppedPFsAnnotation of the node;
+// the annotation is removed with the last of them
+func (r *NodeConfigReconciler) reconfiguredAfterReboot(ctx context.Context, pciAddresses []string) {
+	node := &corev1.Node{}
+	if err := r.Get(ctx, client.ObjectKey{Name: r.nodeNameRef.Name}, node); err != nil {
+		r.log.WithError(err).Warn("failed to get the node - PFs stopped before the reboot are not updated")
+		return
+	}
+	stopped := stoppedPFsOf(node)
+	if !anyStoppedForReboot(stopped, pciAddresses) {
+		return
+	}
+	for _, pciAddress := range pciAddresses {
+		delete(stopped, pciAddress)
+	}
+	remaining := make([]string, 0, len(stopped))
+	for pciAddress := range stopped {
+		remaining = append(remaining, pciAddress)
+	}
+
+	original := node.DeepCopy()
+	if len(remaining) == 0 {
+		delete(node.Annotations, AutoRebootStoppedPFsAnnotation)
+	} else {
+		node.Annotations[AutoRebootStoppedPFsAnnotation] = strings.Join(sortedCopy(remaining), ",")
+	}
+	if err := r.Patch(ctx, node, client.MergeFrom(original)); err != nil {
+		r.log.WithError(err).Warn("failed to update PFs stopped before the reboot")
+		return
+	}
+	r.log.WithField("pfs", pciAddresses).Info("pf-bb-config stopped before the reboot has run again")
+}
+
+func sortedCopy(values []string) []string {
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+// releaseRebootLock releases the reboot lock; lock which is not released expires after its duration
+func (r *NodeConfigReconciler) releaseRebootLock(ctx context.Context) {
+	if err := r.rebootLock.release(ctx); err != nil {
+		r.log.WithError(err).Warn("failed to release reboot lock")
+	}
+}
@@ -0,0 +1,373 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/drainhelper"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+// pfBBConfigOutcomeConfigurer records result of pf-bb-config of every requested PF, as NodeConfigurator does
+type pfBBConfigOutcomeConfigurer struct {
+	pfBBConfigErr *error
+}
+
+func (c pfBBConfigOutcomeConfigurer) ApplySpec(ctx context.Context, spec sriovv2.SriovFecNodeConfigSpec) error {
+	for _, pf := range spec.PhysicalFunctions {
+		recordPfBBConfigOutcome(ctx, pf.PCIAddress, *c.pfBBConfigErr)
+		if *c.pfBBConfigErr == nil {
+			recordAppliedPF(ctx, pf.PCIAddress)
+		}
+	}
+	return *c.pfBBConfigErr
+}
+
+func (c pfBBConfigOutcomeConfigurer) RestartPfBBConfig(context.Context, sriovv2.PhysicalFunctionConfigExt) error {
+	return errors.New("not implemented")
+}
+
+func (c pfBBConfigOutcomeConfigurer) VrbApplySpec(ctx context.Context, spec vrbv1.SriovVrbNodeConfigSpec) error {
+	for _, pf := range spec.PhysicalFunctions {
+		recordPfBBConfigOutcome(ctx, pf.PCIAddress, *c.pfBBConfigErr)
+	}
+	return *c.pfBBConfigErr
+}
+
+func (c pfBBConfigOutcomeConfigurer) VrbRestartPfBBConfig(context.Context, vrbv1.PhysicalFunctionConfigExt) error {
+	return errors.New("not implemented")
+}
+
+var _ = Describe("Automatic reboot on persistent pf-bb-config failure", func() {
+	var (
+		fakeClient          client.Client
+		nodeNameRef         = types.NamespacedName{Name: "worker", Namespace: "testNamespace"}
+		drainer             *drainhelper.FakeDrainer
+		recorder            *record.FakeRecorder
+		clk                 *fakeClock
+		pfBBConfigErr       error
+		executed            [][]string
+		rebootErr           error
+		stopped             []string
+		killed              []int
+		logHook             *test.Hook
+		bootID              = "2c1e4f4e-4d4b-4a0a-9d4e-0e7a4b8f6a11"
+		originalDaemonClock = daemonClock
+		originalRunExecCmd  = runExecCmd
+		originalBootIDPath  = bootIDFilePath
+		originalStop        = stopPfBBConfigBeforeReboot
+		originalRetryPeriod = rebootLockRetryPeriod
+	)
+
+	nodeConfig := func() *sriovv2.SriovFecNodeConfig {
+		nc := &sriovv2.SriovFecNodeConfig{}
+		Expect(fakeClient.Get(context.TODO(), nodeNameRef, nc)).To(Succeed())
+		return nc
+	}
+
+	node := func() *corev1.Node {
+		n := &corev1.Node{}
+		Expect(fakeClient.Get(context.TODO(), client.ObjectKey{Name: nodeNameRef.Name}, n)).To(Succeed())
+		return n
+	}
+
+	reconcile := func() *sriovv2.SriovFecNodeConfig {
+		reconciler, err := NewNodeConfigReconciler(fakeClient, drainer, nodeNameRef, pfBBConfigOutcomeConfigurer{&pfBBConfigErr}, nil,
+			func(context.Context) error { return nil }, recorder, nil)
+		Expect(err).ToNot(HaveOccurred())
+		reconciler.log.AddHook(logHook)
+		_, _ = reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: nodeNameRef})
+		return nodeConfig()
+	}
+
+	reasonOf := func(nc *sriovv2.SriovFecNodeConfig) string {
+		return nc.FindCondition(ConditionConfigured).Reason
+	}
+
+	rebootLockHolder := func() string {
+		lease := &coordinationv1.Lease{}
+		Expect(fakeClient.Get(context.TODO(), types.NamespacedName{Namespace: nodeNameRef.Namespace, Name: rebootLockNameDefault}, lease)).To(Succeed())
+		return leaseHolder(lease)
+	}
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(sriovv2.AddToScheme(scheme)).To(Succeed())
+		Expect(vrbv1.AddToScheme(scheme)).To(Succeed())
+		Expect(corev1.AddToScheme(scheme)).To(Succeed())
+		Expect(coordinationv1.AddToScheme(scheme)).To(Succeed())
+
+		configPath = "testdata/accelerators.json"
+		VrbconfigPath = "testdata/accelerators_vrb.json"
+		procCmdlineFilePath = "testdata/cmdline_test"
+		sysLockdownFilePath = filepath.Join(testTmpFolder, "lockdown")
+		Expect(os.WriteFile(sysLockdownFilePath, []byte("[none] integrity confidentiality"), 0644)).To(Succeed())
+
+		getSriovInventory = func(_ context.Context, log *logrus.Logger) (*sriovv2.NodeInventory, error) {
+			return &sriovv2.NodeInventory{
+				SriovAccelerators: []sriovv2.SriovAccelerator{{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, MaxVFs: 10}},
+			}, nil
+		}
+		VrbgetSriovInventory = func(_ context.Context, log *logrus.Logger) (*vrbv1.NodeInventory, error) {
+			return &vrbv1.NodeInventory{}, nil
+		}
+
+		fakeClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name}},
+			&sriovv2.SriovFecNodeConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace},
+				Spec: sriovv2.SriovFecNodeConfigSpec{
+					PhysicalFunctions: []sriovv2.PhysicalFunctionConfigExt{{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, VFDriver: utils.IGB_UIO, VFAmount: 1}},
+					AutoRebootOnPersistentFailure: &sriovv2.AutoRebootPolicy{
+						Enabled: true, Threshold: 2, Cooldown: &metav1.Duration{Duration: time.Hour}},
+				},
+			},
+			&vrbv1.SriovVrbNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace}},
+		).Build()
+		drainer = &drainhelper.FakeDrainer{}
+		recorder = record.NewFakeRecorder(10)
+		clk = newFakeClock(time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC))
+		daemonClock = clk
+		pfBBConfigErr = errors.New("pf_bb_config failed")
+		executed, rebootErr, stopped, killed = nil, nil, nil, nil
+		logHook = new(test.Hook)
+		runExecCmd = func(_ context.Context, args []string, _ *logrus.Logger) (string, error) {
+			executed = append(executed, args)
+			return "", rebootErr
+		}
+		stopPfBBConfigBeforeReboot = func(pciAddress string, _ *logrus.Logger) ([]int, error) {
+			Expect(executed).To(BeEmpty(), "pf-bb-config is stopped before the reboot")
+			stopped = append(stopped, pciAddress)
+			return killed, nil
+		}
+		bootIDFilePath = filepath.Join(testTmpFolder, "boot_id")
+		Expect(os.WriteFile(bootIDFilePath, []byte(bootID+"\n"), 0644)).To(Succeed())
+		rebootLockRetryPeriod = time.Millisecond
+		Expect(os.Setenv(utils.SRIOV_PREFIX+"REBOOT_LOCK_TIMEOUT", "20ms")).To(Succeed())
+	})
+
+	AfterEach(func() {
+		getSriovInventory = GetSriovInventory
+		VrbgetSriovInventory = VrbGetSriovInventory
+		sysLockdownFilePath = "/sys/kernel/security/lockdown"
+		daemonClock = originalDaemonClock
+		runExecCmd = originalRunExecCmd
+		bootIDFilePath = originalBootIDPath
+		stopPfBBConfigBeforeReboot = originalStop
+		rebootLockRetryPeriod = originalRetryPeriod
+		Expect(os.Unsetenv(utils.SRIOV_PREFIX + "REBOOT_LOCK_TIMEOUT")).To(Succeed())
+	})
+
+	It("reboots the node once pf-bb-config fails threshold times in a row", func() {
+		nc := reconcile()
+		Expect(reasonOf(nc)).To(Equal(string(ConfigurationFailed)))
+		Expect(nc.Status.PfBBConfigFailures).To(Equal(map[string]int{pciAddress: 1}))
+		Expect(executed).To(BeEmpty())
+
+		nc = reconcile()
+		Expect(reasonOf(nc)).To(Equal(string(ConfigurationRebootRequested)))
+		Expect(nc.FindCondition(ConditionConfigured).Message).To(Equal(
+			"pf-bb-config of PF " + pciAddress + " failed 2 times in a row (threshold 2) - node reboot requested"))
+		Expect(nc.Status.PfBBConfigFailures).To(BeNil(), "count is reset by the reboot")
+		Expect(executed).To(Equal([][]string{rebootCommand}))
+		Expect(stopped).To(Equal([]string{pciAddress}))
+		Expect(node().GetAnnotations()).To(And(
+			HaveKeyWithValue(AutoRebootRequestedAtAnnotation, "2023-05-01T12:00:00Z"),
+			HaveKeyWithValue(AutoRebootDeviceAnnotation, pciAddress),
+			HaveKeyWithValue(AutoRebootBootIDAnnotation, bootID),
+			HaveKeyWithValue(AutoRebootStoppedPFsAnnotation, pciAddress)))
+		Expect(recorder.Events).To(Receive(HavePrefix("Warning RebootRequested ")))
+		Expect(drainer.Runs()[1].RebootPending).To(BeTrue(), "node stays cordoned until the reboot")
+	})
+
+	It("does not reboot again within the cooldown", func() {
+		n := node()
+		n.Annotations = map[string]string{AutoRebootRequestedAtAnnotation: "2023-05-01T11:30:00Z"}
+		Expect(fakeClient.Update(context.TODO(), n)).To(Succeed())
+
+		reconcile()
+		nc := reconcile()
+		Expect(reasonOf(nc)).To(Equal(string(ConfigurationFailed)))
+		Expect(nc.Status.PfBBConfigFailures).To(Equal(map[string]int{pciAddress: 2}))
+		Expect(executed).To(BeEmpty())
+		Expect(drainer.Runs()[1].Uncordoned).To(BeTrue())
+
+		clk.Jump(30 * time.Minute)
+		Expect(reasonOf(reconcile())).To(Equal(string(ConfigurationRebootRequested)))
+		Expect(executed).To(HaveLen(1))
+		Expect(node().GetAnnotations()).To(HaveKeyWithValue(AutoRebootRequestedAtAnnotation, "2023-05-01T12:30:00Z"))
+	})
+
+	It("postpones configuration until the node has rebooted", func() {
+		reconcile()
+		reconcile()
+		Expect(drainer.Runs()).To(HaveLen(2))
+
+		reconcile()
+		Expect(drainer.Runs()).To(HaveLen(2), "reboot-pending mark is not cleared by configuration before the reboot")
+		Expect(node().GetAnnotations()).To(HaveKeyWithValue(AutoRebootBootIDAnnotation, bootID))
+		Expect(rebootLockHolder()).To(Equal(nodeNameRef.Name), "reboot lock is held until the reboot")
+
+		n := node()
+		n.Annotations[drainhelper.RebootPendingAnnotation] = "true"
+		Expect(fakeClient.Update(context.TODO(), n)).To(Succeed())
+		Expect(os.WriteFile(bootIDFilePath, []byte("9a0b7c55-2f7e-4c1d-8b8e-3d1f2a6c7e90\n"), 0644)).To(Succeed())
+		pfBBConfigErr = nil
+
+		Expect(reasonOf(reconcile())).To(Equal(string(ConfigurationSucceeded)))
+		Expect(drainer.Runs()).To(HaveLen(3))
+		Expect(node().GetAnnotations()).ToNot(Or(
+			HaveKey(AutoRebootBootIDAnnotation),
+			HaveKey(drainhelper.RebootPendingAnnotation)))
+		Expect(node().GetAnnotations()).To(HaveKey(AutoRebootRequestedAtAnnotation), "cooldown survives the reboot")
+		Expect(node().GetAnnotations()).ToNot(HaveKey(AutoRebootStoppedPFsAnnotation), "stopped PF has been reconfigured")
+		Expect(rebootLockHolder()).To(BeEmpty(), "reboot lock is released once the node has rebooted")
+	})
+
+	It("logs PFs which pf-bb-config had to be killed before the reboot", func() {
+		killed = []int{42}
+
+		reconcile()
+		reconcile()
+		Expect(stopped).To(Equal([]string{pciAddress}))
+		var logged []logrus.Fields
+		for _, entry := range logHook.AllEntries() {
+			if entry.Level == logrus.WarnLevel && entry.Message == "pf-bb-config did not exit on SIGTERM before the reboot - killed" {
+				logged = append(logged, entry.Data)
+			}
+		}
+		Expect(logged).To(ConsistOf(And(
+			HaveKeyWithValue("stoppedPciAddress", pciAddress),
+			HaveKeyWithValue("pids", []int{42}))))
+	})
+
+	It("reconfigures PFs which pf-bb-config was stopped before the reboot", func() {
+		pfBBConfigErr = nil
+		getSriovInventory = func(_ context.Context, log *logrus.Logger) (*sriovv2.NodeInventory, error) {
+			return &sriovv2.NodeInventory{
+				SriovAccelerators: []sriovv2.SriovAccelerator{{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, MaxVFs: 10,
+					VFs: []sriovv2.VF{{PCIAddress: "0000:14:00.2", Driver: utils.IGB_UIO}}}},
+			}, nil
+		}
+		reconcile()
+		Expect(drainer.Runs()).To(BeEmpty(), "requested VFs are exposed already")
+
+		n := node()
+		n.Annotations = map[string]string{AutoRebootStoppedPFsAnnotation: pciAddress + ",0000:f7:00.0"}
+		Expect(fakeClient.Update(context.TODO(), n)).To(Succeed())
+
+		Expect(reasonOf(reconcile())).To(Equal(string(ConfigurationSucceeded)))
+		Expect(drainer.Runs()).To(HaveLen(1), "pf-bb-config of stopped PF is run again")
+		Expect(node().GetAnnotations()).To(HaveKeyWithValue(AutoRebootStoppedPFsAnnotation, "0000:f7:00.0"),
+			"PF which is not requested anymore is kept")
+
+		reconcile()
+		Expect(drainer.Runs()).To(HaveLen(1))
+	})
+
+	It("defers the reboot while the reboot lock is held by other node", func() {
+		other, duration := "other-worker", int32(1800)
+		renewed := metav1.NewMicroTime(clk.Now())
+		lease := &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Namespace: nodeNameRef.Namespace, Name: rebootLockNameDefault},
+			Spec:       coordinationv1.LeaseSpec{HolderIdentity: &other, LeaseDurationSeconds: &duration, RenewTime: &renewed},
+		}
+		Expect(fakeClient.Create(context.TODO(), lease)).To(Succeed())
+
+		reconcile()
+		nc := reconcile()
+		Expect(reasonOf(nc)).To(Equal(string(ConfigurationDeferred)))
+		Expect(nc.FindCondition(ConditionConfigured).Message).To(ContainSubstring(`held by "other-worker"`))
+		Expect(nc.Status.PfBBConfigFailures).To(Equal(map[string]int{pciAddress: 2}), "count is kept for the next attempt")
+		Expect(executed).To(BeEmpty())
+		Expect(stopped).To(BeEmpty())
+		Expect(drainer.Runs()[1].Uncordoned).To(BeTrue())
+		Expect(node().GetAnnotations()).ToNot(HaveKey(AutoRebootBootIDAnnotation))
+
+		Expect(fakeClient.Get(context.TODO(), client.ObjectKeyFromObject(lease), lease)).To(Succeed())
+		lease.Spec.HolderIdentity = nil
+		Expect(fakeClient.Update(context.TODO(), lease)).To(Succeed())
+
+		Expect(reasonOf(reconcile())).To(Equal(string(ConfigurationRebootRequested)))
+		Expect(executed).To(HaveLen(1))
+		Expect(rebootLockHolder()).To(Equal(nodeNameRef.Name))
+	})
+
+	It("uncordons the node when the reboot command fails", func() {
+		rebootErr = errors.New("systemctl failed")
+
+		reconcile()
+		nc := reconcile()
+		Expect(reasonOf(nc)).To(Equal(string(ConfigurationFailed)))
+		Expect(nc.FindCondition(ConditionConfigured).Message).To(ContainSubstring("failed to reboot the node"))
+		Expect(executed).To(HaveLen(1))
+		Expect(drainer.Runs()[1].RebootPending).To(BeFalse())
+		Expect(drainer.Runs()[1].Uncordoned).To(BeTrue())
+		Expect(node().GetAnnotations()).ToNot(HaveKey(AutoRebootBootIDAnnotation))
+		Expect(rebootLockHolder()).To(BeEmpty())
+
+		reconcile()
+		Expect(drainer.Runs()).To(HaveLen(3), "node is not considered waiting for the reboot")
+	})
+
+	It("counts only consecutive failures", func() {
+		reconcile()
+		pfBBConfigErr = nil
+		nc := reconcile()
+		Expect(reasonOf(nc)).To(Equal(string(ConfigurationSucceeded)))
+		Expect(nc.Status.PfBBConfigFailures).To(BeNil())
+
+		pfBBConfigErr = errors.New("pf_bb_config failed")
+		Expect(reconcile().Status.PfBBConfigFailures).To(Equal(map[string]int{pciAddress: 1}))
+		Expect(executed).To(BeEmpty())
+	})
+
+	It("does not reboot when the node cannot be annotated", func() {
+		Expect(fakeClient.Delete(context.TODO(), node())).To(Succeed())
+
+		reconcile()
+		Expect(reasonOf(reconcile())).To(Equal(string(ConfigurationFailed)))
+		Expect(executed).To(BeEmpty())
+	})
+
+	It("neither counts nor reboots when the policy is disabled", func() {
+		nc := nodeConfig()
+		nc.Spec.AutoRebootOnPersistentFailure.Enabled = false
+		Expect(fakeClient.Update(context.TODO(), nc)).To(Succeed())
+
+		for i := 0; i < 3; i++ {
+			Expect(reconcile().Status.PfBBConfigFailures).To(BeNil())
+		}
+		Expect(executed).To(BeEmpty())
+	})
+
+	It("drops counts of PFs which are not requested anymore", func() {
+		policy := &sriovv2.AutoRebootPolicy{Enabled: true}
+
+		Expect(countPfBBConfigFailures(policy, map[string]int{"0000:f7:00.0": 2, "0000:f8:00.0": 1},
+			map[string]bool{"0000:f9:00.0": true}, []string{"0000:f7:00.0", "0000:f9:00.0"})).
+			To(Equal(map[string]int{"0000:f7:00.0": 2, "0000:f9:00.0": 1}))
+	})
+})
@@ -0,0 +1,231 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	bbDevConfigHistoryDirName = "bbdev-config-history"
+	bbDevConfigMirrorPrefix   = "sriov-fec-bbdev-config-"
+	// maxBBDevConfigHistoryEntries and maxBBDevConfigHistoryBytes cap the history of every PF; oldest files are removed
+	// first, the latest one is always kept
+	maxBBDevConfigHistoryEntries = 10
+	maxBBDevConfigHistoryBytes   = 1024 * 1024
+)
+
+// bbDevConfigMirror writes the latest cfg file of every PF into sriov-fec-bbdev-config-<node> ConfigMap, so that it
+// survives reimaging of the node
+type bbDevConfigMirror struct {
+	client      client.Client
+	nodeNameRef types.NamespacedName
+}
+
+// MirrorBBDevConfigs enables mirroring of the latest cfg file of every PF into sriov-fec-bbdev-config-<node> ConfigMap
+func (p *pfBBConfigController) MirrorBBDevConfigs(c client.Client, nodeNameRef types.NamespacedName) {
+	p.bbDevConfigMirror = &bbDevConfigMirror{client: c, nodeNameRef: nodeNameRef}
+}
+
+// recordBBDevConfig stores cfg file pf_bb_config was started with in history of the PF. Failure is only logged,
+// as history must not fail the configuration.
+func (p *pfBBConfigController) recordBBDevConfig(pciAddress, cfgFilepath string) {
+	content, err := os.ReadFile(cfgFilepath)
+	if err == nil {
+		err = storeBBDevConfig(pciAddress, content)
+	}
+	if err != nil {
+		p.log.WithError(err).WithField("pci", pciAddress).Error("failed to store bbdev config file in history")
+		return
+	}
+
+	if p.bbDevConfigMirror == nil {
+		return
+	}
+	if err := p.bbDevConfigMirror.update(pciAddress, content); err != nil {
+		p.log.WithError(err).WithField("pci", pciAddress).Error("failed to mirror bbdev config file into ConfigMap")
+	}
+}
+
+func (m *bbDevConfigMirror) update(pciAddress string, content []byte) error {
+	name := bbDevConfigMirrorPrefix + m.nodeNameRef.Name
+	key := bbDevConfigMirrorKey(pciAddress)
+	retriable := func(err error) bool {
+		return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err)
+	}
+
+	return retry.OnError(retry.DefaultRetry, retriable, func() error {
+		cm := &corev1.ConfigMap{}
+		err := m.client.Get(context.TODO(), client.ObjectKey{Namespace: m.nodeNameRef.Namespace, Name: name}, cm)
+		if apierrors.IsNotFound(err) {
+			return m.client.Create(context.TODO(), &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: m.nodeNameRef.Namespace, Name: name},
+				Data:       map[string]string{key: string(content)},
+			})
+		}
+		if err != nil {
+			return err
+		}
+		if cm.Data[key] == string(content) {
+			return nil
+		}
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[key] = string(content)
+		return m.client.Update(context.TODO(), cm)
+	})
+}
+
+// bbDevConfigMirrorKey returns ConfigMap key of PF's cfg file; ':' is not allowed in ConfigMap keys
+func bbDevConfigMirrorKey(pciAddress string) string {
+	return strings.ReplaceAll(pciAddress, ":", "_") + ".cfg"
+}
+
+func bbDevConfigHistoryDir(pciAddress string) string {
+	return filepath.Join(workdir, bbDevConfigHistoryDirName, pciAddress)
+}
+
+// bbDevConfigGenerations returns numbers of cfg files stored in history of the PF, from the oldest one
+func bbDevConfigGenerations(pciAddress string) ([]int, error) {
+	entries, err := os.ReadDir(bbDevConfigHistoryDir(pciAddress))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var generations []int
+	for _, entry := range entries {
+		generation, err := strconv.Atoi(strings.TrimSuffix(entry.Name(), ".cfg"))
+		if err != nil || !strings.HasSuffix(entry.Name(), ".cfg") {
+			continue
+		}
+		generations = append(generations, generation)
+	}
+	sort.Ints(generations)
+	return generations, nil
+}
+
+func bbDevConfigFile(pciAddress string, generation int) string {
+	return filepath.Join(bbDevConfigHistoryDir(pciAddress), fmt.Sprintf("%06d.cfg", generation))
+}
+
+// storeBBDevConfig stores content as the next generation of PF's cfg file, unless it is equal to the latest one, and
+// rotates the oldest generations out
+func storeBBDevConfig(pciAddress string, content []byte) error {
+	generations, err := bbDevConfigGenerations(pciAddress)
+	if err != nil {
+		return err
+	}
+
+	next := 1
+	if len(generations) > 0 {
+		latest := generations[len(generations)-1]
+		if stored, err := os.ReadFile(bbDevConfigFile(pciAddress, latest)); err == nil && string(stored) == string(content) {
+			return nil
+		}
+		next = latest + 1
+	}
+
+	if err := os.MkdirAll(bbDevConfigHistoryDir(pciAddress), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(bbDevConfigFile(pciAddress, next), content, 0644); err != nil {
+		return err
+	}
+	return rotateBBDevConfigHistory(pciAddress, append(generations, next))
+}
+
+func rotateBBDevConfigHistory(pciAddress string, generations []int) error {
+	sizes := make([]int64, len(generations))
+	var total int64
+	for i, generation := range generations {
+		if info, err := os.Stat(bbDevConfigFile(pciAddress, generation)); err == nil {
+			sizes[i] = info.Size()
+			total += sizes[i]
+		}
+	}
+
+	for i := 0; i < len(generations)-1 && (len(generations)-i > maxBBDevConfigHistoryEntries || total > maxBBDevConfigHistoryBytes); i++ {
+		if err := os.Remove(bbDevConfigFile(pciAddress, generations[i])); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		total -= sizes[i]
+	}
+	return nil
+}
+
+// appliedBBDevConfigHashes returns SHA-256 of the latest cfg files stored in history of given PFs, by PF's PCI address
+func appliedBBDevConfigHashes(pciAddresses []string) map[string]string {
+	var hashes map[string]string
+	for _, pciAddress := range pciAddresses {
+		generations, err := bbDevConfigGenerations(pciAddress)
+		if err != nil || len(generations) == 0 {
+			continue
+		}
+		content, err := os.ReadFile(bbDevConfigFile(pciAddress, generations[len(generations)-1]))
+		if err != nil {
+			continue
+		}
+		if hashes == nil {
+			hashes = map[string]string{}
+		}
+		hashes[pciAddress] = fmt.Sprintf("%x", sha256.Sum256(content))
+	}
+	return hashes
+}
+
+// PrintBBDevConfigDiff prints unified diff between the last two cfg files pf_bb_config was started with for given PF
+func PrintBBDevConfigDiff(out io.Writer, pciAddress string) error {
+	generations, err := bbDevConfigGenerations(pciAddress)
+	if err != nil {
+		return fmt.Errorf("failed to read bbdev config history of %s - %v", pciAddress, err)
+	}
+	if len(generations) < 2 {
+		return fmt.Errorf("bbdev config history of %s holds %d file(s), at least 2 are needed", pciAddress, len(generations))
+	}
+
+	previous, current := generations[len(generations)-2], generations[len(generations)-1]
+	read := func(generation int) ([]string, error) {
+		content, err := os.ReadFile(bbDevConfigFile(pciAddress, generation))
+		if err != nil {
+			return nil, err
+		}
+		return difflib.SplitLines(string(content)), nil
+	}
+	a, err := read(previous)
+	if err != nil {
+		return err
+	}
+	b, err := read(current)
+	if err != nil {
+		return err
+	}
+
+	return difflib.WriteUnifiedDiff(out, difflib.UnifiedDiff{
+		A:        a,
+		B:        b,
+		FromFile: filepath.Base(bbDevConfigFile(pciAddress, previous)),
+		ToFile:   filepath.Base(bbDevConfigFile(pciAddress, current)),
+		Context:  3,
+	})
+}
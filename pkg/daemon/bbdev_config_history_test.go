@@ -0,0 +1,109 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("BBDevConfigHistory", func() {
+	const pciAddress = "0000:f7:00.0"
+	var (
+		originalWorkdir                  = workdir
+		originalRunPfBBConfigCmd         = runPfBBConfigCmd
+		originalVrbSupportedAccelerators = VrbsupportedAccelerators
+	)
+
+	BeforeEach(func() {
+		var err error
+		workdir, err = os.MkdirTemp(testTmpFolder, "bbdev-config-history")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(workdir)).To(Succeed())
+		workdir = originalWorkdir
+		runPfBBConfigCmd = originalRunPfBBConfigCmd
+		VrbsupportedAccelerators = originalVrbSupportedAccelerators
+	})
+
+	It("should store changed cfg files only and rotate the oldest ones out", func() {
+		Expect(storeBBDevConfig(pciAddress, []byte("[MODE]\npf_mode_en = 0\n"))).To(Succeed())
+		Expect(storeBBDevConfig(pciAddress, []byte("[MODE]\npf_mode_en = 0\n"))).To(Succeed())
+		Expect(bbDevConfigGenerations(pciAddress)).To(Equal([]int{1}))
+
+		for i := 0; i < maxBBDevConfigHistoryEntries+2; i++ {
+			Expect(storeBBDevConfig(pciAddress, []byte(fmt.Sprintf("[MODE]\npf_mode_en = %d\n", i+1)))).To(Succeed())
+		}
+		generations, err := bbDevConfigGenerations(pciAddress)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(generations).To(HaveLen(maxBBDevConfigHistoryEntries))
+		Expect(generations[len(generations)-1]).To(Equal(maxBBDevConfigHistoryEntries + 3))
+
+		// the latest file is kept even when it exceeds the size cap
+		large := bytes.Repeat([]byte("x"), maxBBDevConfigHistoryBytes+1)
+		Expect(storeBBDevConfig(pciAddress, large)).To(Succeed())
+		Expect(bbDevConfigGenerations(pciAddress)).To(Equal([]int{maxBBDevConfigHistoryEntries + 4}))
+		Expect(appliedBBDevConfigHashes([]string{pciAddress, "0000:f8:00.0"})).To(Equal(map[string]string{
+			pciAddress: fmt.Sprintf("%x", sha256.Sum256(large)),
+		}))
+	})
+
+	It("should print diff between the last two generations", func() {
+		out := &bytes.Buffer{}
+		Expect(storeBBDevConfig(pciAddress, []byte("[MODE]\npf_mode_en = 0\n\n[VFBUNDLES]\nnum_vf_bundles = 16\n"))).To(Succeed())
+		Expect(PrintBBDevConfigDiff(out, pciAddress)).To(MatchError(ContainSubstring("holds 1 file(s), at least 2 are needed")))
+
+		Expect(storeBBDevConfig(pciAddress, []byte("[MODE]\npf_mode_en = 0\n\n[VFBUNDLES]\nnum_vf_bundles = 8\n"))).To(Succeed())
+		Expect(PrintBBDevConfigDiff(out, pciAddress)).To(Succeed())
+		Expect(out.String()).To(ContainSubstring("--- 000001.cfg\n+++ 000002.cfg\n"))
+		Expect(out.String()).To(ContainSubstring("-num_vf_bundles = 16\n+num_vf_bundles = 8\n"))
+	})
+
+	It("should record cfg file pf_bb_config was started with and mirror it into ConfigMap", func() {
+		runPfBBConfigCmd = func(_ context.Context, args []string, _ *logrus.Logger) (string, error) { return "", nil }
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+		nodeNameRef := types.NamespacedName{Name: "worker", Namespace: "default"}
+
+		p := &pfBBConfigController{log: utils.NewLogger(), fftUpdater: &fftUpdater{log: utils.NewLogger()}}
+		p.MirrorBBDevConfigs(c, nodeNameRef)
+		pf := &vrbv1.PhysicalFunctionConfigExt{PCIAddress: pciAddress}
+		Expect(p.VrbinitializePfBBConfig(context.TODO(), vrbv1.SriovAccelerator{DeviceID: "57c2"}, pf, []byte(validBBDevConfigFile))).ToNot(Succeed())
+		Expect(bbDevConfigGenerations(pciAddress)).To(BeEmpty(), "cfg file which pf_bb_config was not started with is not recorded")
+
+		VrbsupportedAccelerators = utils.AcceleratorDiscoveryConfig{Devices: map[string]string{"57c2": "VRB2"}}
+		Expect(p.VrbinitializePfBBConfig(context.TODO(), vrbv1.SriovAccelerator{DeviceID: "57c2"}, pf, []byte(validBBDevConfigFile))).To(Succeed())
+		Expect(bbDevConfigGenerations(pciAddress)).To(Equal([]int{1}))
+
+		cm := &corev1.ConfigMap{}
+		Expect(c.Get(context.TODO(), client.ObjectKey{Namespace: "default", Name: "sriov-fec-bbdev-config-worker"}, cm)).To(Succeed())
+		Expect(cm.Data).To(HaveKeyWithValue("0000_f7_00.0.cfg", validBBDevConfigFile))
+
+		updated := strings.Replace(validBBDevConfigFile, "num_vf_bundles = 2", "num_vf_bundles = 1", 1)
+		Expect(p.VrbinitializePfBBConfig(context.TODO(), vrbv1.SriovAccelerator{DeviceID: "57c2"}, pf, []byte(updated))).To(Succeed())
+		Expect(bbDevConfigGenerations(pciAddress)).To(Equal([]int{1, 2}))
+		Expect(c.Get(context.TODO(), client.ObjectKey{Namespace: "default", Name: "sriov-fec-bbdev-config-worker"}, cm)).To(Succeed())
+		Expect(cm.Data).To(HaveKeyWithValue("0000_f7_00.0.cfg", updated))
+	})
+})
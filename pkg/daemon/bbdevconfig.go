@@ -4,13 +4,14 @@
 package daemon
 
 import (
+	"context"
 	"crypto/x509"
 	"fmt"
 	"net/http"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
 	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
@@ -22,6 +23,8 @@ var (
 	downloadFile    = DownloadFile
 	untarFile       = Untar
 	artifactsFolder = "/tmp"
+	// pf_bb_config output may be huge when verbose, so only limited part of it is retained
+	runPfBBConfigCmd = withInjectedFaults(execPfBBConfigCmd)
 
 	pfConfigAppFilepath              string
 	srsFftWindowsCoefficientFilepath string
@@ -33,17 +36,16 @@ type fftUpdater struct {
 }
 
 func NewPfBBConfigController(log *logrus.Logger, sharedVfioToken string) *pfBBConfigController {
-	var err error
-	cert := getTlsCert(log)
-
-	httpClient := http.DefaultClient
-	if cert != nil {
+	var certs []*x509.Certificate
+	if cert := getTlsCert(log); cert != nil {
 		log.Info("found certificate - using HTTPS client")
-		httpClient, err = NewSecureHttpsClient(cert)
-		if err != nil {
-			log.WithError(err)
-			return nil
-		}
+		certs = append(certs, cert)
+	}
+
+	httpClient, err := utils.NewHTTPClient(log, certs...)
+	if err != nil {
+		log.WithError(err).Error("failed to create HTTP client")
+		return nil
 	}
 
 	return &pfBBConfigController{
@@ -58,8 +60,27 @@ func NewPfBBConfigController(log *logrus.Logger, sharedVfioToken string) *pfBBCo
 
 type pfBBConfigController struct {
 	log               *logrus.Logger
+	tokenMu           sync.RWMutex
 	sharedVfioToken   string
 	fftUpdater        *fftUpdater
+	bbDevConfigMirror *bbDevConfigMirror
+}
+
+// SetVfioToken replaces VFIO token pf_bb_config instances are started with; already running ones keep the old token
+func (p *pfBBConfigController) SetVfioToken(token string) {
+	p.tokenMu.Lock()
+	defer p.tokenMu.Unlock()
+	if p.sharedVfioToken != token {
+		p.log.Info("VFIO token changed - it will be used by pf_bb_config started from now on")
+		p.sharedVfioToken = token
+	}
+}
+
+func (p *pfBBConfigController) vfioToken() *string {
+	p.tokenMu.RLock()
+	defer p.tokenMu.RUnlock()
+	token := p.sharedVfioToken
+	return &token
 }
 
 func getTlsCert(log *logrus.Logger) *x509.Certificate {
@@ -77,10 +98,14 @@ func getTlsCert(log *logrus.Logger) *x509.Certificate {
 	return cert
 }
 
-func (p *pfBBConfigController) initializePfBBConfig(acc sriovv2.SriovAccelerator, pf *sriovv2.PhysicalFunctionConfigExt) error {
-	if pf.BBDevConfig.N3000 != nil || pf.BBDevConfig.ACC100 != nil || pf.BBDevConfig.ACC200 != nil {
+// initializePfBBConfig configures queues of the device with pf_bb_config; rawConfig is cfg file read from bbDevConfigFrom
+// and takes precedence over pf.BBDevConfig
+func (p *pfBBConfigController) initializePfBBConfig(ctx context.Context, acc sriovv2.SriovAccelerator, pf *sriovv2.PhysicalFunctionConfigExt, rawConfig []byte) error {
+	if rawConfig != nil || pf.BBDevConfig.N3000 != nil || pf.BBDevConfig.ACC100 != nil || pf.BBDevConfig.ACC200 != nil {
 		bbdevConfigFilepath := filepath.Join(workdir, fmt.Sprintf("%s.ini", pf.PCIAddress))
-		if err := generateBBDevConfigFile(pf.BBDevConfig, bbdevConfigFilepath); err != nil {
+		if err := p.writeBBDevConfigFile(rawConfig, bbdevConfigFilepath, func() error {
+			return generateBBDevConfigFile(pf.BBDevConfig, bbdevConfigFilepath)
+		}); err != nil {
 			p.log.WithError(err).WithField("pci", pf.PCIAddress).Error("failed to create bbdev config file")
 			return err
 		}
@@ -88,7 +113,11 @@ func (p *pfBBConfigController) initializePfBBConfig(acc sriovv2.SriovAccelerator
 		deviceName := supportedAccelerators.Devices[acc.DeviceID]
 		var err error
 		if deviceName == "ACC200" {
-			srsFftWindowsCoefficientFilepath, err = p.fftUpdater.getFftFilePath(p, &pf.BBDevConfig.ACC200.FFTLut)
+			fftLut := sriovv2.FFTLutParam{}
+			if pf.BBDevConfig.ACC200 != nil {
+				fftLut = pf.BBDevConfig.ACC200.FFTLut
+			}
+			srsFftWindowsCoefficientFilepath, err = p.fftUpdater.getFftFilePath(p, &fftLut)
 			if err != nil {
 				p.log.WithError(err)
 				return err
@@ -99,18 +128,25 @@ func (p *pfBBConfigController) initializePfBBConfig(acc sriovv2.SriovAccelerator
 			p.log.Infof("SRS FFT file path is : %s", srsFftWindowsCoefficientFilepath)
 		}
 		if pfConfigAppFilepath == "" {
-			pfConfigAppFilepath = "/sriov_workdir/pf_bb_config"
+			pfConfigAppFilepath = defaultPfBBConfigAppFilepath
 		}
 		p.log.Infof("pf-bb-config file path is : %s", pfConfigAppFilepath)
 		var token *string
 		if strings.EqualFold(pf.PFDriver, utils.VFIO_PCI) {
-			token = &p.sharedVfioToken
+			token = p.vfioToken()
 		}
 
-		if err := p.runPFConfig(deviceName, bbdevConfigFilepath, pf.PCIAddress, token); err != nil {
+		var fftLutFile string
+		if deviceName == "ACC100" && acc100FFTLut(pf) != nil {
+			fftLutFile = fftLutPath(pf.PCIAddress)
+		}
+
+		if err := p.runPFConfig(ctx, deviceName, bbdevConfigFilepath, pf.PCIAddress, token, fftLutFile); err != nil {
 			p.log.WithError(err).WithField("pci", pf.PCIAddress).Error("failed to configure device's queues")
 			return err
 		}
+		observePfBBConfigStartup(acc.DeviceID, pf.PCIAddress)
+		p.recordBBDevConfig(pf.PCIAddress, bbdevConfigFilepath)
 	} else {
 		p.log.Info("All sections of 'BBDevConfig' are nil - queues will not be (re)configured")
 	}
@@ -118,10 +154,12 @@ func (p *pfBBConfigController) initializePfBBConfig(acc sriovv2.SriovAccelerator
 	return nil
 }
 
-func (p *pfBBConfigController) VrbinitializePfBBConfig(acc vrbv1.SriovAccelerator, pf *vrbv1.PhysicalFunctionConfigExt) error {
-	if pf.BBDevConfig.VRB1 != nil || pf.BBDevConfig.VRB2 != nil {
+func (p *pfBBConfigController) VrbinitializePfBBConfig(ctx context.Context, acc vrbv1.SriovAccelerator, pf *vrbv1.PhysicalFunctionConfigExt, rawConfig []byte) error {
+	if rawConfig != nil || pf.BBDevConfig.VRB1 != nil || pf.BBDevConfig.VRB2 != nil {
 		bbdevConfigFilepath := filepath.Join(workdir, fmt.Sprintf("%s.ini", pf.PCIAddress))
-		if err := generateVrbBBDevConfigFile(pf.BBDevConfig, bbdevConfigFilepath); err != nil {
+		if err := p.writeBBDevConfigFile(rawConfig, bbdevConfigFilepath, func() error {
+			return generateVrbBBDevConfigFile(pf.BBDevConfig, bbdevConfigFilepath)
+		}); err != nil {
 			p.log.WithError(err).WithField("pci", pf.PCIAddress).Error("failed to create bbdev config file")
 			return err
 		}
@@ -129,7 +167,11 @@ func (p *pfBBConfigController) VrbinitializePfBBConfig(acc vrbv1.SriovAccelerato
 		deviceName := VrbsupportedAccelerators.Devices[acc.DeviceID]
 		var err error
 		if deviceName == "VRB1" {
-			srsFftWindowsCoefficientFilepath, err = p.fftUpdater.VrbgetFftFilePath(p, &pf.BBDevConfig.VRB1.FFTLut)
+			fftLut := vrbv1.FFTLutParam{}
+			if pf.BBDevConfig.VRB1 != nil {
+				fftLut = pf.BBDevConfig.VRB1.FFTLut
+			}
+			srsFftWindowsCoefficientFilepath, err = p.fftUpdater.VrbgetFftFilePath(p, &fftLut)
 			if err != nil {
 				p.log.WithError(err)
 				return err
@@ -140,7 +182,11 @@ func (p *pfBBConfigController) VrbinitializePfBBConfig(acc vrbv1.SriovAccelerato
 			p.log.Infof("SRS FFT file path is : %s", srsFftWindowsCoefficientFilepath)
 		}
 		if deviceName == "VRB2" {
-			srsFftWindowsCoefficientFilepath, err = p.fftUpdater.VrbgetFftFilePath(p, &pf.BBDevConfig.VRB2.FFTLut)
+			fftLut := vrbv1.FFTLutParam{}
+			if pf.BBDevConfig.VRB2 != nil {
+				fftLut = pf.BBDevConfig.VRB2.FFTLut
+			}
+			srsFftWindowsCoefficientFilepath, err = p.fftUpdater.VrbgetFftFilePath(p, &fftLut)
 			if err != nil {
 				p.log.WithError(err)
 				return err
@@ -152,18 +198,20 @@ func (p *pfBBConfigController) VrbinitializePfBBConfig(acc vrbv1.SriovAccelerato
 		}
 
 		if pfConfigAppFilepath == "" {
-			pfConfigAppFilepath = "/sriov_workdir/pf_bb_config"
+			pfConfigAppFilepath = defaultPfBBConfigAppFilepath
 		}
 
 		var token *string
 		if strings.EqualFold(pf.PFDriver, utils.VFIO_PCI) {
-			token = &p.sharedVfioToken
+			token = p.vfioToken()
 		}
 
-		if err := p.runPFConfig(deviceName, bbdevConfigFilepath, pf.PCIAddress, token); err != nil {
+		if err := p.runPFConfig(ctx, deviceName, bbdevConfigFilepath, pf.PCIAddress, token, ""); err != nil {
 			p.log.WithError(err).WithField("pci", pf.PCIAddress).Error("failed to configure device's queues")
 			return err
 		}
+		observePfBBConfigStartup(acc.DeviceID, pf.PCIAddress)
+		p.recordBBDevConfig(pf.PCIAddress, bbdevConfigFilepath)
 	} else {
 		p.log.Info("All sections of 'BBDevConfig' are nil - queues will not be (re)configured")
 	}
@@ -171,54 +219,72 @@ func (p *pfBBConfigController) VrbinitializePfBBConfig(acc vrbv1.SriovAccelerato
 	return nil
 }
 
+// writeBBDevConfigFile writes rawConfig into file as it is; when rawConfig is nil, file is created with generate
+func (p *pfBBConfigController) writeBBDevConfigFile(rawConfig []byte, file string, generate func() error) error {
+	if rawConfig == nil {
+		return generate()
+	}
+	p.log.WithField("file", file).Info("using bbdev config file read from bbDevConfigFrom")
+	if err := os.WriteFile(file, rawConfig, 0644); err != nil {
+		return fmt.Errorf("unable to write config to file: %s - %v", file, err)
+	}
+	return nil
+}
+
 // runPFConfig executes a pf-bb-config tool
 // deviceName is one of: FPGA_LTE or FPGA_5GNR or ACC100
 // cfgFilepath is a filepath to the config
 // pciAddress points to a specific PF device
-func (p *pfBBConfigController) runPFConfig(deviceName, cfgFilepath, pciAddress string, token *string) error {
+// fftLutFile is SRS FFT LUT passed to pf-bb-config of ACC100, if not empty
+func (p *pfBBConfigController) runPFConfig(ctx context.Context, deviceName, cfgFilepath, pciAddress string, token *string, fftLutFile string) error {
 	switch deviceName {
 	case "FPGA_LTE", "FPGA_5GNR", "ACC100", "ACC200", "VRB1", "VRB2":
 	default:
 		return fmt.Errorf("incorrect deviceName for pf config: %s", deviceName)
 	}
+	// NodeConfig may be deleted and recreated while pf_bb_config is still serving the device,
+	// make sure the previous instance is gone before starting a new one
+	if err := terminatePfBBConfig(pciAddress, p.log); err != nil {
+		return err
+	}
+	// only startup of pf-bb-config is timed, termination of the previous instance is not
+	run := func(args []string) error {
+		started := daemonClock.Elapsed()
+		_, err := runPfBBConfigCmd(ctx, args, p.log)
+		if err == nil {
+			pfBBConfigStartups.record(pciAddress, daemonClock.Elapsed()-started)
+			pfBBConfigCpuSets.record(pciAddress, cpuAffinityFrom(ctx))
+		}
+		return err
+	}
 	if token == nil {
 		if deviceName == "ACC200" || deviceName == "VRB1" {
-			_, err := runExecCmd([]string{pfConfigAppFilepath, "VRB1", "-c", cfgFilepath, "-p", pciAddress, "-f", srsFftWindowsCoefficientFilepath}, p.log)
-			return err
+			return run([]string{pfConfigAppFilepath, "VRB1", "-c", cfgFilepath, "-p", pciAddress, "-f", srsFftWindowsCoefficientFilepath})
 		} else if deviceName == "VRB2" {
-			_, err := runExecCmd([]string{pfConfigAppFilepath, deviceName, "-c", cfgFilepath, "-p", pciAddress, "-f", srsFftWindowsCoefficientFilepath}, p.log)
-			return err
+			return run([]string{pfConfigAppFilepath, deviceName, "-c", cfgFilepath, "-p", pciAddress, "-f", srsFftWindowsCoefficientFilepath})
 		} else {
-			_, err := runExecCmd([]string{pfConfigAppFilepath, deviceName, "-c", cfgFilepath, "-p", pciAddress}, p.log)
-			return err
+			return run(withFFTLut([]string{pfConfigAppFilepath, deviceName, "-c", cfgFilepath, "-p", pciAddress}, fftLutFile))
 		}
 	} else {
 		if deviceName == "ACC200" || deviceName == "VRB1" {
-			_, err := runExecCmd([]string{pfConfigAppFilepath, "VRB1", "-c", cfgFilepath, "-v", *token, "-p", pciAddress, "-f", srsFftWindowsCoefficientFilepath}, p.log)
-			return err
+			return run([]string{pfConfigAppFilepath, "VRB1", "-c", cfgFilepath, "-v", *token, "-p", pciAddress, "-f", srsFftWindowsCoefficientFilepath})
 		} else if deviceName == "VRB2" {
-			_, err := runExecCmd([]string{pfConfigAppFilepath, deviceName, "-c", cfgFilepath, "-v", *token, "-p", pciAddress, "-f", srsFftWindowsCoefficientFilepath}, p.log)
-			return err
+			return run([]string{pfConfigAppFilepath, deviceName, "-c", cfgFilepath, "-v", *token, "-p", pciAddress, "-f", srsFftWindowsCoefficientFilepath})
 		} else {
-			_, err := runExecCmd([]string{pfConfigAppFilepath, deviceName, "-c", cfgFilepath, "-v", *token, "-p", pciAddress}, p.log)
-			return err
+			return run(withFFTLut([]string{pfConfigAppFilepath, deviceName, "-c", cfgFilepath, "-v", *token, "-p", pciAddress}, fftLutFile))
 		}
 	}
 }
 
+func withFFTLut(args []string, fftLutFile string) []string {
+	if fftLutFile == "" {
+		return args
+	}
+	return append(args, "-f", fftLutFile)
+}
+
 func (p *pfBBConfigController) stopPfBBConfig(pciAddress string) error {
-	_, err := execAndSuppress([]string{
-		"pkill",
-		"-9",
-		"-f",
-		fmt.Sprintf("pf_bb_config.*%s", pciAddress),
-	}, p.log, func(e error) bool {
-		if ee, ok := e.(*exec.ExitError); ok && ee.ExitCode() == 1 {
-			p.log.Info("ignoring errorCode(1) returned by pkill")
-			return true
-		}
-		return false
-	})
+	err := terminatePfBBConfig(pciAddress, p.log)
 
 	//TODO: Remove workaround
 	//Code below implements workaround problem related with pf_bb_config app. Ticket describing an issue SCSY-190446
@@ -251,6 +317,16 @@ func (f *fftUpdater) getFftFilePath(p *pfBBConfigController, pf *sriovv2.FFTLutP
 	return "", fmt.Errorf("missing one of the FFT parameters")
 }
 
+// fetchFftTarFile downloads FFT tar file unless file with the same checksum was already downloaded, so that
+// the FFT file can be applied again without egress (e.g. after daemon restart in disconnected cluster)
+func (f *fftUpdater) fetchFftTarFile(fftTarFile, fftUrl, fftChecksum string) error {
+	if downloaded, err := verifyChecksum(fftTarFile, fftChecksum); err == nil && downloaded {
+		f.log.WithField("file", fftTarFile).Info("FFT tar file with matching checksum already present - skipping download")
+		return nil
+	}
+	return downloadFile(fftTarFile, fftUrl, fftChecksum, f.httpClient)
+}
+
 func (f *fftUpdater) updateFftFile(fft *sriovv2.FFTLutParam) (string, error) {
 	var newFftFile string
 	fftUrl := fft.FftUrl
@@ -262,14 +338,13 @@ func (f *fftUpdater) updateFftFile(fft *sriovv2.FFTLutParam) (string, error) {
 	fftTarFile := filepath.Join(targetPath, filepath.Base(fftUrl))
 	f.log.Info("Downloading FFT tar file from url", fftUrl)
 
-	err := downloadFile(fftTarFile, fftUrl, fftChecksum, f.httpClient)
-	if err != nil {
+	if err := f.fetchFftTarFile(fftTarFile, fftUrl, fftChecksum); err != nil {
 		return "", err
 	}
 
 	f.log.Info("FFT file downloaded successfully - now extracting...")
 
-	newFftFile, err = untarFile(fftTarFile, targetPath, log)
+	newFftFile, err := untarFile(fftTarFile, targetPath, log)
 	if err != nil {
 		log.Error("Error in extracting the file")
 		return "", err
@@ -306,14 +381,13 @@ func (f *fftUpdater) VrbupdateFftFile(fft *vrbv1.FFTLutParam) (string, error) {
 	fftTarFile := filepath.Join(targetPath, filepath.Base(fftUrl))
 	f.log.Info("Downloading FFT tar file from url", fftUrl)
 
-	err := downloadFile(fftTarFile, fftUrl, fftChecksum, f.httpClient)
-	if err != nil {
+	if err := f.fetchFftTarFile(fftTarFile, fftUrl, fftChecksum); err != nil {
 		return "", err
 	}
 
 	f.log.Info("FFT file downloaded successfully - now extracting...")
 
-	newFftFile, err = untarFile(fftTarFile, targetPath, log)
+	newFftFile, err := untarFile(fftTarFile, targetPath, log)
 	if err != nil {
 		log.Error("Error in extracting the file")
 		return "", err
@@ -0,0 +1,98 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// bbDevConfigFieldDisruptive classifies BBDevConfig fields, identified by JSON path (e.g. n3000.downlink.bandwidth),
+// into disruptive ones (true) - which require VFs to be recreated and so the node to be drained - and non-disruptive
+// ones (false), which are applied just by restarting pf-bb-config with new cfg file. Field not listed is classified
+// by its closest listed parent; fields without any listed parent are disruptive.
+var bbDevConfigFieldDisruptive = map[string]bool{
+	"n3000.networkType": true,
+	"n3000.pfMode":      true,
+	"n3000.flrTimeout":  false,
+	"n3000.downlink":    true,
+	"n3000.uplink":      true,
+	"acc100":            true,
+	"acc200":            true,
+	"vrb1":              true,
+	"vrb2":              true,
+}
+
+// isDisruptiveBBDevConfigField returns classification of BBDevConfig field identified by JSON path
+func isDisruptiveBBDevConfigField(path string) bool {
+	for field := path; field != ""; {
+		if disruptive, known := bbDevConfigFieldDisruptive[field]; known {
+			return disruptive
+		}
+		i := strings.LastIndex(field, ".")
+		if i < 0 {
+			break
+		}
+		field = field[:i]
+	}
+	return true
+}
+
+// changedBBDevConfigFields returns sorted JSON paths of leaf fields which differ between BBDevConfigs
+func changedBBDevConfigFields(old, new interface{}) ([]string, error) {
+	oldFields, err := flattenJSON(old)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := flattenJSON(new)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
+	for path, value := range newFields {
+		if oldValue, ok := oldFields[path]; !ok || !reflect.DeepEqual(oldValue, value) {
+			changed = append(changed, path)
+		}
+	}
+	for path := range oldFields {
+		if _, ok := newFields[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// flattenJSON returns values of leaf fields of JSON representation of v, by JSON path
+func flattenJSON(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var tree interface{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+
+	fields := map[string]interface{}{}
+	var walk func(prefix string, node interface{})
+	walk = func(prefix string, node interface{}) {
+		object, ok := node.(map[string]interface{})
+		if !ok {
+			fields[prefix] = node
+			return
+		}
+		for key, value := range object {
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			walk(key, value)
+		}
+	}
+	walk("", tree)
+	return fields, nil
+}
@@ -0,0 +1,161 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+
+	"gopkg.in/ini.v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	fec "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+)
+
+// bbDevConfigRef points to pf_bb_config cfg file stored in ConfigMap referenced by physical function's bbDevConfigFrom
+type bbDevConfigRef struct {
+	pciAddress string
+	name       string
+	key        string
+}
+
+func fecBBDevConfigRefs(pfs []fec.PhysicalFunctionConfigExt) (refs []bbDevConfigRef) {
+	for _, pf := range pfs {
+		if pf.BBDevConfigFrom != nil {
+			refs = append(refs, bbDevConfigRef{pf.PCIAddress, pf.BBDevConfigFrom.ConfigMapRef.Name, pf.BBDevConfigFrom.ConfigMapRef.Key})
+		}
+	}
+	return
+}
+
+func vrbBBDevConfigRefs(pfs []vrbv1.PhysicalFunctionConfigExt) (refs []bbDevConfigRef) {
+	for _, pf := range pfs {
+		if pf.BBDevConfigFrom != nil {
+			refs = append(refs, bbDevConfigRef{pf.PCIAddress, pf.BBDevConfigFrom.ConfigMapRef.Name, pf.BBDevConfigFrom.ConfigMapRef.Key})
+		}
+	}
+	return
+}
+
+// readBBDevConfigFrom returns validated content of cfg file referenced by ref; ConfigMap is read from given namespace
+func readBBDevConfigFrom(ctx context.Context, c client.Reader, namespace string, ref bbDevConfigRef) ([]byte, error) {
+	cm := &corev1.ConfigMap{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.name}, cm); err != nil {
+		return nil, fmt.Errorf("failed to get ConfigMap %s referenced by bbDevConfigFrom of %s - %v", ref.name, ref.pciAddress, err)
+	}
+
+	content, ok := cm.Data[ref.key]
+	if !ok {
+		return nil, fmt.Errorf("ConfigMap %s referenced by bbDevConfigFrom of %s does not contain key %s", ref.name, ref.pciAddress, ref.key)
+	}
+
+	if err := validateBBDevConfigFile([]byte(content)); err != nil {
+		return nil, fmt.Errorf("cfg file %s/%s referenced by bbDevConfigFrom of %s is invalid - %v", ref.name, ref.key, ref.pciAddress, err)
+	}
+	return []byte(content), nil
+}
+
+// validateBBDevConfigFile performs syntactic sanity check of pf_bb_config cfg file: it has to be parsable ini file
+// with at least one section and no empty sections. Values are validated by pf_bb_config itself.
+func validateBBDevConfigFile(content []byte) error {
+	cfg, err := ini.Load(content)
+	if err != nil {
+		return err
+	}
+
+	sections := 0
+	for _, section := range cfg.Sections() {
+		if section.Name() == ini.DefaultSection {
+			continue
+		}
+		if len(section.Keys()) == 0 {
+			return fmt.Errorf("section [%s] does not contain any keys", section.Name())
+		}
+		sections++
+	}
+
+	if sections == 0 {
+		return fmt.Errorf("cfg file does not contain any sections")
+	}
+	return nil
+}
+
+func bbDevConfigHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// bbDevConfigHashes returns hashes of referenced cfg files by PCI address of physical function; nil when nothing is referenced
+func (r *NodeConfigReconciler) bbDevConfigHashes(ctx context.Context, refs []bbDevConfigRef) (map[string]string, error) {
+	if len(refs) == 0 {
+		return nil, nil
+	}
+
+	hashes := make(map[string]string, len(refs))
+	for _, ref := range refs {
+		content, err := readBBDevConfigFrom(ctx, r, r.nodeNameRef.Namespace, ref)
+		if err != nil {
+			return nil, err
+		}
+		hashes[ref.pciAddress] = bbDevConfigHash(content)
+	}
+	return hashes, nil
+}
+
+func bbDevConfigHashesChanged(current, applied map[string]string) bool {
+	if len(current) == 0 && len(applied) == 0 {
+		return false
+	}
+	return !reflect.DeepEqual(current, applied)
+}
+
+// requestsForConfigMap maps ConfigMap event to reconcile requests of node configs (including secondary SriovFecNodeConfigs
+// of the node), provided that the ConfigMap is referenced by them
+func (r *NodeConfigReconciler) requestsForConfigMap(cm client.Object, nc client.Object) []reconcile.Request {
+	if cm.GetNamespace() != r.nodeNameRef.Namespace {
+		return nil
+	}
+
+	var nodeConfigs []client.Object
+	if err := r.Get(context.TODO(), r.nodeConfigKey(), nc); err == nil {
+		nodeConfigs = append(nodeConfigs, nc)
+	}
+	if _, ok := nc.(*fec.SriovFecNodeConfig); ok {
+		secondary, err := r.secondaryNodeConfigs(context.TODO())
+		if err != nil {
+			r.log.WithError(err).Warn("failed to find secondary node configs referencing the ConfigMap")
+		}
+		for i := range secondary {
+			nodeConfigs = append(nodeConfigs, &secondary[i])
+		}
+	}
+
+	var requests []reconcile.Request
+	for _, nodeConfig := range nodeConfigs {
+		var refs []bbDevConfigRef
+		switch nodeConfig := nodeConfig.(type) {
+		case *fec.SriovFecNodeConfig:
+			refs = fecBBDevConfigRefs(nodeConfig.Spec.PhysicalFunctions)
+		case *vrbv1.SriovVrbNodeConfig:
+			refs = vrbBBDevConfigRefs(nodeConfig.Spec.PhysicalFunctions)
+		}
+
+		for _, ref := range refs {
+			if ref.name == cm.GetName() {
+				r.log.WithField("configMap", cm.GetName()).WithField("nodeConfig", nodeConfig.GetName()).
+					Info("referenced bbDevConfig ConfigMap changed")
+				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(nodeConfig)})
+				break
+			}
+		}
+	}
+	return requests
+}
@@ -0,0 +1,118 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	fec "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+const validBBDevConfigFile = `[MODE]
+pf_mode_en = 0
+
+[VFBUNDLES]
+num_vf_bundles = 2
+`
+
+var _ = Describe("bbDevConfigFrom", func() {
+	nodeRef := types.NamespacedName{Name: "worker", Namespace: "default"}
+
+	newReconciler := func(objects ...runtime.Object) *NodeConfigReconciler {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(fec.AddToScheme(scheme)).To(Succeed())
+		Expect(vrbv1.AddToScheme(scheme)).To(Succeed())
+		return &NodeConfigReconciler{
+			Client:      fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objects...).Build(),
+			log:         utils.NewLogger(),
+			nodeNameRef: nodeRef,
+		}
+	}
+
+	configMap := func(name string, data map[string]string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: nodeRef.Namespace}, Data: data}
+	}
+
+	ref := bbDevConfigRef{pciAddress: "0000:f7:00.0", name: "acc-config", key: "vrb2.cfg"}
+
+	Context("validateBBDevConfigFile", func() {
+		It("should accept cfg file with sections", func() {
+			Expect(validateBBDevConfigFile([]byte(validBBDevConfigFile))).To(Succeed())
+		})
+
+		It("should reject malformed or empty cfg files", func() {
+			for _, content := range []string{
+				"",
+				"pf_mode_en = 0\n",
+				"[MODE]\n",
+				"[MODE\npf_mode_en = 0\n",
+			} {
+				Expect(validateBBDevConfigFile([]byte(content))).ToNot(Succeed(), content)
+			}
+		})
+	})
+
+	Context("bbDevConfigHashes", func() {
+		It("should return nil when nothing is referenced", func() {
+			hashes, err := newReconciler().bbDevConfigHashes(context.TODO(), nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hashes).To(BeNil())
+		})
+
+		It("should hash referenced cfg file", func() {
+			r := newReconciler(configMap(ref.name, map[string]string{ref.key: validBBDevConfigFile}))
+
+			hashes, err := r.bbDevConfigHashes(context.TODO(), []bbDevConfigRef{ref})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hashes).To(Equal(map[string]string{ref.pciAddress: bbDevConfigHash([]byte(validBBDevConfigFile))}))
+			Expect(bbDevConfigHashesChanged(hashes, hashes)).To(BeFalse())
+			Expect(bbDevConfigHashesChanged(hashes, nil)).To(BeTrue())
+		})
+
+		It("should fail when ConfigMap, key or content is invalid", func() {
+			_, err := newReconciler().bbDevConfigHashes(context.TODO(), []bbDevConfigRef{ref})
+			Expect(err).To(MatchError(ContainSubstring("failed to get ConfigMap")))
+
+			_, err = newReconciler(configMap(ref.name, map[string]string{"other.cfg": validBBDevConfigFile})).
+				bbDevConfigHashes(context.TODO(), []bbDevConfigRef{ref})
+			Expect(err).To(MatchError(ContainSubstring("does not contain key")))
+
+			_, err = newReconciler(configMap(ref.name, map[string]string{ref.key: "[MODE]\n"})).
+				bbDevConfigHashes(context.TODO(), []bbDevConfigRef{ref})
+			Expect(err).To(MatchError(ContainSubstring("is invalid")))
+		})
+	})
+
+	Context("requestsForConfigMap", func() {
+		It("should enqueue node config referencing the ConfigMap only", func() {
+			nc := &vrbv1.SriovVrbNodeConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: nodeRef.Name, Namespace: nodeRef.Namespace},
+				Spec: vrbv1.SriovVrbNodeConfigSpec{PhysicalFunctions: []vrbv1.PhysicalFunctionConfigExt{{
+					PCIAddress: ref.pciAddress,
+					BBDevConfigFrom: &vrbv1.BBDevConfigFromSource{
+						ConfigMapRef: vrbv1.ConfigMapKeyReference{Name: ref.name, Key: ref.key},
+					},
+				}}},
+			}
+			r := newReconciler(nc)
+
+			Expect(r.requestsForConfigMap(configMap(ref.name, nil), &vrbv1.SriovVrbNodeConfig{})).
+				To(Equal([]reconcile.Request{{NamespacedName: nodeRef}}))
+			Expect(r.requestsForConfigMap(configMap("unrelated", nil), &vrbv1.SriovVrbNodeConfig{})).To(BeEmpty())
+			Expect(r.requestsForConfigMap(configMap(ref.name, nil), &fec.SriovFecNodeConfig{})).To(BeEmpty())
+		})
+	})
+})
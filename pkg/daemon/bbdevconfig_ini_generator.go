@@ -12,6 +12,7 @@ import (
 	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
 	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
 	"gopkg.in/ini.v1"
+	"k8s.io/utils/pointer"
 )
 
 func generateBBDevConfigFile(bbDevConfig sriovv2.BBDevConfig, file string) (err error) {
@@ -27,6 +28,7 @@ func generateBBDevConfigFile(bbDevConfig sriovv2.BBDevConfig, file string) (err
 		if iniFile, err = createIniFileContent(acc100BBDevConfigToIniStruct, bbDevConfig.ACC100); err != nil {
 			return fmt.Errorf("creation of pf_bb_config config file for ACC100 failed, %s", err)
 		}
+		setInterruptMode(iniFile, bbDevConfig.ACC100.InterruptMode)
 	case bbDevConfig.ACC200 != nil:
 		if iniFile, err = createIniFileContent(acc200BBDevConfigToIniStruct, bbDevConfig.ACC200); err != nil {
 			return fmt.Errorf("creation of pf_bb_config config file for ACC200 failed, %s", err)
@@ -272,7 +274,7 @@ func n3000BBDevConfigToIniStruct(in *sriovv2.N3000BBDevConfig) interface{} {
 		FLR: struct {
 			FLR int `ini:"flr_time_out"`
 		}{
-			FLR: in.FLRTimeOut,
+			FLR: pointer.IntDeref(in.FLRTimeOut, 0),
 		},
 
 		Downlink: struct {
@@ -4,8 +4,12 @@
 package daemon
 
 import (
+	"context"
 	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 	"testing"
@@ -16,6 +20,7 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/sirupsen/logrus"
+	"k8s.io/utils/pointer"
 )
 
 func compareFiles(firstFilepath, secondFilepath string) error {
@@ -65,7 +70,7 @@ var _ = Describe("bbdevconfig", func() {
 					VF7: 0,
 				},
 			},
-			FLRTimeOut: 21,
+			FLRTimeOut: pointer.Int(21),
 		},
 	}
 	sampleBBDevConfig1 := sriovv2.BBDevConfig{
@@ -176,6 +181,29 @@ var _ = Describe("bbdevconfig", func() {
 			Expect(err).To(HaveOccurred())
 		})
 	})
+	var _ = Describe("fetchFftTarFile", func() {
+		var _ = It("will not download FFT tar file which is already present", func() {
+			originalDownloadFile := downloadFile
+			defer func() { downloadFile = originalDownloadFile }()
+			downloaded := false
+			downloadFile = func(path, url, checksum string, client *http.Client) error {
+				downloaded = true
+				return nil
+			}
+
+			tarFile := filepath.Join(testTmpFolder, "fft.tar.gz")
+			Expect(os.WriteFile(tarFile, []byte("fft"), 0644)).To(Succeed())
+			f := &fftUpdater{log: utils.NewLogger()}
+
+			Expect(f.fetchFftTarFile(tarFile, "https://artifacts/fft.tar.gz", "0000000000000000000000000000000000000000")).To(Succeed())
+			Expect(downloaded).To(BeTrue())
+
+			downloaded = false
+			sum := sha1.Sum([]byte("fft"))
+			Expect(f.fetchFftTarFile(tarFile, "https://artifacts/fft.tar.gz", hex.EncodeToString(sum[:]))).To(Succeed())
+			Expect(downloaded).To(BeFalse())
+		})
+	})
 })
 
 func Test(t *testing.T) {
@@ -222,7 +250,7 @@ func Test(t *testing.T) {
 	}
 }
 func FuzzInitializePfBBConfig(f *testing.F) {
-	pfbb := pfBBConfigController{
+	pfbb := &pfBBConfigController{
 		log:               &logrus.Logger{},
 		sharedVfioToken:   "",
 		fftUpdater:        &fftUpdater{},
@@ -256,6 +284,6 @@ func FuzzInitializePfBBConfig(f *testing.F) {
 				t.Errorf("Error: %v", pfbb)
 			}
 		}()
-		_ = pfbb.initializePfBBConfig(accData, &pfData)
+		_ = pfbb.initializePfBBConfig(context.TODO(), accData, &pfData, nil)
 	})
 }
@@ -0,0 +1,144 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("NodeConfigReconciler.Reconcile cancellation", func() {
+	var (
+		fakeClient  client.Client
+		nodeNameRef = types.NamespacedName{Name: "worker", Namespace: "testNamespace"}
+		reconciler  NodeConfigReconciler
+	)
+
+	BeforeEach(func() {
+		scheme := k8sruntime.NewScheme()
+		Expect(sriovv2.AddToScheme(scheme)).To(Succeed())
+		Expect(vrbv1.AddToScheme(scheme)).To(Succeed())
+		Expect(corev1.AddToScheme(scheme)).To(Succeed())
+
+		procCmdlineFilePath = "testdata/cmdline_test"
+		sysLockdownFilePath = filepath.Join(testTmpFolder, "lockdown")
+		Expect(os.WriteFile(sysLockdownFilePath, []byte("[none] integrity confidentiality"), 0644)).To(Succeed())
+
+		getSriovInventory = func(_ context.Context, log *logrus.Logger) (*sriovv2.NodeInventory, error) {
+			return &sriovv2.NodeInventory{
+				SriovAccelerators: []sriovv2.SriovAccelerator{{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, MaxVFs: 10}},
+			}, nil
+		}
+		VrbgetSriovInventory = func(_ context.Context, log *logrus.Logger) (*vrbv1.NodeInventory, error) {
+			return &vrbv1.NodeInventory{}, nil
+		}
+
+		fakeClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&sriovv2.SriovFecNodeConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace, Generation: 1},
+				Spec: sriovv2.SriovFecNodeConfigSpec{
+					DrainSkip:         true,
+					PhysicalFunctions: []sriovv2.PhysicalFunctionConfigExt{{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, VFDriver: utils.IGB_UIO, VFAmount: 1}},
+				},
+			},
+			&vrbv1.SriovVrbNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace}},
+			// device plugin is never recreated, so restart waits for it until reconcile is cancelled
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "sriov-device-plugin-abcde", Namespace: nodeNameRef.Namespace, Labels: devicePluginSelector,
+					OwnerReferences: controlledBy("DaemonSet", "sriov-device-plugin")},
+				Spec: corev1.PodSpec{NodeName: nodeNameRef.Name},
+			},
+		).Build()
+
+		reconciler = NodeConfigReconciler{
+			Client:      fakeClient,
+			log:         utils.NewLogger(),
+			nodeNameRef: nodeNameRef,
+			sriovfecconfigurer: testConfigurerProto{configureNodeFunction: func(sriovv2.SriovFecNodeConfigSpec) error {
+				return nil
+			}},
+			drainerAndExecute: func(ctx context.Context, configurer func(ctx context.Context) bool, drain bool) error {
+				_ = configurer(ctx)
+				return nil
+			},
+			restartDevicePlugin: NewDevicePluginController(fakeClient, utils.NewLogger(), nodeNameRef, record.NewFakeRecorder(10)).RestartDevicePlugin,
+		}
+	})
+
+	AfterEach(func() {
+		getSriovInventory = GetSriovInventory
+		VrbgetSriovInventory = VrbGetSriovInventory
+		sysLockdownFilePath = "/sys/kernel/security/lockdown"
+	})
+
+	It("returns promptly without leaking goroutines when context is cancelled mid-reconcile", func() {
+		goroutines := runtime.NumGoroutine()
+		ctx, cancel := context.WithCancel(context.TODO())
+		defer cancel()
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: nodeNameRef})
+			done <- err
+		}()
+
+		// device plugin pod is deleted once configuration is applied; reconcile is waiting for its replacement then
+		Eventually(func() []corev1.Pod {
+			pods := &corev1.PodList{}
+			Expect(fakeClient.List(context.TODO(), pods)).To(Succeed())
+			return pods.Items
+		}, 5*time.Second, 10*time.Millisecond).Should(BeEmpty())
+		Consistently(done, 200*time.Millisecond).ShouldNot(Receive())
+
+		cancel()
+		Eventually(done, 500*time.Millisecond).Should(Receive(BeNil()))
+		Expect(reconciler.IsConfigurationInProgress()).To(BeFalse())
+
+		nc := &sriovv2.SriovFecNodeConfig{}
+		Expect(fakeClient.Get(context.TODO(), nodeNameRef, nc)).To(Succeed())
+		condition := nc.FindCondition(ConditionConfigured)
+		Expect(condition.Reason).To(Equal(string(ConfigurationFailed)))
+		Expect(condition.Message).To(ContainSubstring(context.Canceled.Error()))
+
+		Eventually(runtime.NumGoroutine, time.Second, 10*time.Millisecond).Should(BeNumerically("<=", goroutines))
+	})
+
+	It("gives up writing sysfs file when context is cancelled", func() {
+		// opening FIFO for writing blocks until it is opened for reading, like write to a sysfs file of busy device
+		fifo := filepath.Join(testTmpFolder, "sriov_numvfs")
+		Expect(syscall.Mkfifo(fifo, 0644)).To(Succeed())
+		defer os.Remove(fifo)
+
+		ctx, cancel := context.WithTimeout(context.TODO(), 100*time.Millisecond)
+		defer cancel()
+		started := time.Now()
+		Expect(writeFileWithTimeout(ctx, fifo, "1")).To(MatchError(ContainSubstring("interrupted")))
+		Expect(time.Since(started)).To(BeNumerically("<", sysfsWriteTimeout))
+
+		// unblock the writer, so that it does not outlive the test
+		reader, err := os.OpenFile(fifo, os.O_RDONLY, 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reader.Close()).To(Succeed())
+	})
+})
@@ -0,0 +1,202 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+const (
+	pfBBConfigOutputLimitEnvVarName = utils.SRIOV_PREFIX + "PF_BB_CONFIG_OUTPUT_LIMIT_KB"
+	defaultPfBBConfigOutputLimitKB  = 4
+	// retained output is published in Configured condition message, which is limited to 32KB
+	maxPfBBConfigOutputLimitKB = 16
+	// size of output's head and tail logged at Info level
+	outputSummarySize = 512
+)
+
+// pfBBConfigOutputLimit returns how many bytes of pf_bb_config output are retained; it is configured in KB with
+// PF_BB_CONFIG_OUTPUT_LIMIT_KB env variable
+func pfBBConfigOutputLimit(log *logrus.Logger) int {
+	limit := defaultPfBBConfigOutputLimitKB
+	limitEnv := os.Getenv(pfBBConfigOutputLimitEnvVarName)
+	if limitEnv != "" {
+		envLimit, err := strconv.Atoi(limitEnv)
+		if err != nil || envLimit <= 0 || envLimit > maxPfBBConfigOutputLimitKB {
+			log.WithError(err).WithField("default", limit).WithField("max", maxPfBBConfigOutputLimitKB).
+				Error("user-provided value is incorrect number of KB, using default value instead")
+		} else {
+			limit = envLimit
+		}
+	}
+	return limit * 1024
+}
+
+// ringBuffer is io.Writer which retains only last len(buf) bytes of written stream
+type ringBuffer struct {
+	buf     []byte
+	pos     int
+	full    bool
+	written int64
+}
+
+func newRingBuffer(size int) *ringBuffer {
+	return &ringBuffer{buf: make([]byte, size)}
+}
+
+func (b *ringBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	b.written += int64(n)
+	if len(b.buf) == 0 {
+		return n, nil
+	}
+	if len(p) > len(b.buf) {
+		p = p[len(p)-len(b.buf):]
+	}
+
+	copied := copy(b.buf[b.pos:], p)
+	copy(b.buf, p[copied:])
+	b.full = b.full || b.pos+len(p) >= len(b.buf)
+	b.pos = (b.pos + len(p)) % len(b.buf)
+	return n, nil
+}
+
+// Truncated tells whether beginning of the stream was dropped
+func (b *ringBuffer) Truncated() bool {
+	return b.written > int64(len(b.buf))
+}
+
+// String returns retained part of the stream; bytes of multi-byte UTF-8 character cut by truncation are skipped
+func (b *ringBuffer) String() string {
+	if !b.full {
+		return string(b.buf[:b.pos])
+	}
+	out := append(append(make([]byte, 0, len(b.buf)), b.buf[b.pos:]...), b.buf[:b.pos]...)
+	if b.Truncated() {
+		out = trimLeadingPartialRune(out)
+	}
+	return string(out)
+}
+
+// headBuffer is io.Writer which retains only first len(buf) bytes of written stream
+type headBuffer struct {
+	buf  []byte
+	size int
+}
+
+func (b *headBuffer) Write(p []byte) (int, error) {
+	if free := b.size - len(b.buf); free > 0 {
+		if len(p) > free {
+			b.buf = append(b.buf, p[:free]...)
+		} else {
+			b.buf = append(b.buf, p...)
+		}
+	}
+	return len(p), nil
+}
+
+// String returns retained part of the stream; bytes of multi-byte UTF-8 character cut by truncation are skipped
+func (b *headBuffer) String() string {
+	return string(trimTrailingPartialRune(b.buf))
+}
+
+func trimLeadingPartialRune(p []byte) []byte {
+	for i := 0; i < utf8.UTFMax-1 && len(p) > 0 && !utf8.RuneStart(p[0]); i++ {
+		p = p[1:]
+	}
+	return p
+}
+
+func trimTrailingPartialRune(p []byte) []byte {
+	for i := 1; i < utf8.UTFMax && i <= len(p); i++ {
+		if utf8.RuneStart(p[len(p)-i]) {
+			if !utf8.FullRune(p[len(p)-i:]) {
+				return p[:len(p)-i]
+			}
+			break
+		}
+	}
+	return p
+}
+
+// traceWriter logs written stream line by line at Trace level
+type traceWriter struct {
+	entry   *logrus.Entry
+	partial []byte
+}
+
+func (w *traceWriter) Write(p []byte) (int, error) {
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		w.entry.Trace(string(w.partial[:i]))
+		w.partial = w.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush logs last line which is not terminated with new line
+func (w *traceWriter) Flush() {
+	if len(w.partial) > 0 {
+		w.entry.Trace(string(w.partial))
+		w.partial = nil
+	}
+}
+
+// execWithCappedOutput executes command and returns last limit bytes of its (combined) output. Only head and tail of
+// the output are logged at Info level, full stream is logged at Trace level.
+func execWithCappedOutput(ctx context.Context, args []string, log *logrus.Logger, limit int) (string, error) {
+	if len(args) == 0 {
+		log.Error("provided cmd is empty")
+		return "", errors.New("cmd is empty")
+	}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	log.WithField("cmd", cmd).Info("executing command")
+
+	head := &headBuffer{size: outputSummarySize}
+	tail := newRingBuffer(limit)
+	summaryTail := newRingBuffer(outputSummarySize)
+	writers := []io.Writer{head, tail, summaryTail}
+	if log.IsLevelEnabled(logrus.TraceLevel) {
+		trace := &traceWriter{entry: log.WithField("cmd", args[0])}
+		defer trace.Flush()
+		writers = append(writers, trace)
+	}
+	cmd.Stdout = io.MultiWriter(writers...)
+	cmd.Stderr = cmd.Stdout
+
+	err := runWithAffinity(cmd, cpuAffinityFrom(ctx))
+
+	entry := log.WithField("cmd", args).WithField("bytes", tail.written)
+	if summaryTail.Truncated() {
+		entry = entry.WithField("head", head.String()).WithField("tail", summaryTail.String())
+	} else {
+		entry = entry.WithField("output", summaryTail.String())
+	}
+	if err != nil {
+		entry.WithError(err).Error("failed to execute command")
+		return tail.String(), fmt.Errorf("%v, output: %s", err, tail.String())
+	}
+	entry.Info("commands output")
+	return tail.String(), nil
+}
+
+func execPfBBConfigCmd(ctx context.Context, args []string, log *logrus.Logger) (string, error) {
+	return execWithCappedOutput(ctx, args, log, pfBBConfigOutputLimit(log))
+}
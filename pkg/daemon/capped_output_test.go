@@ -0,0 +1,135 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+var _ = Describe("CappedOutput", func() {
+	Context("ringBuffer", func() {
+		It("should retain whole stream when it fits", func() {
+			b := newRingBuffer(8)
+			_, _ = b.Write([]byte("abc"))
+			_, _ = b.Write([]byte("defgh"))
+			Expect(b.String()).To(Equal("abcdefgh"))
+			Expect(b.Truncated()).To(BeFalse())
+		})
+
+		It("should retain last bytes of the stream", func() {
+			b := newRingBuffer(8)
+			for _, chunk := range []string{"abc", "defgh", "ij", "klmnopqrstu", "vw"} {
+				n, err := b.Write([]byte(chunk))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(len(chunk)))
+			}
+			Expect(b.String()).To(Equal("pqrstuvw"))
+			Expect(b.Truncated()).To(BeTrue())
+			Expect(b.written).To(BeEquivalentTo(23))
+		})
+
+		It("should not start with partial multi-byte character", func() {
+			// 'ż' is 2 bytes, '€' is 3 bytes long
+			for size := 1; size <= 12; size++ {
+				b := newRingBuffer(size)
+				_, _ = b.Write([]byte("żółw€"))
+				_, _ = b.Write([]byte("€ż"))
+				out := b.String()
+				Expect(utf8.ValidString(out)).To(BeTrue(), fmt.Sprintf("size %d: %q", size, out))
+				Expect("żółw€€ż").To(HaveSuffix(out))
+				Expect(len(out)).To(BeNumerically(">", size-utf8.UTFMax))
+			}
+		})
+	})
+
+	Context("headBuffer", func() {
+		It("should retain first bytes and not end with partial multi-byte character", func() {
+			for size := 1; size <= 12; size++ {
+				b := &headBuffer{size: size}
+				_, _ = b.Write([]byte("€żółw"))
+				_, _ = b.Write([]byte("€"))
+				out := b.String()
+				Expect(utf8.ValidString(out)).To(BeTrue(), fmt.Sprintf("size %d: %q", size, out))
+				Expect("€żółw€").To(HavePrefix(out))
+				Expect(len(out)).To(BeNumerically(">", size-utf8.UTFMax))
+			}
+		})
+	})
+
+	Context("execWithCappedOutput", func() {
+		var (
+			log  *logrus.Logger
+			hook *test.Hook
+		)
+
+		BeforeEach(func() {
+			log, hook = test.NewNullLogger()
+		})
+
+		AfterEach(func() {
+			Expect(os.Unsetenv(pfBBConfigOutputLimitEnvVarName)).To(Succeed())
+		})
+
+		verboseCmd := []string{"sh", "-c", `i=0; while [ $i -lt 1000 ]; do echo "line $i"; i=$((i+1)); done; echo "error" >&2`}
+
+		It("should return tail of combined output and log its summary at Info level", func() {
+			out, err := execWithCappedOutput(context.TODO(), verboseCmd, log, 100)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(out)).To(Equal(100))
+			Expect(out).To(HaveSuffix("line 999\nerror\n"))
+
+			entry := hook.LastEntry()
+			Expect(entry.Level).To(Equal(logrus.InfoLevel))
+			Expect(entry.Data["head"]).To(HavePrefix("line 0\nline 1\n"))
+			Expect(len(entry.Data["head"].(string))).To(Equal(outputSummarySize))
+			Expect(entry.Data["tail"]).To(HaveSuffix("error\n"))
+			Expect(entry.Data["bytes"]).To(BeEquivalentTo(8896))
+			for _, e := range hook.AllEntries() {
+				Expect(e.Level).ToNot(Equal(logrus.TraceLevel))
+			}
+		})
+
+		It("should log full output at Trace level", func() {
+			log.SetLevel(logrus.TraceLevel)
+			_, err := execWithCappedOutput(context.TODO(), verboseCmd, log, 100)
+			Expect(err).ToNot(HaveOccurred())
+
+			var traced []string
+			for _, e := range hook.AllEntries() {
+				if e.Level == logrus.TraceLevel {
+					traced = append(traced, e.Message)
+				}
+			}
+			Expect(traced).To(HaveLen(1001))
+			Expect(traced[0]).To(Equal("line 0"))
+		})
+
+		It("should log short output as a whole and report tail in error", func() {
+			out, err := execWithCappedOutput(context.TODO(), []string{"sh", "-c", "echo failure; exit 3"}, log, 100)
+			Expect(err).To(MatchError("exit status 3, output: failure\n"))
+			Expect(out).To(Equal("failure\n"))
+			Expect(hook.LastEntry().Level).To(Equal(logrus.ErrorLevel))
+			Expect(hook.LastEntry().Data).To(HaveKeyWithValue("output", "failure\n"))
+		})
+
+		It("should use configured output limit", func() {
+			Expect(pfBBConfigOutputLimit(log)).To(Equal(defaultPfBBConfigOutputLimitKB * 1024))
+			Expect(os.Setenv(pfBBConfigOutputLimitEnvVarName, "8")).To(Succeed())
+			Expect(pfBBConfigOutputLimit(log)).To(Equal(8 * 1024))
+			for _, invalid := range []string{"0", "-1", "abc", strings.Repeat("9", 3)} {
+				Expect(os.Setenv(pfBBConfigOutputLimitEnvVarName, invalid)).To(Succeed())
+				Expect(pfBBConfigOutputLimit(log)).To(Equal(defaultPfBBConfigOutputLimitKB * 1024))
+			}
+		})
+	})
+})
@@ -0,0 +1,44 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import "time"
+
+// clock separates wall time from time elapsed within the daemon. Wall time is the one persisted in status and node
+// annotations and compared with timestamps read back from them; it may jump, e.g. on NTP step correction of freshly
+// provisioned node. Durations measured by the daemon itself (soak, cordon age) use elapsed time, which is monotonic.
+type clock interface {
+	// Now returns wall time
+	Now() time.Time
+	// Elapsed returns monotonic time elapsed since an arbitrary point in the past
+	Elapsed() time.Duration
+	// After sends current wall time on the returned channel once d of elapsed time passes
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct {
+	start time.Time
+}
+
+func (c realClock) Now() time.Time {
+	return time.Now()
+}
+
+// Elapsed relies on monotonic clock reading carried by time.Time returned by time.Now
+func (c realClock) Elapsed() time.Duration {
+	return time.Since(c.start)
+}
+
+func (c realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// daemonClock is replaced by tests which simulate passing time and clock jumps
+var daemonClock clock = realClock{start: time.Now()}
+
+// WallTime returns wall time of the daemon clock; drain helper stamps the cordon with it, so that the stamp and age of
+// the cordon measured by CordonMonitor come from the same clock
+func WallTime() time.Time {
+	return daemonClock.Now()
+}
@@ -0,0 +1,116 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakeClock is a clock which time passes only when stepped; wall time can also jump without any time elapsing
+type fakeClock struct {
+	mu      sync.Mutex
+	wall    time.Time
+	elapsed time.Duration
+	waiters []fakeClockWaiter
+}
+
+type fakeClockWaiter struct {
+	at time.Duration
+	ch chan time.Time
+}
+
+func newFakeClock(wall time.Time) *fakeClock {
+	return &fakeClock{wall: wall}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.wall
+}
+
+func (c *fakeClock) Elapsed() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.elapsed
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.wall
+		return ch
+	}
+	c.waiters = append(c.waiters, fakeClockWaiter{at: c.elapsed + d, ch: ch})
+	return ch
+}
+
+// Step lets d pass, both wall and elapsed time advance
+func (c *fakeClock) Step(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.wall = c.wall.Add(d)
+	c.elapsed += d
+
+	pending := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at <= c.elapsed {
+			w.ch <- c.wall
+		} else {
+			pending = append(pending, w)
+		}
+	}
+	c.waiters = pending
+}
+
+// Jump moves wall time by d (backwards when negative), as NTP step correction does; no time elapses
+func (c *fakeClock) Jump(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.wall = c.wall.Add(d)
+}
+
+// Waiters returns number of After calls which did not fire yet
+func (c *fakeClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+var _ = Describe("inventoryRefreshDue", func() {
+	var (
+		clk                 *fakeClock
+		originalDaemonClock = daemonClock
+	)
+
+	BeforeEach(func() {
+		clk = newFakeClock(time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC))
+		daemonClock = clk
+	})
+
+	AfterEach(func() {
+		daemonClock = originalDaemonClock
+	})
+
+	It("is due once the refresh period passes", func() {
+		collectedAt := metav1.NewTime(clk.Now())
+		Expect(inventoryRefreshDue(&collectedAt)).To(BeFalse())
+		clk.Step(inventoryRefreshPeriod)
+		Expect(inventoryRefreshDue(&collectedAt)).To(BeTrue())
+		Expect(inventoryRefreshDue(nil)).To(BeTrue())
+	})
+
+	It("is due when the clock was stepped back since the inventory was stamped", func() {
+		collectedAt := metav1.NewTime(clk.Now())
+		clk.Jump(-time.Hour)
+		Expect(inventoryRefreshDue(&collectedAt)).To(BeTrue())
+	})
+})
@@ -4,31 +4,33 @@
 package daemon
 
 import (
+	"context"
 	"errors"
 	"github.com/sirupsen/logrus"
 	"os/exec"
 )
 
-func execCmd(args []string, log *logrus.Logger) (string, error) {
-	return execAndSuppress(args, log, func(error) bool {
+// execCmd runs the command; it is killed when ctx is done. It runs on CPUs of ctx affinity, if any.
+func execCmd(ctx context.Context, args []string, log *logrus.Logger) (string, error) {
+	return execAndSuppress(ctx, args, log, func(error) bool {
 		return false
 	})
 }
 
-func execAndSuppress(args []string, log *logrus.Logger, suppressError func(e error) bool) (string, error) {
+func execAndSuppress(ctx context.Context, args []string, log *logrus.Logger, suppressError func(e error) bool) (string, error) {
 	var cmd *exec.Cmd
 	if len(args) == 0 {
 		log.Error("provided cmd is empty")
 		return "", errors.New("cmd is empty")
 	} else if len(args) == 1 {
-		cmd = exec.Command(args[0])
+		cmd = exec.CommandContext(ctx, args[0])
 	} else {
-		cmd = exec.Command(args[0], args[1:]...)
+		cmd = exec.CommandContext(ctx, args[0], args[1:]...)
 	}
 
 	log.WithField("cmd", cmd).Info("executing command")
 
-	out, err := cmd.Output()
+	out, err := outputWithAffinity(cmd, cpuAffinityFrom(ctx))
 	if err != nil {
 		if suppressError(err) {
 			log.WithField("cmd", args).WithError(err).Info("ignoring error")
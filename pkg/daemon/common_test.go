@@ -4,6 +4,9 @@
 package daemon
 
 import (
+	"context"
+	"time"
+
 	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -13,16 +16,24 @@ var _ = Describe("common", func() {
 	log = utils.NewLogger()
 	var _ = Context("execCmd", func() {
 		var _ = It("will return error when args is empty ", func() {
-			_, err := execCmd([]string{}, log)
+			_, err := execCmd(context.TODO(), []string{}, log)
 			Expect(err).To(HaveOccurred())
 		})
 		var _ = It("will return error when exec doesn't exist ", func() {
-			_, err := execCmd([]string{"dummyExecFile"}, log)
+			_, err := execCmd(context.TODO(), []string{"dummyExecFile"}, log)
 			Expect(err).To(HaveOccurred())
 		})
 		var _ = It("will call exec ", func() {
-			_, err := execCmd([]string{"ls"}, log)
+			_, err := execCmd(context.TODO(), []string{"ls"}, log)
 			Expect(err).ToNot(HaveOccurred())
 		})
+		var _ = It("will kill the command when context is done ", func() {
+			ctx, cancel := context.WithTimeout(context.TODO(), 100*time.Millisecond)
+			defer cancel()
+			started := time.Now()
+			_, err := execCmd(ctx, []string{"sleep", "10"}, log)
+			Expect(err).To(HaveOccurred())
+			Expect(time.Since(started)).To(BeNumerically("<", 5*time.Second))
+		})
 	})
 })
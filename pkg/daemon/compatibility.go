@@ -0,0 +1,168 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	fec "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var (
+	kernelReleaseFilePath = "/proc/sys/kernel/osrelease"
+	getFirmwareVersion    = readFirmwareVersion
+)
+
+// readFirmwareVersion returns revision of the device as reported by sysfs (e.g. "0x01")
+func readFirmwareVersion(pciAddress string) (string, error) {
+	revision, err := os.ReadFile(filepath.Join(sysBusPciDevices, pciAddress, "revision"))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(revision)), nil
+}
+
+// readKernelRelease returns release of the running kernel (e.g. "5.14.0-284.el9.x86_64")
+func readKernelRelease() (string, error) {
+	release, err := os.ReadFile(kernelReleaseFilePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read kernel release: path: %v, error - %v", kernelReleaseFilePath, err)
+	}
+	return strings.TrimSpace(string(release)), nil
+}
+
+// compatibilityDevice identifies accelerator which is going to be configured
+type compatibilityDevice struct {
+	pciAddress string
+	deviceID   string
+}
+
+func fecCompatibilityDevices(pfs []fec.PhysicalFunctionConfigExt, inventory *fec.NodeInventory) []compatibilityDevice {
+	var devices []compatibilityDevice
+	for _, pf := range pfs {
+		for _, accelerator := range inventory.SriovAccelerators {
+			if accelerator.PCIAddress == pf.PCIAddress {
+				devices = append(devices, compatibilityDevice{pciAddress: pf.PCIAddress, deviceID: accelerator.DeviceID})
+			}
+		}
+	}
+	return devices
+}
+
+func vrbCompatibilityDevices(pfs []vrbv1.PhysicalFunctionConfigExt, inventory *vrbv1.NodeInventory) []compatibilityDevice {
+	var devices []compatibilityDevice
+	for _, pf := range pfs {
+		for _, accelerator := range inventory.SriovAccelerators {
+			if accelerator.PCIAddress == pf.PCIAddress {
+				devices = append(devices, compatibilityDevice{pciAddress: pf.PCIAddress, deviceID: accelerator.DeviceID})
+			}
+		}
+	}
+	return devices
+}
+
+// verifyCompatibility runs compatibility checks from discovery config against devices which are going to be configured.
+// If checks are enforced (default) any violation is returned as an error, otherwise violations are logged and returned
+// as a warning suffix which should be appended to the Configured condition message.
+func (r *NodeConfigReconciler) verifyCompatibility(devices []compatibilityDevice, enforce *bool, discoveryConfig utils.AcceleratorDiscoveryConfig) (string, error) {
+	violations, err := checkCompatibility(discoveryConfig.CompatibilityChecks, devices)
+	if err != nil {
+		return "", err
+	}
+	if len(violations) == 0 {
+		return "", nil
+	}
+
+	message := strings.Join(violations, "; ")
+	if enforce == nil || *enforce {
+		r.log.WithField("violations", violations).Error("compatibility checks failed, refusing configuration")
+		return "", fmt.Errorf("compatibility checks failed: %s", message)
+	}
+
+	r.log.WithField("violations", violations).Warn("compatibility checks failed, proceeding as enforcement is disabled")
+	return fmt.Sprintf(" (compatibility warnings: %s)", message), nil
+}
+
+// checkCompatibility evaluates given compatibility checks against running kernel and firmware of devices which are
+// going to be configured. Returns list of violated checks; an error is returned only if checks cannot be evaluated.
+func checkCompatibility(checks *utils.CompatibilityChecks, devices []compatibilityDevice) ([]string, error) {
+	if checks == nil || len(devices) == 0 {
+		return nil, nil
+	}
+
+	var violations []string
+
+	if checks.MinKernelVersion != "" || len(checks.BlacklistedKernelRanges) > 0 {
+		release, err := readKernelRelease()
+		if err != nil {
+			return nil, err
+		}
+
+		kernelViolations, err := checkKernel(checks, release)
+		if err != nil {
+			return nil, err
+		}
+		violations = append(violations, kernelViolations...)
+	}
+
+	for _, device := range devices {
+		minFirmware, ok := checks.MinFirmwareVersion[device.deviceID]
+		if !ok {
+			continue
+		}
+		firmware, err := getFirmwareVersion(device.pciAddress)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read firmware version of %s - %v", device.pciAddress, err)
+		}
+		cmp, err := utils.CompareVersions(firmware, minFirmware)
+		if err != nil {
+			return nil, err
+		}
+		if cmp < 0 {
+			violations = append(violations, fmt.Sprintf("MinFirmwareVersion: firmware %s of device %s(%s) is lower than required %s",
+				firmware, device.pciAddress, device.deviceID, minFirmware))
+		}
+	}
+
+	return violations, nil
+}
+
+func checkKernel(checks *utils.CompatibilityChecks, release string) ([]string, error) {
+	var violations []string
+
+	if checks.MinKernelVersion != "" {
+		cmp, err := utils.CompareVersions(release, checks.MinKernelVersion)
+		if err != nil {
+			return nil, err
+		}
+		if cmp < 0 {
+			violations = append(violations, fmt.Sprintf("MinKernelVersion: kernel %s is lower than required %s", release, checks.MinKernelVersion))
+		}
+	}
+
+	for _, kernelRange := range checks.BlacklistedKernelRanges {
+		from, err := utils.CompareVersions(release, kernelRange.From)
+		if err != nil {
+			return nil, err
+		}
+		to, err := utils.CompareVersions(release, kernelRange.To)
+		if err != nil {
+			return nil, err
+		}
+		if from >= 0 && to < 0 {
+			violation := fmt.Sprintf("BlacklistedKernelRanges: kernel %s is within blacklisted range [%s, %s)", release, kernelRange.From, kernelRange.To)
+			if kernelRange.Reason != "" {
+				violation += ": " + kernelRange.Reason
+			}
+			violations = append(violations, violation)
+		}
+	}
+
+	return violations, nil
+}
@@ -0,0 +1,83 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("checkCompatibility", func() {
+	var (
+		originalKernelReleaseFilePath = kernelReleaseFilePath
+		originalGetFirmwareVersion    = getFirmwareVersion
+		devices                       = []compatibilityDevice{{pciAddress: "0000:14:00.1", deviceID: "0d5c"}}
+	)
+
+	BeforeEach(func() {
+		tmpDir, err := os.MkdirTemp(testTmpFolder, "compatibility")
+		Expect(err).ToNot(HaveOccurred())
+		kernelReleaseFilePath = filepath.Join(tmpDir, "osrelease")
+		Expect(os.WriteFile(kernelReleaseFilePath, []byte("5.14.0-284.el9.x86_64\n"), 0644)).To(Succeed())
+		getFirmwareVersion = func(string) (string, error) { return "0x01", nil }
+	})
+
+	AfterEach(func() {
+		kernelReleaseFilePath = originalKernelReleaseFilePath
+		getFirmwareVersion = originalGetFirmwareVersion
+	})
+
+	It("will pass when no checks are defined", func() {
+		Expect(checkCompatibility(nil, devices)).To(BeEmpty())
+	})
+
+	It("will pass when environment satisfies checks", func() {
+		checks := &utils.CompatibilityChecks{
+			MinKernelVersion:        "5.14",
+			BlacklistedKernelRanges: []utils.KernelRange{{From: "5.10", To: "5.14"}},
+			MinFirmwareVersion:      map[string]string{"0d5c": "0x01"},
+		}
+		Expect(checkCompatibility(checks, devices)).To(BeEmpty())
+	})
+
+	It("will report kernel and firmware violations", func() {
+		checks := &utils.CompatibilityChecks{
+			MinKernelVersion:        "6.0",
+			BlacklistedKernelRanges: []utils.KernelRange{{From: "5.14.0", To: "5.15", Reason: "broken vfio"}},
+			MinFirmwareVersion:      map[string]string{"0d5c": "0x02", "57c0": "0x10"},
+		}
+		violations, err := checkCompatibility(checks, devices)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(violations).To(HaveLen(3))
+		Expect(violations[0]).To(HavePrefix("MinKernelVersion"))
+		Expect(violations[1]).To(And(HavePrefix("BlacklistedKernelRanges"), ContainSubstring("broken vfio")))
+		Expect(violations[2]).To(HavePrefix("MinFirmwareVersion"))
+	})
+
+	It("will fail when firmware version cannot be read", func() {
+		getFirmwareVersion = func(string) (string, error) { return "", fmt.Errorf("no such file") }
+		checks := &utils.CompatibilityChecks{MinFirmwareVersion: map[string]string{"0d5c": "0x02"}}
+		_, err := checkCompatibility(checks, devices)
+		Expect(err).To(MatchError(ContainSubstring("0000:14:00.1")))
+	})
+
+	It("will return warning instead of error when enforcement is disabled", func() {
+		checks := &utils.CompatibilityChecks{MinKernelVersion: "6.0"}
+		r := &NodeConfigReconciler{log: utils.NewLogger()}
+		enforce := false
+
+		warning, err := r.verifyCompatibility(devices, &enforce, utils.AcceleratorDiscoveryConfig{CompatibilityChecks: checks})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(warning).To(ContainSubstring("MinKernelVersion"))
+
+		_, err = r.verifyCompatibility(devices, nil, utils.AcceleratorDiscoveryConfig{CompatibilityChecks: checks})
+		Expect(err).To(MatchError(ContainSubstring("MinKernelVersion")))
+	})
+})
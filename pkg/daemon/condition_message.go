@@ -0,0 +1,24 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+// emitFullMessage emits event which the message of condition truncated by conditions.Bounded points to; it is a no-op
+// when the message was not truncated. Event of condition which is not True is a warning.
+func emitFullMessage(recorder record.EventRecorder, nc runtime.Object, condition metav1.Condition, full string) {
+	if full == "" || recorder == nil {
+		return
+	}
+	eventType := corev1.EventTypeNormal
+	if condition.Status != metav1.ConditionTrue {
+		eventType = corev1.EventTypeWarning
+	}
+	recorder.Event(nc, eventType, condition.Type, full)
+}
@@ -0,0 +1,114 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"time"
+
+	fec "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/drainhelper"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+const (
+	preConfigureHookName  = "preConfigureHook"
+	postConfigureHookName = "postConfigureHook"
+)
+
+var (
+	defaultHookTimeout = 60 * time.Second
+	// hostRoot is the mount of host's root filesystem; hooks are run chrooted into it, so that they see the host
+	hostRoot    = "/host"
+	hookCommand = chrootHookCommand
+)
+
+// chrootHookCommand returns command running hook's executable on the host
+func chrootHookCommand(path string, args []string) []string {
+	return append([]string{"chroot", hostRoot, path}, args...)
+}
+
+// runConfigurationHook runs the hook (if any) and appends its result to results. Error is returned only when the hook
+// failed and its failure policy aborts the configuration; failures of other hooks are only recorded.
+func (r *NodeConfigReconciler) runConfigurationHook(ctx context.Context, name string, hook *fec.ConfigurationHook, results *[]fec.HookResult) error {
+	if hook == nil {
+		return nil
+	}
+
+	result := fec.HookResult{Hook: name, Path: hook.Path, ExitCode: -1}
+	err := r.executeConfigurationHook(ctx, hook, &result)
+	if err != nil {
+		result.Error = err.Error()
+	}
+	*results = append(*results, result)
+
+	log := r.log.WithField("hook", name).WithField("path", hook.Path).WithField("exitCode", result.ExitCode).
+		WithField("duration", result.Duration)
+	if err == nil {
+		log.Info("configuration hook succeeded")
+		drainhelper.ReportProgress(ctx, name+" succeeded")
+		return nil
+	}
+	if hook.FailurePolicy == fec.HookFailurePolicyContinue {
+		log.WithError(err).Warn("configuration hook failed, continuing as requested by its failure policy")
+		return nil
+	}
+	log.WithError(err).Error("configuration hook failed")
+	return fmt.Errorf("%s %s failed - %v", name, hook.Path, err)
+}
+
+func (r *NodeConfigReconciler) executeConfigurationHook(ctx context.Context, hook *fec.ConfigurationHook, result *fec.HookResult) error {
+	// node config is not validated by the webhook, so the path is restricted here as well
+	if prefix := utils.ConfigurationHookPathPrefix(); !utils.IsAllowedHookPath(hook.Path, prefix) {
+		return fmt.Errorf("path is not a clean absolute path located under %s", prefix)
+	}
+
+	timeout := defaultHookTimeout
+	if hook.Timeout != nil && hook.Timeout.Duration > 0 {
+		timeout = hook.Timeout.Duration
+	}
+	hookCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	started := time.Now()
+	_, err := runExecCmd(hookCtx, hookCommand(hook.Path, hook.Args), r.log)
+	result.Duration = time.Since(started).Round(time.Millisecond).String()
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		result.ExitCode = 0
+	case errors.As(err, &exitErr):
+		// -1 when the hook was killed by a signal
+		result.ExitCode = exitErr.ExitCode()
+	}
+	if err != nil && ctx.Err() == nil && errors.Is(hookCtx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("timed out after %v", timeout)
+	}
+	return err
+}
+
+func fecConfigurationHook(hook *vrbv1.ConfigurationHook) *fec.ConfigurationHook {
+	if hook == nil {
+		return nil
+	}
+	return &fec.ConfigurationHook{
+		Path:          hook.Path,
+		Args:          hook.Args,
+		Timeout:       hook.Timeout,
+		FailurePolicy: fec.HookFailurePolicy(hook.FailurePolicy),
+	}
+}
+
+func toVrbHookResults(results []fec.HookResult) []vrbv1.HookResult {
+	var converted []vrbv1.HookResult
+	for _, result := range results {
+		converted = append(converted, vrbv1.HookResult(result))
+	}
+	return converted
+}
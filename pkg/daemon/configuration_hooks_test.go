@@ -0,0 +1,200 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/drainhelper"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("NodeConfigReconciler.Reconcile with configuration hooks", func() {
+	var (
+		fakeClient  client.Client
+		nodeNameRef = types.NamespacedName{Name: "worker", Namespace: "testNamespace"}
+		drainer     *drainhelper.FakeDrainer
+		hooksDir    string
+	)
+
+	// hook appends its name to the journal, so that order of hooks and configuration can be verified
+	writeHook := func(name, script string) string {
+		path := filepath.Join(hooksDir, name)
+		Expect(os.WriteFile(path, []byte("#!/bin/sh\necho "+name+" >> "+filepath.Join(hooksDir, "journal")+"\n"+script+"\n"), 0755)).To(Succeed())
+		return path
+	}
+
+	journal := func() string {
+		content, err := os.ReadFile(filepath.Join(hooksDir, "journal"))
+		if os.IsNotExist(err) {
+			return ""
+		}
+		Expect(err).ToNot(HaveOccurred())
+		return string(content)
+	}
+
+	reconcile := func(pre, post *sriovv2.ConfigurationHook) *sriovv2.SriovFecNodeConfig {
+		nc := &sriovv2.SriovFecNodeConfig{}
+		Expect(fakeClient.Get(context.TODO(), nodeNameRef, nc)).To(Succeed())
+		nc.Spec.PreConfigureHook, nc.Spec.PostConfigureHook = pre, post
+		Expect(fakeClient.Update(context.TODO(), nc)).To(Succeed())
+
+		reconciler, err := NewNodeConfigReconciler(fakeClient, drainer, nodeNameRef,
+			testConfigurerProto{configureNodeFunction: func(sriovv2.SriovFecNodeConfigSpec) error {
+				f, err := os.OpenFile(filepath.Join(hooksDir, "journal"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+				Expect(err).ToNot(HaveOccurred())
+				defer f.Close()
+				_, err = f.WriteString("configured\n")
+				return err
+			}}, nil,
+			func(context.Context) error { return nil },
+			record.NewFakeRecorder(10), nil)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: nodeNameRef})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(fakeClient.Get(context.TODO(), nodeNameRef, nc)).To(Succeed())
+		return nc
+	}
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(sriovv2.AddToScheme(scheme)).To(Succeed())
+		Expect(vrbv1.AddToScheme(scheme)).To(Succeed())
+
+		configPath = "testdata/accelerators.json"
+		VrbconfigPath = "testdata/accelerators_vrb.json"
+		procCmdlineFilePath = "testdata/cmdline_test"
+		sysLockdownFilePath = filepath.Join(testTmpFolder, "lockdown")
+		Expect(os.WriteFile(sysLockdownFilePath, []byte("[none] integrity confidentiality"), 0644)).To(Succeed())
+
+		getSriovInventory = func(_ context.Context, log *logrus.Logger) (*sriovv2.NodeInventory, error) {
+			return &sriovv2.NodeInventory{
+				SriovAccelerators: []sriovv2.SriovAccelerator{{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, MaxVFs: 10}},
+			}, nil
+		}
+		VrbgetSriovInventory = func(_ context.Context, log *logrus.Logger) (*vrbv1.NodeInventory, error) {
+			return &vrbv1.NodeInventory{}, nil
+		}
+
+		var err error
+		hooksDir, err = os.MkdirTemp(testTmpFolder, "hooks")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.Setenv(utils.CONFIGURATION_HOOK_PATH_PREFIX, hooksDir)).To(Succeed())
+		// hooks are run directly, as tests cannot chroot into host's root
+		hookCommand = func(path string, args []string) []string {
+			return append([]string{path}, args...)
+		}
+
+		fakeClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&sriovv2.SriovFecNodeConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace},
+				Spec: sriovv2.SriovFecNodeConfigSpec{
+					PhysicalFunctions: []sriovv2.PhysicalFunctionConfigExt{{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, VFDriver: utils.IGB_UIO, VFAmount: 1}},
+				},
+			},
+			&vrbv1.SriovVrbNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace}},
+		).Build()
+		drainer = &drainhelper.FakeDrainer{}
+	})
+
+	AfterEach(func() {
+		getSriovInventory = GetSriovInventory
+		VrbgetSriovInventory = VrbGetSriovInventory
+		sysLockdownFilePath = "/sys/kernel/security/lockdown"
+		hookCommand = chrootHookCommand
+		Expect(os.Unsetenv(utils.CONFIGURATION_HOOK_PATH_PREFIX)).To(Succeed())
+		Expect(os.RemoveAll(hooksDir)).To(Succeed())
+	})
+
+	It("runs hooks around configuration within the drain and records their results", func() {
+		pre := writeHook("quiesce", `[ "$1" = "--du" ] || exit 7`)
+		post := writeHook("resume", "exit 0")
+
+		nc := reconcile(&sriovv2.ConfigurationHook{Path: pre, Args: []string{"--du"}}, &sriovv2.ConfigurationHook{Path: post})
+
+		Expect(nc.FindCondition(ConditionConfigured).Reason).To(Equal(string(ConfigurationSucceeded)))
+		Expect(journal()).To(Equal("quiesce\nconfigured\nresume\n"))
+		Expect(nc.Status.HookResults).To(HaveLen(2))
+		Expect(nc.Status.HookResults[0]).To(And(
+			HaveField("Hook", preConfigureHookName), HaveField("Path", pre), HaveField("ExitCode", 0),
+			HaveField("Duration", Not(BeEmpty())), HaveField("Error", BeEmpty())))
+		Expect(nc.Status.HookResults[1]).To(And(HaveField("Hook", postConfigureHookName), HaveField("ExitCode", 0)))
+		Expect(drainer.Runs()).To(HaveLen(1))
+		Expect(drainer.Runs()[0]).To(And(HaveField("Drain", true), HaveField("Uncordoned", true),
+			HaveField("Steps", Equal([]string{"node drained with pod eviction", "preConfigureHook succeeded", "postConfigureHook succeeded"}))))
+	})
+
+	It("aborts configuration when pre hook fails with Abort policy", func() {
+		pre := writeHook("quiesce", "exit 3")
+		post := writeHook("resume", "exit 0")
+
+		nc := reconcile(&sriovv2.ConfigurationHook{Path: pre}, &sriovv2.ConfigurationHook{Path: post})
+
+		condition := nc.FindCondition(ConditionConfigured)
+		Expect(condition.Reason).To(Equal(string(ConfigurationFailed)))
+		Expect(condition.Message).To(ContainSubstring("preConfigureHook " + pre + " failed - exit status 3"))
+		Expect(journal()).To(Equal("quiesce\n"))
+		Expect(nc.Status.HookResults).To(ConsistOf(And(HaveField("ExitCode", 3), HaveField("Error", "exit status 3"))))
+		Expect(drainer.Runs()[0].Uncordoned).To(BeTrue())
+	})
+
+	It("continues configuration when hook fails with Continue policy", func() {
+		pre := writeHook("quiesce", "exit 1")
+		post := writeHook("resume", "exit 2")
+
+		nc := reconcile(&sriovv2.ConfigurationHook{Path: pre, FailurePolicy: sriovv2.HookFailurePolicyContinue},
+			&sriovv2.ConfigurationHook{Path: post})
+
+		condition := nc.FindCondition(ConditionConfigured)
+		Expect(condition.Reason).To(Equal(string(ConfigurationFailed)))
+		Expect(condition.Message).To(ContainSubstring("postConfigureHook " + post + " failed - exit status 2"))
+		Expect(journal()).To(Equal("quiesce\nconfigured\nresume\n"))
+		Expect(nc.Status.HookResults).To(Equal([]sriovv2.HookResult{
+			{Hook: preConfigureHookName, Path: pre, ExitCode: 1, Duration: nc.Status.HookResults[0].Duration, Error: "exit status 1"},
+			{Hook: postConfigureHookName, Path: post, ExitCode: 2, Duration: nc.Status.HookResults[1].Duration, Error: "exit status 2"},
+		}))
+	})
+
+	It("kills hook which exceeds its timeout", func() {
+		pre := writeHook("quiesce", "exec sleep 10")
+
+		started := time.Now()
+		nc := reconcile(&sriovv2.ConfigurationHook{Path: pre, Timeout: &metav1.Duration{Duration: 200 * time.Millisecond}}, nil)
+
+		Expect(time.Since(started)).To(BeNumerically("<", 5*time.Second))
+		Expect(nc.FindCondition(ConditionConfigured).Message).To(ContainSubstring("timed out after 200ms"))
+		Expect(nc.Status.HookResults).To(ConsistOf(And(HaveField("ExitCode", -1), HaveField("Error", "timed out after 200ms"))))
+	})
+
+	It("refuses to run hook located outside of the allowed prefix", func() {
+		outside := filepath.Join(testTmpFolder, "outside")
+		Expect(os.WriteFile(outside, []byte("#!/bin/sh\ntouch "+outside+".executed\n"), 0755)).To(Succeed())
+		defer os.Remove(outside)
+
+		nc := reconcile(&sriovv2.ConfigurationHook{Path: hooksDir + "/../outside"}, nil)
+
+		Expect(nc.FindCondition(ConditionConfigured).Reason).To(Equal(string(ConfigurationFailed)))
+		Expect(nc.Status.HookResults).To(ConsistOf(And(HaveField("ExitCode", -1),
+			HaveField("Error", ContainSubstring("is not a clean absolute path located under "+hooksDir)))))
+		Expect(outside + ".executed").ToNot(BeAnExistingFile())
+		Expect(journal()).To(BeEmpty())
+	})
+})
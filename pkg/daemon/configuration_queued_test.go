@@ -0,0 +1,215 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+// statusRecordingClient records Configured condition of every status patch of SriovFecNodeConfig to events, as
+// "<reason>@<observedGeneration>: <message>"
+type statusRecordingClient struct {
+	client.Client
+	events *[]string
+}
+
+func (c *statusRecordingClient) Status() client.StatusWriter {
+	return &statusRecordingWriter{StatusWriter: c.Client.Status(), events: c.events}
+}
+
+type statusRecordingWriter struct {
+	client.StatusWriter
+	events *[]string
+}
+
+func (w *statusRecordingWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if nc, ok := obj.(*sriovv2.SriovFecNodeConfig); ok {
+		if condition := nc.FindCondition(ConditionConfigured); condition != nil {
+			*w.events = append(*w.events, fmt.Sprintf("%s@%d: %s", condition.Reason, condition.ObservedGeneration, condition.Message))
+		}
+	}
+	return w.StatusWriter.Patch(ctx, obj, patch, opts...)
+}
+
+var _ = Describe("Configuration queued", func() {
+	var (
+		fakeClient                   client.Client
+		nodeNameRef                  = types.NamespacedName{Name: "worker", Namespace: "testNamespace"}
+		reconciler                   *NodeConfigReconciler
+		events                       []string
+		configureErr                 error
+		originalProcCmdlineFilePath  = procCmdlineFilePath
+		originalSysLockdownFilePath  = sysLockdownFilePath
+		originalGetSriovInventory    = getSriovInventory
+		originalVrbGetSriovInventory = VrbgetSriovInventory
+	)
+
+	queued := func(generation int) string {
+		return fmt.Sprintf("InProgress@%d: %s", generation, configurationQueuedMessage)
+	}
+
+	configuredCondition := func() *metav1.Condition {
+		sfnc := new(sriovv2.SriovFecNodeConfig)
+		Expect(fakeClient.Get(context.TODO(), nodeNameRef, sfnc)).To(Succeed())
+		return sfnc.FindCondition(ConditionConfigured)
+	}
+
+	updateSpec := func(update func(*sriovv2.SriovFecNodeConfig)) {
+		sfnc := new(sriovv2.SriovFecNodeConfig)
+		Expect(fakeClient.Get(context.TODO(), nodeNameRef, sfnc)).To(Succeed())
+		update(sfnc)
+		sfnc.Generation++
+		Expect(fakeClient.Update(context.TODO(), sfnc)).To(Succeed())
+	}
+
+	reconcile := func() {
+		_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: nodeNameRef})
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(sriovv2.AddToScheme(scheme)).To(Succeed())
+		Expect(vrbv1.AddToScheme(scheme)).To(Succeed())
+
+		procCmdlineFilePath = "testdata/cmdline_test"
+		sysLockdownFilePath = filepath.Join(testTmpFolder, "lockdown")
+		Expect(os.WriteFile(sysLockdownFilePath, []byte("[none] integrity confidentiality"), 0644)).To(Succeed())
+		events, configureErr = nil, nil
+
+		getSriovInventory = func(context.Context, *logrus.Logger) (*sriovv2.NodeInventory, error) {
+			return &sriovv2.NodeInventory{
+				SriovAccelerators: []sriovv2.SriovAccelerator{{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, MaxVFs: 10}},
+			}, nil
+		}
+		VrbgetSriovInventory = func(context.Context, *logrus.Logger) (*vrbv1.NodeInventory, error) {
+			return &vrbv1.NodeInventory{}, nil
+		}
+
+		sfnc := &sriovv2.SriovFecNodeConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace, Generation: 1},
+			Spec: sriovv2.SriovFecNodeConfigSpec{
+				PhysicalFunctions: []sriovv2.PhysicalFunctionConfigExt{{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, VFDriver: utils.IGB_UIO, VFAmount: 1}},
+			},
+		}
+		vrbnc := &vrbv1.SriovVrbNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace}}
+		fakeClient = &statusRecordingClient{
+			Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(sfnc, vrbnc).Build(),
+			events: &events,
+		}
+
+		reconciler = &NodeConfigReconciler{
+			Client:      fakeClient,
+			log:         utils.NewLogger(),
+			nodeNameRef: nodeNameRef,
+			sriovfecconfigurer: testConfigurerProto{configureNodeFunction: func(sriovv2.SriovFecNodeConfigSpec) error {
+				return configureErr
+			}},
+			drainerAndExecute: func(_ context.Context, configurer func(ctx context.Context) bool, drain bool) error {
+				// the lease is acquired here, which may take long
+				events = append(events, "drain")
+				_ = configurer(context.TODO())
+				return nil
+			},
+			restartDevicePlugin: func(context.Context) error { return nil },
+		}
+	})
+
+	AfterEach(func() {
+		procCmdlineFilePath = originalProcCmdlineFilePath
+		sysLockdownFilePath = originalSysLockdownFilePath
+		getSriovInventory = originalGetSriovInventory
+		VrbgetSriovInventory = originalVrbGetSriovInventory
+	})
+
+	It("reports new generation as queued before the drain", func() {
+		reconcile()
+		Expect(events).ToNot(BeEmpty())
+		Expect(events[0]).To(Equal(queued(1)))
+		Expect(events).To(ContainElement("drain"))
+		for _, event := range events[:indexOf(events, "drain")] {
+			Expect(event).To(HavePrefix("InProgress@1: "))
+		}
+		Expect(configuredCondition().Reason).To(Equal(string(ConfigurationSucceeded)))
+		Expect(configuredCondition().ObservedGeneration).To(BeEquivalentTo(1))
+
+		events = nil
+		updateSpec(func(sfnc *sriovv2.SriovFecNodeConfig) { sfnc.Spec.PhysicalFunctions[0].VFAmount = 2 })
+		reconcile()
+		Expect(events[0]).To(Equal(queued(2)))
+		Expect(indexOf(events, "drain")).To(BeNumerically(">", 0))
+		Expect(configuredCondition().ObservedGeneration).To(BeEquivalentTo(2))
+	})
+
+	It("reports new generation while spec settles", func() {
+		updateSpec(func(sfnc *sriovv2.SriovFecNodeConfig) {
+			sfnc.Spec.ConfigurationDebounce = &metav1.Duration{Duration: time.Minute}
+		})
+
+		reconcile()
+		Expect(events).To(Equal([]string{"InProgress@2: waiting for spec to settle (60s remaining)"}))
+		Expect(configuredCondition().ObservedGeneration).To(BeEquivalentTo(2))
+	})
+
+	It("keeps generation configured successfully when configuration of new one fails", func() {
+		reconcile()
+		Expect(configuredCondition().Reason).To(Equal(string(ConfigurationSucceeded)))
+
+		configureErr = errors.New("pf_bb_config failed")
+		updateSpec(func(sfnc *sriovv2.SriovFecNodeConfig) { sfnc.Spec.PhysicalFunctions[0].VFAmount = 2 })
+		_, _ = reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: nodeNameRef})
+		Expect(events).To(ContainElement(queued(2)))
+		Expect(configuredCondition().Reason).To(Equal(string(ConfigurationFailed)))
+		Expect(configuredCondition().ObservedGeneration).To(BeEquivalentTo(1))
+
+		sfnc := new(sriovv2.SriovFecNodeConfig)
+		Expect(fakeClient.Get(context.TODO(), nodeNameRef, sfnc)).To(Succeed())
+		Expect(sfnc.Status.SucceededGeneration).To(BeEquivalentTo(1))
+
+		// failed configuration is retried and reported as queued again
+		events, configureErr = nil, nil
+		reconcile()
+		Expect(events[0]).To(Equal(queued(2)))
+		Expect(configuredCondition().Reason).To(Equal(string(ConfigurationSucceeded)))
+		Expect(configuredCondition().ObservedGeneration).To(BeEquivalentTo(2))
+	})
+
+	It("tells generation configured successfully from Configured condition", func() {
+		succeeded := metav1.Condition{Reason: string(ConfigurationSucceeded), ObservedGeneration: 3}
+		inProgress := metav1.Condition{Reason: string(ConfigurationInProgress), ObservedGeneration: 4}
+		Expect(succeededGeneration(succeeded, 0)).To(BeEquivalentTo(3))
+		Expect(succeededGeneration(inProgress, 3)).To(BeEquivalentTo(3))
+		Expect(inProgressReported(inProgress, 4)).To(BeTrue())
+		Expect(inProgressReported(inProgress, 5)).To(BeFalse())
+		Expect(inProgressReported(succeeded, 3)).To(BeFalse())
+	})
+})
+
+func indexOf(events []string, event string) int {
+	for i, e := range events {
+		if e == event {
+			return i
+		}
+	}
+	return -1
+}
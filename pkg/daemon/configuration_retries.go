@@ -0,0 +1,53 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	fec "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+)
+
+const configurationGivenUpEvent = "ConfigurationGivenUp"
+
+// currentRetries returns failures counted for the current generation and force-reconfigure annotation of the node
+// config; counting restarts when either of them changes. VRB node configs pass their retries converted to FEC type.
+func currentRetries(retries *fec.ConfigurationRetries, nc client.Object, forceReconfigureAnnotation string) fec.ConfigurationRetries {
+	forceReconfigure := nc.GetAnnotations()[forceReconfigureAnnotation]
+	if retries == nil || retries.Generation != nc.GetGeneration() || retries.ForceReconfigure != forceReconfigure {
+		return fec.ConfigurationRetries{Generation: nc.GetGeneration(), ForceReconfigure: forceReconfigure}
+	}
+	return *retries
+}
+
+// countFailure returns retries with failed configuration counted
+func countFailure(retries fec.ConfigurationRetries, err error) *fec.ConfigurationRetries {
+	retries.Failures++
+	retries.LastError = err.Error()
+	return &retries
+}
+
+// configurationGivenUp tells whether configuration failed more times than maxRetries allows; the first attempt is not
+// a retry, so that configuration is given up after maxRetries+1 failures
+func configurationGivenUp(retries fec.ConfigurationRetries, maxRetries *int) bool {
+	return maxRetries != nil && retries.Failures > *maxRetries
+}
+
+// configurationGivenUpMessage explains given up configuration and how to resume it
+func configurationGivenUpMessage(retries fec.ConfigurationRetries, maxRetries int, forceReconfigureAnnotation string) string {
+	return fmt.Sprintf("configuration of generation %d failed %d times, exceeding maxConfigurationRetries %d - it is not "+
+		"retried until the spec or %s annotation changes; last error: %s",
+		retries.Generation, retries.Failures, maxRetries, forceReconfigureAnnotation, retries.LastError)
+}
+
+// reportConfigurationGivenUp emits warning event about configuration which has just been given up
+func (r *NodeConfigReconciler) reportConfigurationGivenUp(nc client.Object, msg string) {
+	r.log.WithField("nodeConfig", nc.GetName()).Warn(msg)
+	if r.recorder != nil {
+		r.recorder.Event(nc, corev1.EventTypeWarning, configurationGivenUpEvent, msg)
+	}
+}
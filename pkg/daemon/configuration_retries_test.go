@@ -0,0 +1,176 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/drainhelper"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("Maximum configuration retries", func() {
+	var (
+		fakeClient     client.Client
+		nodeNameRef    = types.NamespacedName{Name: "worker", Namespace: "testNamespace"}
+		drainer        *drainhelper.FakeDrainer
+		recorder       *record.FakeRecorder
+		configurations int
+		configureErr   error
+	)
+
+	nodeConfig := func() *sriovv2.SriovFecNodeConfig {
+		nc := &sriovv2.SriovFecNodeConfig{}
+		Expect(fakeClient.Get(context.TODO(), nodeNameRef, nc)).To(Succeed())
+		return nc
+	}
+
+	update := func(modify func(nc *sriovv2.SriovFecNodeConfig)) {
+		nc := nodeConfig()
+		modify(nc)
+		Expect(fakeClient.Update(context.TODO(), nc)).To(Succeed())
+	}
+
+	reconcile := func() *sriovv2.SriovFecNodeConfig {
+		reconciler, err := NewNodeConfigReconciler(fakeClient, drainer, nodeNameRef,
+			testConfigurerProto{configureNodeFunction: func(sriovv2.SriovFecNodeConfigSpec) error {
+				configurations++
+				return configureErr
+			}}, nil,
+			func(context.Context) error { return nil },
+			recorder, nil)
+		Expect(err).ToNot(HaveOccurred())
+		_, _ = reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: nodeNameRef})
+		return nodeConfig()
+	}
+
+	reasonOf := func(nc *sriovv2.SriovFecNodeConfig) string {
+		return nc.FindCondition(ConditionConfigured).Reason
+	}
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(sriovv2.AddToScheme(scheme)).To(Succeed())
+		Expect(vrbv1.AddToScheme(scheme)).To(Succeed())
+
+		configPath = "testdata/accelerators.json"
+		VrbconfigPath = "testdata/accelerators_vrb.json"
+		procCmdlineFilePath = "testdata/cmdline_test"
+		sysLockdownFilePath = filepath.Join(testTmpFolder, "lockdown")
+		Expect(os.WriteFile(sysLockdownFilePath, []byte("[none] integrity confidentiality"), 0644)).To(Succeed())
+
+		getSriovInventory = func(_ context.Context, log *logrus.Logger) (*sriovv2.NodeInventory, error) {
+			return &sriovv2.NodeInventory{
+				SriovAccelerators: []sriovv2.SriovAccelerator{{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, MaxVFs: 10}},
+			}, nil
+		}
+		VrbgetSriovInventory = func(_ context.Context, log *logrus.Logger) (*vrbv1.NodeInventory, error) {
+			return &vrbv1.NodeInventory{}, nil
+		}
+
+		fakeClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&sriovv2.SriovFecNodeConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace},
+				Spec: sriovv2.SriovFecNodeConfigSpec{
+					PhysicalFunctions:       []sriovv2.PhysicalFunctionConfigExt{{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, VFDriver: utils.IGB_UIO, VFAmount: 1}},
+					MaxConfigurationRetries: pointer.Int(1),
+				},
+			},
+			&vrbv1.SriovVrbNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace}},
+		).Build()
+		drainer = &drainhelper.FakeDrainer{}
+		recorder = record.NewFakeRecorder(10)
+		configurations, configureErr = 0, errors.New("pf_bb_config failed")
+	})
+
+	AfterEach(func() {
+		getSriovInventory = GetSriovInventory
+		VrbgetSriovInventory = VrbGetSriovInventory
+		sysLockdownFilePath = "/sys/kernel/security/lockdown"
+	})
+
+	It("gives up configuration of the generation once retries are exhausted", func() {
+		nc := reconcile()
+		Expect(reasonOf(nc)).To(Equal(string(ConfigurationFailed)))
+		Expect(nc.Status.ConfigurationRetries).To(Equal(&sriovv2.ConfigurationRetries{
+			Generation: nc.GetGeneration(), Failures: 1, LastError: "pf_bb_config failed"}))
+
+		nc = reconcile()
+		Expect(reasonOf(nc)).To(Equal(string(ConfigurationGivenUp)))
+		Expect(nc.FindCondition(ConditionConfigured).Message).To(And(
+			ContainSubstring("failed 2 times, exceeding maxConfigurationRetries 1"),
+			ContainSubstring(sriovv2.ForceReconfigureAnnotation),
+			HaveSuffix("last error: pf_bb_config failed")))
+		Expect(nc.Status.ConfigurationRetries.Failures).To(Equal(2))
+		Expect(recorder.Events).To(Receive(HavePrefix("Warning ConfigurationGivenUp ")))
+
+		nc = reconcile()
+		Expect(reasonOf(nc)).To(Equal(string(ConfigurationGivenUp)))
+		Expect(configurations).To(Equal(2))
+		Expect(drainer.Runs()).To(HaveLen(2), "node is not drained anymore")
+		Expect(nc.Status.Inventory.SriovAccelerators).To(HaveLen(1), "inventory is still reported")
+		Expect(recorder.Events).ToNot(Receive(HavePrefix("Warning ConfigurationGivenUp ")))
+	})
+
+	It("resumes configuration when force-reconfigure annotation changes", func() {
+		reconcile()
+		reconcile()
+		update(func(nc *sriovv2.SriovFecNodeConfig) {
+			nc.SetAnnotations(map[string]string{sriovv2.ForceReconfigureAnnotation: "1"})
+		})
+
+		nc := reconcile()
+		Expect(configurations).To(Equal(3))
+		Expect(reasonOf(nc)).To(Equal(string(ConfigurationFailed)))
+		Expect(nc.Status.ConfigurationRetries).To(And(HaveField("Failures", 1), HaveField("ForceReconfigure", "1")))
+
+		configureErr = nil
+		nc = reconcile()
+		Expect(reasonOf(nc)).To(Equal(string(ConfigurationSucceeded)))
+		Expect(nc.Status.ConfigurationRetries).To(BeNil())
+	})
+
+	It("resumes configuration when the spec changes", func() {
+		reconcile()
+		reconcile()
+		update(func(nc *sriovv2.SriovFecNodeConfig) {
+			nc.Spec.PhysicalFunctions[0].VFAmount = 2
+			// fake client does not increment generation on spec change
+			nc.Generation++
+		})
+
+		nc := reconcile()
+		Expect(configurations).To(Equal(3))
+		Expect(nc.Status.ConfigurationRetries).To(And(HaveField("Generation", nc.GetGeneration()), HaveField("Failures", 1)))
+	})
+
+	It("retries without limit by default", func() {
+		update(func(nc *sriovv2.SriovFecNodeConfig) {
+			nc.Spec.MaxConfigurationRetries = nil
+		})
+
+		for i := 0; i < 3; i++ {
+			Expect(reasonOf(reconcile())).To(Equal(string(ConfigurationFailed)))
+		}
+		Expect(configurations).To(Equal(3))
+		Expect(nodeConfig().Status.ConfigurationRetries.Failures).To(Equal(3))
+	})
+})
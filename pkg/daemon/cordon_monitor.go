@@ -0,0 +1,203 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	fec "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/conditions"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/drainhelper"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+const (
+	ConditionCordonOverdue = "CordonOverdue"
+	cordonOverdue          = "Overdue"
+	cordonNotOverdue       = "NotOverdue"
+)
+
+var (
+	cordonMonitorInterval = time.Minute
+
+	nodeCordonedSeconds = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "sriovfec_node_cordoned_seconds",
+		Help: "How long the node has been cordoned by the operator; 0 when node is schedulable or cordoned by admin",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(nodeCordonedSeconds)
+}
+
+// cordonOverdueThreshold returns duration of operator's cordon after which it is reported as overdue; it is configured
+// with CORDON_OVERDUE_THRESHOLD env variable
+func cordonOverdueThreshold(log *logrus.Logger) time.Duration {
+	threshold := time.Hour
+	thresholdEnv := os.Getenv(utils.SRIOV_PREFIX + "CORDON_OVERDUE_THRESHOLD")
+	if thresholdEnv != "" {
+		envDuration, err := time.ParseDuration(thresholdEnv)
+		if err != nil || envDuration <= 0 {
+			log.WithError(err).WithField("default", threshold).Error("user-provided value is incorrect 'Duration', using default value instead")
+		} else {
+			threshold = envDuration
+		}
+	}
+	return threshold
+}
+
+// CordonMonitor periodically checks how long the node has been cordoned by the operator. Cordon lasting longer than
+// threshold (while no reboot is pending) is reported with warning event and CordonOverdue condition of node configs.
+type CordonMonitor struct {
+	client      client.Client
+	recorder    record.EventRecorder
+	nodeNameRef types.NamespacedName
+	// nodeConfigKey returns key of node configs of the node, see NodeConfigReconciler.NodeConfigKey
+	nodeConfigKey func() types.NamespacedName
+	threshold     time.Duration
+	log           *logrus.Logger
+	clock         clock
+	overdue       bool
+	// observed is the operator's cordon the monitor tracks in elapsed time, see cordonedFor
+	observed *observedCordon
+}
+
+// observedCordon is operator's cordon identified by its CordonedAtAnnotation, with its age when first observed
+type observedCordon struct {
+	cordonedAt string
+	age        time.Duration
+	observedAt time.Duration
+}
+
+// NewCordonMonitor creates monitor of nodeNameRef node, which reports to node configs with key returned by
+// nodeConfigKey; c has to be able to read the Node object
+func NewCordonMonitor(c client.Client, recorder record.EventRecorder, nodeNameRef types.NamespacedName, nodeConfigKey func() types.NamespacedName, log *logrus.Logger) *CordonMonitor {
+	return &CordonMonitor{
+		client:        c,
+		recorder:      recorder,
+		nodeNameRef:   nodeNameRef,
+		nodeConfigKey: nodeConfigKey,
+		threshold:     cordonOverdueThreshold(log),
+		log:           log,
+		clock:         daemonClock,
+	}
+}
+
+// Start implements manager.Runnable
+func (m *CordonMonitor) Start(ctx context.Context) error {
+	m.log.WithField("threshold", m.threshold).Info("starting cordon monitor")
+	wait.UntilWithContext(ctx, m.check, cordonMonitorInterval)
+	return nil
+}
+
+func (m *CordonMonitor) check(ctx context.Context) {
+	node := &corev1.Node{}
+	if err := m.client.Get(ctx, client.ObjectKey{Name: m.nodeNameRef.Name}, node); err != nil {
+		m.log.WithError(err).Error("failed to get the node object")
+		return
+	}
+
+	cordonedFor, err := m.cordonedFor(node)
+	if err != nil {
+		m.log.WithError(err).Error("failed to determine how long the node has been cordoned")
+	}
+	nodeCordonedSeconds.Set(cordonedFor.Seconds())
+
+	overdue := cordonedFor > m.threshold && node.GetAnnotations()[drainhelper.RebootPendingAnnotation] != "true"
+	condition := metav1.Condition{
+		Type:    ConditionCordonOverdue,
+		Status:  metav1.ConditionFalse,
+		Reason:  cordonNotOverdue,
+		Message: "Node is not cordoned by the operator",
+	}
+	if overdue {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = cordonOverdue
+		condition.Message = fmt.Sprintf("Node has been cordoned by the operator for more than %s", m.threshold)
+	}
+
+	for _, nc := range []client.Object{&fec.SriovFecNodeConfig{}, &vrbv1.SriovVrbNodeConfig{}} {
+		if err := m.setCondition(ctx, nc, condition, overdue && !m.overdue); err != nil {
+			m.log.WithError(err).WithField("kind", fmt.Sprintf("%T", nc)).Errorf("failed to update %s condition", ConditionCordonOverdue)
+		}
+	}
+
+	if overdue != m.overdue {
+		m.log.WithField("cordonedFor", cordonedFor).WithField("overdue", overdue).Info("cordon overdue transition")
+	}
+	m.overdue = overdue
+}
+
+// cordonedFor returns how long the node has been cordoned by the operator. Persisted cordon timestamp is compared with
+// wall time only when the cordon is first observed; the monitor then adds time elapsed since, so that its age is not
+// affected by clock jumps while it lasts.
+func (m *CordonMonitor) cordonedFor(node *corev1.Node) (time.Duration, error) {
+	if !node.Spec.Unschedulable || drainhelper.IsCordonedByAdmin(node) {
+		m.observed = nil
+		return 0, nil
+	}
+	age, err := drainhelper.CordonedFor(node, m.clock.Now())
+	if err != nil {
+		m.observed = nil
+		return 0, err
+	}
+
+	cordonedAt := node.GetAnnotations()[drainhelper.CordonedAtAnnotation]
+	if m.observed == nil || m.observed.cordonedAt != cordonedAt {
+		m.observed = &observedCordon{cordonedAt: cordonedAt, age: age, observedAt: m.clock.Elapsed()}
+	}
+	return m.observed.age + m.clock.Elapsed() - m.observed.observedAt, nil
+}
+
+// setCondition writes condition into given node config; condition which is not overdue is only written if it was
+// previously set, so node configs of nodes which were never overdue are left untouched
+func (m *CordonMonitor) setCondition(ctx context.Context, nc client.Object, condition metav1.Condition, emitEvent bool) error {
+	if err := m.client.Get(ctx, m.nodeConfigKey(), nc); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	original := nc.DeepCopyObject().(client.Object)
+	var ncConditions *[]metav1.Condition
+	switch nodeConfig := nc.(type) {
+	case *fec.SriovFecNodeConfig:
+		ncConditions = &nodeConfig.Status.Conditions
+	case *vrbv1.SriovVrbNodeConfig:
+		ncConditions = &nodeConfig.Status.Conditions
+	default:
+		return fmt.Errorf("unsupported node config type %T", nc)
+	}
+
+	if condition.Status == metav1.ConditionFalse && meta.FindStatusCondition(*ncConditions, ConditionCordonOverdue) == nil {
+		return nil
+	}
+	if !conditions.SetIfChanged(ncConditions, condition) {
+		return nil
+	}
+
+	if emitEvent && m.recorder != nil {
+		m.recorder.Event(nc, corev1.EventTypeWarning, ConditionCordonOverdue, condition.Message)
+	}
+
+	_, err := patchStatus(ctx, m.client, original, nc)
+	return err
+}
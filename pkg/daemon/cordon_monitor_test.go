@@ -0,0 +1,180 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	fec "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/drainhelper"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("CordonMonitor", func() {
+	var (
+		c        client.Client
+		recorder *record.FakeRecorder
+		monitor  *CordonMonitor
+		clk      *fakeClock
+		now      = time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+		nodeRef  = types.NamespacedName{Name: "worker", Namespace: "default"}
+	)
+
+	newNode := func(unschedulable bool, annotations map[string]string) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: nodeRef.Name, Annotations: annotations},
+			Spec:       corev1.NodeSpec{Unschedulable: unschedulable},
+		}
+	}
+
+	operatorCordon := func(cordonedAt time.Time) map[string]string {
+		return map[string]string{
+			drainhelper.CordonedByAnnotation: drainhelper.CordonOwner,
+			drainhelper.CordonedAtAnnotation: cordonedAt.Format(time.RFC3339),
+		}
+	}
+
+	setup := func(node *corev1.Node) {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(fec.AddToScheme(scheme)).To(Succeed())
+		Expect(vrbv1.AddToScheme(scheme)).To(Succeed())
+
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			node,
+			&fec.SriovFecNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: nodeRef.Name, Namespace: nodeRef.Namespace}},
+			&vrbv1.SriovVrbNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: nodeRef.Name, Namespace: nodeRef.Namespace}},
+		).Build()
+		recorder = record.NewFakeRecorder(10)
+		clk = newFakeClock(now)
+		monitor = &CordonMonitor{
+			client:        c,
+			recorder:      recorder,
+			nodeNameRef:   nodeRef,
+			nodeConfigKey: func() types.NamespacedName { return nodeRef },
+			threshold:     time.Hour,
+			log:           utils.NewLogger(),
+			clock:         clk,
+		}
+	}
+
+	cordonOverdueConditions := func() []*metav1.Condition {
+		sfnc := &fec.SriovFecNodeConfig{}
+		Expect(c.Get(context.TODO(), nodeRef, sfnc)).To(Succeed())
+		vrbnc := &vrbv1.SriovVrbNodeConfig{}
+		Expect(c.Get(context.TODO(), nodeRef, vrbnc)).To(Succeed())
+		return []*metav1.Condition{
+			meta.FindStatusCondition(sfnc.Status.Conditions, ConditionCordonOverdue),
+			meta.FindStatusCondition(vrbnc.Status.Conditions, ConditionCordonOverdue),
+		}
+	}
+
+	It("should report overdue cordon once and clear it on uncordon", func() {
+		node := newNode(true, operatorCordon(now.Add(-2*time.Hour)))
+		setup(node)
+
+		monitor.check(context.TODO())
+		Expect(testutil.ToFloat64(nodeCordonedSeconds)).To(Equal(float64(7200)))
+		for _, condition := range cordonOverdueConditions() {
+			Expect(condition).ToNot(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		}
+		Expect(recorder.Events).To(HaveLen(2))
+
+		monitor.check(context.TODO())
+		Expect(recorder.Events).To(HaveLen(2))
+
+		node.Spec.Unschedulable = false
+		Expect(c.Update(context.TODO(), node)).To(Succeed())
+		monitor.check(context.TODO())
+		Expect(testutil.ToFloat64(nodeCordonedSeconds)).To(BeZero())
+		for _, condition := range cordonOverdueConditions() {
+			Expect(condition).ToNot(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		}
+	})
+
+	It("should not report cordon within threshold", func() {
+		setup(newNode(true, operatorCordon(now.Add(-30*time.Minute))))
+
+		monitor.check(context.TODO())
+		Expect(testutil.ToFloat64(nodeCordonedSeconds)).To(Equal(float64(1800)))
+		Expect(cordonOverdueConditions()).To(ConsistOf(BeNil(), BeNil()))
+		Expect(recorder.Events).To(BeEmpty())
+	})
+
+	It("should not report overdue cordon while reboot is pending", func() {
+		annotations := operatorCordon(now.Add(-2 * time.Hour))
+		annotations[drainhelper.RebootPendingAnnotation] = "true"
+		setup(newNode(true, annotations))
+
+		monitor.check(context.TODO())
+		Expect(testutil.ToFloat64(nodeCordonedSeconds)).To(Equal(float64(7200)))
+		Expect(cordonOverdueConditions()).To(ConsistOf(BeNil(), BeNil()))
+		Expect(recorder.Events).To(BeEmpty())
+	})
+
+	It("should report overdue cordon when the clock is stepped back while cordoned", func() {
+		setup(newNode(true, operatorCordon(now.Add(-30*time.Minute))))
+		monitor.check(context.TODO())
+		Expect(cordonOverdueConditions()).To(ConsistOf(BeNil(), BeNil()))
+
+		// cordon timestamp is ahead of the clock now
+		clk.Jump(-2 * time.Hour)
+		clk.Step(45 * time.Minute)
+		monitor.check(context.TODO())
+		Expect(testutil.ToFloat64(nodeCordonedSeconds)).To(Equal(float64(75 * 60)))
+		for _, condition := range cordonOverdueConditions() {
+			Expect(condition).ToNot(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		}
+	})
+
+	It("should not report cordon as overdue when the clock is stepped forward while cordoned", func() {
+		setup(newNode(true, operatorCordon(now.Add(-10*time.Minute))))
+		monitor.check(context.TODO())
+
+		clk.Jump(365 * 24 * time.Hour)
+		clk.Step(5 * time.Minute)
+		monitor.check(context.TODO())
+		Expect(testutil.ToFloat64(nodeCordonedSeconds)).To(Equal(float64(15 * 60)))
+		Expect(cordonOverdueConditions()).To(ConsistOf(BeNil(), BeNil()))
+		Expect(recorder.Events).To(BeEmpty())
+	})
+
+	It("should not report negative cordon age when first observed after the clock was stepped back", func() {
+		setup(newNode(true, operatorCordon(now.Add(time.Hour))))
+
+		monitor.check(context.TODO())
+		Expect(testutil.ToFloat64(nodeCordonedSeconds)).To(BeZero())
+
+		clk.Step(2 * time.Hour)
+		monitor.check(context.TODO())
+		Expect(testutil.ToFloat64(nodeCordonedSeconds)).To(Equal(float64(7200)), "age is measured since the cordon was observed")
+	})
+
+	It("should ignore cordon applied by admin", func() {
+		setup(newNode(true, nil))
+
+		monitor.check(context.TODO())
+		Expect(testutil.ToFloat64(nodeCordonedSeconds)).To(BeZero())
+		Expect(cordonOverdueConditions()).To(ConsistOf(BeNil(), BeNil()))
+		Expect(recorder.Events).To(BeEmpty())
+	})
+})
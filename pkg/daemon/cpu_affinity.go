@@ -0,0 +1,314 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var (
+	onlineCPUsFilePath = "/sys/devices/system/cpu/online"
+	ownTasksPath       = "/proc/self/task"
+	getAffinity        = unix.SchedGetaffinity
+	setAffinity        = unix.SchedSetaffinity
+)
+
+type cpuAffinityKey struct{}
+
+// withCPUAffinity returns context which child processes are started with affinity to cpus in; no affinity is applied
+// when cpus are empty
+func withCPUAffinity(ctx context.Context, cpus []int) context.Context {
+	return context.WithValue(ctx, cpuAffinityKey{}, cpus)
+}
+
+func cpuAffinityFrom(ctx context.Context) []int {
+	cpus, _ := ctx.Value(cpuAffinityKey{}).([]int)
+	return cpus
+}
+
+// housekeepingCPUs returns CPUs given with housekeepingCpuSet of the node config; when it is not set, CPUs are
+// detected from kernel params. Nil is returned for nodes without isolated CPUs.
+func housekeepingCPUs(cpuset string) ([]int, error) {
+	if cpuset != "" {
+		cpus, err := utils.ParseCPUSet(cpuset)
+		if err != nil {
+			return nil, fmt.Errorf("invalid housekeepingCpuSet - %v", err)
+		}
+		return cpus, nil
+	}
+	return detectHousekeepingCPUs()
+}
+
+// detectHousekeepingCPUs returns online CPUs which are not isolated with isolcpus nor nohz_full kernel params
+func detectHousekeepingCPUs() ([]int, error) {
+	cmdline, err := os.ReadFile(procCmdlineFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file contents: path: %v, error - %v", procCmdlineFilePath, err)
+	}
+	isolated, err := isolatedCPUs(string(cmdline))
+	if err != nil || len(isolated) == 0 {
+		return nil, err
+	}
+
+	online, err := os.ReadFile(onlineCPUsFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file contents: path: %v, error - %v", onlineCPUsFilePath, err)
+	}
+	onlineCPUs, err := utils.ParseCPUSet(string(online))
+	if err != nil {
+		return nil, err
+	}
+	isolatedSet := map[int]bool{}
+	for _, cpu := range isolated {
+		isolatedSet[cpu] = true
+	}
+	var housekeeping []int
+	for _, cpu := range onlineCPUs {
+		if !isolatedSet[cpu] {
+			housekeeping = append(housekeeping, cpu)
+		}
+	}
+	if len(housekeeping) == 0 {
+		return nil, fmt.Errorf("all online CPUs %s are isolated", strings.TrimSpace(string(online)))
+	}
+	return housekeeping, nil
+}
+
+// isolatedCPUs returns CPUs isolated with isolcpus (its flags, e.g. domain or managed_irq, are skipped) and nohz_full
+// kernel params; the last occurrence of each param is effective
+func isolatedCPUs(cmdline string) ([]int, error) {
+	params := parseKernelCmdline(cmdline)
+	var cpuList []string
+	for _, name := range []string{"isolcpus", "nohz_full"} {
+		values := params[name]
+		if len(values) == 0 {
+			continue
+		}
+		for _, item := range strings.Split(values[len(values)-1], ",") {
+			if item != "" && (item[0] < '0' || item[0] > '9') {
+				continue
+			}
+			cpuList = append(cpuList, item)
+		}
+	}
+	cpus, err := utils.ParseCPUSet(strings.Join(cpuList, ","))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse isolated CPUs of kernel params - %v", err)
+	}
+	return cpus, nil
+}
+
+func cpuSetOf(cpus []int) unix.CPUSet {
+	var set unix.CPUSet
+	for _, cpu := range cpus {
+		set.Set(cpu)
+	}
+	return set
+}
+
+// startWithAffinity starts cmd with affinity to cpus, like taskset does; cmd is started as it is when cpus are empty.
+// Child process inherits affinity of the thread it is forked from, so that thread is pinned to cpus for the time of
+// the fork and restored afterwards; processes the child forks (e.g. daemonized pf_bb_config) inherit it as well.
+func startWithAffinity(cmd *exec.Cmd, cpus []int) error {
+	if len(cpus) == 0 {
+		return cmd.Start()
+	}
+
+	runtime.LockOSThread()
+	var original unix.CPUSet
+	if err := getAffinity(0, &original); err != nil {
+		runtime.UnlockOSThread()
+		return fmt.Errorf("failed to get CPU affinity - %v", err)
+	}
+	set := cpuSetOf(cpus)
+	if err := setAffinity(0, &set); err != nil {
+		runtime.UnlockOSThread()
+		return fmt.Errorf("failed to set CPU affinity to %s - %v", utils.FormatCPUSet(cpus), err)
+	}
+	startErr := cmd.Start()
+	// thread which affinity can't be restored stays locked, so that it exits together with the goroutine instead of
+	// running other goroutines on cpus
+	if err := setAffinity(0, &original); err == nil {
+		runtime.UnlockOSThread()
+	}
+	return startErr
+}
+
+// runWithAffinity is exec.Cmd.Run with affinity to cpus
+func runWithAffinity(cmd *exec.Cmd, cpus []int) error {
+	if err := startWithAffinity(cmd, cpus); err != nil {
+		return err
+	}
+	return cmd.Wait()
+}
+
+// outputWithAffinity is exec.Cmd.Output with affinity to cpus
+func outputWithAffinity(cmd *exec.Cmd, cpus []int) ([]byte, error) {
+	if len(cpus) == 0 {
+		return cmd.Output()
+	}
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	err := runWithAffinity(cmd, cpus)
+	return stdout.Bytes(), err
+}
+
+// pinnedCPUs is cpuset the daemon process was pinned to last
+var pinnedCPUs struct {
+	sync.Mutex
+	cpuset string
+	// original is affinity of the daemon before it was pinned first, restored by unpinDaemon; nil until then
+	original *unix.CPUSet
+}
+
+// pinDaemon sets affinity of all threads of the daemon to cpus and GOMAXPROCS to their number, unless GOMAXPROCS is set
+// with env variable. Nothing is done when cpus are empty or the daemon is pinned to them already.
+func pinDaemon(cpus []int, log *logrus.Logger) error {
+	if len(cpus) == 0 {
+		return nil
+	}
+	cpuset := utils.FormatCPUSet(cpus)
+	pinnedCPUs.Lock()
+	defer pinnedCPUs.Unlock()
+	if pinnedCPUs.cpuset == cpuset {
+		return nil
+	}
+
+	if pinnedCPUs.original == nil {
+		original := new(unix.CPUSet)
+		if err := getAffinity(0, original); err != nil {
+			return fmt.Errorf("failed to get CPU affinity of the daemon - %v", err)
+		}
+		pinnedCPUs.original = original
+	}
+	set := cpuSetOf(cpus)
+	if err := setDaemonAffinity(&set, cpuset); err != nil {
+		return err
+	}
+	pinnedCPUs.cpuset = cpuset
+	log.WithField("cpus", cpuset).Info("daemon pinned to housekeeping CPUs")
+	return nil
+}
+
+// unpinDaemon restores affinity and GOMAXPROCS the daemon had before it was pinned first; nothing is done when the
+// daemon is not pinned
+func unpinDaemon(log *logrus.Logger) error {
+	pinnedCPUs.Lock()
+	defer pinnedCPUs.Unlock()
+	if pinnedCPUs.cpuset == "" || pinnedCPUs.original == nil {
+		return nil
+	}
+
+	if err := setDaemonAffinity(pinnedCPUs.original, "original CPUs"); err != nil {
+		return err
+	}
+	pinnedCPUs.cpuset = ""
+	log.WithField("cpus", pinnedCPUs.original.Count()).Info("daemon unpinned from housekeeping CPUs")
+	return nil
+}
+
+// setDaemonAffinity sets affinity of all threads of the daemon to set, described by cpuset in errors, and GOMAXPROCS to
+// its size, unless GOMAXPROCS is set with env variable
+func setDaemonAffinity(set *unix.CPUSet, cpuset string) error {
+	tasks, err := os.ReadDir(ownTasksPath)
+	if err != nil {
+		return fmt.Errorf("failed to list threads of the daemon - %v", err)
+	}
+	for _, task := range tasks {
+		tid, err := strconv.Atoi(task.Name())
+		if err != nil {
+			continue
+		}
+		// thread may exit in the meantime
+		if err := setAffinity(tid, set); err != nil && err != unix.ESRCH {
+			return fmt.Errorf("failed to set CPU affinity of thread %s to %s - %v", filepath.Join(ownTasksPath, task.Name()), cpuset, err)
+		}
+	}
+	if os.Getenv("GOMAXPROCS") == "" {
+		runtime.GOMAXPROCS(set.Count())
+	}
+	return nil
+}
+
+// PinToHousekeepingCPUs pins the daemon to CPUs which are not isolated with kernel params; affinity is not changed on
+// nodes without isolated CPUs. Housekeeping cpuset set in node config takes over once configuration starts.
+func PinToHousekeepingCPUs(log *logrus.Logger) error {
+	cpus, err := detectHousekeepingCPUs()
+	if err != nil {
+		return err
+	}
+	return pinDaemon(cpus, log)
+}
+
+// housekeepingAffinity returns CPUs child processes of the configuration are started on and pins the daemon to them;
+// failure to pin the daemon does not fail the configuration
+func (r *NodeConfigReconciler) housekeepingAffinity(cpuset string) ([]int, error) {
+	cpus, err := housekeepingCPUs(cpuset)
+	if err != nil {
+		return nil, err
+	}
+	if len(cpus) == 0 {
+		// neither housekeeping cpuset is set in node config nor CPUs are isolated, e.g. after the cpuset was removed
+		if err := unpinDaemon(r.log); err != nil {
+			r.log.WithError(err).Warn("failed to restore CPU affinity of the daemon")
+		}
+		return nil, nil
+	}
+	r.log.WithField("cpus", utils.FormatCPUSet(cpus)).Info("child processes are started on housekeeping CPUs")
+	if err := pinDaemon(cpus, r.log); err != nil {
+		r.log.WithError(err).Warn("failed to pin the daemon to housekeeping CPUs")
+	}
+	return cpus, nil
+}
+
+// pfBBConfigCpuSets holds CPUs the last pf-bb-config of PF was started on, by PCI address; empty when it was started
+// without affinity
+var pfBBConfigCpuSets = &startedCpuSets{cpusets: map[string]string{}}
+
+type startedCpuSets struct {
+	mu      sync.Mutex
+	cpusets map[string]string
+}
+
+func (s *startedCpuSets) record(pciAddress string, cpus []int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cpusets[pciAddress] = utils.FormatCPUSet(cpus)
+}
+
+// statusCpuSets returns cpusets of PFs to be exposed in status; PFs not started since the daemon started keep the
+// cpuset reported previously, PFs started without affinity or not configured anymore are dropped
+func (s *startedCpuSets) statusCpuSets(previous map[string]string, pciAddresses []string) map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var cpusets map[string]string
+	for _, pciAddress := range pciAddresses {
+		cpuset, found := previous[pciAddress]
+		if recorded, ok := s.cpusets[pciAddress]; ok {
+			cpuset, found = recorded, true
+		}
+		if !found || cpuset == "" {
+			continue
+		}
+		if cpusets == nil {
+			cpusets = map[string]string{}
+		}
+		cpusets[pciAddress] = cpuset
+	}
+	return cpusets
+}
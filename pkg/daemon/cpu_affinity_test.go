@@ -0,0 +1,177 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+var _ = Describe("CPU affinity", func() {
+	var dir string
+
+	writeCmdline := func(cmdline string) {
+		procCmdlineFilePath = filepath.Join(dir, "cmdline")
+		Expect(os.WriteFile(procCmdlineFilePath, []byte(cmdline), 0644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp(testTmpFolder, "cpu")
+		Expect(err).ToNot(HaveOccurred())
+		onlineCPUsFilePath = filepath.Join(dir, "online")
+		Expect(os.WriteFile(onlineCPUsFilePath, []byte("0-7\n"), 0644)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		procCmdlineFilePath = "testdata/cmdline_test"
+		onlineCPUsFilePath = "/sys/devices/system/cpu/online"
+		getAffinity, setAffinity = unix.SchedGetaffinity, unix.SchedSetaffinity
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	Context("housekeeping CPUs", func() {
+		It("are online CPUs not isolated with isolcpus nor nohz_full", func() {
+			writeCmdline("intel_iommu=on isolcpus=managed_irq,domain,2-3 nohz_full=3-5 iommu=pt\n")
+			Expect(housekeepingCPUs("")).To(Equal([]int{0, 1, 6, 7}))
+		})
+
+		It("are taken from the last occurrence of kernel params", func() {
+			writeCmdline("isolcpus=1-6 isolcpus=4-7")
+			Expect(housekeepingCPUs("")).To(Equal([]int{0, 1, 2, 3}))
+		})
+
+		It("are not detected on nodes without isolated CPUs", func() {
+			writeCmdline("intel_iommu=on iommu=pt")
+			Expect(housekeepingCPUs("")).To(BeEmpty())
+		})
+
+		It("fail detection when all CPUs are isolated", func() {
+			writeCmdline("isolcpus=0-7")
+			_, err := housekeepingCPUs("")
+			Expect(err).To(MatchError(ContainSubstring("all online CPUs 0-7 are isolated")))
+		})
+
+		It("are taken from node config when set", func() {
+			writeCmdline("isolcpus=2-7")
+			Expect(housekeepingCPUs("4,6-7")).To(Equal([]int{4, 6, 7}))
+
+			_, err := housekeepingCPUs("7-6")
+			Expect(err).To(MatchError(ContainSubstring("invalid housekeepingCpuSet")))
+		})
+	})
+
+	Context("child process", func() {
+		It("is started with affinity of ctx", func() {
+			out, err := execCmd(withCPUAffinity(context.TODO(), []int{0}), []string{"grep", "Cpus_allowed_list", "/proc/self/status"}, logrus.New())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(strings.Fields(out)).To(Equal([]string{"Cpus_allowed_list:", "0"}))
+		})
+
+		It("is started on CPUs of the thread after its affinity is restored", func() {
+			var calls []unix.CPUSet
+			getAffinity = func(int, *unix.CPUSet) error { return nil }
+			setAffinity = func(pid int, set *unix.CPUSet) error {
+				calls = append(calls, *set)
+				return nil
+			}
+			Expect(runWithAffinity(exec.Command("true"), []int{1, 3})).To(Succeed())
+			Expect(calls).To(HaveLen(2))
+			Expect(calls[0].Count()).To(Equal(2))
+			Expect(calls[0].IsSet(1) && calls[0].IsSet(3)).To(BeTrue())
+			Expect(calls[1].Count()).To(BeZero(), "original affinity is restored")
+		})
+
+		It("is not started when affinity can't be set", func() {
+			setAffinity = func(int, *unix.CPUSet) error { return unix.EINVAL }
+			cmd := exec.Command("true")
+			Expect(runWithAffinity(cmd, []int{1})).To(MatchError(ContainSubstring("failed to set CPU affinity to 1")))
+			Expect(cmd.Process).To(BeNil())
+		})
+	})
+
+	It("pins all threads of the daemon once", func() {
+		defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(0))
+		defer func() { pinnedCPUs.cpuset, pinnedCPUs.original = "", nil }()
+		Expect(os.Unsetenv("GOMAXPROCS")).To(Succeed())
+		tids := map[int]bool{}
+		setAffinity = func(tid int, set *unix.CPUSet) error {
+			tids[tid] = true
+			return nil
+		}
+
+		Expect(pinDaemon([]int{0, 1}, logrus.New())).To(Succeed())
+		Expect(tids).To(HaveKey(os.Getpid()))
+		Expect(runtime.GOMAXPROCS(0)).To(Equal(2))
+
+		tids = map[int]bool{}
+		Expect(pinDaemon([]int{0, 1}, logrus.New())).To(Succeed())
+		Expect(tids).To(BeEmpty())
+
+		setAffinity = func(int, *unix.CPUSet) error { return errors.New("not permitted") }
+		Expect(pinDaemon([]int{0}, logrus.New())).To(MatchError(ContainSubstring("not permitted")))
+		Expect(pinDaemon(nil, logrus.New())).To(Succeed())
+	})
+
+	It("restores original affinity of the daemon when housekeeping cpuset is removed", func() {
+		defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(0))
+		defer func() { pinnedCPUs.cpuset, pinnedCPUs.original = "", nil }()
+		Expect(os.Unsetenv("GOMAXPROCS")).To(Succeed())
+		getAffinity = func(_ int, set *unix.CPUSet) error {
+			set.Zero()
+			for cpu := 0; cpu < 8; cpu++ {
+				set.Set(cpu)
+			}
+			return nil
+		}
+		var applied []int
+		setAffinity = func(_ int, set *unix.CPUSet) error {
+			applied = append(applied, set.Count())
+			return nil
+		}
+		reconciler := &NodeConfigReconciler{log: logrus.New()}
+
+		Expect(reconciler.housekeepingAffinity("0-1")).To(Equal([]int{0, 1}))
+		Expect(runtime.GOMAXPROCS(0)).To(Equal(2))
+		Expect(applied).ToNot(BeEmpty())
+		Expect(applied[len(applied)-1]).To(Equal(2))
+
+		Expect(reconciler.housekeepingAffinity("")).To(BeEmpty())
+		Expect(applied[len(applied)-1]).To(Equal(8))
+		Expect(runtime.GOMAXPROCS(0)).To(Equal(8))
+		Expect(pinnedCPUs.cpuset).To(BeEmpty())
+
+		// node isolating CPUs gets the daemon back on CPUs which are not isolated
+		writeCmdline("isolcpus=2-7\n")
+		Expect(reconciler.housekeepingAffinity("0")).To(Equal([]int{0}))
+		Expect(reconciler.housekeepingAffinity("")).To(Equal([]int{0, 1}))
+		Expect(pinnedCPUs.cpuset).To(Equal("0-1"))
+	})
+
+	It("exposes cpusets pf-bb-config was started on in status", func() {
+		cpusets := &startedCpuSets{cpusets: map[string]string{}}
+		cpusets.record("0000:f7:00.0", []int{0, 1, 2})
+		cpusets.record("0000:f8:00.0", nil)
+
+		Expect(cpusets.statusCpuSets(map[string]string{
+			"0000:f8:00.0": "0-1",
+			"0000:f9:00.0": "4",
+			"0000:fa:00.0": "5",
+		}, []string{"0000:f7:00.0", "0000:f8:00.0", "0000:f9:00.0"})).To(Equal(map[string]string{
+			"0000:f7:00.0": "0-2",
+			"0000:f9:00.0": "4",
+		}))
+		Expect(cpusets.statusCpuSets(nil, []string{"0000:f8:00.0"})).To(BeNil())
+	})
+})
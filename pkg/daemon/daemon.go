@@ -11,14 +11,21 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/conditions"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/drainhelper"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
@@ -28,31 +35,61 @@ import (
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
-type ConfigurationConditionReason string
+type ConfigurationConditionReason = conditions.Reason
 
 const (
-	ConditionConfigured       string                       = "Configured"
-	ConfigurationInProgress   ConfigurationConditionReason = "InProgress"
-	ConfigurationFailed       ConfigurationConditionReason = "Failed"
-	ConfigurationNotRequested ConfigurationConditionReason = "NotRequested"
-	ConfigurationSucceeded    ConfigurationConditionReason = "Succeeded"
+	ConditionConfigured       = conditions.TypeConfigured
+	ConfigurationInProgress   = conditions.ReasonInProgress
+	ConfigurationFailed       = conditions.ReasonFailed
+	ConfigurationNotRequested = conditions.ReasonNotRequested
+	ConfigurationSucceeded    = conditions.ReasonSucceeded
+	// ConfigurationIncompatibleEnvironment indicates that kernel or firmware violates compatibility checks
+	ConfigurationIncompatibleEnvironment = conditions.ReasonIncompatibleEnvironment
+	// ConfigurationHalted indicates that configuration is postponed due to cluster-wide emergency stop
+	ConfigurationHalted = conditions.ReasonConfigurationHalted
+	// ConfigurationUnsupportedDevice indicates that requested accelerator is present, but missing in the discovery config
+	ConfigurationUnsupportedDevice = conditions.ReasonUnsupportedDevice
+	// ConfigurationInsufficientPrivileges indicates that the daemon is not able to configure accelerators, see ProbePrivileges
+	ConfigurationInsufficientPrivileges = conditions.ReasonInsufficientPrivileges
+	// ConfigurationDeviceInUse indicates that configuration without drain was refused, as VFs are used by pods
+	ConfigurationDeviceInUse = conditions.ReasonDeviceInUse
+	// ConfigurationDeferred indicates that configuration waits for kernel params applied by MachineConfig, see
+	// kernelParamsDeferral, or that node config was written by newer operator version, see newerWriterDeferral, or
+	// that reboot on persistent pf-bb-config failure waits for the reboot lock, see rebootDeferredError
+	ConfigurationDeferred = conditions.ReasonConfigurationDeferred
+	// ConfigurationNoAcceleratorsDiscovered indicates that spec requests PFs, but inventory has no supported accelerators
+	ConfigurationNoAcceleratorsDiscovered = conditions.ReasonNoAcceleratorsDiscovered
+	// ConfigurationGivenUp indicates that configuration failed more times than maxConfigurationRetries allows, see
+	// configurationGivenUp
+	ConfigurationGivenUp = conditions.ReasonConfigurationGivenUp
+	// ConfigurationRebootRequested indicates that the node is rebooted after repeated pf-bb-config failures
+	ConfigurationRebootRequested = conditions.ReasonRebootRequested
 )
 
 var (
-	resyncPeriod             = time.Minute
+	resyncPeriod             = newResyncPeriod()
 	configPath               = "/sriov_config/config/accelerators.json"
 	VrbconfigPath            = "/sriov_config/config/accelerators_vrb.json"
 	getSriovInventory        = GetSriovInventory
 	VrbgetSriovInventory     = VrbGetSriovInventory
 	supportedAccelerators    utils.AcceleratorDiscoveryConfig
 	VrbsupportedAccelerators utils.AcceleratorDiscoveryConfig
-	procCmdlineFilePath      = "/proc/cmdline"
-	sysLockdownFilePath      = "/sys/kernel/security/lockdown"
-	kernelParams             = []string{"intel_iommu=on", "iommu=pt"}
+	// discovery config hashes are computed whenever the configs are (re)loaded and reported in status of node configs;
+	// guarded by discoveryConfigHashLock as the configs are reloaded by dependency watch
+	discoveryConfigHash     string
+	VrbdiscoveryConfigHash  string
+	discoveryConfigHashLock sync.RWMutex
+	procCmdlineFilePath     = "/proc/cmdline"
+	sysLockdownFilePath     = "/sys/kernel/security/lockdown"
 )
 
 type NodeConfigReconciler struct {
@@ -63,23 +100,60 @@ type NodeConfigReconciler struct {
 	sriovfecconfigurer  Configurer
 	vrbconfigurer       VrbConfigurer
 	restartDevicePlugin RestartDevicePluginFunction
+	verifyRescheduling  VerifyRescheduling
+	// reschedulingReports are reports of rescheduling of evicted pods running in background
+	reschedulingReports sync.WaitGroup
+	recorder            record.EventRecorder
+	// configurationInProgress is shared by SriovFecNodeConfig and SriovVrbNodeConfig controllers
+	configurationInProgress int32
+	fecSpecDebouncer        specDebouncer
+	vrbSpecDebouncer        specDebouncer
+	// secondarySpecDebouncers are debouncers of secondary SriovFecNodeConfigs by name
+	secondarySpecDebouncers sync.Map
+	audit                   *AuditSink
+	dependencies            *dependencyWatcher
+	// rebootLock is held while the node reboots on persistent pf-bb-config failure
+	rebootLock *rebootLock
+	// rescans carry node configs which reconcile was requested for with RequestRescan
+	rescans chan event.GenericEvent
+	// missingPrivileges are reported by ProbePrivileges at startup
+	missingPrivileges []string
+	// pfBBConfigCapabilities are detected by DetectPfBBConfigCapabilities at startup; nil when unknown
+	pfBBConfigCapabilities *pfBBConfigCapabilities
+	// nodeNameMatching tells how names of node configs are matched against name of the node
+	nodeNameMatching NodeNameMatching
+	// nodeConfigName is name of node configs adopted with hostname matching; empty when they are named after the node
+	nodeConfigName struct {
+		sync.Mutex
+		name string
+	}
 }
 
-type DrainAndExecute func(configurer func(ctx context.Context) bool, drain bool) error
+type DrainAndExecute func(ctx context.Context, configurer func(ctx context.Context) bool, drain bool) error
+
+// VerifyRescheduling checks whether pods evicted during the last drain are running again; returns nil if nothing was evicted
+type VerifyRescheduling func(ctx context.Context) *drainhelper.ReschedulingSummary
 
 type Configurer interface {
-	ApplySpec(nodeConfig fec.SriovFecNodeConfigSpec) error
+	// ApplySpec configures accelerators as requested by the spec; progress is reported with drainhelper.ReportProgress
+	ApplySpec(ctx context.Context, nodeConfig fec.SriovFecNodeConfigSpec) error
+	// RestartPfBBConfig applies BBDevConfig of already configured PF by restarting pf-bb-config; VFs are kept
+	RestartPfBBConfig(ctx context.Context, pf fec.PhysicalFunctionConfigExt) error
 }
 
 type VrbConfigurer interface {
-	VrbApplySpec(nodeConfig vrbv1.SriovVrbNodeConfigSpec) error
+	VrbApplySpec(ctx context.Context, nodeConfig vrbv1.SriovVrbNodeConfigSpec) error
+	VrbRestartPfBBConfig(ctx context.Context, pf vrbv1.PhysicalFunctionConfigExt) error
 }
 
-type RestartDevicePluginFunction func() error
+type RestartDevicePluginFunction func(ctx context.Context) error
 
-func NewNodeConfigReconciler(k8sClient client.Client, drainer DrainAndExecute,
+// NewNodeConfigReconciler creates reconciler which configures accelerators of the node within drains performed by
+// drainer
+func NewNodeConfigReconciler(k8sClient client.Client, drainer drainhelper.Drainer,
 	nodeNameRef types.NamespacedName, sriovfecconfigurer Configurer, vrbconfigurer VrbConfigurer,
-	restartDevicePluginFunction RestartDevicePluginFunction) (r *NodeConfigReconciler, err error) {
+	restartDevicePluginFunction RestartDevicePluginFunction,
+	recorder record.EventRecorder, audit *AuditSink) (r *NodeConfigReconciler, err error) {
 
 	if supportedAccelerators, err = utils.LoadDiscoveryConfig(configPath); err != nil {
 		return nil, err
@@ -89,196 +163,623 @@ func NewNodeConfigReconciler(k8sClient client.Client, drainer DrainAndExecute,
 		return nil, err
 	}
 
+	log := utils.NewLogger()
+	discoveryConfigHash = utils.DiscoveryConfigHash(supportedAccelerators)
+	VrbdiscoveryConfigHash = utils.DiscoveryConfigHash(VrbsupportedAccelerators)
+	log.WithField("hash", discoveryConfigHash).WithField("vrbHash", VrbdiscoveryConfigHash).Info("discovery configs loaded")
+
 	return &NodeConfigReconciler{
 		Client:              k8sClient,
-		drainerAndExecute:   drainer,
-		log:                 utils.NewLogger(),
+		drainerAndExecute:   drainer.Run,
+		log:                 log,
 		nodeNameRef:         nodeNameRef,
 		sriovfecconfigurer:  sriovfecconfigurer,
 		vrbconfigurer:       vrbconfigurer,
 		restartDevicePlugin: restartDevicePluginFunction,
+		verifyRescheduling:  drainer.VerifyRescheduling,
+		recorder:            recorder,
+		audit:               audit,
+		dependencies:        newDependencyWatcher(),
+		rescans:             make(chan event.GenericEvent, 8),
+		rebootLock:          newRebootLock(k8sClient, nodeNameRef, log),
 	}, nil
 }
 
-func (r *NodeConfigReconciler) Reconcile(_ context.Context, req ctrl.Request) (ctrl.Result, error) {
-	r.log.Infof("Reconcile(...) triggered by %s", req.NamespacedName.String())
+// IsConfigurationInProgress tells whether accelerators of the node are being configured
+func (r *NodeConfigReconciler) IsConfigurationInProgress() bool {
+	return atomic.LoadInt32(&r.configurationInProgress) == 1
+}
+
+func (r *NodeConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	// node configs failed when reconcile panics; narrowed down to the one being configured
+	var affected []client.Object
+	defer r.recoverReconcilePanic(ctx, &affected, &result, &err)
+
+	ctx = withStatusBaselines(withReconcileID(ctx, uuid.NewString()))
+	r.log.WithField("reconcileID", reconcileIDFrom(ctx)).Infof("Reconcile(...) triggered by %s", req.NamespacedName.String())
+	r.reloadDependenciesIfChanged(ctx)
+
+	// configuration would clear the reboot-pending mark of the node, which is kept until the host has rebooted
+	if r.awaitingReboot(ctx) {
+		return requeueLater()
+	}
 
-	sfnc, err := r.readSriovFecNodeConfig(req.NamespacedName)
+	if req.Name != r.nodeConfigKey().Name {
+		return r.reconcileSecondaryNodeConfig(ctx, req.NamespacedName, &affected)
+	}
+
+	sfnc, err := r.readSriovFecNodeConfig(ctx, req.NamespacedName)
 	if err != nil {
 		return requeueNowWithError(err)
 	}
 
-	vrbnc, err := r.readVrbNodeConfig(req.NamespacedName)
+	vrbnc, err := r.readVrbNodeConfig(ctx, req.NamespacedName)
 
 	if err != nil {
 		return requeueNowWithError(err)
 	}
+	affected = []client.Object{sfnc, vrbnc}
+
+	if err := r.migrateStatus(ctx, sfnc); err != nil {
+		return requeueNowWithError(err)
+	}
+
+	if err := r.VrbmigrateStatus(ctx, vrbnc); err != nil {
+		return requeueNowWithError(err)
+	}
+
+	// neither node config is acted on, both are written by the same cluster controllers
+	fecWriterDeferral, vrbWriterDeferral := r.newerWriterDeferral(sfnc), r.newerWriterDeferral(vrbnc)
+	if fecWriterDeferral != "" || vrbWriterDeferral != "" {
+		if fecWriterDeferral != "" {
+			if err := r.updateStatus(ctx, sfnc, metav1.ConditionFalse, ConfigurationDeferred, fecWriterDeferral); err != nil {
+				return requeueNowWithError(err)
+			}
+		}
+		if vrbWriterDeferral != "" {
+			if err := r.VrbupdateStatus(ctx, vrbnc, metav1.ConditionFalse, ConfigurationDeferred, vrbWriterDeferral); err != nil {
+				return requeueNowWithError(err)
+			}
+		}
+		return requeueLater()
+	}
 
 	if err := validateNodeConfig(sfnc.Spec); err != nil {
-		return requeueNowWithError(r.updateStatus(sfnc, metav1.ConditionFalse, ConfigurationFailed, err.Error()))
+		if deferral := r.kernelParamsDeferral(ctx, requiredKernelParams(supportedAccelerators, hostArch)); deferral != "" {
+			r.log.WithField("reason", deferral).Info("configuration deferred")
+			return requeueLaterOrNowIfError(r.updateStatus(ctx, sfnc, metav1.ConditionFalse, ConfigurationDeferred, deferral))
+		}
+		return requeueNowWithError(r.updateStatus(ctx, sfnc, metav1.ConditionFalse, ConfigurationFailed, err.Error()))
 	}
 
-	detectedInventory, err := r.readExistingInventory()
+	detectedInventory, err := r.readExistingInventory(ctx)
 	if err != nil {
 		return requeueNowWithError(err)
 	}
 
-	vrbdetectedInventory, err := r.VrbreadExistingInventory()
+	// accelerators owned by secondary node configs are left to them
+	fecScope, err := r.fecAcceleratorScope(ctx, sfnc)
+	if err != nil {
+		return requeueNowWithError(err)
+	}
+	fecInventory := fecScope.inventory(detectedInventory)
+
+	vrbdetectedInventory, err := r.VrbreadExistingInventory(ctx)
 	if err != nil {
 		return requeueNowWithError(err)
 	}
 
 	if err := validateVrbNodeConfig(vrbnc.Spec); err != nil {
-		return requeueNowWithError(r.VrbupdateStatus(vrbnc, metav1.ConditionFalse, ConfigurationFailed, err.Error()))
+		if deferral := r.kernelParamsDeferral(ctx, requiredKernelParams(VrbsupportedAccelerators, hostArch)); deferral != "" {
+			r.log.WithField("reason", deferral).Info("configuration deferred")
+			return requeueLaterOrNowIfError(r.VrbupdateStatus(ctx, vrbnc, metav1.ConditionFalse, ConfigurationDeferred, deferral))
+		}
+		return requeueNowWithError(r.VrbupdateStatus(ctx, vrbnc, metav1.ConditionFalse, ConfigurationFailed, err.Error()))
+	}
+
+	if err := fecUnsupportedDeviceRequested(sfnc.Spec.PhysicalFunctions, detectedInventory); err != nil {
+		r.log.WithError(err).Info("requested configuration refers to unsupported accelerator")
+		return requeueLaterOrNowIfError(r.updateStatus(ctx, sfnc, metav1.ConditionFalse, ConfigurationUnsupportedDevice, err.Error()))
+	}
+
+	if err := vrbUnsupportedDeviceRequested(vrbnc.Spec.PhysicalFunctions, vrbdetectedInventory); err != nil {
+		r.log.WithError(err).Info("requested configuration refers to unsupported accelerator")
+		return requeueLaterOrNowIfError(r.VrbupdateStatus(ctx, vrbnc, metav1.ConditionFalse, ConfigurationUnsupportedDevice, err.Error()))
+	}
+
+	// no drain, as configuration would fail anyway
+	if noAcceleratorsDiscovered(len(sfnc.Spec.PhysicalFunctions), len(detectedInventory.SriovAccelerators)) {
+		msg := noAcceleratorsDiscoveredMessage(len(sfnc.Spec.PhysicalFunctions))
+		r.reportNoAcceleratorsDiscovered(sfnc, sfnc.FindCondition(ConditionConfigured), msg)
+		return requeueDegradedOrNowIfError(r.updateStatus(ctx, sfnc, metav1.ConditionFalse, ConfigurationNoAcceleratorsDiscovered, msg), r.log)
+	}
+
+	if noAcceleratorsDiscovered(len(vrbnc.Spec.PhysicalFunctions), len(vrbdetectedInventory.SriovAccelerators)) {
+		msg := noAcceleratorsDiscoveredMessage(len(vrbnc.Spec.PhysicalFunctions))
+		r.reportNoAcceleratorsDiscovered(vrbnc, vrbnc.FindCondition(ConditionConfigured), msg)
+		return requeueDegradedOrNowIfError(r.VrbupdateStatus(ctx, vrbnc, metav1.ConditionFalse, ConfigurationNoAcceleratorsDiscovered, msg), r.log)
 	}
 
 	if isConfigurationOfNonExistingInventoryRequested(sfnc.Spec.PhysicalFunctions, detectedInventory) {
 		r.log.Info("requested configuration refers to not existing accelerator(s)")
-		return requeueLaterOrNowIfError(r.updateStatus(sfnc, metav1.ConditionFalse, ConfigurationFailed, "requested configuration refers to not existing accelerator"))
+		return requeueLaterOrNowIfError(r.updateStatus(ctx, sfnc, metav1.ConditionFalse, ConfigurationFailed, "requested configuration refers to not existing accelerator"))
 	}
 
 	if VrbisConfigurationOfNonExistingInventoryRequested(vrbnc.Spec.PhysicalFunctions, vrbdetectedInventory) {
 		r.log.Info("requested configuration refers to not existing accelerator(s)")
-		return requeueLaterOrNowIfError(r.VrbupdateStatus(vrbnc, metav1.ConditionFalse, ConfigurationFailed, "requested configuration refers to not existing accelerator"))
+		return requeueLaterOrNowIfError(r.VrbupdateStatus(ctx, vrbnc, metav1.ConditionFalse, ConfigurationFailed, "requested configuration refers to not existing accelerator"))
+	}
+
+	bbDevConfigHashes, err := r.bbDevConfigHashes(ctx, fecBBDevConfigRefs(sfnc.Spec.PhysicalFunctions))
+	if err != nil {
+		r.log.WithError(err).Error("failed to read bbDevConfigFrom")
+		return requeueLaterOrNowIfError(r.updateStatus(ctx, sfnc, metav1.ConditionFalse, ConfigurationFailed, err.Error()))
+	}
+
+	vrbBBDevConfigHashes, err := r.bbDevConfigHashes(ctx, vrbBBDevConfigRefs(vrbnc.Spec.PhysicalFunctions))
+	if err != nil {
+		r.log.WithError(err).Error("failed to read bbDevConfigFrom")
+		return requeueLaterOrNowIfError(r.VrbupdateStatus(ctx, vrbnc, metav1.ConditionFalse, ConfigurationFailed, err.Error()))
+	}
+
+	// debounce does not affect the hardware, so changing it alone does not require reconfiguration
+	fecSpec, vrbSpec := sfnc.Spec, vrbnc.Spec
+	fecSpec.ConfigurationDebounce, vrbSpec.ConfigurationDebounce = nil, nil
+	// nor does soak after configuration
+	fecSpec.PostConfigureSoakSeconds, vrbSpec.PostConfigureSoakSeconds = 0, 0
+	// neither does forcing removal of VFs in use
+	fecSpec.ForceVfRemoval, vrbSpec.ForceVfRemoval = false, false
+	// nor adoption of configuration found on the node, which is considered only when configuration is required anyway
+	fecSpec.AdoptExistingConfig, vrbSpec.AdoptExistingConfig = false, false
+	// nor logical names, which are only published
+	fecSpec.PhysicalFunctions = fecWithoutLogicalNamePrefixes(fecSpec.PhysicalFunctions)
+	vrbSpec.PhysicalFunctions = vrbWithoutLogicalNamePrefixes(vrbSpec.PhysicalFunctions)
+
+	fecSpecHash, err := specHash(fecSpec)
+	if err != nil {
+		return requeueNowWithError(err)
 	}
 
-	if !r.isCardUpdateRequired(sfnc, detectedInventory) && !r.VrbisCardUpdateRequired(vrbnc, vrbdetectedInventory) {
+	vrbSpecHash, err := specHash(vrbSpec)
+	if err != nil {
+		return requeueNowWithError(err)
+	}
+
+	// pf-bb-config stopped before reboot of the node is run again, even if nothing else has changed
+	stoppedForReboot := r.pfBBConfigsStoppedForReboot(ctx)
+	fecUpdateRequired := r.isCardUpdateRequired(ctx, sfnc, fecInventory, fecSpecHash) || bbDevConfigHashesChanged(bbDevConfigHashes, sfnc.Status.BBDevConfigHashes) ||
+		anyStoppedForReboot(stoppedForReboot, fecPCIAddresses(sfnc.Spec))
+	vrbUpdateRequired := r.VrbisCardUpdateRequired(ctx, vrbnc, vrbdetectedInventory, vrbSpecHash) || bbDevConfigHashesChanged(vrbBBDevConfigHashes, vrbnc.Status.BBDevConfigHashes) ||
+		anyStoppedForReboot(stoppedForReboot, vrbPCIAddresses(vrbnc.Spec))
+
+	if !fecUpdateRequired && !vrbUpdateRequired {
 		r.log.Info("Nothing to do")
-		return requeueLater()
+		if err := r.refreshInventory(ctx, sfnc, detectedInventory); err != nil {
+			return requeueNowWithError(err)
+		}
+		return requeueLaterOrNowIfError(r.VrbrefreshInventory(ctx, vrbnc, vrbdetectedInventory))
 	}
 
-	if r.isCardUpdateRequired(sfnc, detectedInventory) {
+	if fecUpdateRequired {
+		affected = []client.Object{sfnc}
+		return r.configureFecNodeConfig(withAcceleratorScope(ctx, fecScope), sfnc, fecInventory, fecSpec, fecSpecHash, bbDevConfigHashes)
+	}
+
+	if vrbUpdateRequired {
+		affected = []client.Object{vrbnc}
+
+		if vrbnc.IsConfigurationHalted() {
+			r.log.Info("configuration is halted cluster-wide - postponing")
+			return requeueLaterOrNowIfError(r.VrbupdateStatus(ctx, vrbnc, metav1.ConditionFalse, ConfigurationHalted, "Configuration halted by cluster-wide emergency stop"))
+		}
+
+		// retries of broken hardware would only drain the node over and over again; inventory is still reported
+		if retries := currentRetries((*fec.ConfigurationRetries)(vrbnc.Status.ConfigurationRetries), vrbnc, vrbv1.ForceReconfigureAnnotation); configurationGivenUp(retries, vrbnc.Spec.MaxConfigurationRetries) {
+			r.log.Info("configuration is given up - waiting for spec or force-reconfigure annotation change")
+			return requeueLaterOrNowIfError(r.VrbupdateStatus(ctx, vrbnc, metav1.ConditionFalse, ConfigurationGivenUp,
+				configurationGivenUpMessage(retries, *vrbnc.Spec.MaxConfigurationRetries, vrbv1.ForceReconfigureAnnotation)))
+		}
+
+		// no drain, as configuration would fail anyway
+		if len(r.missingPrivileges) > 0 {
+			return requeueLaterOrNowIfError(r.VrbupdateStatus(ctx, vrbnc, metav1.ConditionFalse, ConfigurationInsufficientPrivileges, r.insufficientPrivilegesMessage()))
+		}
+
+		// waiting does not hold the drain lease, it is acquired only once the spec settles
+		if remaining := r.vrbSpecDebouncer.remaining(vrbnc.GetGeneration(), vrbnc.Spec.ConfigurationDebounce, time.Now()); remaining > 0 {
+			r.log.WithField("remaining", remaining).Info("waiting for spec to settle - postponing")
+			if err := r.VrbupdateStatus(ctx, vrbnc, metav1.ConditionFalse, ConfigurationInProgress, specSettleMessage(remaining)); err != nil {
+				return requeueNowWithError(err)
+			}
+			return reconcile.Result{RequeueAfter: remaining}, nil
+		}
+
+		// checks below and drain waiting for the lease may take long, so that spec change is reported right away
+		if !inProgressReported(VrbfindOrCreateConfigurationStatusCondition(vrbnc), vrbnc.GetGeneration()) {
+			if err := r.VrbupdateStatus(ctx, vrbnc, metav1.ConditionFalse, ConfigurationInProgress, configurationQueuedMessage); err != nil {
+				return requeueNowWithError(err)
+			}
+		}
+
+		compatibilityWarning, err := r.verifyCompatibility(vrbCompatibilityDevices(vrbnc.Spec.PhysicalFunctions, vrbdetectedInventory), vrbnc.Spec.EnforceCompatibilityChecks, VrbsupportedAccelerators)
+		if err != nil {
+			return requeueLaterOrNowIfError(r.VrbupdateStatus(ctx, vrbnc, metav1.ConditionFalse, ConfigurationIncompatibleEnvironment, err.Error()))
+		}
+
+		if err := validateVrbPhysicalFunctions(vrbnc.Spec.PhysicalFunctions, vrbdetectedInventory, VrbsupportedAccelerators.VFDrivers); err != nil {
+			r.log.WithError(err).Error("requested configuration is invalid")
+			return requeueLaterOrNowIfError(r.VrbupdateStatus(ctx, vrbnc, metav1.ConditionFalse, ConfigurationFailed, err.Error()))
+		}
+
+		if err := checkVFsMSIXFeasibility(vrbRequestedVFs(vrbnc)); err != nil {
+			r.log.WithError(err).Error("requested VFs cannot be created")
+			return requeueLaterOrNowIfError(r.VrbupdateStatus(ctx, vrbnc, metav1.ConditionFalse, ConfigurationFailed, err.Error()))
+		}
+
+		if err := r.pfBBConfigCapabilities.verify(vrbPfBBConfigFeatureUses(vrbnc.Spec.PhysicalFunctions)); err != nil {
+			r.log.WithError(err).Error("requested BBDevConfig is not supported by pf_bb_config")
+			return requeueLaterOrNowIfError(r.VrbupdateStatus(ctx, vrbnc, metav1.ConditionFalse, ConfigurationIncompatibleEnvironment, err.Error()))
+		}
+
+		// configuration found on the node is adopted as it is, when it matches the spec completely
+		adoptedPFs := r.vrbAdoptablePFs(ctx, vrbnc.Spec, vrbdetectedInventory)
+		if len(adoptedPFs) > 0 && len(adoptedPFs) == len(vrbnc.Spec.PhysicalFunctions) && !vrbCleanupRequired(vrbnc.Spec.PhysicalFunctions, vrbdetectedInventory) {
+			r.log.WithField("pfs", adoptedPCIAddresses(adoptedPFs)).Info("configuration found on the node matches the spec - adopting it")
+			r.recordAdoptedBBDevConfigs(adoptedPFs)
+			vrbnc.Status.AdoptedPFs = adoptedPCIAddresses(adoptedPFs)
+			vrbnc.Status.BBDevConfigHashes = vrbBBDevConfigHashes
+			vrbnc.Status.AppliedSpecHash = vrbSpecHash
+			r.rememberAppliedSpec(hitlessUpdateKindVrb, vrbSpec)
+			return r.requeueIfUpdatedMeanwhile(ctx, vrbnc, r.VrbupdateStatus(ctx, vrbnc, metav1.ConditionTrue, ConfigurationSucceeded, "Configured successfully (adopted existing configuration)"+compatibilityWarning))
+		}
+
+		atomic.StoreInt32(&r.configurationInProgress, 1)
+		defer atomic.StoreInt32(&r.configurationInProgress, 0)
 
-		if err := r.updateStatus(sfnc, metav1.ConditionFalse, ConfigurationInProgress, "Configuration started"); err != nil {
+		if err := r.VrbupdateStatus(ctx, vrbnc, metav1.ConditionFalse, ConfigurationInProgress, "Configuration started"+compatibilityWarning+vfPodDisruptionMessage(vrbVFUsers(vrbdetectedInventory))); err != nil {
 			return requeueNowWithError(err)
 		}
 
-		if err := r.configureNode(sfnc); err != nil {
+		if hitlessPFs := r.vrbHitlessUpdatablePFs(vrbnc, vrbSpec, vrbdetectedInventory, vrbBBDevConfigHashes); len(hitlessPFs) > 0 {
+			err := r.restartPfBBConfigs(len(hitlessPFs), func(i int) error { return r.vrbconfigurer.VrbRestartPfBBConfig(ctx, hitlessPFs[i]) })
+			r.audit.commit(ctx, auditKindVrb, vrbnc.GetGeneration())
+			if err == nil {
+				vrbnc.Status.AdoptedPFs = nil
+				vrbnc.Status.BBDevConfigHashes = vrbBBDevConfigHashes
+				vrbnc.Status.AppliedSpecHash = vrbSpecHash
+				r.rememberAppliedSpec(hitlessUpdateKindVrb, vrbSpec)
+				return r.requeueIfUpdatedMeanwhile(ctx, vrbnc, r.VrbupdateStatus(ctx, vrbnc, metav1.ConditionTrue, ConfigurationSucceeded, "Configured successfully (hitless update)"+compatibilityWarning))
+			}
+		}
+
+		// without drain pods are not evicted, VFs would be removed from under them
+		if vrbnc.Spec.DrainSkip && !vrbnc.Spec.ForceVfRemoval {
+			if pods := r.podsUsingVFs(ctx, vrbVFAddresses(vrbdetectedInventory)); len(pods) > 0 {
+				r.log.WithField("pods", pods).Info("VFs are in use and drain is skipped - refusing configuration")
+				return requeueLaterOrNowIfError(r.VrbupdateStatus(ctx, vrbnc, metav1.ConditionFalse, ConfigurationDeviceInUse, deviceInUseMessage(pods)))
+			}
+		}
+
+		// PFs matching the spec are left untouched, only the other ones are configured
+		if err := r.VrbconfigureNode(withAdoptedPFs(ctx, adoptedPFs), vrbnc); err != nil {
 			r.log.WithError(err).Error("error occurred during configuring node")
-			return requeueNowWithError(r.updateStatus(sfnc, metav1.ConditionFalse, ConfigurationFailed, err.Error()))
+			retries := countFailure(currentRetries((*fec.ConfigurationRetries)(vrbnc.Status.ConfigurationRetries), vrbnc, vrbv1.ForceReconfigureAnnotation), err)
+			vrbnc.Status.ConfigurationRetries = (*vrbv1.ConfigurationRetries)(retries)
+			if configurationGivenUp(*retries, vrbnc.Spec.MaxConfigurationRetries) {
+				msg := configurationGivenUpMessage(*retries, *vrbnc.Spec.MaxConfigurationRetries, vrbv1.ForceReconfigureAnnotation)
+				r.reportConfigurationGivenUp(vrbnc, msg)
+				return requeueLaterOrNowIfError(r.VrbupdateStatus(ctx, vrbnc, metav1.ConditionFalse, ConfigurationGivenUp, msg))
+			}
+			if rebootRequested := new(rebootRequestedError); errors.As(err, &rebootRequested) {
+				return requeueLaterOrNowIfError(r.VrbupdateStatus(ctx, vrbnc, metav1.ConditionFalse, ConfigurationRebootRequested, err.Error()))
+			}
+			if rebootDeferred := new(rebootDeferredError); errors.As(err, &rebootDeferred) {
+				return requeueLaterOrNowIfError(r.VrbupdateStatus(ctx, vrbnc, metav1.ConditionFalse, ConfigurationDeferred, err.Error()))
+			}
+			return requeueNowWithError(r.VrbupdateStatus(ctx, vrbnc, metav1.ConditionFalse, ConfigurationFailed, err.Error()))
 		} else {
-			return requeueLaterOrNowIfError(r.updateStatus(sfnc, metav1.ConditionTrue, ConfigurationSucceeded, "Configured successfully"))
+			r.waitForInventorySettle(ctx, vrbRequestedVFs(vrbnc), vrbExposedVFs)
+			r.recordAdoptedBBDevConfigs(adoptedPFs)
+			vrbnc.Status.AdoptedPFs = adoptedPCIAddresses(adoptedPFs)
+			vrbnc.Status.BBDevConfigHashes = vrbBBDevConfigHashes
+			vrbnc.Status.AppliedSpecHash = vrbSpecHash
+			r.rememberAppliedSpec(hitlessUpdateKindVrb, vrbSpec)
+			return r.requeueIfUpdatedMeanwhile(ctx, vrbnc, r.VrbupdateStatus(ctx, vrbnc, metav1.ConditionTrue, ConfigurationSucceeded, "Configured successfully"+compatibilityWarning))
 		}
+
+	}
+
+	return requeueLater()
+}
+
+// configureFecNodeConfig applies spec of SriovFecNodeConfig to accelerators of inventory owned by it
+func (r *NodeConfigReconciler) configureFecNodeConfig(ctx context.Context, sfnc *fec.SriovFecNodeConfig, inventory *fec.NodeInventory,
+	fecSpec fec.SriovFecNodeConfigSpec, fecSpecHash string, bbDevConfigHashes map[string]string) (reconcile.Result, error) {
+
+	if sfnc.IsConfigurationHalted() {
+		r.log.Info("configuration is halted cluster-wide - postponing")
+		return requeueLaterOrNowIfError(r.updateStatus(ctx, sfnc, metav1.ConditionFalse, ConfigurationHalted, "Configuration halted by cluster-wide emergency stop"))
+	}
+
+	// retries of broken hardware would only drain the node over and over again; inventory is still reported
+	if retries := currentRetries(sfnc.Status.ConfigurationRetries, sfnc, fec.ForceReconfigureAnnotation); configurationGivenUp(retries, sfnc.Spec.MaxConfigurationRetries) {
+		r.log.Info("configuration is given up - waiting for spec or force-reconfigure annotation change")
+		return requeueLaterOrNowIfError(r.updateStatus(ctx, sfnc, metav1.ConditionFalse, ConfigurationGivenUp,
+			configurationGivenUpMessage(retries, *sfnc.Spec.MaxConfigurationRetries, fec.ForceReconfigureAnnotation)))
 	}
 
-	if r.VrbisCardUpdateRequired(vrbnc, vrbdetectedInventory) {
+	// no drain, as configuration would fail anyway
+	if len(r.missingPrivileges) > 0 {
+		return requeueLaterOrNowIfError(r.updateStatus(ctx, sfnc, metav1.ConditionFalse, ConfigurationInsufficientPrivileges, r.insufficientPrivilegesMessage()))
+	}
 
-		if err := r.VrbupdateStatus(vrbnc, metav1.ConditionFalse, ConfigurationInProgress, "Configuration started"); err != nil {
+	// waiting does not hold the drain lease, it is acquired only once the spec settles
+	if remaining := r.fecSpecDebouncerOf(sfnc).remaining(sfnc.GetGeneration(), sfnc.Spec.ConfigurationDebounce, time.Now()); remaining > 0 {
+		r.log.WithField("remaining", remaining).Info("waiting for spec to settle - postponing")
+		if err := r.updateStatus(ctx, sfnc, metav1.ConditionFalse, ConfigurationInProgress, specSettleMessage(remaining)); err != nil {
 			return requeueNowWithError(err)
 		}
+		return reconcile.Result{RequeueAfter: remaining}, nil
+	}
 
-		if err := r.VrbconfigureNode(vrbnc); err != nil {
-			r.log.WithError(err).Error("error occurred during configuring node")
-			return requeueNowWithError(r.VrbupdateStatus(vrbnc, metav1.ConditionFalse, ConfigurationFailed, err.Error()))
-		} else {
-			return requeueLaterOrNowIfError(r.VrbupdateStatus(vrbnc, metav1.ConditionTrue, ConfigurationSucceeded, "Configured successfully"))
+	// checks below and drain waiting for the lease may take long, so that spec change is reported right away
+	if !inProgressReported(findOrCreateConfigurationStatusCondition(sfnc), sfnc.GetGeneration()) {
+		if err := r.updateStatus(ctx, sfnc, metav1.ConditionFalse, ConfigurationInProgress, configurationQueuedMessage); err != nil {
+			return requeueNowWithError(err)
 		}
+	}
 
+	compatibilityWarning, err := r.verifyCompatibility(fecCompatibilityDevices(sfnc.Spec.PhysicalFunctions, inventory), sfnc.Spec.EnforceCompatibilityChecks, supportedAccelerators)
+	if err != nil {
+		return requeueLaterOrNowIfError(r.updateStatus(ctx, sfnc, metav1.ConditionFalse, ConfigurationIncompatibleEnvironment, err.Error()))
 	}
 
-	return requeueLater()
+	if err := validateFecPhysicalFunctions(sfnc.Spec.PhysicalFunctions, inventory, supportedAccelerators.VFDrivers); err != nil {
+		r.log.WithError(err).Error("requested configuration is invalid")
+		return requeueLaterOrNowIfError(r.updateStatus(ctx, sfnc, metav1.ConditionFalse, ConfigurationFailed, err.Error()))
+	}
+
+	if err := checkVFsMSIXFeasibility(fecRequestedVFs(sfnc)); err != nil {
+		r.log.WithError(err).Error("requested VFs cannot be created")
+		return requeueLaterOrNowIfError(r.updateStatus(ctx, sfnc, metav1.ConditionFalse, ConfigurationFailed, err.Error()))
+	}
+
+	if err := r.pfBBConfigCapabilities.verify(fecPfBBConfigFeatureUses(sfnc.Spec.PhysicalFunctions)); err != nil {
+		r.log.WithError(err).Error("requested BBDevConfig is not supported by pf_bb_config")
+		return requeueLaterOrNowIfError(r.updateStatus(ctx, sfnc, metav1.ConditionFalse, ConfigurationIncompatibleEnvironment, err.Error()))
+	}
+
+	if err := r.verifyFFTLuts(ctx, sfnc.Spec.PhysicalFunctions); err != nil {
+		r.log.WithError(err).Error("referenced FFT LUT cannot be used")
+		return requeueLaterOrNowIfError(r.updateStatus(ctx, sfnc, metav1.ConditionFalse, ConfigurationFailed, err.Error()))
+	}
+
+	// configuration found on the node is adopted as it is, when it matches the spec completely
+	adoptedPFs := r.fecAdoptablePFs(ctx, sfnc.Spec, inventory)
+	if len(adoptedPFs) > 0 && len(adoptedPFs) == len(sfnc.Spec.PhysicalFunctions) && !fecCleanupRequired(sfnc.Spec.PhysicalFunctions, inventory) {
+		r.log.WithField("pfs", adoptedPCIAddresses(adoptedPFs)).Info("configuration found on the node matches the spec - adopting it")
+		r.recordAdoptedBBDevConfigs(adoptedPFs)
+		sfnc.Status.AdoptedPFs = adoptedPCIAddresses(adoptedPFs)
+		sfnc.Status.BBDevConfigHashes = bbDevConfigHashes
+		sfnc.Status.AppliedSpecHash = fecSpecHash
+		r.rememberAppliedSpec(fecAppliedSpecKind(sfnc), fecSpec)
+		return r.requeueIfUpdatedMeanwhile(ctx, sfnc, r.updateStatus(ctx, sfnc, metav1.ConditionTrue, ConfigurationSucceeded, "Configured successfully (adopted existing configuration)"+compatibilityWarning))
+	}
+
+	atomic.StoreInt32(&r.configurationInProgress, 1)
+	defer atomic.StoreInt32(&r.configurationInProgress, 0)
+
+	if err := r.updateStatus(ctx, sfnc, metav1.ConditionFalse, ConfigurationInProgress, "Configuration started"+compatibilityWarning+vfPodDisruptionMessage(fecVFUsers(inventory))); err != nil {
+		return requeueNowWithError(err)
+	}
+
+	if hitlessPFs := r.fecHitlessUpdatablePFs(sfnc, fecSpec, inventory, bbDevConfigHashes); len(hitlessPFs) > 0 {
+		err := r.restartPfBBConfigs(len(hitlessPFs), func(i int) error { return r.sriovfecconfigurer.RestartPfBBConfig(ctx, hitlessPFs[i]) })
+		r.audit.commit(ctx, auditKindFec, sfnc.GetGeneration())
+		if err == nil {
+			sfnc.Status.AdoptedPFs = nil
+			sfnc.Status.BBDevConfigHashes = bbDevConfigHashes
+			sfnc.Status.AppliedSpecHash = fecSpecHash
+			r.rememberAppliedSpec(fecAppliedSpecKind(sfnc), fecSpec)
+			return r.requeueIfUpdatedMeanwhile(ctx, sfnc, r.updateStatus(ctx, sfnc, metav1.ConditionTrue, ConfigurationSucceeded, "Configured successfully (hitless update)"+compatibilityWarning))
+		}
+	}
+
+	// without drain pods are not evicted, VFs would be removed from under them
+	if sfnc.Spec.DrainSkip && !sfnc.Spec.ForceVfRemoval {
+		if pods := r.podsUsingVFs(ctx, fecVFAddresses(inventory)); len(pods) > 0 {
+			r.log.WithField("pods", pods).Info("VFs are in use and drain is skipped - refusing configuration")
+			return requeueLaterOrNowIfError(r.updateStatus(ctx, sfnc, metav1.ConditionFalse, ConfigurationDeviceInUse, deviceInUseMessage(pods)))
+		}
+	}
+
+	// PFs matching the spec are left untouched, only the other ones are configured
+	if err := r.configureNode(withAdoptedPFs(ctx, adoptedPFs), sfnc); err != nil {
+		r.log.WithError(err).Error("error occurred during configuring node")
+		sfnc.Status.ConfigurationRetries = countFailure(currentRetries(sfnc.Status.ConfigurationRetries, sfnc, fec.ForceReconfigureAnnotation), err)
+		if configurationGivenUp(*sfnc.Status.ConfigurationRetries, sfnc.Spec.MaxConfigurationRetries) {
+			msg := configurationGivenUpMessage(*sfnc.Status.ConfigurationRetries, *sfnc.Spec.MaxConfigurationRetries, fec.ForceReconfigureAnnotation)
+			r.reportConfigurationGivenUp(sfnc, msg)
+			return requeueLaterOrNowIfError(r.updateStatus(ctx, sfnc, metav1.ConditionFalse, ConfigurationGivenUp, msg))
+		}
+		if rebootRequested := new(rebootRequestedError); errors.As(err, &rebootRequested) {
+			return requeueLaterOrNowIfError(r.updateStatus(ctx, sfnc, metav1.ConditionFalse, ConfigurationRebootRequested, err.Error()))
+		}
+		if rebootDeferred := new(rebootDeferredError); errors.As(err, &rebootDeferred) {
+			return requeueLaterOrNowIfError(r.updateStatus(ctx, sfnc, metav1.ConditionFalse, ConfigurationDeferred, err.Error()))
+		}
+		return requeueNowWithError(r.updateStatus(ctx, sfnc, metav1.ConditionFalse, ConfigurationFailed, err.Error()))
+	} else {
+		r.waitForInventorySettle(ctx, fecRequestedVFs(sfnc), fecExposedVFs)
+		r.recordAdoptedBBDevConfigs(adoptedPFs)
+		sfnc.Status.AdoptedPFs = adoptedPCIAddresses(adoptedPFs)
+		sfnc.Status.BBDevConfigHashes = bbDevConfigHashes
+		sfnc.Status.AppliedSpecHash = fecSpecHash
+		r.rememberAppliedSpec(fecAppliedSpecKind(sfnc), fecSpec)
+		return r.requeueIfUpdatedMeanwhile(ctx, sfnc, r.updateStatus(ctx, sfnc, metav1.ConditionTrue, ConfigurationSucceeded, "Configured successfully"+compatibilityWarning))
+	}
 }
 
 // CreateEmptyNodeConfigIfNeeded creates empty CR to be Reconciled in near future and filled with Status.
 // If invoked before manager's Start, it'll need a direct API client
 // (Manager's/Controller's client is cached and cache is not initialized yet).
-func (r *NodeConfigReconciler) CreateEmptyNodeConfigIfNeeded(c client.Client) error {
+func (r *NodeConfigReconciler) CreateEmptyNodeConfigIfNeeded(ctx context.Context, c client.Client) error {
 	SriovFecnodeConfig := &fec.SriovFecNodeConfig{}
 
-	err := c.Get(context.Background(), client.ObjectKey{Name: r.nodeNameRef.Name, Namespace: r.nodeNameRef.Namespace}, SriovFecnodeConfig)
+	if err := r.adoptNodeConfigIfNeeded(ctx, c, &fec.SriovFecNodeConfigList{}); err != nil {
+		return err
+	}
+	nodeConfigRef := r.nodeConfigKey()
+
+	err := c.Get(ctx, nodeConfigRef, SriovFecnodeConfig)
 	if err == nil {
 		r.log.Info("already exists")
-		return nil
+		return r.markAdopted(ctx, c, SriovFecnodeConfig, fec.AdoptedByNodeAnnotation)
 	}
 
 	if !k8serrors.IsNotFound(err) {
 		return err
 	}
 
-	r.log.Infof("SriovFecNodeConfig{%s} not found - creating", r.nodeNameRef)
+	r.log.Infof("SriovFecNodeConfig{%s} not found - creating", nodeConfigRef)
 
 	SriovFecnodeConfig = &fec.SriovFecNodeConfig{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      r.nodeNameRef.Name,
-			Namespace: r.nodeNameRef.Namespace,
+			Name:      nodeConfigRef.Name,
+			Namespace: nodeConfigRef.Namespace,
 		},
 		Spec: fec.SriovFecNodeConfigSpec{
 			PhysicalFunctions: []fec.PhysicalFunctionConfigExt{},
 		},
 	}
 
-	if createErr := c.Create(context.Background(), SriovFecnodeConfig); createErr != nil {
+	key := client.ObjectKeyFromObject(SriovFecnodeConfig)
+	// empty spec created by the daemon is observed right away, spec created by the cluster controller is not
+	var observedGeneration int64
+	switch createErr := c.Create(ctx, SriovFecnodeConfig); {
+	case createErr == nil:
+		observedGeneration = SriovFecnodeConfig.GetGeneration()
+	case k8serrors.IsAlreadyExists(createErr):
+		// created by the cluster controller in the meantime; its spec is kept, only status is initialized
+		r.log.Info("created concurrently - proceeding with the existing one")
+		if err := c.Get(ctx, key, SriovFecnodeConfig); err != nil {
+			return err
+		}
+	default:
 		r.log.WithError(createErr).Error("failed to create")
 		return createErr
 	}
+	if err := r.markAdopted(ctx, c, SriovFecnodeConfig, fec.AdoptedByNodeAnnotation); err != nil {
+		return err
+	}
 
-	meta.SetStatusCondition(&SriovFecnodeConfig.Status.Conditions, metav1.Condition{
-		Type:               ConditionConfigured,
-		Status:             metav1.ConditionFalse,
-		Reason:             string(ConfigurationNotRequested),
-		Message:            "",
-		ObservedGeneration: SriovFecnodeConfig.GetGeneration(),
-	})
-
-	if inv, err := r.readExistingInventory(); err != nil {
+	inv, err := r.readExistingInventory(ctx)
+	if err != nil {
 		return err
-	} else {
-		SriovFecnodeConfig.Status.Inventory = *inv
 	}
 
-	if updateErr := c.Status().Update(context.Background(), SriovFecnodeConfig); updateErr != nil {
+	// only status is patched; it is patched against the stored node config, which may be updated concurrently
+	updateErr := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		original := SriovFecnodeConfig.DeepCopy()
+		if SriovFecnodeConfig.FindCondition(ConditionConfigured) == nil {
+			meta.SetStatusCondition(&SriovFecnodeConfig.Status.Conditions,
+				conditions.Configured(metav1.ConditionFalse, ConfigurationNotRequested, "", observedGeneration))
+		}
+		SriovFecnodeConfig.Status.Inventory = *inv
+		SriovFecnodeConfig.Status.UnsupportedDevices = inv.UnsupportedDevices
+		fecInventoryCollected(&SriovFecnodeConfig.Status)
+
+		_, err := patchStatus(ctx, c, original, SriovFecnodeConfig)
+		if k8serrors.IsConflict(err) {
+			if getErr := c.Get(ctx, key, SriovFecnodeConfig); getErr != nil {
+				return getErr
+			}
+		}
+		return err
+	})
+	if updateErr != nil {
 		r.log.WithError(updateErr).Error("failed to update cr status")
 		return updateErr
 	}
 	return nil
 }
 
-func (r *NodeConfigReconciler) VrbCreateEmptyNodeConfigIfNeeded(c client.Client) error {
+func (r *NodeConfigReconciler) VrbCreateEmptyNodeConfigIfNeeded(ctx context.Context, c client.Client) error {
 
 	VrbnodeConfig := &vrbv1.SriovVrbNodeConfig{}
 
-	err := c.Get(context.Background(), client.ObjectKey{Name: r.nodeNameRef.Name, Namespace: r.nodeNameRef.Namespace}, VrbnodeConfig)
+	if err := r.adoptNodeConfigIfNeeded(ctx, c, &vrbv1.SriovVrbNodeConfigList{}); err != nil {
+		return err
+	}
+	nodeConfigRef := r.nodeConfigKey()
+
+	err := c.Get(ctx, nodeConfigRef, VrbnodeConfig)
 	if err == nil {
 		r.log.Info("already exists")
-		return nil
+		return r.markAdopted(ctx, c, VrbnodeConfig, vrbv1.AdoptedByNodeAnnotation)
 	}
 
 	if !k8serrors.IsNotFound(err) {
 		return err
 	}
 
-	r.log.Infof("VrbnodeConfig{%s} not found - creating", r.nodeNameRef)
+	r.log.Infof("VrbnodeConfig{%s} not found - creating", nodeConfigRef)
 
 	VrbnodeConfig = &vrbv1.SriovVrbNodeConfig{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      r.nodeNameRef.Name,
-			Namespace: r.nodeNameRef.Namespace,
+			Name:      nodeConfigRef.Name,
+			Namespace: nodeConfigRef.Namespace,
 		},
 		Spec: vrbv1.SriovVrbNodeConfigSpec{
 			PhysicalFunctions: []vrbv1.PhysicalFunctionConfigExt{},
 		},
 	}
 
-	if createErr := c.Create(context.Background(), VrbnodeConfig); createErr != nil {
+	key := client.ObjectKeyFromObject(VrbnodeConfig)
+	// empty spec created by the daemon is observed right away, spec created by the cluster controller is not
+	var observedGeneration int64
+	switch createErr := c.Create(ctx, VrbnodeConfig); {
+	case createErr == nil:
+		observedGeneration = VrbnodeConfig.GetGeneration()
+	case k8serrors.IsAlreadyExists(createErr):
+		// created by the cluster controller in the meantime; its spec is kept, only status is initialized
+		r.log.Info("created concurrently - proceeding with the existing one")
+		if err := c.Get(ctx, key, VrbnodeConfig); err != nil {
+			return err
+		}
+	default:
 		r.log.WithError(createErr).Error("failed to create")
 		return createErr
 	}
+	if err := r.markAdopted(ctx, c, VrbnodeConfig, vrbv1.AdoptedByNodeAnnotation); err != nil {
+		return err
+	}
 
-	meta.SetStatusCondition(&VrbnodeConfig.Status.Conditions, metav1.Condition{
-		Type:               ConditionConfigured,
-		Status:             metav1.ConditionFalse,
-		Reason:             string(ConfigurationNotRequested),
-		Message:            "",
-		ObservedGeneration: VrbnodeConfig.GetGeneration(),
-	})
-
-	if inv, err := r.VrbreadExistingInventory(); err != nil {
+	inv, err := r.VrbreadExistingInventory(ctx)
+	if err != nil {
 		return err
-	} else {
-		VrbnodeConfig.Status.Inventory = *inv
 	}
 
-	if updateErr := c.Status().Update(context.Background(), VrbnodeConfig); updateErr != nil {
+	// only status is patched; it is patched against the stored node config, which may be updated concurrently
+	updateErr := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		original := VrbnodeConfig.DeepCopy()
+		if VrbnodeConfig.FindCondition(ConditionConfigured) == nil {
+			meta.SetStatusCondition(&VrbnodeConfig.Status.Conditions,
+				conditions.Configured(metav1.ConditionFalse, ConfigurationNotRequested, "", observedGeneration))
+		}
+		VrbnodeConfig.Status.Inventory = *inv
+		VrbnodeConfig.Status.UnsupportedDevices = inv.UnsupportedDevices
+		vrbInventoryCollected(&VrbnodeConfig.Status)
+
+		_, err := patchStatus(ctx, c, original, VrbnodeConfig)
+		if k8serrors.IsConflict(err) {
+			if getErr := c.Get(ctx, key, VrbnodeConfig); getErr != nil {
+				return getErr
+			}
+		}
+		return err
+	})
+	if updateErr != nil {
 		r.log.WithError(updateErr).Error("failed to update cr status")
 		return updateErr
 	}
@@ -286,71 +787,137 @@ func (r *NodeConfigReconciler) VrbCreateEmptyNodeConfigIfNeeded(c client.Client)
 	return nil
 }
 
+func (r *NodeConfigReconciler) nodeConfigPredicate() predicate.Predicate {
+	return predicate.And(
+		resourceNamePredicate{
+			requiredName: r.nodeNameRef.Name,
+			matching:     r.nodeNameMatching,
+			log:          r.log,
+		},
+		// create events always pass (e.g. node config restored from backup with stale status), whether hardware
+		// has to be configured is decided by Reconcile from applied spec hash and detected inventory
+		predicate.Or(
+			predicate.GenerationChangedPredicate{},
+			// annotation change is required to react on cluster-wide emergency stop being lifted and on configuration
+			// given up being forced
+			controlAnnotationsChangedPredicate{
+				annotations: []string{fec.ConfigurationHaltedAnnotation, vrbv1.ConfigurationHaltedAnnotation,
+					fec.WrittenByVersionAnnotation, vrbv1.WrittenByVersionAnnotation,
+					fec.IgnoreWriterVersionAnnotation, vrbv1.IgnoreWriterVersionAnnotation,
+					fec.ForceReconfigureAnnotation, vrbv1.ForceReconfigureAnnotation},
+			},
+		),
+	)
+}
+
 func (r *NodeConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
 
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&fec.SriovFecNodeConfig{}).
-		WithEventFilter(
-			predicate.And(
-				resourceNamePredicate{
-					requiredName: r.nodeNameRef.Name,
-					log:          r.log,
-				},
-				predicate.GenerationChangedPredicate{},
-			),
-		).Complete(r)
+		For(&fec.SriovFecNodeConfig{}, builder.WithPredicates(r.nodeConfigPredicate())).
+		// changes of ConfigMaps referenced by bbDevConfigFrom have to be applied as well
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, handler.EnqueueRequestsFromMapFunc(func(cm client.Object) []reconcile.Request {
+			return r.requestsForConfigMap(cm, &fec.SriovFecNodeConfig{})
+		})).
+		// ConfigMaps and Secrets consumed by the daemon are reloaded without restart; Reconcile handles both
+		// SriovFecNodeConfig and SriovVrbNodeConfig, so it is enough to enqueue reload-triggered reconcile here
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, handler.EnqueueRequestsFromMapFunc(r.requestsForDependency)).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(r.requestsForDependency)).
+		Watches(&source.Channel{Source: r.dependencies.events}, &handler.EnqueueRequestForObject{}).
+		Watches(&source.Channel{Source: r.rescans}, &handler.EnqueueRequestForObject{}).
+		Watches(&source.Kind{Type: &fec.SriovFecNodeConfig{}}, handler.EnqueueRequestsFromMapFunc(r.requestsForPrimaryNodeConfig),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(r)
 }
 
 func (r *NodeConfigReconciler) VrbSetupWithManager(mgr ctrl.Manager) error {
 
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&vrbv1.SriovVrbNodeConfig{}).
-		WithEventFilter(
-			predicate.And(
-				resourceNamePredicate{
-					requiredName: r.nodeNameRef.Name,
-					log:          r.log,
-				},
-				predicate.GenerationChangedPredicate{},
-			),
-		).Complete(r)
-}
-
-func (r *NodeConfigReconciler) updateStatus(nc *fec.SriovFecNodeConfig, status metav1.ConditionStatus, reason ConfigurationConditionReason, msg string) error {
+		For(&vrbv1.SriovVrbNodeConfig{}, builder.WithPredicates(r.nodeConfigPredicate())).
+		// changes of ConfigMaps referenced by bbDevConfigFrom have to be applied as well
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, handler.EnqueueRequestsFromMapFunc(func(cm client.Object) []reconcile.Request {
+			return r.requestsForConfigMap(cm, &vrbv1.SriovVrbNodeConfig{})
+		})).
+		Complete(r)
+}
+
+func (r *NodeConfigReconciler) updateStatus(ctx context.Context, nc *fec.SriovFecNodeConfig, status metav1.ConditionStatus, reason ConfigurationConditionReason, msg string) error {
+	// diff against node config as last read or written within the reconcile, so that status fields set before this
+	// call (e.g. eviction summary) are patched too; resourceVersion is not diffed, so patch does not fail when object
+	// was modified in the meantime
+	original := statusBaselineOf(ctx, nc)
+	original.SetResourceVersion(nc.GetResourceVersion())
 	previousCondition := findOrCreateConfigurationStatusCondition(nc)
 
+	var pciAddresses []string
+	for _, pf := range nc.Spec.PhysicalFunctions {
+		pciAddresses = append(pciAddresses, pf.PCIAddress)
+	}
+	if reason == ConfigurationSucceeded {
+		nc.Status.AppliedGenerations = setAppliedGenerations(nc.Status.AppliedGenerations, pciAddresses, nc.GetGeneration())
+		nc.Status.ConfigurationRetries = nil
+	}
+	nc.Status.AppliedGenerations = pruneAppliedGenerations(nc.Status.AppliedGenerations, pciAddresses)
+
 	// SriovFecNodeConfig.generation is under K8S management
 	// metav1.Condition.observedGeneration is under this reconciler management.
-	// observedGeneration would be incremented then and only then when spec which comes with updated generation would be processed without any error.
+	// observedGeneration would be incremented then and only then when spec which comes with updated generation would be processed without any error,
+	// i.e. when every PF was configured with it. While configuration is in progress, it is the generation being configured.
+	nc.Status.SucceededGeneration = succeededGeneration(previousCondition, nc.Status.SucceededGeneration)
+	if reason == ConfigurationSucceeded && appliedGenerationsCurrent(nc.Status.AppliedGenerations, pciAddresses, nc.GetGeneration()) {
+		nc.Status.SucceededGeneration = nc.GetGeneration()
+	}
 	determineGeneration := func() int64 {
-		if reason == ConfigurationSucceeded {
+		if reason == ConfigurationInProgress {
 			return nc.GetGeneration()
-		} else {
-			return previousCondition.ObservedGeneration
 		}
+		return nc.Status.SucceededGeneration
 	}
 
-	condition := metav1.Condition{
-		Type:               ConditionConfigured,
-		Status:             status,
-		Reason:             string(reason),
-		Message:            msg,
-		ObservedGeneration: determineGeneration(),
-	}
+	condition, fullMessage := conditions.Bounded(conditions.Configured(status, reason, msg, determineGeneration()))
 
-	meta.SetStatusCondition(&nc.Status.Conditions, condition)
-	if inv, err := getSriovInventory(r.log); err != nil {
+	conditionChanged := conditions.SetIfChanged(&nc.Status.Conditions, condition)
+	// VF addresses are published by the configurator during configuration
+	nc.Status.PredictedVFs = original.Status.PredictedVFs
+	if inv, err := getSriovInventory(ctx, r.log); err != nil {
 		r.log.WithError(err).
 			WithField("reason", condition.Reason).
 			WithField("message", condition.Message).
 			Error("failed to obtain sriov inventory for the node")
 	} else {
+		inv = fecStatusInventory(nc, inv)
 		nc.Status.Inventory = *inv
-	}
-
-	if err := r.Status().Update(context.Background(), nc); err != nil {
+		nc.Status.UnsupportedDevices = inv.UnsupportedDevices
+		fecInventoryCollected(&nc.Status)
+		fecVerifyPredictedVFs(nc, r.log)
+		fecSetLogicalNames(nc)
+	}
+
+	nc.Status.AppliedBBDevConfigHashes = appliedBBDevConfigHashes(pciAddresses)
+	nc.Status.AppliedInterruptModes = appliedInterruptModes(pciAddresses)
+	nc.Status.PfBBConfigDurations = pfBBConfigStartups.statusDurations(nc.Status.PfBBConfigDurations, pciAddresses)
+	nc.Status.PfBBConfigCpuSets = pfBBConfigCpuSets.statusCpuSets(nc.Status.PfBBConfigCpuSets, pciAddresses)
+	deviceIDs := map[string]string{}
+	for _, acc := range nc.Status.Inventory.SriovAccelerators {
+		deviceIDs[acc.PCIAddress] = acc.DeviceID
+	}
+	r.reportSlowPfBBConfigStartups(nc, &nc.Status.Conditions, nc.GetGeneration(), nc.Status.PfBBConfigDurations, deviceIDs, supportedAccelerators)
+	if condition.Reason != previousCondition.Reason {
+		nc.Status.PfTransitions = recordPFTransition(nc.Status.PfTransitions, pciAddresses, fec.PFTransition{
+			Time: metav1.NewTime(daemonClock.Now()), Reason: condition.Reason, Generation: condition.ObservedGeneration,
+		})
+	}
+
+	// only status is patched - spec of nc may be outdated already, e.g. when it was updated during drain
+	updated := original.DeepCopy()
+	updated.Status = nc.Status
+	if _, err := patchStatus(ctx, r.Client, original, updated); err != nil {
 		return err
 	}
+	r.publishFecLogicalNames(ctx, nc)
+	if !conditionChanged {
+		return nil
+	}
+	emitFullMessage(r.recorder, nc, condition, fullMessage)
 
 	r.log.WithField("previous", previousCondition).
 		WithField("current", condition).
@@ -359,41 +926,82 @@ func (r *NodeConfigReconciler) updateStatus(nc *fec.SriovFecNodeConfig, status m
 	return nil
 }
 
-func (r *NodeConfigReconciler) VrbupdateStatus(nc *vrbv1.SriovVrbNodeConfig, status metav1.ConditionStatus, reason ConfigurationConditionReason, msg string) error {
+func (r *NodeConfigReconciler) VrbupdateStatus(ctx context.Context, nc *vrbv1.SriovVrbNodeConfig, status metav1.ConditionStatus, reason ConfigurationConditionReason, msg string) error {
+	// diff against node config as last read or written within the reconcile, so that status fields set before this
+	// call (e.g. eviction summary) are patched too; resourceVersion is not diffed, so patch does not fail when object
+	// was modified in the meantime
+	original := statusBaselineOf(ctx, nc)
+	original.SetResourceVersion(nc.GetResourceVersion())
 	previousCondition := VrbfindOrCreateConfigurationStatusCondition(nc)
 
+	var pciAddresses []string
+	for _, pf := range nc.Spec.PhysicalFunctions {
+		pciAddresses = append(pciAddresses, pf.PCIAddress)
+	}
+	if reason == ConfigurationSucceeded {
+		nc.Status.AppliedGenerations = setAppliedGenerations(nc.Status.AppliedGenerations, pciAddresses, nc.GetGeneration())
+		nc.Status.ConfigurationRetries = nil
+	}
+	nc.Status.AppliedGenerations = pruneAppliedGenerations(nc.Status.AppliedGenerations, pciAddresses)
+
 	// SriovFecNodeConfig.generation is under K8S management
 	// metav1.Condition.observedGeneration is under this reconciler management.
-	// observedGeneration would be incremented then and only then when spec which comes with updated generation would be processed without any error.
+	// observedGeneration would be incremented then and only then when spec which comes with updated generation would be processed without any error,
+	// i.e. when every PF was configured with it. While configuration is in progress, it is the generation being configured.
+	nc.Status.SucceededGeneration = succeededGeneration(previousCondition, nc.Status.SucceededGeneration)
+	if reason == ConfigurationSucceeded && appliedGenerationsCurrent(nc.Status.AppliedGenerations, pciAddresses, nc.GetGeneration()) {
+		nc.Status.SucceededGeneration = nc.GetGeneration()
+	}
 	determineGeneration := func() int64 {
-		if reason == ConfigurationSucceeded {
+		if reason == ConfigurationInProgress {
 			return nc.GetGeneration()
-		} else {
-			return previousCondition.ObservedGeneration
 		}
+		return nc.Status.SucceededGeneration
 	}
 
-	condition := metav1.Condition{
-		Type:               ConditionConfigured,
-		Status:             status,
-		Reason:             string(reason),
-		Message:            msg,
-		ObservedGeneration: determineGeneration(),
-	}
+	condition, fullMessage := conditions.Bounded(conditions.Configured(status, reason, msg, determineGeneration()))
 
-	meta.SetStatusCondition(&nc.Status.Conditions, condition)
-	if inv, err := VrbgetSriovInventory(r.log); err != nil {
+	conditionChanged := conditions.SetIfChanged(&nc.Status.Conditions, condition)
+	// VF addresses are published by the configurator during configuration
+	nc.Status.PredictedVFs = original.Status.PredictedVFs
+	if inv, err := VrbgetSriovInventory(ctx, r.log); err != nil {
 		r.log.WithError(err).
 			WithField("reason", condition.Reason).
 			WithField("message", condition.Message).
 			Error("failed to obtain sriov inventory for the node")
 	} else {
 		nc.Status.Inventory = *inv
-	}
-
-	if err := r.Status().Update(context.Background(), nc); err != nil {
+		nc.Status.UnsupportedDevices = inv.UnsupportedDevices
+		vrbInventoryCollected(&nc.Status)
+		vrbVerifyPredictedVFs(nc, r.log)
+		vrbSetLogicalNames(nc)
+	}
+
+	nc.Status.AppliedBBDevConfigHashes = appliedBBDevConfigHashes(pciAddresses)
+	nc.Status.PfBBConfigDurations = pfBBConfigStartups.statusDurations(nc.Status.PfBBConfigDurations, pciAddresses)
+	nc.Status.PfBBConfigCpuSets = pfBBConfigCpuSets.statusCpuSets(nc.Status.PfBBConfigCpuSets, pciAddresses)
+	deviceIDs := map[string]string{}
+	for _, acc := range nc.Status.Inventory.SriovAccelerators {
+		deviceIDs[acc.PCIAddress] = acc.DeviceID
+	}
+	r.reportSlowPfBBConfigStartups(nc, &nc.Status.Conditions, nc.GetGeneration(), nc.Status.PfBBConfigDurations, deviceIDs, VrbsupportedAccelerators)
+	if condition.Reason != previousCondition.Reason {
+		nc.Status.PfTransitions = recordPFTransition(nc.Status.PfTransitions, pciAddresses, vrbv1.PFTransition{
+			Time: metav1.NewTime(daemonClock.Now()), Reason: condition.Reason, Generation: condition.ObservedGeneration,
+		})
+	}
+
+	// only status is patched - spec of nc may be outdated already, e.g. when it was updated during drain
+	updated := original.DeepCopy()
+	updated.Status = nc.Status
+	if _, err := patchStatus(ctx, r.Client, original, updated); err != nil {
 		return err
 	}
+	r.publishVrbLogicalNames(ctx, nc)
+	if !conditionChanged {
+		return nil
+	}
+	emitFullMessage(r.recorder, nc, condition, fullMessage)
 
 	r.log.WithField("previous", previousCondition).
 		WithField("current", condition).
@@ -402,26 +1010,26 @@ func (r *NodeConfigReconciler) VrbupdateStatus(nc *vrbv1.SriovVrbNodeConfig, sta
 	return nil
 }
 
-func (r *NodeConfigReconciler) readExistingInventory() (*fec.NodeInventory, error) {
-	inv, err := getSriovInventory(r.log)
+func (r *NodeConfigReconciler) readExistingInventory(ctx context.Context) (*fec.NodeInventory, error) {
+	inv, err := getSriovInventory(ctx, r.log)
 	if err != nil {
 		r.log.WithError(err).Error("failed to obtain sriov inventory for the node")
 	}
 	return inv, err
 }
 
-func (r *NodeConfigReconciler) VrbreadExistingInventory() (*vrbv1.NodeInventory, error) {
-	inv, err := VrbgetSriovInventory(r.log)
+func (r *NodeConfigReconciler) VrbreadExistingInventory(ctx context.Context) (*vrbv1.NodeInventory, error) {
+	inv, err := VrbgetSriovInventory(ctx, r.log)
 	if err != nil {
 		r.log.WithError(err).Error("failed to obtain sriov inventory for the node")
 	}
 	return inv, err
 }
 
-func (r *NodeConfigReconciler) readSriovFecNodeConfig(nn types.NamespacedName) (nc *fec.SriovFecNodeConfig, err error) {
+func (r *NodeConfigReconciler) readSriovFecNodeConfig(ctx context.Context, nn types.NamespacedName) (nc *fec.SriovFecNodeConfig, err error) {
 	getSriovFecNodeConfig := func() (*fec.SriovFecNodeConfig, error) {
 		sfnc := new(fec.SriovFecNodeConfig)
-		if err := r.Client.Get(context.TODO(), nn, sfnc); err != nil {
+		if err := r.Client.Get(ctx, nn, sfnc); err != nil {
 			r.log.Info(err)
 			return nil, err
 		}
@@ -435,7 +1043,7 @@ func (r *NodeConfigReconciler) readSriovFecNodeConfig(nn types.NamespacedName) (
 		}
 
 		r.log.Info("SriovFecNodeConfig not found - creating")
-		if err := r.CreateEmptyNodeConfigIfNeeded(r.Client); err != nil {
+		if err := r.CreateEmptyNodeConfigIfNeeded(ctx, r.Client); err != nil {
 			r.log.WithError(err).Error("Couldn't create SriovFecNodeConfig")
 			return nil, err
 		}
@@ -445,13 +1053,15 @@ func (r *NodeConfigReconciler) readSriovFecNodeConfig(nn types.NamespacedName) (
 		}
 	}
 
+	normalizeFecPCIAddresses(&nc.Spec)
+	recordStatusBaseline(ctx, nc)
 	return nc, nil
 }
 
-func (r *NodeConfigReconciler) readVrbNodeConfig(nn types.NamespacedName) (nc *vrbv1.SriovVrbNodeConfig, err error) {
+func (r *NodeConfigReconciler) readVrbNodeConfig(ctx context.Context, nn types.NamespacedName) (nc *vrbv1.SriovVrbNodeConfig, err error) {
 	getVrbNodeConfig := func() (*vrbv1.SriovVrbNodeConfig, error) {
 		vrbnc := new(vrbv1.SriovVrbNodeConfig)
-		if err := r.Client.Get(context.TODO(), nn, vrbnc); err != nil {
+		if err := r.Client.Get(ctx, nn, vrbnc); err != nil {
 			r.log.Info(err)
 			return nil, err
 		}
@@ -464,7 +1074,7 @@ func (r *NodeConfigReconciler) readVrbNodeConfig(nn types.NamespacedName) (nc *v
 			return nil, err
 		}
 		r.log.Info("SriovVrbNodeConfig not found - creating")
-		if err := r.VrbCreateEmptyNodeConfigIfNeeded(r.Client); err != nil {
+		if err := r.VrbCreateEmptyNodeConfigIfNeeded(ctx, r.Client); err != nil {
 			r.log.WithError(err).Error("Couldn't create SriovVrbNodeConfig")
 			return nil, err
 		}
@@ -474,70 +1084,328 @@ func (r *NodeConfigReconciler) readVrbNodeConfig(nn types.NamespacedName) (nc *v
 		}
 	}
 
+	normalizeVrbPCIAddresses(&nc.Spec)
+	recordStatusBaseline(ctx, nc)
 	return nc, nil
 }
 
-func (r *NodeConfigReconciler) configureNode(nodeConfig *fec.SriovFecNodeConfig) error {
-	var configurationError error
+// normalizeFecPCIAddresses brings PCI addresses of the spec to the form used by sysfs and inventory, so that
+// addresses provided without domain or in uppercase refer to the same accelerators. Spec is never written back.
+func normalizeFecPCIAddresses(spec *fec.SriovFecNodeConfigSpec) {
+	for i := range spec.PhysicalFunctions {
+		spec.PhysicalFunctions[i].PCIAddress = utils.CanonicalPCIAddress(spec.PhysicalFunctions[i].PCIAddress)
+	}
+}
 
-	drainFunc := func(ctx context.Context) bool {
-		if err := r.sriovfecconfigurer.ApplySpec(nodeConfig.Spec); err != nil {
-			r.log.WithError(err).Error("failed applying new PF/VF configuration")
+// normalizeVrbPCIAddresses is normalizeFecPCIAddresses counterpart for SriovVrbNodeConfig
+func normalizeVrbPCIAddresses(spec *vrbv1.SriovVrbNodeConfigSpec) {
+	for i := range spec.PhysicalFunctions {
+		spec.PhysicalFunctions[i].PCIAddress = utils.CanonicalPCIAddress(spec.PhysicalFunctions[i].PCIAddress)
+	}
+}
+
+// fecPCIAddresses returns PCI addresses of PFs requested by the spec
+func fecPCIAddresses(spec fec.SriovFecNodeConfigSpec) []string {
+	var pciAddresses []string
+	for _, pf := range spec.PhysicalFunctions {
+		pciAddresses = append(pciAddresses, pf.PCIAddress)
+	}
+	return pciAddresses
+}
+
+// vrbPCIAddresses is fecPCIAddresses counterpart for SriovVrbNodeConfig
+func vrbPCIAddresses(spec vrbv1.SriovVrbNodeConfigSpec) []string {
+	var pciAddresses []string
+	for _, pf := range spec.PhysicalFunctions {
+		pciAddresses = append(pciAddresses, pf.PCIAddress)
+	}
+	return pciAddresses
+}
+
+// normalizePCIAddressesOf normalizes PCI addresses of node config of any kind; other objects are left intact
+func normalizePCIAddressesOf(o client.Object) {
+	switch nc := o.(type) {
+	case *fec.SriovFecNodeConfig:
+		normalizeFecPCIAddresses(&nc.Spec)
+	case *vrbv1.SriovVrbNodeConfig:
+		normalizeVrbPCIAddresses(&nc.Spec)
+	}
+}
+
+// configurationStepReporter exposes the last completed step of configuration in InProgress condition
+func (r *NodeConfigReconciler) configurationStepReporter(updateStatus func(msg string) error) drainhelper.ProgressReporter {
+	return func(step string) {
+		if err := updateStatus("Configuration in progress: " + step); err != nil {
+			r.log.WithError(err).WithField("step", step).Warn("failed to report configuration step")
+		}
+	}
+}
+
+// drainOptions returns options removing pods from the node as its config requests
+func drainOptions(mode string, gracePeriod *int64) drainhelper.DrainOptions {
+	return drainhelper.DrainOptions{EvictionMode: drainhelper.EvictionMode(mode), GracePeriodSeconds: gracePeriod}
+}
+
+// reportDrained reports drain, which preceded configuration, as its first step
+func reportDrained(ctx context.Context, drain bool) {
+	if drain {
+		drainhelper.ReportProgress(ctx, "node drained with "+drainhelper.DrainOptionsFrom(ctx).String())
+	}
+}
+
+func (r *NodeConfigReconciler) configureNode(ctx context.Context, nodeConfig *fec.SriovFecNodeConfig) error {
+	var configurationError error
+	defer r.audit.commit(ctx, auditKindFec, nodeConfig.GetGeneration())
+
+	drainFunc := func(ctx context.Context) (performUncordon bool) {
+		// worker runs in goroutine of the leader elector, where panic would crash the daemon with the node cordoned;
+		// it fails the configuration instead, like an error does
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				configurationError = r.panicError(recovered)
+				performUncordon = true
+			}
+		}()
+		ctx = drainhelper.WithProgressReporter(ctx, r.configurationStepReporter(func(msg string) error {
+			return r.updateStatus(ctx, nodeConfig, metav1.ConditionFalse, ConfigurationInProgress, msg)
+		}))
+		reportDrained(ctx, !nodeConfig.Spec.DrainSkip)
+		// hooks run within the drain; results of the previous configuration are replaced
+		nodeConfig.Status.HookResults, nodeConfig.Status.Warnings = nil, nil
+		if err := r.runConfigurationHook(ctx, preConfigureHookName, nodeConfig.Spec.PreConfigureHook, &nodeConfig.Status.HookResults); err != nil {
 			configurationError = err
 			return true
 		}
+		warnings, applied, outcomes := &applyWarnings{}, &appliedPFs{}, &pfBBConfigOutcomes{}
+		err := r.sriovfecconfigurer.ApplySpec(withPfBBConfigOutcomes(withAppliedPFs(withApplyWarnings(ctx, warnings), applied), outcomes), nodeConfig.Spec)
+		nodeConfig.Status.Warnings = r.reportApplyWarnings(nodeConfig, warnings.list())
+		// PFs configured before a failure keep generation they were configured with
+		nodeConfig.Status.AppliedGenerations = setAppliedGenerations(nodeConfig.Status.AppliedGenerations, applied.list(), nodeConfig.GetGeneration())
+		r.reconfiguredAfterReboot(ctx, applied.list())
+		var requested []string
+		for _, pf := range nodeConfig.Spec.PhysicalFunctions {
+			requested = append(requested, pf.PCIAddress)
+		}
+		nodeConfig.Status.PfBBConfigFailures = countPfBBConfigFailures(nodeConfig.Spec.AutoRebootOnPersistentFailure, nodeConfig.Status.PfBBConfigFailures, outcomes.list(), requested)
+		if err != nil {
+			r.log.WithError(err).Error("failed applying new PF/VF configuration")
+			configurationError = err
+		} else if configurationError = r.restartDevicePlugin(ctx); configurationError == nil {
+			configurationError = r.soak(ctx, nodeConfig.Spec.PostConfigureSoakSeconds)
+		}
 
-		configurationError = r.restartDevicePlugin()
+		// post hook runs even when configuration failed, so that it can resume what pre hook has stopped
+		if err := r.runConfigurationHook(ctx, postConfigureHookName, nodeConfig.Spec.PostConfigureHook, &nodeConfig.Status.HookResults); err != nil && configurationError == nil {
+			configurationError = err
+		}
+
+		// device in error state, which pf-bb-config keeps failing on, may be recovered by reboot; node stays cordoned
+		// and the drain lease stays held until then
+		if err != nil {
+			if err := r.rebootOnPersistentFailure(ctx, nodeConfig, auditKindFec, nodeConfig.Spec.AutoRebootOnPersistentFailure, nodeConfig.Status.PfBBConfigFailures, requested, func(msg string) error {
+				return r.updateStatus(ctx, nodeConfig, metav1.ConditionFalse, ConfigurationRebootRequested, msg)
+			}); err != nil {
+				configurationError = err
+				// node is uncordoned when the reboot command failed
+				var rebootRequested *rebootRequestedError
+				return !errors.As(err, &rebootRequested)
+			}
+		}
 		return true
 	}
 
-	if err := r.drainerAndExecute(drainFunc, !nodeConfig.Spec.DrainSkip); err != nil {
+	cpus, err := r.housekeepingAffinity(nodeConfig.Spec.HousekeepingCpuSet)
+	if err != nil {
 		return err
 	}
+	ctx = withCPUAffinity(ctx, cpus)
+	ctx = drainhelper.WithDrainOptions(ctx, drainOptions(string(nodeConfig.Spec.DrainEvictionMode), nodeConfig.Spec.DrainGracePeriodSeconds))
+	if err := r.auditedDrainAndExecute(ctx, drainFunc, !nodeConfig.Spec.DrainSkip); err != nil {
+		return missingPermissionError(err)
+	}
+
+	r.reportReschedulingAsync(ctx, nodeConfig)
 
-	return configurationError
+	return missingPermissionError(configurationError)
 }
 
-func (r *NodeConfigReconciler) VrbconfigureNode(nodeConfig *vrbv1.SriovVrbNodeConfig) error {
+func (r *NodeConfigReconciler) VrbconfigureNode(ctx context.Context, nodeConfig *vrbv1.SriovVrbNodeConfig) error {
 	var configurationError error
-
-	drainFunc := func(ctx context.Context) bool {
-		if err := r.vrbconfigurer.VrbApplySpec(nodeConfig.Spec); err != nil {
-			r.log.WithError(err).Error("failed applying new PF/VF configuration")
+	defer r.audit.commit(ctx, auditKindVrb, nodeConfig.GetGeneration())
+
+	drainFunc := func(ctx context.Context) (performUncordon bool) {
+		// worker runs in goroutine of the leader elector, where panic would crash the daemon with the node cordoned;
+		// it fails the configuration instead, like an error does
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				configurationError = r.panicError(recovered)
+				performUncordon = true
+			}
+		}()
+		ctx = drainhelper.WithProgressReporter(ctx, r.configurationStepReporter(func(msg string) error {
+			return r.VrbupdateStatus(ctx, nodeConfig, metav1.ConditionFalse, ConfigurationInProgress, msg)
+		}))
+		reportDrained(ctx, !nodeConfig.Spec.DrainSkip)
+		// hooks run within the drain; results of the previous configuration are replaced
+		var hookResults []fec.HookResult
+		defer func() { nodeConfig.Status.HookResults = toVrbHookResults(hookResults) }()
+		nodeConfig.Status.Warnings = nil
+		if err := r.runConfigurationHook(ctx, preConfigureHookName, fecConfigurationHook(nodeConfig.Spec.PreConfigureHook), &hookResults); err != nil {
 			configurationError = err
 			return true
 		}
+		warnings, applied, outcomes := &applyWarnings{}, &appliedPFs{}, &pfBBConfigOutcomes{}
+		err := r.vrbconfigurer.VrbApplySpec(withPfBBConfigOutcomes(withAppliedPFs(withApplyWarnings(ctx, warnings), applied), outcomes), nodeConfig.Spec)
+		nodeConfig.Status.Warnings = r.reportApplyWarnings(nodeConfig, warnings.list())
+		// PFs configured before a failure keep generation they were configured with
+		nodeConfig.Status.AppliedGenerations = setAppliedGenerations(nodeConfig.Status.AppliedGenerations, applied.list(), nodeConfig.GetGeneration())
+		r.reconfiguredAfterReboot(ctx, applied.list())
+		var requested []string
+		for _, pf := range nodeConfig.Spec.PhysicalFunctions {
+			requested = append(requested, pf.PCIAddress)
+		}
+		nodeConfig.Status.PfBBConfigFailures = countPfBBConfigFailures((*fec.AutoRebootPolicy)(nodeConfig.Spec.AutoRebootOnPersistentFailure), nodeConfig.Status.PfBBConfigFailures, outcomes.list(), requested)
+		if err != nil {
+			r.log.WithError(err).Error("failed applying new PF/VF configuration")
+			configurationError = err
+		} else if configurationError = r.restartDevicePlugin(ctx); configurationError == nil {
+			configurationError = r.soak(ctx, nodeConfig.Spec.PostConfigureSoakSeconds)
+		}
+
+		// post hook runs even when configuration failed, so that it can resume what pre hook has stopped
+		if err := r.runConfigurationHook(ctx, postConfigureHookName, fecConfigurationHook(nodeConfig.Spec.PostConfigureHook), &hookResults); err != nil && configurationError == nil {
+			configurationError = err
+		}
 
-		configurationError = r.restartDevicePlugin()
+		// device in error state, which pf-bb-config keeps failing on, may be recovered by reboot; node stays cordoned
+		// and the drain lease stays held until then
+		if err != nil {
+			if err := r.rebootOnPersistentFailure(ctx, nodeConfig, auditKindVrb, (*fec.AutoRebootPolicy)(nodeConfig.Spec.AutoRebootOnPersistentFailure), nodeConfig.Status.PfBBConfigFailures, requested, func(msg string) error {
+				return r.VrbupdateStatus(ctx, nodeConfig, metav1.ConditionFalse, ConfigurationRebootRequested, msg)
+			}); err != nil {
+				configurationError = err
+				// node is uncordoned when the reboot command failed
+				var rebootRequested *rebootRequestedError
+				return !errors.As(err, &rebootRequested)
+			}
+		}
 		return true
 	}
 
-	if err := r.drainerAndExecute(drainFunc, !nodeConfig.Spec.DrainSkip); err != nil {
+	cpus, err := r.housekeepingAffinity(nodeConfig.Spec.HousekeepingCpuSet)
+	if err != nil {
 		return err
 	}
+	ctx = withCPUAffinity(ctx, cpus)
+	ctx = drainhelper.WithDrainOptions(ctx, drainOptions(string(nodeConfig.Spec.DrainEvictionMode), nodeConfig.Spec.DrainGracePeriodSeconds))
+	if err := r.auditedDrainAndExecute(ctx, drainFunc, !nodeConfig.Spec.DrainSkip); err != nil {
+		return missingPermissionError(err)
+	}
 
-	return configurationError
+	r.reportReschedulingAsync(ctx, nodeConfig)
+
+	return missingPermissionError(configurationError)
 }
 
-func (r *NodeConfigReconciler) isCardUpdateRequired(nc *fec.SriovFecNodeConfig, detectedInventory *fec.NodeInventory) bool {
+// reportReschedulingAsync reports rescheduling of pods evicted during drain in background, as waiting for it may take
+// up to the reschedule timeout; summary is patched into status.evictionSummary of the node config once known
+func (r *NodeConfigReconciler) reportReschedulingAsync(ctx context.Context, nodeConfig client.Object) {
+	if r.verifyRescheduling == nil {
+		return
+	}
+
+	// node config is used further by the reconcile, which may have already finished when pods are rescheduled
+	ctx, nodeConfig = withoutStatusBaselines(context.WithoutCancel(ctx)), nodeConfig.DeepCopyObject().(client.Object)
+	r.reschedulingReports.Add(1)
+	go func() {
+		defer r.reschedulingReports.Done()
+		summary := r.reportRescheduling(ctx, nodeConfig)
+		if summary == "" {
+			return
+		}
+		if err := r.patchEvictionSummary(ctx, nodeConfig, summary); err != nil {
+			r.log.WithError(err).WithField("summary", summary).Error("failed to report eviction summary")
+		}
+	}()
+}
+
+// patchEvictionSummary sets status.evictionSummary of the node config as stored
+func (r *NodeConfigReconciler) patchEvictionSummary(ctx context.Context, nodeConfig client.Object, summary string) error {
+	latest := nodeConfig.DeepCopyObject().(client.Object)
+	if err := r.Get(ctx, client.ObjectKeyFromObject(nodeConfig), latest); err != nil {
+		return err
+	}
+	original := latest.DeepCopyObject().(client.Object)
+	switch nc := latest.(type) {
+	case *fec.SriovFecNodeConfig:
+		nc.Status.EvictionSummary = summary
+	case *vrbv1.SriovVrbNodeConfig:
+		nc.Status.EvictionSummary = summary
+	}
+	_, err := patchStatus(ctx, r.Client, original, latest)
+	return err
+}
+
+// reportRescheduling waits until pods evicted during drain are running again and reports result as an event on given
+// node config. Pods which were not rescheduled in time are reported as a warning only - configuration is not failed.
+func (r *NodeConfigReconciler) reportRescheduling(ctx context.Context, nodeConfig runtime.Object) string {
+	if r.verifyRescheduling == nil {
+		return ""
+	}
+
+	summary := r.verifyRescheduling(ctx)
+	if summary == nil {
+		return ""
+	}
+
+	// events tell how pods were removed, as pods deleted bypassing PodDisruptionBudgets may explain disruption
+	options := drainhelper.DrainOptionsFrom(ctx)
+	r.log.WithField("summary", summary.String()).WithField("drain", options.String()).Info("evicted pods rescheduling verified")
+	if r.recorder != nil {
+		if len(summary.Pending) > 0 {
+			r.recorder.Eventf(nodeConfig, corev1.EventTypeWarning, "EvictedPodsNotRescheduled", "%s (%s): %s",
+				summary.String(), options.String(), strings.Join(summary.Pending, ", "))
+		} else {
+			r.recorder.Eventf(nodeConfig, corev1.EventTypeNormal, "EvictedPodsRescheduled", "%s (%s)", summary.String(), options.String())
+		}
+	}
+	return summary.String()
+}
+
+// requeueIfUpdatedMeanwhile returns result indicating necessity of re-queuing Reconcile(...) immediately when the node
+// config was updated after processed nc had been read (e.g. by the user during drain), so that newer spec is not
+// postponed until the next resync; otherwise it behaves as requeueLaterOrNowIfError
+func (r *NodeConfigReconciler) requeueIfUpdatedMeanwhile(ctx context.Context, nc client.Object, err error) (reconcile.Result, error) {
+	if err != nil {
+		return requeueLaterOrNowIfError(err)
+	}
+
+	live := nc.DeepCopyObject().(client.Object)
+	if err := r.Get(ctx, client.ObjectKeyFromObject(nc), live); err != nil {
+		return requeueNowWithError(err)
+	}
+	if live.GetGeneration() != nc.GetGeneration() {
+		r.log.WithField("processed", nc.GetGeneration()).
+			WithField("current", live.GetGeneration()).
+			Info("node config updated during configuration - processing newer spec")
+		return reconcile.Result{Requeue: true}, nil
+	}
+	return requeueLater()
+}
+
+func (r *NodeConfigReconciler) isCardUpdateRequired(ctx context.Context, nc *fec.SriovFecNodeConfig, detectedInventory *fec.NodeInventory, specHash string) bool {
 	pciToVfsAmount := map[string]int{}
 	for _, physicalFunction := range nc.Spec.PhysicalFunctions {
 		pciToVfsAmount[physicalFunction.PCIAddress] = physicalFunction.VFAmount
 	}
 
-	isGenerationChanged := func() bool {
-		observedGeneration := findOrCreateConfigurationStatusCondition(nc).ObservedGeneration
-		if nc.GetGeneration() != observedGeneration {
-			r.log.WithField("observed", observedGeneration).
-				WithField("requested", nc.GetGeneration()).
-				Info("Observed generation doesn't reflect requested one")
-			return true
-		}
-		return false
+	isSpecChanged := func() bool {
+		return r.isAppliedSpecOutdated(specHash, nc.Status.AppliedSpecHash,
+			nc.GetGeneration(), succeededGeneration(findOrCreateConfigurationStatusCondition(nc), nc.Status.SucceededGeneration))
 	}
 
 	if len(nc.Spec.PhysicalFunctions) == 0 {
-		if isGenerationChanged() {
+		if isSpecChanged() {
 			return true
 		}
 		r.log.Info("Empty SriovFec PF")
@@ -560,7 +1428,7 @@ func (r *NodeConfigReconciler) isCardUpdateRequired(nc *fec.SriovFecNodeConfig,
 	bbDevConfigDaemonIsDead := func() bool {
 		for _, acc := range nc.Spec.PhysicalFunctions {
 			if strings.EqualFold(acc.PFDriver, utils.VFIO_PCI) {
-				if pfBbConfigProcIsDead(r.log, acc.PCIAddress) {
+				if pfBbConfigProcIsDead(ctx, r.log, acc.PCIAddress) {
 					r.log.WithField("pciAddress", acc.PCIAddress).
 						Info("pf-bb-config process for card is not running")
 					return true
@@ -570,27 +1438,21 @@ func (r *NodeConfigReconciler) isCardUpdateRequired(nc *fec.SriovFecNodeConfig,
 		return false
 	}
 
-	return isGenerationChanged() || exposedInventoryOutdated() || bbDevConfigDaemonIsDead()
+	return isSpecChanged() || exposedInventoryOutdated() || bbDevConfigDaemonIsDead()
 }
 
-func (r *NodeConfigReconciler) VrbisCardUpdateRequired(nc *vrbv1.SriovVrbNodeConfig, detectedInventory *vrbv1.NodeInventory) bool {
+func (r *NodeConfigReconciler) VrbisCardUpdateRequired(ctx context.Context, nc *vrbv1.SriovVrbNodeConfig, detectedInventory *vrbv1.NodeInventory, specHash string) bool {
 	pciToVfsAmount := map[string]int{}
 	for _, physicalFunction := range nc.Spec.PhysicalFunctions {
 		pciToVfsAmount[physicalFunction.PCIAddress] = physicalFunction.VFAmount
 	}
-	isGenerationChanged := func() bool {
-		observedGeneration := VrbfindOrCreateConfigurationStatusCondition(nc).ObservedGeneration
-		if nc.GetGeneration() != observedGeneration {
-			r.log.WithField("observed", observedGeneration).
-				WithField("requested", nc.GetGeneration()).
-				Info("Observed generation doesn't reflect requested one")
-			return true
-		}
-		return false
+	isSpecChanged := func() bool {
+		return r.isAppliedSpecOutdated(specHash, nc.Status.AppliedSpecHash,
+			nc.GetGeneration(), succeededGeneration(VrbfindOrCreateConfigurationStatusCondition(nc), nc.Status.SucceededGeneration))
 	}
 
 	if len(nc.Spec.PhysicalFunctions) == 0 {
-		if isGenerationChanged() {
+		if isSpecChanged() {
 			return true
 		}
 		r.log.Info("Empty VRB PF")
@@ -613,7 +1475,7 @@ func (r *NodeConfigReconciler) VrbisCardUpdateRequired(nc *vrbv1.SriovVrbNodeCon
 	bbDevConfigDaemonIsDead := func() bool {
 		for _, acc := range nc.Spec.PhysicalFunctions {
 			if strings.EqualFold(acc.PFDriver, utils.VFIO_PCI) {
-				if pfBbConfigProcIsDead(r.log, acc.PCIAddress) {
+				if pfBbConfigProcIsDead(ctx, r.log, acc.PCIAddress) {
 					r.log.WithField("pciAddress", acc.PCIAddress).
 						Info("pf-bb-config process for card is not running")
 					return true
@@ -623,11 +1485,11 @@ func (r *NodeConfigReconciler) VrbisCardUpdateRequired(nc *vrbv1.SriovVrbNodeCon
 		return false
 	}
 
-	return isGenerationChanged() || exposedInventoryOutdated() || bbDevConfigDaemonIsDead()
+	return isSpecChanged() || exposedInventoryOutdated() || bbDevConfigDaemonIsDead()
 }
 
-func pfBbConfigProcIsDead(log *logrus.Logger, pciAddr string) bool {
-	stdout, err := execCmd([]string{
+func pfBbConfigProcIsDead(ctx context.Context, log *logrus.Logger, pciAddr string) bool {
+	stdout, err := execCmd(ctx, []string{
 		"pgrep",
 		"--count",
 		"--full",
@@ -657,12 +1519,7 @@ func isReady(p corev1.Pod) bool {
 func findOrCreateConfigurationStatusCondition(nc *fec.SriovFecNodeConfig) metav1.Condition {
 	configurationStatusCondition := nc.FindCondition(ConditionConfigured)
 	if configurationStatusCondition == nil {
-		return metav1.Condition{
-			Type:               ConditionConfigured,
-			Status:             metav1.ConditionTrue,
-			Reason:             string(ConfigurationNotRequested),
-			ObservedGeneration: 0,
-		}
+		return conditions.Configured(metav1.ConditionTrue, ConfigurationNotRequested, "", 0)
 	}
 
 	return *configurationStatusCondition
@@ -671,12 +1528,7 @@ func findOrCreateConfigurationStatusCondition(nc *fec.SriovFecNodeConfig) metav1
 func VrbfindOrCreateConfigurationStatusCondition(nc *vrbv1.SriovVrbNodeConfig) metav1.Condition {
 	configurationStatusCondition := nc.FindCondition(ConditionConfigured)
 	if configurationStatusCondition == nil {
-		return metav1.Condition{
-			Type:               ConditionConfigured,
-			Status:             metav1.ConditionTrue,
-			Reason:             string(ConfigurationNotRequested),
-			ObservedGeneration: 0,
-		}
+		return conditions.Configured(metav1.ConditionTrue, ConfigurationNotRequested, "", 0)
 	}
 
 	return *configurationStatusCondition
@@ -723,6 +1575,12 @@ func CreateManager(config *rest.Config, scheme *runtime.Scheme, namespace string
 		return nil, err
 	}
 
+	// device plugin pods of the node are looked up in the cache by the index instead of listing all pods
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &corev1.Pod{}, podNodeNameField, podNodeName); err != nil {
+		log.WithError(err).Error("unable to index pods by node name")
+		return nil, err
+	}
+
 	if err := mgr.AddHealthzCheck("health", healthz.Ping); err != nil {
 		log.WithError(err).Error("unable to set up health check")
 		return nil, err
@@ -741,7 +1599,7 @@ func validateNodeConfig(nodeConfig fec.SriovFecNodeConfigSpec) error {
 	}
 	cmdline := string(cmdlineBytes)
 	//common attributes for SRIOV
-	if err := validateOrdinalKernelParams(cmdline); err != nil {
+	if err := validateOrdinalKernelParams(cmdline, requiredKernelParams(supportedAccelerators, hostArch)); err != nil {
 		return err
 	}
 
@@ -784,7 +1642,7 @@ func validateVrbNodeConfig(nodeConfig vrbv1.SriovVrbNodeConfigSpec) error {
 	}
 	cmdline := string(cmdlineBytes)
 	//common attributes for SRIOV
-	if err := validateOrdinalKernelParams(cmdline); err != nil {
+	if err := validateOrdinalKernelParams(cmdline, requiredKernelParams(VrbsupportedAccelerators, hostArch)); err != nil {
 		return err
 	}
 
@@ -828,10 +1686,11 @@ func moduleParameterIsEnabled(moduleName, parameter string) error {
 	return nil
 }
 
-func validateOrdinalKernelParams(cmdline string) error {
+func validateOrdinalKernelParams(cmdline string, kernelParams []string) error {
+	params := parseKernelCmdline(cmdline)
 	for _, param := range kernelParams {
-		if !strings.Contains(cmdline, param) {
-			return fmt.Errorf("missing kernel param(%s)", param)
+		if err := params.require(param); err != nil {
+			return err
 		}
 	}
 	return nil
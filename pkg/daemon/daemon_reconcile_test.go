@@ -6,15 +6,21 @@ package daemon
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"time"
 
 	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
 	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/drainhelper"
 	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
@@ -73,7 +79,7 @@ var _ = Describe("NodeConfigReconciler.Reconcile", func() {
 				},
 			}
 
-			getSriovInventory = func(log *logrus.Logger) (*sriovv2.NodeInventory, error) {
+			getSriovInventory = func(_ context.Context, log *logrus.Logger) (*sriovv2.NodeInventory, error) {
 				return nodeInventory, nil
 			}
 
@@ -83,10 +89,10 @@ var _ = Describe("NodeConfigReconciler.Reconcile", func() {
 				nodeNameRef:        nodeNameRef,
 				sriovfecconfigurer: configurer,
 				vrbconfigurer:      nil,
-				drainerAndExecute: func(configurer func(ctx context.Context) bool, drain bool) error {
+				drainerAndExecute: func(_ context.Context, configurer func(ctx context.Context) bool, drain bool) error {
 					_ = configurer(context.TODO())
 					return nil
-				}, restartDevicePlugin: func() error {
+				}, restartDevicePlugin: func(context.Context) error {
 					return nil
 				}}
 			reconcileRequestes = ctrl.Request{NamespacedName: nodeNameRef}
@@ -144,10 +150,491 @@ var _ = Describe("NodeConfigReconciler.Reconcile", func() {
 	})
 })
 
+var _ = Describe("NodeConfigReconciler.Reconcile during cluster-wide emergency stop", func() {
+	var (
+		fakeClient      client.Client
+		nodeNameRef     = types.NamespacedName{Name: "worker", Namespace: "testNamespace"}
+		reconciler      NodeConfigReconciler
+		configureCalled bool
+	)
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(sriovv2.AddToScheme(scheme)).ToNot(HaveOccurred())
+		Expect(vrbv1.AddToScheme(scheme)).ToNot(HaveOccurred())
+
+		procCmdlineFilePath = "testdata/cmdline_test"
+		sysLockdownFilePath = filepath.Join(testTmpFolder, "lockdown")
+		Expect(os.WriteFile(sysLockdownFilePath, []byte("[none] integrity confidentiality"), 0644)).To(Succeed())
+		configureCalled = false
+
+		getSriovInventory = func(_ context.Context, log *logrus.Logger) (*sriovv2.NodeInventory, error) {
+			return &sriovv2.NodeInventory{
+				SriovAccelerators: []sriovv2.SriovAccelerator{{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, MaxVFs: 10}},
+			}, nil
+		}
+		VrbgetSriovInventory = func(_ context.Context, log *logrus.Logger) (*vrbv1.NodeInventory, error) {
+			return &vrbv1.NodeInventory{}, nil
+		}
+
+		sfnc := &sriovv2.SriovFecNodeConfig{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        nodeNameRef.Name,
+				Namespace:   nodeNameRef.Namespace,
+				Generation:  1,
+				Annotations: map[string]string{sriovv2.ConfigurationHaltedAnnotation: "true"},
+			},
+			Spec: sriovv2.SriovFecNodeConfigSpec{
+				PhysicalFunctions: []sriovv2.PhysicalFunctionConfigExt{{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, VFDriver: utils.IGB_UIO, VFAmount: 1}},
+			},
+		}
+		vrbnc := &vrbv1.SriovVrbNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace}}
+		fakeClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(sfnc, vrbnc).Build()
+
+		reconciler = NodeConfigReconciler{
+			Client:      fakeClient,
+			log:         utils.NewLogger(),
+			nodeNameRef: nodeNameRef,
+			sriovfecconfigurer: testConfigurerProto{configureNodeFunction: func(sriovv2.SriovFecNodeConfigSpec) error {
+				configureCalled = true
+				return nil
+			}},
+			drainerAndExecute: func(_ context.Context, configurer func(ctx context.Context) bool, drain bool) error {
+				_ = configurer(context.TODO())
+				return nil
+			},
+			restartDevicePlugin: func(context.Context) error { return nil },
+		}
+	})
+
+	AfterEach(func() {
+		getSriovInventory = GetSriovInventory
+		VrbgetSriovInventory = VrbGetSriovInventory
+		sysLockdownFilePath = "/sys/kernel/security/lockdown"
+	})
+
+	It("postpones configuration until emergency stop is lifted", func() {
+		_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: nodeNameRef})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(configureCalled).To(BeFalse())
+
+		sfnc := new(sriovv2.SriovFecNodeConfig)
+		Expect(fakeClient.Get(context.TODO(), nodeNameRef, sfnc)).To(Succeed())
+		Expect(sfnc.FindCondition(ConditionConfigured).Reason).To(Equal(string(ConfigurationHalted)))
+		Expect(sfnc.Status.Inventory.SriovAccelerators).To(HaveLen(1))
+
+		delete(sfnc.Annotations, sriovv2.ConfigurationHaltedAnnotation)
+		Expect(fakeClient.Update(context.TODO(), sfnc)).To(Succeed())
+
+		_, err = reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: nodeNameRef})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(configureCalled).To(BeTrue())
+	})
+
+	It("stamps inventory with collection time and sequence number on every scan", func() {
+		sfnc := new(sriovv2.SriovFecNodeConfig)
+		for sequence := int64(1); sequence <= 2; sequence++ {
+			_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: nodeNameRef})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeClient.Get(context.TODO(), nodeNameRef, sfnc)).To(Succeed())
+			Expect(sfnc.Status.InventorySequence).To(Equal(sequence))
+			Expect(sfnc.Status.InventoryCollectedAt).ToNot(BeNil())
+			Expect(sfnc.Status.InventoryCollectedAt.Time).To(BeTemporally("~", time.Now(), time.Minute))
+		}
+	})
+})
+
+var _ = Describe("NodeConfigReconciler.configureNode rescheduling report", func() {
+	var (
+		recorder   *record.FakeRecorder
+		reconciler NodeConfigReconciler
+		summary    *drainhelper.ReschedulingSummary
+		nodeConfig *sriovv2.SriovFecNodeConfig
+	)
+
+	BeforeEach(func() {
+		recorder = record.NewFakeRecorder(10)
+		summary = nil
+		nodeConfig = &sriovv2.SriovFecNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "default"}}
+		scheme := runtime.NewScheme()
+		Expect(sriovv2.AddToScheme(scheme)).ToNot(HaveOccurred())
+		reconciler = NodeConfigReconciler{
+			// drain progress and eviction summary are reported to status of the node config
+			Client:             fake.NewClientBuilder().WithScheme(scheme).WithObjects(nodeConfig.DeepCopy()).Build(),
+			log:                utils.NewLogger(),
+			sriovfecconfigurer: testConfigurerProto{configureNodeFunction: func(sriovv2.SriovFecNodeConfigSpec) error { return nil }},
+			drainerAndExecute: func(_ context.Context, configurer func(ctx context.Context) bool, drain bool) error {
+				_ = configurer(context.TODO())
+				return nil
+			},
+			restartDevicePlugin: func(context.Context) error { return nil },
+			verifyRescheduling:  func(context.Context) *drainhelper.ReschedulingSummary { return summary },
+			recorder:            recorder,
+		}
+	})
+
+	storedEvictionSummary := func() string {
+		reconciler.reschedulingReports.Wait()
+		stored := new(sriovv2.SriovFecNodeConfig)
+		Expect(reconciler.Get(context.TODO(), client.ObjectKeyFromObject(nodeConfig), stored)).To(Succeed())
+		return stored.Status.EvictionSummary
+	}
+
+	It("does not report anything when no pods were evicted", func() {
+		Expect(reconciler.configureNode(context.TODO(), nodeConfig)).To(Succeed())
+		Expect(storedEvictionSummary()).To(BeEmpty())
+		Expect(recorder.Events).To(BeEmpty())
+	})
+
+	It("reports rescheduled pods as normal event and in status", func() {
+		summary = &drainhelper.ReschedulingSummary{Evicted: 2, Rescheduled: 2}
+		Expect(reconciler.configureNode(context.TODO(), nodeConfig)).To(Succeed())
+		Expect(storedEvictionSummary()).To(Equal("2 pods evicted, 2 rescheduled, 0 pending"))
+		Expect(recorder.Events).To(Receive(HavePrefix("Normal EvictedPodsRescheduled")))
+	})
+
+	It("reports pending pods as warning without failing configuration", func() {
+		summary = &drainhelper.ReschedulingSummary{Evicted: 2, Rescheduled: 1, Pending: []string{"ns/pod"}}
+		Expect(reconciler.configureNode(context.TODO(), nodeConfig)).To(Succeed())
+		Expect(storedEvictionSummary()).To(Equal("2 pods evicted, 1 rescheduled, 1 pending"))
+		Expect(recorder.Events).To(Receive(And(HavePrefix("Warning EvictedPodsNotRescheduled"), ContainSubstring("ns/pod"))))
+	})
+
+	It("does not wait for rescheduling", func() {
+		rescheduled := make(chan struct{})
+		reconciler.verifyRescheduling = func(context.Context) *drainhelper.ReschedulingSummary {
+			<-rescheduled
+			return &drainhelper.ReschedulingSummary{Evicted: 1, Rescheduled: 1}
+		}
+
+		Expect(reconciler.configureNode(context.TODO(), nodeConfig)).To(Succeed())
+		close(rescheduled)
+		Expect(storedEvictionSummary()).To(Equal("1 pods evicted, 1 rescheduled, 0 pending"))
+	})
+
+	It("keeps eviction summary on status update", func() {
+		summary = &drainhelper.ReschedulingSummary{Evicted: 1, Rescheduled: 1}
+		ctx := withStatusBaselines(context.TODO())
+		recordStatusBaseline(ctx, nodeConfig)
+
+		Expect(reconciler.configureNode(ctx, nodeConfig)).To(Succeed())
+		Expect(storedEvictionSummary()).To(Equal("1 pods evicted, 1 rescheduled, 0 pending"))
+		Expect(reconciler.updateStatus(ctx, nodeConfig, metav1.ConditionTrue, ConfigurationSucceeded, "Configured successfully")).To(Succeed())
+		Expect(storedEvictionSummary()).To(Equal("1 pods evicted, 1 rescheduled, 0 pending"))
+	})
+})
+
+var _ = Describe("NodeConfigReconciler.Reconcile with VFs exposed incrementally", func() {
+	const requestedVFs = 16
+
+	var (
+		fakeClient         *statusPatchCountingClient
+		nodeNameRef        = types.NamespacedName{Name: "worker", Namespace: "testNamespace"}
+		reconciler         *NodeConfigReconciler
+		configured         bool
+		exposedVFs         int
+		patchesAfterConfig int
+
+		originalSettleInterval = inventorySettleInterval
+	)
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(sriovv2.AddToScheme(scheme)).ToNot(HaveOccurred())
+		Expect(vrbv1.AddToScheme(scheme)).ToNot(HaveOccurred())
+
+		procCmdlineFilePath = "testdata/cmdline_test"
+		sysLockdownFilePath = filepath.Join(testTmpFolder, "lockdown")
+		Expect(os.WriteFile(sysLockdownFilePath, []byte("[none] integrity confidentiality"), 0644)).To(Succeed())
+		inventorySettleInterval = 10 * time.Millisecond
+		configured, exposedVFs, patchesAfterConfig = false, 0, 0
+
+		// every inventory read after configuration exposes one more VF, like kernel does during numvfs ramp-up
+		getSriovInventory = func(_ context.Context, log *logrus.Logger) (*sriovv2.NodeInventory, error) {
+			if configured && exposedVFs < requestedVFs {
+				exposedVFs++
+			}
+			acc := sriovv2.SriovAccelerator{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, MaxVFs: requestedVFs, VFs: []sriovv2.VF{}}
+			for i := 0; i < exposedVFs; i++ {
+				acc.VFs = append(acc.VFs, sriovv2.VF{PCIAddress: fmt.Sprintf("0000:15:01.%d", i), Driver: utils.IGB_UIO})
+			}
+			return &sriovv2.NodeInventory{SriovAccelerators: []sriovv2.SriovAccelerator{acc}}, nil
+		}
+		VrbgetSriovInventory = func(_ context.Context, log *logrus.Logger) (*vrbv1.NodeInventory, error) {
+			return &vrbv1.NodeInventory{}, nil
+		}
+
+		sfnc := &sriovv2.SriovFecNodeConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace, Generation: 1},
+			Spec: sriovv2.SriovFecNodeConfigSpec{
+				PhysicalFunctions: []sriovv2.PhysicalFunctionConfigExt{{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, VFDriver: utils.IGB_UIO, VFAmount: requestedVFs}},
+			},
+		}
+		// VRB inventory was exposed when the node config was created
+		vrbnc := &vrbv1.SriovVrbNodeConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace},
+			Status:     vrbv1.SriovVrbNodeConfigStatus{InventoryCollectedAt: &metav1.Time{Time: time.Now()}, InventorySequence: 1},
+		}
+		fakeClient = &statusPatchCountingClient{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(sfnc, vrbnc).Build()}
+
+		reconciler = &NodeConfigReconciler{
+			Client:      fakeClient,
+			log:         utils.NewLogger(),
+			nodeNameRef: nodeNameRef,
+			sriovfecconfigurer: testConfigurerProto{configureNodeFunction: func(sriovv2.SriovFecNodeConfigSpec) error {
+				configured = true
+				// inventory-driven refresh requested during configuration must not write status
+				nc := new(sriovv2.SriovFecNodeConfig)
+				Expect(fakeClient.Get(context.TODO(), nodeNameRef, nc)).To(Succeed())
+				inv, _ := getSriovInventory(context.TODO(), nil)
+				Expect(reconciler.refreshInventory(context.TODO(), nc, inv)).To(Succeed())
+				patchesAfterConfig = fakeClient.patches
+				return nil
+			}},
+			drainerAndExecute: func(_ context.Context, configurer func(ctx context.Context) bool, drain bool) error {
+				_ = configurer(context.TODO())
+				return nil
+			},
+			restartDevicePlugin: func(context.Context) error { return nil },
+		}
+	})
+
+	AfterEach(func() {
+		getSriovInventory = GetSriovInventory
+		VrbgetSriovInventory = VrbGetSriovInventory
+		sysLockdownFilePath = "/sys/kernel/security/lockdown"
+		inventorySettleInterval = originalSettleInterval
+	})
+
+	It("exposes consolidated inventory with a single post-config status write", func() {
+		_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: nodeNameRef})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(configured).To(BeTrue())
+		Expect(fakeClient.patches - patchesAfterConfig).To(Equal(1))
+
+		sfnc := new(sriovv2.SriovFecNodeConfig)
+		Expect(fakeClient.Get(context.TODO(), nodeNameRef, sfnc)).To(Succeed())
+		Expect(sfnc.FindCondition(ConditionConfigured).Reason).To(Equal(string(ConfigurationSucceeded)))
+		Expect(sfnc.Status.Inventory.SriovAccelerators[0].VFs).To(HaveLen(requestedVFs))
+
+		// next reconcile finds node configured and its inventory already exposed
+		_, err = reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: nodeNameRef})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(fakeClient.patches - patchesAfterConfig).To(Equal(1))
+	})
+})
+
+var _ = Describe("NodeConfigReconciler.Reconcile of spec updated during drain", func() {
+	var (
+		fakeClient  client.Client
+		nodeNameRef = types.NamespacedName{Name: "worker", Namespace: "testNamespace"}
+		reconciler  *NodeConfigReconciler
+		applied     []int
+		onDrain     func()
+
+		originalSettleInterval = inventorySettleInterval
+		originalWorkdir        = workdir
+	)
+
+	BeforeEach(func() {
+		workdir = filepath.Join(testTmpFolder, "drain-update")
+		Expect(os.MkdirAll(workdir, 0755)).To(Succeed())
+		scheme := runtime.NewScheme()
+		Expect(sriovv2.AddToScheme(scheme)).ToNot(HaveOccurred())
+		Expect(vrbv1.AddToScheme(scheme)).ToNot(HaveOccurred())
+
+		procCmdlineFilePath = "testdata/cmdline_test"
+		sysLockdownFilePath = filepath.Join(testTmpFolder, "lockdown")
+		Expect(os.WriteFile(sysLockdownFilePath, []byte("[none] integrity confidentiality"), 0644)).To(Succeed())
+		inventorySettleInterval = 10 * time.Millisecond
+		applied, onDrain = nil, func() {}
+
+		getSriovInventory = func(_ context.Context, log *logrus.Logger) (*sriovv2.NodeInventory, error) {
+			acc := sriovv2.SriovAccelerator{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, MaxVFs: 16, VFs: []sriovv2.VF{}}
+			if len(applied) > 0 {
+				for i := 0; i < applied[len(applied)-1]; i++ {
+					acc.VFs = append(acc.VFs, sriovv2.VF{PCIAddress: fmt.Sprintf("0000:15:01.%d", i), Driver: utils.IGB_UIO})
+				}
+			}
+			return &sriovv2.NodeInventory{SriovAccelerators: []sriovv2.SriovAccelerator{acc}}, nil
+		}
+		VrbgetSriovInventory = func(_ context.Context, log *logrus.Logger) (*vrbv1.NodeInventory, error) {
+			return &vrbv1.NodeInventory{}, nil
+		}
+
+		sfnc := &sriovv2.SriovFecNodeConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace, Generation: 1},
+			Spec: sriovv2.SriovFecNodeConfigSpec{
+				PhysicalFunctions: []sriovv2.PhysicalFunctionConfigExt{{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, VFDriver: utils.IGB_UIO, VFAmount: 1}},
+			},
+		}
+		vrbnc := &vrbv1.SriovVrbNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace}}
+		fakeClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(sfnc, vrbnc).Build()
+
+		reconciler = &NodeConfigReconciler{
+			Client:      fakeClient,
+			log:         utils.NewLogger(),
+			nodeNameRef: nodeNameRef,
+			sriovfecconfigurer: testConfigurerProto{configureNodeFunction: func(spec sriovv2.SriovFecNodeConfigSpec) error {
+				applied = append(applied, spec.PhysicalFunctions[0].VFAmount)
+				return nil
+			}},
+			drainerAndExecute: func(_ context.Context, configurer func(ctx context.Context) bool, drain bool) error {
+				onDrain()
+				_ = configurer(context.TODO())
+				return nil
+			},
+			restartDevicePlugin: func(context.Context) error { return nil },
+		}
+	})
+
+	AfterEach(func() {
+		getSriovInventory = GetSriovInventory
+		VrbgetSriovInventory = VrbGetSriovInventory
+		sysLockdownFilePath = "/sys/kernel/security/lockdown"
+		inventorySettleInterval = originalSettleInterval
+		workdir = originalWorkdir
+	})
+
+	configuredCondition := func() *metav1.Condition {
+		sfnc := new(sriovv2.SriovFecNodeConfig)
+		Expect(fakeClient.Get(context.TODO(), nodeNameRef, sfnc)).To(Succeed())
+		return sfnc.FindCondition(ConditionConfigured)
+	}
+
+	It("reports processed generation and requeues immediately to apply the newer spec", func() {
+		onDrain = func() {
+			onDrain = func() {}
+			sfnc := new(sriovv2.SriovFecNodeConfig)
+			Expect(fakeClient.Get(context.TODO(), nodeNameRef, sfnc)).To(Succeed())
+			sfnc.Spec.PhysicalFunctions[0].VFAmount = 2
+			sfnc.Generation++
+			Expect(fakeClient.Update(context.TODO(), sfnc)).To(Succeed())
+		}
+
+		result, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: nodeNameRef})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Requeue).To(BeTrue())
+		Expect(applied).To(Equal([]int{1}))
+		Expect(configuredCondition().Reason).To(Equal(string(ConfigurationSucceeded)))
+		Expect(configuredCondition().ObservedGeneration).To(BeEquivalentTo(1))
+
+		result, err = reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: nodeNameRef})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Requeue).To(BeFalse())
+		Expect(result.RequeueAfter).To(Equal(resyncPeriod.Get()))
+		Expect(applied).To(Equal([]int{1, 2}))
+		Expect(configuredCondition().ObservedGeneration).To(BeEquivalentTo(2))
+	})
+
+	It("requeues on schedule when spec was not updated", func() {
+		result, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: nodeNameRef})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Requeue).To(BeFalse())
+		Expect(applied).To(Equal([]int{1}))
+		Expect(configuredCondition().ObservedGeneration).To(BeEquivalentTo(1))
+	})
+})
+
+// statusPatchCountingClient counts status patches sent to the API server
+type statusPatchCountingClient struct {
+	client.Client
+	patches int
+}
+
+func (c *statusPatchCountingClient) Status() client.StatusWriter {
+	return &statusPatchCountingWriter{StatusWriter: c.Client.Status(), client: c}
+}
+
+type statusPatchCountingWriter struct {
+	client.StatusWriter
+	client *statusPatchCountingClient
+}
+
+func (w *statusPatchCountingWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	w.client.patches++
+	return w.StatusWriter.Patch(ctx, obj, patch, opts...)
+}
+
 type testConfigurerProto struct {
 	configureNodeFunction func(nodeConfig sriovv2.SriovFecNodeConfigSpec) error
+	restartFunction       func(pf sriovv2.PhysicalFunctionConfigExt) error
 }
 
-func (t testConfigurerProto) ApplySpec(nodeConfig sriovv2.SriovFecNodeConfigSpec) error {
+func (t testConfigurerProto) ApplySpec(_ context.Context, nodeConfig sriovv2.SriovFecNodeConfigSpec) error {
 	return t.configureNodeFunction(nodeConfig)
 }
+
+func (t testConfigurerProto) RestartPfBBConfig(ctx context.Context, pf sriovv2.PhysicalFunctionConfigExt) error {
+	if t.restartFunction == nil {
+		return fmt.Errorf("not implemented")
+	}
+	return t.restartFunction(pf)
+}
+
+var _ = Describe("NodeConfigReconciler.Reconcile of unsupported accelerator", func() {
+	var (
+		fakeClient  client.Client
+		nodeNameRef = types.NamespacedName{Name: "worker", Namespace: "testNamespace"}
+		reconciler  NodeConfigReconciler
+		drained     bool
+	)
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(sriovv2.AddToScheme(scheme)).ToNot(HaveOccurred())
+		Expect(vrbv1.AddToScheme(scheme)).ToNot(HaveOccurred())
+		procCmdlineFilePath = "testdata/cmdline_test"
+		sysLockdownFilePath = filepath.Join(testTmpFolder, "lockdown")
+		Expect(os.WriteFile(sysLockdownFilePath, []byte("[none] integrity confidentiality"), 0644)).To(Succeed())
+		drained = false
+
+		getSriovInventory = func(_ context.Context, log *logrus.Logger) (*sriovv2.NodeInventory, error) {
+			return &sriovv2.NodeInventory{
+				UnsupportedDevices: []sriovv2.UnsupportedDevice{{PCIAddress: pciAddress, VendorID: "8086", DeviceID: "57c2"}},
+			}, nil
+		}
+		VrbgetSriovInventory = func(_ context.Context, log *logrus.Logger) (*vrbv1.NodeInventory, error) {
+			return &vrbv1.NodeInventory{}, nil
+		}
+
+		sfnc := &sriovv2.SriovFecNodeConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace, Generation: 1},
+			Spec: sriovv2.SriovFecNodeConfigSpec{
+				PhysicalFunctions: []sriovv2.PhysicalFunctionConfigExt{{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, VFDriver: utils.IGB_UIO, VFAmount: 1}},
+			},
+		}
+		vrbnc := &vrbv1.SriovVrbNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace}}
+		fakeClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(sfnc, vrbnc).Build()
+
+		reconciler = NodeConfigReconciler{
+			Client:      fakeClient,
+			log:         utils.NewLogger(),
+			nodeNameRef: nodeNameRef,
+			drainerAndExecute: func(_ context.Context, configurer func(ctx context.Context) bool, drain bool) error {
+				drained = true
+				return nil
+			},
+		}
+	})
+
+	AfterEach(func() {
+		getSriovInventory = GetSriovInventory
+		VrbgetSriovInventory = VrbGetSriovInventory
+		sysLockdownFilePath = "/sys/kernel/security/lockdown"
+	})
+
+	It("fails before drain, pointing at the discovery config", func() {
+		_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: nodeNameRef})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(drained).To(BeFalse())
+
+		sfnc := new(sriovv2.SriovFecNodeConfig)
+		Expect(fakeClient.Get(context.TODO(), nodeNameRef, sfnc)).To(Succeed())
+		condition := sfnc.FindCondition(ConditionConfigured)
+		Expect(condition.Reason).To(Equal(string(ConfigurationUnsupportedDevice)))
+		Expect(condition.Message).To(ContainSubstring("device ID 57c2 is missing in accelerators.json"))
+		Expect(sfnc.Status.UnsupportedDevices).To(Equal([]sriovv2.UnsupportedDevice{{PCIAddress: pciAddress, VendorID: "8086", DeviceID: "57c2"}}))
+	})
+})
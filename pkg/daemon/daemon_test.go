@@ -10,11 +10,15 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	fuzz "github.com/google/gofuzz"
 	"github.com/google/uuid"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/conditions"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/drainhelper"
 	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
 
 	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
@@ -69,15 +73,32 @@ var _ = Describe("NodeConfigReconciler", func() {
 			Expect(createFiles(filepath.Join(sysBusPciDrivers, utils.IGB_UIO), "bind")).To(Succeed())
 			Expect(createFiles(filepath.Join(sysBusPciDrivers, utils.PCI_PF_STUB_DASH), "bind")).To(Succeed())
 
-			getVFconfigured = func(string) int {
-				return 0
+			// VFs are "created" by writing their amount into sysfs of PF, as kernel does
+			vfs := []string{"0000:14:01.0", "0000:14:01.1", "0000:14:01.2", "0000:14:01.3", "0000:14:01.4"}
+			for _, vf := range vfs {
+				Expect(createFiles(filepath.Join(sysBusPciDevices, vf), "driver_override")).To(Succeed())
+			}
+			Expect(createFiles(filepath.Join(sysBusPciDrivers, "v"), "bind")).To(Succeed())
+
+			getVFconfigured = func(pf string) int {
+				configured := 0
+				for _, file := range []string{vfNumFileDefault, vfNumFileIgbUio} {
+					content, _ := os.ReadFile(filepath.Join(sysBusPciDevices, pf, file))
+					if amount, err := strconv.Atoi(strings.TrimSpace(string(content))); err == nil && amount > configured {
+						configured = amount
+					}
+				}
+				return configured
 			}
 
-			getVFList = func(string) ([]string, error) {
-				return nil, nil
+			getVFList = func(pf string) ([]string, error) {
+				if configured := getVFconfigured(pf); configured < len(vfs) {
+					return vfs[:configured], nil
+				}
+				return vfs, nil
 			}
 
-			getSriovInventory = func(_ *logrus.Logger) (*sriovv2.NodeInventory, error) {
+			getSriovInventory = func(_ context.Context, _ *logrus.Logger) (*sriovv2.NodeInventory, error) {
 				return &data.SriovFecNodeConfig.Status.Inventory, nil
 			}
 
@@ -104,10 +125,8 @@ var _ = Describe("NodeConfigReconciler", func() {
 
 				nodeNameRef := types.NamespacedName{Namespace: _SUPPORTED_NAMESPACE, Name: _THIS_NODE_NAME}
 
-				drainer := func(operation func(ctx context.Context) bool, drain bool) error { return nil }
-
 				var err error
-				reconciler, err = NewNodeConfigReconciler(&onGetErrorReturningClient, drainer, nodeNameRef, nil, nil, nil)
+				reconciler, err = NewNodeConfigReconciler(&onGetErrorReturningClient, &drainhelper.FakeDrainer{}, nodeNameRef, nil, nil, nil, nil, nil)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(reconciler).ToNot(BeNil())
 			})
@@ -141,20 +160,19 @@ var _ = Describe("NodeConfigReconciler", func() {
 
 					nodeNameRef := types.NamespacedName{Namespace: _SUPPORTED_NAMESPACE, Name: _THIS_NODE_NAME}
 					pfBBConfigController := NewPfBBConfigController(log, uuid.New().String())
-					configurer := NewNodeConfigurator(logrus.New(), pfBBConfigController, k8sClient, nodeNameRef)
+					configurer := NewNodeConfigurator(logrus.New(), pfBBConfigController, k8sClient, nodeNameRef, nil, nil)
 
 					reconciler, err := NewNodeConfigReconciler(
 						k8sClient,
-						func(configure func(ctx context.Context) bool, drain bool) error {
-							configure(context.TODO())
-							return nil
-						},
+						&drainhelper.FakeDrainer{},
 						nodeNameRef,
 						configurer,
 						configurer,
-						func() error {
+						func(context.Context) error {
 							return nil
-						})
+						},
+						nil,
+						nil)
 
 					Expect(err).ToNot(HaveOccurred())
 
@@ -167,7 +185,7 @@ var _ = Describe("NodeConfigReconciler", func() {
 					Expect(k8sClient.Create(context.TODO(), &data.Node)).To(Succeed())
 
 					//initialize empty SriovFecNodeConfig
-					Expect(reconciler.CreateEmptyNodeConfigIfNeeded(k8sClient)).To(Succeed())
+					Expect(reconciler.CreateEmptyNodeConfigIfNeeded(context.TODO(), k8sClient)).To(Succeed())
 					go func() {
 						Expect(k8sManager.Start(context.TODO())).ToNot(HaveOccurred())
 					}()
@@ -181,8 +199,6 @@ var _ = Describe("NodeConfigReconciler", func() {
 				Context("Requested spec/config is correct and refers to existing accelerators", func() {
 					It("spec/config should be applied", func() {
 						osExecMock := new(runExecCmdMock).
-							onCall([]string{"pkill -9 -f pf_bb_config.*0000:14:00.1"}).
-							Return("", nil).
 							onCall([]string{"modprobe", utils.IGB_UIO}).
 							Return("", nil).
 							onCall([]string{"modprobe", "v"}).
@@ -226,7 +242,7 @@ var _ = Describe("NodeConfigReconciler", func() {
 				It("spec/config should not be applied, error info should be exposed over configuration ccondition", func() {
 
 					//existing inventory
-					getSriovInventory = func(log *logrus.Logger) (*sriovv2.NodeInventory, error) {
+					getSriovInventory = func(_ context.Context, log *logrus.Logger) (*sriovv2.NodeInventory, error) {
 						inventory := data.SriovFecNodeConfig.Status.Inventory
 						inventory.SriovAccelerators[0].PCIAddress = "0000:99:00.1"
 						return &inventory, nil
@@ -272,14 +288,9 @@ var _ = Describe("NodeConfigReconciler", func() {
 					Expect(err).ToNot(HaveOccurred())
 					Expect(k8sClient).ToNot(BeNil())
 
-					drainer := func(configure func(ctx context.Context) bool, drain bool) error {
-						configure(context.TODO())
-						return nil
-					}
-
 					nodeNameRef := types.NamespacedName{Namespace: _SUPPORTED_NAMESPACE, Name: _THIS_NODE_NAME}
 
-					nodeReconciler, err := NewNodeConfigReconciler(k8sClient, drainer, nodeNameRef, nil, nil, nil)
+					nodeReconciler, err := NewNodeConfigReconciler(k8sClient, &drainhelper.FakeDrainer{}, nodeNameRef, nil, nil, nil, nil, nil)
 					Expect(err).ToNot(HaveOccurred())
 
 					reconciler := nodeRecocnilerWrapper{
@@ -350,7 +361,7 @@ var _ = Describe("NodeConfigReconciler", func() {
 					fakeClient := fake.NewClientBuilder().WithObjects(&data.SriovFecNodeConfig).Build()
 					nodeNameRef := types.NamespacedName{Namespace: _SUPPORTED_NAMESPACE, Name: _THIS_NODE_NAME}
 					reconciler := NodeConfigReconciler{Client: fakeClient, log: log, nodeNameRef: nodeNameRef}
-					getSriovInventory = func(log *logrus.Logger) (*sriovv2.NodeInventory, error) {
+					getSriovInventory = func(_ context.Context, log *logrus.Logger) (*sriovv2.NodeInventory, error) {
 						return nil, fmt.Errorf("cannot read inventory")
 					}
 
@@ -381,24 +392,27 @@ var _ = Describe("NodeConfigReconciler", func() {
 
 		Expect(nodeConfig.Status.Conditions).To(BeEmpty())
 
-		Expect(reconciler.updateStatus(&nodeConfig, metav1.ConditionUnknown, ConfigurationNotRequested, "Unknown")).To(Succeed())
+		Expect(reconciler.updateStatus(context.TODO(), &nodeConfig, metav1.ConditionUnknown, ConfigurationNotRequested, "Unknown")).To(Succeed())
 
 		res := new(sriovv2.SriovFecNodeConfig)
 		Expect(fakeClient.Get(context.TODO(), client.ObjectKeyFromObject(&nodeConfig), res)).To(Succeed())
-		Expect(res.Status.Conditions).To(HaveLen(1))
+		Expect(res.Status.Conditions).To(HaveLen(4), "Configured with Available, Progressing and Degraded")
 		Expect(res.FindCondition(ConditionConfigured)).ToNot(BeNil())
 		Expect(res.FindCondition(ConditionConfigured).Reason).To(ContainSubstring("NotRequested"), "Condition.Reason")
 		Expect(res.FindCondition(ConditionConfigured).Message).To(ContainSubstring("Unknown"), "Condition.Message")
 		Expect(res.FindCondition(ConditionConfigured).Status).To(BeEquivalentTo(metav1.ConditionUnknown), "Condition.Status")
 
-		Expect(reconciler.updateStatus(&nodeConfig, metav1.ConditionTrue, ConfigurationSucceeded, string(ConfigurationSucceeded))).To(Succeed())
+		Expect(reconciler.updateStatus(context.TODO(), &nodeConfig, metav1.ConditionTrue, ConfigurationSucceeded, string(ConfigurationSucceeded))).To(Succeed())
 		res = new(sriovv2.SriovFecNodeConfig)
 		Expect(fakeClient.Get(context.TODO(), client.ObjectKeyFromObject(&nodeConfig), res)).To(Succeed())
-		Expect(res.Status.Conditions).To(HaveLen(1))
+		Expect(res.Status.Conditions).To(HaveLen(4), "Configured with Available, Progressing and Degraded")
 		Expect(res.FindCondition(ConditionConfigured)).ToNot(BeNil())
 		Expect(res.FindCondition(ConditionConfigured).Status).To(BeEquivalentTo(metav1.ConditionTrue), "Condition.Status")
 		Expect(res.FindCondition(ConditionConfigured).Message).To(ContainSubstring("Succeeded"), "Condition.Message")
 		Expect(res.FindCondition(ConditionConfigured).Reason).To(ContainSubstring("Succeeded"), "Condition.Reason")
+		Expect(res.FindCondition(conditions.TypeAvailable).Status).To(BeEquivalentTo(metav1.ConditionTrue), "Available")
+		Expect(res.FindCondition(conditions.TypeProgressing).Status).To(BeEquivalentTo(metav1.ConditionFalse), "Progressing")
+		Expect(res.FindCondition(conditions.TypeDegraded).Status).To(BeEquivalentTo(metav1.ConditionFalse), "Degraded")
 	})
 
 	Describe("isConfigurationOfNonExistingInventoryRequested()", func() {
@@ -523,8 +537,9 @@ func (d *TestData) NodeConfigNS() string {
 	return d.SriovFecNodeConfig.Namespace
 }
 
-func initNodeConfiguratorRunExecCmd(f func([]string, *logrus.Logger) (string, error)) {
+func initNodeConfiguratorRunExecCmd(f cmdRunner) {
 	runExecCmd = f
+	runPfBBConfigCmd = f
 }
 
 type runExecCmdMock struct {
@@ -545,7 +560,7 @@ func (r *runExecCmdMock) onCall(expected []string) *resultCatcher {
 	return &resultCatcher{toBeReturned: &tbr, mock: r}
 }
 
-func (r *runExecCmdMock) execute(args []string, l *logrus.Logger) (string, error) {
+func (r *runExecCmdMock) execute(_ context.Context, args []string, l *logrus.Logger) (string, error) {
 	l.Info("runExecCmdMock:", "command", args)
 	defer func() { r.executionCount++ }()
 
@@ -596,12 +611,12 @@ func FuzzIsCardUpdateRequired(f *testing.F) {
 		Client:      nil,
 		log:         &logrus.Logger{},
 		nodeNameRef: types.NamespacedName{},
-		drainerAndExecute: func(configurer func(ctx context.Context) bool, drain bool) error {
+		drainerAndExecute: func(_ context.Context, configurer func(ctx context.Context) bool, drain bool) error {
 			return nil
 		},
 		sriovfecconfigurer: nil,
 		vrbconfigurer:      nil,
-		restartDevicePlugin: func() error {
+		restartDevicePlugin: func(context.Context) error {
 			return nil
 		},
 	}
@@ -622,10 +637,11 @@ func FuzzIsCardUpdateRequired(f *testing.F) {
 
 		defer func() {
 			if r := recover(); r != nil {
-				t.Errorf("Error: %v", icur)
+				t.Errorf("Error: %v", &icur)
 			}
 		}()
-		_ = icur.isCardUpdateRequired(&sfnc, &detectedInventory)
+		hash, _ := specHash(sfnc.Spec)
+		_ = icur.isCardUpdateRequired(context.TODO(), &sfnc, &detectedInventory, hash)
 	})
 }
 
@@ -634,12 +650,12 @@ func FuzzVrbIsCardUpdateRequired(f *testing.F) {
 		Client:      nil,
 		log:         &logrus.Logger{},
 		nodeNameRef: types.NamespacedName{},
-		drainerAndExecute: func(configurer func(ctx context.Context) bool, drain bool) error {
+		drainerAndExecute: func(_ context.Context, configurer func(ctx context.Context) bool, drain bool) error {
 			return nil
 		},
 		sriovfecconfigurer: nil,
 		vrbconfigurer:      nil,
-		restartDevicePlugin: func() error {
+		restartDevicePlugin: func(context.Context) error {
 			return nil
 		},
 	}
@@ -660,10 +676,11 @@ func FuzzVrbIsCardUpdateRequired(f *testing.F) {
 
 		defer func() {
 			if r := recover(); r != nil {
-				t.Errorf("Error: %v", vicur)
+				t.Errorf("Error: %v", &vicur)
 			}
 		}()
-		_ = vicur.VrbisCardUpdateRequired(&svnc, &detectedInventory)
+		hash, _ := specHash(svnc.Spec)
+		_ = vicur.VrbisCardUpdateRequired(context.TODO(), &svnc, &detectedInventory, hash)
 	})
 }
 
@@ -0,0 +1,296 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	fec "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/conditions"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+const (
+	// debugSocketDir is subdirectory of the state dir the endpoint's unix socket is created in. The endpoint is not
+	// authenticated, so it is not exposed on any network interface; the directory is mounted from the host instead, so
+	// that node-local debugging tools reach it.
+	debugSocketDir  = "debug"
+	debugSocketName = "daemon.sock"
+)
+
+var debugEndpointShutdownTimeout = 5 * time.Second
+
+// debugNodeConfig is the state of node config served by the debug endpoint. It is read from node config's status
+// written by the reconciler, so that the endpoint never shows anything else than the status does.
+type debugNodeConfig struct {
+	Kind              string             `json:"kind"`
+	Name              string             `json:"name"`
+	AppliedSpecHash   string             `json:"appliedSpecHash,omitempty"`
+	Conditions        []metav1.Condition `json:"conditions,omitempty"`
+	PhysicalFunctions []debugPF          `json:"physicalFunctions,omitempty"`
+	// Inventory is fec.NodeInventory or vrbv1.NodeInventory, as exposed in status
+	Inventory interface{} `json:"inventory"`
+}
+
+// debugPF is the state of PF requested by node config's spec
+type debugPF struct {
+	PCIAddress             string            `json:"pciAddress"`
+	Health                 *metav1.Condition `json:"health,omitempty"`
+	AppliedBBDevConfigHash string            `json:"appliedBBDevConfigHash,omitempty"`
+	PfBBConfig             pfBBConfigState   `json:"pfBBConfig"`
+}
+
+// pfBBConfigState tells which pf_bb_config processes serve the PF
+type pfBBConfigState struct {
+	Running bool   `json:"running"`
+	PIDs    []int  `json:"pids,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+func readPfBBConfigState(pciAddress string) pfBBConfigState {
+	pids, err := findPfBBConfigProcesses(pciAddress)
+	if err != nil {
+		return pfBBConfigState{Error: err.Error()}
+	}
+	return pfBBConfigState{Running: len(pids) != 0, PIDs: pids}
+}
+
+func newDebugPFs(pciAddresses []string, nodeConditions []metav1.Condition, appliedBBDevConfigHashes map[string]string) []debugPF {
+	var pfs []debugPF
+	for _, pciAddress := range pciAddresses {
+		pfs = append(pfs, debugPF{
+			PCIAddress:             pciAddress,
+			Health:                 meta.FindStatusCondition(nodeConditions, conditions.PFHealthyType(pciAddress)),
+			AppliedBBDevConfigHash: appliedBBDevConfigHashes[pciAddress],
+			PfBBConfig:             readPfBBConfigState(pciAddress),
+		})
+	}
+	return pfs
+}
+
+func newFecDebugNodeConfig(nc *fec.SriovFecNodeConfig) debugNodeConfig {
+	var pciAddresses []string
+	for _, pf := range nc.Spec.PhysicalFunctions {
+		pciAddresses = append(pciAddresses, utils.CanonicalPCIAddress(pf.PCIAddress))
+	}
+	return debugNodeConfig{
+		Kind:              "SriovFecNodeConfig",
+		Name:              nc.Name,
+		AppliedSpecHash:   nc.Status.AppliedSpecHash,
+		Conditions:        nc.Status.Conditions,
+		PhysicalFunctions: newDebugPFs(pciAddresses, nc.Status.Conditions, nc.Status.AppliedBBDevConfigHashes),
+		Inventory:         nc.Status.Inventory,
+	}
+}
+
+func newVrbDebugNodeConfig(nc *vrbv1.SriovVrbNodeConfig) debugNodeConfig {
+	var pciAddresses []string
+	for _, pf := range nc.Spec.PhysicalFunctions {
+		pciAddresses = append(pciAddresses, utils.CanonicalPCIAddress(pf.PCIAddress))
+	}
+	return debugNodeConfig{
+		Kind:              "SriovVrbNodeConfig",
+		Name:              nc.Name,
+		AppliedSpecHash:   nc.Status.AppliedSpecHash,
+		Conditions:        nc.Status.Conditions,
+		PhysicalFunctions: newDebugPFs(pciAddresses, nc.Status.Conditions, nc.Status.AppliedBBDevConfigHashes),
+		Inventory:         nc.Status.Inventory,
+	}
+}
+
+// DebugServer serves read-only state of the daemon as JSON to node-local debugging tools and lets them trigger
+// inventory rescan. It listens on unix socket accessible to root only and has no authentication beyond that.
+type DebugServer struct {
+	reconciler      *NodeConfigReconciler
+	effectiveConfig EffectiveConfig
+	socketPath      string
+	log             *logrus.Logger
+}
+
+// NewDebugServer creates server serving state of node configs handled by reconciler
+func NewDebugServer(reconciler *NodeConfigReconciler, effectiveConfig EffectiveConfig, log *logrus.Logger) *DebugServer {
+	return &DebugServer{
+		reconciler:      reconciler,
+		effectiveConfig: effectiveConfig,
+		socketPath:      filepath.Join(workdir, debugSocketDir, debugSocketName),
+		log:             log,
+	}
+}
+
+// Start implements manager.Runnable
+func (d *DebugServer) Start(ctx context.Context) error {
+	if err := os.MkdirAll(filepath.Dir(d.socketPath), 0700); err != nil {
+		return fmt.Errorf("failed to create directory of debug endpoint socket - %v", err)
+	}
+	// socket left behind by previous instance of the daemon
+	if err := os.Remove(d.socketPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove stale debug endpoint socket - %v", err)
+	}
+	listener, err := net.Listen("unix", d.socketPath)
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(d.socketPath, 0600); err != nil {
+		listener.Close()
+		return fmt.Errorf("failed to restrict access to debug endpoint socket - %v", err)
+	}
+	server := &http.Server{Handler: d.handler(), ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), debugEndpointShutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			d.log.WithError(err).Error("failed to shut down debug endpoint")
+		}
+	}()
+
+	d.log.WithField("socket", d.socketPath).Info("starting debug endpoint")
+	if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (d *DebugServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", d.get(func(ctx context.Context) (interface{}, error) {
+		return d.nodeConfigs(ctx)
+	}))
+	mux.HandleFunc("/inventory", d.get(func(ctx context.Context) (interface{}, error) {
+		nodeConfigs, err := d.nodeConfigs(ctx)
+		if err != nil {
+			return nil, err
+		}
+		inventory := map[string]interface{}{}
+		for _, nc := range nodeConfigs {
+			inventory[nc.Kind+"/"+nc.Name] = nc.Inventory
+		}
+		return inventory, nil
+	}))
+	mux.HandleFunc("/pf-bb-config", d.get(func(ctx context.Context) (interface{}, error) {
+		nodeConfigs, err := d.nodeConfigs(ctx)
+		if err != nil {
+			return nil, err
+		}
+		states := map[string]pfBBConfigState{}
+		for _, nc := range nodeConfigs {
+			for _, pf := range nc.PhysicalFunctions {
+				states[pf.PCIAddress] = pf.PfBBConfig
+			}
+		}
+		return states, nil
+	}))
+	mux.HandleFunc("/effective-config", d.get(func(context.Context) (interface{}, error) {
+		return d.effectiveConfig, nil
+	}))
+	mux.HandleFunc("/rescan", func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := d.reconciler.RequestRescan(req.Context()); err != nil {
+			d.log.WithError(err).Error("failed to request inventory rescan")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		d.log.Info("inventory rescan requested with debug endpoint")
+		w.WriteHeader(http.StatusAccepted)
+	})
+	return mux
+}
+
+// get serves JSON returned by read on GET requests
+func (d *DebugServer) get(read func(ctx context.Context) (interface{}, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		value, err := read(req.Context())
+		if err != nil {
+			d.log.WithError(err).WithField("path", req.URL.Path).Error("failed to read state for debug endpoint")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(value); err != nil {
+			d.log.WithError(err).WithField("path", req.URL.Path).Error("failed to write debug endpoint response")
+		}
+	}
+}
+
+// nodeConfigs returns state of the node configs of the node; the ones which do not exist yet are skipped
+func (d *DebugServer) nodeConfigs(ctx context.Context) ([]debugNodeConfig, error) {
+	r := d.reconciler
+	var nodeConfigs []debugNodeConfig
+
+	sfnc := &fec.SriovFecNodeConfig{}
+	if err := r.Get(ctx, r.nodeConfigKey(), sfnc); err == nil {
+		nodeConfigs = append(nodeConfigs, newFecDebugNodeConfig(sfnc))
+	} else if !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+
+	secondaries, err := r.secondaryNodeConfigs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(secondaries, func(i, j int) bool { return secondaries[i].Name < secondaries[j].Name })
+	for i := range secondaries {
+		nodeConfigs = append(nodeConfigs, newFecDebugNodeConfig(&secondaries[i]))
+	}
+
+	vrbnc := &vrbv1.SriovVrbNodeConfig{}
+	if err := r.Get(ctx, r.nodeConfigKey(), vrbnc); err == nil {
+		nodeConfigs = append(nodeConfigs, newVrbDebugNodeConfig(vrbnc))
+	} else if !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	return nodeConfigs, nil
+}
+
+// RequestRescan enqueues reconcile of all node configs of the node, which exposes freshly scanned inventory in their
+// status; accelerators are configured as well if the scan reveals they are not in the requested state
+func (r *NodeConfigReconciler) RequestRescan(ctx context.Context) error {
+	secondaries, err := r.secondaryNodeConfigs(ctx)
+	if err != nil {
+		return err
+	}
+	// reconcile of node's SriovFecNodeConfig covers SriovVrbNodeConfig as well
+	nodeConfigRef := r.nodeConfigKey()
+	nodeConfigs := []*fec.SriovFecNodeConfig{{ObjectMeta: metav1.ObjectMeta{Name: nodeConfigRef.Name, Namespace: nodeConfigRef.Namespace}}}
+	for i := range secondaries {
+		nodeConfigs = append(nodeConfigs, &secondaries[i])
+	}
+
+	for _, nc := range nodeConfigs {
+		select {
+		case r.rescans <- event.GenericEvent{Object: nc}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/conditions"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/drainhelper"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("DebugServer", func() {
+	const n3000, acc100, vrb1 = "0000:1b:00.0", "0000:f7:00.0", "0000:f8:00.0"
+
+	var (
+		log                 = logrus.New()
+		nodeNameRef         = types.NamespacedName{Name: "worker", Namespace: "testNamespace"}
+		originalProcPath    = procPath
+		originalOwnProcPath = ownProcPath
+		reconciler          *NodeConfigReconciler
+		server              *DebugServer
+	)
+
+	request := func(method, path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		server.handler().ServeHTTP(recorder, httptest.NewRequest(method, path, nil))
+		return recorder
+	}
+
+	BeforeEach(func() {
+		var err error
+		procPath, err = os.MkdirTemp(testTmpFolder, "proc")
+		Expect(err).ToNot(HaveOccurred())
+		ownProcPath = procPath
+		Expect(os.MkdirAll(filepath.Join(procPath, "42"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(procPath, "42", "cmdline"),
+			[]byte(strings.Join([]string{"/sriov_workdir/pf_bb_config", "ACC100", "-p", acc100}, "\x00")), 0644)).To(Succeed())
+
+		scheme := runtime.NewScheme()
+		Expect(sriovv2.AddToScheme(scheme)).To(Succeed())
+		Expect(vrbv1.AddToScheme(scheme)).To(Succeed())
+
+		configPath = "testdata/accelerators.json"
+		VrbconfigPath = "testdata/accelerators_vrb.json"
+		fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&sriovv2.SriovFecNodeConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace},
+				Spec:       sriovv2.SriovFecNodeConfigSpec{PhysicalFunctions: []sriovv2.PhysicalFunctionConfigExt{{PCIAddress: n3000}}},
+				Status: sriovv2.SriovFecNodeConfigStatus{
+					Conditions: []metav1.Condition{
+						conditions.Configured(metav1.ConditionTrue, conditions.ReasonSucceeded, "Configured successfully", 1),
+						conditions.PFHealthy(n3000, metav1.ConditionFalse, conditions.ReasonDegraded, "uncorrectable errors", 1),
+					},
+					Inventory: sriovv2.NodeInventory{SriovAccelerators: []sriovv2.SriovAccelerator{
+						{PCIAddress: n3000, PFDriver: utils.PCI_PF_STUB_DASH, MaxVFs: 8}, {PCIAddress: acc100, PFDriver: utils.PCI_PF_STUB_DASH, MaxVFs: 16},
+					}},
+					AppliedSpecHash: "fec-hash",
+				},
+			},
+			&sriovv2.SriovFecNodeConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "worker-acc100", Namespace: nodeNameRef.Namespace},
+				Spec: sriovv2.SriovFecNodeConfigSpec{
+					NodeName:          nodeNameRef.Name,
+					PhysicalFunctions: []sriovv2.PhysicalFunctionConfigExt{{PCIAddress: acc100}},
+				},
+				Status: sriovv2.SriovFecNodeConfigStatus{
+					AppliedSpecHash:          "secondary-hash",
+					AppliedBBDevConfigHashes: map[string]string{acc100: "cfg-hash"},
+				},
+			},
+			&vrbv1.SriovVrbNodeConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace},
+				Spec:       vrbv1.SriovVrbNodeConfigSpec{PhysicalFunctions: []vrbv1.PhysicalFunctionConfigExt{{PCIAddress: vrb1}}},
+				Status:     vrbv1.SriovVrbNodeConfigStatus{AppliedSpecHash: "vrb-hash"},
+			},
+		).Build()
+
+		reconciler, err = NewNodeConfigReconciler(fakeClient, &drainhelper.FakeDrainer{}, nodeNameRef, nil, nil,
+			func(context.Context) error { return nil }, record.NewFakeRecorder(10), nil)
+		Expect(err).ToNot(HaveOccurred())
+		server = NewDebugServer(reconciler, EffectiveConfig{ClusterType: "single-node", FeatureGates: "DebugEndpoint=true"}, log)
+	})
+
+	AfterEach(func() {
+		procPath = originalProcPath
+		ownProcPath = originalOwnProcPath
+	})
+
+	It("listens on unix socket in the state dir accessible to root only", func() {
+		Expect(server.socketPath).To(Equal(filepath.Join(workdir, "debug", "daemon.sock")))
+
+		// unix socket path is limited in length, so it is not nested in testTmpFolder
+		dir, err := os.MkdirTemp("", "state")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+		server.socketPath = filepath.Join(dir, "debug", "daemon.sock")
+		// stale socket of previous instance is replaced
+		Expect(os.MkdirAll(filepath.Dir(server.socketPath), 0700)).To(Succeed())
+		Expect(os.WriteFile(server.socketPath, nil, 0644)).To(Succeed())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() { done <- server.Start(ctx) }()
+		defer func() {
+			cancel()
+			Expect(<-done).To(Succeed())
+		}()
+
+		httpClient := &http.Client{Transport: &http.Transport{DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", server.socketPath)
+		}}}
+		Eventually(func() (int, error) {
+			response, err := httpClient.Get("http://daemon/effective-config")
+			if err != nil {
+				return 0, err
+			}
+			defer response.Body.Close()
+			return response.StatusCode, nil
+		}).Should(Equal(http.StatusOK))
+
+		info, err := os.Stat(server.socketPath)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.Mode().Type()).To(Equal(os.ModeSocket))
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+	})
+
+	It("serves status of node configs of the node together with pf_bb_config processes", func() {
+		response := request(http.MethodGet, "/status")
+		Expect(response.Code).To(Equal(http.StatusOK))
+		Expect(response.Header().Get("Content-Type")).To(Equal("application/json"))
+
+		var nodeConfigs []debugNodeConfig
+		Expect(json.Unmarshal(response.Body.Bytes(), &nodeConfigs)).To(Succeed())
+		Expect(nodeConfigs).To(HaveLen(3))
+
+		Expect(nodeConfigs[0].Kind).To(Equal("SriovFecNodeConfig"))
+		Expect(nodeConfigs[0].Name).To(Equal("worker"))
+		Expect(nodeConfigs[0].AppliedSpecHash).To(Equal("fec-hash"))
+		Expect(nodeConfigs[0].Conditions).To(HaveLen(2))
+		Expect(nodeConfigs[0].PhysicalFunctions).To(HaveLen(1))
+		Expect(nodeConfigs[0].PhysicalFunctions[0].Health.Reason).To(Equal(string(conditions.ReasonDegraded)))
+		Expect(nodeConfigs[0].PhysicalFunctions[0].PfBBConfig).To(Equal(pfBBConfigState{}))
+
+		Expect(nodeConfigs[1].Name).To(Equal("worker-acc100"))
+		Expect(nodeConfigs[1].AppliedSpecHash).To(Equal("secondary-hash"))
+		Expect(nodeConfigs[1].PhysicalFunctions).To(ConsistOf(debugPF{
+			PCIAddress:             acc100,
+			AppliedBBDevConfigHash: "cfg-hash",
+			PfBBConfig:             pfBBConfigState{Running: true, PIDs: []int{42}},
+		}))
+
+		Expect(nodeConfigs[2].Kind).To(Equal("SriovVrbNodeConfig"))
+		Expect(nodeConfigs[2].AppliedSpecHash).To(Equal("vrb-hash"))
+	})
+
+	It("serves inventory exposed in status", func() {
+		response := request(http.MethodGet, "/inventory")
+		Expect(response.Code).To(Equal(http.StatusOK))
+
+		inventory := map[string]sriovv2.NodeInventory{}
+		Expect(json.Unmarshal(response.Body.Bytes(), &inventory)).To(Succeed())
+		Expect(inventory).To(HaveKey("SriovFecNodeConfig/worker"))
+		Expect(inventory["SriovFecNodeConfig/worker"].SriovAccelerators).To(HaveLen(2))
+		Expect(inventory).To(HaveKey("SriovFecNodeConfig/worker-acc100"))
+		Expect(inventory).To(HaveKey("SriovVrbNodeConfig/worker"))
+	})
+
+	It("serves pf_bb_config processes and effective configuration", func() {
+		response := request(http.MethodGet, "/pf-bb-config")
+		Expect(response.Code).To(Equal(http.StatusOK))
+		states := map[string]pfBBConfigState{}
+		Expect(json.Unmarshal(response.Body.Bytes(), &states)).To(Succeed())
+		Expect(states).To(Equal(map[string]pfBBConfigState{
+			n3000:  {},
+			acc100: {Running: true, PIDs: []int{42}},
+			vrb1:   {},
+		}))
+
+		response = request(http.MethodGet, "/effective-config")
+		Expect(response.Code).To(Equal(http.StatusOK))
+		cfg := EffectiveConfig{}
+		Expect(json.Unmarshal(response.Body.Bytes(), &cfg)).To(Succeed())
+		Expect(cfg.ClusterType).To(Equal("single-node"))
+	})
+
+	It("enqueues reconcile of all node configs of the node on rescan", func() {
+		Expect(request(http.MethodPost, "/rescan").Code).To(Equal(http.StatusAccepted))
+
+		var enqueued []string
+		for len(reconciler.rescans) != 0 {
+			enqueued = append(enqueued, (<-reconciler.rescans).Object.GetName())
+		}
+		Expect(enqueued).To(ConsistOf("worker", "worker-acc100"))
+	})
+
+	It("is read-only except for rescan", func() {
+		Expect(request(http.MethodPost, "/status").Code).To(Equal(http.StatusMethodNotAllowed))
+		Expect(request(http.MethodDelete, "/inventory").Code).To(Equal(http.StatusMethodNotAllowed))
+		Expect(request(http.MethodGet, "/rescan").Code).To(Equal(http.StatusMethodNotAllowed))
+		Expect(reconciler.rescans).To(BeEmpty())
+	})
+})
@@ -0,0 +1,165 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	fec "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+const (
+	supportedAcceleratorsConfigMapName = "supported-accelerators"
+	supportedAcceleratorsKey           = "accelerators.json"
+	vrbSupportedAcceleratorsKey        = "accelerators_vrb.json"
+	vfioTokenSecretName                = "vfio-token"
+	vfioTokenSecretKey                 = "VFIO_TOKEN"
+)
+
+// dependencyReloadDebounce coalesces bursts of changes of daemon's ConfigMaps and Secrets into a single reload
+var dependencyReloadDebounce = 5 * time.Second
+
+// dependencyWatcher tracks changes of ConfigMaps and Secrets consumed by the daemon. Once they settle, reload is
+// marked as pending and reconcile of node's NodeConfig is enqueued; reload itself is done by the reconciler, so that
+// in-memory state does not change in the middle of configuration.
+type dependencyWatcher struct {
+	mu      sync.Mutex
+	timer   *time.Timer
+	pending bool
+	events  chan event.GenericEvent
+	// onVfioTokenChange is called with VFIO token read from vfio-token Secret
+	onVfioTokenChange func(token string)
+}
+
+func newDependencyWatcher() *dependencyWatcher {
+	return &dependencyWatcher{events: make(chan event.GenericEvent, 1)}
+}
+
+// OnVfioTokenChange registers function the VFIO token is passed to whenever vfio-token Secret changes
+func (r *NodeConfigReconciler) OnVfioTokenChange(f func(token string)) {
+	r.dependencies.mu.Lock()
+	defer r.dependencies.mu.Unlock()
+	r.dependencies.onVfioTokenChange = f
+}
+
+func isDaemonDependency(obj client.Object) bool {
+	switch obj.(type) {
+	case *corev1.ConfigMap:
+		return obj.GetName() == supportedAcceleratorsConfigMapName
+	case *corev1.Secret:
+		return obj.GetName() == vfioTokenSecretName
+	}
+	return false
+}
+
+// requestsForDependency schedules reload when one of daemon's dependencies changes; reconcile is enqueued by
+// the watcher once the debounce expires, so nothing is enqueued directly
+func (r *NodeConfigReconciler) requestsForDependency(obj client.Object) []reconcile.Request {
+	if obj.GetNamespace() != r.nodeNameRef.Namespace || !isDaemonDependency(obj) {
+		return nil
+	}
+
+	r.log.WithField("kind", reflect.TypeOf(obj).Elem().Name()).WithField("name", obj.GetName()).
+		Info("daemon dependency changed - scheduling reload")
+	nodeConfigRef := r.nodeConfigKey()
+	r.dependencies.changed(nodeConfigRef.Name, nodeConfigRef.Namespace)
+	return nil
+}
+
+// changed (re)starts the debounce; when it expires, reload is marked as pending and node's NodeConfig is enqueued
+func (w *dependencyWatcher) changed(name, namespace string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.timer = time.AfterFunc(dependencyReloadDebounce, func() {
+		w.mu.Lock()
+		w.pending = true
+		w.mu.Unlock()
+
+		nc := &fec.SriovFecNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+		select {
+		case w.events <- event.GenericEvent{Object: nc}:
+		default:
+			// reconcile is already enqueued
+		}
+	})
+}
+
+func (w *dependencyWatcher) takePending() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	pending := w.pending
+	w.pending = false
+	return pending
+}
+
+// reloadDependenciesIfChanged reloads discovery configs and VFIO token when their ConfigMap or Secret changed since
+// the last reconcile. Failures are only logged and previous state is kept.
+func (r *NodeConfigReconciler) reloadDependenciesIfChanged(ctx context.Context) {
+	if r.dependencies == nil || !r.dependencies.takePending() {
+		return
+	}
+
+	cm := &corev1.ConfigMap{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: r.nodeNameRef.Namespace, Name: supportedAcceleratorsConfigMapName}, cm); err != nil {
+		r.log.WithError(err).Error("failed to reload supported accelerators")
+	} else {
+		r.reloadDiscoveryConfig(cm, supportedAcceleratorsKey, &supportedAccelerators, &discoveryConfigHash)
+		r.reloadDiscoveryConfig(cm, vrbSupportedAcceleratorsKey, &VrbsupportedAccelerators, &VrbdiscoveryConfigHash)
+	}
+
+	r.dependencies.mu.Lock()
+	onVfioTokenChange := r.dependencies.onVfioTokenChange
+	r.dependencies.mu.Unlock()
+	if onVfioTokenChange == nil {
+		return
+	}
+
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: r.nodeNameRef.Namespace, Name: vfioTokenSecretName}, secret); err != nil {
+		r.log.WithError(err).Error("failed to reload VFIO token")
+		return
+	}
+	token, err := uuid.ParseBytes(secret.Data[vfioTokenSecretKey])
+	if err != nil {
+		r.log.WithError(err).Error("VFIO token is not in UUID format - keeping the previous one")
+		return
+	}
+	onVfioTokenChange(token.String())
+}
+
+func (r *NodeConfigReconciler) reloadDiscoveryConfig(cm *corev1.ConfigMap, key string, target *utils.AcceleratorDiscoveryConfig, targetHash *string) {
+	data, ok := cm.Data[key]
+	if !ok {
+		r.log.WithField("key", key).Error("supported accelerators ConfigMap misses discovery config - keeping the previous one")
+		return
+	}
+	cfg, err := utils.ParseDiscoveryConfig([]byte(data))
+	if err != nil {
+		r.log.WithError(err).WithField("key", key).Error("invalid discovery config - keeping the previous one")
+		return
+	}
+	if !reflect.DeepEqual(cfg, *target) {
+		*target = cfg
+		hash := utils.DiscoveryConfigHash(cfg)
+		discoveryConfigHashLock.Lock()
+		*targetHash = hash
+		discoveryConfigHashLock.Unlock()
+		r.log.WithField("key", key).WithField("hash", hash).Info("discovery config reloaded")
+	}
+}
@@ -0,0 +1,113 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("DependencyWatcher", func() {
+	const token = "7ef2b9a5-2a64-4f5b-a4cc-1fa6e2ef4c6a"
+	var (
+		originalDebounce                 = dependencyReloadDebounce
+		originalSupportedAccelerators    = supportedAccelerators
+		originalVrbSupportedAccelerators = VrbsupportedAccelerators
+		originalDiscoveryConfigHash      = discoveryConfigHash
+		originalVrbDiscoveryConfigHash   = VrbdiscoveryConfigHash
+		nodeNameRef                      = types.NamespacedName{Name: "worker", Namespace: "default"}
+		reconciler                       *NodeConfigReconciler
+		receivedToken                    string
+	)
+
+	acceleratorsConfigMap := func(devices string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: supportedAcceleratorsConfigMapName, Namespace: nodeNameRef.Namespace},
+			Data: map[string]string{
+				supportedAcceleratorsKey:    `{"VendorID": {"8086": "Intel Corporation"}, "Class": "12", "SubClass": "00", "Devices": {` + devices + `}}`,
+				vrbSupportedAcceleratorsKey: `{"VendorID": {"8086": "Intel Corporation"}, "Class": "12", "SubClass": "00", "Devices": {"57c2": "VRB2"}}`,
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		dependencyReloadDebounce = 50 * time.Millisecond
+		supportedAccelerators = utils.AcceleratorDiscoveryConfig{}
+		VrbsupportedAccelerators = utils.AcceleratorDiscoveryConfig{}
+		receivedToken = ""
+
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			acceleratorsConfigMap(`"0d5c": "ACC100"`),
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: vfioTokenSecretName, Namespace: nodeNameRef.Namespace},
+				Data:       map[string][]byte{vfioTokenSecretKey: []byte(token)},
+			},
+		).Build()
+
+		reconciler = &NodeConfigReconciler{Client: c, log: utils.NewLogger(), nodeNameRef: nodeNameRef, dependencies: newDependencyWatcher()}
+		reconciler.OnVfioTokenChange(func(t string) { receivedToken = t })
+	})
+
+	AfterEach(func() {
+		dependencyReloadDebounce = originalDebounce
+		supportedAccelerators = originalSupportedAccelerators
+		VrbsupportedAccelerators = originalVrbSupportedAccelerators
+		discoveryConfigHash = originalDiscoveryConfigHash
+		VrbdiscoveryConfigHash = originalVrbDiscoveryConfigHash
+	})
+
+	It("should enqueue single reconcile of node's NodeConfig for burst of dependency changes", func() {
+		Expect(reconciler.requestsForDependency(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "unrelated", Namespace: nodeNameRef.Namespace}})).To(BeEmpty())
+		Expect(reconciler.requestsForDependency(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: vfioTokenSecretName, Namespace: "other"}})).To(BeEmpty())
+		Consistently(reconciler.dependencies.events, 3*dependencyReloadDebounce).ShouldNot(Receive())
+
+		for i := 0; i < 5; i++ {
+			Expect(reconciler.requestsForDependency(acceleratorsConfigMap(`"0d5c": "ACC100"`))).To(BeEmpty())
+			Expect(reconciler.requestsForDependency(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: vfioTokenSecretName, Namespace: nodeNameRef.Namespace}})).To(BeEmpty())
+		}
+		var e event.GenericEvent
+		Eventually(reconciler.dependencies.events).Should(Receive(&e))
+		Expect(client.ObjectKeyFromObject(e.Object)).To(Equal(nodeNameRef))
+		Consistently(reconciler.dependencies.events, 3*dependencyReloadDebounce).ShouldNot(Receive())
+	})
+
+	It("should reload discovery configs and VFIO token on reconcile triggered by dependency change", func() {
+		reconciler.reloadDependenciesIfChanged(context.TODO())
+		Expect(supportedAccelerators.Devices).To(BeEmpty(), "nothing is reloaded unless dependency changed")
+		Expect(receivedToken).To(BeEmpty())
+
+		reconciler.requestsForDependency(acceleratorsConfigMap(`"0d5c": "ACC100"`))
+		Eventually(reconciler.dependencies.events).Should(Receive())
+		reconciler.reloadDependenciesIfChanged(context.TODO())
+		Expect(supportedAccelerators.Devices).To(Equal(map[string]string{"0d5c": "ACC100"}))
+		Expect(VrbsupportedAccelerators.Devices).To(Equal(map[string]string{"57c2": "VRB2"}))
+		Expect(discoveryConfigHash).To(Equal(utils.DiscoveryConfigHash(supportedAccelerators)))
+		Expect(VrbdiscoveryConfigHash).To(Equal(utils.DiscoveryConfigHash(VrbsupportedAccelerators)))
+		Expect(receivedToken).To(Equal(token))
+		reloadedHash := discoveryConfigHash
+
+		// invalid config keeps the previous one
+		Expect(reconciler.Update(context.TODO(), acceleratorsConfigMap(`"0d5c": `))).To(Succeed())
+		reconciler.requestsForDependency(acceleratorsConfigMap(`"0d5c": `))
+		Eventually(reconciler.dependencies.events).Should(Receive())
+		reconciler.reloadDependenciesIfChanged(context.TODO())
+		Expect(supportedAccelerators.Devices).To(Equal(map[string]string{"0d5c": "ACC100"}))
+		Expect(discoveryConfigHash).To(Equal(reloadedHash))
+	})
+})
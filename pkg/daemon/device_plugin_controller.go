@@ -8,18 +8,34 @@ import (
 	"fmt"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strings"
 	"time"
 )
 
-func NewDevicePluginController(c client.Client, log *logrus.Logger, nnr types.NamespacedName) *devicePluginController {
+// devicePluginSelector selects sriov-device-plugin pods which are restarted after accelerators are (re)configured
+var devicePluginSelector = client.MatchingLabels{"app": "sriov-device-plugin-daemonset"}
+
+// podNodeNameField selects pods scheduled on given node. API server supports it as field selector and CreateManager
+// indexes cached pods by it, so that pods of the node are looked up without listing whole namespace with any client.
+const podNodeNameField = "spec.nodeName"
+
+func podNodeName(obj client.Object) []string {
+	return []string{obj.(*corev1.Pod).Spec.NodeName}
+}
+
+func NewDevicePluginController(c client.Client, log *logrus.Logger, nnr types.NamespacedName, recorder record.EventRecorder) *devicePluginController {
 	return &devicePluginController{
 		Client:      c,
 		log:         log,
 		nodeNameRef: nnr,
+		recorder:    recorder,
 	}
 }
 
@@ -27,53 +43,236 @@ type devicePluginController struct {
 	client.Client
 	log         *logrus.Logger
 	nodeNameRef types.NamespacedName
+	recorder    record.EventRecorder
 }
 
-func (d *devicePluginController) RestartDevicePlugin() error {
-	pods := &corev1.PodList{}
-	err := d.List(context.TODO(), pods,
-		client.InNamespace(d.nodeNameRef.Namespace),
-		&client.MatchingLabels{"app": "sriov-device-plugin-daemonset"})
+const (
+	// devicePluginRestartRequired is a reason of event emitted when device plugin pod cannot be restarted by the daemon
+	devicePluginRestartRequired = "DevicePluginRestartRequired"
+	// ownerKindNone is logged for pods which are not managed by any controller
+	ownerKindNone = "none"
+)
 
+// devicePluginOwnerKind returns kind of the controller managing the pod: DaemonSet, Deployment (for pods of
+// Deployment's ReplicaSet), ReplicaSet, Node (static pod) or ownerKindNone
+func devicePluginOwnerKind(pod *corev1.Pod) string {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return ownerKindNone
+	}
+	// ReplicaSets of Deployment are named <deployment>-<pod-template-hash>
+	if hash := pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey]; owner.Kind == "ReplicaSet" && hash != "" && strings.HasSuffix(owner.Name, "-"+hash) {
+		return "Deployment"
+	}
+	return owner.Kind
+}
+
+func (d *devicePluginController) RestartDevicePlugin(ctx context.Context) error {
+	started := time.Now()
+	pods, err := d.listDevicePluginPods(ctx)
 	if err != nil {
 		return errors.Wrap(err, "failed to get pods")
 	}
-	if len(pods.Items) == 0 {
+	d.log.WithField("duration", time.Since(started).String()).WithField("pods", len(pods)).
+		Info("looked up device plugin pods of the node")
+	if len(pods) == 0 {
 		d.log.Info("there is no running instance of device plugin, nothing to restart")
 	}
 
-	for _, pod := range pods.Items {
-		if pod.Spec.NodeName != d.nodeNameRef.Name {
+	for i := range pods {
+		if err := d.restartDevicePluginPod(ctx, &pods[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restartDevicePluginPod deletes the pod and waits until its controller replaces it. Pods which would not be recreated
+// (static pods and pods without controller) are not deleted, user is asked to restart them with an event instead.
+func (d *devicePluginController) restartDevicePluginPod(ctx context.Context, pod *corev1.Pod) error {
+	ownerKind := devicePluginOwnerKind(pod)
+	log := d.log.WithField("pod", pod.Name).WithField("ownerKind", ownerKind)
+
+	var replaced wait.ConditionFunc
+	notReplaced := func() error {
+		return fmt.Errorf("failed to restart sriov-device-plugin (owner kind: %s) within specified time", ownerKind)
+	}
+	switch ownerKind {
+	case ownerKindNone, "Node":
+		log.Warn("device plugin pod would not be recreated once deleted - it has to be restarted manually")
+		d.recorder.Eventf(pod, corev1.EventTypeWarning, devicePluginRestartRequired,
+			"device plugin pod (owner kind: %s) is not recreated once deleted, so it is not restarted by the daemon; "+
+				"restart it manually, so that accelerators reconfigured on node %s are advertised", ownerKind, d.nodeNameRef.Name)
+		return nil
+	case "ReplicaSet", "Deployment":
+		// replacement may be scheduled on another node, so that pods of the ReplicaSet are counted in whole namespace
+		owner := metav1.GetControllerOf(pod)
+		replicas, err := d.countControlledPods(ctx, owner.UID, "", false)
+		if err != nil {
+			return errors.Wrap(err, "failed to get pods")
+		}
+		replaced = func() (bool, error) {
+			ready, err := d.countControlledPods(ctx, owner.UID, pod.Name, true)
+			if err != nil {
+				log.WithError(err).Error("failed to list pods for sriov-device-plugin")
+				return false, err
+			}
+			log.WithField("ready", ready).WithField("expected", replicas).Info("waiting for replacement of device plugin pod")
+			return ready >= replicas, nil
+		}
+	case "DaemonSet":
+		replaced, notReplaced = d.waitForDaemonSetPod(ctx, pod, log)
+	default:
+		replaced = d.waitForDevicePluginRestart(ctx, pod.Name)
+	}
+
+	log.Info("restarting device plugin")
+	deleteCtx, cancel := withAPICallTimeout(ctx)
+	defer cancel()
+	if err := d.Delete(deleteCtx, pod, &client.DeleteOptions{}); err != nil {
+		return errors.Wrap(err, "failed to delete sriov-device-plugin-daemonset pod")
+	}
+
+	backoff := wait.Backoff{Steps: 300, Duration: 1 * time.Second, Factor: 1}
+	err := wait.ExponentialBackoffWithContext(ctx, backoff, replaced)
+	if err == wait.ErrWaitTimeout || errors.Is(err, context.DeadlineExceeded) {
+		return notReplaced()
+	}
+	return err
+}
+
+// daemonSetTemplateGenerationLabel is set by DaemonSet controller on its pods to generation of the template they were
+// created from, which DaemonSet reports in appsv1.DeprecatedTemplateGeneration annotation
+const daemonSetTemplateGenerationLabel = "pod-template-generation"
+
+// waitForDaemonSetPod returns condition satisfied once deleted DaemonSet pod is replaced, together with error reported
+// when it is not. DaemonSet may be in the middle of rolling update, when pod of the old template may be still
+// terminating and the new one may be created by the rollout rather than in reaction to the deletion, so only ready pod
+// of the node created from the current template generation is accepted. When the replacement is not ready in time
+// while the rollout is in progress, the rollout is reported as broken instead of the restart.
+func (d *devicePluginController) waitForDaemonSetPod(ctx context.Context, pod *corev1.Pod, log *logrus.Entry) (wait.ConditionFunc, func() error) {
+	owner := metav1.GetControllerOf(pod)
+	key := types.NamespacedName{Namespace: pod.Namespace, Name: owner.Name}
+	anyReplacement := d.waitForDevicePluginRestart(ctx, pod.Name)
+	// daemonSet is the last observed state of the DaemonSet, nil when it could not be read
+	var daemonSet *appsv1.DaemonSet
+
+	replaced := func() (bool, error) {
+		current := &appsv1.DaemonSet{}
+		getCtx, cancel := withAPICallTimeout(ctx)
+		defer cancel()
+		if err := d.Get(getCtx, key, current); err != nil {
+			log.WithError(err).Warn("failed to get DaemonSet of device plugin - template generation of replacement is not checked")
+			daemonSet = nil
+			return anyReplacement()
+		}
+		daemonSet = current
+
+		pods, err := d.listDevicePluginPods(ctx)
+		if err != nil {
+			log.WithError(err).Error("failed to list pods for sriov-device-plugin")
+			return false, err
+		}
+		// generation of DaemonSet is bumped by changes outside of the template as well, e.g. of update strategy
+		generation, ok := current.Annotations[appsv1.DeprecatedTemplateGeneration]
+		if !ok {
+			log.Warn("DaemonSet of device plugin does not report its template generation - template generation of replacement is not checked")
+			return anyReplacement()
+		}
+		for _, p := range pods {
+			if controller := metav1.GetControllerOf(&p); p.Name == pod.Name || p.DeletionTimestamp != nil ||
+				controller == nil || controller.UID != owner.UID {
+				continue
+			}
+			if p.Labels[daemonSetTemplateGenerationLabel] != generation {
+				log.WithField("replacement", p.Name).WithField("templateGeneration", p.Labels[daemonSetTemplateGenerationLabel]).
+					WithField("currentGeneration", generation).Info("ignoring device plugin pod of previous template generation")
+				continue
+			}
+			if isReady(p) {
+				log.WithField("replacement", p.Name).Info("device-plugin is running")
+				return true, nil
+			}
+		}
+		log.WithField("templateGeneration", generation).Info("waiting for replacement of device plugin pod")
+		return false, nil
+	}
+
+	notReplaced := func() error {
+		if daemonSet != nil && daemonSet.Annotations[appsv1.DeprecatedTemplateGeneration] != "" && daemonSetRolloutInProgress(daemonSet) {
+			return fmt.Errorf("rollout of sriov-device-plugin DaemonSet %s is broken - %d of %d pods are updated to "+
+				"template generation %s and none of them became ready on the node within specified time",
+				daemonSet.Name, daemonSet.Status.UpdatedNumberScheduled, daemonSet.Status.DesiredNumberScheduled,
+				daemonSet.Annotations[appsv1.DeprecatedTemplateGeneration])
+		}
+		return fmt.Errorf("failed to restart sriov-device-plugin (owner kind: DaemonSet) - deleted pod was not " +
+			"replaced with ready one within specified time")
+	}
+	return replaced, notReplaced
+}
+
+// daemonSetRolloutInProgress tells whether DaemonSet has pods not updated to its current template yet
+func daemonSetRolloutInProgress(ds *appsv1.DaemonSet) bool {
+	return ds.Status.ObservedGeneration < ds.Generation || ds.Status.UpdatedNumberScheduled < ds.Status.DesiredNumberScheduled
+}
+
+// countControlledPods counts device plugin pods of the namespace controlled by owner, except for the excluded one
+func (d *devicePluginController) countControlledPods(ctx context.Context, owner types.UID, excluded string, readyOnly bool) (int, error) {
+	pods := &corev1.PodList{}
+	listCtx, cancel := withAPICallTimeout(ctx)
+	defer cancel()
+	if err := d.List(listCtx, pods, client.InNamespace(d.nodeNameRef.Namespace), devicePluginSelector); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if controller := metav1.GetControllerOf(pod); controller == nil || controller.UID != owner || pod.Name == excluded {
 			continue
 		}
-		if err := d.Delete(context.TODO(), &pod, &client.DeleteOptions{}); err != nil {
-			return errors.Wrap(err, "failed to delete sriov-device-plugin-daemonset pod")
+		if !readyOnly || isReady(*pod) {
+			count++
 		}
+	}
+	return count, nil
+}
+
+// listDevicePluginPods returns device plugin pods scheduled on this node; they are filtered by the API server (or by
+// the index of the cache), not by listing all pods of the namespace
+func (d *devicePluginController) listDevicePluginPods(ctx context.Context) ([]corev1.Pod, error) {
+	pods := &corev1.PodList{}
+	listCtx, cancel := withAPICallTimeout(ctx)
+	defer cancel()
+
+	err := d.List(listCtx, pods,
+		client.InNamespace(d.nodeNameRef.Namespace),
+		devicePluginSelector,
+		client.MatchingFields{podNodeNameField: d.nodeNameRef.Name})
+	if err != nil {
+		return nil, err
+	}
 
-		backoff := wait.Backoff{Steps: 300, Duration: 1 * time.Second, Factor: 1}
-		err = wait.ExponentialBackoff(backoff, d.waitForDevicePluginRestart(pod.Name))
-		if err == wait.ErrWaitTimeout {
-			return fmt.Errorf("failed to restart sriov-device-plugin within specified time")
+	// clients which do not support field selectors return pods of other nodes as well
+	var nodePods []corev1.Pod
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName == d.nodeNameRef.Name {
+			nodePods = append(nodePods, pod)
 		}
-		return err
 	}
-	return nil
+	return nodePods, nil
 }
 
-func (d *devicePluginController) waitForDevicePluginRestart(oldPodName string) func() (bool, error) {
+func (d *devicePluginController) waitForDevicePluginRestart(ctx context.Context, oldPodName string) func() (bool, error) {
 	return func() (bool, error) {
-		pods := &corev1.PodList{}
-
-		err := d.List(context.TODO(), pods,
-			client.InNamespace(d.nodeNameRef.Namespace),
-			&client.MatchingLabels{"app": "sriov-device-plugin-daemonset"})
+		pods, err := d.listDevicePluginPods(ctx)
 		if err != nil {
 			d.log.WithError(err).Error("failed to list pods for sriov-device-plugin")
 			return false, err
 		}
 
-		for _, pod := range pods.Items {
-			if pod.Spec.NodeName == d.nodeNameRef.Name && pod.Name != oldPodName && isReady(pod) {
+		for _, pod := range pods {
+			if pod.Name != oldPodName && isReady(pod) {
 				d.log.Info("device-plugin is running")
 				return true, nil
 			}
@@ -0,0 +1,290 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+// controlledBy returns owner references of pod managed by controller of given kind
+func controlledBy(kind, name string) []metav1.OwnerReference {
+	controller := true
+	return []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: kind, Name: name, UID: types.UID(kind + "-" + name), Controller: &controller}}
+}
+
+// serverSideFilteringClient filters pods by field selector like API server does (fake client ignores field
+// selectors) and recreates deleted device plugin pods like their controller does
+type serverSideFilteringClient struct {
+	client.Client
+	lists []client.ListOptions
+	// notReplaced disables recreation of deleted pods
+	notReplaced bool
+	// replacementLabels are set on recreated pods in addition to labels of the deleted one
+	replacementLabels map[string]string
+	// replacementNotReady makes recreated pods not ready
+	replacementNotReady bool
+}
+
+func (c *serverSideFilteringClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	listOpts := client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+	c.lists = append(c.lists, listOpts)
+	if err := c.Client.List(ctx, list, opts...); err != nil {
+		return err
+	}
+
+	pods := list.(*corev1.PodList)
+	var filtered []corev1.Pod
+	for _, pod := range pods.Items {
+		if listOpts.FieldSelector == nil || listOpts.FieldSelector.Matches(fields.Set{podNodeNameField: pod.Spec.NodeName}) {
+			filtered = append(filtered, pod)
+		}
+	}
+	pods.Items = filtered
+	return nil
+}
+
+func (c *serverSideFilteringClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	if err := c.Client.Delete(ctx, obj, opts...); err != nil || c.notReplaced {
+		return err
+	}
+	replacement := obj.(*corev1.Pod).DeepCopy()
+	replacement.ObjectMeta = metav1.ObjectMeta{Name: replacement.Name + "-new", Namespace: replacement.Namespace, Labels: replacement.Labels,
+		OwnerReferences: replacement.OwnerReferences}
+	for key, value := range c.replacementLabels {
+		replacement.Labels[key] = value
+	}
+	replacement.Status = corev1.PodStatus{Phase: corev1.PodRunning, Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}}
+	if c.replacementNotReady {
+		replacement.Status = corev1.PodStatus{Phase: corev1.PodPending}
+	}
+	return c.Client.Create(ctx, replacement)
+}
+
+var _ = Describe("devicePluginController.RestartDevicePlugin", func() {
+	var (
+		c           *serverSideFilteringClient
+		recorder    *record.FakeRecorder
+		nodeNameRef = types.NamespacedName{Name: "worker", Namespace: "testNamespace"}
+	)
+
+	pod := func(name, nodeName string, labels map[string]string, owners ...metav1.OwnerReference) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: nodeNameRef.Namespace, Labels: labels, OwnerReferences: owners},
+			Spec:       corev1.PodSpec{NodeName: nodeName},
+		}
+	}
+
+	readyPod := func(name, nodeName string, labels map[string]string, owners ...metav1.OwnerReference) *corev1.Pod {
+		p := pod(name, nodeName, labels, owners...)
+		p.Status = corev1.PodStatus{Phase: corev1.PodRunning, Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}}
+		return p
+	}
+
+	restart := func() error {
+		return NewDevicePluginController(c, utils.NewLogger(), nodeNameRef, recorder).RestartDevicePlugin(context.TODO())
+	}
+
+	podNames := func() []string {
+		pods := &corev1.PodList{}
+		Expect(c.Client.List(context.TODO(), pods)).To(Succeed())
+		var names []string
+		for _, p := range pods.Items {
+			names = append(names, p.Name)
+		}
+		return names
+	}
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(corev1.AddToScheme(scheme)).To(Succeed())
+		Expect(appsv1.AddToScheme(scheme)).To(Succeed())
+		c = &serverSideFilteringClient{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			pod("sriov-device-plugin-other", "other-worker", devicePluginSelector),
+			pod("workload", nodeNameRef.Name, map[string]string{"app": "workload"}),
+		).Build()}
+		recorder = record.NewFakeRecorder(10)
+	})
+
+	It("deletes only device plugin pod of this node, looked up with server-side filtering", func() {
+		Expect(c.Create(context.TODO(), pod("sriov-device-plugin-abcde", nodeNameRef.Name, devicePluginSelector,
+			controlledBy("DaemonSet", "sriov-device-plugin")...))).To(Succeed())
+
+		Expect(restart()).To(Succeed())
+
+		Expect(podNames()).To(ConsistOf("sriov-device-plugin-other", "workload", "sriov-device-plugin-abcde-new"))
+		Expect(c.lists).ToNot(BeEmpty())
+		for _, list := range c.lists {
+			Expect(list.Namespace).To(Equal(nodeNameRef.Namespace))
+			Expect(list.LabelSelector.String()).To(Equal("app=sriov-device-plugin-daemonset"))
+			Expect(list.FieldSelector.String()).To(Equal("spec.nodeName=worker"))
+		}
+	})
+
+	It("does nothing when device plugin is not running on this node", func() {
+		Expect(restart()).To(Succeed())
+
+		Expect(podNames()).To(ConsistOf("sriov-device-plugin-other", "workload"))
+	})
+
+	It("skips pods of other nodes returned by clients ignoring field selectors", func() {
+		plain := c.Client
+		Expect(plain.Create(context.TODO(), pod("sriov-device-plugin-abcde", nodeNameRef.Name, devicePluginSelector))).To(Succeed())
+
+		pods, err := NewDevicePluginController(plain, utils.NewLogger(), nodeNameRef, recorder).listDevicePluginPods(context.TODO())
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pods).To(ConsistOf(HaveField("Name", "sriov-device-plugin-abcde")))
+	})
+
+	It("asks for manual restart instead of deleting pod which would not be recreated", func() {
+		for _, p := range []*corev1.Pod{
+			pod("sriov-device-plugin-plain", nodeNameRef.Name, devicePluginSelector),
+			// static pod is mirrored by kubelet, deletion of the mirror pod does not restart it
+			pod("sriov-device-plugin-worker", nodeNameRef.Name, devicePluginSelector,
+				metav1.OwnerReference{APIVersion: "v1", Kind: "Node", Name: nodeNameRef.Name, Controller: pointer.Bool(true)}),
+		} {
+			Expect(c.Create(context.TODO(), p)).To(Succeed())
+		}
+
+		Expect(restart()).To(Succeed())
+
+		Expect(podNames()).To(ConsistOf("sriov-device-plugin-other", "workload", "sriov-device-plugin-plain", "sriov-device-plugin-worker"))
+		Expect(recorder.Events).To(HaveLen(2))
+		Expect([]string{<-recorder.Events, <-recorder.Events}).To(ConsistOf(
+			HavePrefix("Warning DevicePluginRestartRequired device plugin pod (owner kind: none) is not recreated once deleted"),
+			HavePrefix("Warning DevicePluginRestartRequired device plugin pod (owner kind: Node) is not recreated once deleted")))
+	})
+
+	It("waits until replica count of ReplicaSet managing the pod is restored", func() {
+		// pod of Deployment, its replica on other node is counted as well
+		labels := map[string]string{"app": "sriov-device-plugin-daemonset", appsv1.DefaultDeploymentUniqueLabelKey: "7d4b9c"}
+		owner := controlledBy("ReplicaSet", "sriov-device-plugin-7d4b9c")
+		Expect(c.Create(context.TODO(), readyPod("sriov-device-plugin-7d4b9c-abcde", nodeNameRef.Name, labels, owner...))).To(Succeed())
+		Expect(c.Create(context.TODO(), readyPod("sriov-device-plugin-7d4b9c-fghij", "other-worker", labels, owner...))).To(Succeed())
+
+		Expect(restart()).To(Succeed())
+
+		Expect(podNames()).To(ConsistOf("sriov-device-plugin-other", "workload", "sriov-device-plugin-7d4b9c-fghij",
+			"sriov-device-plugin-7d4b9c-abcde-new"))
+		Expect(recorder.Events).To(BeEmpty())
+	})
+
+	It("fails when ReplicaSet does not replace deleted pod", func() {
+		owner := controlledBy("ReplicaSet", "sriov-device-plugin")
+		Expect(c.Create(context.TODO(), readyPod("sriov-device-plugin-abcde", nodeNameRef.Name, devicePluginSelector, owner...))).To(Succeed())
+		Expect(c.Create(context.TODO(), readyPod("sriov-device-plugin-fghij", "other-worker", devicePluginSelector, owner...))).To(Succeed())
+		c.notReplaced = true
+
+		ctx, cancel := context.WithTimeout(context.TODO(), 100*time.Millisecond)
+		defer cancel()
+		Expect(NewDevicePluginController(c, utils.NewLogger(), nodeNameRef, recorder).RestartDevicePlugin(ctx)).ToNot(Succeed())
+		Expect(podNames()).To(ConsistOf("sriov-device-plugin-other", "workload", "sriov-device-plugin-fghij"))
+	})
+
+	Context("pod of DaemonSet", func() {
+		daemonSet := func(generation int64, updated, desired int32) *appsv1.DaemonSet {
+			return &appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "sriov-device-plugin", Namespace: nodeNameRef.Namespace, Generation: generation,
+					Annotations: map[string]string{appsv1.DeprecatedTemplateGeneration: strconv.FormatInt(generation, 10)}},
+				Status: appsv1.DaemonSetStatus{ObservedGeneration: generation, UpdatedNumberScheduled: updated,
+					DesiredNumberScheduled: desired},
+			}
+		}
+		templateGeneration := func(generation string) map[string]string {
+			return map[string]string{"app": "sriov-device-plugin-daemonset", daemonSetTemplateGenerationLabel: generation}
+		}
+		restartWithTimeout := func() error {
+			ctx, cancel := context.WithTimeout(context.TODO(), 100*time.Millisecond)
+			defer cancel()
+			return NewDevicePluginController(c, utils.NewLogger(), nodeNameRef, recorder).RestartDevicePlugin(ctx)
+		}
+
+		It("waits for pod of current template generation during rolling update", func() {
+			Expect(c.Create(context.TODO(), daemonSet(2, 1, 3))).To(Succeed())
+			Expect(c.Create(context.TODO(), readyPod("sriov-device-plugin-abcde", nodeNameRef.Name, templateGeneration("1"),
+				controlledBy("DaemonSet", "sriov-device-plugin")...))).To(Succeed())
+			c.replacementLabels = templateGeneration("2")
+
+			Expect(restartWithTimeout()).To(Succeed())
+			Expect(podNames()).To(ContainElement("sriov-device-plugin-abcde-new"))
+		})
+
+		It("does not accept ready pod of previous template generation", func() {
+			Expect(c.Create(context.TODO(), daemonSet(2, 3, 3))).To(Succeed())
+			Expect(c.Create(context.TODO(), readyPod("sriov-device-plugin-abcde", nodeNameRef.Name, templateGeneration("1"),
+				controlledBy("DaemonSet", "sriov-device-plugin")...))).To(Succeed())
+
+			err := restartWithTimeout()
+			Expect(err).To(MatchError("failed to restart sriov-device-plugin (owner kind: DaemonSet) - deleted pod was not " +
+				"replaced with ready one within specified time"))
+		})
+
+		It("reports broken rollout when pod of current template generation does not become ready", func() {
+			Expect(c.Create(context.TODO(), daemonSet(2, 1, 3))).To(Succeed())
+			Expect(c.Create(context.TODO(), readyPod("sriov-device-plugin-abcde", nodeNameRef.Name, templateGeneration("1"),
+				controlledBy("DaemonSet", "sriov-device-plugin")...))).To(Succeed())
+			c.replacementLabels = templateGeneration("2")
+			c.replacementNotReady = true
+
+			err := restartWithTimeout()
+			Expect(err).To(MatchError("rollout of sriov-device-plugin DaemonSet sriov-device-plugin is broken - 1 of 3 pods " +
+				"are updated to template generation 2 and none of them became ready on the node within specified time"))
+		})
+
+		It("compares pods with template generation rather than with generation of DaemonSet", func() {
+			ds := daemonSet(3, 3, 3)
+			ds.Annotations[appsv1.DeprecatedTemplateGeneration] = "2"
+			Expect(c.Create(context.TODO(), ds)).To(Succeed())
+			Expect(c.Create(context.TODO(), readyPod("sriov-device-plugin-abcde", nodeNameRef.Name, templateGeneration("2"),
+				controlledBy("DaemonSet", "sriov-device-plugin")...))).To(Succeed())
+			c.replacementLabels = templateGeneration("2")
+
+			Expect(restartWithTimeout()).To(Succeed())
+			Expect(podNames()).To(ContainElement("sriov-device-plugin-abcde-new"))
+		})
+
+		It("reports restart not completing when DaemonSet is rolled out", func() {
+			Expect(c.Create(context.TODO(), daemonSet(2, 3, 3))).To(Succeed())
+			Expect(c.Create(context.TODO(), readyPod("sriov-device-plugin-abcde", nodeNameRef.Name, templateGeneration("2"),
+				controlledBy("DaemonSet", "sriov-device-plugin")...))).To(Succeed())
+			c.notReplaced = true
+
+			err := restartWithTimeout()
+			Expect(err).To(MatchError(ContainSubstring("deleted pod was not replaced with ready one")))
+			Expect(podNames()).To(ConsistOf("sriov-device-plugin-other", "workload"))
+		})
+	})
+
+	It("detects kind of controller managing the pod", func() {
+		deploymentLabels := map[string]string{appsv1.DefaultDeploymentUniqueLabelKey: "7d4b9c"}
+		for expected, p := range map[string]*corev1.Pod{
+			"DaemonSet":  pod("a", nodeNameRef.Name, nil, controlledBy("DaemonSet", "sriov-device-plugin")...),
+			"Deployment": pod("b", nodeNameRef.Name, deploymentLabels, controlledBy("ReplicaSet", "sriov-device-plugin-7d4b9c")...),
+			"ReplicaSet": pod("c", nodeNameRef.Name, deploymentLabels, controlledBy("ReplicaSet", "sriov-device-plugin")...),
+			"Node": pod("d", nodeNameRef.Name, nil,
+				metav1.OwnerReference{APIVersion: "v1", Kind: "Node", Name: nodeNameRef.Name, Controller: pointer.Bool(true)}),
+			ownerKindNone: pod("e", nodeNameRef.Name, nil, metav1.OwnerReference{APIVersion: "v1", Kind: "ConfigMap", Name: "not-a-controller"}),
+		} {
+			Expect(devicePluginOwnerKind(p)).To(Equal(expected), p.Name)
+		}
+	})
+})
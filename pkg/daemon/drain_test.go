@@ -0,0 +1,168 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/drainhelper"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("NodeConfigReconciler.Reconcile within drain", func() {
+	var (
+		fakeClient  client.Client
+		nodeNameRef = types.NamespacedName{Name: "worker", Namespace: "testNamespace"}
+		drainer     *drainhelper.FakeDrainer
+		configured  bool
+		recorder    *record.FakeRecorder
+		// customize is applied to spec of node config before reconcile
+		customize func(spec *sriovv2.SriovFecNodeConfigSpec)
+	)
+
+	reconcile := func(drainSkip bool) *metav1.Condition {
+		nc := &sriovv2.SriovFecNodeConfig{}
+		Expect(fakeClient.Get(context.TODO(), nodeNameRef, nc)).To(Succeed())
+		nc.Spec.DrainSkip = drainSkip
+		if customize != nil {
+			customize(&nc.Spec)
+		}
+		Expect(fakeClient.Update(context.TODO(), nc)).To(Succeed())
+
+		reconciler, err := NewNodeConfigReconciler(fakeClient, drainer, nodeNameRef,
+			testConfigurerProto{configureNodeFunction: func(sriovv2.SriovFecNodeConfigSpec) error {
+				configured = true
+				return nil
+			}}, nil,
+			func(context.Context) error { return nil },
+			recorder, nil)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: nodeNameRef})
+		Expect(err).ToNot(HaveOccurred())
+		reconciler.reschedulingReports.Wait()
+
+		Expect(fakeClient.Get(context.TODO(), nodeNameRef, nc)).To(Succeed())
+		return nc.FindCondition(ConditionConfigured)
+	}
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(sriovv2.AddToScheme(scheme)).To(Succeed())
+		Expect(vrbv1.AddToScheme(scheme)).To(Succeed())
+
+		configPath = "testdata/accelerators.json"
+		VrbconfigPath = "testdata/accelerators_vrb.json"
+		procCmdlineFilePath = "testdata/cmdline_test"
+		sysLockdownFilePath = filepath.Join(testTmpFolder, "lockdown")
+		Expect(os.WriteFile(sysLockdownFilePath, []byte("[none] integrity confidentiality"), 0644)).To(Succeed())
+
+		getSriovInventory = func(_ context.Context, log *logrus.Logger) (*sriovv2.NodeInventory, error) {
+			return &sriovv2.NodeInventory{
+				SriovAccelerators: []sriovv2.SriovAccelerator{{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, MaxVFs: 10}},
+			}, nil
+		}
+		VrbgetSriovInventory = func(_ context.Context, log *logrus.Logger) (*vrbv1.NodeInventory, error) {
+			return &vrbv1.NodeInventory{}, nil
+		}
+
+		fakeClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&sriovv2.SriovFecNodeConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace},
+				Spec: sriovv2.SriovFecNodeConfigSpec{
+					PhysicalFunctions: []sriovv2.PhysicalFunctionConfigExt{{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, VFDriver: utils.IGB_UIO, VFAmount: 1}},
+				},
+			},
+			&vrbv1.SriovVrbNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace}},
+		).Build()
+		drainer = &drainhelper.FakeDrainer{}
+		configured = false
+		recorder = record.NewFakeRecorder(10)
+		customize = nil
+	})
+
+	AfterEach(func() {
+		getSriovInventory = GetSriovInventory
+		VrbgetSriovInventory = VrbGetSriovInventory
+		sysLockdownFilePath = "/sys/kernel/security/lockdown"
+	})
+
+	It("drains the node and uncordons it after configuration", func() {
+		condition := reconcile(false)
+
+		Expect(configured).To(BeTrue())
+		Expect(condition.Reason).To(Equal(string(ConfigurationSucceeded)))
+		Expect(drainer.Runs()).To(Equal([]drainhelper.FakeRun{{Drain: true, Executed: true, Uncordoned: true,
+			Steps: []string{"node drained with pod eviction"}}}))
+	})
+
+	It("drains the node by deleting pods when requested", func() {
+		drainer.Rescheduling = &drainhelper.ReschedulingSummary{Evicted: 1, Rescheduled: 1}
+		gracePeriod := int64(0)
+		customize = func(spec *sriovv2.SriovFecNodeConfigSpec) {
+			spec.DrainEvictionMode = sriovv2.DrainEvictionModeDelete
+			spec.DrainGracePeriodSeconds = &gracePeriod
+		}
+
+		Expect(reconcile(false).Reason).To(Equal(string(ConfigurationSucceeded)))
+		Expect(drainer.Runs()).To(HaveLen(1))
+		Expect(drainer.Runs()[0].Options).To(Equal(drainhelper.DrainOptions{EvictionMode: drainhelper.EvictionModeDelete, GracePeriodSeconds: &gracePeriod}))
+		Expect(drainer.Runs()[0].Steps).To(Equal([]string{"node drained with pod deletion with 0s grace period"}))
+		Expect(recorder.Events).To(Receive(Equal("Normal EvictedPodsRescheduled 1 pods evicted, 1 rescheduled, 0 pending (pod deletion with 0s grace period)")))
+	})
+
+	It("configures without drain when drainSkip is set", func() {
+		condition := reconcile(true)
+
+		Expect(configured).To(BeTrue())
+		Expect(condition.Reason).To(Equal(string(ConfigurationSucceeded)))
+		Expect(drainer.Runs()).To(Equal([]drainhelper.FakeRun{{Drain: false, Executed: true}}))
+	})
+
+	It("fails configuration without touching accelerators when drain fails", func() {
+		drainer.DrainErr = errors.New("cannot evict pod as it would violate the pod's disruption budget")
+
+		condition := reconcile(false)
+
+		Expect(configured).To(BeFalse())
+		Expect(drainer.Runs()).To(Equal([]drainhelper.FakeRun{{Drain: true}}))
+		Expect(condition.Reason).To(Equal(string(ConfigurationFailed)))
+		Expect(condition.Message).To(ContainSubstring("disruption budget"))
+	})
+
+	It("fails configuration when node cannot be uncordoned", func() {
+		drainer.UncordonErr = errors.New("failed to uncordon node")
+
+		condition := reconcile(false)
+
+		Expect(configured).To(BeTrue())
+		Expect(condition.Reason).To(Equal(string(ConfigurationFailed)))
+		Expect(condition.Message).To(ContainSubstring("failed to uncordon node"))
+	})
+
+	It("exposes rescheduling of pods evicted by the drainer", func() {
+		drainer.Rescheduling = &drainhelper.ReschedulingSummary{Evicted: 3, Rescheduled: 2, Pending: []string{"ns/pod"}}
+
+		Expect(reconcile(false).Reason).To(Equal(string(ConfigurationSucceeded)))
+
+		nc := &sriovv2.SriovFecNodeConfig{}
+		Expect(fakeClient.Get(context.TODO(), nodeNameRef, nc)).To(Succeed())
+		Expect(nc.Status.EvictionSummary).To(Equal("3 pods evicted, 2 rescheduled, 1 pending"))
+	})
+})
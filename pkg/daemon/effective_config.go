@@ -0,0 +1,115 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/drainhelper"
+)
+
+// EffectiveConfigMapName is a name of ConfigMap which holds effective configuration of each daemon under node name key
+const EffectiveConfigMapName = "sriov-fec-daemon-effective-config"
+
+// EffectiveConfig is a configuration resolved by daemon on startup
+type EffectiveConfig struct {
+	ResyncPeriod            string `json:"resyncPeriod"`
+	MetricGatherInterval    string `json:"metricGatherInterval"`
+	DrainTimeout            string `json:"drainTimeout"`
+	LeaseDuration           string `json:"leaseDuration"`
+	RenewDeadline           string `json:"renewDeadline"`
+	RetryPeriod             string `json:"retryPeriod"`
+	RescheduleTimeout       string `json:"rescheduleTimeout"`
+	RebootLeaseDuration     string `json:"rebootLeaseDuration"`
+	CordonOverdueThreshold  string `json:"cordonOverdueThreshold"`
+	DegradedRequeueInterval string `json:"degradedRequeueInterval"`
+	DevicePluginSelector    string `json:"devicePluginSelector"`
+	ClusterType             string `json:"clusterType"`
+	FeatureGates            string `json:"featureGates"`
+	StateDir                string `json:"stateDir"`
+	PfBBConfigOutputLimit   string `json:"pfBBConfigOutputLimit"`
+	RebootLockName          string `json:"rebootLockName"`
+	RebootLockTimeout       string `json:"rebootLockTimeout"`
+	RebootLockDuration      string `json:"rebootLockDuration"`
+}
+
+func NewEffectiveConfig(drainSettings drainhelper.Settings, isSingleNodeCluster bool, featureGates FeatureGates, log *logrus.Logger) EffectiveConfig {
+	clusterType := "multi-node"
+	if isSingleNodeCluster {
+		clusterType = "single-node"
+	}
+
+	return EffectiveConfig{
+		ResyncPeriod:            resyncPeriod.Get().String(),
+		MetricGatherInterval:    metricGatherInterval(log).String(),
+		DrainTimeout:            drainSettings.DrainTimeout.String(),
+		LeaseDuration:           drainSettings.LeaseDuration.String(),
+		RenewDeadline:           drainSettings.RenewDeadline.String(),
+		RetryPeriod:             drainSettings.RetryPeriod.String(),
+		RescheduleTimeout:       drainSettings.RescheduleTimeout.String(),
+		RebootLeaseDuration:     drainSettings.RebootLeaseDuration.String(),
+		CordonOverdueThreshold:  cordonOverdueThreshold(log).String(),
+		DegradedRequeueInterval: degradedRequeueInterval(log).String(),
+		DevicePluginSelector:    labels.SelectorFromSet(labels.Set(devicePluginSelector)).String(),
+		ClusterType:             clusterType,
+		FeatureGates:            featureGates.String(),
+		StateDir:                workdir,
+		PfBBConfigOutputLimit:   fmt.Sprintf("%dKB", pfBBConfigOutputLimit(log)/1024),
+		RebootLockName:          rebootLockName(),
+		RebootLockTimeout:       rebootLockTimeout(log).String(),
+		RebootLockDuration:      rebootLockDuration(log).String(),
+	}
+}
+
+// PublishEffectiveConfig stores effective configuration under nodeName key of EffectiveConfigMapName ConfigMap.
+// It is meant to be called once on daemon's startup. Object is not touched if stored configuration is up to date,
+// otherwise nodeName key is updated. Conflicting writes of daemons running on other nodes are retried.
+func PublishEffectiveConfig(c client.Client, namespace, nodeName string, cfg EffectiveConfig, log *logrus.Logger) error {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	value := string(data)
+
+	retriable := func(err error) bool {
+		return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err)
+	}
+
+	return retry.OnError(retry.DefaultRetry, retriable, func() error {
+		cm := &corev1.ConfigMap{}
+		err := c.Get(context.TODO(), client.ObjectKey{Namespace: namespace, Name: EffectiveConfigMapName}, cm)
+		if apierrors.IsNotFound(err) {
+			log.WithField("configMap", EffectiveConfigMapName).Info("creating effective config")
+			return c.Create(context.TODO(), &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: EffectiveConfigMapName},
+				Data:       map[string]string{nodeName: value},
+			})
+		}
+		if err != nil {
+			return err
+		}
+
+		if cm.Data[nodeName] == value {
+			log.WithField("configMap", EffectiveConfigMapName).Info("effective config is up to date")
+			return nil
+		}
+
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[nodeName] = value
+		log.WithField("configMap", EffectiveConfigMapName).Info("updating effective config")
+		return c.Update(context.TODO(), cm)
+	})
+}
@@ -0,0 +1,81 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/drainhelper"
+)
+
+var _ = Describe("EffectiveConfig", func() {
+	const namespace = "default"
+
+	var (
+		log = logrus.New()
+		cfg = NewEffectiveConfig(drainhelper.Settings{
+			DrainTimeout:      90 * time.Second,
+			LeaseDuration:     137 * time.Second,
+			RenewDeadline:     107 * time.Second,
+			RetryPeriod:       26 * time.Second,
+			RescheduleTimeout: 120 * time.Second,
+		}, false, FeatureGates{VFPodUsage: true}, log)
+	)
+
+	getConfigMap := func(c client.Client) *corev1.ConfigMap {
+		cm := &corev1.ConfigMap{}
+		Expect(c.Get(context.TODO(), client.ObjectKey{Namespace: namespace, Name: EffectiveConfigMapName}, cm)).To(Succeed())
+		return cm
+	}
+
+	It("should resolve effective configuration", func() {
+		Expect(cfg.DrainTimeout).To(Equal("1m30s"))
+		Expect(cfg.LeaseDuration).To(Equal("2m17s"))
+		Expect(cfg.RescheduleTimeout).To(Equal("2m0s"))
+		Expect(cfg.ResyncPeriod).To(Equal(resyncPeriod.Get().String()))
+		Expect(cfg.DevicePluginSelector).To(Equal("app=sriov-device-plugin-daemonset"))
+		Expect(cfg.ClusterType).To(Equal("multi-node"))
+		Expect(cfg.PfBBConfigOutputLimit).To(Equal("4KB"))
+		Expect(cfg.FeatureGates).To(Equal("AERMonitoring=false,AuditLog=false,BBDevConfigMirror=false,DebugEndpoint=false,InventoryEnrichment=false,Telemetry=true,TolerateAbsentDevices=true,VFPodUsage=true"))
+	})
+
+	It("should create ConfigMap with configuration stored under node name", func() {
+		c := fake.NewClientBuilder().Build()
+		Expect(PublishEffectiveConfig(c, namespace, "worker", cfg, log)).To(Succeed())
+
+		stored := EffectiveConfig{}
+		Expect(json.Unmarshal([]byte(getConfigMap(c).Data["worker"]), &stored)).To(Succeed())
+		Expect(stored).To(Equal(cfg))
+	})
+
+	It("should keep configuration of other nodes and not write unchanged configuration", func() {
+		c := fake.NewClientBuilder().WithObjects(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: EffectiveConfigMapName},
+			Data:       map[string]string{"other": "{}"},
+		}).Build()
+
+		Expect(PublishEffectiveConfig(c, namespace, "worker", cfg, log)).To(Succeed())
+		cm := getConfigMap(c)
+		Expect(cm.Data).To(HaveKeyWithValue("other", "{}"))
+		Expect(cm.Data).To(HaveKey("worker"))
+
+		Expect(PublishEffectiveConfig(c, namespace, "worker", cfg, log)).To(Succeed())
+		Expect(getConfigMap(c).ResourceVersion).To(Equal(cm.ResourceVersion))
+
+		changed := cfg
+		changed.DrainTimeout = "5m0s"
+		Expect(PublishEffectiveConfig(c, namespace, "worker", changed, log)).To(Succeed())
+		Expect(getConfigMap(c).Data["worker"]).To(ContainSubstring(`"drainTimeout":"5m0s"`))
+	})
+})
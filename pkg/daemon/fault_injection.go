@@ -0,0 +1,29 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/faultinjection"
+)
+
+type cmdRunner func(ctx context.Context, args []string, log *logrus.Logger) (string, error)
+
+// withInjectedFaults wraps command runner, so that commands matching faults of fault injection file fail without
+// being executed
+func withInjectedFaults(run cmdRunner) cmdRunner {
+	return func(ctx context.Context, args []string, log *logrus.Logger) (string, error) {
+		if len(args) > 0 {
+			if err := faultinjection.Check(faultinjection.Exec, filepath.Base(args[0]), args...); err != nil {
+				log.WithField("cmd", args).WithError(err).Error("injected command failure")
+				return "", err
+			}
+		}
+		return run(ctx, args, log)
+	}
+}
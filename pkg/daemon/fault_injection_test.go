@@ -0,0 +1,343 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/drainhelper"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/faultinjection"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+// Reconcile flow runs with real NodeConfigurator on top of fake sysfs and fake client, while faults are injected
+// the same way as in the daemon - from file pointed to by SRIOV_FEC_FAULT_INJECTION env variable
+var _ = Describe("Fault injection", func() {
+	const (
+		pfPCIAddress = "0000:f7:00.0"
+		requestedVFs = 2
+	)
+
+	var (
+		fakeClient  client.Client
+		nodeNameRef = types.NamespacedName{Name: "worker", Namespace: "testNamespace"}
+		reconciler  *NodeConfigReconciler
+		root        string
+		executed    []string
+
+		originalSysBusPciDevices      = sysBusPciDevices
+		originalSysBusPciDrivers      = sysBusPciDrivers
+		originalWorkdir               = workdir
+		originalProcPath              = procPath
+		originalOwnProcPath           = ownProcPath
+		originalRunExecCmd            = runExecCmd
+		originalRunPfBBConfigCmd      = runPfBBConfigCmd
+		originalGetVFconfigured       = getVFconfigured
+		originalGetVFList             = getVFList
+		originalSupportedAccelerators = supportedAccelerators
+		originalSettleInterval        = inventorySettleInterval
+		originalBootIDFilePath        = bootIDFilePath
+	)
+
+	numVFs := func() int {
+		content, _ := os.ReadFile(filepath.Join(sysBusPciDevices, pfPCIAddress, vfNumFileDefault))
+		n, _ := strconv.Atoi(strings.TrimSpace(string(content)))
+		return n
+	}
+
+	vfAddresses := func() []string {
+		var vfs []string
+		for i := 0; i < numVFs(); i++ {
+			vfs = append(vfs, fmt.Sprintf("0000:f7:00.%d", i+1))
+		}
+		return vfs
+	}
+
+	injectFaults := func(faults string) {
+		path := filepath.Join(root, "faults.json")
+		Expect(os.WriteFile(path, []byte(faults), 0644)).To(Succeed())
+		Expect(os.Setenv(faultinjection.EnvVarName, path)).To(Succeed())
+		Expect(faultinjection.LoadFromEnv()).To(BeTrue())
+	}
+
+	nodeConfig := func() *sriovv2.SriovFecNodeConfig {
+		nc := new(sriovv2.SriovFecNodeConfig)
+		Expect(fakeClient.Get(context.TODO(), nodeNameRef, nc)).To(Succeed())
+		return nc
+	}
+
+	reconcileAndGetCondition := func() *metav1.Condition {
+		_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: nodeNameRef})
+		Expect(err).ToNot(HaveOccurred())
+		return nodeConfig().FindCondition(ConditionConfigured)
+	}
+
+	pfBBConfigExecuted := func() bool {
+		for _, cmd := range executed {
+			if strings.HasPrefix(cmd, "/sriov_workdir/pf_bb_config ") {
+				return true
+			}
+		}
+		return false
+	}
+
+	BeforeEach(func() {
+		var err error
+		root, err = os.MkdirTemp(testTmpFolder, "fault-injection")
+		Expect(err).ToNot(HaveOccurred())
+		executed = nil
+
+		// fake sysfs of ACC100 PF with VFs created on write to sriov_numvfs
+		sysBusPciDevices = filepath.Join(root, "devices")
+		sysBusPciDrivers = filepath.Join(root, "drivers")
+		Expect(createFiles(filepath.Join(sysBusPciDevices, pfPCIAddress), "driver_override", vfNumFileDefault, "reset")).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(sysBusPciDevices, pfPCIAddress, sriovOffsetFile), []byte("1\n"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(sysBusPciDevices, pfPCIAddress, sriovStrideFile), []byte("1\n"), 0644)).To(Succeed())
+		for i := 1; i <= requestedVFs; i++ {
+			Expect(createFiles(filepath.Join(sysBusPciDevices, fmt.Sprintf("0000:f7:00.%d", i)), "driver_override")).To(Succeed())
+		}
+		for _, driver := range []string{utils.PCI_PF_STUB_DASH, utils.VFIO_PCI} {
+			Expect(createFiles(filepath.Join(sysBusPciDrivers, driver), "bind", "unbind")).To(Succeed())
+		}
+		getVFconfigured = func(string) int { return numVFs() }
+		getVFList = func(string) ([]string, error) { return vfAddresses(), nil }
+		getSriovInventory = func(context.Context, *logrus.Logger) (*sriovv2.NodeInventory, error) {
+			acc := sriovv2.SriovAccelerator{VendorID: "8086", DeviceID: "0d5c", PCIAddress: pfPCIAddress, PFDriver: utils.PCI_PF_STUB_DASH, MaxVFs: 16, VFs: []sriovv2.VF{}}
+			for _, vf := range vfAddresses() {
+				acc.VFs = append(acc.VFs, sriovv2.VF{PCIAddress: vf, Driver: utils.VFIO_PCI, DeviceID: "0d5d"})
+			}
+			return &sriovv2.NodeInventory{SriovAccelerators: []sriovv2.SriovAccelerator{acc}}, nil
+		}
+		VrbgetSriovInventory = func(context.Context, *logrus.Logger) (*vrbv1.NodeInventory, error) {
+			return &vrbv1.NodeInventory{}, nil
+		}
+		supportedAccelerators = utils.AcceleratorDiscoveryConfig{
+			VendorID: map[string]string{"8086": "Intel Corporation"},
+			Devices:  map[string]string{"0d5c": "ACC100"},
+		}
+
+		// commands are executed by fake runner, which fault injection is layered on top of as in the daemon
+		fakeRunner := withInjectedFaults(func(_ context.Context, args []string, _ *logrus.Logger) (string, error) {
+			executed = append(executed, strings.Join(args, " "))
+			return "", nil
+		})
+		runExecCmd, runPfBBConfigCmd = fakeRunner, fakeRunner
+
+		Expect(os.MkdirAll(filepath.Join(root, "proc"), 0755)).To(Succeed())
+		procPath, ownProcPath = filepath.Join(root, "proc"), filepath.Join(root, "proc")
+		workdir = root
+		procCmdlineFilePath = "testdata/cmdline_test"
+		sysLockdownFilePath = filepath.Join(root, "lockdown")
+		Expect(os.WriteFile(sysLockdownFilePath, []byte("[none] integrity confidentiality"), 0644)).To(Succeed())
+		inventorySettleInterval = 10 * time.Millisecond
+		bootIDFilePath = filepath.Join(root, "boot_id")
+		Expect(os.WriteFile(bootIDFilePath, []byte("8f5d6e2a-0d3c-4d8e-9a43-1b2c3d4e5f60\n"), 0644)).To(Succeed())
+
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(sriovv2.AddToScheme(scheme)).To(Succeed())
+		Expect(vrbv1.AddToScheme(scheme)).To(Succeed())
+		sfnc := &sriovv2.SriovFecNodeConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace, Generation: 1},
+			Spec: sriovv2.SriovFecNodeConfigSpec{
+				PhysicalFunctions: []sriovv2.PhysicalFunctionConfigExt{{
+					PCIAddress: pfPCIAddress,
+					PFDriver:   utils.PCI_PF_STUB_DASH,
+					VFDriver:   utils.VFIO_PCI,
+					VFAmount:   requestedVFs,
+					BBDevConfig: sriovv2.BBDevConfig{ACC100: &sriovv2.ACC100BBDevConfig{
+						NumVfBundles: requestedVFs,
+						MaxQueueSize: 1024,
+						Uplink4G:     sriovv2.QueueGroupConfig{NumQueueGroups: 2, NumAqsPerGroups: 16, AqDepthLog2: 4},
+						Downlink4G:   sriovv2.QueueGroupConfig{NumQueueGroups: 2, NumAqsPerGroups: 16, AqDepthLog2: 4},
+						Uplink5G:     sriovv2.QueueGroupConfig{NumQueueGroups: 2, NumAqsPerGroups: 16, AqDepthLog2: 4},
+						Downlink5G:   sriovv2.QueueGroupConfig{NumQueueGroups: 2, NumAqsPerGroups: 16, AqDepthLog2: 4},
+					}},
+				}},
+			},
+		}
+		vrbnc := &vrbv1.SriovVrbNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace}}
+		fakeClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(sfnc, vrbnc).Build()
+
+		pfBBConfigController := &pfBBConfigController{log: utils.NewLogger(), fftUpdater: &fftUpdater{log: utils.NewLogger()}}
+		configurer := NewNodeConfigurator(utils.NewLogger(), pfBBConfigController, fakeClient, nodeNameRef, nil, nil)
+		reconciler = &NodeConfigReconciler{
+			Client:             fakeClient,
+			log:                utils.NewLogger(),
+			nodeNameRef:        nodeNameRef,
+			sriovfecconfigurer: configurer,
+			vrbconfigurer:      configurer,
+			// stands in for DrainHelper.Run, which consults the same faults before cordoning & draining the node
+			drainerAndExecute: func(_ context.Context, configure func(ctx context.Context) bool, drain bool) error {
+				if drain {
+					if err := faultinjection.Check(faultinjection.Drain, "", nodeNameRef.Name); err != nil {
+						return err
+					}
+				}
+				configure(context.TODO())
+				return nil
+			},
+			restartDevicePlugin: func(context.Context) error { return nil },
+			recorder:            record.NewFakeRecorder(10),
+		}
+	})
+
+	AfterEach(func() {
+		faultinjection.Reset()
+		Expect(os.Unsetenv(faultinjection.EnvVarName)).To(Succeed())
+		sysBusPciDevices = originalSysBusPciDevices
+		sysBusPciDrivers = originalSysBusPciDrivers
+		workdir = originalWorkdir
+		procPath = originalProcPath
+		ownProcPath = originalOwnProcPath
+		runExecCmd = originalRunExecCmd
+		runPfBBConfigCmd = originalRunPfBBConfigCmd
+		getVFconfigured = originalGetVFconfigured
+		getVFList = originalGetVFList
+		supportedAccelerators = originalSupportedAccelerators
+		inventorySettleInterval = originalSettleInterval
+		bootIDFilePath = originalBootIDFilePath
+		getSriovInventory = GetSriovInventory
+		VrbgetSriovInventory = VrbGetSriovInventory
+		sysLockdownFilePath = "/sys/kernel/security/lockdown"
+		Expect(os.RemoveAll(root)).To(Succeed())
+	})
+
+	It("should configure the node when no fault is injected", func() {
+		Expect(reconcileAndGetCondition().Reason).To(Equal(string(ConfigurationSucceeded)))
+		Expect(pfBBConfigExecuted()).To(BeTrue())
+		Expect(numVFs()).To(Equal(requestedVFs))
+		Expect(nodeConfig().Status.Warnings).To(BeEmpty())
+		Expect(nodeConfig().Status.PredictedVFs).To(HaveLen(1))
+	})
+
+	It("should fail configuration when critical step fails", func() {
+		injectFaults(fmt.Sprintf(`{"faults": [{"operation": "exec", "target": "setpci", "device": %q, "message": "setpci: no such device"}]}`, pfPCIAddress))
+
+		condition := reconcileAndGetCondition()
+		Expect(condition.Reason).To(Equal(string(ConfigurationFailed)))
+		Expect(condition.Message).To(ContainSubstring("setpci: no such device"))
+		Expect(pfBBConfigExecuted()).To(BeFalse())
+		Expect(nodeConfig().Status.Warnings).To(BeEmpty())
+	})
+
+	It("should configure the node with warnings when best-effort step fails", func() {
+		// SR-IOV capability is not exposed, so VF addresses cannot be predicted
+		Expect(os.Remove(filepath.Join(sysBusPciDevices, pfPCIAddress, sriovOffsetFile))).To(Succeed())
+
+		Expect(reconcileAndGetCondition().Reason).To(Equal(string(ConfigurationSucceeded)))
+		Expect(numVFs()).To(Equal(requestedVFs))
+		nc := nodeConfig()
+		Expect(nc.Status.PredictedVFs).To(BeEmpty())
+		Expect(nc.Status.Warnings).To(ConsistOf(HavePrefix("publish predicted VF addresses of 0000:f7:00.0 failed - ")))
+		Expect(reconciler.recorder.(*record.FakeRecorder).Events).To(Receive(HavePrefix("Warning ConfigurationWarnings publish predicted VF addresses")))
+
+		// warnings of the previous configuration are replaced
+		Expect(os.WriteFile(filepath.Join(sysBusPciDevices, pfPCIAddress, sriovOffsetFile), []byte("1\n"), 0644)).To(Succeed())
+		nc.Spec.PhysicalFunctions[0].VFAmount, nc.Spec.PhysicalFunctions[0].BBDevConfig.ACC100.NumVfBundles = 1, 1
+		Expect(fakeClient.Update(context.TODO(), nc)).To(Succeed())
+		Expect(reconcileAndGetCondition().Reason).To(Equal(string(ConfigurationSucceeded)))
+		Expect(nodeConfig().Status.Warnings).To(BeEmpty())
+	})
+
+	It("should fail configuration on drain timeout without touching the device", func() {
+		injectFaults(`{"faults": [{"operation": "drain", "message": "drain did not complete within 90s", "times": 1}]}`)
+
+		condition := reconcileAndGetCondition()
+		Expect(condition.Reason).To(Equal(string(ConfigurationFailed)))
+		Expect(condition.Message).To(ContainSubstring("drain did not complete within 90s"))
+		Expect(executed).To(BeEmpty())
+		Expect(numVFs()).To(BeZero())
+
+		Expect(reconcileAndGetCondition().Reason).To(Equal(string(ConfigurationSucceeded)))
+		Expect(numVFs()).To(Equal(requestedVFs))
+	})
+
+	It("should fail configuration when pf_bb_config crashes and recover on the next reconcile", func() {
+		injectFaults(fmt.Sprintf(`{"faults": [{"operation": "exec", "target": "pf_bb_config", "device": %q, "message": "signal: segmentation fault", "times": 1}]}`, pfPCIAddress))
+
+		condition := reconcileAndGetCondition()
+		Expect(condition.Reason).To(Equal(string(ConfigurationFailed)))
+		Expect(condition.Message).To(ContainSubstring("signal: segmentation fault"))
+		Expect(pfBBConfigExecuted()).To(BeFalse())
+		Expect(numVFs()).To(BeZero(), "VFs are created only after pf_bb_config succeeds")
+
+		Expect(reconcileAndGetCondition().Reason).To(Equal(string(ConfigurationSucceeded)))
+		Expect(pfBBConfigExecuted()).To(BeTrue())
+		Expect(numVFs()).To(Equal(requestedVFs))
+	})
+
+	It("should fail configuration while sysfs reports the device busy", func() {
+		injectFaults(fmt.Sprintf(`{"faults": [{"operation": "sysfsWrite", "target": "sriov_numvfs", "device": %q, "errno": "EBUSY"}]}`, pfPCIAddress))
+
+		for i := 0; i < 2; i++ {
+			condition := reconcileAndGetCondition()
+			Expect(condition.Reason).To(Equal(string(ConfigurationFailed)))
+			Expect(condition.Message).To(ContainSubstring("failed to set new amount of VFs (2) for PF (0000:f7:00.0)"))
+			Expect(condition.Message).To(ContainSubstring("device or resource busy"))
+			Expect(numVFs()).To(BeZero())
+		}
+	})
+
+	It("should resume configuration interrupted by lost drain lease", func() {
+		var steps []string
+		// stands in for DrainHelper.Run, which renews the lease on reported progress; lease is lost once PF is bound
+		reconciler.drainerAndExecute = func(_ context.Context, configure func(ctx context.Context) bool, drain bool) error {
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+			configure(drainhelper.WithProgressReporter(ctx, func(step string) {
+				steps = append(steps, step)
+				if step == pfPCIAddress+" "+applyStepPFBound {
+					cancel()
+				}
+			}))
+			return nil
+		}
+
+		condition := reconcileAndGetCondition()
+		Expect(condition.Reason).To(Equal(string(ConfigurationFailed)))
+		Expect(condition.Message).To(ContainSubstring("configuration interrupted, it will be resumed"))
+		Expect(steps).To(Equal([]string{"node drained with pod eviction", pfPCIAddress + " cleaned", pfPCIAddress + " pf-bound"}))
+		Expect(pfBBConfigExecuted()).To(BeFalse())
+
+		executed, steps = nil, nil
+		var inProgress *metav1.Condition
+		reconciler.drainerAndExecute = func(_ context.Context, configure func(ctx context.Context) bool, drain bool) error {
+			configure(drainhelper.WithProgressReporter(context.TODO(), func(step string) { steps = append(steps, step) }))
+			nc := new(sriovv2.SriovFecNodeConfig)
+			Expect(fakeClient.Get(context.TODO(), nodeNameRef, nc)).To(Succeed())
+			inProgress = nc.FindCondition(ConditionConfigured)
+			return nil
+		}
+
+		Expect(reconcileAndGetCondition().Reason).To(Equal(string(ConfigurationSucceeded)))
+		Expect(steps).To(Equal([]string{"node drained with pod eviction", pfPCIAddress + " pf-bb-config", pfPCIAddress + " vfs-created", pfPCIAddress + " done"}))
+		Expect(inProgress.Reason).To(Equal(string(ConfigurationInProgress)))
+		Expect(inProgress.Message).To(Equal("Configuration in progress: " + pfPCIAddress + " done"))
+		Expect(executed).ToNot(ContainElement(HavePrefix("modprobe")), "drivers were loaded before interruption")
+		Expect(pfBBConfigExecuted()).To(BeTrue())
+		Expect(numVFs()).To(Equal(requestedVFs))
+		Expect(applyCheckpointFile(hitlessUpdateKindFec)).ToNot(BeAnExistingFile())
+	})
+})
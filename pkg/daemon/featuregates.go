@@ -0,0 +1,144 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const featureGatesEnvVarName = "FEATURE_GATES"
+
+type FeatureGate string
+
+const (
+	// AERMonitoring enables collection of AER (PCIe errors) counters of configured PFs
+	AERMonitoring FeatureGate = "AERMonitoring"
+	// AuditLog enables recording of hardware-affecting actions (drain, driver bind, VF count change) in per-node audit log
+	AuditLog FeatureGate = "AuditLog"
+	// BBDevConfigMirror enables mirroring of the latest pf_bb_config cfg file of every PF into per-node ConfigMap
+	BBDevConfigMirror FeatureGate = "BBDevConfigMirror"
+	// DebugEndpoint enables HTTP endpoint on host-mounted unix socket serving state of the daemon to node-local debugging tools
+	DebugEndpoint FeatureGate = "DebugEndpoint"
+	// InventoryEnrichment enables built-in inventory enrichers (NUMA node, link speed, firmware version of accelerators)
+	InventoryEnrichment FeatureGate = "InventoryEnrichment"
+	// Telemetry enables gathering of pf_bb_config telemetry
+	Telemetry FeatureGate = "Telemetry"
+	// TolerateAbsentDevices considers teardown of PFs removed from the spec successful when the device is absent
+	TolerateAbsentDevices FeatureGate = "TolerateAbsentDevices"
+	// VFPodUsage enables reporting of pods using VFs, read from kubelet pod-resources API
+	VFPodUsage FeatureGate = "VFPodUsage"
+)
+
+// knownFeatureGates holds all supported gates together with their default values
+var knownFeatureGates = map[FeatureGate]bool{
+	AERMonitoring:         false,
+	AuditLog:              false,
+	BBDevConfigMirror:     false,
+	DebugEndpoint:         false,
+	InventoryEnrichment:   false,
+	Telemetry:             true,
+	TolerateAbsentDevices: true,
+	VFPodUsage:            false,
+}
+
+// removedFeatureGates were dropped without ever being implemented; they are still accepted, so that daemons configured
+// with them keep starting, but they have no effect
+var removedFeatureGates = map[FeatureGate]bool{
+	"DriftRemediation": true,
+	"ParallelConfig":   true,
+}
+
+var featureGateInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "feature_gate",
+	Help: `state of daemon's feature gates. 'name' - represents name of feature gate. Value: 1 - enabled, 0 - disabled`,
+}, []string{"name"})
+
+func init() {
+	metrics.Registry.MustRegister(featureGateInfo)
+}
+
+// FeatureGates holds state of feature gates; gates which are not set explicitly have their default value
+type FeatureGates map[FeatureGate]bool
+
+// ParseFeatureGates parses comma-separated list of gates e.g. "AuditLog=true,Telemetry=false"
+func ParseFeatureGates(value string) (FeatureGates, error) {
+	gates := FeatureGates{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid feature gate '%s' - expected <name>=<bool>", item)
+		}
+
+		name := FeatureGate(strings.TrimSpace(kv[0]))
+		if _, ok := knownFeatureGates[name]; !ok && !removedFeatureGates[name] {
+			return nil, fmt.Errorf("unknown feature gate '%s'", name)
+		}
+
+		enabled, err := strconv.ParseBool(strings.TrimSpace(kv[1]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of feature gate '%s' - %v", name, err)
+		}
+		gates[name] = enabled
+	}
+	return gates, nil
+}
+
+// FeatureGatesFromEnv parses feature gates provided with FEATURE_GATES env variable
+func FeatureGatesFromEnv() (FeatureGates, error) {
+	return ParseFeatureGates(os.Getenv(featureGatesEnvVarName))
+}
+
+// Enabled returns state of the gate; nil FeatureGates return defaults
+func (fg FeatureGates) Enabled(gate FeatureGate) bool {
+	if enabled, ok := fg[gate]; ok {
+		return enabled
+	}
+	return knownFeatureGates[gate]
+}
+
+// String returns state of all known gates in FEATURE_GATES format, sorted by name
+func (fg FeatureGates) String() string {
+	var names []string
+	for name := range knownFeatureGates {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+
+	var items []string
+	for _, name := range names {
+		items = append(items, fmt.Sprintf("%s=%t", name, fg.Enabled(FeatureGate(name))))
+	}
+	return strings.Join(items, ",")
+}
+
+// Report logs state of all known gates and exposes it with feature_gate metric; removed gates which are set are
+// reported with a warning
+func (fg FeatureGates) Report(log *logrus.Logger) {
+	for name := range fg {
+		if removedFeatureGates[name] {
+			log.WithField("featureGate", name).Warn("feature gate was removed and has no effect")
+		}
+	}
+	for name := range knownFeatureGates {
+		value := 0.0
+		if fg.Enabled(name) {
+			value = 1
+		}
+		featureGateInfo.WithLabelValues(string(name)).Set(value)
+	}
+	log.WithField("featureGates", fg.String()).Info("feature gates")
+}
@@ -0,0 +1,64 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+var _ = Describe("FeatureGates", func() {
+	It("should use defaults when no gate is provided", func() {
+		gates, err := ParseFeatureGates("")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(gates.Enabled(AuditLog)).To(BeFalse())
+		Expect(gates.Enabled(VFPodUsage)).To(BeFalse())
+		Expect(gates.Enabled(Telemetry)).To(BeTrue())
+
+		var nilGates FeatureGates
+		Expect(nilGates.String()).To(Equal(gates.String()))
+	})
+
+	It("should override defaults", func() {
+		gates, err := ParseFeatureGates("AuditLog=true, Telemetry=false,")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(gates.Enabled(AuditLog)).To(BeTrue())
+		Expect(gates.Enabled(VFPodUsage)).To(BeFalse())
+		Expect(gates.Enabled(Telemetry)).To(BeFalse())
+		Expect(gates.String()).To(Equal("AERMonitoring=false,AuditLog=true,BBDevConfigMirror=false,DebugEndpoint=false,InventoryEnrichment=false,Telemetry=false,TolerateAbsentDevices=true,VFPodUsage=false"))
+	})
+
+	It("should reject invalid gates", func() {
+		for value, expectedError := range map[string]string{
+			"AuditLog=true,Foo=true": "unknown feature gate 'Foo'",
+			"VFPodUsage":             "expected <name>=<bool>",
+			"VFPodUsage=yes":         "invalid value of feature gate 'VFPodUsage'",
+		} {
+			_, err := ParseFeatureGates(value)
+			Expect(err).To(MatchError(ContainSubstring(expectedError)), value)
+		}
+	})
+
+	It("should accept removed gates without effect", func() {
+		gates, err := ParseFeatureGates("DriftRemediation=true,ParallelConfig=true")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(gates.String()).To(Equal(FeatureGates{}.String()))
+
+		log, hook := test.NewNullLogger()
+		gates.Report(log)
+		Expect(hook.AllEntries()).To(ContainElement(And(
+			HaveField("Level", logrus.WarnLevel),
+			HaveField("Message", "feature gate was removed and has no effect"),
+		)))
+	})
+
+	It("should expose state of gates as metric", func() {
+		FeatureGates{VFPodUsage: true}.Report(logrus.New())
+		Expect(testutil.ToFloat64(featureGateInfo.WithLabelValues(string(VFPodUsage)))).To(Equal(1.0))
+		Expect(testutil.ToFloat64(featureGateInfo.WithLabelValues(string(AuditLog)))).To(Equal(0.0))
+	})
+})
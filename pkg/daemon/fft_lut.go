@@ -0,0 +1,114 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	fec "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+)
+
+// fftLutPath is where SRS FFT LUT referenced by fftLutFrom of ACC100 PF is stored for pf_bb_config
+func fftLutPath(pciAddress string) string {
+	return filepath.Join(workdir, fmt.Sprintf("%s.fft_lut.bin", pciAddress))
+}
+
+func acc100FFTLut(pf *fec.PhysicalFunctionConfigExt) *fec.FFTLutSource {
+	if pf.BBDevConfig.ACC100 == nil {
+		return nil
+	}
+	return pf.BBDevConfig.ACC100.FFTLutFrom
+}
+
+// readFFTLut returns SRS FFT LUT referenced by fftLutFrom of PF, verified against its checksum; ConfigMap or Secret is
+// read from given namespace
+func readFFTLut(ctx context.Context, c client.Reader, namespace, pciAddress string, src *fec.FFTLutSource) ([]byte, error) {
+	var (
+		content []byte
+		kind    string
+		name    string
+		found   bool
+	)
+
+	switch {
+	case src.ConfigMapRef != nil:
+		kind, name = "ConfigMap", src.ConfigMapRef.Name
+		cm := &corev1.ConfigMap{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, cm); err != nil {
+			return nil, fmt.Errorf("failed to get ConfigMap %s referenced by fftLutFrom of %s - %v", name, pciAddress, err)
+		}
+		if content, found = cm.BinaryData[src.Key]; !found {
+			var data string
+			data, found = cm.Data[src.Key]
+			content = []byte(data)
+		}
+	case src.SecretRef != nil:
+		kind, name = "Secret", src.SecretRef.Name
+		secret := &corev1.Secret{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, secret); err != nil {
+			return nil, fmt.Errorf("failed to get Secret %s referenced by fftLutFrom of %s - %v", name, pciAddress, err)
+		}
+		content, found = secret.Data[src.Key]
+	default:
+		return nil, fmt.Errorf("fftLutFrom of %s references neither ConfigMap nor Secret", pciAddress)
+	}
+
+	if !found {
+		return nil, fmt.Errorf("%s %s referenced by fftLutFrom of %s does not contain key %s", kind, name, pciAddress, src.Key)
+	}
+
+	sum := sha256.Sum256(content)
+	if checksum := hex.EncodeToString(sum[:]); !strings.EqualFold(checksum, src.Checksum) {
+		return nil, fmt.Errorf("FFT LUT %s/%s referenced by fftLutFrom of %s does not match checksum - expected %s, got %s",
+			name, src.Key, pciAddress, strings.ToLower(src.Checksum), checksum)
+	}
+	return content, nil
+}
+
+// verifyFFTLuts checks that FFT LUTs referenced by PFs are available and match their checksums, so that
+// configuration fails before the node is drained
+func (r *NodeConfigReconciler) verifyFFTLuts(ctx context.Context, pfs []fec.PhysicalFunctionConfigExt) error {
+	for i := range pfs {
+		if src := acc100FFTLut(&pfs[i]); src != nil {
+			if _, err := readFFTLut(ctx, r, r.nodeNameRef.Namespace, pfs[i].PCIAddress, src); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// provisionFFTLut writes FFT LUT referenced by PF into the state directory, where pf_bb_config takes it from; file
+// of PF which does not reference any is removed
+func (n *NodeConfigurator) provisionFFTLut(ctx context.Context, pf *fec.PhysicalFunctionConfigExt) error {
+	path := fftLutPath(pf.PCIAddress)
+	src := acc100FFTLut(pf)
+	if src == nil {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove FFT LUT %s - %v", path, err)
+		}
+		return nil
+	}
+
+	content, err := readFFTLut(ctx, n, n.nodeNameRef.Namespace, pf.PCIAddress, src)
+	if err != nil {
+		n.Log.WithError(err).WithField("pci", pf.PCIAddress).Error("failed to read fftLut")
+		return err
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("failed to write FFT LUT %s - %v", path, err)
+	}
+	n.Log.WithField("pci", pf.PCIAddress).WithField("file", path).Info("FFT LUT provisioned")
+	return nil
+}
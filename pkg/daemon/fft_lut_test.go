@@ -0,0 +1,108 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	fec "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("FFTLut", func() {
+	const pciAddress = "0000:f7:00.0"
+	var (
+		lut             = []byte{0x00, 0x01, 0xfe, 0xff}
+		checksum        string
+		c               client.Client
+		nodeNameRef     = types.NamespacedName{Namespace: "default", Name: "worker"}
+		originalWorkdir = workdir
+	)
+
+	acc100PF := func(src *fec.FFTLutSource) fec.PhysicalFunctionConfigExt {
+		return fec.PhysicalFunctionConfigExt{
+			PCIAddress:  pciAddress,
+			BBDevConfig: fec.BBDevConfig{ACC100: &fec.ACC100BBDevConfig{NumVfBundles: 16, FFTLutFrom: src}},
+		}
+	}
+
+	BeforeEach(func() {
+		sum := sha256.Sum256(lut)
+		checksum = hex.EncodeToString(sum[:])
+
+		c = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+			&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "fft-lut", Namespace: nodeNameRef.Namespace},
+				BinaryData: map[string][]byte{"srs_fft.bin": lut},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "fft-lut", Namespace: nodeNameRef.Namespace},
+				Data:       map[string][]byte{"srs_fft.bin": lut},
+			},
+		).Build()
+
+		var err error
+		workdir, err = os.MkdirTemp(testTmpFolder, "fftlut")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(workdir)).To(Succeed())
+		workdir = originalWorkdir
+	})
+
+	It("should read FFT LUT from ConfigMap and Secret", func() {
+		for _, src := range []*fec.FFTLutSource{
+			{ConfigMapRef: &fec.LocalObjectReference{Name: "fft-lut"}, Key: "srs_fft.bin", Checksum: checksum},
+			{SecretRef: &fec.LocalObjectReference{Name: "fft-lut"}, Key: "srs_fft.bin", Checksum: checksum},
+		} {
+			Expect(readFFTLut(context.TODO(), c, nodeNameRef.Namespace, pciAddress, src)).To(Equal(lut))
+		}
+	})
+
+	It("should fail precisely when FFT LUT is missing or does not match checksum", func() {
+		missingKey := &fec.FFTLutSource{SecretRef: &fec.LocalObjectReference{Name: "fft-lut"}, Key: "other.bin", Checksum: checksum}
+		_, err := readFFTLut(context.TODO(), c, nodeNameRef.Namespace, pciAddress, missingKey)
+		Expect(err).To(MatchError("Secret fft-lut referenced by fftLutFrom of 0000:f7:00.0 does not contain key other.bin"))
+
+		missingConfigMap := &fec.FFTLutSource{ConfigMapRef: &fec.LocalObjectReference{Name: "missing"}, Key: "srs_fft.bin", Checksum: checksum}
+		_, err = readFFTLut(context.TODO(), c, nodeNameRef.Namespace, pciAddress, missingConfigMap)
+		Expect(err).To(MatchError(ContainSubstring("failed to get ConfigMap missing referenced by fftLutFrom of 0000:f7:00.0")))
+
+		mismatch := &fec.FFTLutSource{ConfigMapRef: &fec.LocalObjectReference{Name: "fft-lut"}, Key: "srs_fft.bin", Checksum: "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"}
+		reconciler := &NodeConfigReconciler{Client: c, nodeNameRef: nodeNameRef}
+		Expect(reconciler.verifyFFTLuts(context.TODO(), []fec.PhysicalFunctionConfigExt{acc100PF(mismatch)})).To(MatchError(
+			"FFT LUT fft-lut/srs_fft.bin referenced by fftLutFrom of 0000:f7:00.0 does not match checksum - expected " +
+				"0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef, got " + checksum))
+	})
+
+	It("should provision FFT LUT for pf_bb_config and remove it once not referenced", func() {
+		configurator := &NodeConfigurator{Client: c, Log: utils.NewLogger(), nodeNameRef: nodeNameRef}
+		pf := acc100PF(&fec.FFTLutSource{ConfigMapRef: &fec.LocalObjectReference{Name: "fft-lut"}, Key: "srs_fft.bin", Checksum: checksum})
+		Expect(configurator.provisionFFTLut(context.TODO(), &pf)).To(Succeed())
+		Expect(os.ReadFile(fftLutPath(pciAddress))).To(Equal(lut))
+
+		pf = acc100PF(nil)
+		Expect(configurator.provisionFFTLut(context.TODO(), &pf)).To(Succeed())
+		Expect(fftLutPath(pciAddress)).ToNot(BeAnExistingFile())
+	})
+
+	It("should pass FFT LUT to pf_bb_config", func() {
+		args := []string{"/sriov_workdir/pf_bb_config", "ACC100", "-c", "cfg.ini", "-p", pciAddress}
+		Expect(withFFTLut(args, "")).To(Equal(args))
+		Expect(withFFTLut(args, fftLutPath(pciAddress))).To(Equal(append(args, "-f", fftLutPath(pciAddress))))
+	})
+})
@@ -0,0 +1,161 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"k8s.io/apimachinery/pkg/api/equality"
+
+	fec "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+)
+
+const hitlessUpdateKindFec, hitlessUpdateKindVrb = "sriovfec", "sriovvrb"
+
+// fecHitlessUpdatablePFs returns PFs which can be reconfigured just by restarting pf-bb-config, i.e. when the spec
+// differs from the applied one only in non-disruptive BBDevConfig fields. Returns nil when full reconfiguration
+// (with drain) is required.
+func (r *NodeConfigReconciler) fecHitlessUpdatablePFs(nc *fec.SriovFecNodeConfig, spec fec.SriovFecNodeConfigSpec, inventory *fec.NodeInventory, bbDevConfigHashes map[string]string) []fec.PhysicalFunctionConfigExt {
+	if bbDevConfigHashesChanged(bbDevConfigHashes, nc.Status.BBDevConfigHashes) {
+		return nil
+	}
+
+	applied := fec.SriovFecNodeConfigSpec{}
+	if ok, err := loadAppliedSpec(fecAppliedSpecKind(nc), nc.Status.AppliedSpecHash, &applied); !ok || err != nil {
+		if err != nil {
+			r.log.WithError(err).Warn("failed to load applied spec - hitless update is not possible")
+		}
+		return nil
+	}
+
+	if len(applied.PhysicalFunctions) != len(spec.PhysicalFunctions) {
+		return nil
+	}
+	// apart from BBDevConfig of PFs, specs have to be the same
+	requestedRest, appliedRest := *spec.DeepCopy(), *applied.DeepCopy()
+	for i := range requestedRest.PhysicalFunctions {
+		requestedRest.PhysicalFunctions[i].BBDevConfig = fec.BBDevConfig{}
+		appliedRest.PhysicalFunctions[i].BBDevConfig = fec.BBDevConfig{}
+	}
+	if !equality.Semantic.DeepEqual(requestedRest, appliedRest) {
+		return nil
+	}
+
+	var pfs []fec.PhysicalFunctionConfigExt
+	for i, pf := range spec.PhysicalFunctions {
+		changed, err := changedBBDevConfigFields(applied.PhysicalFunctions[i].BBDevConfig, pf.BBDevConfig)
+		if err != nil {
+			r.log.WithError(err).Warn("failed to compare bbDevConfig - hitless update is not possible")
+			return nil
+		}
+		if len(changed) == 0 {
+			continue
+		}
+		// bbDevConfigFrom takes precedence over bbDevConfig, so restarting pf-bb-config with bbDevConfig is wrong
+		if pf.BBDevConfigFrom != nil || !r.isHitlessChange(pf.PCIAddress, changed) {
+			return nil
+		}
+		if !fecVFsExposed(inventory, pf.PCIAddress, pf.VFAmount) {
+			return nil
+		}
+		pfs = append(pfs, pf)
+	}
+	return pfs
+}
+
+// vrbHitlessUpdatablePFs is the VRB counterpart of fecHitlessUpdatablePFs
+func (r *NodeConfigReconciler) vrbHitlessUpdatablePFs(nc *vrbv1.SriovVrbNodeConfig, spec vrbv1.SriovVrbNodeConfigSpec, inventory *vrbv1.NodeInventory, bbDevConfigHashes map[string]string) []vrbv1.PhysicalFunctionConfigExt {
+	if bbDevConfigHashesChanged(bbDevConfigHashes, nc.Status.BBDevConfigHashes) {
+		return nil
+	}
+
+	applied := vrbv1.SriovVrbNodeConfigSpec{}
+	if ok, err := loadAppliedSpec(hitlessUpdateKindVrb, nc.Status.AppliedSpecHash, &applied); !ok || err != nil {
+		if err != nil {
+			r.log.WithError(err).Warn("failed to load applied spec - hitless update is not possible")
+		}
+		return nil
+	}
+
+	if len(applied.PhysicalFunctions) != len(spec.PhysicalFunctions) {
+		return nil
+	}
+	requestedRest, appliedRest := *spec.DeepCopy(), *applied.DeepCopy()
+	for i := range requestedRest.PhysicalFunctions {
+		requestedRest.PhysicalFunctions[i].BBDevConfig = vrbv1.BBDevConfig{}
+		appliedRest.PhysicalFunctions[i].BBDevConfig = vrbv1.BBDevConfig{}
+	}
+	if !equality.Semantic.DeepEqual(requestedRest, appliedRest) {
+		return nil
+	}
+
+	var pfs []vrbv1.PhysicalFunctionConfigExt
+	for i, pf := range spec.PhysicalFunctions {
+		changed, err := changedBBDevConfigFields(applied.PhysicalFunctions[i].BBDevConfig, pf.BBDevConfig)
+		if err != nil {
+			r.log.WithError(err).Warn("failed to compare bbDevConfig - hitless update is not possible")
+			return nil
+		}
+		if len(changed) == 0 {
+			continue
+		}
+		if pf.BBDevConfigFrom != nil || !r.isHitlessChange(pf.PCIAddress, changed) {
+			return nil
+		}
+		if !vrbVFsExposed(inventory, pf.PCIAddress, pf.VFAmount) {
+			return nil
+		}
+		pfs = append(pfs, pf)
+	}
+	return pfs
+}
+
+func (r *NodeConfigReconciler) isHitlessChange(pciAddress string, changed []string) bool {
+	for _, field := range changed {
+		if isDisruptiveBBDevConfigField(field) {
+			r.log.WithField("pci", pciAddress).WithField("field", field).Info("disruptive bbDevConfig field changed")
+			return false
+		}
+	}
+	return true
+}
+
+// fecVFsExposed tells whether PF already exposes requested number of VFs, so that they do not have to be recreated
+func fecVFsExposed(inventory *fec.NodeInventory, pciAddress string, vfAmount int) bool {
+	for _, acc := range inventory.SriovAccelerators {
+		if acc.PCIAddress == pciAddress {
+			return len(acc.VFs) == vfAmount
+		}
+	}
+	return false
+}
+
+func vrbVFsExposed(inventory *vrbv1.NodeInventory, pciAddress string, vfAmount int) bool {
+	for _, acc := range inventory.SriovAccelerators {
+		if acc.PCIAddress == pciAddress {
+			return len(acc.VFs) == vfAmount
+		}
+	}
+	return false
+}
+
+// restartPfBBConfigs restarts pf-bb-config of count PFs with restart; no drain nor device plugin restart is done, as
+// VFs are kept. On failure, the caller is expected to fall back to full reconfiguration.
+func (r *NodeConfigReconciler) restartPfBBConfigs(count int, restart func(i int) error) error {
+	r.log.WithField("pfs", count).Info("applying non-disruptive bbDevConfig change (hitless update)")
+	for i := 0; i < count; i++ {
+		if err := restart(i); err != nil {
+			r.log.WithError(err).Warn("hitless update failed - falling back to full reconfiguration")
+			return err
+		}
+	}
+	return nil
+}
+
+// rememberAppliedSpec stores spec applied to the hardware as base of the next hitless update; failure only disables
+// hitless update of the next spec
+func (r *NodeConfigReconciler) rememberAppliedSpec(kind string, spec interface{}) {
+	if err := storeAppliedSpec(kind, spec); err != nil {
+		r.log.WithError(err).WithField("kind", kind).Warn("failed to store applied spec")
+	}
+}
@@ -0,0 +1,218 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("HitlessUpdate", func() {
+	It("should classify bbDevConfig fields conservatively", func() {
+		for path, disruptive := range map[string]bool{
+			"n3000.flrTimeout":           false,
+			"n3000.networkType":          true,
+			"n3000.pfMode":               true,
+			"n3000.downlink.queues.vf0":  true,
+			"n3000.uplink.bandwidth":     true,
+			"acc100.numVfBundles":        true,
+			"acc200.qfft.numQueueGroups": true,
+			"vrb2.maxQueueSize":          true,
+			"n3000.telemetryLevel":       true,
+			"acc300.flrTimeout":          true,
+			"n3000":                      true,
+		} {
+			Expect(isDisruptiveBBDevConfigField(path)).To(Equal(disruptive), path)
+		}
+	})
+
+	flrTimeout := func(v int) *int { return &v }
+
+	Context("changedBBDevConfigFields", func() {
+		It("should report changed leaf fields", func() {
+			old := sriovv2.BBDevConfig{N3000: &sriovv2.N3000BBDevConfig{NetworkType: "FPGA_5GNR", FLRTimeOut: flrTimeout(10)}}
+			new := sriovv2.BBDevConfig{N3000: &sriovv2.N3000BBDevConfig{NetworkType: "FPGA_5GNR", FLRTimeOut: flrTimeout(20)}}
+			new.N3000.Downlink.Queues.VF1 = 16
+			Expect(changedBBDevConfigFields(old, new)).To(Equal([]string{"n3000.downlink.queues.vf1", "n3000.flrTimeout"}))
+		})
+
+		It("should report fields of removed and added devices", func() {
+			old := sriovv2.BBDevConfig{N3000: &sriovv2.N3000BBDevConfig{FLRTimeOut: flrTimeout(10)}}
+			new := sriovv2.BBDevConfig{ACC100: &sriovv2.ACC100BBDevConfig{NumVfBundles: 16}}
+			changed, err := changedBBDevConfigFields(old, new)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(changed).To(ContainElements("n3000.flrTimeout", "acc100.numVfBundles"))
+		})
+
+		It("should report nothing for equal configs", func() {
+			config := sriovv2.BBDevConfig{N3000: &sriovv2.N3000BBDevConfig{FLRTimeOut: flrTimeout(10)}}
+			Expect(changedBBDevConfigFields(config, *config.DeepCopy())).To(BeEmpty())
+		})
+	})
+
+	Context("NodeConfigReconciler.Reconcile", func() {
+		var (
+			fakeClient      client.Client
+			nodeNameRef     = types.NamespacedName{Name: "worker", Namespace: "testNamespace"}
+			reconciler      *NodeConfigReconciler
+			drained         bool
+			configured      bool
+			restarted       []string
+			restartErr      error
+			originalWorkdir string
+		)
+
+		BeforeEach(func() {
+			originalWorkdir = workdir
+			workdir = filepath.Join(testTmpFolder, "hitless")
+			Expect(os.MkdirAll(workdir, 0755)).To(Succeed())
+
+			scheme := runtime.NewScheme()
+			Expect(sriovv2.AddToScheme(scheme)).ToNot(HaveOccurred())
+			Expect(vrbv1.AddToScheme(scheme)).ToNot(HaveOccurred())
+
+			procCmdlineFilePath = "testdata/cmdline_test"
+			sysLockdownFilePath = filepath.Join(testTmpFolder, "lockdown")
+			Expect(os.WriteFile(sysLockdownFilePath, []byte("[none] integrity confidentiality"), 0644)).To(Succeed())
+			drained, configured, restarted, restartErr = false, false, nil, nil
+
+			getSriovInventory = func(_ context.Context, log *logrus.Logger) (*sriovv2.NodeInventory, error) {
+				return &sriovv2.NodeInventory{
+					SriovAccelerators: []sriovv2.SriovAccelerator{{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, MaxVFs: 10,
+						VFs: []sriovv2.VF{{PCIAddress: "0000:14:00.1", Driver: utils.IGB_UIO}}}},
+				}, nil
+			}
+			VrbgetSriovInventory = func(_ context.Context, log *logrus.Logger) (*vrbv1.NodeInventory, error) {
+				return &vrbv1.NodeInventory{}, nil
+			}
+
+			spec := sriovv2.SriovFecNodeConfigSpec{
+				PhysicalFunctions: []sriovv2.PhysicalFunctionConfigExt{{
+					PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, VFDriver: utils.IGB_UIO, VFAmount: 1,
+					BBDevConfig: sriovv2.BBDevConfig{N3000: &sriovv2.N3000BBDevConfig{NetworkType: "FPGA_5GNR", FLRTimeOut: flrTimeout(10)}},
+				}},
+			}
+			Expect(storeAppliedSpec(hitlessUpdateKindFec, spec)).To(Succeed())
+			appliedHash, err := specHash(spec)
+			Expect(err).ToNot(HaveOccurred())
+
+			sfnc := &sriovv2.SriovFecNodeConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace, Generation: 1},
+				Spec:       spec,
+				Status:     sriovv2.SriovFecNodeConfigStatus{AppliedSpecHash: appliedHash},
+			}
+			vrbnc := &vrbv1.SriovVrbNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace}}
+			fakeClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(sfnc, vrbnc).Build()
+
+			reconciler = &NodeConfigReconciler{
+				Client:      fakeClient,
+				log:         utils.NewLogger(),
+				nodeNameRef: nodeNameRef,
+				sriovfecconfigurer: testConfigurerProto{
+					configureNodeFunction: func(sriovv2.SriovFecNodeConfigSpec) error {
+						configured = true
+						return nil
+					},
+					restartFunction: func(pf sriovv2.PhysicalFunctionConfigExt) error {
+						restarted = append(restarted, pf.PCIAddress)
+						return restartErr
+					},
+				},
+				drainerAndExecute: func(_ context.Context, configurer func(ctx context.Context) bool, drain bool) error {
+					drained = true
+					_ = configurer(context.TODO())
+					return nil
+				},
+				restartDevicePlugin: func(context.Context) error { return nil },
+			}
+		})
+
+		AfterEach(func() {
+			workdir = originalWorkdir
+			getSriovInventory = GetSriovInventory
+			VrbgetSriovInventory = VrbGetSriovInventory
+			sysLockdownFilePath = "/sys/kernel/security/lockdown"
+		})
+
+		updateSpec := func(update func(config *sriovv2.N3000BBDevConfig)) {
+			sfnc := new(sriovv2.SriovFecNodeConfig)
+			Expect(fakeClient.Get(context.TODO(), nodeNameRef, sfnc)).To(Succeed())
+			update(sfnc.Spec.PhysicalFunctions[0].BBDevConfig.N3000)
+			sfnc.Generation++
+			Expect(fakeClient.Update(context.TODO(), sfnc)).To(Succeed())
+		}
+
+		configuredCondition := func() *metav1.Condition {
+			sfnc := new(sriovv2.SriovFecNodeConfig)
+			Expect(fakeClient.Get(context.TODO(), nodeNameRef, sfnc)).To(Succeed())
+			return sfnc.FindCondition(ConditionConfigured)
+		}
+
+		It("should restart pf-bb-config without drain when only non-disruptive field changes", func() {
+			updateSpec(func(config *sriovv2.N3000BBDevConfig) { config.FLRTimeOut = flrTimeout(20) })
+
+			_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: nodeNameRef})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(drained).To(BeFalse())
+			Expect(configured).To(BeFalse())
+			Expect(restarted).To(Equal([]string{pciAddress}))
+			Expect(configuredCondition().Reason).To(Equal(string(ConfigurationSucceeded)))
+			Expect(configuredCondition().Message).To(Equal("Configured successfully (hitless update)"))
+
+			// applied spec is the base of the next hitless update
+			updateSpec(func(config *sriovv2.N3000BBDevConfig) { config.FLRTimeOut = flrTimeout(30) })
+			_, err = reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: nodeNameRef})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(drained).To(BeFalse())
+			Expect(restarted).To(HaveLen(2))
+		})
+
+		It("should drain and reconfigure node when disruptive field changes", func() {
+			updateSpec(func(config *sriovv2.N3000BBDevConfig) {
+				config.FLRTimeOut = flrTimeout(20)
+				config.Downlink.Bandwidth = 3
+			})
+
+			_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: nodeNameRef})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(restarted).To(BeEmpty())
+			Expect(drained).To(BeTrue())
+			Expect(configured).To(BeTrue())
+			Expect(configuredCondition().Message).To(Equal("Configured successfully"))
+		})
+
+		It("should fall back to full reconfiguration when applied spec is unknown or restart fails", func() {
+			Expect(os.Remove(appliedSpecFile(hitlessUpdateKindFec))).To(Succeed())
+			updateSpec(func(config *sriovv2.N3000BBDevConfig) { config.FLRTimeOut = flrTimeout(20) })
+			_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: nodeNameRef})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(restarted).To(BeEmpty())
+			Expect(drained).To(BeTrue())
+
+			drained = false
+			restartErr = os.ErrDeadlineExceeded
+			updateSpec(func(config *sriovv2.N3000BBDevConfig) { config.FLRTimeOut = flrTimeout(30) })
+			_, err = reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: nodeNameRef})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(restarted).To(HaveLen(1))
+			Expect(drained).To(BeTrue())
+			Expect(configuredCondition().Message).To(Equal("Configured successfully"))
+		})
+	})
+})
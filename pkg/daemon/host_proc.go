@@ -0,0 +1,149 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+const hostProcPathEnvVarName = utils.SRIOV_PREFIX + "HOST_PROC_PATH"
+
+var (
+	// ownProcPath is proc filesystem of the daemon container; it shows host processes only when daemon runs with hostPID
+	ownProcPath = "/proc"
+	// hostProcMountPath is where host /proc is expected to be mounted when daemon can't run with hostPID
+	hostProcMountPath = "/host/proc"
+)
+
+// InitHostProcPath points process discovery (e.g. finding pf_bb_config instances to be terminated) to proc filesystem
+// of the host. Path configured with SRIOV_FEC_HOST_PROC_PATH env variable is used if set, otherwise host /proc mounted
+// at /host/proc or own /proc of the daemon running with hostPID is detected. When none of them is available, error is
+// returned and discovery is limited to processes of the daemon container.
+func InitHostProcPath(log *logrus.Logger) (string, error) {
+	path, err := detectHostProcPath(os.Getenv(hostProcPathEnvVarName))
+	if err != nil {
+		return "", err
+	}
+	procPath = path
+	log.WithField("hostProcPath", path).Info("host proc path")
+	return path, nil
+}
+
+func detectHostProcPath(configured string) (string, error) {
+	if configured != "" {
+		if !isProcTree(configured) {
+			return "", fmt.Errorf("%s=%s does not point to proc filesystem", hostProcPathEnvVarName, configured)
+		}
+		return configured, nil
+	}
+
+	if isProcTree(hostProcMountPath) {
+		return hostProcMountPath, nil
+	}
+	if isHostInit(ownProcPath) {
+		return ownProcPath, nil
+	}
+	return "", fmt.Errorf("host processes are not visible - run daemon with hostPID: true, mount host /proc at %s or point %s to the mount",
+		hostProcMountPath, hostProcPathEnvVarName)
+}
+
+func isProcTree(path string) bool {
+	_, err := os.Stat(filepath.Join(path, "1", "cmdline"))
+	return err == nil
+}
+
+// isHostInit tells whether PID 1 of given proc filesystem is init of the host, which is visible only to containers
+// sharing PID namespace of the host
+func isHostInit(path string) bool {
+	comm, err := os.ReadFile(filepath.Join(path, "1", "comm"))
+	if err != nil {
+		return false
+	}
+	switch strings.TrimSpace(string(comm)) {
+	case "systemd", "init":
+		return true
+	}
+	return false
+}
+
+// localPID translates PID of process found in procPath into PID in the daemon's PID namespace, which is required to
+// signal the process. Processes outside of the daemon's PID namespace can't be signaled.
+func localPID(pid int) (int, error) {
+	if procPath == ownProcPath {
+		return pid, nil
+	}
+
+	processDir := filepath.Join(procPath, strconv.Itoa(pid))
+	ns, err := os.Readlink(filepath.Join(processDir, "ns", "pid"))
+	if err != nil {
+		return 0, err
+	}
+	own, err := os.Readlink(filepath.Join(ownProcPath, "self", "ns", "pid"))
+	if err != nil {
+		return 0, err
+	}
+	if ns != own {
+		return 0, fmt.Errorf("process %d runs outside of PID namespace of the daemon and cannot be signaled - run daemon with hostPID: true", pid)
+	}
+
+	status, err := os.ReadFile(filepath.Join(processDir, "status"))
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(string(status), "\n") {
+		// NSpid lists PIDs of the process from the outermost to its own PID namespace
+		if fields := strings.Fields(line); len(fields) > 1 && fields[0] == "NSpid:" {
+			return strconv.Atoi(fields[len(fields)-1])
+		}
+	}
+	return 0, fmt.Errorf("NSpid of process %d not found", pid)
+}
+
+// clockTicksPerSecond is USER_HZ, unit of process start time in /proc/<pid>/stat; it is 100 on all supported platforms
+const clockTicksPerSecond = 100
+
+// processAge returns how long the process, identified by PID in namespace of the daemon, has been running
+func processAge(pid int) (time.Duration, error) {
+	stat, err := os.ReadFile(filepath.Join(ownProcPath, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return 0, err
+	}
+	// command in parentheses may contain spaces, fields following it start with state (3rd field)
+	end := strings.LastIndexByte(string(stat), ')')
+	if end < 0 {
+		return 0, fmt.Errorf("invalid stat of process %d", pid)
+	}
+	fields := strings.Fields(string(stat[end+1:]))
+	const startTimeField = 22 - 3
+	if len(fields) <= startTimeField {
+		return 0, fmt.Errorf("start time of process %d not found", pid)
+	}
+	startTicks, err := strconv.ParseUint(fields[startTimeField], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid start time of process %d - %v", pid, err)
+	}
+	uptime, err := os.ReadFile(filepath.Join(ownProcPath, "uptime"))
+	if err != nil {
+		return 0, err
+	}
+	uptimeFields := strings.Fields(string(uptime))
+	if len(uptimeFields) == 0 {
+		return 0, fmt.Errorf("invalid uptime %q", uptime)
+	}
+	uptimeSeconds, err := strconv.ParseFloat(uptimeFields[0], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid uptime %q - %v", uptime, err)
+	}
+	started := time.Duration(startTicks) * time.Second / clockTicksPerSecond
+	return time.Duration(uptimeSeconds*float64(time.Second)) - started, nil
+}
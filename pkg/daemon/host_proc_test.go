@@ -0,0 +1,113 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("HostProcPath", func() {
+	const (
+		pciAddress    = "0000:14:00.0"
+		hostPIDNs     = "pid:[4026531836]"
+		containerPIDs = "pid:[4026532301]"
+	)
+
+	var (
+		originalProcPath          = procPath
+		originalOwnProcPath       = ownProcPath
+		originalHostProcMountPath = hostProcMountPath
+		root                      string
+	)
+
+	// fakeProcess creates /proc/<pid> entry; nsPIDs are PIDs of the process in nested PID namespaces
+	fakeProcess := func(proc string, pid int, comm, ns string, nsPIDs []int, args ...string) {
+		dir := filepath.Join(proc, strconv.Itoa(pid))
+		Expect(os.MkdirAll(filepath.Join(dir, "ns"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "comm"), []byte(comm+"\n"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "cmdline"), []byte(strings.Join(args, "\x00")+"\x00"), 0644)).To(Succeed())
+		Expect(os.Symlink(ns, filepath.Join(dir, "ns", "pid"))).To(Succeed())
+
+		status := "Name:\t" + comm + "\nNSpid:"
+		for _, nsPID := range append([]int{pid}, nsPIDs...) {
+			status += "\t" + strconv.Itoa(nsPID)
+		}
+		Expect(os.WriteFile(filepath.Join(dir, "status"), []byte(status+"\n"), 0644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+		root, err = os.MkdirTemp(testTmpFolder, "host-proc")
+		Expect(err).ToNot(HaveOccurred())
+		ownProcPath = filepath.Join(root, "proc")
+		hostProcMountPath = filepath.Join(root, "host", "proc")
+
+		// daemon container without hostPID: PID 1 is the daemon itself
+		fakeProcess(ownProcPath, 1, "sriov_fec_daemon", containerPIDs, nil, "/sriov_fec_daemon")
+		Expect(os.Symlink("1", filepath.Join(ownProcPath, "self"))).To(Succeed())
+	})
+
+	AfterEach(func() {
+		procPath = originalProcPath
+		ownProcPath = originalOwnProcPath
+		hostProcMountPath = originalHostProcMountPath
+		Expect(os.Unsetenv(hostProcPathEnvVarName)).To(Succeed())
+		Expect(os.RemoveAll(root)).To(Succeed())
+	})
+
+	It("should fail when host processes are not visible", func() {
+		_, err := InitHostProcPath(utils.NewLogger())
+		Expect(err).To(MatchError(ContainSubstring("host processes are not visible - run daemon with hostPID: true, mount host /proc at")))
+		Expect(procPath).To(Equal(originalProcPath))
+	})
+
+	It("should use own /proc when daemon runs with hostPID", func() {
+		Expect(os.WriteFile(filepath.Join(ownProcPath, "1", "comm"), []byte("systemd\n"), 0644)).To(Succeed())
+		Expect(InitHostProcPath(utils.NewLogger())).To(Equal(ownProcPath))
+		Expect(procPath).To(Equal(ownProcPath))
+	})
+
+	It("should prefer configured path and reject one which is not proc filesystem", func() {
+		fakeProcess(hostProcMountPath, 1, "systemd", hostPIDNs, nil, "/sbin/init")
+		configured := filepath.Join(root, "custom")
+		fakeProcess(configured, 1, "systemd", hostPIDNs, nil, "/sbin/init")
+
+		Expect(os.Setenv(hostProcPathEnvVarName, configured)).To(Succeed())
+		Expect(InitHostProcPath(utils.NewLogger())).To(Equal(configured))
+
+		Expect(os.Setenv(hostProcPathEnvVarName, filepath.Join(root, "missing"))).To(Succeed())
+		_, err := InitHostProcPath(utils.NewLogger())
+		Expect(err).To(MatchError(ContainSubstring("does not point to proc filesystem")))
+	})
+
+	Context("with host /proc mounted", func() {
+		BeforeEach(func() {
+			fakeProcess(hostProcMountPath, 1, "systemd", hostPIDNs, nil, "/sbin/init")
+			// the daemon as seen from the host
+			fakeProcess(hostProcMountPath, 4100, "sriov_fec_daemon", containerPIDs, []int{1}, "/sriov_fec_daemon")
+			Expect(InitHostProcPath(utils.NewLogger())).To(Equal(hostProcMountPath))
+		})
+
+		It("should find pf_bb_config started by the daemon with PID of daemon's namespace", func() {
+			fakeProcess(hostProcMountPath, 4200, "pf_bb_config", containerPIDs, []int{42},
+				"/sriov_workdir/pf_bb_config", "ACC100", "-p", pciAddress)
+			Expect(findPfBBConfigProcesses(pciAddress)).To(ConsistOf(42))
+		})
+
+		It("should fail to manage pf_bb_config running outside of daemon's namespace", func() {
+			fakeProcess(hostProcMountPath, 3000, "pf_bb_config", hostPIDNs, nil,
+				"/usr/bin/pf_bb_config", "ACC100", "-p", pciAddress)
+			_, err := findPfBBConfigProcesses(pciAddress)
+			Expect(err).To(MatchError(ContainSubstring("runs outside of PID namespace of the daemon")))
+		})
+	})
+})
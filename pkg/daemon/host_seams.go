@@ -0,0 +1,62 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+
+	fec "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+)
+
+// HostSeams are dependencies of NodeConfigReconciler on the host it runs on. Tests running the reconciler outside of
+// accelerated node (e.g. against envtest API server, together with the cluster controller) replace them.
+type HostSeams struct {
+	// DiscoveryConfigPath is accelerators.json of FEC accelerators, read by NewNodeConfigReconciler
+	DiscoveryConfigPath string
+	// VrbDiscoveryConfigPath is accelerators_vrb.json of VRB accelerators, read by NewNodeConfigReconciler
+	VrbDiscoveryConfigPath string
+	// KernelCmdlinePath is /proc/cmdline the kernel params are checked against
+	KernelCmdlinePath string
+	// LockdownPath is /sys/kernel/security/lockdown
+	LockdownPath string
+	// Inventory discovers FEC accelerators of the node
+	Inventory func(_ context.Context, log *logrus.Logger) (*fec.NodeInventory, error)
+	// VrbInventory discovers VRB accelerators of the node
+	VrbInventory func(_ context.Context, log *logrus.Logger) (*vrbv1.NodeInventory, error)
+}
+
+// ReplaceHostSeams replaces dependencies on the host with the non-empty ones of seams and returns function restoring
+// the replaced ones. Seams are shared by all reconcilers of the process.
+func ReplaceHostSeams(seams HostSeams) (restore func()) {
+	previous := HostSeams{
+		DiscoveryConfigPath:    configPath,
+		VrbDiscoveryConfigPath: VrbconfigPath,
+		KernelCmdlinePath:      procCmdlineFilePath,
+		LockdownPath:           sysLockdownFilePath,
+		Inventory:              getSriovInventory,
+		VrbInventory:           VrbgetSriovInventory,
+	}
+	if seams.DiscoveryConfigPath != "" {
+		configPath = seams.DiscoveryConfigPath
+	}
+	if seams.VrbDiscoveryConfigPath != "" {
+		VrbconfigPath = seams.VrbDiscoveryConfigPath
+	}
+	if seams.KernelCmdlinePath != "" {
+		procCmdlineFilePath = seams.KernelCmdlinePath
+	}
+	if seams.LockdownPath != "" {
+		sysLockdownFilePath = seams.LockdownPath
+	}
+	if seams.Inventory != nil {
+		getSriovInventory = seams.Inventory
+	}
+	if seams.VrbInventory != nil {
+		VrbgetSriovInventory = seams.VrbInventory
+	}
+	return func() { ReplaceHostSeams(previous) }
+}
@@ -0,0 +1,89 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"gopkg.in/ini.v1"
+
+	fec "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+)
+
+const (
+	// interruptSection of pf-bb-config cfg file enables MSI of the PF with msiEnabledKey
+	interruptSection = "INTERRUPT"
+	msiEnabledKey    = "msi_en"
+	// msiBusFile allows (1) or disallows (0) MSI for drivers bound to the device afterwards
+	msiBusFile = "msi_bus"
+)
+
+// acc100InterruptMode returns interrupt mode requested for ACC100 PF, empty when pf-bb-config default is kept
+func acc100InterruptMode(pf *fec.PhysicalFunctionConfigExt) fec.InterruptMode {
+	if pf.BBDevConfig.ACC100 == nil {
+		return ""
+	}
+	return pf.BBDevConfig.ACC100.InterruptMode
+}
+
+// setInterruptMode adds section enabling or disabling MSI to pf-bb-config cfg file; cfg file is not modified when
+// mode is empty
+func setInterruptMode(iniFile *ini.File, mode fec.InterruptMode) {
+	if mode == "" {
+		return
+	}
+	iniFile.Section(interruptSection).Key(msiEnabledKey).SetValue(AsIntString(mode == fec.InterruptModeMSI))
+}
+
+// setInterruptMode allows or disallows MSI of ACC100 PF in sysfs; it has to be done before PF is bound to its driver,
+// which decides whether to use MSI when it is bound. MSI is allowed when mode is empty, so that polling mode requested
+// by previous configuration does not stick to the PF.
+func (n *NodeConfigurator) setInterruptMode(ctx context.Context, pf *fec.PhysicalFunctionConfigExt) error {
+	mode := acc100InterruptMode(pf)
+	if mode == "" {
+		return n.resetInterruptMode(ctx, pf.PCIAddress)
+	}
+	path := filepath.Join(sysBusPciDevices, pf.PCIAddress, msiBusFile)
+	if err := writeFileWithTimeout(ctx, path, AsIntString(mode == fec.InterruptModeMSI)); err != nil {
+		return fmt.Errorf("failed to set %s interrupt mode of %s - %v", mode, pf.PCIAddress, err)
+	}
+	n.Log.WithField("pci", pf.PCIAddress).WithField("mode", mode).Info("interrupt mode set")
+	return nil
+}
+
+// resetInterruptMode allows MSI of the PF in sysfs, which is the kernel default
+func (n *NodeConfigurator) resetInterruptMode(ctx context.Context, pciAddress string) error {
+	path := filepath.Join(sysBusPciDevices, pciAddress, msiBusFile)
+	if err := writeFileWithTimeout(ctx, path, AsIntString(true)); err != nil {
+		return fmt.Errorf("failed to reset interrupt mode of %s - %v", pciAddress, err)
+	}
+	return nil
+}
+
+// appliedInterruptModes returns interrupt modes set in the latest cfg files stored in history of given PFs, by PF's
+// PCI address; PFs which cfg file does not set the mode are omitted
+func appliedInterruptModes(pciAddresses []string) map[string]string {
+	var modes map[string]string
+	for _, pciAddress := range pciAddresses {
+		generations, err := bbDevConfigGenerations(pciAddress)
+		if err != nil || len(generations) == 0 {
+			continue
+		}
+		cfg, err := ini.Load(bbDevConfigFile(pciAddress, generations[len(generations)-1]))
+		if err != nil || !cfg.Section(interruptSection).HasKey(msiEnabledKey) {
+			continue
+		}
+		mode := fec.InterruptModePolling
+		if msiEnabled, err := cfg.Section(interruptSection).Key(msiEnabledKey).Bool(); err == nil && msiEnabled {
+			mode = fec.InterruptModeMSI
+		}
+		if modes == nil {
+			modes = map[string]string{}
+		}
+		modes[pciAddress] = string(mode)
+	}
+	return modes
+}
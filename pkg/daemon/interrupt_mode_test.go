@@ -0,0 +1,122 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("ACC100 interrupt mode", func() {
+	const pfPCIAddress = "0000:f7:00.0"
+	var (
+		root                     string
+		configurator             *NodeConfigurator
+		originalSysBusPciDevices = sysBusPciDevices
+		originalWorkdir          = workdir
+	)
+
+	acc100PF := func(mode sriovv2.InterruptMode) *sriovv2.PhysicalFunctionConfigExt {
+		return &sriovv2.PhysicalFunctionConfigExt{
+			PCIAddress: pfPCIAddress,
+			PFDriver:   utils.VFIO_PCI,
+			BBDevConfig: sriovv2.BBDevConfig{ACC100: &sriovv2.ACC100BBDevConfig{
+				PFMode:        true,
+				NumVfBundles:  16,
+				MaxQueueSize:  1024,
+				Uplink4G:      sriovv2.QueueGroupConfig{NumQueueGroups: 2, NumAqsPerGroups: 16, AqDepthLog2: 4},
+				Downlink4G:    sriovv2.QueueGroupConfig{NumQueueGroups: 2, NumAqsPerGroups: 16, AqDepthLog2: 4},
+				Uplink5G:      sriovv2.QueueGroupConfig{NumQueueGroups: 2, NumAqsPerGroups: 16, AqDepthLog2: 4},
+				Downlink5G:    sriovv2.QueueGroupConfig{NumQueueGroups: 2, NumAqsPerGroups: 16, AqDepthLog2: 4},
+				InterruptMode: mode,
+			}},
+		}
+	}
+
+	BeforeEach(func() {
+		var err error
+		root, err = os.MkdirTemp(testTmpFolder, "interrupt-mode")
+		Expect(err).ToNot(HaveOccurred())
+		sysBusPciDevices = filepath.Join(root, "devices")
+		workdir = root
+		Expect(createFiles(filepath.Join(sysBusPciDevices, pfPCIAddress), msiBusFile)).To(Succeed())
+		configurator = &NodeConfigurator{Log: utils.NewLogger()}
+	})
+
+	AfterEach(func() {
+		sysBusPciDevices = originalSysBusPciDevices
+		workdir = originalWorkdir
+		Expect(os.RemoveAll(root)).To(Succeed())
+	})
+
+	DescribeTable("is applied to cfg file, sysfs and status",
+		func(mode sriovv2.InterruptMode, golden, msiBus string) {
+			pf := acc100PF(mode)
+			cfg := filepath.Join(root, "config.cfg")
+			Expect(generateBBDevConfigFile(pf.BBDevConfig, cfg)).To(Succeed())
+			Expect(compareFiles(cfg, golden)).To(Succeed())
+
+			Expect(configurator.setInterruptMode(context.TODO(), pf)).To(Succeed())
+			content, err := os.ReadFile(filepath.Join(sysBusPciDevices, pfPCIAddress, msiBusFile))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(content)).To(Equal(msiBus))
+
+			content, err = os.ReadFile(cfg)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(storeBBDevConfig(pfPCIAddress, content)).To(Succeed())
+			Expect(appliedInterruptModes([]string{pfPCIAddress})).To(Equal(map[string]string{pfPCIAddress: string(mode)}))
+		},
+		Entry("msi", sriovv2.InterruptModeMSI, "testdata/bbdevconfig_acc100_msi.cfg", "1"),
+		Entry("polling", sriovv2.InterruptModePolling, "testdata/bbdevconfig_acc100_polling.cfg", "0"),
+	)
+
+	It("keeps pf-bb-config default and allows MSI when not specified", func() {
+		Expect(os.WriteFile(filepath.Join(sysBusPciDevices, pfPCIAddress, msiBusFile), []byte("0"), 0644)).To(Succeed())
+		pf := acc100PF("")
+		cfg := filepath.Join(root, "config.cfg")
+		Expect(generateBBDevConfigFile(pf.BBDevConfig, cfg)).To(Succeed())
+		Expect(compareFiles(cfg, "testdata/bbdevconfig_test2.cfg")).To(Succeed())
+
+		Expect(configurator.setInterruptMode(context.TODO(), pf)).To(Succeed())
+		content, err := os.ReadFile(filepath.Join(sysBusPciDevices, pfPCIAddress, msiBusFile))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(content)).To(Equal("1"), "polling mode of previous configuration is not kept")
+
+		content, err = os.ReadFile(cfg)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(storeBBDevConfig(pfPCIAddress, content)).To(Succeed())
+		Expect(appliedInterruptModes([]string{pfPCIAddress})).To(BeNil())
+	})
+
+	It("allows MSI when PF is torn down", func() {
+		originalProcPath, originalOwnProcPath := procPath, ownProcPath
+		originalGetVFconfigured, originalGetVFList := getVFconfigured, getVFList
+		defer func() {
+			procPath, ownProcPath = originalProcPath, originalOwnProcPath
+			getVFconfigured, getVFList = originalGetVFconfigured, originalGetVFList
+		}()
+		procPath = filepath.Join(root, "proc")
+		ownProcPath = procPath
+		Expect(os.MkdirAll(procPath, 0755)).To(Succeed())
+		getVFconfigured = func(string) int { return 0 }
+		getVFList = func(string) ([]string, error) { return nil, nil }
+		Expect(createFiles(filepath.Join(sysBusPciDevices, pfPCIAddress), "reset")).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(sysBusPciDevices, pfPCIAddress, msiBusFile), []byte("0"), 0644)).To(Succeed())
+		configurator.pfBBConfigController = &pfBBConfigController{log: utils.NewLogger()}
+
+		Expect(configurator.cleanAcceleratorConfig(context.TODO(), sriovv2.SriovAccelerator{PCIAddress: pfPCIAddress, PFDriver: utils.VFIO_PCI}, true)).To(Succeed())
+
+		content, err := os.ReadFile(filepath.Join(sysBusPciDevices, pfPCIAddress, msiBusFile))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(content)).To(Equal("1"))
+	})
+})
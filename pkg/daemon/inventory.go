@@ -4,29 +4,26 @@
 package daemon
 
 import (
+	"context"
 	"errors"
 
-	commonUtils "github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
-	"github.com/jaypipes/ghw/pkg/pci"
 	"github.com/sirupsen/logrus"
 
 	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
 	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
-	"github.com/jaypipes/ghw"
-	"github.com/k8snetworkplumbingwg/sriov-network-device-plugin/pkg/utils"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
 )
 
-func GetSriovInventory(log *logrus.Logger) (*sriovv2.NodeInventory, error) {
-	pciInfo, err := ghw.PCI()
+func GetSriovInventory(ctx context.Context, log *logrus.Logger) (*sriovv2.NodeInventory, error) {
+	snapshot, err := scanPCIDevices(log, "sriovfec")
 	if err != nil {
 		log.WithError(err).Error("failed to get PCI info")
 		return nil, err
 	}
 
-	devices := pciInfo.ListDevices()
-	if len(devices) == 0 {
+	if len(snapshot.devices) == 0 {
 		log.Info("got 0 pci devices")
-		err := errors.New("pci.ListDevices() returned 0 devices")
+		err := errors.New("pci scan returned 0 devices")
 		return nil, err
 	}
 
@@ -34,52 +31,31 @@ func GetSriovInventory(log *logrus.Logger) (*sriovv2.NodeInventory, error) {
 		SriovAccelerators: []sriovv2.SriovAccelerator{},
 	}
 
-	for _, device := range commonUtils.Filter(devices, isKnownDevice) {
-		if !utils.IsSriovPF(device.Address) {
-			log.WithField("pci", device.Address).Info("ignoring non SriovPF capable device")
+	for _, device := range snapshot.filter(isKnownDevice) {
+		if !device.sriovCapable {
+			log.WithField("pci", device.address).Info("ignoring non SriovPF capable device")
 			continue
 		}
 
-		driver, err := utils.GetDriverName(device.Address)
-		if err != nil {
-			log.WithField("pci", device.Address).WithField("reason", err.Error()).Info("unable to get driver for device")
-			driver = ""
-		}
-
 		acc := sriovv2.SriovAccelerator{
-			VendorID:   device.Vendor.ID,
-			DeviceID:   device.Product.ID,
-			PCIAddress: device.Address,
-			PFDriver:   driver,
-			MaxVFs:     utils.GetSriovVFcapacity(device.Address),
+			VendorID:   device.vendorID,
+			DeviceID:   device.deviceID,
+			PCIAddress: device.address,
+			PFDriver:   device.driver,
+			MaxVFs:     device.totalVFs,
 			VFs:        []sriovv2.VF{},
 		}
 
-		vfs, err := utils.GetVFList(device.Address)
-		if err != nil {
-			log.WithError(err).WithField("pci", device.Address).Error("failed to get list of VFs for device")
-		}
-
-		for _, vf := range vfs {
+		for _, vf := range device.vfs {
 			vfInfo := sriovv2.VF{
 				PCIAddress: vf,
 			}
 
-			driver, err := utils.GetDriverName(vf)
-			if err != nil {
-				log.WithFields(logrus.Fields{
-					"pci":    vf,
-					"pf":     device.Address,
-					"reason": err.Error(),
-				}).Info("failed to get driver name for VF")
-			} else {
-				vfInfo.Driver = driver
-			}
-
-			if vfDeviceInfo := pciInfo.GetDevice(vf); vfDeviceInfo == nil {
+			if vfDevice := snapshot.get(vf); vfDevice == nil {
 				log.WithField("pci", vf).Info("failed to get device info for vf")
 			} else {
-				vfInfo.DeviceID = vfDeviceInfo.Product.ID
+				vfInfo.Driver = vfDevice.driver
+				vfInfo.DeviceID = vfDevice.deviceID
 			}
 
 			acc.VFs = append(acc.VFs, vfInfo)
@@ -88,20 +64,30 @@ func GetSriovInventory(log *logrus.Logger) (*sriovv2.NodeInventory, error) {
 		accelerators.SriovAccelerators = append(accelerators.SriovAccelerators, acc)
 	}
 
+	for _, device := range snapshot.filter(isUnsupportedDevice) {
+		log.WithField("pci", device.address).WithField("deviceID", device.deviceID).Info("ignoring unsupported accelerator")
+		accelerators.UnsupportedDevices = append(accelerators.UnsupportedDevices, sriovv2.UnsupportedDevice{
+			PCIAddress: device.address,
+			VendorID:   device.vendorID,
+			DeviceID:   device.deviceID,
+		})
+	}
+
+	enrichInventory(ctx, accelerators, log)
+	publishInventoryInfo("sriovfec", fecInventoryInfoSeries(accelerators))
 	return accelerators, nil
 }
 
-func VrbGetSriovInventory(log *logrus.Logger) (*vrbv1.NodeInventory, error) {
-	pciInfo, err := ghw.PCI()
+func VrbGetSriovInventory(ctx context.Context, log *logrus.Logger) (*vrbv1.NodeInventory, error) {
+	snapshot, err := scanPCIDevices(log, "sriovvrb")
 	if err != nil {
 		log.WithError(err).Error("failed to get PCI info")
 		return nil, err
 	}
 
-	devices := pciInfo.ListDevices()
-	if len(devices) == 0 {
+	if len(snapshot.devices) == 0 {
 		log.Info("got 0 pci devices")
-		err := errors.New("pci.ListDevices() returned 0 devices")
+		err := errors.New("pci scan returned 0 devices")
 		return nil, err
 	}
 
@@ -109,52 +95,31 @@ func VrbGetSriovInventory(log *logrus.Logger) (*vrbv1.NodeInventory, error) {
 		SriovAccelerators: []vrbv1.SriovAccelerator{},
 	}
 
-	for _, device := range commonUtils.Filter(devices, VrbisKnownDevice) {
-		if !utils.IsSriovPF(device.Address) {
-			log.WithField("pci", device.Address).Info("ignoring non SriovPF capable device")
+	for _, device := range snapshot.filter(VrbisKnownDevice) {
+		if !device.sriovCapable {
+			log.WithField("pci", device.address).Info("ignoring non SriovPF capable device")
 			continue
 		}
 
-		driver, err := utils.GetDriverName(device.Address)
-		if err != nil {
-			log.WithField("pci", device.Address).WithField("reason", err.Error()).Info("unable to get driver for device")
-			driver = ""
-		}
-
 		acc := vrbv1.SriovAccelerator{
-			VendorID:   device.Vendor.ID,
-			DeviceID:   device.Product.ID,
-			PCIAddress: device.Address,
-			PFDriver:   driver,
-			MaxVFs:     utils.GetSriovVFcapacity(device.Address),
+			VendorID:   device.vendorID,
+			DeviceID:   device.deviceID,
+			PCIAddress: device.address,
+			PFDriver:   device.driver,
+			MaxVFs:     device.totalVFs,
 			VFs:        []vrbv1.VF{},
 		}
 
-		vfs, err := utils.GetVFList(device.Address)
-		if err != nil {
-			log.WithError(err).WithField("pci", device.Address).Error("failed to get list of VFs for device")
-		}
-
-		for _, vf := range vfs {
+		for _, vf := range device.vfs {
 			vfInfo := vrbv1.VF{
 				PCIAddress: vf,
 			}
 
-			driver, err := utils.GetDriverName(vf)
-			if err != nil {
-				log.WithFields(logrus.Fields{
-					"pci":    vf,
-					"pf":     device.Address,
-					"reason": err.Error(),
-				}).Info("failed to get driver name for VF")
-			} else {
-				vfInfo.Driver = driver
-			}
-
-			if vfDeviceInfo := pciInfo.GetDevice(vf); vfDeviceInfo == nil {
+			if vfDevice := snapshot.get(vf); vfDevice == nil {
 				log.WithField("pci", vf).Info("failed to get device info for vf")
 			} else {
-				vfInfo.DeviceID = vfDeviceInfo.Product.ID
+				vfInfo.Driver = vfDevice.driver
+				vfInfo.DeviceID = vfDevice.deviceID
 			}
 
 			acc.VFs = append(acc.VFs, vfInfo)
@@ -163,25 +128,53 @@ func VrbGetSriovInventory(log *logrus.Logger) (*vrbv1.NodeInventory, error) {
 		accelerators.SriovAccelerators = append(accelerators.SriovAccelerators, acc)
 	}
 
+	for _, device := range snapshot.filter(isUnsupportedDevice) {
+		log.WithField("pci", device.address).WithField("deviceID", device.deviceID).Info("ignoring unsupported accelerator")
+		accelerators.UnsupportedDevices = append(accelerators.UnsupportedDevices, vrbv1.UnsupportedDevice{
+			PCIAddress: device.address,
+			VendorID:   device.vendorID,
+			DeviceID:   device.deviceID,
+		})
+	}
+
+	VrbenrichInventory(ctx, accelerators, log)
+	publishInventoryInfo("sriovvrb", vrbInventoryInfoSeries(accelerators))
 	return accelerators, nil
 }
 
-func isKnownDevice(device *pci.Device) bool {
-	_, hasKnownVendor := supportedAccelerators.VendorID[device.Vendor.ID]
-	_, hasKnownDeviceId := supportedAccelerators.Devices[device.Product.ID]
+func isKnownDevice(device *pciDevice) bool {
+	_, hasKnownVendor := supportedAccelerators.VendorID[device.vendorID]
+	_, hasKnownDeviceId := supportedAccelerators.Devices[device.deviceID]
 
 	return hasKnownVendor &&
 		hasKnownDeviceId &&
-		device.Class.ID == supportedAccelerators.Class &&
-		device.Subclass.ID == supportedAccelerators.SubClass
+		device.classID == supportedAccelerators.Class &&
+		device.subclassID == supportedAccelerators.SubClass
 }
 
-func VrbisKnownDevice(device *pci.Device) bool {
-	_, hasKnownVendor := VrbsupportedAccelerators.VendorID[device.Vendor.ID]
-	_, hasKnownDeviceId := VrbsupportedAccelerators.Devices[device.Product.ID]
+func VrbisKnownDevice(device *pciDevice) bool {
+	_, hasKnownVendor := VrbsupportedAccelerators.VendorID[device.vendorID]
+	_, hasKnownDeviceId := VrbsupportedAccelerators.Devices[device.deviceID]
 
 	return hasKnownVendor &&
 		hasKnownDeviceId &&
-		device.Class.ID == VrbsupportedAccelerators.Class &&
-		device.Subclass.ID == VrbsupportedAccelerators.SubClass
+		device.classID == VrbsupportedAccelerators.Class &&
+		device.subclassID == VrbsupportedAccelerators.SubClass
+}
+
+// isUnsupportedDevice returns true for SR-IOV PF of known vendor and accelerator class, which device ID is missing in
+// both discovery configs, e.g. newer stepping of the card
+func isUnsupportedDevice(device *pciDevice) bool {
+	if !device.sriovCapable || isKnownDevice(device) || VrbisKnownDevice(device) {
+		return false
+	}
+
+	for _, config := range []utils.AcceleratorDiscoveryConfig{supportedAccelerators, VrbsupportedAccelerators} {
+		if _, hasKnownVendor := config.VendorID[device.vendorID]; hasKnownVendor &&
+			device.classID == config.Class &&
+			device.subclassID == config.SubClass {
+			return true
+		}
+	}
+	return false
 }
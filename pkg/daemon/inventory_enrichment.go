@@ -0,0 +1,160 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+const (
+	NUMANodeAttribute        = "numaNode"
+	LinkSpeedAttribute       = "linkSpeed"
+	FirmwareVersionAttribute = "firmwareVersion"
+)
+
+// InventoryEnricher adds extra information to accelerators of detected inventory, preferably as Attributes.
+// Inventory of SriovVrbNodeConfig is passed to enrichers converted to sriovfec's NodeInventory.
+type InventoryEnricher interface {
+	// Name identifies enricher in logs
+	Name() string
+	Enrich(ctx context.Context, inventory *sriovv2.NodeInventory) error
+}
+
+var (
+	inventoryEnrichersLock sync.RWMutex
+	inventoryEnrichers     []InventoryEnricher
+)
+
+// RegisterInventoryEnricher adds enricher which runs after already registered ones every time inventory is detected
+func RegisterInventoryEnricher(enricher InventoryEnricher) {
+	inventoryEnrichersLock.Lock()
+	defer inventoryEnrichersLock.Unlock()
+	inventoryEnrichers = append(inventoryEnrichers, enricher)
+}
+
+// RegisterBuiltinInventoryEnrichers registers enrichers shipped with the daemon, if enabled with InventoryEnrichment
+// (attributes of accelerators) and VFPodUsage (pods using VFs) gates
+func RegisterBuiltinInventoryEnrichers(featureGates FeatureGates) {
+	if featureGates.Enabled(InventoryEnrichment) {
+		RegisterInventoryEnricher(sysfsAttributeEnricher{name: "numa-node", attribute: NUMANodeAttribute, read: readNUMANode})
+		RegisterInventoryEnricher(sysfsAttributeEnricher{name: "link-speed", attribute: LinkSpeedAttribute, read: readLinkSpeed})
+		RegisterInventoryEnricher(sysfsAttributeEnricher{name: "firmware", attribute: FirmwareVersionAttribute, read: getFirmwareVersion})
+	}
+	if featureGates.Enabled(VFPodUsage) {
+		RegisterInventoryEnricher(newPodResourcesEnricher(podResourcesSocket, utils.NewLogger()))
+	}
+}
+
+func registeredInventoryEnrichers() []InventoryEnricher {
+	inventoryEnrichersLock.RLock()
+	defer inventoryEnrichersLock.RUnlock()
+	return append([]InventoryEnricher{}, inventoryEnrichers...)
+}
+
+// enrichInventory runs registered enrichers in order. Each of them works on a copy of the inventory, so failing
+// enricher is only logged and its partial changes are dropped, while base inventory and results of others are kept.
+func enrichInventory(ctx context.Context, inventory *sriovv2.NodeInventory, log *logrus.Logger) {
+	for _, enricher := range registeredInventoryEnrichers() {
+		enriched := inventory.DeepCopy()
+		if err := enricher.Enrich(ctx, enriched); err != nil {
+			log.WithError(err).WithField("enricher", enricher.Name()).Error("failed to enrich inventory - skipping")
+			continue
+		}
+		*inventory = *enriched
+	}
+}
+
+// VrbenrichInventory runs registered enrichers on inventory of SriovVrbNodeConfig
+func VrbenrichInventory(ctx context.Context, inventory *vrbv1.NodeInventory, log *logrus.Logger) {
+	if len(registeredInventoryEnrichers()) == 0 {
+		return
+	}
+
+	// both inventories share the schema
+	converted := &sriovv2.NodeInventory{}
+	if err := convertInventory(inventory, converted); err != nil {
+		log.WithError(err).Error("failed to convert inventory for enrichment - skipping")
+		return
+	}
+	enrichInventory(ctx, converted, log)
+
+	enriched := &vrbv1.NodeInventory{}
+	if err := convertInventory(converted, enriched); err != nil {
+		log.WithError(err).Error("failed to convert enriched inventory - skipping")
+		return
+	}
+	*inventory = *enriched
+}
+
+func convertInventory(from, to interface{}) error {
+	data, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, to)
+}
+
+// sysfsAttributeEnricher sets attribute of each accelerator to value read from sysfs
+type sysfsAttributeEnricher struct {
+	name      string
+	attribute string
+	read      func(pciAddress string) (string, error)
+}
+
+func (e sysfsAttributeEnricher) Name() string {
+	return e.name
+}
+
+func (e sysfsAttributeEnricher) Enrich(_ context.Context, inventory *sriovv2.NodeInventory) error {
+	for i := range inventory.SriovAccelerators {
+		accelerator := &inventory.SriovAccelerators[i]
+		value, err := e.read(accelerator.PCIAddress)
+		if err != nil {
+			return fmt.Errorf("failed to read %s of %s - %v", e.attribute, accelerator.PCIAddress, err)
+		}
+		if accelerator.Attributes == nil {
+			accelerator.Attributes = map[string]string{}
+		}
+		accelerator.Attributes[e.attribute] = value
+	}
+	return nil
+}
+
+func readSysfsDeviceFile(pciAddress, file string) (string, error) {
+	content, err := os.ReadFile(filepath.Join(sysBusPciDevices, pciAddress, file))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+// readNUMANode returns NUMA node of the device; "-1" when platform does not report it
+func readNUMANode(pciAddress string) (string, error) {
+	return readSysfsDeviceFile(pciAddress, "numa_node")
+}
+
+// readLinkSpeed returns current PCIe link speed and width of the device, e.g. "16.0 GT/s PCIe x16"
+func readLinkSpeed(pciAddress string) (string, error) {
+	speed, err := readSysfsDeviceFile(pciAddress, "current_link_speed")
+	if err != nil {
+		return "", err
+	}
+	width, err := readSysfsDeviceFile(pciAddress, "current_link_width")
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s x%s", speed, width), nil
+}
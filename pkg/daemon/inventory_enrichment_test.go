@@ -0,0 +1,178 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+// rackLocationEnricher is an example of enricher which exposes rack location of the node (read from node label)
+// in attributes of each accelerator
+type rackLocationEnricher struct {
+	client   client.Reader
+	nodeName string
+}
+
+func (e rackLocationEnricher) Name() string {
+	return "rack-location"
+}
+
+func (e rackLocationEnricher) Enrich(ctx context.Context, inventory *sriovv2.NodeInventory) error {
+	node := &corev1.Node{}
+	if err := e.client.Get(ctx, client.ObjectKey{Name: e.nodeName}, node); err != nil {
+		return err
+	}
+	for i := range inventory.SriovAccelerators {
+		if inventory.SriovAccelerators[i].Attributes == nil {
+			inventory.SriovAccelerators[i].Attributes = map[string]string{}
+		}
+		inventory.SriovAccelerators[i].Attributes["rack"] = node.Labels["topology.example.com/rack"]
+	}
+	return nil
+}
+
+type enricherFunc func(inventory *sriovv2.NodeInventory) error
+
+func (f enricherFunc) Name() string {
+	return "func"
+}
+
+func (f enricherFunc) Enrich(_ context.Context, inventory *sriovv2.NodeInventory) error {
+	return f(inventory)
+}
+
+var _ = Describe("InventoryEnrichment", func() {
+	const pciAddress = "0000:f7:00.0"
+	var (
+		originalEnrichers        = inventoryEnrichers
+		originalSysBusPciDevices = sysBusPciDevices
+		log                      = utils.NewLogger()
+		inventory                *sriovv2.NodeInventory
+	)
+
+	setAttribute := func(key, value string) enricherFunc {
+		return func(inventory *sriovv2.NodeInventory) error {
+			for i := range inventory.SriovAccelerators {
+				if inventory.SriovAccelerators[i].Attributes == nil {
+					inventory.SriovAccelerators[i].Attributes = map[string]string{}
+				}
+				inventory.SriovAccelerators[i].Attributes[key] = value
+			}
+			return nil
+		}
+	}
+
+	BeforeEach(func() {
+		inventoryEnrichers = nil
+		inventory = &sriovv2.NodeInventory{SriovAccelerators: []sriovv2.SriovAccelerator{
+			{PCIAddress: pciAddress, DeviceID: "57c0", VFs: []sriovv2.VF{{PCIAddress: "0000:f7:00.1"}}},
+		}}
+	})
+
+	AfterEach(func() {
+		inventoryEnrichers = originalEnrichers
+		sysBusPciDevices = originalSysBusPciDevices
+	})
+
+	It("should run enrichers in order of registration and keep inventory when one of them fails", func() {
+		RegisterInventoryEnricher(setAttribute("order", "first"))
+		RegisterInventoryEnricher(enricherFunc(func(inventory *sriovv2.NodeInventory) error {
+			inventory.SriovAccelerators = nil
+			return errors.New("enricher failed")
+		}))
+		RegisterInventoryEnricher(setAttribute("order", "last"))
+		RegisterInventoryEnricher(setAttribute("other", "value"))
+
+		enrichInventory(context.TODO(), inventory, log)
+
+		Expect(inventory.SriovAccelerators).To(HaveLen(1))
+		Expect(inventory.SriovAccelerators[0].PCIAddress).To(Equal(pciAddress))
+		Expect(inventory.SriovAccelerators[0].VFs).To(HaveLen(1))
+		Expect(inventory.SriovAccelerators[0].Attributes).To(Equal(map[string]string{"order": "last", "other": "value"}))
+	})
+
+	It("should enrich inventory of VRB node config", func() {
+		RegisterInventoryEnricher(setAttribute("key", "value"))
+		vrbInventory := &vrbv1.NodeInventory{SriovAccelerators: []vrbv1.SriovAccelerator{
+			{PCIAddress: pciAddress, DeviceID: "57c0", MaxVFs: 16, VFs: []vrbv1.VF{{PCIAddress: "0000:f7:00.1"}}},
+		}}
+
+		VrbenrichInventory(context.TODO(), vrbInventory, log)
+
+		Expect(vrbInventory.SriovAccelerators).To(Equal([]vrbv1.SriovAccelerator{
+			{PCIAddress: pciAddress, DeviceID: "57c0", MaxVFs: 16, VFs: []vrbv1.VF{{PCIAddress: "0000:f7:00.1"}},
+				Attributes: map[string]string{"key": "value"}},
+		}))
+	})
+
+	It("should run example enricher reading node labels", func() {
+		c := fake.NewClientBuilder().WithObjects(&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker", Labels: map[string]string{"topology.example.com/rack": "r42"}},
+		}).Build()
+		RegisterInventoryEnricher(rackLocationEnricher{client: c, nodeName: "worker"})
+		RegisterInventoryEnricher(rackLocationEnricher{client: c, nodeName: "missing"})
+
+		enrichInventory(context.TODO(), inventory, log)
+		Expect(inventory.SriovAccelerators[0].Attributes).To(HaveKeyWithValue("rack", "r42"))
+	})
+
+	Context("builtin enrichers", func() {
+		BeforeEach(func() {
+			root, err := os.MkdirTemp(testTmpFolder, "enrichment")
+			Expect(err).ToNot(HaveOccurred())
+			sysBusPciDevices = root
+			deviceDir := filepath.Join(root, pciAddress)
+			Expect(os.MkdirAll(deviceDir, 0755)).To(Succeed())
+			for file, content := range map[string]string{
+				"numa_node":          "1\n",
+				"current_link_speed": "16.0 GT/s PCIe\n",
+				"current_link_width": "16\n",
+				"revision":           "0x01\n",
+			} {
+				Expect(os.WriteFile(filepath.Join(deviceDir, file), []byte(content), 0644)).To(Succeed())
+			}
+		})
+
+		It("should not be registered when InventoryEnrichment gate is disabled", func() {
+			RegisterBuiltinInventoryEnrichers(FeatureGates{})
+			Expect(registeredInventoryEnrichers()).To(BeEmpty())
+		})
+
+		It("should expose NUMA node, link speed and firmware version", func() {
+			RegisterBuiltinInventoryEnrichers(FeatureGates{InventoryEnrichment: true})
+			enrichInventory(context.TODO(), inventory, log)
+
+			Expect(inventory.SriovAccelerators[0].Attributes).To(Equal(map[string]string{
+				NUMANodeAttribute:        "1",
+				LinkSpeedAttribute:       "16.0 GT/s PCIe x16",
+				FirmwareVersionAttribute: "0x01",
+			}))
+		})
+
+		It("should keep attributes of other enrichers when sysfs file is missing", func() {
+			Expect(os.Remove(filepath.Join(sysBusPciDevices, pciAddress, "current_link_width"))).To(Succeed())
+			RegisterBuiltinInventoryEnrichers(FeatureGates{InventoryEnrichment: true})
+			enrichInventory(context.TODO(), inventory, log)
+
+			Expect(inventory.SriovAccelerators[0].Attributes).To(Equal(map[string]string{
+				NUMANodeAttribute:        "1",
+				FirmwareVersionAttribute: "0x01",
+			}))
+		})
+	})
+})
@@ -0,0 +1,151 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+)
+
+const (
+	nodeLabel      = "node"
+	pfAddressLabel = "pf_pci_address"
+	deviceIDLabel  = "device_id"
+	driverLabel    = "driver"
+	firmwareLabel  = "firmware"
+	numaLabel      = "numa"
+)
+
+var (
+	acceleratorInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "sriovfec_accelerator_info",
+		Help: `equals to 1 for each discovered accelerator. 'node' - represents name of the node. 'pci_address' - represents unique BDF for PF. 'device_id' - represents PCI device ID. 'driver' - represents driver PF is bound to. 'firmware' and 'numa' - represent firmware version and NUMA node, empty unless InventoryEnrichment feature gate is enabled`,
+	}, []string{nodeLabel, pciAddressLabel, deviceIDLabel, driverLabel, firmwareLabel, numaLabel})
+
+	vfInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "sriovfec_vf_info",
+		Help: `equals to 1 for each VF of discovered accelerator. 'node' - represents name of the node. 'pci_address' - represents unique BDF for VF. 'pf_pci_address' - represents unique BDF for PF of the VF. 'device_id' - represents PCI device ID. 'driver' - represents driver VF is bound to`,
+	}, []string{nodeLabel, pciAddressLabel, pfAddressLabel, deviceIDLabel, driverLabel})
+
+	inventoryInfoNodeName string
+	inventoryInfoLock     sync.Mutex
+	// publishedInventoryInfo holds label sets of info series published by last scan of each inventory kind
+	publishedInventoryInfo = map[string]inventoryInfoSeries{}
+)
+
+func init() {
+	metrics.Registry.MustRegister(acceleratorInfo, vfInfo)
+}
+
+// SetInventoryInfoNodeName sets value of 'node' label of accelerator and VF info series
+func SetInventoryInfoNodeName(nodeName string) {
+	inventoryInfoLock.Lock()
+	defer inventoryInfoLock.Unlock()
+	inventoryInfoNodeName = nodeName
+}
+
+// inventoryInfoSeries are label sets of info series describing single inventory. Only stable identifiers are used as
+// labels, so that number of series is bounded by number of devices.
+type inventoryInfoSeries struct {
+	accelerators, vfs []prometheus.Labels
+}
+
+func fecInventoryInfoSeries(inventory *sriovv2.NodeInventory) (series inventoryInfoSeries) {
+	for _, acc := range inventory.SriovAccelerators {
+		series.accelerators = append(series.accelerators, prometheus.Labels{
+			pciAddressLabel: acc.PCIAddress,
+			deviceIDLabel:   acc.DeviceID,
+			driverLabel:     acc.PFDriver,
+			firmwareLabel:   acc.Attributes[FirmwareVersionAttribute],
+			numaLabel:       acc.Attributes[NUMANodeAttribute],
+		})
+		for _, vf := range acc.VFs {
+			series.vfs = append(series.vfs, prometheus.Labels{
+				pciAddressLabel: vf.PCIAddress,
+				pfAddressLabel:  acc.PCIAddress,
+				deviceIDLabel:   vf.DeviceID,
+				driverLabel:     vf.Driver,
+			})
+		}
+	}
+	return series
+}
+
+func vrbInventoryInfoSeries(inventory *vrbv1.NodeInventory) (series inventoryInfoSeries) {
+	for _, acc := range inventory.SriovAccelerators {
+		series.accelerators = append(series.accelerators, prometheus.Labels{
+			pciAddressLabel: acc.PCIAddress,
+			deviceIDLabel:   acc.DeviceID,
+			driverLabel:     acc.PFDriver,
+			firmwareLabel:   acc.Attributes[FirmwareVersionAttribute],
+			numaLabel:       acc.Attributes[NUMANodeAttribute],
+		})
+		for _, vf := range acc.VFs {
+			series.vfs = append(series.vfs, prometheus.Labels{
+				pciAddressLabel: vf.PCIAddress,
+				pfAddressLabel:  acc.PCIAddress,
+				deviceIDLabel:   vf.DeviceID,
+				driverLabel:     vf.Driver,
+			})
+		}
+	}
+	return series
+}
+
+// publishInventoryInfo replaces info series of given inventory kind with the ones describing freshly scanned
+// inventory. Series of devices which disappeared (or changed e.g. driver) are deleted, unless the other inventory kind
+// still publishes them.
+func publishInventoryInfo(kind string, series inventoryInfoSeries) {
+	inventoryInfoLock.Lock()
+	defer inventoryInfoLock.Unlock()
+
+	for i := range series.accelerators {
+		series.accelerators[i][nodeLabel] = inventoryInfoNodeName
+	}
+	for i := range series.vfs {
+		series.vfs[i][nodeLabel] = inventoryInfoNodeName
+	}
+	previous := publishedInventoryInfo[kind]
+	publishedInventoryInfo[kind] = series
+
+	currentAccelerators, currentVFs := map[string]bool{}, map[string]bool{}
+	for _, published := range publishedInventoryInfo {
+		for _, labels := range published.accelerators {
+			currentAccelerators[seriesKey(labels)] = true
+		}
+		for _, labels := range published.vfs {
+			currentVFs[seriesKey(labels)] = true
+		}
+	}
+	deleteStale := func(gauge *prometheus.GaugeVec, stale []prometheus.Labels, current map[string]bool) {
+		for _, labels := range stale {
+			if !current[seriesKey(labels)] {
+				gauge.Delete(labels)
+			}
+		}
+	}
+	deleteStale(acceleratorInfo, previous.accelerators, currentAccelerators)
+	deleteStale(vfInfo, previous.vfs, currentVFs)
+
+	for _, labels := range series.accelerators {
+		acceleratorInfo.With(labels).Set(1)
+	}
+	for _, labels := range series.vfs {
+		vfInfo.With(labels).Set(1)
+	}
+}
+
+// seriesKey identifies series by values of all its labels
+func seriesKey(labels prometheus.Labels) string {
+	var key string
+	for _, name := range []string{nodeLabel, pciAddressLabel, pfAddressLabel, deviceIDLabel, driverLabel, firmwareLabel, numaLabel} {
+		key += labels[name] + "\x00"
+	}
+	return key
+}
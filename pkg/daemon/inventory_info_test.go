@@ -0,0 +1,90 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	fec "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+)
+
+var _ = Describe("Inventory info series", func() {
+	acc100 := func(vfDriver string) fec.SriovAccelerator {
+		return fec.SriovAccelerator{
+			VendorID: "8086", DeviceID: "0d5c", PCIAddress: "0000:af:00.0", PFDriver: "pci-pf-stub",
+			Attributes: map[string]string{FirmwareVersionAttribute: "1.2", NUMANodeAttribute: "1"},
+			VFs: []fec.VF{
+				{PCIAddress: "0000:b0:00.0", Driver: vfDriver, DeviceID: "0d5d"},
+				{PCIAddress: "0000:b0:00.1", Driver: vfDriver, DeviceID: "0d5d"},
+			},
+		}
+	}
+	acc200 := fec.SriovAccelerator{VendorID: "8086", DeviceID: "57c0", PCIAddress: "0000:f7:00.0", PFDriver: "vfio-pci"}
+
+	accLabels := func(pciAddress, deviceID, driver, firmware, numa string) prometheus.Labels {
+		return prometheus.Labels{nodeLabel: "worker", pciAddressLabel: pciAddress, deviceIDLabel: deviceID,
+			driverLabel: driver, firmwareLabel: firmware, numaLabel: numa}
+	}
+	vfLabels := func(pciAddress, driver string) prometheus.Labels {
+		return prometheus.Labels{nodeLabel: "worker", pciAddressLabel: pciAddress, pfAddressLabel: "0000:af:00.0",
+			deviceIDLabel: "0d5d", driverLabel: driver}
+	}
+
+	BeforeEach(func() {
+		acceleratorInfo.Reset()
+		vfInfo.Reset()
+		publishedInventoryInfo = map[string]inventoryInfoSeries{}
+		SetInventoryInfoNodeName("worker")
+	})
+
+	AfterEach(func() {
+		SetInventoryInfoNodeName("")
+	})
+
+	It("should publish series of accelerators and their VFs", func() {
+		publishInventoryInfo("sriovfec", fecInventoryInfoSeries(&fec.NodeInventory{
+			SriovAccelerators: []fec.SriovAccelerator{acc100("vfio-pci"), acc200},
+		}))
+
+		Expect(testutil.CollectAndCount(acceleratorInfo)).To(Equal(2))
+		Expect(testutil.ToFloat64(acceleratorInfo.With(accLabels("0000:af:00.0", "0d5c", "pci-pf-stub", "1.2", "1")))).To(Equal(float64(1)))
+		Expect(testutil.ToFloat64(acceleratorInfo.With(accLabels("0000:f7:00.0", "57c0", "vfio-pci", "", "")))).To(Equal(float64(1)))
+		Expect(testutil.CollectAndCount(vfInfo)).To(Equal(2))
+		Expect(testutil.ToFloat64(vfInfo.With(vfLabels("0000:b0:00.1", "vfio-pci")))).To(Equal(float64(1)))
+	})
+
+	It("should delete series of disappeared and changed devices", func() {
+		publishInventoryInfo("sriovfec", fecInventoryInfoSeries(&fec.NodeInventory{
+			SriovAccelerators: []fec.SriovAccelerator{acc100("vfio-pci"), acc200},
+		}))
+		publishInventoryInfo("sriovfec", fecInventoryInfoSeries(&fec.NodeInventory{
+			SriovAccelerators: []fec.SriovAccelerator{acc100("igb_uio")},
+		}))
+
+		Expect(testutil.CollectAndCount(acceleratorInfo)).To(Equal(1))
+		Expect(testutil.ToFloat64(acceleratorInfo.With(accLabels("0000:af:00.0", "0d5c", "pci-pf-stub", "1.2", "1")))).To(Equal(float64(1)))
+		Expect(testutil.CollectAndCount(vfInfo)).To(Equal(2))
+		Expect(vfInfo.Delete(vfLabels("0000:b0:00.0", "igb_uio"))).To(BeTrue())
+		Expect(vfInfo.Delete(vfLabels("0000:b0:00.0", "vfio-pci"))).To(BeFalse(), "series of VF with previous driver has to be deleted")
+	})
+
+	It("should keep series published by the other inventory kind", func() {
+		publishInventoryInfo("sriovfec", fecInventoryInfoSeries(&fec.NodeInventory{
+			SriovAccelerators: []fec.SriovAccelerator{acc200},
+		}))
+		publishInventoryInfo("sriovvrb", vrbInventoryInfoSeries(&vrbv1.NodeInventory{
+			SriovAccelerators: []vrbv1.SriovAccelerator{{VendorID: "8086", DeviceID: "57c0", PCIAddress: "0000:f7:00.0", PFDriver: "vfio-pci"}},
+		}))
+		publishInventoryInfo("sriovfec", fecInventoryInfoSeries(&fec.NodeInventory{}))
+
+		Expect(testutil.CollectAndCount(acceleratorInfo)).To(Equal(1))
+
+		publishInventoryInfo("sriovvrb", vrbInventoryInfoSeries(&vrbv1.NodeInventory{}))
+		Expect(testutil.CollectAndCount(acceleratorInfo)).To(BeZero())
+	})
+})
@@ -0,0 +1,159 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	fec "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+var (
+	inventorySettleTimeout  = 30 * time.Second
+	inventorySettleInterval = time.Second
+	// inventoryRefreshPeriod - unchanged inventory stamped more recently is not stamped again, so that back-to-back
+	// reconciles do not write status; it is shorter than resyncPeriod, so every periodic scan refreshes the stamp
+	inventoryRefreshPeriod = 30 * time.Second
+)
+
+// waitForInventorySettle waits until all requested VFs are exposed or amount of exposed VFs stops changing.
+// VFs are exposed gradually after sriov_numvfs is written, so inventory read right after configuration may be partial.
+func (r *NodeConfigReconciler) waitForInventorySettle(ctx context.Context, requested map[string]int, exposedVFs func(context.Context) (map[string]int, error)) {
+	var previous map[string]int
+	err := wait.PollImmediateWithContext(ctx, inventorySettleInterval, inventorySettleTimeout, func(context.Context) (bool, error) {
+		current, err := exposedVFs(ctx)
+		if err != nil {
+			r.log.WithError(err).Info("failed to read inventory while waiting for VFs")
+			return false, nil
+		}
+
+		settled := true
+		for pciAddress, amount := range requested {
+			if current[pciAddress] != amount {
+				settled = false
+			}
+		}
+		if settled || (previous != nil && reflect.DeepEqual(previous, current)) {
+			return true, nil
+		}
+		previous = current
+		return false, nil
+	})
+	if err != nil {
+		r.log.WithError(err).WithField("requested", requested).Info("inventory has not settled, exposing current one")
+	}
+}
+
+func fecRequestedVFs(nc *fec.SriovFecNodeConfig) map[string]int {
+	requested := map[string]int{}
+	for _, pf := range nc.Spec.PhysicalFunctions {
+		requested[pf.PCIAddress] = pf.VFAmount
+	}
+	return requested
+}
+
+func fecExposedVFs(ctx context.Context) (map[string]int, error) {
+	inv, err := getSriovInventory(ctx, log)
+	if err != nil {
+		return nil, err
+	}
+	exposed := map[string]int{}
+	for _, acc := range inv.SriovAccelerators {
+		exposed[acc.PCIAddress] = len(acc.VFs)
+	}
+	return exposed, nil
+}
+
+func vrbRequestedVFs(nc *vrbv1.SriovVrbNodeConfig) map[string]int {
+	requested := map[string]int{}
+	for _, pf := range nc.Spec.PhysicalFunctions {
+		requested[pf.PCIAddress] = pf.VFAmount
+	}
+	return requested
+}
+
+func vrbExposedVFs(ctx context.Context) (map[string]int, error) {
+	inv, err := VrbgetSriovInventory(ctx, log)
+	if err != nil {
+		return nil, err
+	}
+	exposed := map[string]int{}
+	for _, acc := range inv.SriovAccelerators {
+		exposed[acc.PCIAddress] = len(acc.VFs)
+	}
+	return exposed, nil
+}
+
+// refreshInventory exposes inventory changes which do not require reconfiguration. Refresh is skipped while
+// configuration is in progress - inventory is exposed once configuration completes.
+func (r *NodeConfigReconciler) refreshInventory(ctx context.Context, nc *fec.SriovFecNodeConfig, inv *fec.NodeInventory) error {
+	if atomic.LoadInt32(&r.configurationInProgress) == 1 {
+		r.log.Debug("configuration in progress - skipping inventory refresh")
+		return nil
+	}
+	original := nc.DeepCopy()
+	inv = fecStatusInventory(nc, inv)
+	nc.Status.Inventory = *inv
+	nc.Status.UnsupportedDevices = inv.UnsupportedDevices
+	nc.Status.AppliedGenerations = fecSpecAppliedGenerations(nc)
+	if !reflect.DeepEqual(original.Status.Inventory, nc.Status.Inventory) || inventoryRefreshDue(nc.Status.InventoryCollectedAt) {
+		fecInventoryCollected(&nc.Status)
+	}
+	fecSetLogicalNames(nc)
+	if _, err := patchStatus(ctx, r.Client, original, nc); err != nil {
+		return err
+	}
+	r.publishFecLogicalNames(ctx, nc)
+	return nil
+}
+
+func (r *NodeConfigReconciler) VrbrefreshInventory(ctx context.Context, nc *vrbv1.SriovVrbNodeConfig, inv *vrbv1.NodeInventory) error {
+	if atomic.LoadInt32(&r.configurationInProgress) == 1 {
+		r.log.Debug("configuration in progress - skipping inventory refresh")
+		return nil
+	}
+	original := nc.DeepCopy()
+	nc.Status.Inventory = *inv
+	nc.Status.UnsupportedDevices = inv.UnsupportedDevices
+	nc.Status.AppliedGenerations = vrbSpecAppliedGenerations(nc)
+	if !reflect.DeepEqual(original.Status.Inventory, nc.Status.Inventory) || inventoryRefreshDue(nc.Status.InventoryCollectedAt) {
+		vrbInventoryCollected(&nc.Status)
+	}
+	vrbSetLogicalNames(nc)
+	if _, err := patchStatus(ctx, r.Client, original, nc); err != nil {
+		return err
+	}
+	r.publishVrbLogicalNames(ctx, nc)
+	return nil
+}
+
+// inventoryRefreshDue tells whether stamp of unchanged inventory has to be refreshed; stamp ahead of the clock, which
+// was stepped back since it was written, is refreshed as well, otherwise it would not be until the clock catches up
+func inventoryRefreshDue(collectedAt *metav1.Time) bool {
+	if collectedAt == nil {
+		return true
+	}
+	age := daemonClock.Now().Sub(collectedAt.Time)
+	return age < 0 || age >= inventoryRefreshPeriod
+}
+
+// fecInventoryCollected stamps status with time and sequence number of the inventory scan it exposes, so that
+// the cluster controller knows how fresh the inventory is
+func fecInventoryCollected(status *fec.SriovFecNodeConfigStatus) {
+	now := metav1.NewTime(daemonClock.Now())
+	status.InventoryCollectedAt = &now
+	status.InventorySequence++
+}
+
+func vrbInventoryCollected(status *vrbv1.SriovVrbNodeConfigStatus) {
+	now := metav1.NewTime(daemonClock.Now())
+	status.InventoryCollectedAt = &now
+	status.InventorySequence++
+}
@@ -4,6 +4,8 @@
 package daemon
 
 import (
+	"context"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/sirupsen/logrus"
@@ -13,7 +15,7 @@ var _ = Describe("SriovInventoryTest", func() {
 	log := logrus.New()
 	var _ = Context("GetSriovInventory", func() {
 		var _ = It("will return error when config is nil ", func() {
-			_, err := GetSriovInventory(log)
+			_, err := GetSriovInventory(context.TODO(), log)
 			Expect(err).ToNot(HaveOccurred())
 		})
 	})
@@ -0,0 +1,164 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	kernelLogSourceEnvVarName = "KERNEL_LOG_SOURCE"
+	defaultKernelLogSource    = "/dev/kmsg"
+	// KernelLogSourceDisabled as kernel log source disables correlation of failed sysfs writes with kernel messages
+	KernelLogSourceDisabled = "none"
+
+	// kernelLogWindow is how long before the failure kernel messages are correlated with it; applies to /dev/kmsg only,
+	// as timestamps of text logs are not parsed
+	kernelLogWindow = 10 * time.Second
+	// kernelLogLines is the maximum number of kernel messages appended to the error
+	kernelLogLines = 5
+	// kernelLogLineLength is the maximum length of single kernel message appended to the error
+	kernelLogLineLength = 200
+	// kernelLogReadLimit is the maximum number of bytes read from kernel log; only the end of text logs is read
+	kernelLogReadLimit = 1024 * 1024
+)
+
+// kmsgRecord is record of /dev/kmsg: "<priority>,<sequence>,<microseconds since boot>,<flags>[,...];<message>"
+var kmsgRecord = regexp.MustCompile(`^\d+,\d+,(\d+),[^;]*;(.*)$`)
+
+// kernelUptime returns time since boot, as used by timestamps of /dev/kmsg records; replaced by tests
+var kernelUptime = func() (time.Duration, error) {
+	var ts unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_MONOTONIC, &ts); err != nil {
+		return 0, err
+	}
+	return time.Duration(ts.Nano()), nil
+}
+
+// KernelLogSourceFromEnv reads source of kernel messages from KERNEL_LOG_SOURCE env variable; /dev/kmsg is used when it
+// is not set
+func KernelLogSourceFromEnv() string {
+	if source := os.Getenv(kernelLogSourceEnvVarName); source != "" {
+		return source
+	}
+	return defaultKernelLogSource
+}
+
+// SetKernelLogSource sets source of kernel messages which failed bind, unbind and sriov_numvfs writes are correlated
+// with: /dev/kmsg or text kernel log (e.g. kern.log of the host mounted into the daemon); KernelLogSourceDisabled or
+// empty source disables the correlation
+func (n *NodeConfigurator) SetKernelLogSource(source string) {
+	n.kernelLogSource = source
+}
+
+// withKernelLog appends kernel messages mentioning the device, which were logged shortly before the failure, to err;
+// reason of the failure (e.g. device held in use by another driver, IOMMU group issues) is reported by the kernel
+// only. err is returned as is when there are no such messages or kernel log is not accessible.
+func (n *NodeConfigurator) withKernelLog(err error, pciAddress string) error {
+	if err == nil || n.kernelLogSource == "" || n.kernelLogSource == KernelLogSourceDisabled {
+		return err
+	}
+
+	messages, readErr := readKernelLog(n.kernelLogSource, pciAddress)
+	if readErr != nil {
+		n.Log.WithError(readErr).WithField("source", n.kernelLogSource).
+			Debug("kernel log is not accessible - failure is reported without kernel messages")
+		return err
+	}
+	if len(messages) == 0 {
+		return err
+	}
+	return fmt.Errorf("%w; kernel log: %s", err, strings.Join(messages, " | "))
+}
+
+// readKernelLog returns up to kernelLogLines latest kernel messages mentioning the device. Kernel log is read without
+// blocking, so that reading /dev/kmsg stops at its last record instead of waiting for new ones.
+func readKernelLog(source, pciAddress string) ([]string, error) {
+	fd, err := unix.Open(source, unix.O_RDONLY|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: source, Err: err}
+	}
+	defer unix.Close(fd)
+
+	var stat unix.Stat_t
+	if err := unix.Fstat(fd, &stat); err != nil {
+		return nil, &os.PathError{Op: "stat", Path: source, Err: err}
+	}
+	// seeking to the end of /dev/kmsg would skip all of its records
+	if stat.Mode&unix.S_IFMT == unix.S_IFREG && stat.Size > kernelLogReadLimit {
+		if _, err := unix.Seek(fd, stat.Size-kernelLogReadLimit, 0); err != nil {
+			return nil, &os.PathError{Op: "seek", Path: source, Err: err}
+		}
+	}
+
+	var content bytes.Buffer
+	buf := make([]byte, 8192)
+	for content.Len() < kernelLogReadLimit {
+		read, err := unix.Read(fd, buf)
+		switch {
+		// records of /dev/kmsg were overwritten while reading them, reading continues with the oldest available one
+		case errors.Is(err, unix.EPIPE):
+			continue
+		case err != nil && !errors.Is(err, unix.EAGAIN):
+			return nil, &os.PathError{Op: "read", Path: source, Err: err}
+		case err != nil, read == 0:
+			return kernelMessages(content.String(), pciAddress), nil
+		}
+		content.Write(buf[:read])
+	}
+	return kernelMessages(content.String(), pciAddress), nil
+}
+
+// kernelMessages returns up to kernelLogLines latest messages of the log mentioning the device; /dev/kmsg records older
+// than kernelLogWindow are skipped
+func kernelMessages(log, pciAddress string) []string {
+	uptime, uptimeErr := kernelUptime()
+
+	var messages []string
+	for _, line := range strings.Split(log, "\n") {
+		// continuation lines of /dev/kmsg records carry their dictionary, e.g. " DEVICE=+pci:0000:f7:00.0"
+		if line == "" || strings.HasPrefix(line, " ") {
+			continue
+		}
+		message := line
+		if record := kmsgRecord.FindStringSubmatch(line); record != nil {
+			message = record[2]
+			if microseconds, err := strconv.ParseInt(record[1], 10, 64); err == nil && uptimeErr == nil &&
+				uptime-time.Duration(microseconds)*time.Microsecond > kernelLogWindow {
+				continue
+			}
+		}
+		if strings.Contains(message, pciAddress) {
+			messages = append(messages, sanitizeKernelMessage(message))
+		}
+	}
+	if len(messages) > kernelLogLines {
+		messages = messages[len(messages)-kernelLogLines:]
+	}
+	return messages
+}
+
+// sanitizeKernelMessage drops non-printable characters of the message and truncates it, as it ends up in status
+func sanitizeKernelMessage(message string) string {
+	message = strings.Map(func(r rune) rune {
+		if !unicode.IsPrint(r) {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(message))
+	if len(message) > kernelLogLineLength {
+		message = message[:kernelLogLineLength] + "..."
+	}
+	return message
+}
@@ -0,0 +1,137 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/faultinjection"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("Kernel log correlation", func() {
+	const pciAddress = "0000:f7:00.0"
+
+	var (
+		nc                       *NodeConfigurator
+		root                     string
+		originalKernelUptime     = kernelUptime
+		originalSysBusPciDevices = sysBusPciDevices
+		originalGetVFconfigured  = getVFconfigured
+	)
+
+	writeLog := func(lines ...string) string {
+		path := filepath.Join(root, "kmsg")
+		Expect(os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644)).To(Succeed())
+		return path
+	}
+
+	BeforeEach(func() {
+		var err error
+		root, err = os.MkdirTemp(testTmpFolder, "kernel-log")
+		Expect(err).ToNot(HaveOccurred())
+		kernelUptime = func() (time.Duration, error) { return 100 * time.Second, nil }
+		nc = NewNodeConfigurator(utils.NewLogger(), &pfBBConfigController{log: utils.NewLogger()},
+			fake.NewClientBuilder().Build(), types.NamespacedName{Namespace: "default", Name: "worker"}, nil, nil)
+	})
+
+	AfterEach(func() {
+		faultinjection.Reset()
+		kernelUptime = originalKernelUptime
+		sysBusPciDevices = originalSysBusPciDevices
+		getVFconfigured = originalGetVFconfigured
+		Expect(os.RemoveAll(root)).To(Succeed())
+	})
+
+	Context("readKernelLog", func() {
+		It("returns recent kmsg records mentioning the device", func() {
+			source := writeLog(
+				"3,100,50000000,-;vfio-pci "+pciAddress+": old failure",
+				"6,101,95000000,-;pci-pf-stub "+pciAddress+": claimed by pci-pf-stub",
+				" SUBSYSTEM=pci",
+				" DEVICE=+pci:"+pciAddress,
+				"3,102,96000000,-;vfio-pci 0000:f8:00.0: other device",
+				"3,103,99000000,-,caller=T1;vfio-pci "+pciAddress+": \x1b[31mcannot bind\x07",
+			)
+
+			Expect(readKernelLog(source, pciAddress)).To(Equal([]string{
+				"pci-pf-stub " + pciAddress + ": claimed by pci-pf-stub",
+				"vfio-pci " + pciAddress + ": [31mcannot bind",
+			}))
+		})
+
+		It("returns the latest messages of text log, truncated", func() {
+			var lines []string
+			for i := 0; i < kernelLogLines+2; i++ {
+				lines = append(lines, fmt.Sprintf("May  1 12:00:0%d worker kernel: vfio-pci %s: message %d", i, pciAddress, i))
+			}
+			lines = append(lines, "May  1 12:00:09 worker kernel: vfio-pci "+pciAddress+": "+strings.Repeat("x", kernelLogLineLength))
+
+			messages, err := readKernelLog(writeLog(lines...), pciAddress)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(messages).To(HaveLen(kernelLogLines))
+			Expect(messages[0]).To(HaveSuffix("message 3"))
+			Expect(messages[kernelLogLines-1]).To(HaveLen(kernelLogLineLength + len("...")))
+		})
+
+		It("fails when the log is not accessible", func() {
+			_, err := readKernelLog(filepath.Join(root, "missing"), pciAddress)
+
+			Expect(errors.Is(err, os.ErrNotExist)).To(BeTrue())
+		})
+	})
+
+	Context("withKernelLog", func() {
+		failure := &os.PathError{Op: "write", Path: "bind", Err: syscall.EINVAL}
+
+		It("appends kernel messages to the error", func() {
+			nc.SetKernelLogSource(writeLog("3,100,99000000,-;vfio-pci " + pciAddress + ": group is not viable"))
+
+			err := nc.withKernelLog(failure, pciAddress)
+
+			Expect(err).To(MatchError("write bind: invalid argument; kernel log: vfio-pci " + pciAddress + ": group is not viable"))
+			Expect(errors.Is(err, syscall.EINVAL)).To(BeTrue())
+		})
+
+		It("returns the error as is when there are no kernel messages", func() {
+			nc.SetKernelLogSource(writeLog("3,100,99000000,-;vfio-pci 0000:f8:00.0: group is not viable"))
+
+			Expect(nc.withKernelLog(failure, pciAddress)).To(BeIdenticalTo(failure))
+		})
+
+		It("returns the error as is when the log is not accessible or correlation is disabled", func() {
+			for _, source := range []string{"", KernelLogSourceDisabled, filepath.Join(root, "missing")} {
+				nc.SetKernelLogSource(source)
+
+				Expect(nc.withKernelLog(failure, pciAddress)).To(BeIdenticalTo(failure), source)
+			}
+		})
+	})
+
+	It("reports kernel messages of failed sriov_numvfs write", func() {
+		sysBusPciDevices = filepath.Join(root, "devices")
+		Expect(createFiles(filepath.Join(sysBusPciDevices, pciAddress), vfNumFileDefault)).To(Succeed())
+		getVFconfigured = func(string) int { return 0 }
+		Expect(faultinjection.Load(writeFaults(root, fmt.Sprintf(`{"faults": [{"operation": "sysfsWrite", "target": %q, "errno": "EBUSY"}]}`, vfNumFileDefault)))).To(Succeed())
+		nc.SetKernelLogSource(writeLog("3,100,99000000,-;pci-pf-stub " + pciAddress + ": SR-IOV: bus number out of range"))
+
+		err := nc.changeAmountOfVFs(context.TODO(), utils.PCI_PF_STUB_DASH, pciAddress, 2)
+
+		Expect(err).To(MatchError(HaveSuffix("kernel log: pci-pf-stub " + pciAddress + ": SR-IOV: bus number out of range")))
+		Expect(errors.Is(err, syscall.EBUSY)).To(BeTrue())
+	})
+})
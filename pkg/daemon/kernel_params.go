@@ -0,0 +1,119 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var (
+	// hostArch is the architecture of the node; daemon image is built per architecture, so it matches GOARCH
+	hostArch = runtime.GOARCH
+
+	// defaultKernelParams are IOMMU params required to configure the accelerators, by architecture
+	defaultKernelParams = map[string][]string{
+		"amd64": {"intel_iommu=on", "iommu=pt"},
+		"arm64": {"iommu.passthrough=1"},
+	}
+)
+
+// requiredKernelParams returns kernel params required on given architecture. Params from discovery config take
+// precedence over the defaults; no params are required on architectures known to neither of them.
+func requiredKernelParams(config utils.AcceleratorDiscoveryConfig, arch string) []string {
+	if params, ok := config.KernelParams[arch]; ok {
+		return params
+	}
+	return defaultKernelParams[arch]
+}
+
+// kernelCmdline maps normalized names of kernel params to their values in order of appearance; the same param may be
+// given several times (e.g. duplicated by bootloader tooling)
+type kernelCmdline map[string][]string
+
+// parseKernelCmdline splits kernel command line into params; double quotes protect spaces in values, like the kernel
+// does
+func parseKernelCmdline(cmdline string) kernelCmdline {
+	params := kernelCmdline{}
+	for _, field := range splitKernelCmdline(cmdline) {
+		name, value, _ := strings.Cut(field, "=")
+		name = normalizeKernelParamName(name)
+		params[name] = append(params[name], strings.Trim(value, `"`))
+	}
+	return params
+}
+
+func splitKernelCmdline(cmdline string) []string {
+	var fields []string
+	var current strings.Builder
+	quoted := false
+	for _, r := range cmdline {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			current.WriteRune(r)
+		case !quoted && (r == ' ' || r == '\t' || r == '\n'):
+			if current.Len() > 0 {
+				fields = append(fields, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		fields = append(fields, current.String())
+	}
+	return fields
+}
+
+// normalizeKernelParamName treats dashes and underscores in param name as equal, as the kernel does
+func normalizeKernelParamName(name string) string {
+	return strings.ReplaceAll(name, "-", "_")
+}
+
+// require checks that param given as name or name=value is set. The last occurrence of the param is effective, its
+// comma separated options (e.g. intel_iommu=on,sm_on) and boolean spellings (on/1/y) are compared semantically.
+// Param set to another value is reported as conflict rather than missing.
+func (c kernelCmdline) require(param string) error {
+	name, value, hasValue := strings.Cut(param, "=")
+	values, ok := c[normalizeKernelParamName(name)]
+	if !ok {
+		return fmt.Errorf("missing kernel param(%s)", param)
+	}
+	if !hasValue {
+		return nil
+	}
+
+	effective := values[len(values)-1]
+	for _, option := range strings.Split(effective, ",") {
+		if kernelParamValuesEqual(option, value) {
+			return nil
+		}
+	}
+	return fmt.Errorf("kernel param(%s=%s) conflicts with required %s", name, effective, param)
+}
+
+func kernelParamValuesEqual(a, b string) bool {
+	if strings.EqualFold(a, b) {
+		return true
+	}
+	boolA, okA := kernelParamBool(a)
+	boolB, okB := kernelParamBool(b)
+	return okA && okB && boolA == boolB
+}
+
+// kernelParamBool parses boolean value the way kernel's kstrtobool does
+func kernelParamBool(value string) (bool, bool) {
+	switch strings.ToLower(value) {
+	case "1", "y", "yes", "on", "true":
+		return true, true
+	case "0", "n", "no", "off", "false":
+		return false, true
+	}
+	return false, false
+}
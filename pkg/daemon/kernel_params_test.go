@@ -0,0 +1,87 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("requiredKernelParams", func() {
+	const (
+		amd64Cmdline = "BOOT_IMAGE=/vmlinuz intel_iommu=on iommu=pt quiet"
+		arm64Cmdline = "BOOT_IMAGE=/Image iommu.passthrough=1 quiet"
+	)
+
+	It("will require Intel IOMMU params on amd64", func() {
+		params := requiredKernelParams(utils.AcceleratorDiscoveryConfig{}, "amd64")
+		Expect(params).To(Equal([]string{"intel_iommu=on", "iommu=pt"}))
+		Expect(validateOrdinalKernelParams(amd64Cmdline, params)).To(Succeed())
+		Expect(validateOrdinalKernelParams(arm64Cmdline, params)).To(MatchError("missing kernel param(intel_iommu=on)"))
+	})
+
+	It("will require SMMU passthrough on arm64", func() {
+		params := requiredKernelParams(utils.AcceleratorDiscoveryConfig{}, "arm64")
+		Expect(params).To(Equal([]string{"iommu.passthrough=1"}))
+		Expect(validateOrdinalKernelParams(arm64Cmdline, params)).To(Succeed())
+		Expect(validateOrdinalKernelParams(amd64Cmdline, params)).To(MatchError("missing kernel param(iommu.passthrough=1)"))
+	})
+
+	It("will prefer params of discovery config", func() {
+		config := utils.AcceleratorDiscoveryConfig{KernelParams: map[string][]string{"arm64": {"arm-smmu.disable_bypass=0"}, "amd64": {}}}
+		Expect(requiredKernelParams(config, "arm64")).To(Equal([]string{"arm-smmu.disable_bypass=0"}))
+		Expect(validateOrdinalKernelParams("quiet", requiredKernelParams(config, "amd64"))).To(Succeed())
+	})
+
+	It("will not require any params on unknown architecture", func() {
+		Expect(requiredKernelParams(utils.AcceleratorDiscoveryConfig{}, "riscv64")).To(BeEmpty())
+	})
+})
+
+var _ = Describe("validateOrdinalKernelParams", func() {
+	required := []string{"intel_iommu=on", "iommu=pt"}
+
+	DescribeTable("real-world cmdlines",
+		func(cmdline string, expectedErr string) {
+			err := validateOrdinalKernelParams(cmdline, required)
+			if expectedErr == "" {
+				Expect(err).ToNot(HaveOccurred())
+			} else {
+				Expect(err).To(MatchError(expectedErr))
+			}
+		},
+		Entry("RHEL with params duplicated by repeated grubby calls",
+			"BOOT_IMAGE=(hd0,gpt2)/vmlinuz-4.18.0-372.9.1.el8.x86_64 root=/dev/mapper/rhel-root ro crashkernel=auto "+
+				"resume=/dev/mapper/rhel-swap rd.lvm.lv=rhel/root rd.lvm.lv=rhel/swap rhgb quiet intel_iommu=on iommu=pt "+
+				"intel_iommu=on iommu=pt", ""),
+		Entry("RHCOS with params in reverse order",
+			"BOOT_IMAGE=(hd0,gpt3)/ostree/rhcos-5e2d/vmlinuz-4.18.0-305.el8.x86_64 random.trust_cpu=on console=tty0 "+
+				"console=ttyS0,115200n8 ignition.platform.id=metal ostree=/ostree/boot.1/rhcos/5e2d/0 root=UUID=91d8 rw "+
+				"rootflags=prjquota iommu=pt intel_iommu=on", ""),
+		Entry("Ubuntu with comma separated options and scalable mode",
+			`BOOT_IMAGE=/boot/vmlinuz-5.15.0-76-generic root=UUID=5c1c ro quiet splash intel_iommu=on,sm_on iommu=pt vt.handoff=7`, ""),
+		Entry("IOMMU passthrough listed as one of options", "root=/dev/sda1 intel_iommu=pt,on iommu=pt", ""),
+		Entry("boolean value spelled differently", "root=/dev/sda1 intel_iommu=1 iommu=pt", ""),
+		Entry("param name with dashes", "root=/dev/sda1 intel-iommu=on iommu=pt", ""),
+		Entry("quoted value with spaces", `root=/dev/sda1 dyndbg="file drivers/iommu/* +p" intel_iommu=on iommu=pt`, ""),
+		Entry("only substring of required param", "root=/dev/sda1 xintel_iommu=on iommu=pt",
+			"missing kernel param(intel_iommu=on)"),
+		Entry("param disabled", "root=/dev/sda1 intel_iommu=off iommu=pt",
+			"kernel param(intel_iommu=off) conflicts with required intel_iommu=on"),
+		Entry("param overridden by later occurrence", "root=/dev/sda1 intel_iommu=on iommu=pt intel_iommu=off",
+			"kernel param(intel_iommu=off) conflicts with required intel_iommu=on"),
+		Entry("param enabled by later occurrence", "root=/dev/sda1 intel_iommu=off iommu=pt intel_iommu=on", ""),
+		Entry("different IOMMU mode", "root=/dev/sda1 intel_iommu=on iommu=nopt",
+			"kernel param(iommu=nopt) conflicts with required iommu=pt"),
+	)
+
+	It("will match flag params by name", func() {
+		Expect(validateOrdinalKernelParams("root=/dev/sda1 nosmt quiet", []string{"nosmt"})).To(Succeed())
+		Expect(validateOrdinalKernelParams("root=/dev/sda1 nosmt=force", []string{"nosmt"})).To(Succeed())
+		Expect(validateOrdinalKernelParams("root=/dev/sda1 quiet", []string{"nosmt"})).To(MatchError("missing kernel param(nosmt)"))
+	})
+})
@@ -0,0 +1,139 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	fec "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+)
+
+// fecWithoutLogicalNamePrefixes returns copy of PFs without logicalNamePrefix; logical names are only published, so
+// changing the prefix alone does not require reconfiguration
+func fecWithoutLogicalNamePrefixes(pfs []fec.PhysicalFunctionConfigExt) []fec.PhysicalFunctionConfigExt {
+	if pfs == nil {
+		return nil
+	}
+	stripped := make([]fec.PhysicalFunctionConfigExt, len(pfs))
+	for i, pf := range pfs {
+		pf.LogicalNamePrefix = ""
+		stripped[i] = pf
+	}
+	return stripped
+}
+
+func vrbWithoutLogicalNamePrefixes(pfs []vrbv1.PhysicalFunctionConfigExt) []vrbv1.PhysicalFunctionConfigExt {
+	if pfs == nil {
+		return nil
+	}
+	stripped := make([]vrbv1.PhysicalFunctionConfigExt, len(pfs))
+	for i, pf := range pfs {
+		pf.LogicalNamePrefix = ""
+		stripped[i] = pf
+	}
+	return stripped
+}
+
+// fecSetLogicalNames assigns logical names to VFs found in inventory exposed in status of the node config
+func fecSetLogicalNames(nc *fec.SriovFecNodeConfig) {
+	vfs := map[string][]string{}
+	for _, acc := range nc.Status.Inventory.SriovAccelerators {
+		for _, vf := range acc.VFs {
+			vfs[acc.PCIAddress] = append(vfs[acc.PCIAddress], vf.PCIAddress)
+		}
+	}
+	nc.Status.LogicalNames = fec.AssignLogicalNames(nc.Spec.PhysicalFunctions, vfs)
+}
+
+func vrbSetLogicalNames(nc *vrbv1.SriovVrbNodeConfig) {
+	vfs := map[string][]string{}
+	for _, acc := range nc.Status.Inventory.SriovAccelerators {
+		for _, vf := range acc.VFs {
+			vfs[acc.PCIAddress] = append(vfs[acc.PCIAddress], vf.PCIAddress)
+		}
+	}
+	nc.Status.LogicalNames = vrbv1.AssignLogicalNames(nc.Spec.PhysicalFunctions, vfs)
+}
+
+// publishFecLogicalNames publishes logical names of VFs configured by the primary and secondary node configs of
+// the node in fec.LogicalNamesAnnotation of the node; nc is the node config which status was just patched
+func (r *NodeConfigReconciler) publishFecLogicalNames(ctx context.Context, nc *fec.SriovFecNodeConfig) {
+	nodeConfigs := &fec.SriovFecNodeConfigList{}
+	if err := r.List(ctx, nodeConfigs, client.InNamespace(r.nodeNameRef.Namespace)); err != nil {
+		r.log.WithError(err).Warn("failed to list node configs - logical names are not published in node annotation")
+		return
+	}
+
+	names := map[string]string{}
+	addLogicalNames := func(pfs []fec.PFLogicalNames) {
+		for _, pf := range pfs {
+			for name, pciAddress := range pf.VFs {
+				names[name] = pciAddress
+			}
+		}
+	}
+	addLogicalNames(nc.Status.LogicalNames)
+	for i := range nodeConfigs.Items {
+		other := &nodeConfigs.Items[i]
+		if other.Name != nc.Name && (other.Name == r.nodeConfigKey().Name || isSecondaryNodeConfigOf(other, r.nodeNameRef.Name)) {
+			addLogicalNames(other.Status.LogicalNames)
+		}
+	}
+	r.publishLogicalNames(ctx, fec.LogicalNamesAnnotation, names)
+}
+
+// publishVrbLogicalNames publishes logical names of VFs configured by the node config in vrbv1.LogicalNamesAnnotation
+func (r *NodeConfigReconciler) publishVrbLogicalNames(ctx context.Context, nc *vrbv1.SriovVrbNodeConfig) {
+	names := map[string]string{}
+	for _, pf := range nc.Status.LogicalNames {
+		for name, pciAddress := range pf.VFs {
+			names[name] = pciAddress
+		}
+	}
+	r.publishLogicalNames(ctx, vrbv1.LogicalNamesAnnotation, names)
+}
+
+// publishLogicalNames sets the annotation of the node to JSON object mapping logical names to PCI addresses, or
+// removes it when there are no names. It is best-effort, as names are published in node config status as well.
+func (r *NodeConfigReconciler) publishLogicalNames(ctx context.Context, annotation string, names map[string]string) {
+	log := r.log.WithField("annotation", annotation)
+	node := &corev1.Node{}
+	if err := r.Get(ctx, client.ObjectKey{Name: r.nodeNameRef.Name}, node); err != nil {
+		log.WithError(err).Warn("failed to get the node - logical names are not published in its annotation")
+		return
+	}
+
+	var value string
+	if len(names) > 0 {
+		content, err := json.Marshal(names)
+		if err != nil {
+			log.WithError(err).Warn("failed to marshal logical names")
+			return
+		}
+		value = string(content)
+	}
+	if node.GetAnnotations()[annotation] == value {
+		return
+	}
+
+	original := node.DeepCopy()
+	if value == "" {
+		delete(node.Annotations, annotation)
+	} else {
+		if node.Annotations == nil {
+			node.Annotations = map[string]string{}
+		}
+		node.Annotations[annotation] = value
+	}
+	if err := r.Patch(ctx, node, client.MergeFrom(original)); err != nil {
+		log.WithError(err).Warn("failed to publish logical names in node annotation")
+		return
+	}
+	log.WithField("names", len(names)).Info("published logical names in node annotation")
+}
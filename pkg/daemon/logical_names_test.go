@@ -0,0 +1,131 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("Logical names of VFs", func() {
+	var (
+		nodeNameRef               = types.NamespacedName{Name: "worker", Namespace: "default"}
+		reconciler                *NodeConfigReconciler
+		originalGetSriovInventory = getSriovInventory
+	)
+
+	nodeConfig := func(name, nodeName, pfPCIAddress, prefix string) *sriovv2.SriovFecNodeConfig {
+		return &sriovv2.SriovFecNodeConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: nodeNameRef.Namespace},
+			Spec: sriovv2.SriovFecNodeConfigSpec{NodeName: nodeName, PhysicalFunctions: []sriovv2.PhysicalFunctionConfigExt{
+				{PCIAddress: pfPCIAddress, LogicalNamePrefix: prefix},
+			}},
+		}
+	}
+
+	nodeAnnotation := func() string {
+		node := &corev1.Node{}
+		Expect(reconciler.Get(context.TODO(), client.ObjectKey{Name: nodeNameRef.Name}, node)).To(Succeed())
+		return node.Annotations[sriovv2.LogicalNamesAnnotation]
+	}
+
+	BeforeEach(func() {
+		getSriovInventory = func(context.Context, *logrus.Logger) (*sriovv2.NodeInventory, error) {
+			return &sriovv2.NodeInventory{SriovAccelerators: []sriovv2.SriovAccelerator{
+				{PCIAddress: "0000:f7:00.0", VFs: []sriovv2.VF{{PCIAddress: "0000:f7:00.2"}, {PCIAddress: "0000:f7:00.1"}}},
+				{PCIAddress: "0000:f8:00.0", VFs: []sriovv2.VF{{PCIAddress: "0000:f8:00.1"}}},
+			}}, nil
+		}
+
+		scheme := runtime.NewScheme()
+		Expect(corev1.AddToScheme(scheme)).To(Succeed())
+		Expect(sriovv2.AddToScheme(scheme)).To(Succeed())
+		secondary := nodeConfig("worker-acc100", nodeNameRef.Name, "0000:f8:00.0", "fec-acc100")
+		secondary.Status.LogicalNames = []sriovv2.PFLogicalNames{{PCIAddress: "0000:f8:00.0", VFs: map[string]string{"fec-acc100-0-vf0": "0000:f8:00.1"}}}
+		reconciler = &NodeConfigReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+				&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Annotations: map[string]string{"other": "kept"}}},
+				nodeConfig(nodeNameRef.Name, "", "0000:f7:00.0", "fec"),
+				secondary,
+				// node config of another node
+				nodeConfig("worker-2", "", "0000:f7:00.0", "fec-other"),
+			).Build(),
+			log:         utils.NewLogger(),
+			nodeNameRef: nodeNameRef,
+			recorder:    record.NewFakeRecorder(10),
+		}
+	})
+
+	AfterEach(func() {
+		getSriovInventory = originalGetSriovInventory
+	})
+
+	It("are published in status and node annotation", func() {
+		nc := &sriovv2.SriovFecNodeConfig{}
+		Expect(reconciler.Get(context.TODO(), nodeNameRef, nc)).To(Succeed())
+		Expect(reconciler.updateStatus(context.TODO(), nc, metav1.ConditionTrue, ConfigurationSucceeded, "Configured successfully")).To(Succeed())
+
+		stored := &sriovv2.SriovFecNodeConfig{}
+		Expect(reconciler.Get(context.TODO(), nodeNameRef, stored)).To(Succeed())
+		Expect(stored.Status.LogicalNames).To(Equal([]sriovv2.PFLogicalNames{
+			{PCIAddress: "0000:f7:00.0", VFs: map[string]string{"fec-0-vf0": "0000:f7:00.1", "fec-0-vf1": "0000:f7:00.2"}},
+		}))
+		pciAddress, found := stored.ResolveLogicalName("fec-0-vf1")
+		Expect(found).To(BeTrue())
+		Expect(pciAddress).To(Equal("0000:f7:00.2"))
+
+		// names of the secondary node config are published too
+		Expect(nodeAnnotation()).To(Equal(`{"fec-0-vf0":"0000:f7:00.1","fec-0-vf1":"0000:f7:00.2","fec-acc100-0-vf0":"0000:f8:00.1"}`))
+		node := &corev1.Node{}
+		Expect(reconciler.Get(context.TODO(), client.ObjectKey{Name: nodeNameRef.Name}, node)).To(Succeed())
+		Expect(node.Annotations).To(HaveKeyWithValue("other", "kept"))
+		Expect(sriovv2.ResolveNodeLogicalName(node, "fec-acc100-0-vf0")).To(Equal("0000:f8:00.1"))
+	})
+
+	It("are removed from node annotation once prefixes are removed", func() {
+		nc := &sriovv2.SriovFecNodeConfig{}
+		Expect(reconciler.Get(context.TODO(), nodeNameRef, nc)).To(Succeed())
+		inv, err := getSriovInventory(context.TODO(), reconciler.log)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reconciler.refreshInventory(context.TODO(), nc, inv)).To(Succeed())
+		Expect(nodeAnnotation()).To(ContainSubstring("fec-0-vf0"))
+
+		secondary := &sriovv2.SriovFecNodeConfig{}
+		Expect(reconciler.Get(context.TODO(), types.NamespacedName{Name: "worker-acc100", Namespace: nodeNameRef.Namespace}, secondary)).To(Succeed())
+		secondary.Spec.PhysicalFunctions[0].LogicalNamePrefix = ""
+		Expect(reconciler.refreshInventory(context.TODO(), secondary, inv)).To(Succeed())
+		Expect(nodeAnnotation()).ToNot(ContainSubstring("fec-acc100"))
+
+		nc.Spec.PhysicalFunctions[0].LogicalNamePrefix = ""
+		Expect(reconciler.refreshInventory(context.TODO(), nc, inv)).To(Succeed())
+		Expect(nodeAnnotation()).To(BeEmpty())
+	})
+
+	It("do not require reconfiguration when prefix changes", func() {
+		pfs := nodeConfig(nodeNameRef.Name, "", "0000:f7:00.0", "fec").Spec.PhysicalFunctions
+		stripped := fecWithoutLogicalNamePrefixes(pfs)
+		Expect(stripped[0].LogicalNamePrefix).To(BeEmpty())
+		Expect(pfs[0].LogicalNamePrefix).To(Equal("fec"), "spec of node config is not modified")
+
+		hash, err := specHash(sriovv2.SriovFecNodeConfigSpec{PhysicalFunctions: stripped})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(specHash(sriovv2.SriovFecNodeConfigSpec{PhysicalFunctions: []sriovv2.PhysicalFunctionConfigExt{{PCIAddress: "0000:f7:00.0"}}})).To(Equal(hash))
+		// spec without PFs keeps its hash
+		Expect(fecWithoutLogicalNamePrefixes([]sriovv2.PhysicalFunctionConfigExt{})).ToNot(BeNil())
+		Expect(fecWithoutLogicalNamePrefixes(nil)).To(BeNil())
+	})
+})
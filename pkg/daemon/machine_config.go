@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// mcoDesiredConfigAnnotation is set on nodes managed by Machine Config Operator (OpenShift); it names the rendered
+// MachineConfig of node's pool, which the node runs or is being updated to
+const mcoDesiredConfigAnnotation = "machineconfiguration.openshift.io/desiredConfig"
+
+var machineConfigGVK = schema.GroupVersionKind{Group: "machineconfiguration.openshift.io", Version: "v1", Kind: "MachineConfig"}
+
+// kernelParamsDeferral tells why configuration waits for required kernel params which are missing in /proc/cmdline.
+// On OpenShift, users often add them with a MachineConfig; when rendered MachineConfig of the node provides all of
+// them, MCO reboots the node with them, so configuration is deferred instead of failed. Empty string is returned
+// when params are not missing or nothing is going to provide them, e.g. on clusters without MCO.
+func (r *NodeConfigReconciler) kernelParamsDeferral(ctx context.Context, required []string) string {
+	cmdline, err := os.ReadFile(procCmdlineFilePath)
+	if err != nil || validateOrdinalKernelParams(string(cmdline), required) == nil {
+		return ""
+	}
+
+	renderedConfig, kernelArguments, err := r.desiredMachineConfigKernelArguments(ctx)
+	if err != nil {
+		r.log.WithError(err).Warn("failed to check kernel params provided by MachineConfig")
+		return ""
+	}
+	if renderedConfig == "" || validateOrdinalKernelParams(strings.Join(kernelArguments, " "), required) != nil {
+		return ""
+	}
+	return fmt.Sprintf("waiting for kernel params (%s) of MachineConfig %s to be applied by reboot of the node",
+		strings.Join(required, " "), renderedConfig)
+}
+
+// desiredMachineConfigKernelArguments returns name and kernel arguments of rendered MachineConfig which MCO applies to
+// the node; empty name is returned when the node is not managed by MCO
+func (r *NodeConfigReconciler) desiredMachineConfigKernelArguments(ctx context.Context) (string, []string, error) {
+	// unstructured objects are read directly rather than through informers, so no cache of all nodes is started
+	node := &unstructured.Unstructured{}
+	node.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Node"))
+	if err := r.Get(ctx, client.ObjectKey{Name: r.nodeNameRef.Name}, node); err != nil {
+		return "", nil, fmt.Errorf("failed to get node - %v", err)
+	}
+	renderedConfig := node.GetAnnotations()[mcoDesiredConfigAnnotation]
+	if renderedConfig == "" {
+		return "", nil, nil
+	}
+
+	machineConfig := &unstructured.Unstructured{}
+	machineConfig.SetGroupVersionKind(machineConfigGVK)
+	if err := r.Get(ctx, client.ObjectKey{Name: renderedConfig}, machineConfig); err != nil {
+		if k8serrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return "", nil, nil
+		}
+		return "", nil, fmt.Errorf("failed to get MachineConfig %s - %v", renderedConfig, missingPermissionError(err))
+	}
+	kernelArguments, _, err := unstructured.NestedStringSlice(machineConfig.Object, "spec", "kernelArguments")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read kernelArguments of MachineConfig %s - %v", renderedConfig, err)
+	}
+	return renderedConfig, kernelArguments, nil
+}
@@ -0,0 +1,144 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/drainhelper"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("NodeConfigReconciler.Reconcile with kernel params of MachineConfig", func() {
+	var (
+		nodeNameRef = types.NamespacedName{Name: "worker", Namespace: "testNamespace"}
+		configured  bool
+	)
+
+	machineConfig := func(name string, kernelArguments ...interface{}) *unstructured.Unstructured {
+		mc := &unstructured.Unstructured{Object: map[string]interface{}{
+			"spec": map[string]interface{}{"kernelArguments": kernelArguments},
+		}}
+		mc.SetGroupVersionKind(machineConfigGVK)
+		mc.SetName(name)
+		return mc
+	}
+
+	reconcile := func(node *corev1.Node, objs ...client.Object) *metav1.Condition {
+		scheme := runtime.NewScheme()
+		Expect(sriovv2.AddToScheme(scheme)).To(Succeed())
+		Expect(vrbv1.AddToScheme(scheme)).To(Succeed())
+		Expect(corev1.AddToScheme(scheme)).To(Succeed())
+		scheme.AddKnownTypeWithName(machineConfigGVK, &unstructured.Unstructured{})
+
+		fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).WithObjects(
+			node,
+			&sriovv2.SriovFecNodeConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace},
+				Spec: sriovv2.SriovFecNodeConfigSpec{
+					PhysicalFunctions: []sriovv2.PhysicalFunctionConfigExt{{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, VFDriver: utils.IGB_UIO, VFAmount: 1}},
+				},
+			},
+			&vrbv1.SriovVrbNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace}},
+		).Build()
+
+		reconciler, err := NewNodeConfigReconciler(fakeClient, &drainhelper.FakeDrainer{}, nodeNameRef,
+			testConfigurerProto{configureNodeFunction: func(sriovv2.SriovFecNodeConfigSpec) error {
+				configured = true
+				return nil
+			}}, nil,
+			func(context.Context) error { return nil },
+			record.NewFakeRecorder(10), nil)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: nodeNameRef})
+		Expect(err).ToNot(HaveOccurred())
+
+		nc := &sriovv2.SriovFecNodeConfig{}
+		Expect(fakeClient.Get(context.TODO(), nodeNameRef, nc)).To(Succeed())
+		return nc.FindCondition(ConditionConfigured)
+	}
+
+	openShiftNode := func(renderedConfig string) *corev1.Node {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name,
+			Annotations: map[string]string{mcoDesiredConfigAnnotation: renderedConfig}}}
+	}
+
+	BeforeEach(func() {
+		hostArch = "amd64"
+		configPath = "testdata/accelerators.json"
+		VrbconfigPath = "testdata/accelerators_vrb.json"
+		// node was not rebooted with IOMMU params yet
+		procCmdlineFilePath = filepath.Join(testTmpFolder, "cmdline")
+		Expect(os.WriteFile(procCmdlineFilePath, []byte("BOOT_IMAGE=/vmlinuz root=/dev/sda1"), 0644)).To(Succeed())
+		sysLockdownFilePath = filepath.Join(testTmpFolder, "lockdown")
+		Expect(os.WriteFile(sysLockdownFilePath, []byte("[none] integrity confidentiality"), 0644)).To(Succeed())
+
+		getSriovInventory = func(_ context.Context, log *logrus.Logger) (*sriovv2.NodeInventory, error) {
+			return &sriovv2.NodeInventory{
+				SriovAccelerators: []sriovv2.SriovAccelerator{{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, MaxVFs: 10}},
+			}, nil
+		}
+		VrbgetSriovInventory = func(_ context.Context, log *logrus.Logger) (*vrbv1.NodeInventory, error) {
+			return &vrbv1.NodeInventory{}, nil
+		}
+		configured = false
+	})
+
+	AfterEach(func() {
+		getSriovInventory = GetSriovInventory
+		VrbgetSriovInventory = VrbGetSriovInventory
+		procCmdlineFilePath = "testdata/cmdline_test"
+		sysLockdownFilePath = "/sys/kernel/security/lockdown"
+	})
+
+	It("defers configuration until MCO reboots the node with params of rendered MachineConfig", func() {
+		condition := reconcile(openShiftNode("rendered-worker-1a2b"),
+			machineConfig("rendered-worker-1a2b", "intel_iommu=on", "iommu=pt", "hugepagesz=1G"))
+
+		Expect(configured).To(BeFalse())
+		Expect(condition.Reason).To(Equal(string(ConfigurationDeferred)))
+		Expect(condition.Message).To(Equal("waiting for kernel params (intel_iommu=on iommu=pt) of MachineConfig " +
+			"rendered-worker-1a2b to be applied by reboot of the node"))
+	})
+
+	It("fails configuration when rendered MachineConfig does not provide all params", func() {
+		condition := reconcile(openShiftNode("rendered-worker-1a2b"), machineConfig("rendered-worker-1a2b", "intel_iommu=on"))
+
+		Expect(condition.Reason).To(Equal(string(ConfigurationFailed)))
+		Expect(condition.Message).To(ContainSubstring("missing kernel param(intel_iommu=on)"))
+	})
+
+	It("fails configuration on clusters without MCO", func() {
+		condition := reconcile(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name}})
+
+		Expect(condition.Reason).To(Equal(string(ConfigurationFailed)))
+		Expect(condition.Message).To(ContainSubstring("missing kernel param(intel_iommu=on)"))
+	})
+
+	It("configures the node once it runs with the params", func() {
+		Expect(os.WriteFile(procCmdlineFilePath, []byte("BOOT_IMAGE=/vmlinuz intel_iommu=on iommu=pt"), 0644)).To(Succeed())
+
+		condition := reconcile(openShiftNode("rendered-worker-1a2b"), machineConfig("rendered-worker-1a2b", "intel_iommu=on", "iommu=pt"))
+
+		Expect(configured).To(BeTrue())
+		Expect(condition.Reason).To(Equal(string(ConfigurationSucceeded)))
+	})
+})
@@ -0,0 +1,37 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// forbiddenByRBAC matches reason of Forbidden error returned by RBAC authorizer, e.g.
+// User "system:serviceaccount:ns:sriov-fec-daemon" cannot delete resource "pods" in API group "" in the namespace "ns"
+var forbiddenByRBAC = regexp.MustCompile(`User "([^"]+)" cannot (\S+) resource "([^"]+)" in API group "([^"]*)"(?: in the namespace "([^"]+)")?`)
+
+// missingPermissionError names the permission which daemon lacks when err was caused by RBAC denial; other errors are
+// returned unchanged. Message is matched instead of the error type, because type is lost when error is formatted into
+// another one on its way up (e.g. by kubectl's drain).
+func missingPermissionError(err error) error {
+	if err == nil {
+		return nil
+	}
+	match := forbiddenByRBAC.FindStringSubmatch(err.Error())
+	if match == nil {
+		return err
+	}
+	user, verb, resource, group, namespace := match[1], match[2], match[3], match[4], match[5]
+
+	if group == "" {
+		group = "core"
+	}
+	scope := "cluster-wide"
+	if namespace != "" {
+		scope = "in namespace " + namespace
+	}
+	return fmt.Errorf("missing permission: %s is not allowed to %s %s (API group %s) %s; grant it to the daemon's role - %v",
+		user, verb, resource, group, scope, err)
+}
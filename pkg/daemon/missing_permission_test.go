@@ -0,0 +1,130 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+const daemonUser = "system:serviceaccount:testNamespace:sriov-fec-daemon"
+
+// forbidden is the error returned by API server when RBAC denies the request
+func forbidden(verb, resource, name, namespace string) error {
+	reason := fmt.Sprintf(`User "%s" cannot %s resource "%s" in API group ""`, daemonUser, verb, resource)
+	if namespace != "" {
+		reason += fmt.Sprintf(` in the namespace "%s"`, namespace)
+	} else {
+		reason += " at the cluster scope"
+	}
+	return apierrors.NewForbidden(schema.GroupResource{Resource: resource}, name, errors.New(reason))
+}
+
+// verbRejectingClient denies deletes and patches as if daemon's role did not grant them
+type verbRejectingClient struct {
+	client.Client
+	rejectDelete, rejectPatch bool
+}
+
+func (c *verbRejectingClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	if c.rejectDelete {
+		return forbidden("delete", "pods", obj.GetName(), obj.GetNamespace())
+	}
+	return c.Client.Delete(ctx, obj, opts...)
+}
+
+func (c *verbRejectingClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if c.rejectPatch {
+		return forbidden("patch", "nodes", obj.GetName(), "")
+	}
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+var _ = Describe("Missing permission", func() {
+	nodeNameRef := types.NamespacedName{Name: "worker", Namespace: "testNamespace"}
+
+	newClient := func(rejectDelete, rejectPatch bool) client.Client {
+		scheme := runtime.NewScheme()
+		Expect(corev1.AddToScheme(scheme)).To(Succeed())
+		return &verbRejectingClient{
+			Client: fake.NewClientBuilder().WithScheme(scheme).
+				WithObjects(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name}}).
+				WithObjects(&corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Name: "sriov-device-plugin-abcde", Namespace: nodeNameRef.Namespace,
+						Labels: devicePluginSelector, OwnerReferences: controlledBy("DaemonSet", "sriov-device-plugin")},
+					Spec: corev1.PodSpec{NodeName: nodeNameRef.Name},
+				}).Build(),
+			rejectDelete: rejectDelete,
+			rejectPatch:  rejectPatch,
+		}
+	}
+
+	newReconciler := func(c client.Client, drainer DrainAndExecute) *NodeConfigReconciler {
+		return &NodeConfigReconciler{
+			Client:            c,
+			log:               utils.NewLogger(),
+			nodeNameRef:       nodeNameRef,
+			drainerAndExecute: drainer,
+			sriovfecconfigurer: testConfigurerProto{configureNodeFunction: func(sriovv2.SriovFecNodeConfigSpec) error {
+				return nil
+			}},
+			restartDevicePlugin: NewDevicePluginController(c, utils.NewLogger(), nodeNameRef, record.NewFakeRecorder(10)).RestartDevicePlugin,
+		}
+	}
+
+	executeWithoutDrain := func(_ context.Context, configurer func(ctx context.Context) bool, _ bool) error {
+		configurer(context.TODO())
+		return nil
+	}
+
+	It("should name the verb denied to the daemon when device plugin cannot be restarted", func() {
+		r := newReconciler(newClient(true, false), executeWithoutDrain)
+
+		err := r.configureNode(context.TODO(), &sriovv2.SriovFecNodeConfig{Spec: sriovv2.SriovFecNodeConfigSpec{DrainSkip: true}})
+
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(HavePrefix("missing permission: " + daemonUser +
+			" is not allowed to delete pods (API group core) in namespace testNamespace; grant it to the daemon's role - "))
+		Expect(err.Error()).To(ContainSubstring("failed to delete sriov-device-plugin-daemonset pod"))
+	})
+
+	It("should name the verb denied to the daemon when node cannot be cordoned", func() {
+		c := newClient(false, true)
+		r := newReconciler(c, func(_ context.Context, configurer func(ctx context.Context) bool, drain bool) error {
+			node := &corev1.Node{}
+			Expect(c.Get(context.TODO(), client.ObjectKey{Name: nodeNameRef.Name}, node)).To(Succeed())
+			return c.Patch(context.TODO(), node, client.MergeFrom(node.DeepCopy()))
+		})
+
+		err := r.configureNode(context.TODO(), &sriovv2.SriovFecNodeConfig{})
+
+		Expect(err).To(MatchError(HavePrefix("missing permission: " + daemonUser +
+			" is not allowed to patch nodes (API group core) cluster-wide; grant it to the daemon's role - ")))
+	})
+
+	It("should leave errors other than RBAC denial unchanged", func() {
+		err := errors.New("failed to get pods")
+		Expect(missingPermissionError(err)).To(Equal(err))
+		Expect(missingPermissionError(nil)).To(BeNil())
+
+		notFound := apierrors.NewNotFound(schema.GroupResource{Resource: "pods"}, "pod")
+		Expect(missingPermissionError(notFound)).To(Equal(notFound))
+	})
+})
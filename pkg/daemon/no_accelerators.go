@@ -0,0 +1,64 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+const defaultDegradedRequeueInterval = 5 * time.Minute
+
+// degradedRequeueInterval returns interval of reconciliation of node config which cannot be configured until the node
+// is fixed (e.g. no accelerators are discovered); it is configured with DEGRADED_REQUEUE_INTERVAL env variable
+func degradedRequeueInterval(log *logrus.Logger) time.Duration {
+	interval := defaultDegradedRequeueInterval
+	intervalEnv := os.Getenv(utils.SRIOV_PREFIX + "DEGRADED_REQUEUE_INTERVAL")
+	if intervalEnv != "" {
+		envDuration, err := time.ParseDuration(intervalEnv)
+		if err != nil || envDuration <= 0 {
+			log.WithError(err).WithField("default", interval).Error("user-provided value is incorrect 'Duration', using default value instead")
+		} else {
+			interval = envDuration
+		}
+	}
+	return interval
+}
+
+// noAcceleratorsDiscovered tells whether spec requests PFs while inventory has no supported accelerators at all.
+// Configuration would fail only after drain then, so it is not attempted.
+func noAcceleratorsDiscovered(requestedPFs, discoveredAccelerators int) bool {
+	return requestedPFs > 0 && discoveredAccelerators == 0
+}
+
+func noAcceleratorsDiscoveredMessage(requestedPFs int) string {
+	return fmt.Sprintf("no supported accelerators discovered on the node while %d PF(s) are requested - check whether "+
+		"devices are visible with lspci and whether dmesg reports errors of them or their drivers", requestedPFs)
+}
+
+// reportNoAcceleratorsDiscovered emits warning event, unless node config already reports that no accelerators are
+// discovered
+func (r *NodeConfigReconciler) reportNoAcceleratorsDiscovered(nc runtime.Object, previous *metav1.Condition, msg string) {
+	r.log.WithField("message", msg).Error("no supported accelerators discovered - configuration is not attempted")
+	if r.recorder == nil || (previous != nil && previous.Reason == string(ConfigurationNoAcceleratorsDiscovered)) {
+		return
+	}
+	r.recorder.Event(nc, corev1.EventTypeWarning, string(ConfigurationNoAcceleratorsDiscovered), msg)
+}
+
+// returns result indicating necessity of re-queuing Reconcile(...):
+// immediately - in case when given err is non-nil;
+// after degraded requeue interval, when err is nil
+func requeueDegradedOrNowIfError(e error, log *logrus.Logger) (reconcile.Result, error) {
+	return reconcile.Result{RequeueAfter: degradedRequeueInterval(log)}, e
+}
@@ -0,0 +1,159 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("No accelerators discovered", func() {
+	const envName = utils.SRIOV_PREFIX + "DEGRADED_REQUEUE_INTERVAL"
+
+	Context("degradedRequeueInterval", func() {
+		AfterEach(func() {
+			Expect(os.Unsetenv(envName)).To(Succeed())
+		})
+
+		It("should use the default unless valid interval is set", func() {
+			Expect(degradedRequeueInterval(utils.NewLogger())).To(Equal(defaultDegradedRequeueInterval))
+
+			Expect(os.Setenv(envName, "2m")).To(Succeed())
+			Expect(degradedRequeueInterval(utils.NewLogger())).To(Equal(2 * time.Minute))
+
+			for _, invalid := range []string{"soon", "0s", "-1m"} {
+				Expect(os.Setenv(envName, invalid)).To(Succeed())
+				Expect(degradedRequeueInterval(utils.NewLogger())).To(Equal(defaultDegradedRequeueInterval), invalid)
+			}
+		})
+	})
+
+	Context("NodeConfigReconciler.Reconcile", func() {
+		var (
+			fakeClient   client.Client
+			nodeNameRef  = types.NamespacedName{Name: "worker", Namespace: "testNamespace"}
+			reconciler   *NodeConfigReconciler
+			recorder     *record.FakeRecorder
+			drained      bool
+			configured   bool
+			originalFec  func(_ context.Context, log *logrus.Logger) (*sriovv2.NodeInventory, error)
+			originalVrb  func(_ context.Context, log *logrus.Logger) (*vrbv1.NodeInventory, error)
+			reconcileReq = ctrl.Request{NamespacedName: nodeNameRef}
+		)
+
+		BeforeEach(func() {
+			scheme := runtime.NewScheme()
+			Expect(sriovv2.AddToScheme(scheme)).To(Succeed())
+			Expect(vrbv1.AddToScheme(scheme)).To(Succeed())
+
+			procCmdlineFilePath = "testdata/cmdline_test"
+			sysLockdownFilePath = filepath.Join(testTmpFolder, "lockdown")
+			Expect(os.WriteFile(sysLockdownFilePath, []byte("[none] integrity confidentiality"), 0644)).To(Succeed())
+			drained, configured = false, false
+
+			originalFec, originalVrb = getSriovInventory, VrbgetSriovInventory
+			getSriovInventory = func(_ context.Context, log *logrus.Logger) (*sriovv2.NodeInventory, error) {
+				return &sriovv2.NodeInventory{}, nil
+			}
+			VrbgetSriovInventory = func(_ context.Context, log *logrus.Logger) (*vrbv1.NodeInventory, error) {
+				return &vrbv1.NodeInventory{}, nil
+			}
+
+			sfnc := &sriovv2.SriovFecNodeConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace, Generation: 1},
+				Spec: sriovv2.SriovFecNodeConfigSpec{
+					PhysicalFunctions: []sriovv2.PhysicalFunctionConfigExt{
+						{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, VFDriver: utils.VFIO_PCI, VFAmount: 2},
+					},
+				},
+			}
+			vrbnc := &vrbv1.SriovVrbNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace}}
+			fakeClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(sfnc, vrbnc).Build()
+
+			recorder = record.NewFakeRecorder(10)
+			reconciler = &NodeConfigReconciler{
+				Client:      fakeClient,
+				log:         utils.NewLogger(),
+				nodeNameRef: nodeNameRef,
+				recorder:    recorder,
+				sriovfecconfigurer: testConfigurerProto{configureNodeFunction: func(sriovv2.SriovFecNodeConfigSpec) error {
+					configured = true
+					return nil
+				}},
+				drainerAndExecute: func(ctx context.Context, configurer func(ctx context.Context) bool, drain bool) error {
+					drained = true
+					_ = configurer(ctx)
+					return nil
+				},
+				restartDevicePlugin: func(context.Context) error { return nil },
+			}
+		})
+
+		AfterEach(func() {
+			getSriovInventory, VrbgetSriovInventory = originalFec, originalVrb
+			sysLockdownFilePath = "/sys/kernel/security/lockdown"
+		})
+
+		It("should fail configuration without drain and requeue after degraded interval", func() {
+			result, err := reconciler.Reconcile(context.TODO(), reconcileReq)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(defaultDegradedRequeueInterval))
+			Expect(drained).To(BeFalse())
+			Expect(configured).To(BeFalse())
+
+			sfnc := new(sriovv2.SriovFecNodeConfig)
+			Expect(fakeClient.Get(context.TODO(), nodeNameRef, sfnc)).To(Succeed())
+			condition := sfnc.FindCondition(ConditionConfigured)
+			Expect(condition).ToNot(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(string(ConfigurationNoAcceleratorsDiscovered)))
+			Expect(condition.Message).To(ContainSubstring("1 PF(s) are requested"))
+
+			Expect(recorder.Events).To(Receive(And(ContainSubstring("Warning NoAcceleratorsDiscovered"), ContainSubstring("lspci"), ContainSubstring("dmesg"))))
+		})
+
+		It("should emit event only once while no accelerators are discovered", func() {
+			_, err := reconciler.Reconcile(context.TODO(), reconcileReq)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(recorder.Events).To(Receive())
+
+			_, err = reconciler.Reconcile(context.TODO(), reconcileReq)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(recorder.Events).ToNot(Receive())
+		})
+
+		It("should not consider empty spec", func() {
+			sfnc := new(sriovv2.SriovFecNodeConfig)
+			Expect(fakeClient.Get(context.TODO(), nodeNameRef, sfnc)).To(Succeed())
+			sfnc.Spec.PhysicalFunctions = nil
+			Expect(fakeClient.Update(context.TODO(), sfnc)).To(Succeed())
+
+			_, err := reconciler.Reconcile(context.TODO(), reconcileReq)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeClient.Get(context.TODO(), nodeNameRef, sfnc)).To(Succeed())
+			if condition := sfnc.FindCondition(ConditionConfigured); condition != nil {
+				Expect(condition.Reason).ToNot(Equal(string(ConfigurationNoAcceleratorsDiscovered)))
+			}
+			Expect(recorder.Events).ToNot(Receive())
+		})
+	})
+})
@@ -0,0 +1,147 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+// racingCreateClient creates object given as concurrent right before the object requested by the caller, as the
+// cluster controller does when it creates node config of a new node at the same moment as the daemon
+type racingCreateClient struct {
+	client.Client
+	concurrent client.Object
+	// conflicts is a number of status patches failed with conflict
+	conflicts int
+	patches   int
+}
+
+func (c *racingCreateClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if c.concurrent != nil {
+		if err := c.Client.Create(ctx, c.concurrent); err != nil {
+			return err
+		}
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func (c *racingCreateClient) Status() client.StatusWriter {
+	return &racingCreateStatusWriter{StatusWriter: c.Client.Status(), client: c}
+}
+
+type racingCreateStatusWriter struct {
+	client.StatusWriter
+	client *racingCreateClient
+}
+
+func (w *racingCreateStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	w.client.patches++
+	if w.client.patches <= w.client.conflicts {
+		return k8serrors.NewConflict(schema.GroupResource{Resource: "nodeconfigs"}, obj.GetName(), nil)
+	}
+	return w.StatusWriter.Patch(ctx, obj, patch, opts...)
+}
+
+var _ = Describe("CreateEmptyNodeConfigIfNeeded", func() {
+	var (
+		nodeNameRef                  = types.NamespacedName{Name: "worker", Namespace: "default"}
+		reconciler                   *NodeConfigReconciler
+		c                            *racingCreateClient
+		originalGetSriovInventory    = getSriovInventory
+		originalVrbGetSriovInventory = VrbgetSriovInventory
+	)
+
+	BeforeEach(func() {
+		getSriovInventory = func(context.Context, *logrus.Logger) (*sriovv2.NodeInventory, error) {
+			return &sriovv2.NodeInventory{SriovAccelerators: []sriovv2.SriovAccelerator{{PCIAddress: "0000:f7:00.0", MaxVFs: 16}}}, nil
+		}
+		VrbgetSriovInventory = func(context.Context, *logrus.Logger) (*vrbv1.NodeInventory, error) {
+			return &vrbv1.NodeInventory{SriovAccelerators: []vrbv1.SriovAccelerator{{PCIAddress: "0000:f8:00.0", MaxVFs: 16}}}, nil
+		}
+		scheme := runtime.NewScheme()
+		Expect(sriovv2.AddToScheme(scheme)).To(Succeed())
+		Expect(vrbv1.AddToScheme(scheme)).To(Succeed())
+		c = &racingCreateClient{Client: fake.NewClientBuilder().WithScheme(scheme).Build()}
+		reconciler = &NodeConfigReconciler{Client: c, log: utils.NewLogger(), nodeNameRef: nodeNameRef}
+	})
+
+	AfterEach(func() {
+		getSriovInventory = originalGetSriovInventory
+		VrbgetSriovInventory = originalVrbGetSriovInventory
+	})
+
+	It("creates empty node config with initialized status", func() {
+		Expect(reconciler.CreateEmptyNodeConfigIfNeeded(context.TODO(), c)).To(Succeed())
+
+		nc := &sriovv2.SriovFecNodeConfig{}
+		Expect(c.Get(context.TODO(), nodeNameRef, nc)).To(Succeed())
+		Expect(nc.Spec.PhysicalFunctions).To(BeEmpty())
+		Expect(nc.Status.Inventory.SriovAccelerators).To(HaveLen(1))
+		condition := nc.FindCondition(ConditionConfigured)
+		Expect(condition).ToNot(BeNil())
+		Expect(condition.Reason).To(Equal(string(ConfigurationNotRequested)))
+		Expect(condition.ObservedGeneration).To(Equal(nc.GetGeneration()))
+	})
+
+	It("keeps spec of node config created concurrently by the cluster controller", func() {
+		c.concurrent = &sriovv2.SriovFecNodeConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace},
+			Spec: sriovv2.SriovFecNodeConfigSpec{PhysicalFunctions: []sriovv2.PhysicalFunctionConfigExt{
+				{PCIAddress: "0000:f7:00.0", PFDriver: utils.PCI_PF_STUB_DASH, VFDriver: utils.VFIO_PCI, VFAmount: 2},
+			}},
+		}
+
+		Expect(reconciler.CreateEmptyNodeConfigIfNeeded(context.TODO(), c)).To(Succeed())
+
+		nc := &sriovv2.SriovFecNodeConfig{}
+		Expect(c.Get(context.TODO(), nodeNameRef, nc)).To(Succeed())
+		Expect(nc.Spec.PhysicalFunctions).To(HaveLen(1))
+		Expect(nc.Spec.PhysicalFunctions[0].VFAmount).To(Equal(2))
+		Expect(nc.Status.Inventory.SriovAccelerators).To(HaveLen(1))
+		// spec of the cluster controller is not applied yet
+		Expect(nc.FindCondition(ConditionConfigured).ObservedGeneration).To(BeZero())
+	})
+
+	It("keeps spec of VRB node config created concurrently by the cluster controller", func() {
+		c.concurrent = &vrbv1.SriovVrbNodeConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace},
+			Spec: vrbv1.SriovVrbNodeConfigSpec{PhysicalFunctions: []vrbv1.PhysicalFunctionConfigExt{
+				{PCIAddress: "0000:f8:00.0", PFDriver: utils.PCI_PF_STUB_DASH, VFDriver: utils.VFIO_PCI, VFAmount: 2},
+			}},
+		}
+
+		Expect(reconciler.VrbCreateEmptyNodeConfigIfNeeded(context.TODO(), c)).To(Succeed())
+
+		nc := &vrbv1.SriovVrbNodeConfig{}
+		Expect(c.Get(context.TODO(), nodeNameRef, nc)).To(Succeed())
+		Expect(nc.Spec.PhysicalFunctions).To(HaveLen(1))
+		Expect(nc.Status.Inventory.SriovAccelerators).To(HaveLen(1))
+	})
+
+	It("retries status update which conflicted", func() {
+		c.conflicts = 2
+
+		Expect(reconciler.CreateEmptyNodeConfigIfNeeded(context.TODO(), c)).To(Succeed())
+		Expect(c.patches).To(Equal(3))
+
+		nc := &sriovv2.SriovFecNodeConfig{}
+		Expect(c.Get(context.TODO(), nodeNameRef, nc)).To(Succeed())
+		Expect(nc.Status.Inventory.SriovAccelerators).To(HaveLen(1))
+	})
+})
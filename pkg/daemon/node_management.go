@@ -4,11 +4,15 @@
 package daemon
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
 	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
@@ -25,7 +29,7 @@ const (
 )
 
 var (
-	runExecCmd       = execCmd
+	runExecCmd       = withInjectedFaults(execCmd)
 	getVFconfigured  = utils.GetVFconfigured
 	getVFList        = utils.GetVFList
 	workdir          = "/tmp"
@@ -33,12 +37,14 @@ var (
 	sysBusPciDrivers = "/sys/bus/pci/drivers"
 )
 
-func NewNodeConfigurator(logger *logrus.Logger, PfBBConfigController *pfBBConfigController, client client.Client, nodeNameRef types.NamespacedName) *NodeConfigurator {
+func NewNodeConfigurator(logger *logrus.Logger, PfBBConfigController *pfBBConfigController, client client.Client, nodeNameRef types.NamespacedName, featureGates FeatureGates, audit *AuditSink) *NodeConfigurator {
 	return &NodeConfigurator{
 		Client:               client,
 		Log:                  logger,
 		nodeNameRef:          nodeNameRef,
 		pfBBConfigController: PfBBConfigController,
+		featureGates:         featureGates,
+		audit:                audit,
 	}
 }
 
@@ -47,13 +53,30 @@ type NodeConfigurator struct {
 	Log                  *logrus.Logger
 	nodeNameRef          types.NamespacedName
 	pfBBConfigController *pfBBConfigController
+	featureGates         FeatureGates
+	audit                *AuditSink
+	kernelLogSource      string
+	// nodeConfigKey returns key of node configs of the node; nodeNameRef is used when not set
+	nodeConfigKey func() types.NamespacedName
 }
 
-func (n *NodeConfigurator) loadModule(module string) error {
+// SetNodeConfigKey sets function returning key of node configs of the node, see NodeConfigReconciler.NodeConfigKey
+func (n *NodeConfigurator) SetNodeConfigKey(nodeConfigKey func() types.NamespacedName) {
+	n.nodeConfigKey = nodeConfigKey
+}
+
+func (n *NodeConfigurator) nodeConfigRef() types.NamespacedName {
+	if n.nodeConfigKey == nil {
+		return n.nodeNameRef
+	}
+	return n.nodeConfigKey()
+}
+
+func (n *NodeConfigurator) loadModule(ctx context.Context, module string) error {
 	if module == "" {
 		return fmt.Errorf("module cannot be empty string")
 	}
-	_, err := runExecCmd(append([]string{"modprobe", module}, appendMandatoryArgs(module)...), n.Log)
+	_, err := runExecCmd(ctx, append([]string{"modprobe", module}, appendMandatoryArgs(module)...), n.Log)
 	return err
 }
 
@@ -71,7 +94,7 @@ func (n *NodeConfigurator) isDeviceBoundToDriver(pciAddr string) (bool, error) {
 	}
 }
 
-func (n *NodeConfigurator) unbindDeviceFromDriver(pciAddress string) error {
+func (n *NodeConfigurator) unbindDeviceFromDriver(ctx context.Context, pciAddress string) error {
 	deviceDriverPath := filepath.Join(sysBusPciDevices, pciAddress, "driver")
 	driverPath, err := filepath.EvalSymlinks(deviceDriverPath)
 	if err != nil {
@@ -80,20 +103,21 @@ func (n *NodeConfigurator) unbindDeviceFromDriver(pciAddress string) error {
 	}
 	n.Log.WithField("pciAddress", pciAddress).WithField("driver", driverPath).Info("driver to unbound device from")
 	unbindPath := filepath.Join(driverPath, "unbind")
-	err = writeFileWithTimeout(unbindPath, pciAddress)
+	err = writeFileWithTimeout(ctx, unbindPath, pciAddress)
 	if err != nil {
+		err = n.withKernelLog(err, pciAddress)
 		n.Log.WithError(err).WithField("pciAddress", pciAddress).WithField("unbindPath", unbindPath).Error("failed to unbind driver from device")
 	}
 
 	return err
 }
 
-func (n *NodeConfigurator) unbindIfBound(pciAddress string) error {
+func (n *NodeConfigurator) unbindIfBound(ctx context.Context, pciAddress string) error {
 	if isBound, err := n.isDeviceBoundToDriver(pciAddress); err != nil {
 		n.Log.WithField("pci", pciAddress).WithError(err).Error("failed to check if device is bound to driver")
 		return err
 	} else if isBound {
-		if err := n.unbindDeviceFromDriver(pciAddress); err != nil {
+		if err := n.unbindDeviceFromDriver(ctx, pciAddress); err != nil {
 			n.Log.WithField("pci", pciAddress).WithError(err).Error("failed to unbind device from driver")
 			return err
 		}
@@ -101,40 +125,161 @@ func (n *NodeConfigurator) unbindIfBound(pciAddress string) error {
 	return nil
 }
 
-func (n *NodeConfigurator) bindDeviceToDriver(pciAddress, driver string) error {
-	if isBound, err := n.isDeviceBoundToDriver(pciAddress); err != nil {
-		n.Log.WithField("pci", pciAddress).WithError(err).Error("failed to check if device is bound to driver")
+func (n *NodeConfigurator) bindDeviceToDriver(ctx context.Context, pciAddress, driver string) (err error) {
+	defer func(started time.Time) { n.audit.observe(auditActionBind, pciAddress, driver, started, err) }(time.Now())
+
+	if err := n.unbindIfBound(ctx, pciAddress); err != nil {
 		return err
-	} else if isBound {
-		if err := n.unbindDeviceFromDriver(pciAddress); err != nil {
-			n.Log.WithField("pci", pciAddress).WithError(err).Error("failed to unbind device from driver")
-			return err
-		}
 	}
 
 	driverOverridePath := filepath.Join(sysBusPciDevices, pciAddress, "driver_override")
 	n.Log.WithField("path", driverOverridePath).Info("device's driver_override path")
-	if err := writeFileWithTimeout(driverOverridePath, driver); err != nil {
+	if err := writeFileWithTimeout(ctx, driverOverridePath, driver); err != nil {
+		err = n.withKernelLog(err, pciAddress)
 		n.Log.WithError(err).WithField("path", driverOverridePath).WithField("driver", driver).Error("failed to override driver")
 		return err
 	}
 
+	if driverRequiresNewID(driver) {
+		if err := n.registerDeviceID(ctx, pciAddress, driver); err != nil {
+			return err
+		}
+		// kernel probes all matching unbound devices as soon as new ID is registered,
+		// so device might be already bound to requested driver
+		if n.isDeviceBoundTo(pciAddress, driver) {
+			n.Log.WithField("pciAddress", pciAddress).WithField("driver", driver).Info("device has been bound by kernel after registering new_id")
+			return nil
+		}
+	}
+
 	driverBindPath := filepath.Join(sysBusPciDrivers, driver, "bind")
 	n.Log.WithField("path", driverBindPath).Info("driver bind path")
-	err := writeFileWithTimeout(driverBindPath, pciAddress)
+	err = writeFileWithTimeout(ctx, driverBindPath, pciAddress)
 	if err != nil {
+		if n.isDeviceBoundTo(pciAddress, driver) {
+			n.Log.WithError(err).WithField("pciAddress", pciAddress).WithField("driver", driver).Info("bind failed, but device is already bound to requested driver")
+			return nil
+		}
+		err = n.withKernelLog(err, pciAddress)
 		n.Log.WithError(err).WithField("pciAddress", pciAddress).WithField("driverBindPath", driverBindPath).Error("failed to bind driver to device")
 	}
 
 	return err
 }
 
-func (n *NodeConfigurator) configureCommandRegister(pciAddr string) error {
+// driverRequiresNewID returns true for drivers which have no static device ID table
+// and have to be told which devices they should claim by writing into their new_id
+func driverRequiresNewID(driver string) bool {
+	return driver == sriovutils.IGB_UIO
+}
+
+// boundDriver returns name of the driver device is bound to or empty string if device is not bound
+func boundDriver(pciAddress string) (string, error) {
+	driverPath, err := filepath.EvalSymlinks(filepath.Join(sysBusPciDevices, pciAddress, "driver"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return filepath.Base(driverPath), nil
+}
+
+func (n *NodeConfigurator) isDeviceBoundTo(pciAddress, driver string) bool {
+	current, err := boundDriver(pciAddress)
+	if err != nil {
+		n.Log.WithError(err).WithField("pciAddress", pciAddress).Warn("failed to read device's driver")
+		return false
+	}
+	return current == driver
+}
+
+// readDeviceID returns vendor and device ID of the device in format accepted by new_id and remove_id, e.g. "8086 0d5d"
+func readDeviceID(pciAddress string) (string, error) {
+	var ids []string
+	for _, file := range []string{"vendor", "device"} {
+		content, err := os.ReadFile(filepath.Join(sysBusPciDevices, pciAddress, file))
+		if err != nil {
+			return "", err
+		}
+		ids = append(ids, strings.TrimPrefix(strings.TrimSpace(string(content)), "0x"))
+	}
+	return strings.Join(ids, " "), nil
+}
+
+// registerDeviceID makes driver claim device's vendor/device ID. Registering ID which is already claimed by driver
+// is not considered as an error.
+func (n *NodeConfigurator) registerDeviceID(ctx context.Context, pciAddress, driver string) error {
+	newIDPath := filepath.Join(sysBusPciDrivers, driver, "new_id")
+	if _, err := os.Stat(newIDPath); os.IsNotExist(err) {
+		n.Log.WithField("driver", driver).Info("driver does not support dynamic IDs, skipping new_id")
+		return nil
+	}
+
+	id, err := readDeviceID(pciAddress)
+	if err != nil {
+		n.Log.WithError(err).WithField("pciAddress", pciAddress).Error("failed to read device's vendor/device ID")
+		return err
+	}
+
+	n.Log.WithField("path", newIDPath).WithField("id", id).Info("registering device ID in driver")
+	if err := writeFileWithTimeout(ctx, newIDPath, id); err != nil {
+		if errors.Is(err, syscall.EEXIST) {
+			n.Log.WithField("driver", driver).WithField("id", id).Info("driver already claims device ID")
+			return nil
+		}
+		n.Log.WithError(err).WithField("path", newIDPath).WithField("id", id).Error("failed to register device ID in driver")
+		return err
+	}
+	return nil
+}
+
+// unregisterDeviceID removes device's vendor/device ID previously registered through new_id.
+// Removing ID which is not registered (anymore) is not considered as an error.
+func (n *NodeConfigurator) unregisterDeviceID(ctx context.Context, pciAddress, driver string) error {
+	removeIDPath := filepath.Join(sysBusPciDrivers, driver, "remove_id")
+	if _, err := os.Stat(removeIDPath); os.IsNotExist(err) {
+		return nil
+	}
+
+	id, err := readDeviceID(pciAddress)
+	if err != nil {
+		n.Log.WithError(err).WithField("pciAddress", pciAddress).Error("failed to read device's vendor/device ID")
+		return err
+	}
+
+	n.Log.WithField("path", removeIDPath).WithField("id", id).Info("removing device ID from driver")
+	if err := writeFileWithTimeout(ctx, removeIDPath, id); err != nil && !errors.Is(err, syscall.ENODEV) {
+		n.Log.WithError(err).WithField("path", removeIDPath).WithField("id", id).Error("failed to remove device ID from driver")
+		return err
+	}
+	return nil
+}
+
+// unbindVF unbinds VF from its driver and withdraws VF's ID from driver if it was registered through new_id
+func (n *NodeConfigurator) unbindVF(ctx context.Context, vfPCIAddress string) error {
+	driver, err := boundDriver(vfPCIAddress)
+	if err != nil {
+		n.Log.WithField("pci", vfPCIAddress).WithError(err).Error("failed to check if device is bound to driver")
+		return err
+	}
+
+	if err := n.unbindIfBound(ctx, vfPCIAddress); err != nil {
+		return err
+	}
+
+	if driverRequiresNewID(driver) {
+		return n.unregisterDeviceID(ctx, vfPCIAddress, driver)
+	}
+	return nil
+}
+
+func (n *NodeConfigurator) configureCommandRegister(ctx context.Context, pciAddr string) error {
 	// Configures PCI COMMAND register that enables
 	// 0X02 bit - PCI_COMMAND_MEMORY which is required for MMIO in pf-bb-config
 	// 0X04 bit - PCI_COMMAND_MASTER which required for PF to correctly manage VFs
 	cmd := []string{"setpci", "-v", "-s", pciAddr, "COMMAND=06"}
-	_, err := runExecCmd(cmd, n.Log)
+	_, err := runExecCmd(ctx, cmd, n.Log)
 	if err != nil {
 		n.Log.WithError(err).Error("failed to configure PCI command bridge for card: " + pciAddr)
 		return err
@@ -142,11 +287,14 @@ func (n *NodeConfigurator) configureCommandRegister(pciAddr string) error {
 	return nil
 }
 
-func (n *NodeConfigurator) changeAmountOfVFs(driver string, pfPCIAddress string, vfsAmount int) error {
+func (n *NodeConfigurator) changeAmountOfVFs(ctx context.Context, driver string, pfPCIAddress string, vfsAmount int) (err error) {
 	currentAmount := getVFconfigured(pfPCIAddress)
 	if currentAmount == vfsAmount {
 		return nil
 	}
+	defer func(started time.Time) {
+		n.audit.observe(auditActionNumVFs, pfPCIAddress, strconv.Itoa(vfsAmount), started, err)
+	}(time.Now())
 
 	writeVfs := func(pfPCIAddress string, vfsAmount int) error {
 		unbindPath := filepath.Join(sysBusPciDevices, pfPCIAddress)
@@ -160,8 +308,9 @@ func (n *NodeConfigurator) changeAmountOfVFs(driver string, pfPCIAddress string,
 			return fmt.Errorf("unknown driver %v", driver)
 		}
 
-		err := writeFileWithTimeout(unbindPath, strconv.Itoa(vfsAmount))
+		err := writeFileWithTimeout(ctx, unbindPath, strconv.Itoa(vfsAmount))
 		if err != nil {
+			err = n.withKernelLog(err, pfPCIAddress)
 			n.Log.WithError(err).WithField("pf", pfPCIAddress).WithField("vfsAmount", vfsAmount).Error("failed to set new amount of VFs for PF")
 			return fmt.Errorf("failed to set new amount of VFs (%d) for PF (%s): %w", vfsAmount, pfPCIAddress, err)
 		}
@@ -175,86 +324,138 @@ func (n *NodeConfigurator) changeAmountOfVFs(driver string, pfPCIAddress string,
 	}
 
 	if vfsAmount > 0 {
-		return writeVfs(pfPCIAddress, vfsAmount)
+		if err := writeVfs(pfPCIAddress, vfsAmount); err != nil {
+			return err
+		}
+		return n.verifyVFsCreated(pfPCIAddress, vfsAmount)
 	}
 
 	return nil
 }
 
-func (n *NodeConfigurator) flrReset(pfPCIAddress string) error {
+// verifyVFsCreated checks that kernel created all requested VFs, as it may silently create fewer of them, e.g. when
+// MSI-X vectors are exhausted
+func (n *NodeConfigurator) verifyVFsCreated(pfPCIAddress string, vfsAmount int) error {
+	vfs, err := getVFList(pfPCIAddress)
+	if err != nil {
+		return fmt.Errorf("failed to list VFs of PF (%s) - %v", pfPCIAddress, err)
+	}
+
+	created := len(vfs)
+	if configured := getVFconfigured(pfPCIAddress); configured < created {
+		created = configured
+	}
+	if created >= vfsAmount {
+		return nil
+	}
+
+	err = fmt.Errorf("kernel created only %d of %d requested VFs for PF (%s)", created, vfsAmount, pfPCIAddress)
+	if total, ok := readVFTotalMSIX(pfPCIAddress); ok {
+		err = fmt.Errorf("%v - device exposes %d MSI-X vectors for VFs (%s), reduce vfAmount", err, total, vfTotalMSIXFile)
+	}
+	n.Log.WithError(err).WithField("pf", pfPCIAddress).Error("VFs were not created")
+	return err
+}
+
+func (n *NodeConfigurator) flrReset(ctx context.Context, pfPCIAddress string) error {
 	n.Log.Infof("executing FLR for %s", pfPCIAddress)
 
 	path := filepath.Join(sysBusPciDevices, pfPCIAddress, "reset")
-	if err := writeFileWithTimeout(path, strconv.Itoa(1)); err != nil {
+	if err := writeFileWithTimeout(ctx, path, strconv.Itoa(1)); err != nil {
 		return fmt.Errorf("failed to execute Function Level Reset for PF (%s): %s", pfPCIAddress, err)
 	}
 
 	return nil
 }
 
-func (n *NodeConfigurator) cleanAcceleratorConfig(acc sriovv2.SriovAccelerator) error {
+// cleanAcceleratorConfig stops pf-bb-config, removes VFs and resets the PF; with teardown (PF removed from the spec), steps failed
+// because the device is absent are considered done
+func (n *NodeConfigurator) cleanAcceleratorConfig(ctx context.Context, acc sriovv2.SriovAccelerator, teardown bool) error {
 	n.Log.Infof("cleaning configuration on %s", acc.PCIAddress)
 
+	tolerate := func(err error) error {
+		if !teardown {
+			return err
+		}
+		return n.tolerateAbsentDevice(acc.PCIAddress, err)
+	}
+
 	if err := n.pfBBConfigController.stopPfBBConfig(acc.PCIAddress); err != nil {
 		return err
 	}
 
-	if err := unbindVFs(n, acc); err != nil {
+	if err := tolerate(unbindVFs(ctx, n, acc)); err != nil {
 		return err
 	}
 
-	if err := removeVFs(n, acc); err != nil {
+	if err := tolerate(removeVFs(ctx, n, acc)); err != nil {
 		return err
 	}
 
-	if err := n.flrReset(acc.PCIAddress); err != nil {
+	if err := tolerate(n.flrReset(ctx, acc.PCIAddress)); err != nil {
 		return err
 	}
 
+	// PF removed from the spec is left with MSI allowed; otherwise interrupt mode is set before PF is bound again
+	if teardown {
+		if err := tolerate(n.resetInterruptMode(ctx, acc.PCIAddress)); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-func (n *NodeConfigurator) VrbcleanAcceleratorConfig(acc vrbv1.SriovAccelerator) error {
+// VrbcleanAcceleratorConfig stops pf-bb-config, removes VFs and resets the PF; with teardown (PF removed from the spec), steps failed
+// because the device is absent are considered done
+func (n *NodeConfigurator) VrbcleanAcceleratorConfig(ctx context.Context, acc vrbv1.SriovAccelerator, teardown bool) error {
 	n.Log.Infof("cleaning configuration on %s", acc.PCIAddress)
 
+	tolerate := func(err error) error {
+		if !teardown {
+			return err
+		}
+		return n.tolerateAbsentDevice(acc.PCIAddress, err)
+	}
+
 	if err := n.pfBBConfigController.stopPfBBConfig(acc.PCIAddress); err != nil {
 		return err
 	}
 
-	if err := VrbunbindVFs(n, acc); err != nil {
+	if err := tolerate(VrbunbindVFs(ctx, n, acc)); err != nil {
 		return err
 	}
 
-	if err := VrbremoveVFs(n, acc); err != nil {
+	if err := tolerate(VrbremoveVFs(ctx, n, acc)); err != nil {
 		return err
 	}
 
-	if err := n.flrReset(acc.PCIAddress); err != nil {
+	if err := tolerate(n.flrReset(ctx, acc.PCIAddress)); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func removeVFs(nc *NodeConfigurator, acc sriovv2.SriovAccelerator) error {
+func removeVFs(ctx context.Context, nc *NodeConfigurator, acc sriovv2.SriovAccelerator) error {
 	if len(acc.VFs) > 0 {
-		if err := nc.changeAmountOfVFs(acc.PFDriver, acc.PCIAddress, 0); err != nil {
+		if err := nc.changeAmountOfVFs(ctx, acc.PFDriver, acc.PCIAddress, 0); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func VrbremoveVFs(nc *NodeConfigurator, acc vrbv1.SriovAccelerator) error {
+func VrbremoveVFs(ctx context.Context, nc *NodeConfigurator, acc vrbv1.SriovAccelerator) error {
 	if len(acc.VFs) > 0 {
-		if err := nc.changeAmountOfVFs(acc.PFDriver, acc.PCIAddress, 0); err != nil {
+		if err := nc.changeAmountOfVFs(ctx, acc.PFDriver, acc.PCIAddress, 0); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func unbindVFs(nc *NodeConfigurator, acc sriovv2.SriovAccelerator) error {
+func unbindVFs(ctx context.Context, nc *NodeConfigurator, acc sriovv2.SriovAccelerator) error {
 	existingVfs, err := getVFList(acc.PCIAddress)
 	if err != nil {
 		nc.Log.WithError(err).Error("failed to get list of newly created VFs")
@@ -262,14 +463,14 @@ func unbindVFs(nc *NodeConfigurator, acc sriovv2.SriovAccelerator) error {
 	}
 
 	for _, vf := range existingVfs {
-		if err := nc.unbindIfBound(vf); err != nil {
+		if err := nc.unbindVF(ctx, vf); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func VrbunbindVFs(nc *NodeConfigurator, acc vrbv1.SriovAccelerator) error {
+func VrbunbindVFs(ctx context.Context, nc *NodeConfigurator, acc vrbv1.SriovAccelerator) error {
 	existingVfs, err := getVFList(acc.PCIAddress)
 	if err != nil {
 		nc.Log.WithError(err).Error("failed to get list of newly created VFs")
@@ -277,57 +478,91 @@ func VrbunbindVFs(nc *NodeConfigurator, acc vrbv1.SriovAccelerator) error {
 	}
 
 	for _, vf := range existingVfs {
-		if err := nc.unbindIfBound(vf); err != nil {
+		if err := nc.unbindVF(ctx, vf); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func loadDrivers(nc *NodeConfigurator, pfDriver string, vfDriver string) error {
-	if err := nc.loadModule(pfDriver); err != nil {
+func loadDrivers(ctx context.Context, nc *NodeConfigurator, pfDriver string, vfDriver string) error {
+	if err := nc.loadModule(ctx, pfDriver); err != nil {
 		nc.Log.WithField("driver", pfDriver).Info("failed to load module for PF driver")
 		return err
 	}
 
-	if err := nc.loadModule(vfDriver); err != nil {
+	if err := nc.loadModule(ctx, vfDriver); err != nil {
 		nc.Log.WithField("driver", vfDriver).Info("failed to load module for VF driver")
 		return err
 	}
 	return nil
 }
 
-func (n *NodeConfigurator) ApplySpec(nodeConfig sriovv2.SriovFecNodeConfigSpec) error {
-	inv, err := getSriovInventory(n.Log)
+func (n *NodeConfigurator) ApplySpec(ctx context.Context, nodeConfig sriovv2.SriovFecNodeConfigSpec) (err error) {
+	ctx, audit := withSysfsAudit(ctx, n.Log)
+	defer func() { audit.summarize(err) }()
+
+	inv, err := getSriovInventory(ctx, n.Log)
 	if err != nil {
 		n.Log.WithError(err).Error("failed to obtain current sriov inventory")
 		return err
 	}
+	// accelerators owned by other node configs of the node are not touched; each node config resumes its own
+	// interrupted configuration
+	checkpointKind := hitlessUpdateKindFec
+	if scope, ok := acceleratorScopeFrom(ctx); ok {
+		inv = scope.inventory(inv)
+		checkpointKind = scope.appliedSpecKind()
+	}
 
 	n.Log.WithField("inventory", inv).Info("current node status")
 
+	checkpoint, err := loadApplyCheckpoint(checkpointKind, nodeConfig, n.Log)
+	if err != nil {
+		return err
+	}
+	defer func() { checkpoint.finish(ctx, err) }()
+
 	for _, acc := range inv.SriovAccelerators {
+		if err := checkpoint.interrupted(ctx); err != nil {
+			return err
+		}
 		requestedConfig := getMatchingConfiguration(acc.PCIAddress, nodeConfig.PhysicalFunctions)
 		if requestedConfig == nil {
-			if len(acc.VFs) > 0 {
+			if len(acc.VFs) > 0 && !checkpoint.completed(acc.PCIAddress, applyStepDone) {
 				n.Log.WithField("pci", acc.PCIAddress).WithField("driverName", acc.PFDriver).Info("zeroing VFs")
-				if err := n.cleanAcceleratorConfig(acc); err != nil {
+				if err := n.runStep(ctx, stepCleanAcceleratorConfig, acc.PCIAddress, func() error { return n.cleanAcceleratorConfig(ctx, acc, true) }); err != nil {
+					return err
+				}
+				if err := n.runStep(ctx, stepRestoreOriginalDriver, acc.PCIAddress, func() error {
+					return n.tolerateAbsentDevice(acc.PCIAddress, n.restoreOriginalDriver(ctx, acc.PCIAddress, acc.PFDriver))
+				}); err != nil {
 					return err
 				}
+				checkpoint.complete(ctx, acc.PCIAddress, applyStepDone)
 			}
 
 			continue
 		}
-		if err := n.configureAccelerator(acc, requestedConfig); err != nil {
+		if isAdoptedPF(ctx, acc.PCIAddress) {
+			n.Log.WithField("pci", acc.PCIAddress).Info("configuration found on the node was adopted - leaving PF untouched")
+			recordAppliedPF(ctx, acc.PCIAddress)
+			continue
+		}
+		if err := n.configureAccelerator(ctx, acc, requestedConfig, checkpoint); err != nil {
 			return err
 		}
+		recordAppliedPF(ctx, acc.PCIAddress)
 	}
 
 	return nil
 }
 
-func (n *NodeConfigurator) VrbApplySpec(nodeConfig vrbv1.SriovVrbNodeConfigSpec) error {
-	inv, err := VrbgetSriovInventory(n.Log)
+func (n *NodeConfigurator) VrbApplySpec(ctx context.Context, nodeConfig vrbv1.SriovVrbNodeConfigSpec) (err error) {
+	ctx, audit := withSysfsAudit(ctx, n.Log)
+	defer func() { audit.summarize(err) }()
+
+	inv, err := VrbgetSriovInventory(ctx, n.Log)
 	if err != nil {
 		n.Log.WithError(err).Error("failed to obtain current sriov inventory")
 		return err
@@ -335,50 +570,198 @@ func (n *NodeConfigurator) VrbApplySpec(nodeConfig vrbv1.SriovVrbNodeConfigSpec)
 
 	n.Log.WithField("inventory", inv).Info("current node status")
 
+	checkpoint, err := loadApplyCheckpoint(hitlessUpdateKindVrb, nodeConfig, n.Log)
+	if err != nil {
+		return err
+	}
+	defer func() { checkpoint.finish(ctx, err) }()
+
 	for _, acc := range inv.SriovAccelerators {
+		if err := checkpoint.interrupted(ctx); err != nil {
+			return err
+		}
 		requestedConfig := VrbgetMatchingConfiguration(acc.PCIAddress, nodeConfig.PhysicalFunctions)
 		if requestedConfig == nil {
-			if len(acc.VFs) > 0 {
+			if len(acc.VFs) > 0 && !checkpoint.completed(acc.PCIAddress, applyStepDone) {
 				n.Log.WithField("pci", acc.PCIAddress).WithField("driverName", acc.PFDriver).Info("zeroing VFs")
-				if err := n.VrbcleanAcceleratorConfig(acc); err != nil {
+				if err := n.runStep(ctx, stepCleanAcceleratorConfig, acc.PCIAddress, func() error { return n.VrbcleanAcceleratorConfig(ctx, acc, true) }); err != nil {
+					return err
+				}
+				if err := n.runStep(ctx, stepRestoreOriginalDriver, acc.PCIAddress, func() error {
+					return n.tolerateAbsentDevice(acc.PCIAddress, n.restoreOriginalDriver(ctx, acc.PCIAddress, acc.PFDriver))
+				}); err != nil {
 					return err
 				}
+				checkpoint.complete(ctx, acc.PCIAddress, applyStepDone)
 			}
 
 			continue
 		}
-		if err := n.VrbconfigureAccelerator(acc, requestedConfig); err != nil {
+		if isAdoptedPF(ctx, acc.PCIAddress) {
+			n.Log.WithField("pci", acc.PCIAddress).Info("configuration found on the node was adopted - leaving PF untouched")
+			recordAppliedPF(ctx, acc.PCIAddress)
+			continue
+		}
+		if err := n.VrbconfigureAccelerator(ctx, acc, requestedConfig, checkpoint); err != nil {
 			return err
 		}
+		recordAppliedPF(ctx, acc.PCIAddress)
 	}
 
 	return nil
 }
 
-func (n *NodeConfigurator) configureAccelerator(acc sriovv2.SriovAccelerator, requestedConfig *sriovv2.PhysicalFunctionConfigExt) error {
-	n.Log.WithField("requestedConfig", requestedConfig).Info("configuring PF")
-
-	if err := n.cleanAcceleratorConfig(acc); err != nil {
+func (n *NodeConfigurator) RestartPfBBConfig(ctx context.Context, pf sriovv2.PhysicalFunctionConfigExt) error {
+	inv, err := getSriovInventory(ctx, n.Log)
+	if err != nil {
+		n.Log.WithError(err).Error("failed to obtain current sriov inventory")
 		return err
 	}
 
-	if err := loadDrivers(n, requestedConfig.PFDriver, requestedConfig.VFDriver); err != nil {
-		return err
+	for _, acc := range inv.SriovAccelerators {
+		if acc.PCIAddress == pf.PCIAddress {
+			n.Log.WithField("pci", pf.PCIAddress).Info("restarting pf-bb-config with new config")
+			started := time.Now()
+			if err := n.provisionFFTLut(ctx, &pf); err != nil {
+				return err
+			}
+			err := n.pfBBConfigController.initializePfBBConfig(ctx, acc, &pf, nil)
+			n.audit.observe(auditActionPfBBConfigRestart, pf.PCIAddress, "", started, err)
+			return err
+		}
 	}
+	return fmt.Errorf("accelerator %s not found in inventory", pf.PCIAddress)
+}
 
-	if err := n.bindDeviceToDriver(requestedConfig.PCIAddress, requestedConfig.PFDriver); err != nil {
+func (n *NodeConfigurator) VrbRestartPfBBConfig(ctx context.Context, pf vrbv1.PhysicalFunctionConfigExt) error {
+	inv, err := VrbgetSriovInventory(ctx, n.Log)
+	if err != nil {
+		n.Log.WithError(err).Error("failed to obtain current sriov inventory")
 		return err
 	}
 
-	if err := n.configureCommandRegister(requestedConfig.PCIAddress); err != nil {
-		return err
+	for _, acc := range inv.SriovAccelerators {
+		if acc.PCIAddress == pf.PCIAddress {
+			n.Log.WithField("pci", pf.PCIAddress).Info("restarting pf-bb-config with new config")
+			started := time.Now()
+			err := n.pfBBConfigController.VrbinitializePfBBConfig(ctx, acc, &pf, nil)
+			n.audit.observe(auditActionPfBBConfigRestart, pf.PCIAddress, "", started, err)
+			return err
+		}
 	}
+	return fmt.Errorf("accelerator %s not found in inventory", pf.PCIAddress)
+}
 
-	if err := n.pfBBConfigController.initializePfBBConfig(acc, requestedConfig); err != nil {
-		return err
+// readBBDevConfigFrom returns content of cfg file referenced by physical function; nil when bbDevConfigFrom is not used
+func (n *NodeConfigurator) readBBDevConfigFrom(ctx context.Context, refs []bbDevConfigRef) ([]byte, error) {
+	if len(refs) == 0 {
+		return nil, nil
+	}
+	content, err := readBBDevConfigFrom(ctx, n, n.nodeNameRef.Namespace, refs[0])
+	if err != nil {
+		n.Log.WithError(err).WithField("pci", refs[0].pciAddress).Error("failed to read bbDevConfigFrom")
+		return nil, err
+	}
+	return content, nil
+}
+
+func (n *NodeConfigurator) configureAccelerator(ctx context.Context, acc sriovv2.SriovAccelerator, requestedConfig *sriovv2.PhysicalFunctionConfigExt, checkpoint *applyCheckpoint) error {
+	n.Log.WithField("requestedConfig", requestedConfig).Info("configuring PF")
+
+	bbDevConfig := requestedConfig.BBDevConfig
+	checkpoint.resetIfPfBBConfigIsGone(acc.PCIAddress, requestedConfig.BBDevConfigFrom != nil ||
+		bbDevConfig.N3000 != nil || bbDevConfig.ACC100 != nil || bbDevConfig.ACC200 != nil)
+	if checkpoint.completed(acc.PCIAddress, applyStepDone) {
+		n.Log.WithField("pci", acc.PCIAddress).Info("PF is already configured by interrupted configuration")
+		return nil
+	}
+
+	if !checkpoint.completed(acc.PCIAddress, applyStepCleaned) {
+		if err := n.runStep(ctx, stepStorePristineState, acc.PCIAddress, func() error { return n.ensurePristineState(ctx, acc.PCIAddress) }); err != nil {
+			return err
+		}
+
+		if err := n.runStep(ctx, stepCleanAcceleratorConfig, acc.PCIAddress, func() error { return n.cleanAcceleratorConfig(ctx, acc, false) }); err != nil {
+			return err
+		}
+		checkpoint.complete(ctx, acc.PCIAddress, applyStepCleaned)
+	}
+
+	if !checkpoint.completed(acc.PCIAddress, applyStepPFBound) {
+		if err := checkpoint.interrupted(ctx); err != nil {
+			return err
+		}
+
+		if err := n.runStep(ctx, stepLoadDrivers, acc.PCIAddress, func() error {
+			return loadDrivers(ctx, n, requestedConfig.PFDriver, requestedConfig.VFDriver)
+		}); err != nil {
+			return err
+		}
+
+		if err := n.runStep(ctx, stepSetInterruptMode, acc.PCIAddress, func() error { return n.setInterruptMode(ctx, requestedConfig) }); err != nil {
+			return err
+		}
+
+		if err := n.runStep(ctx, stepBindPF, acc.PCIAddress, func() error {
+			return n.bindDeviceToDriver(ctx, requestedConfig.PCIAddress, requestedConfig.PFDriver)
+		}); err != nil {
+			return err
+		}
+
+		if err := n.runStep(ctx, stepConfigureCommandRegister, acc.PCIAddress, func() error {
+			return n.configureCommandRegister(ctx, requestedConfig.PCIAddress)
+		}); err != nil {
+			return err
+		}
+		checkpoint.complete(ctx, acc.PCIAddress, applyStepPFBound)
 	}
 
-	if err := n.changeAmountOfVFs(requestedConfig.PFDriver, requestedConfig.PCIAddress, requestedConfig.VFAmount); err != nil {
+	if !checkpoint.completed(acc.PCIAddress, applyStepPfBBConfig) {
+		if err := checkpoint.interrupted(ctx); err != nil {
+			return err
+		}
+
+		var rawConfig []byte
+		if err := n.runStep(ctx, stepReadBBDevConfigFrom, acc.PCIAddress, func() (err error) {
+			rawConfig, err = n.readBBDevConfigFrom(ctx, fecBBDevConfigRefs([]sriovv2.PhysicalFunctionConfigExt{*requestedConfig}))
+			return err
+		}); err != nil {
+			return err
+		}
+
+		if err := n.runStep(ctx, stepProvisionFFTLut, acc.PCIAddress, func() error { return n.provisionFFTLut(ctx, requestedConfig) }); err != nil {
+			return err
+		}
+
+		err := n.runStep(ctx, stepStartPfBBConfig, acc.PCIAddress, func() error {
+			return n.pfBBConfigController.initializePfBBConfig(ctx, acc, requestedConfig, rawConfig)
+		})
+		recordPfBBConfigOutcome(ctx, acc.PCIAddress, err)
+		if err != nil {
+			return err
+		}
+		checkpoint.complete(ctx, acc.PCIAddress, applyStepPfBBConfig)
+	}
+
+	if !checkpoint.completed(acc.PCIAddress, applyStepVFsCreated) {
+		if err := checkpoint.interrupted(ctx); err != nil {
+			return err
+		}
+
+		if err := n.runStep(ctx, stepCreateVFs, acc.PCIAddress, func() error {
+			return n.changeAmountOfVFs(ctx, requestedConfig.PFDriver, requestedConfig.PCIAddress, requestedConfig.VFAmount)
+		}); err != nil {
+			return err
+		}
+		if err := n.runStep(ctx, stepPublishPredictedVFs, acc.PCIAddress, func() error {
+			return n.publishPredictedVFs(ctx, requestedConfig.PCIAddress, requestedConfig.VFAmount)
+		}); err != nil {
+			return err
+		}
+		checkpoint.complete(ctx, acc.PCIAddress, applyStepVFsCreated)
+	}
+
+	if err := checkpoint.interrupted(ctx); err != nil {
 		return err
 	}
 
@@ -388,40 +771,111 @@ func (n *NodeConfigurator) configureAccelerator(acc sriovv2.SriovAccelerator, re
 		return err
 	}
 
-	for _, vf := range createdVfs {
-		if err := n.bindDeviceToDriver(vf, requestedConfig.VFDriver); err != nil {
-			return err
+	if err := n.runStep(ctx, stepBindVFs, acc.PCIAddress, func() error {
+		for _, vf := range createdVfs {
+			if err := n.bindDeviceToDriver(ctx, vf, requestedConfig.VFDriver); err != nil {
+				return err
+			}
 		}
+		return nil
+	}); err != nil {
+		return err
 	}
+	checkpoint.complete(ctx, acc.PCIAddress, applyStepDone)
 
 	return nil
 
 }
 
-func (n *NodeConfigurator) VrbconfigureAccelerator(acc vrbv1.SriovAccelerator, requestedConfig *vrbv1.PhysicalFunctionConfigExt) error {
+func (n *NodeConfigurator) VrbconfigureAccelerator(ctx context.Context, acc vrbv1.SriovAccelerator, requestedConfig *vrbv1.PhysicalFunctionConfigExt, checkpoint *applyCheckpoint) error {
 	n.Log.WithField("requestedConfig", requestedConfig).Info("configuring PF")
 
-	if err := n.VrbcleanAcceleratorConfig(acc); err != nil {
-		return err
+	bbDevConfig := requestedConfig.BBDevConfig
+	checkpoint.resetIfPfBBConfigIsGone(acc.PCIAddress, requestedConfig.BBDevConfigFrom != nil ||
+		bbDevConfig.VRB1 != nil || bbDevConfig.VRB2 != nil)
+	if checkpoint.completed(acc.PCIAddress, applyStepDone) {
+		n.Log.WithField("pci", acc.PCIAddress).Info("PF is already configured by interrupted configuration")
+		return nil
 	}
 
-	if err := loadDrivers(n, requestedConfig.PFDriver, requestedConfig.VFDriver); err != nil {
-		return err
+	if !checkpoint.completed(acc.PCIAddress, applyStepCleaned) {
+		if err := n.runStep(ctx, stepStorePristineState, acc.PCIAddress, func() error { return n.ensurePristineState(ctx, acc.PCIAddress) }); err != nil {
+			return err
+		}
+
+		if err := n.runStep(ctx, stepCleanAcceleratorConfig, acc.PCIAddress, func() error { return n.VrbcleanAcceleratorConfig(ctx, acc, false) }); err != nil {
+			return err
+		}
+		checkpoint.complete(ctx, acc.PCIAddress, applyStepCleaned)
 	}
 
-	if err := n.bindDeviceToDriver(requestedConfig.PCIAddress, requestedConfig.PFDriver); err != nil {
-		return err
+	if !checkpoint.completed(acc.PCIAddress, applyStepPFBound) {
+		if err := checkpoint.interrupted(ctx); err != nil {
+			return err
+		}
+
+		if err := n.runStep(ctx, stepLoadDrivers, acc.PCIAddress, func() error {
+			return loadDrivers(ctx, n, requestedConfig.PFDriver, requestedConfig.VFDriver)
+		}); err != nil {
+			return err
+		}
+
+		if err := n.runStep(ctx, stepBindPF, acc.PCIAddress, func() error {
+			return n.bindDeviceToDriver(ctx, requestedConfig.PCIAddress, requestedConfig.PFDriver)
+		}); err != nil {
+			return err
+		}
+
+		if err := n.runStep(ctx, stepConfigureCommandRegister, acc.PCIAddress, func() error {
+			return n.configureCommandRegister(ctx, requestedConfig.PCIAddress)
+		}); err != nil {
+			return err
+		}
+		checkpoint.complete(ctx, acc.PCIAddress, applyStepPFBound)
 	}
 
-	if err := n.configureCommandRegister(requestedConfig.PCIAddress); err != nil {
-		return err
+	if !checkpoint.completed(acc.PCIAddress, applyStepPfBBConfig) {
+		if err := checkpoint.interrupted(ctx); err != nil {
+			return err
+		}
+
+		var rawConfig []byte
+		if err := n.runStep(ctx, stepReadBBDevConfigFrom, acc.PCIAddress, func() (err error) {
+			rawConfig, err = n.readBBDevConfigFrom(ctx, vrbBBDevConfigRefs([]vrbv1.PhysicalFunctionConfigExt{*requestedConfig}))
+			return err
+		}); err != nil {
+			return err
+		}
+
+		err := n.runStep(ctx, stepStartPfBBConfig, acc.PCIAddress, func() error {
+			return n.pfBBConfigController.VrbinitializePfBBConfig(ctx, acc, requestedConfig, rawConfig)
+		})
+		recordPfBBConfigOutcome(ctx, acc.PCIAddress, err)
+		if err != nil {
+			return err
+		}
+		checkpoint.complete(ctx, acc.PCIAddress, applyStepPfBBConfig)
 	}
 
-	if err := n.pfBBConfigController.VrbinitializePfBBConfig(acc, requestedConfig); err != nil {
-		return err
+	if !checkpoint.completed(acc.PCIAddress, applyStepVFsCreated) {
+		if err := checkpoint.interrupted(ctx); err != nil {
+			return err
+		}
+
+		if err := n.runStep(ctx, stepCreateVFs, acc.PCIAddress, func() error {
+			return n.changeAmountOfVFs(ctx, requestedConfig.PFDriver, requestedConfig.PCIAddress, requestedConfig.VFAmount)
+		}); err != nil {
+			return err
+		}
+		if err := n.runStep(ctx, stepPublishPredictedVFs, acc.PCIAddress, func() error {
+			return n.VrbpublishPredictedVFs(ctx, requestedConfig.PCIAddress, requestedConfig.VFAmount)
+		}); err != nil {
+			return err
+		}
+		checkpoint.complete(ctx, acc.PCIAddress, applyStepVFsCreated)
 	}
 
-	if err := n.changeAmountOfVFs(requestedConfig.PFDriver, requestedConfig.PCIAddress, requestedConfig.VFAmount); err != nil {
+	if err := checkpoint.interrupted(ctx); err != nil {
 		return err
 	}
 
@@ -431,11 +885,17 @@ func (n *NodeConfigurator) VrbconfigureAccelerator(acc vrbv1.SriovAccelerator, r
 		return err
 	}
 
-	for _, vf := range createdVfs {
-		if err := n.bindDeviceToDriver(vf, requestedConfig.VFDriver); err != nil {
-			return err
+	if err := n.runStep(ctx, stepBindVFs, acc.PCIAddress, func() error {
+		for _, vf := range createdVfs {
+			if err := n.bindDeviceToDriver(ctx, vf, requestedConfig.VFDriver); err != nil {
+				return err
+			}
 		}
+		return nil
+	}); err != nil {
+		return err
 	}
+	checkpoint.complete(ctx, acc.PCIAddress, applyStepDone)
 
 	return nil
 
@@ -0,0 +1,115 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("NodeConfigurator VF binding", func() {
+	const vfPCIAddress = "0000:15:00.0"
+
+	var (
+		nc                       *NodeConfigurator
+		originalSysBusPciDevices = sysBusPciDevices
+		originalSysBusPciDrivers = sysBusPciDrivers
+	)
+
+	readSysfs := func(path ...string) string {
+		content, err := os.ReadFile(filepath.Join(path...))
+		Expect(err).ToNot(HaveOccurred())
+		return string(content)
+	}
+
+	// prepareFakeSysfs creates VF device with given ID and both supported VF drivers. Only igb_uio supports dynamic IDs.
+	prepareFakeSysfs := func(deviceID string) {
+		root, err := os.MkdirTemp(testTmpFolder, "sysfs")
+		Expect(err).ToNot(HaveOccurred())
+		sysBusPciDevices = filepath.Join(root, "devices")
+		sysBusPciDrivers = filepath.Join(root, "drivers")
+
+		Expect(createFiles(filepath.Join(sysBusPciDevices, vfPCIAddress), "driver_override", "vendor", "device")).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(sysBusPciDevices, vfPCIAddress, "vendor"), []byte("0x8086\n"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(sysBusPciDevices, vfPCIAddress, "device"), []byte("0x"+deviceID+"\n"), 0644)).To(Succeed())
+		Expect(createFiles(filepath.Join(sysBusPciDrivers, utils.IGB_UIO), "bind", "unbind", "new_id", "remove_id")).To(Succeed())
+		Expect(createFiles(filepath.Join(sysBusPciDrivers, utils.VFIO_PCI), "bind", "unbind", "new_id", "remove_id")).To(Succeed())
+	}
+
+	bindToDriver := func(driver string) {
+		Expect(os.Symlink(filepath.Join(sysBusPciDrivers, driver), filepath.Join(sysBusPciDevices, vfPCIAddress, "driver"))).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		nc = &NodeConfigurator{Log: utils.NewLogger()}
+	})
+
+	AfterEach(func() {
+		sysBusPciDevices = originalSysBusPciDevices
+		sysBusPciDrivers = originalSysBusPciDrivers
+	})
+
+	for _, family := range []struct{ name, vfDeviceID string }{{"N3000", "0d90"}, {"ACC100", "0d5d"}} {
+		family := family
+
+		Context("for "+family.name+" VF", func() {
+			BeforeEach(func() {
+				prepareFakeSysfs(family.vfDeviceID)
+			})
+
+			It("will register VF ID in igb_uio before binding", func() {
+				Expect(nc.bindDeviceToDriver(context.TODO(), vfPCIAddress, utils.IGB_UIO)).To(Succeed())
+				Expect(readSysfs(sysBusPciDevices, vfPCIAddress, "driver_override")).To(Equal(utils.IGB_UIO))
+				Expect(readSysfs(sysBusPciDrivers, utils.IGB_UIO, "new_id")).To(Equal("8086 " + family.vfDeviceID))
+				Expect(readSysfs(sysBusPciDrivers, utils.IGB_UIO, "bind")).To(Equal(vfPCIAddress))
+			})
+
+			It("will not register VF ID in vfio-pci", func() {
+				Expect(nc.bindDeviceToDriver(context.TODO(), vfPCIAddress, utils.VFIO_PCI)).To(Succeed())
+				Expect(readSysfs(sysBusPciDevices, vfPCIAddress, "driver_override")).To(Equal(utils.VFIO_PCI))
+				Expect(readSysfs(sysBusPciDrivers, utils.VFIO_PCI, "new_id")).To(BeEmpty())
+				Expect(readSysfs(sysBusPciDrivers, utils.VFIO_PCI, "bind")).To(Equal(vfPCIAddress))
+			})
+
+			It("will skip bind when kernel bound VF to igb_uio after registering new_id", func() {
+				// fake sysfs does not remove driver symlink on unbind, which emulates auto-bind done by kernel
+				bindToDriver(utils.IGB_UIO)
+
+				Expect(nc.bindDeviceToDriver(context.TODO(), vfPCIAddress, utils.IGB_UIO)).To(Succeed())
+				Expect(readSysfs(sysBusPciDrivers, utils.IGB_UIO, "new_id")).To(Equal("8086 " + family.vfDeviceID))
+				Expect(readSysfs(sysBusPciDrivers, utils.IGB_UIO, "bind")).To(BeEmpty())
+			})
+
+			It("will not fail when bind fails but VF is already bound to requested driver", func() {
+				bindToDriver(utils.VFIO_PCI)
+				Expect(os.Remove(filepath.Join(sysBusPciDrivers, utils.VFIO_PCI, "bind"))).To(Succeed())
+				Expect(os.Mkdir(filepath.Join(sysBusPciDrivers, utils.VFIO_PCI, "bind"), 0755)).To(Succeed())
+
+				Expect(nc.bindDeviceToDriver(context.TODO(), vfPCIAddress, utils.VFIO_PCI)).To(Succeed())
+			})
+
+			It("will remove VF ID from igb_uio on unbind", func() {
+				bindToDriver(utils.IGB_UIO)
+
+				Expect(nc.unbindVF(context.TODO(), vfPCIAddress)).To(Succeed())
+				Expect(readSysfs(sysBusPciDrivers, utils.IGB_UIO, "unbind")).To(Equal(vfPCIAddress))
+				Expect(readSysfs(sysBusPciDrivers, utils.IGB_UIO, "remove_id")).To(Equal("8086 " + family.vfDeviceID))
+			})
+
+			It("will not remove VF ID from vfio-pci on unbind", func() {
+				bindToDriver(utils.VFIO_PCI)
+
+				Expect(nc.unbindVF(context.TODO(), vfPCIAddress)).To(Succeed())
+				Expect(readSysfs(sysBusPciDrivers, utils.VFIO_PCI, "unbind")).To(Equal(vfPCIAddress))
+				Expect(readSysfs(sysBusPciDrivers, utils.VFIO_PCI, "remove_id")).To(BeEmpty())
+			})
+		})
+	}
+})
@@ -0,0 +1,145 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	fec "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+)
+
+const nodeNameMatchingEnvVarName = "NODE_NAME_MATCHING"
+
+// NodeNameMatching tells how names of node configs are matched against name of the node
+type NodeNameMatching string
+
+const (
+	// NodeNameMatchingExact requires node configs to be named exactly as the node
+	NodeNameMatchingExact NodeNameMatching = "exact"
+	// NodeNameMatchingHostname compares first DNS labels only, so that node config named with short hostname is acted on
+	// by daemon of node named with FQDN (and vice versa)
+	NodeNameMatchingHostname NodeNameMatching = "hostname"
+)
+
+// NodeNameMatchingFromEnv reads matching from NODE_NAME_MATCHING env variable; exact matching is used when it is not set
+func NodeNameMatchingFromEnv() (NodeNameMatching, error) {
+	switch value := NodeNameMatching(os.Getenv(nodeNameMatchingEnvVarName)); value {
+	case "":
+		return NodeNameMatchingExact, nil
+	case NodeNameMatchingExact, NodeNameMatchingHostname:
+		return value, nil
+	default:
+		return "", fmt.Errorf("invalid %s '%s' - expected '%s' or '%s'", nodeNameMatchingEnvVarName, value,
+			NodeNameMatchingExact, NodeNameMatchingHostname)
+	}
+}
+
+// matches tells whether node config of given name belongs to the node
+func (m NodeNameMatching) matches(name, nodeName string) bool {
+	if m == NodeNameMatchingHostname {
+		return firstDNSLabel(name) == firstDNSLabel(nodeName)
+	}
+	return name == nodeName
+}
+
+func firstDNSLabel(name string) string {
+	label, _, _ := strings.Cut(name, ".")
+	return label
+}
+
+// SetNodeNameMatching sets how names of node configs are matched against name of the node; it has to be set before
+// the reconciler is set up with manager
+func (r *NodeConfigReconciler) SetNodeNameMatching(matching NodeNameMatching) {
+	r.nodeNameMatching = matching
+}
+
+// nodeConfigKey returns key of node configs the daemon acts on; they are named after the node, unless node config
+// named differently was adopted
+func (r *NodeConfigReconciler) nodeConfigKey() types.NamespacedName {
+	r.nodeConfigName.Lock()
+	defer r.nodeConfigName.Unlock()
+	key := r.nodeNameRef
+	if r.nodeConfigName.name != "" {
+		key.Name = r.nodeConfigName.name
+	}
+	return key
+}
+
+// NodeConfigKey returns key of node configs the daemon acts on; components reading them besides the reconciler (e.g.
+// monitors) have to use it rather than name of the node, as node configs may be adopted under a different name
+func (r *NodeConfigReconciler) NodeConfigKey() types.NamespacedName {
+	return r.nodeConfigKey()
+}
+
+// markAdopted sets AdoptedByNodeAnnotation on adopted node config, so that cluster controller writes spec of the node
+// into it rather than into node config named after the node; nothing is done for node config named after the node
+func (r *NodeConfigReconciler) markAdopted(ctx context.Context, c client.Client, nc client.Object, annotation string) error {
+	if nc.GetName() == r.nodeNameRef.Name || nc.GetAnnotations()[annotation] == r.nodeNameRef.Name {
+		return nil
+	}
+	original := nc.DeepCopyObject().(client.Object)
+	annotations := nc.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[annotation] = r.nodeNameRef.Name
+	nc.SetAnnotations(annotations)
+	if err := c.Patch(ctx, nc, client.MergeFrom(original)); err != nil {
+		return fmt.Errorf("failed to mark node config %s adopted - %v", nc.GetName(), err)
+	}
+	return nil
+}
+
+// adoptNodeConfigIfNeeded looks for node config matching the node by hostname, when node config of the daemon does not
+// exist; the first one by name is adopted, so that both SriovFecNodeConfig and SriovVrbNodeConfig of that name are acted
+// on. Node configs are adopted only with hostname matching and only once.
+func (r *NodeConfigReconciler) adoptNodeConfigIfNeeded(ctx context.Context, c client.Client, list client.ObjectList) error {
+	key := r.nodeConfigKey()
+	if r.nodeNameMatching != NodeNameMatchingHostname || key.Name != r.nodeNameRef.Name {
+		return nil
+	}
+
+	if err := c.List(ctx, list, client.InNamespace(key.Namespace)); err != nil {
+		return fmt.Errorf("failed to list node configs - %v", err)
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return err
+	}
+	var candidates []string
+	for _, item := range items {
+		o, ok := item.(client.Object)
+		if !ok || !r.nodeNameMatching.matches(o.GetName(), r.nodeNameRef.Name) {
+			continue
+		}
+		// secondary node configs of node named with FQDN match by hostname as well
+		if nc, ok := o.(*fec.SriovFecNodeConfig); ok && nc.IsSecondary() {
+			continue
+		}
+		if o.GetName() == r.nodeNameRef.Name {
+			return nil
+		}
+		candidates = append(candidates, o.GetName())
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	sort.Strings(candidates)
+
+	r.nodeConfigName.Lock()
+	r.nodeConfigName.name = candidates[0]
+	r.nodeConfigName.Unlock()
+	r.log.WithField("node", r.nodeNameRef.Name).WithField("nodeConfig", candidates[0]).WithField("candidates", candidates).
+		Warn("ADOPTING NODE CONFIG NAMED DIFFERENTLY THAN THE NODE - it matches the node by hostname only; " +
+			"name node configs exactly as node objects to avoid ambiguity")
+	return nil
+}
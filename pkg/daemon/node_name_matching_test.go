@@ -0,0 +1,187 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("NodeNameMatchingFromEnv", func() {
+	AfterEach(func() {
+		Expect(os.Unsetenv(nodeNameMatchingEnvVarName)).To(Succeed())
+	})
+
+	It("defaults to exact matching", func() {
+		Expect(NodeNameMatchingFromEnv()).To(Equal(NodeNameMatchingExact))
+	})
+
+	It("accepts hostname matching", func() {
+		Expect(os.Setenv(nodeNameMatchingEnvVarName, "hostname")).To(Succeed())
+		Expect(NodeNameMatchingFromEnv()).To(Equal(NodeNameMatchingHostname))
+	})
+
+	It("rejects unknown matching", func() {
+		Expect(os.Setenv(nodeNameMatchingEnvVarName, "prefix")).To(Succeed())
+		_, err := NodeNameMatchingFromEnv()
+		Expect(err).To(MatchError(ContainSubstring("invalid NODE_NAME_MATCHING 'prefix'")))
+	})
+})
+
+var _ = Describe("resourceNamePredicate with hostname matching", func() {
+	nodeConfig := func(name string) client.Object {
+		return &sriovv2.SriovFecNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+	}
+
+	It("passes node configs matching the node by hostname", func() {
+		p := resourceNamePredicate{requiredName: "worker.example.com", matching: NodeNameMatchingHostname, log: utils.NewLogger()}
+		Expect(p.Create(event.CreateEvent{Object: nodeConfig("worker.example.com")})).To(BeTrue())
+		Expect(p.Create(event.CreateEvent{Object: nodeConfig("worker")})).To(BeTrue())
+		Expect(p.Create(event.CreateEvent{Object: nodeConfig("worker.other.com")})).To(BeTrue())
+		Expect(p.Create(event.CreateEvent{Object: nodeConfig("worker-2")})).To(BeFalse())
+	})
+
+	It("passes node config named with FQDN to the node named with hostname", func() {
+		p := resourceNamePredicate{requiredName: "worker", matching: NodeNameMatchingHostname, log: utils.NewLogger()}
+		Expect(p.Update(event.UpdateEvent{ObjectOld: nodeConfig("worker.example.com"), ObjectNew: nodeConfig("worker.example.com")})).To(BeTrue())
+	})
+
+	It("does not pass node configs matching by hostname with exact matching", func() {
+		p := resourceNamePredicate{requiredName: "worker.example.com", matching: NodeNameMatchingExact, log: utils.NewLogger()}
+		Expect(p.Create(event.CreateEvent{Object: nodeConfig("worker")})).To(BeFalse())
+	})
+})
+
+var _ = Describe("Node config adoption", func() {
+	var (
+		nodeNameRef                  = types.NamespacedName{Name: "worker.example.com", Namespace: "default"}
+		reconciler                   *NodeConfigReconciler
+		c                            client.Client
+		originalGetSriovInventory    = getSriovInventory
+		originalVrbGetSriovInventory = VrbgetSriovInventory
+	)
+
+	nodeConfig := func(name string) *sriovv2.SriovFecNodeConfig {
+		return &sriovv2.SriovFecNodeConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: nodeNameRef.Namespace},
+			Spec: sriovv2.SriovFecNodeConfigSpec{PhysicalFunctions: []sriovv2.PhysicalFunctionConfigExt{
+				{PCIAddress: "0000:f7:00.0", PFDriver: utils.PCI_PF_STUB_DASH, VFDriver: utils.VFIO_PCI, VFAmount: 2},
+			}},
+		}
+	}
+
+	BeforeEach(func() {
+		getSriovInventory = func(context.Context, *logrus.Logger) (*sriovv2.NodeInventory, error) {
+			return &sriovv2.NodeInventory{SriovAccelerators: []sriovv2.SriovAccelerator{{PCIAddress: "0000:f7:00.0", MaxVFs: 16}}}, nil
+		}
+		VrbgetSriovInventory = func(context.Context, *logrus.Logger) (*vrbv1.NodeInventory, error) {
+			return &vrbv1.NodeInventory{}, nil
+		}
+		scheme := runtime.NewScheme()
+		Expect(sriovv2.AddToScheme(scheme)).To(Succeed())
+		Expect(vrbv1.AddToScheme(scheme)).To(Succeed())
+		c = fake.NewClientBuilder().WithScheme(scheme).Build()
+		reconciler = &NodeConfigReconciler{Client: c, log: utils.NewLogger(), nodeNameRef: nodeNameRef}
+		reconciler.SetNodeNameMatching(NodeNameMatchingHostname)
+	})
+
+	AfterEach(func() {
+		getSriovInventory = originalGetSriovInventory
+		VrbgetSriovInventory = originalVrbGetSriovInventory
+	})
+
+	It("adopts node config named with hostname instead of creating one named after the node", func() {
+		Expect(c.Create(context.TODO(), nodeConfig("worker"))).To(Succeed())
+
+		Expect(reconciler.CreateEmptyNodeConfigIfNeeded(context.TODO(), c)).To(Succeed())
+		Expect(reconciler.VrbCreateEmptyNodeConfigIfNeeded(context.TODO(), c)).To(Succeed())
+
+		Expect(reconciler.nodeConfigKey()).To(Equal(types.NamespacedName{Name: "worker", Namespace: nodeNameRef.Namespace}))
+		Expect(c.Get(context.TODO(), nodeNameRef, &sriovv2.SriovFecNodeConfig{})).ToNot(Succeed())
+		nc := &sriovv2.SriovFecNodeConfig{}
+		Expect(c.Get(context.TODO(), reconciler.nodeConfigKey(), nc)).To(Succeed())
+		Expect(nc.Spec.PhysicalFunctions).To(HaveLen(1))
+		// VRB node config is created under the adopted name, as both are reconciled together
+		Expect(c.Get(context.TODO(), reconciler.nodeConfigKey(), &vrbv1.SriovVrbNodeConfig{})).To(Succeed())
+	})
+
+	It("marks adopted node configs for cluster controller", func() {
+		Expect(c.Create(context.TODO(), nodeConfig("worker"))).To(Succeed())
+
+		Expect(reconciler.CreateEmptyNodeConfigIfNeeded(context.TODO(), c)).To(Succeed())
+		Expect(reconciler.VrbCreateEmptyNodeConfigIfNeeded(context.TODO(), c)).To(Succeed())
+
+		nc := &sriovv2.SriovFecNodeConfig{}
+		Expect(c.Get(context.TODO(), reconciler.NodeConfigKey(), nc)).To(Succeed())
+		Expect(nc.Annotations).To(HaveKeyWithValue(sriovv2.AdoptedByNodeAnnotation, nodeNameRef.Name))
+		vrbNc := &vrbv1.SriovVrbNodeConfig{}
+		Expect(c.Get(context.TODO(), reconciler.NodeConfigKey(), vrbNc)).To(Succeed())
+		Expect(vrbNc.Annotations).To(HaveKeyWithValue(vrbv1.AdoptedByNodeAnnotation, nodeNameRef.Name))
+	})
+
+	It("does not mark node config named after the node", func() {
+		Expect(reconciler.CreateEmptyNodeConfigIfNeeded(context.TODO(), c)).To(Succeed())
+
+		nc := &sriovv2.SriovFecNodeConfig{}
+		Expect(c.Get(context.TODO(), nodeNameRef, nc)).To(Succeed())
+		Expect(nc.Annotations).ToNot(HaveKey(sriovv2.AdoptedByNodeAnnotation))
+	})
+
+	It("prefers node config named exactly as the node", func() {
+		Expect(c.Create(context.TODO(), nodeConfig("worker"))).To(Succeed())
+		Expect(c.Create(context.TODO(), nodeConfig(nodeNameRef.Name))).To(Succeed())
+
+		Expect(reconciler.CreateEmptyNodeConfigIfNeeded(context.TODO(), c)).To(Succeed())
+
+		Expect(reconciler.nodeConfigKey()).To(Equal(nodeNameRef))
+	})
+
+	It("does not adopt secondary node configs", func() {
+		secondary := nodeConfig("worker.example.com-acc100")
+		secondary.Spec.NodeName = nodeNameRef.Name
+		Expect(c.Create(context.TODO(), secondary)).To(Succeed())
+
+		Expect(reconciler.CreateEmptyNodeConfigIfNeeded(context.TODO(), c)).To(Succeed())
+
+		Expect(reconciler.nodeConfigKey()).To(Equal(nodeNameRef))
+		Expect(c.Get(context.TODO(), nodeNameRef, &sriovv2.SriovFecNodeConfig{})).To(Succeed())
+	})
+
+	It("does not adopt node configs with exact matching", func() {
+		reconciler.SetNodeNameMatching(NodeNameMatchingExact)
+		Expect(c.Create(context.TODO(), nodeConfig("worker"))).To(Succeed())
+
+		Expect(reconciler.CreateEmptyNodeConfigIfNeeded(context.TODO(), c)).To(Succeed())
+
+		Expect(reconciler.nodeConfigKey()).To(Equal(nodeNameRef))
+		Expect(c.Get(context.TODO(), nodeNameRef, &sriovv2.SriovFecNodeConfig{})).To(Succeed())
+	})
+
+	It("ignores node configs matching by hostname other than the adopted one", func() {
+		Expect(c.Create(context.TODO(), nodeConfig("worker"))).To(Succeed())
+		Expect(reconciler.CreateEmptyNodeConfigIfNeeded(context.TODO(), c)).To(Succeed())
+		Expect(c.Create(context.TODO(), nodeConfig("worker.other.com"))).To(Succeed())
+
+		var affected []client.Object
+		result, err := reconciler.reconcileSecondaryNodeConfig(context.TODO(), types.NamespacedName{Name: "worker.other.com", Namespace: nodeNameRef.Namespace}, &affected)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal(reconcile.Result{}))
+		Expect(affected).To(BeEmpty())
+	})
+})
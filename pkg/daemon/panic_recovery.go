@@ -0,0 +1,86 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"regexp"
+	"runtime/debug"
+	"strings"
+
+	fec "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	// call arguments and offsets of program counter differ between occurrences of the same panic
+	stackArgumentsRegexp = regexp.MustCompile(`\([^()]*\)$`)
+	stackOffsetRegexp    = regexp.MustCompile(` \+0x[0-9a-f]+$`)
+)
+
+// stackDigest identifies code path of the panic; goroutine ids, call arguments and offsets are left out, so that
+// the same panic gets the same digest on every node
+func stackDigest(stack []byte) string {
+	var frames []string
+	for _, line := range strings.Split(string(stack), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "goroutine ") {
+			continue
+		}
+		line = stackArgumentsRegexp.ReplaceAllString(line, "")
+		frames = append(frames, stackOffsetRegexp.ReplaceAllString(line, ""))
+	}
+	sum := sha256.Sum256([]byte(strings.Join(frames, "\n")))
+	return fmt.Sprintf("%x", sum[:6])
+}
+
+// panicError converts value recovered from panic into error carrying the panic message and digest of the stack;
+// the whole stack is logged only
+func (r *NodeConfigReconciler) panicError(recovered interface{}) error {
+	stack := debug.Stack()
+	digest := stackDigest(stack)
+	r.log.WithField("panic", recovered).WithField("digest", digest).Errorf("recovered from panic:\n%s", stack)
+	return fmt.Errorf("panic: %v (stack digest %s)", recovered, digest)
+}
+
+// recoverReconcilePanic, deferred by Reconcile, converts panic into ConfigurationFailed condition of node configs
+// being reconciled, so that the daemon is not restarted with the node left cordoned; returned error makes
+// controller-runtime back off
+func (r *NodeConfigReconciler) recoverReconcilePanic(ctx context.Context, affected *[]client.Object, result *ctrl.Result, err *error) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+	*result, *err = ctrl.Result{}, r.panicError(recovered)
+
+	for _, nc := range *affected {
+		r.reportRecoveredPanic(ctx, nc, *err)
+	}
+}
+
+// reportRecoveredPanic sets ConfigurationFailed condition of the node config; status update may hit the same bug,
+// so it is guarded as well
+func (r *NodeConfigReconciler) reportRecoveredPanic(ctx context.Context, nc client.Object, panicErr error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			r.log.WithField("panic", recovered).WithField("name", nc.GetName()).Error("failed to report recovered panic")
+		}
+	}()
+
+	var err error
+	switch nc := nc.(type) {
+	case *fec.SriovFecNodeConfig:
+		err = r.updateStatus(ctx, nc, metav1.ConditionFalse, ConfigurationFailed, panicErr.Error())
+	case *vrbv1.SriovVrbNodeConfig:
+		err = r.VrbupdateStatus(ctx, nc, metav1.ConditionFalse, ConfigurationFailed, panicErr.Error())
+	}
+	if err != nil {
+		r.log.WithError(err).WithField("name", nc.GetName()).Error("failed to report recovered panic")
+	}
+}
@@ -0,0 +1,165 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("NodeConfigReconciler.Reconcile recovering from panic", func() {
+	const panicMessage = `^panic: runtime error: invalid memory address or nil pointer dereference \(stack digest [0-9a-f]{12}\)$`
+
+	var (
+		fakeClient      client.Client
+		nodeNameRef     = types.NamespacedName{Name: "worker", Namespace: "testNamespace"}
+		reconciler      NodeConfigReconciler
+		performUncordon *bool
+	)
+
+	// the nil inventory dereference class of bugs
+	dereferenceNilInventory := func() int {
+		var inventory *sriovv2.NodeInventory
+		return len(inventory.SriovAccelerators)
+	}
+
+	reconcile := func() (err error) {
+		Expect(func() {
+			_, err = reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: nodeNameRef})
+		}).ToNot(Panic())
+		return err
+	}
+
+	configuredCondition := func(nc client.Object) *metav1.Condition {
+		Expect(fakeClient.Get(context.TODO(), nodeNameRef, nc)).To(Succeed())
+		switch nc := nc.(type) {
+		case *sriovv2.SriovFecNodeConfig:
+			return nc.FindCondition(ConditionConfigured)
+		case *vrbv1.SriovVrbNodeConfig:
+			return nc.FindCondition(ConditionConfigured)
+		}
+		return nil
+	}
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(sriovv2.AddToScheme(scheme)).ToNot(HaveOccurred())
+		Expect(vrbv1.AddToScheme(scheme)).ToNot(HaveOccurred())
+
+		procCmdlineFilePath = "testdata/cmdline_test"
+		sysLockdownFilePath = filepath.Join(testTmpFolder, "lockdown")
+		Expect(os.WriteFile(sysLockdownFilePath, []byte("[none] integrity confidentiality"), 0644)).To(Succeed())
+		performUncordon = nil
+
+		getSriovInventory = func(_ context.Context, log *logrus.Logger) (*sriovv2.NodeInventory, error) {
+			return &sriovv2.NodeInventory{
+				SriovAccelerators: []sriovv2.SriovAccelerator{{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, MaxVFs: 10}},
+			}, nil
+		}
+		VrbgetSriovInventory = func(_ context.Context, log *logrus.Logger) (*vrbv1.NodeInventory, error) {
+			return &vrbv1.NodeInventory{}, nil
+		}
+
+		sfnc := &sriovv2.SriovFecNodeConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace, Generation: 1},
+			Spec: sriovv2.SriovFecNodeConfigSpec{
+				PhysicalFunctions: []sriovv2.PhysicalFunctionConfigExt{{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, VFDriver: utils.IGB_UIO, VFAmount: 1}},
+			},
+		}
+		vrbnc := &vrbv1.SriovVrbNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace}}
+		fakeClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(sfnc, vrbnc).Build()
+
+		reconciler = NodeConfigReconciler{
+			Client:      fakeClient,
+			log:         utils.NewLogger(),
+			nodeNameRef: nodeNameRef,
+			sriovfecconfigurer: testConfigurerProto{configureNodeFunction: func(sriovv2.SriovFecNodeConfigSpec) error {
+				dereferenceNilInventory()
+				return nil
+			}},
+			drainerAndExecute: func(_ context.Context, configurer func(ctx context.Context) bool, drain bool) error {
+				uncordon := configurer(context.TODO())
+				performUncordon = &uncordon
+				return nil
+			},
+			restartDevicePlugin: func(context.Context) error { return nil },
+		}
+	})
+
+	AfterEach(func() {
+		getSriovInventory = GetSriovInventory
+		VrbgetSriovInventory = VrbGetSriovInventory
+		sysLockdownFilePath = "/sys/kernel/security/lockdown"
+	})
+
+	It("fails configuration when configurator panics in the drain callback", func() {
+		Expect(reconcile()).To(Succeed())
+
+		Expect(performUncordon).ToNot(BeNil(), "drain callback has to return")
+		Expect(*performUncordon).To(BeTrue())
+		Expect(reconciler.IsConfigurationInProgress()).To(BeFalse())
+
+		condition := configuredCondition(&sriovv2.SriovFecNodeConfig{})
+		Expect(condition.Reason).To(Equal(string(ConfigurationFailed)))
+		Expect(condition.Message).To(MatchRegexp(panicMessage))
+	})
+
+	It("fails node configs being reconciled when reconcile body panics", func() {
+		panicked := false
+		VrbgetSriovInventory = func(_ context.Context, log *logrus.Logger) (*vrbv1.NodeInventory, error) {
+			if !panicked {
+				panicked = true
+				dereferenceNilInventory()
+			}
+			return &vrbv1.NodeInventory{}, nil
+		}
+
+		Expect(reconcile()).To(MatchError(MatchRegexp(panicMessage)))
+
+		Expect(performUncordon).To(BeNil(), "node must not be drained")
+		for _, nc := range []client.Object{&sriovv2.SriovFecNodeConfig{}, &vrbv1.SriovVrbNodeConfig{}} {
+			condition := configuredCondition(nc)
+			Expect(condition).ToNot(BeNil())
+			Expect(condition.Reason).To(Equal(string(ConfigurationFailed)))
+			Expect(condition.Message).To(MatchRegexp(panicMessage))
+		}
+	})
+
+	It("does not fail reconcile when reporting the panic panics again", func() {
+		getSriovInventory = func(_ context.Context, log *logrus.Logger) (*sriovv2.NodeInventory, error) {
+			dereferenceNilInventory()
+			return nil, nil
+		}
+
+		Expect(reconcile()).To(MatchError(MatchRegexp(panicMessage)))
+	})
+})
+
+var _ = Describe("stackDigest", func() {
+	It("identifies code path regardless of goroutine, arguments and offsets", func() {
+		stack := func(goroutine, argument, offset string) []byte {
+			return []byte("goroutine " + goroutine + " [running]:\n" +
+				"github.com/smart-edge-open/sriov-fec-operator/pkg/daemon.(*NodeConfigReconciler).configureNode(" + argument + ")\n" +
+				"\t/workspace/pkg/daemon/daemon.go:742 +0x" + offset + "\n")
+		}
+
+		Expect(stackDigest(stack("7", "0xc0001", "1a"))).To(Equal(stackDigest(stack("42", "0xc0002, 0x3", "2b"))))
+		Expect(stackDigest(stack("7", "0xc0001", "1a"))).ToNot(Equal(stackDigest([]byte("goroutine 7 [running]:\nmain.main()\n"))))
+	})
+})
@@ -0,0 +1,162 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var inventoryScanDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "inventory_scan_duration_seconds",
+	Help:    `duration of sysfs scan performed to collect accelerators inventory. 'kind' - represents inventory kind. Available values: 'sriovfec', 'sriovvrb'`,
+	Buckets: prometheus.ExponentialBuckets(0.001, 2, 14),
+}, []string{"kind"})
+
+func init() {
+	metrics.Registry.MustRegister(inventoryScanDuration)
+}
+
+// pciDevice holds attributes of single PCI device read from sysfs
+type pciDevice struct {
+	address    string
+	vendorID   string
+	deviceID   string
+	classID    string
+	subclassID string
+	driver     string
+	// sriovCapable is true for SR-IOV physical functions i.e. devices exposing sriov_totalvfs
+	sriovCapable bool
+	totalVFs     int
+	vfs          []string
+}
+
+// pciSnapshot is an in-memory snapshot of all PCI devices, taken with single walk over sysfs
+type pciSnapshot struct {
+	devices   []*pciDevice
+	byAddress map[string]*pciDevice
+}
+
+func (s *pciSnapshot) filter(f func(*pciDevice) bool) []*pciDevice {
+	var filtered []*pciDevice
+	for _, d := range s.devices {
+		if f(d) {
+			filtered = append(filtered, d)
+		}
+	}
+	return filtered
+}
+
+func (s *pciSnapshot) get(address string) *pciDevice {
+	return s.byAddress[address]
+}
+
+// scanPCIDevices takes snapshot of PCI devices and records duration of the scan
+func scanPCIDevices(log *logrus.Logger, kind string) (*pciSnapshot, error) {
+	start := time.Now()
+	snapshot, err := takePCISnapshot(log)
+	duration := time.Since(start)
+
+	inventoryScanDuration.WithLabelValues(kind).Observe(duration.Seconds())
+	if err != nil {
+		return nil, err
+	}
+
+	log.WithField("kind", kind).WithField("duration", duration).WithField("devices", len(snapshot.devices)).
+		Debug("pci devices scanned")
+	return snapshot, nil
+}
+
+// takePCISnapshot reads all sysfs attributes required to build the inventory. Entries which cannot be parsed
+// (e.g. missing vendor, device or class) are logged and skipped.
+func takePCISnapshot(log *logrus.Logger) (*pciSnapshot, error) {
+	entries, err := os.ReadDir(sysBusPciDevices)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pci devices: %v", err)
+	}
+
+	snapshot := &pciSnapshot{byAddress: map[string]*pciDevice{}}
+	for _, entry := range entries {
+		device, err := readPCIDevice(entry.Name())
+		if err != nil {
+			log.WithField("pci", entry.Name()).WithField("reason", err.Error()).Info("ignoring malformed pci device entry")
+			continue
+		}
+		snapshot.devices = append(snapshot.devices, device)
+		snapshot.byAddress[device.address] = device
+	}
+	return snapshot, nil
+}
+
+func readPCIDevice(address string) (*pciDevice, error) {
+	dir := filepath.Join(sysBusPciDevices, address)
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	device := &pciDevice{address: address}
+	// os.ReadDir returns entries sorted by name, so virtfn links are ordered the same way as by filepath.Glob
+	for _, f := range files {
+		name := f.Name()
+		switch {
+		case name == "vendor":
+			if device.vendorID, err = readHexAttribute(dir, name); err != nil {
+				return nil, err
+			}
+		case name == "device":
+			if device.deviceID, err = readHexAttribute(dir, name); err != nil {
+				return nil, err
+			}
+		case name == "class":
+			class, err := readHexAttribute(dir, name)
+			if err != nil {
+				return nil, err
+			}
+			if len(class) != 6 {
+				return nil, fmt.Errorf("unexpected class '%s'", class)
+			}
+			device.classID, device.subclassID = class[0:2], class[2:4]
+		case name == "driver":
+			if link, err := os.Readlink(filepath.Join(dir, name)); err == nil {
+				device.driver = filepath.Base(link)
+			}
+		case name == "sriov_totalvfs":
+			device.sriovCapable = true
+			if content, err := os.ReadFile(filepath.Join(dir, name)); err == nil {
+				device.totalVFs, _ = strconv.Atoi(strings.TrimSpace(string(content)))
+			}
+		case strings.HasPrefix(name, "virtfn"):
+			if link, err := os.Readlink(filepath.Join(dir, name)); err == nil {
+				device.vfs = append(device.vfs, filepath.Base(link))
+			}
+		}
+	}
+
+	if device.vendorID == "" || device.deviceID == "" || device.classID == "" {
+		return nil, fmt.Errorf("missing vendor, device or class")
+	}
+	return device, nil
+}
+
+// readHexAttribute returns content of sysfs attribute like "0x8086" as lowercase hex digits without prefix ("8086")
+func readHexAttribute(dir, name string) (string, error) {
+	content, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return "", err
+	}
+	value := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(string(content)), "0x"))
+	if _, err := strconv.ParseUint(value, 16, 64); err != nil {
+		return "", fmt.Errorf("invalid %s '%s'", name, value)
+	}
+	return value, nil
+}
@@ -0,0 +1,172 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+
+	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("Inventory built from sysfs snapshot", func() {
+	const (
+		pfPCIAddress      = "0000:14:00.0"
+		vf0PCIAddress     = "0000:15:00.0"
+		vf1PCIAddress     = "0000:15:00.1"
+		noSriovPCIAddress = "0000:16:00.0"
+		unknownPCIAddress = "0000:17:00.0"
+	)
+
+	var (
+		log                              = logrus.New()
+		originalSysBusPciDevices         = sysBusPciDevices
+		originalSupportedAccelerators    = supportedAccelerators
+		originalVrbSupportedAccelerators = VrbsupportedAccelerators
+		root                             string
+	)
+
+	createDevice := func(pciAddress string, attributes map[string]string) {
+		dir := filepath.Join(sysBusPciDevices, pciAddress)
+		Expect(os.MkdirAll(dir, 0755)).To(Succeed())
+		for name, value := range attributes {
+			Expect(os.WriteFile(filepath.Join(dir, name), []byte(value+"\n"), 0644)).To(Succeed())
+		}
+	}
+
+	link := func(pciAddress, name, target string) {
+		Expect(os.Symlink(target, filepath.Join(sysBusPciDevices, pciAddress, name))).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+		root, err = os.MkdirTemp(testTmpFolder, "sysfs")
+		Expect(err).ToNot(HaveOccurred())
+		sysBusPciDevices = filepath.Join(root, "devices")
+		Expect(os.MkdirAll(sysBusPciDevices, 0755)).To(Succeed())
+
+		supportedAccelerators = utils.AcceleratorDiscoveryConfig{
+			VendorID: map[string]string{"8086": "Intel"},
+			Class:    "12",
+			SubClass: "00",
+			Devices:  map[string]string{"0d5c": "ACC100"},
+		}
+		VrbsupportedAccelerators = utils.AcceleratorDiscoveryConfig{
+			VendorID: map[string]string{"8086": "Intel"},
+			Class:    "12",
+			SubClass: "00",
+			Devices:  map[string]string{"57c0": "VRB1"},
+		}
+
+		createDevice(pfPCIAddress, map[string]string{"vendor": "0x8086", "device": "0x0D5C", "class": "0x120000", "sriov_totalvfs": "16"})
+		link(pfPCIAddress, "driver", "../../../bus/pci/drivers/pci-pf-stub")
+		link(pfPCIAddress, "virtfn0", "../"+vf0PCIAddress)
+		link(pfPCIAddress, "virtfn1", "../"+vf1PCIAddress)
+
+		createDevice(vf0PCIAddress, map[string]string{"vendor": "0x8086", "device": "0x0d5d", "class": "0x120000"})
+		link(vf0PCIAddress, "driver", "../../../bus/pci/drivers/vfio-pci")
+		createDevice(vf1PCIAddress, map[string]string{"vendor": "0x8086", "device": "0x0d5d", "class": "0x120000"})
+
+		createDevice(noSriovPCIAddress, map[string]string{"vendor": "0x8086", "device": "0x0d5c", "class": "0x120000"})
+		createDevice(unknownPCIAddress, map[string]string{"vendor": "0x8086", "device": "0x1572", "class": "0x020000", "sriov_totalvfs": "64"})
+	})
+
+	AfterEach(func() {
+		sysBusPciDevices = originalSysBusPciDevices
+		supportedAccelerators = originalSupportedAccelerators
+		VrbsupportedAccelerators = originalVrbSupportedAccelerators
+		Expect(os.RemoveAll(root)).To(Succeed())
+	})
+
+	It("should report known SR-IOV PFs together with their VFs", func() {
+		inventory, err := GetSriovInventory(context.TODO(), log)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(inventory.SriovAccelerators).To(HaveLen(1))
+		// VFs, devices without SR-IOV and devices of other class are not accelerators
+		Expect(inventory.UnsupportedDevices).To(BeEmpty())
+
+		acc := inventory.SriovAccelerators[0]
+		Expect(acc.PCIAddress).To(Equal(pfPCIAddress))
+		Expect(acc.VendorID).To(Equal("8086"))
+		Expect(acc.DeviceID).To(Equal("0d5c"))
+		Expect(acc.PFDriver).To(Equal(utils.PCI_PF_STUB_DASH))
+		Expect(acc.MaxVFs).To(Equal(16))
+		Expect(acc.VFs).To(HaveLen(2))
+		Expect(acc.VFs[0].PCIAddress).To(Equal(vf0PCIAddress))
+		Expect(acc.VFs[0].Driver).To(Equal("vfio-pci"))
+		Expect(acc.VFs[0].DeviceID).To(Equal("0d5d"))
+		Expect(acc.VFs[1].PCIAddress).To(Equal(vf1PCIAddress))
+		Expect(acc.VFs[1].Driver).To(BeEmpty())
+	})
+
+	It("should use VRB discovery config for VRB inventory", func() {
+		inventory, err := VrbGetSriovInventory(context.TODO(), log)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(inventory.SriovAccelerators).To(BeEmpty())
+	})
+
+	It("should report present SR-IOV accelerators missing in discovery configs as unsupported", func() {
+		createDevice("0000:f7:00.0", map[string]string{"vendor": "0x8086", "device": "0x57c2", "class": "0x120000", "sriov_totalvfs": "16"})
+
+		inventory, err := GetSriovInventory(context.TODO(), log)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(inventory.SriovAccelerators).To(HaveLen(1))
+		Expect(inventory.UnsupportedDevices).To(Equal([]sriovv2.UnsupportedDevice{{PCIAddress: "0000:f7:00.0", VendorID: "8086", DeviceID: "57c2"}}))
+
+		vrbInventory, err := VrbGetSriovInventory(context.TODO(), log)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(vrbInventory.UnsupportedDevices).To(Equal([]vrbv1.UnsupportedDevice{{PCIAddress: "0000:f7:00.0", VendorID: "8086", DeviceID: "57c2"}}))
+
+		pfs := []sriovv2.PhysicalFunctionConfigExt{{PCIAddress: pfPCIAddress}}
+		Expect(fecUnsupportedDeviceRequested(pfs, inventory)).To(Succeed())
+		pfs = append(pfs, sriovv2.PhysicalFunctionConfigExt{PCIAddress: "0000:f7:00.0"})
+		Expect(fecUnsupportedDeviceRequested(pfs, inventory)).To(MatchError(
+			"accelerator 0000:f7:00.0 (8086:57c2) is present but not supported - device ID 57c2 is missing in accelerators.json of supported-accelerators ConfigMap"))
+		Expect(vrbUnsupportedDeviceRequested([]vrbv1.PhysicalFunctionConfigExt{{PCIAddress: "0000:f7:00.0"}}, vrbInventory)).To(MatchError(
+			ContainSubstring("device ID 57c2 is missing in accelerators_vrb.json")))
+	})
+
+	It("should skip malformed entries", func() {
+		createDevice("0000:18:00.0", map[string]string{"device": "0x0d5c", "class": "0x120000", "sriov_totalvfs": "16"})
+		createDevice("0000:19:00.0", map[string]string{"vendor": "0x8086", "device": "0x0d5c", "class": "bogus", "sriov_totalvfs": "16"})
+		createDevice("0000:1a:00.0", map[string]string{"vendor": "0x8086", "device": "0x0d5c", "class": "0x12", "sriov_totalvfs": "16"})
+		Expect(os.WriteFile(filepath.Join(sysBusPciDevices, "0000:1b:00.0"), []byte{}, 0644)).To(Succeed())
+
+		snapshot, err := takePCISnapshot(log)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(snapshot.devices).To(HaveLen(5))
+		Expect(snapshot.get("0000:18:00.0")).To(BeNil())
+		Expect(snapshot.get("0000:19:00.0")).To(BeNil())
+		Expect(snapshot.get("0000:1a:00.0")).To(BeNil())
+
+		inventory, err := GetSriovInventory(context.TODO(), log)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(inventory.SriovAccelerators).To(HaveLen(1))
+	})
+
+	It("should report missing VF entry without driver and device ID", func() {
+		Expect(os.RemoveAll(filepath.Join(sysBusPciDevices, vf1PCIAddress))).To(Succeed())
+
+		inventory, err := GetSriovInventory(context.TODO(), log)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(inventory.SriovAccelerators[0].VFs).To(HaveLen(2))
+		Expect(inventory.SriovAccelerators[0].VFs[1].PCIAddress).To(Equal(vf1PCIAddress))
+		Expect(inventory.SriovAccelerators[0].VFs[1].DeviceID).To(BeEmpty())
+	})
+
+	It("should return error when no pci devices were found", func() {
+		Expect(os.RemoveAll(sysBusPciDevices)).To(Succeed())
+		Expect(os.MkdirAll(sysBusPciDevices, 0755)).To(Succeed())
+
+		_, err := GetSriovInventory(context.TODO(), log)
+		Expect(err).To(MatchError("pci scan returned 0 devices"))
+	})
+})
@@ -0,0 +1,257 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	fec "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+)
+
+const defaultPfBBConfigAppFilepath = "/sriov_workdir/pf_bb_config"
+
+// BBDevConfig features which are not supported by all pf_bb_config releases
+const (
+	featureACC100              = "ACC100 configuration"
+	featureACC100FFTLut        = "ACC100 SRS FFT LUT (fftLut)"
+	featureACC100InterruptMode = "ACC100 INTERRUPT section (interruptMode)"
+	featureACC200              = "ACC200 configuration"
+	featureVRB1                = "VRB1 configuration"
+	featureFFTQueues           = "FFT queues (qfft)"
+	featureFFTWindows          = "custom SRS FFT windows (fftLut)"
+	featureVRB2                = "VRB2 configuration"
+	featureMLDQueues           = "MLD queues (qmld)"
+)
+
+// pfBBConfigFeatures tells which pf_bb_config release introduced each of BBDevConfig features
+var pfBBConfigFeatures = []struct {
+	feature    string
+	minVersion string
+}{
+	{featureACC100, "21.03"},
+	{featureACC200, "22.11"},
+	{featureVRB1, "22.11"},
+	{featureFFTQueues, "22.11"},
+	{featureFFTWindows, "23.03"},
+	{featureVRB2, "23.11"},
+	{featureMLDQueues, "24.03"},
+	{featureACC100InterruptMode, "24.03"},
+	{featureACC100FFTLut, "24.07"},
+}
+
+// pfBBConfigTelemetrySocketVersion is pf_bb_config release which introduced telemetry socket; older releases dump
+// their counters on SIGUSR2, while newer ones don't handle the signal and are terminated by it
+const pfBBConfigTelemetrySocketVersion = "22.03"
+
+// pfBBConfigVersionPattern matches version in output of pf_bb_config --version, e.g. "Version 24.03-0-g1a2b3c" or
+// "pf_bb_config version v22.11"; only numbers following "version" or "v" are taken, not e.g. a year of copyright
+var pfBBConfigVersionPattern = regexp.MustCompile(`(?i)(?:\bversion:?\s*v?|\bv)(\d+\.\d+(?:\.\d+)?)\b`)
+
+// pf_bb_config releases are versioned by year and month of the release, YY.MM
+const (
+	minPfBBConfigMajorVersion = 19
+	maxPfBBConfigMajorVersion = 99
+)
+
+// pfBBConfigVersion is [major, minor, patch] version of pf_bb_config
+type pfBBConfigVersion [3]int
+
+// parsePfBBConfigVersion finds version in output of pf_bb_config --version; version which does not look like a
+// pf_bb_config release is rejected, so that the version is treated as unknown
+func parsePfBBConfigVersion(output string) (pfBBConfigVersion, error) {
+	match := pfBBConfigVersionPattern.FindStringSubmatch(output)
+	if match == nil {
+		return pfBBConfigVersion{}, fmt.Errorf("no version found in %q", strings.TrimSpace(output))
+	}
+	version, err := newPfBBConfigVersion(match[1])
+	if err != nil {
+		return pfBBConfigVersion{}, err
+	}
+	if version[0] < minPfBBConfigMajorVersion || version[0] > maxPfBBConfigMajorVersion || version[1] < 1 || version[1] > 12 {
+		return pfBBConfigVersion{}, fmt.Errorf("implausible version %q found in %q", match[1], strings.TrimSpace(output))
+	}
+	return version, nil
+}
+
+// newPfBBConfigVersion parses version in major.minor[.patch] form
+func newPfBBConfigVersion(s string) (pfBBConfigVersion, error) {
+	parts := strings.Split(s, ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return pfBBConfigVersion{}, fmt.Errorf("invalid version %q", s)
+	}
+	var version pfBBConfigVersion
+	for i, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			return pfBBConfigVersion{}, fmt.Errorf("invalid version %q - %v", s, err)
+		}
+		version[i] = number
+	}
+	return version, nil
+}
+
+func (v pfBBConfigVersion) String() string {
+	if v[2] == 0 {
+		return fmt.Sprintf("%d.%02d", v[0], v[1])
+	}
+	return fmt.Sprintf("%d.%02d.%d", v[0], v[1], v[2])
+}
+
+func (v pfBBConfigVersion) less(other pfBBConfigVersion) bool {
+	for i := range v {
+		if v[i] != other[i] {
+			return v[i] < other[i]
+		}
+	}
+	return false
+}
+
+// pfBBConfigCapabilities are BBDevConfig features supported by pf_bb_config installed in the daemon
+type pfBBConfigCapabilities struct {
+	version string
+	// telemetrySocket tells whether pf_bb_config serves telemetry requests on its socket
+	telemetrySocket bool
+	// unsupported features by minimum pf_bb_config version which supports them
+	unsupported map[string]string
+}
+
+func newPfBBConfigCapabilities(versionOutput string) (*pfBBConfigCapabilities, error) {
+	version, err := parsePfBBConfigVersion(versionOutput)
+	if err != nil {
+		return nil, err
+	}
+	capabilities := &pfBBConfigCapabilities{
+		version:     version.String(),
+		unsupported: map[string]string{},
+	}
+	for _, f := range pfBBConfigFeatures {
+		minVersion, err := newPfBBConfigVersion(f.minVersion)
+		if err != nil {
+			return nil, err
+		}
+		if version.less(minVersion) {
+			capabilities.unsupported[f.feature] = f.minVersion
+		}
+	}
+	telemetrySocketVersion, err := newPfBBConfigVersion(pfBBConfigTelemetrySocketVersion)
+	if err != nil {
+		return nil, err
+	}
+	capabilities.telemetrySocket = !version.less(telemetrySocketVersion)
+	return capabilities, nil
+}
+
+// pfBBConfigFeatureUse is feature of BBDevConfig used by PF
+type pfBBConfigFeatureUse struct {
+	pciAddress string
+	feature    string
+}
+
+// verify returns error naming features used by PFs which installed pf_bb_config does not support, together with
+// version which introduced them; nothing is verified when version of pf_bb_config is unknown
+func (c *pfBBConfigCapabilities) verify(uses []pfBBConfigFeatureUse) error {
+	if c == nil {
+		return nil
+	}
+	var unsupported []string
+	for _, use := range uses {
+		if minVersion, ok := c.unsupported[use.feature]; ok {
+			unsupported = append(unsupported, fmt.Sprintf("%s of PF %s requires pf_bb_config %s or newer", use.feature, use.pciAddress, minVersion))
+		}
+	}
+	if len(unsupported) == 0 {
+		return nil
+	}
+	return fmt.Errorf("BBDevConfig is not supported by pf_bb_config %s installed on the node: %s", c.version, strings.Join(unsupported, "; "))
+}
+
+func fecPfBBConfigFeatureUses(pfs []fec.PhysicalFunctionConfigExt) []pfBBConfigFeatureUse {
+	var uses []pfBBConfigFeatureUse
+	for i := range pfs {
+		pf := &pfs[i]
+		var features []string
+		switch config := pf.BBDevConfig; {
+		case config.ACC100 != nil:
+			features = append(features, featureACC100)
+			if acc100FFTLut(pf) != nil {
+				features = append(features, featureACC100FFTLut)
+			}
+			if acc100InterruptMode(pf) != "" {
+				features = append(features, featureACC100InterruptMode)
+			}
+		case config.ACC200 != nil:
+			features = append(features, featureACC200)
+			if config.ACC200.QFFT.NumQueueGroups > 0 {
+				features = append(features, featureFFTQueues)
+			}
+			if config.ACC200.FFTLut.FftUrl != "" {
+				features = append(features, featureFFTWindows)
+			}
+		}
+		for _, feature := range features {
+			uses = append(uses, pfBBConfigFeatureUse{pciAddress: pf.PCIAddress, feature: feature})
+		}
+	}
+	return uses
+}
+
+func vrbPfBBConfigFeatureUses(pfs []vrbv1.PhysicalFunctionConfigExt) []pfBBConfigFeatureUse {
+	var uses []pfBBConfigFeatureUse
+	for _, pf := range pfs {
+		var features []string
+		switch config := pf.BBDevConfig; {
+		case config.VRB1 != nil:
+			features = append(features, featureVRB1)
+			if config.VRB1.QFFT.NumQueueGroups > 0 {
+				features = append(features, featureFFTQueues)
+			}
+			if config.VRB1.FFTLut.FftUrl != "" {
+				features = append(features, featureFFTWindows)
+			}
+		case config.VRB2 != nil:
+			features = append(features, featureVRB2)
+			if config.VRB2.QFFT.NumQueueGroups > 0 {
+				features = append(features, featureFFTQueues)
+			}
+			if config.VRB2.QMLD.NumQueueGroups > 0 {
+				features = append(features, featureMLDQueues)
+			}
+			if config.VRB2.FFTLut.FftUrl != "" {
+				features = append(features, featureFFTWindows)
+			}
+		}
+		for _, feature := range features {
+			uses = append(uses, pfBBConfigFeatureUse{pciAddress: pf.PCIAddress, feature: feature})
+		}
+	}
+	return uses
+}
+
+// DetectPfBBConfigCapabilities detects version of installed pf_bb_config, so that BBDevConfigs it does not support
+// are rejected before the node is drained and its telemetry is requested with mechanism it supports. If the version
+// cannot be detected, BBDevConfigs are not verified.
+func (r *NodeConfigReconciler) DetectPfBBConfigCapabilities(ctx context.Context) {
+	path := pfConfigAppFilepath
+	if path == "" {
+		path = defaultPfBBConfigAppFilepath
+	}
+	output, err := runExecCmd(ctx, []string{path, "--version"}, r.log)
+	if err != nil {
+		r.log.WithError(err).Warn("failed to detect version of pf_bb_config - BBDevConfigs are not verified against it")
+		return
+	}
+	capabilities, err := newPfBBConfigCapabilities(output)
+	if err != nil {
+		r.log.WithError(err).Warn("failed to parse version of pf_bb_config - BBDevConfigs are not verified against it")
+		return
+	}
+	r.log.WithField("version", capabilities.version).Info("pf_bb_config version detected")
+	r.pfBBConfigCapabilities = capabilities
+	setTelemetryCapabilities(capabilities)
+}
@@ -0,0 +1,155 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+
+	fec "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("pf_bb_config capabilities", func() {
+	const pf = "0000:f7:00.0"
+
+	allFeatures := []pfBBConfigFeatureUse{
+		{pf, featureACC100}, {pf, featureACC100FFTLut}, {pf, featureACC100InterruptMode}, {pf, featureACC200}, {pf, featureVRB1},
+		{pf, featureFFTQueues}, {pf, featureFFTWindows}, {pf, featureVRB2}, {pf, featureMLDQueues},
+	}
+
+	table.DescribeTable("capabilities of recorded versions",
+		func(output, version string, unsupported map[string]string) {
+			capabilities, err := newPfBBConfigCapabilities(output)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(capabilities.version).To(Equal(version))
+			Expect(capabilities.unsupported).To(Equal(unsupported))
+		},
+		table.Entry("21.3", "Version 21.3\n", "21.03", map[string]string{
+			featureACC200: "22.11", featureVRB1: "22.11", featureFFTQueues: "22.11", featureFFTWindows: "23.03", featureVRB2: "23.11",
+			featureMLDQueues: "24.03", featureACC100InterruptMode: "24.03", featureACC100FFTLut: "24.07",
+		}),
+		table.Entry("22.11", "pf_bb_config version v22.11-0-g3c2a1b0\n", "22.11", map[string]string{
+			featureFFTWindows: "23.03", featureVRB2: "23.11", featureMLDQueues: "24.03", featureACC100InterruptMode: "24.03",
+			featureACC100FFTLut: "24.07",
+		}),
+		table.Entry("23.11 patch release", "Version: 23.11.1\nCopyright(c) 2023 Intel Corporation\n", "23.11.1", map[string]string{
+			featureMLDQueues: "24.03", featureACC100InterruptMode: "24.03", featureACC100FFTLut: "24.07",
+		}),
+		table.Entry("24.03", "v24.03", "24.03", map[string]string{featureACC100FFTLut: "24.07"}),
+		table.Entry("24.11", "Version 24.11-0", "24.11", map[string]string{}),
+	)
+
+	It("tells whether telemetry socket is supported", func() {
+		for output, telemetrySocket := range map[string]bool{"Version 21.11": false, "Version 22.03": true, "Version 24.11": true} {
+			capabilities, err := newPfBBConfigCapabilities(output)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(capabilities.telemetrySocket).To(Equal(telemetrySocket), output)
+		}
+	})
+
+	It("fails for output without version", func() {
+		_, err := newPfBBConfigCapabilities("pf_bb_config: unrecognized option '--version'\n")
+
+		Expect(err).To(MatchError(ContainSubstring("no version found")))
+	})
+
+	It("takes only version following version prefix", func() {
+		capabilities, err := newPfBBConfigCapabilities("Built with DPDK 22.11.1 on kernel 5.14.0\npf_bb_config Version 24.03-0-g1a2b3c\n")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(capabilities.version).To(Equal("24.03"))
+
+		_, err = newPfBBConfigCapabilities("Built with DPDK 22.11.1\n")
+		Expect(err).To(MatchError(ContainSubstring("no version found")))
+	})
+
+	It("treats implausible version as unknown", func() {
+		for _, output := range []string{"Version 2.6.32", "Version 2024.03", "v24.13"} {
+			_, err := newPfBBConfigCapabilities(output)
+			Expect(err).To(MatchError(ContainSubstring("implausible version")), output)
+		}
+	})
+
+	It("rejects features which installed pf_bb_config does not support", func() {
+		capabilities, err := newPfBBConfigCapabilities("Version 23.11")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(capabilities.verify(allFeatures[3:8])).To(Succeed())
+		Expect(capabilities.verify(allFeatures)).To(MatchError("BBDevConfig is not supported by pf_bb_config 23.11 installed on the node: " +
+			"ACC100 SRS FFT LUT (fftLut) of PF 0000:f7:00.0 requires pf_bb_config 24.07 or newer; " +
+			"ACC100 INTERRUPT section (interruptMode) of PF 0000:f7:00.0 requires pf_bb_config 24.03 or newer; " +
+			"MLD queues (qmld) of PF 0000:f7:00.0 requires pf_bb_config 24.03 or newer"))
+	})
+
+	It("does not verify anything when version is unknown", func() {
+		var capabilities *pfBBConfigCapabilities
+
+		Expect(capabilities.verify(allFeatures)).To(Succeed())
+	})
+
+	It("finds features used by BBDevConfigs", func() {
+		Expect(fecPfBBConfigFeatureUses([]fec.PhysicalFunctionConfigExt{
+			{PCIAddress: "0000:f7:00.0", BBDevConfig: fec.BBDevConfig{ACC100: &fec.ACC100BBDevConfig{
+				InterruptMode: fec.InterruptModeMSI, FFTLutFrom: &fec.FFTLutSource{}}}},
+			{PCIAddress: "0000:f8:00.0", BBDevConfig: fec.BBDevConfig{ACC200: &fec.ACC200BBDevConfig{
+				QFFT: fec.QueueGroupConfig{NumQueueGroups: 4}}}},
+			{PCIAddress: "0000:f9:00.0", BBDevConfig: fec.BBDevConfig{N3000: &fec.N3000BBDevConfig{}}},
+		})).To(Equal([]pfBBConfigFeatureUse{
+			{"0000:f7:00.0", featureACC100}, {"0000:f7:00.0", featureACC100FFTLut}, {"0000:f7:00.0", featureACC100InterruptMode},
+			{"0000:f8:00.0", featureACC200}, {"0000:f8:00.0", featureFFTQueues},
+		}))
+
+		Expect(vrbPfBBConfigFeatureUses([]vrbv1.PhysicalFunctionConfigExt{
+			{PCIAddress: "0000:f7:00.0", BBDevConfig: vrbv1.BBDevConfig{VRB1: &vrbv1.VRB1BBDevConfig{
+				FFTLut: vrbv1.FFTLutParam{FftUrl: "http://example.com/fft.tar.gz"}}}},
+			{PCIAddress: "0000:f8:00.0", BBDevConfig: vrbv1.BBDevConfig{VRB2: &vrbv1.VRB2BBDevConfig{
+				QMLD: vrbv1.QueueGroupConfig{NumQueueGroups: 4}}}},
+		})).To(Equal([]pfBBConfigFeatureUse{
+			{"0000:f7:00.0", featureVRB1}, {"0000:f7:00.0", featureFFTWindows},
+			{"0000:f8:00.0", featureVRB2}, {"0000:f8:00.0", featureMLDQueues},
+		}))
+	})
+
+	Context("DetectPfBBConfigCapabilities", func() {
+		var originalRunExecCmd = runExecCmd
+
+		AfterEach(func() {
+			runExecCmd = originalRunExecCmd
+			setTelemetryCapabilities(nil)
+		})
+
+		It("detects version with pf_bb_config --version", func() {
+			var executed []string
+			runExecCmd = func(_ context.Context, args []string, _ *logrus.Logger) (string, error) {
+				executed = args
+				return "Version 23.11\n", nil
+			}
+			r := &NodeConfigReconciler{log: utils.NewLogger()}
+
+			r.DetectPfBBConfigCapabilities(context.TODO())
+
+			Expect(executed).To(Equal([]string{defaultPfBBConfigAppFilepath, "--version"}))
+			Expect(r.pfBBConfigCapabilities.version).To(Equal("23.11"))
+			Expect(telemetryCapabilities).To(BeIdenticalTo(r.pfBBConfigCapabilities), "telemetry is requested by the version")
+		})
+
+		It("leaves capabilities unknown when pf_bb_config fails", func() {
+			runExecCmd = func(context.Context, []string, *logrus.Logger) (string, error) {
+				return "", errors.New("exit status 1")
+			}
+			r := &NodeConfigReconciler{log: utils.NewLogger()}
+
+			r.DetectPfBBConfigCapabilities(context.TODO())
+
+			Expect(r.pfBBConfigCapabilities).To(BeNil())
+		})
+	})
+})
@@ -99,6 +99,10 @@ func ShowHelp() {
 	fmt.Println("\treg_dump")
 	fmt.Println("\tmm_read <reg_addr>")
 	fmt.Println("\tdevice_data")
+	fmt.Println("Usage: ./sriov_fec_daemon -bbdev-config-diff <pci_address>")
+	fmt.Println("\tprints diff between the last two pf_bb_config cfg files of the PF")
+	fmt.Println("Usage: ./sriov_fec_daemon -standalone [-local-spec <file>]")
+	fmt.Println("\tconfigures the node once outside of a pod and prints node configs with resulting status")
 }
 
 func sendCmd(pciAddr string, cmd []byte, log *logrus.Logger) error {
@@ -0,0 +1,144 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+var (
+	// procPath is proc filesystem used to discover host processes, see InitHostProcPath
+	procPath                          = "/proc"
+	signalProcess                     = syscall.Kill
+	pfBBConfigTerminationTimeout      = 10 * time.Second
+	pfBBConfigTerminationPollInterval = 200 * time.Millisecond
+)
+
+// findPfBBConfigProcesses scans cmdlines of running processes and returns PIDs of pf_bb_config instances
+// started for given PCI address (i.e. with "-p <pciAddress>" argument)
+func findPfBBConfigProcesses(pciAddress string) ([]int, error) {
+	entries, err := os.ReadDir(procPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processes - %v", err)
+	}
+
+	var pids []int
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		// process may exit in the meantime, ignore it
+		cmdline, err := os.ReadFile(filepath.Join(procPath, entry.Name(), "cmdline"))
+		if err != nil {
+			continue
+		}
+		if !isPfBBConfigCmdline(cmdline, pciAddress) {
+			continue
+		}
+		local, err := localPID(pid)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to find pf_bb_config(%d) in PID namespace of the daemon - %v", pid, err)
+		}
+		pids = append(pids, local)
+	}
+	return pids, nil
+}
+
+func isPfBBConfigCmdline(cmdline []byte, pciAddress string) bool {
+	args := bytes.Split(bytes.TrimRight(cmdline, "\x00"), []byte{0})
+	if len(args) == 0 || filepath.Base(string(args[0])) != "pf_bb_config" {
+		return false
+	}
+	for i := 1; i < len(args)-1; i++ {
+		if string(args[i]) == "-p" && string(args[i+1]) == pciAddress {
+			return true
+		}
+	}
+	return false
+}
+
+// pfBBConfigArg returns value of flag pf_bb_config was started with; empty when the flag is not present
+func pfBBConfigArg(cmdline []byte, flag string) string {
+	args := bytes.Split(bytes.TrimRight(cmdline, "\x00"), []byte{0})
+	for i := 1; i < len(args)-1; i++ {
+		if string(args[i]) == flag {
+			return string(args[i+1])
+		}
+	}
+	return ""
+}
+
+// terminatePfBBConfig stops all pf_bb_config instances serving given PCI address. Processes are asked to exit with
+// SIGTERM first, the ones still running after pfBBConfigTerminationTimeout are killed with SIGKILL.
+func terminatePfBBConfig(pciAddress string, log *logrus.Logger) error {
+	_, err := stopPfBBConfig(pciAddress, log)
+	return err
+}
+
+// stopPfBBConfig is terminatePfBBConfig which returns PIDs of instances that had to be killed with SIGKILL
+func stopPfBBConfig(pciAddress string, log *logrus.Logger) ([]int, error) {
+	pids, err := findPfBBConfigProcesses(pciAddress)
+	if err != nil {
+		return nil, err
+	}
+	if len(pids) == 0 {
+		return nil, nil
+	}
+
+	log.WithField("pci", pciAddress).WithField("pids", pids).Info("terminating running pf_bb_config")
+	for _, pid := range pids {
+		if err := signalProcess(pid, syscall.SIGTERM); err != nil && err != syscall.ESRCH {
+			return nil, fmt.Errorf("failed to send SIGTERM to pf_bb_config(%d) - %v", pid, err)
+		}
+	}
+
+	var running []int
+	err = wait.PollImmediate(pfBBConfigTerminationPollInterval, pfBBConfigTerminationTimeout, func() (bool, error) {
+		running, err = stillRunning(pciAddress, pids)
+		return len(running) == 0, err
+	})
+	if err == nil {
+		return nil, nil
+	}
+	if err != wait.ErrWaitTimeout {
+		return nil, err
+	}
+
+	log.WithField("pci", pciAddress).WithField("pids", running).Info("pf_bb_config did not exit on SIGTERM - killing")
+	for _, pid := range running {
+		if err := signalProcess(pid, syscall.SIGKILL); err != nil && err != syscall.ESRCH {
+			return nil, fmt.Errorf("failed to send SIGKILL to pf_bb_config(%d) - %v", pid, err)
+		}
+	}
+	return running, nil
+}
+
+func stillRunning(pciAddress string, pids []int) ([]int, error) {
+	current, err := findPfBBConfigProcesses(pciAddress)
+	if err != nil {
+		return nil, err
+	}
+	var running []int
+	for _, pid := range pids {
+		for _, c := range current {
+			if pid == c {
+				running = append(running, pid)
+			}
+		}
+	}
+	return running, nil
+}
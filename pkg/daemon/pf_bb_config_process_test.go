@@ -0,0 +1,143 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+)
+
+var _ = Describe("pf_bb_config restart", func() {
+	const (
+		pciAddress      = "0000:14:00.0"
+		otherPciAddress = "0000:15:00.0"
+	)
+
+	var (
+		log                      = logrus.New()
+		originalProcPath         = procPath
+		originalOwnProcPath      = ownProcPath
+		originalSignal           = signalProcess
+		originalTimeout          = pfBBConfigTerminationTimeout
+		originalPollInterval     = pfBBConfigTerminationPollInterval
+		originalRunPfBBConfigCmd = runPfBBConfigCmd
+		originalAppFilepath      = pfConfigAppFilepath
+
+		// ignoringSigterm holds PIDs of fake processes which exit only on SIGKILL
+		ignoringSigterm map[int]bool
+		signals         map[int][]syscall.Signal
+	)
+
+	// startFakeProcess creates /proc/<pid>/cmdline entry
+	startFakeProcess := func(pid int, args ...string) {
+		dir := filepath.Join(procPath, strconv.Itoa(pid))
+		Expect(os.MkdirAll(dir, 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "cmdline"), []byte(strings.Join(args, "\x00")+"\x00"), 0644)).To(Succeed())
+	}
+
+	isRunning := func(pid int) bool {
+		_, err := os.Stat(filepath.Join(procPath, strconv.Itoa(pid)))
+		return err == nil
+	}
+
+	BeforeEach(func() {
+		var err error
+		procPath, err = os.MkdirTemp(testTmpFolder, "proc")
+		Expect(err).ToNot(HaveOccurred())
+		ownProcPath = procPath
+		pfBBConfigTerminationTimeout = 100 * time.Millisecond
+		pfBBConfigTerminationPollInterval = 10 * time.Millisecond
+		pfConfigAppFilepath = "/sriov_workdir/pf_bb_config"
+
+		ignoringSigterm = map[int]bool{}
+		signals = map[int][]syscall.Signal{}
+		signalProcess = func(pid int, sig syscall.Signal) error {
+			if !isRunning(pid) {
+				return syscall.ESRCH
+			}
+			signals[pid] = append(signals[pid], sig)
+			if sig == syscall.SIGKILL || !ignoringSigterm[pid] {
+				return os.RemoveAll(filepath.Join(procPath, strconv.Itoa(pid)))
+			}
+			return nil
+		}
+
+		startFakeProcess(1, "/sbin/init")
+		startFakeProcess(20, "/sriov_workdir/pf_bb_config", "ACC100", "-c", "/sriov_workdir/"+otherPciAddress+".ini", "-p", otherPciAddress)
+		Expect(os.MkdirAll(filepath.Join(procPath, "self"), 0755)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(procPath)).To(Succeed())
+		procPath = originalProcPath
+		ownProcPath = originalOwnProcPath
+		signalProcess = originalSignal
+		pfBBConfigTerminationTimeout = originalTimeout
+		pfBBConfigTerminationPollInterval = originalPollInterval
+		runPfBBConfigCmd = originalRunPfBBConfigCmd
+		pfConfigAppFilepath = originalAppFilepath
+	})
+
+	It("should find only pf_bb_config instances serving given PCI address", func() {
+		startFakeProcess(10, "/sriov_workdir/pf_bb_config", "ACC100", "-c", "/sriov_workdir/"+pciAddress+".ini", "-p", pciAddress)
+		startFakeProcess(11, "/usr/bin/grep", "-p", pciAddress)
+
+		Expect(findPfBBConfigProcesses(pciAddress)).To(ConsistOf(10))
+	})
+
+	It("should stop previous instance with SIGTERM before starting new one", func() {
+		startFakeProcess(10, "/sriov_workdir/pf_bb_config", "ACC100", "-c", "/sriov_workdir/"+pciAddress+".ini", "-p", pciAddress)
+
+		var runningOnStart []int
+		runPfBBConfigCmd = func(_ context.Context, args []string, _ *logrus.Logger) (string, error) {
+			var err error
+			runningOnStart, err = findPfBBConfigProcesses(pciAddress)
+			return "", err
+		}
+
+		p := &pfBBConfigController{log: log}
+		Expect(p.runPFConfig(context.TODO(), "ACC100", "/sriov_workdir/"+pciAddress+".ini", pciAddress, nil, "")).To(Succeed())
+
+		Expect(runningOnStart).To(BeEmpty())
+		Expect(signals).To(Equal(map[int][]syscall.Signal{10: {syscall.SIGTERM}}))
+		Expect(isRunning(20)).To(BeTrue())
+	})
+
+	It("should kill previous instance which ignores SIGTERM", func() {
+		startFakeProcess(10, "/sriov_workdir/pf_bb_config", "ACC100", "-c", "/sriov_workdir/"+pciAddress+".ini", "-p", pciAddress)
+		ignoringSigterm[10] = true
+
+		runPfBBConfigCmd = func(_ context.Context, args []string, _ *logrus.Logger) (string, error) { return "", nil }
+
+		p := &pfBBConfigController{log: log}
+		Expect(p.runPFConfig(context.TODO(), "ACC100", "/sriov_workdir/"+pciAddress+".ini", pciAddress, nil, "")).To(Succeed())
+
+		Expect(signals).To(Equal(map[int][]syscall.Signal{10: {syscall.SIGTERM, syscall.SIGKILL}}))
+		Expect(isRunning(10)).To(BeFalse())
+		Expect(isRunning(20)).To(BeTrue())
+	})
+
+	It("should report instances which had to be killed", func() {
+		startFakeProcess(10, "/sriov_workdir/pf_bb_config", "ACC100", "-c", "/sriov_workdir/"+pciAddress+".ini", "-p", pciAddress)
+		startFakeProcess(11, "/sriov_workdir/pf_bb_config", "ACC100", "-c", "/sriov_workdir/"+pciAddress+".ini", "-p", pciAddress)
+		ignoringSigterm[11] = true
+
+		Expect(stopPfBBConfig(pciAddress, log)).To(Equal([]int{11}))
+		Expect(signals).To(Equal(map[int][]syscall.Signal{10: {syscall.SIGTERM}, 11: {syscall.SIGTERM, syscall.SIGKILL}}))
+	})
+
+	It("should not send any signal when pf_bb_config is not running", func() {
+		Expect(terminatePfBBConfig(pciAddress, log)).To(Succeed())
+		Expect(signals).To(BeEmpty())
+	})
+})
@@ -0,0 +1,129 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/conditions"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+const (
+	slowPfBBConfigStartup = "SlowPfBBConfigStartup"
+	// slowStartupMessagePrefix starts messages of PFHealthy conditions set due to pf-bb-config startup, so that only
+	// these are turned healthy again by faster startup
+	slowStartupMessagePrefix = "pf-bb-config startup of "
+)
+
+var pfBBConfigStartupDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "sriovfec_pf_bb_config_startup_duration_seconds",
+	Help:    `duration of pf-bb-config configuring PF; healthy device configures within seconds. 'device_id' - represents device ID of PF`,
+	Buckets: prometheus.ExponentialBuckets(0.5, 2, 10),
+}, []string{deviceIDLabel})
+
+func init() {
+	metrics.Registry.MustRegister(pfBBConfigStartupDuration)
+}
+
+// pfBBConfigStartups holds duration of the last successful pf-bb-config startup by PCI address of PF
+var pfBBConfigStartups = &startupDurations{durations: map[string]time.Duration{}}
+
+type startupDurations struct {
+	mu        sync.Mutex
+	durations map[string]time.Duration
+}
+
+func (s *startupDurations) record(pciAddress string, duration time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.durations[pciAddress] = duration
+}
+
+func (s *startupDurations) get(pciAddress string) (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	duration, found := s.durations[pciAddress]
+	return duration, found
+}
+
+// statusDurations returns durations of PFs to be exposed in status; PFs not started since the daemon started keep the
+// duration reported previously, PFs which are not configured anymore are dropped
+func (s *startupDurations) statusDurations(previous map[string]metav1.Duration, pciAddresses []string) map[string]metav1.Duration {
+	var durations map[string]metav1.Duration
+	for _, pciAddress := range pciAddresses {
+		duration, found := previous[pciAddress]
+		if recorded, ok := s.get(pciAddress); ok {
+			duration, found = metav1.Duration{Duration: recorded}, true
+		}
+		if !found {
+			continue
+		}
+		if durations == nil {
+			durations = map[string]metav1.Duration{}
+		}
+		durations[pciAddress] = duration
+	}
+	return durations
+}
+
+// observePfBBConfigStartup exposes duration of the last pf-bb-config startup of the PF in the metric
+func observePfBBConfigStartup(deviceID, pciAddress string) {
+	if duration, found := pfBBConfigStartups.get(pciAddress); found {
+		pfBBConfigStartupDuration.WithLabelValues(deviceID).Observe(duration.Seconds())
+	}
+}
+
+// reportSlowPfBBConfigStartups marks health of PFs which pf-bb-config took longer than threshold of their device from
+// the discovery config to configure as degraded, and emits warning event; configuration is not failed. PFs degraded
+// this way become healthy again once pf-bb-config starts within the threshold. deviceIDs holds device ID by PCI address.
+func (r *NodeConfigReconciler) reportSlowPfBBConfigStartups(nc runtime.Object, ncConditions *[]metav1.Condition, generation int64,
+	durations map[string]metav1.Duration, deviceIDs map[string]string, discoveryConfig utils.AcceleratorDiscoveryConfig) {
+	for pciAddress, duration := range durations {
+		deviceID := deviceIDs[pciAddress]
+		threshold, ok, err := discoveryConfig.PfBBConfigStartupThreshold(deviceID)
+		if err != nil {
+			r.log.WithError(err).WithField("pci", pciAddress).Warn("pf-bb-config startup duration is not checked")
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		previous := meta.FindStatusCondition(*ncConditions, conditions.PFHealthyType(pciAddress))
+		var condition metav1.Condition
+		if duration.Duration > threshold {
+			condition = conditions.PFHealthy(pciAddress, metav1.ConditionFalse, conditions.ReasonDegraded,
+				fmt.Sprintf("%s%s took %s, above %s threshold of device %s", slowStartupMessagePrefix, pciAddress,
+					duration.Round(time.Millisecond), threshold, deviceID), generation)
+		} else if previous != nil && previous.Status == metav1.ConditionFalse && strings.HasPrefix(previous.Message, slowStartupMessagePrefix) {
+			condition = conditions.PFHealthy(pciAddress, metav1.ConditionTrue, conditions.ReasonHealthy,
+				fmt.Sprintf("%s%s took %s, within %s threshold", slowStartupMessagePrefix, pciAddress,
+					duration.Round(time.Millisecond), threshold), generation)
+		} else {
+			continue
+		}
+
+		if !conditions.SetIfChanged(ncConditions, condition) {
+			continue
+		}
+		if condition.Status == metav1.ConditionFalse && (previous == nil || previous.Message != condition.Message) {
+			r.log.WithField("pci", pciAddress).WithField("duration", duration.Duration).WithField("threshold", threshold).
+				Warn("slow pf-bb-config startup - PF health is degraded")
+			if r.recorder != nil {
+				r.recorder.Event(nc, corev1.EventTypeWarning, slowPfBBConfigStartup, condition.Message)
+			}
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	fec "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/conditions"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("pf-bb-config startup duration", func() {
+	const pciAddress = "0000:f7:00.0"
+	var (
+		originalDaemonClock      = daemonClock
+		originalRunPfBBConfigCmd = runPfBBConfigCmd
+		clk                      *fakeClock
+	)
+
+	BeforeEach(func() {
+		clk = newFakeClock(time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC))
+		daemonClock = clk
+	})
+
+	AfterEach(func() {
+		daemonClock = originalDaemonClock
+		runPfBBConfigCmd = originalRunPfBBConfigCmd
+		pfBBConfigStartups = &startupDurations{durations: map[string]time.Duration{}}
+	})
+
+	It("is recorded for successful startup only", func() {
+		startup := 65 * time.Second
+		var runErr error
+		runPfBBConfigCmd = func(_ context.Context, _ []string, _ *logrus.Logger) (string, error) {
+			clk.Step(startup)
+			return "", runErr
+		}
+		p := &pfBBConfigController{log: utils.NewLogger()}
+
+		Expect(p.runPFConfig(context.TODO(), "ACC100", "/sriov_workdir/"+pciAddress+".ini", pciAddress, nil, "")).To(Succeed())
+		recorded, found := pfBBConfigStartups.get(pciAddress)
+		Expect(found).To(BeTrue())
+		Expect(recorded).To(Equal(startup))
+
+		startup, runErr = 2*time.Second, fmt.Errorf("pf_bb_config failed")
+		Expect(p.runPFConfig(context.TODO(), "ACC100", "/sriov_workdir/"+pciAddress+".ini", pciAddress, nil, "")).ToNot(Succeed())
+		recorded, _ = pfBBConfigStartups.get(pciAddress)
+		Expect(recorded).To(Equal(65 * time.Second))
+	})
+
+	It("is exposed in status for configured PFs only", func() {
+		pfBBConfigStartups.record(pciAddress, 5*time.Second)
+		previous := map[string]metav1.Duration{
+			pciAddress:     {Duration: time.Minute},
+			"0000:f8:00.0": {Duration: 4 * time.Second},
+			"0000:f9:00.0": {Duration: 3 * time.Second},
+		}
+
+		Expect(pfBBConfigStartups.statusDurations(previous, []string{pciAddress, "0000:f8:00.0", "0000:1b:00.0"})).To(Equal(map[string]metav1.Duration{
+			pciAddress:     {Duration: 5 * time.Second},
+			"0000:f8:00.0": {Duration: 4 * time.Second},
+		}))
+		Expect(pfBBConfigStartups.statusDurations(nil, []string{"0000:1b:00.0"})).To(BeNil())
+	})
+
+	Context("exceeding threshold", func() {
+		var (
+			reconciler      *NodeConfigReconciler
+			recorder        *record.FakeRecorder
+			nc              *fec.SriovFecNodeConfig
+			discoveryConfig = utils.AcceleratorDiscoveryConfig{PfBBConfigStartupThresholds: map[string]string{"0d5c": "30s"}}
+			deviceIDs       = map[string]string{pciAddress: "0d5c", "0000:1b:00.0": "0d8f"}
+		)
+
+		report := func(durations map[string]time.Duration) {
+			statusDurations := map[string]metav1.Duration{}
+			for pciAddress, duration := range durations {
+				statusDurations[pciAddress] = metav1.Duration{Duration: duration}
+			}
+			reconciler.reportSlowPfBBConfigStartups(nc, &nc.Status.Conditions, nc.GetGeneration(), statusDurations, deviceIDs, discoveryConfig)
+		}
+		healthCondition := func() *metav1.Condition {
+			return meta.FindStatusCondition(nc.Status.Conditions, conditions.PFHealthyType(pciAddress))
+		}
+
+		BeforeEach(func() {
+			recorder = record.NewFakeRecorder(10)
+			reconciler = &NodeConfigReconciler{log: utils.NewLogger(), recorder: recorder}
+			nc = &fec.SriovFecNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: "worker", Generation: 3}}
+		})
+
+		It("degrades health of PF and emits warning event once", func() {
+			report(map[string]time.Duration{pciAddress: 65 * time.Second, "0000:1b:00.0": time.Hour})
+
+			condition := healthCondition()
+			Expect(condition).ToNot(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(string(conditions.ReasonDegraded)))
+			Expect(condition.Message).To(Equal("pf-bb-config startup of 0000:f7:00.0 took 1m5s, above 30s threshold of device 0d5c"))
+			Expect(recorder.Events).To(Receive(Equal("Warning SlowPfBBConfigStartup " + condition.Message)))
+			// device without threshold is not checked
+			Expect(meta.FindStatusCondition(nc.Status.Conditions, conditions.PFHealthyType("0000:1b:00.0"))).To(BeNil())
+
+			nc.Generation++
+			report(map[string]time.Duration{pciAddress: 65 * time.Second})
+			Expect(healthCondition().ObservedGeneration).To(Equal(int64(4)))
+			Expect(recorder.Events).ToNot(Receive())
+		})
+
+		It("restores health of PF once startup is within threshold", func() {
+			report(map[string]time.Duration{pciAddress: time.Minute})
+			Expect(recorder.Events).To(Receive())
+
+			report(map[string]time.Duration{pciAddress: 5 * time.Second})
+			condition := healthCondition()
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Reason).To(Equal(string(conditions.ReasonHealthy)))
+			Expect(condition.Message).To(Equal("pf-bb-config startup of 0000:f7:00.0 took 5s, within 30s threshold"))
+			Expect(recorder.Events).ToNot(Receive())
+		})
+
+		It("does not touch health of PF degraded for other reasons", func() {
+			report(map[string]time.Duration{pciAddress: 5 * time.Second})
+			Expect(healthCondition()).To(BeNil(), "healthy condition is not written for PF which was never degraded")
+
+			aerCondition := conditions.PFHealthy(pciAddress, metav1.ConditionFalse, conditions.ReasonDegraded, "Uncorrectable PCIe errors increased", 3)
+			meta.SetStatusCondition(&nc.Status.Conditions, aerCondition)
+			report(map[string]time.Duration{pciAddress: 5 * time.Second})
+			Expect(healthCondition().Message).To(Equal(aerCondition.Message))
+		})
+
+		It("is not checked with invalid threshold", func() {
+			discoveryConfig := utils.AcceleratorDiscoveryConfig{PfBBConfigStartupThresholds: map[string]string{"0d5c": "slow"}}
+			reconciler.reportSlowPfBBConfigStartups(nc, &nc.Status.Conditions, 1,
+				map[string]metav1.Duration{pciAddress: {Duration: time.Hour}}, deviceIDs, discoveryConfig)
+			Expect(nc.Status.Conditions).To(BeEmpty())
+		})
+	})
+})
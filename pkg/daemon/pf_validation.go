@@ -0,0 +1,68 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	fec "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var physicalFunctionsPath = field.NewPath("spec", "physicalFunctions")
+
+// validateFecPhysicalFunctions validates requested PFs the same way as the webhook does, but also against accelerators
+// reported by the inventory and VF drivers allowed for them on the running kernel, so that invalid configuration fails
+// before the node is drained
+func validateFecPhysicalFunctions(pfs []fec.PhysicalFunctionConfigExt, inventory *fec.NodeInventory, vfDrivers map[string][]utils.VFDriverSupport) error {
+	kernel, err := vfDriversKernel(vfDrivers)
+	if err != nil {
+		return err
+	}
+	var errs field.ErrorList
+	for i := range pfs {
+		var device *fec.SriovAccelerator
+		for j := range inventory.SriovAccelerators {
+			if inventory.SriovAccelerators[j].PCIAddress == pfs[i].PCIAddress {
+				device = &inventory.SriovAccelerators[j]
+			}
+		}
+		errs = append(errs, fec.ValidateNodePhysicalFunction(physicalFunctionsPath.Index(i), pfs[i], device)...)
+		if device != nil {
+			errs = append(errs, fec.ValidateVFDriver(physicalFunctionsPath.Index(i), pfs[i].VFDriver, device.DeviceID, kernel, vfDrivers)...)
+		}
+	}
+	return errs.ToAggregate()
+}
+
+func validateVrbPhysicalFunctions(pfs []vrbv1.PhysicalFunctionConfigExt, inventory *vrbv1.NodeInventory, vfDrivers map[string][]utils.VFDriverSupport) error {
+	kernel, err := vfDriversKernel(vfDrivers)
+	if err != nil {
+		return err
+	}
+	var errs field.ErrorList
+	for i := range pfs {
+		var device *vrbv1.SriovAccelerator
+		for j := range inventory.SriovAccelerators {
+			if inventory.SriovAccelerators[j].PCIAddress == pfs[i].PCIAddress {
+				device = &inventory.SriovAccelerators[j]
+			}
+		}
+		errs = append(errs, vrbv1.ValidateNodePhysicalFunction(physicalFunctionsPath.Index(i), pfs[i], device)...)
+		if device != nil {
+			errs = append(errs, vrbv1.ValidateVFDriver(physicalFunctionsPath.Index(i), pfs[i].VFDriver, device.DeviceID, kernel, vfDrivers)...)
+		}
+	}
+	return errs.ToAggregate()
+}
+
+// vfDriversKernel returns release of the running kernel VF drivers are validated against; kernel is not read when the
+// discovery config does not restrict VF drivers
+func vfDriversKernel(vfDrivers map[string][]utils.VFDriverSupport) (string, error) {
+	if len(vfDrivers) == 0 {
+		return "", nil
+	}
+	return readKernelRelease()
+}
@@ -0,0 +1,94 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	fec "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("validateFecPhysicalFunctions", func() {
+	inventory := &fec.NodeInventory{SriovAccelerators: []fec.SriovAccelerator{
+		{PCIAddress: "0000:af:00.0", MaxVFs: 16},
+		{PCIAddress: "0000:b0:00.0", MaxVFs: 2},
+	}}
+
+	It("should accept PFs without bbDevConfig within device limits", func() {
+		pfs := []fec.PhysicalFunctionConfigExt{
+			{PCIAddress: "0000:af:00.0", PFDriver: "vfio-pci", VFAmount: 16},
+			{PCIAddress: "0000:b0:00.0", PFDriver: "vfio-pci", VFAmount: 2},
+		}
+		Expect(validateFecPhysicalFunctions(pfs, inventory, nil)).To(Succeed())
+	})
+
+	It("should report fields of all invalid PFs", func() {
+		pfs := []fec.PhysicalFunctionConfigExt{
+			{PCIAddress: "0000:af:00.0", PFDriver: "vfio-pci", VFAmount: 2,
+				BBDevConfig: fec.BBDevConfig{ACC100: &fec.ACC100BBDevConfig{NumVfBundles: 2}}},
+			{PCIAddress: "0000:b0:00.0", PFDriver: "vfio-pci", VFAmount: 4},
+		}
+		err := validateFecPhysicalFunctions(pfs, inventory, nil)
+		Expect(err).To(MatchError(ContainSubstring("spec.physicalFunctions[0].bbDevConfig.acc100.uplink4G: Required value")))
+		Expect(err).To(MatchError(ContainSubstring("spec.physicalFunctions[1].vfAmount: Invalid value: 4: value should not be greater than 2 supported by 0000:b0:00.0")))
+	})
+
+	Context("when discovery config restricts VF drivers", func() {
+		var originalKernelReleaseFilePath = kernelReleaseFilePath
+
+		vfDrivers := map[string][]utils.VFDriverSupport{
+			"0d8f": {{Driver: utils.VFIO_PCI}, {Driver: utils.IGB_UIO, MaxKernelVersion: "5.15"}},
+		}
+		inventory := &fec.NodeInventory{SriovAccelerators: []fec.SriovAccelerator{
+			{PCIAddress: "0000:af:00.0", DeviceID: "0d8f", MaxVFs: 8},
+			{PCIAddress: "0000:b0:00.0", DeviceID: "0d5c", MaxVFs: 16},
+		}}
+
+		BeforeEach(func() {
+			tmpDir, err := os.MkdirTemp(testTmpFolder, "pf_validation")
+			Expect(err).ToNot(HaveOccurred())
+			kernelReleaseFilePath = filepath.Join(tmpDir, "osrelease")
+			Expect(os.WriteFile(kernelReleaseFilePath, []byte("5.15.0-1023-realtime\n"), 0644)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			kernelReleaseFilePath = originalKernelReleaseFilePath
+		})
+
+		It("should report VF driver not supported by the device on the running kernel", func() {
+			pfs := []fec.PhysicalFunctionConfigExt{
+				{PCIAddress: "0000:af:00.0", PFDriver: "pci-pf-stub", VFDriver: utils.IGB_UIO, VFAmount: 8},
+				{PCIAddress: "0000:b0:00.0", PFDriver: "pci-pf-stub", VFDriver: utils.IGB_UIO, VFAmount: 16},
+			}
+			Expect(validateFecPhysicalFunctions(pfs, inventory, vfDrivers)).To(MatchError(
+				`spec.physicalFunctions[0].vfDriver: Invalid value: "igb_uio": vfDriver igb_uio not supported for device 0d8f on kernel >= 5.15`))
+
+			pfs[0].VFDriver = utils.VFIO_PCI
+			Expect(validateFecPhysicalFunctions(pfs, inventory, vfDrivers)).To(Succeed())
+		})
+
+		It("should fail when kernel release cannot be read", func() {
+			kernelReleaseFilePath = filepath.Join(testTmpFolder, "missing")
+			pfs := []fec.PhysicalFunctionConfigExt{{PCIAddress: "0000:af:00.0", PFDriver: "pci-pf-stub", VFDriver: utils.VFIO_PCI, VFAmount: 8}}
+			Expect(validateFecPhysicalFunctions(pfs, inventory, vfDrivers)).To(MatchError(ContainSubstring("failed to read kernel release")))
+			Expect(validateFecPhysicalFunctions(pfs, inventory, nil)).To(Succeed())
+		})
+	})
+})
+
+var _ = Describe("validateVrbPhysicalFunctions", func() {
+	It("should report VFs exceeding device limit", func() {
+		inventory := &vrbv1.NodeInventory{SriovAccelerators: []vrbv1.SriovAccelerator{{PCIAddress: "0000:f7:00.0", MaxVFs: 16}}}
+		pfs := []vrbv1.PhysicalFunctionConfigExt{{PCIAddress: "0000:f7:00.0", PFDriver: "vfio-pci", VFAmount: 17}}
+
+		Expect(validateVrbPhysicalFunctions(pfs, inventory, nil)).
+			To(MatchError(ContainSubstring("spec.physicalFunctions[0].vfAmount: Invalid value: 17")))
+	})
+})
@@ -0,0 +1,58 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("resourceNamePredicate", func() {
+	p := resourceNamePredicate{requiredName: "worker", log: utils.NewLogger()}
+
+	nodeConfig := func(name string) client.Object {
+		return &sriovv2.SriovFecNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+	}
+	secondaryNodeConfig := func(name, nodeName string) client.Object {
+		return &sriovv2.SriovFecNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: sriovv2.SriovFecNodeConfigSpec{NodeName: nodeName}}
+	}
+
+	events := map[string]func(o client.Object) bool{
+		"Create": func(o client.Object) bool { return p.Create(event.CreateEvent{Object: o}) },
+		"Update": func(o client.Object) bool {
+			return p.Update(event.UpdateEvent{ObjectOld: nodeConfig("worker"), ObjectNew: o})
+		},
+		"Delete":  func(o client.Object) bool { return p.Delete(event.DeleteEvent{Object: o}) },
+		"Generic": func(o client.Object) bool { return p.Generic(event.GenericEvent{Object: o}) },
+	}
+
+	cases := []struct {
+		description string
+		object      client.Object
+		expected    bool
+	}{
+		{"object of this node", nodeConfig("worker"), true},
+		{"object of another node", nodeConfig("worker-2"), false},
+		{"secondary object of this node", secondaryNodeConfig("worker-acc100", "worker"), true},
+		{"secondary object of another node", secondaryNodeConfig("worker-2-acc100", "worker-2"), false},
+		{"object with empty name", nodeConfig(""), false},
+		{"missing object", nil, false},
+	}
+
+	for eventType, filter := range events {
+		for _, c := range cases {
+			eventType, filter, c := eventType, filter, c
+			It(eventType+" event for "+c.description+" should return "+map[bool]string{true: "true", false: "false"}[c.expected], func() {
+				Expect(filter(c.object)).To(Equal(c.expected))
+			})
+		}
+	}
+})
@@ -0,0 +1,167 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	pristineStateConfigMapPrefix = "sriov-fec-pristine-state-"
+	pristineStateKey             = "devices"
+)
+
+// pristineKernelParamPrefixes selects kernel cmdline params which are relevant for accelerators' configuration
+var pristineKernelParamPrefixes = []string{"intel_iommu=", "iommu=", "iommu.", "vfio_pci.", "vfio-pci.", "pci="}
+
+// PristineDeviceState describes how PF looked before operator configured it for the first time
+type PristineDeviceState struct {
+	// Unknown is set for PFs which had been already configured (e.g. by older version of the operator)
+	// when the snapshot was taken; such snapshot cannot be used to restore the device
+	Unknown      bool        `json:"unknown,omitempty"`
+	Driver       string      `json:"driver,omitempty"`
+	NumVFs       int         `json:"numVFs"`
+	KernelParams []string    `json:"kernelParams,omitempty"`
+	CapturedAt   metav1.Time `json:"capturedAt"`
+}
+
+func pristineStateConfigMapName(nodeName string) string {
+	return pristineStateConfigMapPrefix + nodeName
+}
+
+// capturePristineState reads current state of the PF. PF which already has VFs is considered as configured earlier
+// by older version of the operator and its snapshot is marked as unknown.
+func capturePristineState(pciAddress string) (PristineDeviceState, error) {
+	driver, err := boundDriver(pciAddress)
+	if err != nil {
+		return PristineDeviceState{}, err
+	}
+
+	cmdline, err := os.ReadFile(procCmdlineFilePath)
+	if err != nil {
+		return PristineDeviceState{}, fmt.Errorf("failed to read file contents: path: %v, error - %v", procCmdlineFilePath, err)
+	}
+
+	state := PristineDeviceState{
+		Driver:       driver,
+		NumVFs:       getVFconfigured(pciAddress),
+		KernelParams: relevantKernelParams(string(cmdline)),
+		CapturedAt:   metav1.Now(),
+	}
+	state.Unknown = state.NumVFs > 0
+	return state, nil
+}
+
+func relevantKernelParams(cmdline string) []string {
+	var params []string
+	for _, param := range strings.Fields(cmdline) {
+		for _, prefix := range pristineKernelParamPrefixes {
+			if strings.HasPrefix(param, prefix) {
+				params = append(params, param)
+				break
+			}
+		}
+	}
+	return params
+}
+
+func (n *NodeConfigurator) getPristineStates(ctx context.Context) (map[string]PristineDeviceState, *corev1.ConfigMap, error) {
+	cm := &corev1.ConfigMap{}
+	key := client.ObjectKey{Namespace: n.nodeNameRef.Namespace, Name: pristineStateConfigMapName(n.nodeNameRef.Name)}
+	if err := n.Get(ctx, key, cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			return map[string]PristineDeviceState{}, nil, nil
+		}
+		return nil, nil, err
+	}
+
+	states := map[string]PristineDeviceState{}
+	if data, ok := cm.Data[pristineStateKey]; ok {
+		if err := json.Unmarshal([]byte(data), &states); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse %s ConfigMap - %v", cm.Name, err)
+		}
+	}
+	return states, cm, nil
+}
+
+// ensurePristineState stores snapshot of the PF unless it was already stored. Existing snapshots are never overwritten.
+func (n *NodeConfigurator) ensurePristineState(ctx context.Context, pciAddress string) error {
+	return retry.OnError(retry.DefaultRetry, func(err error) bool {
+		return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err)
+	}, func() error {
+		states, cm, err := n.getPristineStates(ctx)
+		if err != nil {
+			return err
+		}
+		if _, ok := states[pciAddress]; ok {
+			return nil
+		}
+
+		state, err := capturePristineState(pciAddress)
+		if err != nil {
+			return err
+		}
+		n.Log.WithField("pci", pciAddress).WithField("pristineState", state).Info("storing pristine state of the device")
+
+		states[pciAddress] = state
+		data, err := json.Marshal(states)
+		if err != nil {
+			return err
+		}
+
+		if cm == nil {
+			return n.Create(ctx, &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: n.nodeNameRef.Namespace,
+					Name:      pristineStateConfigMapName(n.nodeNameRef.Name),
+				},
+				Data: map[string]string{pristineStateKey: string(data)},
+			})
+		}
+
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[pristineStateKey] = string(data)
+		return n.Update(ctx, cm)
+	})
+}
+
+// restoreOriginalDriver binds PF back to the driver it used before operator configured it for the first time.
+// Nothing is done for PFs without snapshot or with unknown snapshot.
+func (n *NodeConfigurator) restoreOriginalDriver(ctx context.Context, pciAddress, currentDriver string) error {
+	states, _, err := n.getPristineStates(ctx)
+	if err != nil {
+		return err
+	}
+
+	state, ok := states[pciAddress]
+	if !ok || state.Unknown {
+		n.Log.WithField("pci", pciAddress).Info("pristine state of the device is unknown - keeping current driver")
+		return nil
+	}
+	if state.Driver == currentDriver {
+		return nil
+	}
+
+	n.Log.WithField("pci", pciAddress).WithField("driver", state.Driver).Info("restoring original driver")
+	if state.Driver == "" {
+		if err := n.unbindIfBound(ctx, pciAddress); err != nil {
+			return err
+		}
+		return writeFileWithTimeout(ctx, filepath.Join(sysBusPciDevices, pciAddress, "driver_override"), "\n")
+	}
+	return n.bindDeviceToDriver(ctx, pciAddress, state.Driver)
+}
@@ -0,0 +1,130 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("Pristine device state", func() {
+	const pfPCIAddress = "0000:14:00.0"
+
+	var (
+		nc                          *NodeConfigurator
+		vfs                         int
+		originalSysBusPciDevices    = sysBusPciDevices
+		originalSysBusPciDrivers    = sysBusPciDrivers
+		originalProcCmdlineFilePath = procCmdlineFilePath
+		originalGetVFconfigured     = getVFconfigured
+	)
+
+	bindToDriver := func(driver string) {
+		link := filepath.Join(sysBusPciDevices, pfPCIAddress, "driver")
+		_ = os.Remove(link)
+		Expect(os.Symlink(filepath.Join(sysBusPciDrivers, driver), link)).To(Succeed())
+	}
+
+	readSysfs := func(path ...string) string {
+		content, err := os.ReadFile(filepath.Join(path...))
+		Expect(err).ToNot(HaveOccurred())
+		return string(content)
+	}
+
+	storedStates := func() map[string]PristineDeviceState {
+		cm := &corev1.ConfigMap{}
+		Expect(nc.Get(context.TODO(), client.ObjectKey{Namespace: "default", Name: "sriov-fec-pristine-state-worker"}, cm)).To(Succeed())
+		states := map[string]PristineDeviceState{}
+		Expect(json.Unmarshal([]byte(cm.Data["devices"]), &states)).To(Succeed())
+		return states
+	}
+
+	BeforeEach(func() {
+		root, err := os.MkdirTemp(testTmpFolder, "sysfs")
+		Expect(err).ToNot(HaveOccurred())
+		sysBusPciDevices = filepath.Join(root, "devices")
+		sysBusPciDrivers = filepath.Join(root, "drivers")
+		procCmdlineFilePath = filepath.Join(root, "cmdline")
+		Expect(os.WriteFile(procCmdlineFilePath, []byte("BOOT_IMAGE=/vmlinuz intel_iommu=on iommu=pt vfio_pci.enable_sriov=1 quiet\n"), 0644)).To(Succeed())
+
+		Expect(createFiles(filepath.Join(sysBusPciDevices, pfPCIAddress), "driver_override")).To(Succeed())
+		for _, driver := range []string{utils.PCI_PF_STUB_DASH, utils.VFIO_PCI, "acc_driver"} {
+			Expect(createFiles(filepath.Join(sysBusPciDrivers, driver), "bind", "unbind")).To(Succeed())
+		}
+
+		vfs = 0
+		getVFconfigured = func(string) int { return vfs }
+
+		nc = &NodeConfigurator{
+			Client:      fake.NewClientBuilder().Build(),
+			Log:         utils.NewLogger(),
+			nodeNameRef: types.NamespacedName{Namespace: "default", Name: "worker"},
+		}
+	})
+
+	AfterEach(func() {
+		sysBusPciDevices = originalSysBusPciDevices
+		sysBusPciDrivers = originalSysBusPciDrivers
+		procCmdlineFilePath = originalProcCmdlineFilePath
+		getVFconfigured = originalGetVFconfigured
+	})
+
+	It("should store state captured before the first configuration and never overwrite it", func() {
+		bindToDriver("acc_driver")
+		Expect(nc.ensurePristineState(context.TODO(), pfPCIAddress)).To(Succeed())
+
+		state := storedStates()[pfPCIAddress]
+		Expect(state.Unknown).To(BeFalse())
+		Expect(state.Driver).To(Equal("acc_driver"))
+		Expect(state.NumVFs).To(Equal(0))
+		Expect(state.KernelParams).To(Equal([]string{"intel_iommu=on", "iommu=pt", "vfio_pci.enable_sriov=1"}))
+
+		bindToDriver(utils.VFIO_PCI)
+		vfs = 4
+		Expect(nc.ensurePristineState(context.TODO(), pfPCIAddress)).To(Succeed())
+		Expect(storedStates()[pfPCIAddress]).To(Equal(state))
+	})
+
+	It("should mark state of PF configured by older version as unknown", func() {
+		bindToDriver(utils.PCI_PF_STUB_DASH)
+		vfs = 16
+		Expect(nc.ensurePristineState(context.TODO(), pfPCIAddress)).To(Succeed())
+
+		Expect(storedStates()[pfPCIAddress].Unknown).To(BeTrue())
+	})
+
+	It("should restore original driver", func() {
+		bindToDriver("acc_driver")
+		Expect(nc.ensurePristineState(context.TODO(), pfPCIAddress)).To(Succeed())
+		bindToDriver(utils.VFIO_PCI)
+
+		Expect(nc.restoreOriginalDriver(context.TODO(), pfPCIAddress, utils.VFIO_PCI)).To(Succeed())
+		Expect(readSysfs(sysBusPciDrivers, utils.VFIO_PCI, "unbind")).To(Equal(pfPCIAddress))
+		Expect(readSysfs(sysBusPciDevices, pfPCIAddress, "driver_override")).To(Equal("acc_driver"))
+		Expect(readSysfs(sysBusPciDrivers, "acc_driver", "bind")).To(Equal(pfPCIAddress))
+	})
+
+	It("should keep current driver when pristine state is unknown or missing", func() {
+		bindToDriver(utils.VFIO_PCI)
+		Expect(nc.restoreOriginalDriver(context.TODO(), pfPCIAddress, utils.VFIO_PCI)).To(Succeed())
+
+		vfs = 16
+		Expect(nc.ensurePristineState(context.TODO(), pfPCIAddress)).To(Succeed())
+		Expect(nc.restoreOriginalDriver(context.TODO(), pfPCIAddress, utils.VFIO_PCI)).To(Succeed())
+
+		Expect(readSysfs(sysBusPciDrivers, utils.VFIO_PCI, "unbind")).To(BeEmpty())
+		Expect(readSysfs(sysBusPciDevices, pfPCIAddress, "driver_override")).To(BeEmpty())
+	})
+})
@@ -0,0 +1,118 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// capabilities (see capabilities(7)) required to configure accelerators
+const (
+	capKill      = 5
+	capSysChroot = 18
+	capSysAdmin  = 21
+)
+
+var (
+	procSelfStatusPath = "/proc/self/status"
+	openFile           = os.OpenFile
+
+	requiredCapabilities = []struct {
+		bit  uint
+		name string
+	}{
+		{capSysAdmin, "CAP_SYS_ADMIN"},
+		{capKill, "CAP_KILL"},
+		{capSysChroot, "CAP_SYS_CHROOT"},
+	}
+)
+
+// probePrivileges checks whether the daemon is able to configure accelerators with given PCI addresses and returns
+// missing privileges. Probe has no side effects - capabilities are read from proc and sriov_numvfs is only opened.
+func probePrivileges(pciAddresses []string) ([]string, error) {
+	effective, err := effectiveCapabilities()
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for _, c := range requiredCapabilities {
+		if effective&(1<<c.bit) == 0 {
+			missing = append(missing, c.name)
+		}
+	}
+
+	// writing sriov_numvfs requires also writable /sys, so it is probed on the first discovered device
+	if len(pciAddresses) > 0 {
+		path := filepath.Join(sysBusPciDevices, pciAddresses[0], vfNumFileDefault)
+		f, err := openFile(path, os.O_WRONLY, 0)
+		if err == nil {
+			_ = f.Close()
+		} else if os.IsPermission(err) || errors.Is(err, syscall.EROFS) {
+			missing = append(missing, "write access to "+path)
+		}
+	}
+	return missing, nil
+}
+
+func effectiveCapabilities() (uint64, error) {
+	f, err := os.Open(procSelfStatusPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read capabilities - %v", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if value, found := strings.CutPrefix(scanner.Text(), "CapEff:"); found {
+			capabilities, err := strconv.ParseUint(strings.TrimSpace(value), 16, 64)
+			if err != nil {
+				return 0, fmt.Errorf("failed to parse effective capabilities - %v", err)
+			}
+			return capabilities, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read capabilities - %v", err)
+	}
+	return 0, fmt.Errorf("effective capabilities not found in %s", procSelfStatusPath)
+}
+
+// ProbePrivileges checks privileges of the daemon container at startup; if any is missing, configuration is refused
+// before the node is drained
+func (r *NodeConfigReconciler) ProbePrivileges() {
+	var pciAddresses []string
+	if inventory, err := getSriovInventory(context.Background(), r.log); err == nil {
+		for _, acc := range inventory.SriovAccelerators {
+			pciAddresses = append(pciAddresses, acc.PCIAddress)
+		}
+	}
+	if inventory, err := VrbgetSriovInventory(context.Background(), r.log); err == nil {
+		for _, acc := range inventory.SriovAccelerators {
+			pciAddresses = append(pciAddresses, acc.PCIAddress)
+		}
+	}
+
+	missing, err := probePrivileges(pciAddresses)
+	if err != nil {
+		r.log.WithError(err).Warn("failed to probe privileges of the daemon")
+		return
+	}
+	if len(missing) > 0 {
+		r.log.WithField("missing", missing).Error("daemon lacks privileges required to configure accelerators")
+	}
+	r.missingPrivileges = missing
+}
+
+func (r *NodeConfigReconciler) insufficientPrivilegesMessage() string {
+	return "daemon lacks privileges required to configure accelerators: " + strings.Join(r.missingPrivileges, ", ")
+}
@@ -0,0 +1,155 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("probePrivileges", func() {
+	const pf = "0000:f7:00.0"
+
+	var (
+		originalSysBusPciDevices   = sysBusPciDevices
+		originalProcSelfStatusPath = procSelfStatusPath
+		originalOpenFile           = openFile
+	)
+
+	writeCapEff := func(capEff string) {
+		Expect(os.WriteFile(procSelfStatusPath, []byte("Name:\tsriov-fec-daemon\nCapInh:\t0000000000000000\nCapEff:\t"+capEff+"\n"), 0644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		root, err := os.MkdirTemp(testTmpFolder, "privileges")
+		Expect(err).ToNot(HaveOccurred())
+		sysBusPciDevices = filepath.Join(root, "devices")
+		Expect(createFiles(filepath.Join(sysBusPciDevices, pf), vfNumFileDefault)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(sysBusPciDevices, pf, vfNumFileDefault), []byte("2\n"), 0644)).To(Succeed())
+		procSelfStatusPath = filepath.Join(root, "status")
+	})
+
+	AfterEach(func() {
+		sysBusPciDevices = originalSysBusPciDevices
+		procSelfStatusPath = originalProcSelfStatusPath
+		openFile = originalOpenFile
+	})
+
+	It("should not report anything for privileged container, leaving sriov_numvfs untouched", func() {
+		writeCapEff("000001ffffffffff")
+
+		Expect(probePrivileges([]string{pf})).To(BeEmpty())
+		Expect(os.ReadFile(filepath.Join(sysBusPciDevices, pf, vfNumFileDefault))).To(Equal([]byte("2\n")))
+	})
+
+	It("should report missing capabilities", func() {
+		// default capabilities of container runtimes
+		writeCapEff("00000000a80425fb")
+		Expect(probePrivileges(nil)).To(Equal([]string{"CAP_SYS_ADMIN"}))
+
+		writeCapEff("0000000000000001")
+		Expect(probePrivileges(nil)).To(Equal([]string{"CAP_SYS_ADMIN", "CAP_KILL", "CAP_SYS_CHROOT"}))
+	})
+
+	It("should report sriov_numvfs which cannot be opened for writing", func() {
+		writeCapEff("000001ffffffffff")
+		openFile = func(name string, flag int, perm os.FileMode) (*os.File, error) {
+			return nil, &os.PathError{Op: "open", Path: name, Err: syscall.EROFS}
+		}
+
+		Expect(probePrivileges([]string{pf})).
+			To(Equal([]string{"write access to " + filepath.Join(sysBusPciDevices, pf, vfNumFileDefault)}))
+	})
+
+	It("should fail when capabilities cannot be determined", func() {
+		Expect(os.WriteFile(procSelfStatusPath, []byte("Name:\tsriov-fec-daemon\n"), 0644)).To(Succeed())
+
+		_, err := probePrivileges(nil)
+		Expect(err).To(MatchError(ContainSubstring("effective capabilities not found")))
+	})
+})
+
+var _ = Describe("NodeConfigReconciler.Reconcile with insufficient privileges", func() {
+	var (
+		fakeClient  client.Client
+		nodeNameRef = types.NamespacedName{Name: "worker", Namespace: "testNamespace"}
+		reconciler  NodeConfigReconciler
+		drained     bool
+	)
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(sriovv2.AddToScheme(scheme)).ToNot(HaveOccurred())
+		Expect(vrbv1.AddToScheme(scheme)).ToNot(HaveOccurred())
+		procCmdlineFilePath = "testdata/cmdline_test"
+		sysLockdownFilePath = filepath.Join(testTmpFolder, "lockdown")
+		Expect(os.WriteFile(sysLockdownFilePath, []byte("[none] integrity confidentiality"), 0644)).To(Succeed())
+		drained = false
+
+		getSriovInventory = func(_ context.Context, log *logrus.Logger) (*sriovv2.NodeInventory, error) {
+			return &sriovv2.NodeInventory{
+				SriovAccelerators: []sriovv2.SriovAccelerator{{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, MaxVFs: 10}},
+			}, nil
+		}
+		VrbgetSriovInventory = func(_ context.Context, log *logrus.Logger) (*vrbv1.NodeInventory, error) {
+			return &vrbv1.NodeInventory{}, nil
+		}
+
+		sfnc := &sriovv2.SriovFecNodeConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace, Generation: 1},
+			Spec: sriovv2.SriovFecNodeConfigSpec{
+				PhysicalFunctions: []sriovv2.PhysicalFunctionConfigExt{{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, VFDriver: utils.IGB_UIO, VFAmount: 1}},
+			},
+		}
+		vrbnc := &vrbv1.SriovVrbNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace}}
+		fakeClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(sfnc, vrbnc).Build()
+
+		reconciler = NodeConfigReconciler{
+			Client:      fakeClient,
+			log:         utils.NewLogger(),
+			nodeNameRef: nodeNameRef,
+			drainerAndExecute: func(_ context.Context, configurer func(ctx context.Context) bool, drain bool) error {
+				drained = true
+				return nil
+			},
+			missingPrivileges: []string{"CAP_SYS_ADMIN", "write access to /sys/bus/pci/devices/0000:f7:00.0/sriov_numvfs"},
+		}
+	})
+
+	AfterEach(func() {
+		getSriovInventory = GetSriovInventory
+		VrbgetSriovInventory = VrbGetSriovInventory
+		sysLockdownFilePath = "/sys/kernel/security/lockdown"
+	})
+
+	It("fails without draining the node, listing missing privileges", func() {
+		_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: nodeNameRef})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(drained).To(BeFalse())
+
+		sfnc := new(sriovv2.SriovFecNodeConfig)
+		Expect(fakeClient.Get(context.TODO(), nodeNameRef, sfnc)).To(Succeed())
+		condition := sfnc.FindCondition(ConditionConfigured)
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(string(ConfigurationInsufficientPrivileges)))
+		Expect(condition.Message).To(Equal("daemon lacks privileges required to configure accelerators: " +
+			"CAP_SYS_ADMIN, write access to /sys/bus/pci/devices/0000:f7:00.0/sriov_numvfs"))
+	})
+})
@@ -0,0 +1,192 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+const (
+	rebootLockNameDefault     = "sriov-fec-reboot-lock"
+	rebootLockTimeoutDefault  = 5 * time.Minute
+	rebootLockDurationDefault = 30 * time.Minute
+)
+
+// rebootLockRetryPeriod is how often the lock held by other holder is checked while it is being acquired
+var rebootLockRetryPeriod = 10 * time.Second
+
+// rebootLock is Lease which the daemon holds from before it reboots the node until the new boot ID of the host is
+// observed. Agents rebooting nodes of the cluster (e.g. Machine Config Operator tooling) may share it by its name, so
+// that nodes are not rebooted by them and by the daemon at the same time. Lease is separate from the drain lease.
+type rebootLock struct {
+	client client.Client
+	key    types.NamespacedName
+	holder string
+	// timeout bounds waiting for the lock held by other holder
+	timeout time.Duration
+	// duration is how long the lock is held without renewal, i.e. how long the node may take to reboot before
+	// other holders take the lock over
+	duration time.Duration
+	log      *logrus.Logger
+}
+
+// newRebootLock creates reboot lock of the node in namespace of nodeNameRef
+func newRebootLock(c client.Client, nodeNameRef types.NamespacedName, log *logrus.Logger) *rebootLock {
+	return &rebootLock{
+		client:   c,
+		key:      types.NamespacedName{Namespace: nodeNameRef.Namespace, Name: rebootLockName()},
+		holder:   nodeNameRef.Name,
+		timeout:  rebootLockTimeout(log),
+		duration: rebootLockDuration(log),
+		log:      log,
+	}
+}
+
+// rebootLockName returns name of the reboot lock; it is configured with SRIOV_FEC_REBOOT_LOCK_NAME env variable
+func rebootLockName() string {
+	if name := os.Getenv(utils.SRIOV_PREFIX + "REBOOT_LOCK_NAME"); name != "" {
+		return name
+	}
+	return rebootLockNameDefault
+}
+
+// rebootLockTimeout returns how long the reboot lock is waited for; it is configured with SRIOV_FEC_REBOOT_LOCK_TIMEOUT
+// env variable
+func rebootLockTimeout(log *logrus.Logger) time.Duration {
+	return durationFromEnv(utils.SRIOV_PREFIX+"REBOOT_LOCK_TIMEOUT", rebootLockTimeoutDefault, log)
+}
+
+// rebootLockDuration returns how long the reboot lock is held without renewal; it is configured with
+// SRIOV_FEC_REBOOT_LOCK_DURATION env variable
+func rebootLockDuration(log *logrus.Logger) time.Duration {
+	return durationFromEnv(utils.SRIOV_PREFIX+"REBOOT_LOCK_DURATION", rebootLockDurationDefault, log)
+}
+
+// durationFromEnv returns positive duration set in env variable, or the default when it is not set or invalid
+func durationFromEnv(name string, defaultValue time.Duration, log *logrus.Logger) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		log.WithError(err).WithField("variable", name).WithField("default", defaultValue).
+			Error("user-provided value is incorrect 'Duration', using default value instead")
+		return defaultValue
+	}
+	return duration
+}
+
+// acquire waits until the lock is free, expired or already held by the node and takes it; error is returned when
+// the lock is not acquired within the timeout
+func (l *rebootLock) acquire(ctx context.Context) error {
+	var holder string
+	err := wait.PollImmediateWithContext(ctx, rebootLockRetryPeriod, l.timeout, func(ctx context.Context) (bool, error) {
+		var err error
+		if holder, err = l.tryAcquire(ctx); err != nil {
+			l.log.WithError(err).WithField("lease", l.key).Warn("failed to acquire reboot lock - retrying")
+			return false, nil
+		}
+		if holder != l.holder {
+			l.log.WithField("lease", l.key).WithField("holder", holder).Info("reboot lock is held - waiting")
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("reboot lock %s was not acquired within %s (held by %q) - %v", l.key, l.timeout, holder, err)
+	}
+	l.log.WithField("lease", l.key).WithField("duration", l.duration).Info("reboot lock acquired")
+	return nil
+}
+
+// tryAcquire takes the lock unless it is held by other holder; returns holder of the lock
+func (l *rebootLock) tryAcquire(ctx context.Context) (string, error) {
+	now := metav1.NewMicroTime(daemonClock.Now())
+	durationSeconds := int32(l.duration.Seconds())
+	lease := &coordinationv1.Lease{}
+	err := l.client.Get(ctx, l.key, lease)
+	if apierrors.IsNotFound(err) {
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Namespace: l.key.Namespace, Name: l.key.Name},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &l.holder,
+				LeaseDurationSeconds: &durationSeconds,
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}
+		if err := l.client.Create(ctx, lease); err != nil {
+			return "", err
+		}
+		return l.holder, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	holder := leaseHolder(lease)
+	if holder != "" && holder != l.holder && !leaseExpired(lease, now.Time) {
+		return holder, nil
+	}
+	if holder != l.holder {
+		lease.Spec.AcquireTime = &now
+	}
+	lease.Spec.HolderIdentity = &l.holder
+	lease.Spec.LeaseDurationSeconds = &durationSeconds
+	lease.Spec.RenewTime = &now
+	// update fails on conflict, when other holder has taken the lock meanwhile
+	if err := l.client.Update(ctx, lease); err != nil {
+		return "", err
+	}
+	return l.holder, nil
+}
+
+// release frees the lock, provided that it is held by the node
+func (l *rebootLock) release(ctx context.Context) error {
+	lease := &coordinationv1.Lease{}
+	if err := l.client.Get(ctx, l.key, lease); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if leaseHolder(lease) != l.holder {
+		return nil
+	}
+	lease.Spec.HolderIdentity = nil
+	if err := l.client.Update(ctx, lease); err != nil {
+		return err
+	}
+	l.log.WithField("lease", l.key).Info("reboot lock released")
+	return nil
+}
+
+func leaseHolder(lease *coordinationv1.Lease) string {
+	if lease.Spec.HolderIdentity == nil {
+		return ""
+	}
+	return *lease.Spec.HolderIdentity
+}
+
+// leaseExpired tells whether the lease has not been renewed for its duration
+func leaseExpired(lease *coordinationv1.Lease, now time.Time) bool {
+	if lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		return true
+	}
+	return !now.Before(lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second))
+}
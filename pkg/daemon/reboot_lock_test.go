@@ -0,0 +1,97 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("Reboot lock", func() {
+	var (
+		fakeClient          client.Client
+		lock                *rebootLock
+		clk                 *fakeClock
+		key                 = types.NamespacedName{Namespace: "testNamespace", Name: "reboot-lock"}
+		originalDaemonClock = daemonClock
+		originalRetryPeriod = rebootLockRetryPeriod
+	)
+
+	lease := func() *coordinationv1.Lease {
+		l := &coordinationv1.Lease{}
+		Expect(fakeClient.Get(context.TODO(), key, l)).To(Succeed())
+		return l
+	}
+
+	heldBy := func(holder string, renewedAgo time.Duration) {
+		duration := int32(60)
+		renewed := metav1.NewMicroTime(clk.Now().Add(-renewedAgo))
+		Expect(fakeClient.Create(context.TODO(), &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name},
+			Spec:       coordinationv1.LeaseSpec{HolderIdentity: &holder, LeaseDurationSeconds: &duration, RenewTime: &renewed},
+		})).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(coordinationv1.AddToScheme(scheme)).To(Succeed())
+		fakeClient = fake.NewClientBuilder().WithScheme(scheme).Build()
+		clk = newFakeClock(time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC))
+		daemonClock = clk
+		rebootLockRetryPeriod = time.Millisecond
+		lock = &rebootLock{client: fakeClient, key: key, holder: "worker", timeout: 20 * time.Millisecond,
+			duration: 30 * time.Minute, log: utils.NewLogger()}
+	})
+
+	AfterEach(func() {
+		daemonClock = originalDaemonClock
+		rebootLockRetryPeriod = originalRetryPeriod
+	})
+
+	It("creates the lease when it does not exist", func() {
+		Expect(lock.acquire(context.TODO())).To(Succeed())
+		Expect(leaseHolder(lease())).To(Equal("worker"))
+		Expect(*lease().Spec.LeaseDurationSeconds).To(Equal(int32(1800)))
+		Expect(lease().Spec.RenewTime.Time).To(BeTemporally("==", clk.Now()))
+	})
+
+	It("is not acquired while held by other holder", func() {
+		heldBy("other-worker", 30*time.Second)
+
+		err := lock.acquire(context.TODO())
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring(`held by "other-worker"`))
+		Expect(leaseHolder(lease())).To(Equal("other-worker"))
+	})
+
+	It("takes over the lease which has expired", func() {
+		heldBy("other-worker", 2*time.Minute)
+
+		Expect(lock.acquire(context.TODO())).To(Succeed())
+		Expect(leaseHolder(lease())).To(Equal("worker"))
+		Expect(lease().Spec.AcquireTime.Time).To(BeTemporally("==", clk.Now()))
+	})
+
+	It("releases only the lease held by the node", func() {
+		heldBy("other-worker", 0)
+		Expect(lock.release(context.TODO())).To(Succeed())
+		Expect(leaseHolder(lease())).To(Equal("other-worker"))
+
+		Expect(fakeClient.Delete(context.TODO(), lease())).To(Succeed())
+		Expect(lock.acquire(context.TODO())).To(Succeed())
+		Expect(lock.release(context.TODO())).To(Succeed())
+		Expect(leaseHolder(lease())).To(BeEmpty())
+	})
+})
@@ -0,0 +1,228 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	fec "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/conditions"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+const unknownCount = "-"
+
+// pfVFCounts are VF counts of single PF from all the sources compared by ResourceConsistencyChecker
+type pfVFCounts struct {
+	pciAddress string
+	spec       int
+	sysfs      int
+	inventory  int
+	// resource is empty when VFs of the PF are not mapped to any device plugin resource
+	resource    string
+	allocatable int64
+	// expected is the number of allocatable VFs of all PFs mapped to the same resource
+	expected int64
+}
+
+func (c pfVFCounts) consistent() bool {
+	return c.spec == c.sysfs && c.spec == c.inventory && (c.resource == "" || c.allocatable == c.expected)
+}
+
+func (c pfVFCounts) String() string {
+	allocatable := unknownCount
+	if c.resource != "" {
+		allocatable = fmt.Sprintf("%d(%s)", c.allocatable, c.resource)
+	}
+	return fmt.Sprintf("%s %d/%d/%d/%s", c.pciAddress, c.spec, c.sysfs, c.inventory, allocatable)
+}
+
+// ResourceConsistencyChecker periodically compares number of VFs of configured PFs requested in spec, exposed in sysfs,
+// reported in inventory and allocatable on the node. Disagreement is reported with ResourceConsistency condition of
+// the node config. Node configs are checked only in steady state, i.e. when the spec is applied and no configuration
+// is in progress.
+type ResourceConsistencyChecker struct {
+	client      client.Client
+	nodeNameRef types.NamespacedName
+	// nodeConfigKey returns key of node configs of the node, see NodeConfigReconciler.NodeConfigKey
+	nodeConfigKey func() types.NamespacedName
+	recorder      record.EventRecorder
+	log           *logrus.Logger
+	inProgress    func() bool
+}
+
+// NewResourceConsistencyChecker creates checker of nodeNameRef node, which checks node configs with key returned by
+// nodeConfigKey; inProgress reports whether configuration is in progress on the node
+func NewResourceConsistencyChecker(c client.Client, recorder record.EventRecorder, nodeNameRef types.NamespacedName, nodeConfigKey func() types.NamespacedName, inProgress func() bool, log *logrus.Logger) *ResourceConsistencyChecker {
+	return &ResourceConsistencyChecker{
+		client:        c,
+		recorder:      recorder,
+		nodeNameRef:   nodeNameRef,
+		nodeConfigKey: nodeConfigKey,
+		log:           log,
+		inProgress:    inProgress,
+	}
+}
+
+// Start implements manager.Runnable
+func (r *ResourceConsistencyChecker) Start(ctx context.Context) error {
+	r.log.WithField("interval", resyncPeriod.Get()).Info("starting resource consistency checker")
+	wait.UntilWithContext(ctx, r.check, resyncPeriod.Get())
+	return nil
+}
+
+func (r *ResourceConsistencyChecker) check(ctx context.Context) {
+	if r.inProgress() {
+		r.log.Debug("configuration in progress - skipping resource consistency check")
+		return
+	}
+
+	node := &corev1.Node{}
+	if err := r.client.Get(ctx, client.ObjectKey{Name: r.nodeNameRef.Name}, node); err != nil {
+		r.log.WithError(err).Error("failed to get node - skipping resource consistency check")
+		return
+	}
+
+	resources, err := r.devicePluginResources(ctx)
+	if err != nil {
+		r.log.WithError(err).Error("failed to read device plugin config - allocatable resources are not checked")
+	}
+
+	for _, nc := range []client.Object{&fec.SriovFecNodeConfig{}, &vrbv1.SriovVrbNodeConfig{}} {
+		if err := r.checkNodeConfig(ctx, nc, node.Status.Allocatable, resources); err != nil {
+			r.log.WithError(err).WithField("kind", fmt.Sprintf("%T", nc)).Error("failed to check resource consistency")
+		}
+	}
+}
+
+// devicePluginResources returns resources defined in sriov-device-plugin config with names prefixed like in node's
+// allocatable, e.g. intel.com/intel_fec_acc100
+func (r *ResourceConsistencyChecker) devicePluginResources(ctx context.Context) ([]utils.DevicePluginResource, error) {
+	cm := &corev1.ConfigMap{}
+	if err := r.client.Get(ctx, client.ObjectKey{Namespace: r.nodeNameRef.Namespace, Name: utils.DevicePluginConfigMapName}, cm); err != nil {
+		return nil, err
+	}
+	return utils.ParseDevicePluginResources(cm.Data)
+}
+
+func (r *ResourceConsistencyChecker) checkNodeConfig(ctx context.Context, nc client.Object, allocatable corev1.ResourceList, resources []utils.DevicePluginResource) error {
+	if err := r.client.Get(ctx, r.nodeConfigKey(), nc); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	original := nc.DeepCopyObject().(client.Object)
+	var counts []pfVFCounts
+	var ncConditions *[]metav1.Condition
+	switch nodeConfig := nc.(type) {
+	case *fec.SriovFecNodeConfig:
+		for _, pf := range nodeConfig.Spec.PhysicalFunctions {
+			pciAddress := utils.CanonicalPCIAddress(pf.PCIAddress)
+			pfCounts := pfVFCounts{pciAddress: pciAddress, spec: pf.VFAmount, sysfs: getVFconfigured(pciAddress)}
+			for _, acc := range nodeConfig.Status.Inventory.SriovAccelerators {
+				if acc.PCIAddress != pciAddress {
+					continue
+				}
+				pfCounts.inventory = len(acc.VFs)
+				if len(acc.VFs) > 0 {
+					pfCounts.resource = utils.ResourceOfVF(resources, acc.VendorID, acc.VFs[0].DeviceID, acc.VFs[0].Driver)
+				}
+			}
+			counts = append(counts, pfCounts)
+		}
+		ncConditions = &nodeConfig.Status.Conditions
+	case *vrbv1.SriovVrbNodeConfig:
+		for _, pf := range nodeConfig.Spec.PhysicalFunctions {
+			pciAddress := utils.CanonicalPCIAddress(pf.PCIAddress)
+			pfCounts := pfVFCounts{pciAddress: pciAddress, spec: pf.VFAmount, sysfs: getVFconfigured(pciAddress)}
+			for _, acc := range nodeConfig.Status.Inventory.SriovAccelerators {
+				if acc.PCIAddress != pciAddress {
+					continue
+				}
+				pfCounts.inventory = len(acc.VFs)
+				if len(acc.VFs) > 0 {
+					pfCounts.resource = utils.ResourceOfVF(resources, acc.VendorID, acc.VFs[0].DeviceID, acc.VFs[0].Driver)
+				}
+			}
+			counts = append(counts, pfCounts)
+		}
+		ncConditions = &nodeConfig.Status.Conditions
+	default:
+		return fmt.Errorf("unsupported node config type %T", nc)
+	}
+
+	if !isSteadyState(*ncConditions, nc.GetGeneration()) {
+		r.log.WithField("kind", fmt.Sprintf("%T", nc)).Debug("spec is not applied - skipping resource consistency check")
+		return nil
+	}
+
+	condition, fullMessage := conditions.Bounded(resourceConsistencyCondition(counts, allocatable, nc.GetGeneration()))
+	if condition.Status == metav1.ConditionTrue && meta.FindStatusCondition(*ncConditions, condition.Type) == nil {
+		// do not write consistent condition of node config which was never inconsistent
+		return nil
+	}
+	// configuration may have started while VF counts were read
+	if r.inProgress() || !conditions.SetIfChanged(ncConditions, condition) {
+		return nil
+	}
+	if condition.Status == metav1.ConditionFalse {
+		r.log.WithField("kind", fmt.Sprintf("%T", nc)).Warn(condition.Message)
+	}
+	if _, err := patchStatus(ctx, r.client, original, nc); err != nil {
+		return err
+	}
+	emitFullMessage(r.recorder, nc, condition, fullMessage)
+	return nil
+}
+
+// isSteadyState tells whether the current generation of node config was successfully applied
+func isSteadyState(ncConditions []metav1.Condition, generation int64) bool {
+	configured := meta.FindStatusCondition(ncConditions, ConditionConfigured)
+	return configured != nil && configured.Reason == string(ConfigurationSucceeded) && configured.ObservedGeneration == generation
+}
+
+// resourceConsistencyCondition compares VF counts of all PFs; allocatable of resource shared by several PFs is
+// compared with the number of VFs requested for all of them
+func resourceConsistencyCondition(counts []pfVFCounts, allocatable corev1.ResourceList, generation int64) metav1.Condition {
+	expected := map[string]int64{}
+	for _, c := range counts {
+		if c.resource != "" {
+			expected[c.resource] += int64(c.spec)
+		}
+	}
+
+	consistent := true
+	rows := make([]string, 0, len(counts))
+	for i := range counts {
+		if counts[i].resource != "" {
+			quantity := allocatable[corev1.ResourceName(counts[i].resource)]
+			counts[i].allocatable = quantity.Value()
+			counts[i].expected = expected[counts[i].resource]
+		}
+		consistent = consistent && counts[i].consistent()
+		rows = append(rows, counts[i].String())
+	}
+
+	if consistent {
+		return conditions.ResourceConsistency(metav1.ConditionTrue, conditions.ReasonConsistent,
+			"VF counts in spec, sysfs, inventory and node allocatable agree", generation)
+	}
+	return conditions.ResourceConsistency(metav1.ConditionFalse, conditions.ReasonInconsistent,
+		"VF counts differ (pf spec/sysfs/inventory/allocatable): "+strings.Join(rows, ", "), generation)
+}
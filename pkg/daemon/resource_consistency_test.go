@@ -0,0 +1,157 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	fec "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/conditions"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("ResourceConsistencyChecker", func() {
+	const (
+		pf1 = "0000:f7:00.0"
+		pf2 = "0000:f8:00.0"
+	)
+	var (
+		originalGetVFconfigured = getVFconfigured
+		nodeRef                 = types.NamespacedName{Name: "worker", Namespace: "default"}
+		c                       client.Client
+		checker                 *ResourceConsistencyChecker
+		inProgress              bool
+		sysfsVFs                map[string]int
+	)
+
+	vfs := func(count int) []fec.VF {
+		vfs := make([]fec.VF, count)
+		for i := range vfs {
+			vfs[i] = fec.VF{PCIAddress: "0000:f7:00.1", DeviceID: "57c1", Driver: utils.VFIO_PCI}
+		}
+		return vfs
+	}
+
+	consistencyCondition := func() *metav1.Condition {
+		nc := &fec.SriovFecNodeConfig{}
+		Expect(c.Get(context.TODO(), nodeRef, nc)).To(Succeed())
+		return meta.FindStatusCondition(nc.Status.Conditions, conditions.TypeResourceConsistency)
+	}
+
+	BeforeEach(func() {
+		inProgress = false
+		sysfsVFs = map[string]int{pf1: 16, pf2: 4}
+		getVFconfigured = func(pciAddr string) int {
+			return sysfsVFs[pciAddr]
+		}
+
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(fec.AddToScheme(scheme)).To(Succeed())
+		Expect(vrbv1.AddToScheme(scheme)).To(Succeed())
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: nodeRef.Name},
+				Status: corev1.NodeStatus{Allocatable: corev1.ResourceList{
+					"intel.com/intel_fec_acc200": resource.MustParse("20"),
+				}},
+			},
+			&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: utils.DevicePluginConfigMapName, Namespace: nodeRef.Namespace},
+				Data: map[string]string{utils.DevicePluginConfigKey: `{"resourceList": [
+					{"resourceName": "intel_fec_acc100", "selectors": {"vendors": ["8086"], "devices": ["0d5d"]}},
+					{"resourceName": "intel_fec_acc200", "selectors": {"vendors": ["8086"], "devices": ["57c1"], "drivers": ["vfio-pci"]}}
+				]}`},
+			},
+			&fec.SriovFecNodeConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: nodeRef.Name, Namespace: nodeRef.Namespace, Generation: 2},
+				Spec: fec.SriovFecNodeConfigSpec{PhysicalFunctions: []fec.PhysicalFunctionConfigExt{
+					{PCIAddress: pf1, VFAmount: 16},
+					{PCIAddress: pf2, VFAmount: 4},
+				}},
+				Status: fec.SriovFecNodeConfigStatus{
+					Conditions: []metav1.Condition{conditions.Configured(metav1.ConditionTrue, ConfigurationSucceeded, "Configured successfully", 2)},
+					Inventory: fec.NodeInventory{SriovAccelerators: []fec.SriovAccelerator{
+						{PCIAddress: pf1, VendorID: "8086", VFs: vfs(16)},
+						{PCIAddress: pf2, VendorID: "8086", VFs: vfs(4)},
+					}},
+				},
+			},
+		).Build()
+		checker = NewResourceConsistencyChecker(c, nil, nodeRef, func() types.NamespacedName { return nodeRef }, func() bool { return inProgress }, utils.NewLogger())
+	})
+
+	AfterEach(func() {
+		getVFconfigured = originalGetVFconfigured
+	})
+
+	setAllocatable := func(quantity string) {
+		node := &corev1.Node{}
+		Expect(c.Get(context.TODO(), client.ObjectKey{Name: nodeRef.Name}, node)).To(Succeed())
+		node.Status.Allocatable["intel.com/intel_fec_acc200"] = resource.MustParse(quantity)
+		Expect(c.Status().Update(context.TODO(), node)).To(Succeed())
+	}
+
+	It("should not set condition when VF counts agree", func() {
+		checker.check(context.TODO())
+		Expect(consistencyCondition()).To(BeNil())
+	})
+
+	It("should report VF counts of all PFs when they disagree and clear condition once resolved", func() {
+		setAllocatable("16")
+		checker.check(context.TODO())
+		condition := consistencyCondition()
+		Expect(condition).ToNot(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(string(conditions.ReasonInconsistent)))
+		Expect(condition.Message).To(Equal("VF counts differ (pf spec/sysfs/inventory/allocatable): " +
+			"0000:f7:00.0 16/16/16/16(intel.com/intel_fec_acc200), 0000:f8:00.0 4/4/4/16(intel.com/intel_fec_acc200)"))
+
+		setAllocatable("20")
+		checker.check(context.TODO())
+		Expect(consistencyCondition().Status).To(Equal(metav1.ConditionTrue))
+		Expect(consistencyCondition().Reason).To(Equal(string(conditions.ReasonConsistent)))
+	})
+
+	It("should report mismatch of sysfs and PFs without device plugin resource", func() {
+		sysfsVFs[pf2] = 2
+		nc := &fec.SriovFecNodeConfig{}
+		Expect(c.Get(context.TODO(), nodeRef, nc)).To(Succeed())
+		nc.Status.Inventory.SriovAccelerators[1].VFs = nil
+		Expect(c.Status().Update(context.TODO(), nc)).To(Succeed())
+		setAllocatable("16")
+
+		checker.check(context.TODO())
+		Expect(consistencyCondition().Message).To(Equal("VF counts differ (pf spec/sysfs/inventory/allocatable): " +
+			"0000:f7:00.0 16/16/16/16(intel.com/intel_fec_acc200), 0000:f8:00.0 4/2/0/-"))
+	})
+
+	It("should skip check when configuration is in progress or spec is not applied", func() {
+		setAllocatable("12")
+		inProgress = true
+		checker.check(context.TODO())
+		Expect(consistencyCondition()).To(BeNil())
+
+		inProgress = false
+		nc := &fec.SriovFecNodeConfig{}
+		Expect(c.Get(context.TODO(), nodeRef, nc)).To(Succeed())
+		nc.Generation = 3
+		Expect(c.Update(context.TODO(), nc)).To(Succeed())
+		checker.check(context.TODO())
+		Expect(consistencyCondition()).To(BeNil())
+	})
+})
@@ -0,0 +1,36 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+const defaultResyncPeriod = time.Minute
+
+// syncedResyncPeriod is read by reconciles while SriovFecOperatorConfig may change it
+type syncedResyncPeriod struct {
+	nanos int64
+}
+
+func newResyncPeriod() *syncedResyncPeriod {
+	return &syncedResyncPeriod{nanos: int64(defaultResyncPeriod)}
+}
+
+func (p *syncedResyncPeriod) Get() time.Duration {
+	return time.Duration(atomic.LoadInt64(&p.nanos))
+}
+
+// SetResyncPeriod changes period of NodeConfig reconciliation, it is used from next requeue on; non-positive period
+// restores the default
+func SetResyncPeriod(period time.Duration) {
+	if period <= 0 {
+		period = defaultResyncPeriod
+	}
+	if old := resyncPeriod.Get(); old != period {
+		log.WithField("old", old).WithField("new", period).Info("resync period changed")
+	}
+	atomic.StoreInt64(&resyncPeriod.nanos, int64(period))
+}
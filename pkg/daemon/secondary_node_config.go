@@ -0,0 +1,245 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	fec "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+)
+
+// Accelerators of the node are configured by SriovFecNodeConfig named after the node (the primary one) and by
+// SriovFecNodeConfigs named <node>-<suffix> targeting the node with spec.nodeName (secondary ones). Secondary node
+// config owns accelerators listed in its spec (the webhook keeps them disjoint), the primary one owns all the others.
+// Each of them is configured separately, touching only accelerators it owns.
+
+// acceleratorScope tells which accelerators are configured by the node config
+type acceleratorScope struct {
+	nodeConfig types.NamespacedName
+	secondary  bool
+	// addresses are PCI addresses of accelerators owned by the secondary node config, or by secondary node configs
+	// of the node in case of the primary one
+	addresses map[string]bool
+}
+
+type acceleratorScopeKey struct{}
+
+func (s acceleratorScope) owns(pciAddress string) bool {
+	if s.secondary {
+		return s.addresses[pciAddress]
+	}
+	return !s.addresses[pciAddress]
+}
+
+// inventory returns accelerators of the inventory owned by the node config
+func (s acceleratorScope) inventory(inv *fec.NodeInventory) *fec.NodeInventory {
+	owned := &fec.NodeInventory{}
+	for _, acc := range inv.SriovAccelerators {
+		if s.owns(acc.PCIAddress) {
+			owned.SriovAccelerators = append(owned.SriovAccelerators, acc)
+		}
+	}
+	// devices which cannot be configured are reported by the primary node config only
+	if !s.secondary {
+		owned.UnsupportedDevices = inv.UnsupportedDevices
+	}
+	return owned
+}
+
+// withAcceleratorScope limits configuration done with the context to accelerators in scope
+func withAcceleratorScope(ctx context.Context, scope acceleratorScope) context.Context {
+	return context.WithValue(ctx, acceleratorScopeKey{}, scope)
+}
+
+// acceleratorScopeFrom returns scope of configuration done with the context; false is returned when it is not limited
+func acceleratorScopeFrom(ctx context.Context) (acceleratorScope, bool) {
+	scope, ok := ctx.Value(acceleratorScopeKey{}).(acceleratorScope)
+	return scope, ok
+}
+
+// fecAcceleratorScope returns accelerators configured by the node config
+func (r *NodeConfigReconciler) fecAcceleratorScope(ctx context.Context, nc *fec.SriovFecNodeConfig) (acceleratorScope, error) {
+	if nc.IsSecondary() {
+		return secondaryAcceleratorScope(nc), nil
+	}
+	secondary, err := r.secondaryNodeConfigs(ctx)
+	if err != nil {
+		return acceleratorScope{}, err
+	}
+	scope := acceleratorScope{nodeConfig: client.ObjectKeyFromObject(nc), addresses: map[string]bool{}}
+	for _, nodeConfig := range secondary {
+		for _, pf := range nodeConfig.Spec.PhysicalFunctions {
+			scope.addresses[pf.PCIAddress] = true
+		}
+	}
+	return scope, nil
+}
+
+func secondaryAcceleratorScope(nc *fec.SriovFecNodeConfig) acceleratorScope {
+	scope := acceleratorScope{nodeConfig: client.ObjectKeyFromObject(nc), secondary: true, addresses: map[string]bool{}}
+	for _, pf := range nc.Spec.PhysicalFunctions {
+		scope.addresses[pf.PCIAddress] = true
+	}
+	return scope
+}
+
+// fecStatusInventory returns inventory exposed in status of the node config: secondary node config exposes only
+// accelerators it owns, the primary one exposes inventory of the whole node
+func fecStatusInventory(nc *fec.SriovFecNodeConfig, inv *fec.NodeInventory) *fec.NodeInventory {
+	if !nc.IsSecondary() {
+		return inv
+	}
+	return secondaryAcceleratorScope(nc).inventory(inv)
+}
+
+// secondaryNodeConfigs returns SriovFecNodeConfigs named <node>-<suffix> which target the node
+func (r *NodeConfigReconciler) secondaryNodeConfigs(ctx context.Context) ([]fec.SriovFecNodeConfig, error) {
+	list := &fec.SriovFecNodeConfigList{}
+	if err := r.List(ctx, list, client.InNamespace(r.nodeNameRef.Namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list node configs - %v", err)
+	}
+	var secondary []fec.SriovFecNodeConfig
+	for i := range list.Items {
+		if isSecondaryNodeConfigOf(&list.Items[i], r.nodeNameRef.Name) {
+			normalizeFecPCIAddresses(&list.Items[i].Spec)
+			secondary = append(secondary, list.Items[i])
+		}
+	}
+	return secondary, nil
+}
+
+// isSecondaryNodeConfigOf tells whether the object is secondary node config targeting the node
+func isSecondaryNodeConfigOf(o client.Object, nodeName string) bool {
+	nc, ok := o.(*fec.SriovFecNodeConfig)
+	return ok && nc.IsSecondary() && nc.Spec.NodeName == nodeName && strings.HasPrefix(nc.Name, nodeName+"-")
+}
+
+// fecAppliedSpecKind separates specs applied by secondary node configs from the one applied by the primary one
+func fecAppliedSpecKind(nc *fec.SriovFecNodeConfig) string {
+	if nc.IsSecondary() {
+		return hitlessUpdateKindFec + "-" + nc.Name
+	}
+	return hitlessUpdateKindFec
+}
+
+// appliedSpecKind is fecAppliedSpecKind of the node config configuring accelerators in scope
+func (s acceleratorScope) appliedSpecKind() string {
+	if s.secondary {
+		return hitlessUpdateKindFec + "-" + s.nodeConfig.Name
+	}
+	return hitlessUpdateKindFec
+}
+
+// fecSpecDebouncerOf returns debouncer of the node config; spec of each node config settles separately
+func (r *NodeConfigReconciler) fecSpecDebouncerOf(nc *fec.SriovFecNodeConfig) *specDebouncer {
+	if !nc.IsSecondary() {
+		return &r.fecSpecDebouncer
+	}
+	debouncer, _ := r.secondarySpecDebouncers.LoadOrStore(nc.Name, &specDebouncer{})
+	return debouncer.(*specDebouncer)
+}
+
+// reconcileSecondaryNodeConfig configures accelerators owned by secondary node config. Unlike the primary one, it is
+// not created by the daemon and it has no SriovVrbNodeConfig counterpart.
+func (r *NodeConfigReconciler) reconcileSecondaryNodeConfig(ctx context.Context, nn types.NamespacedName, affected *[]client.Object) (reconcile.Result, error) {
+	sfnc := &fec.SriovFecNodeConfig{}
+	if err := r.Get(ctx, nn, sfnc); err != nil {
+		if k8serrors.IsNotFound(err) {
+			// its accelerators are left to the primary node config, which is reconciled on deletion as well
+			r.log.WithField("nodeConfig", nn).Info("secondary node config deleted")
+			r.secondarySpecDebouncers.Delete(nn.Name)
+			return reconcile.Result{}, nil
+		}
+		return requeueNowWithError(err)
+	}
+	// with hostname matching, node configs matching the node by hostname are passed as well; only the adopted one is
+	// acted on
+	if !isSecondaryNodeConfigOf(sfnc, r.nodeNameRef.Name) {
+		r.log.WithField("nodeConfig", nn).WithField("actedOn", r.nodeConfigKey().Name).
+			Warn("node config matches the node by hostname only - ignoring")
+		return reconcile.Result{}, nil
+	}
+	normalizeFecPCIAddresses(&sfnc.Spec)
+	recordStatusBaseline(ctx, sfnc)
+	*affected = []client.Object{sfnc}
+
+	if err := r.migrateStatus(ctx, sfnc); err != nil {
+		return requeueNowWithError(err)
+	}
+
+	if deferral := r.newerWriterDeferral(sfnc); deferral != "" {
+		return requeueLaterOrNowIfError(r.updateStatus(ctx, sfnc, metav1.ConditionFalse, ConfigurationDeferred, deferral))
+	}
+
+	if err := validateNodeConfig(sfnc.Spec); err != nil {
+		if deferral := r.kernelParamsDeferral(ctx, requiredKernelParams(supportedAccelerators, hostArch)); deferral != "" {
+			r.log.WithField("reason", deferral).Info("configuration deferred")
+			return requeueLaterOrNowIfError(r.updateStatus(ctx, sfnc, metav1.ConditionFalse, ConfigurationDeferred, deferral))
+		}
+		return requeueNowWithError(r.updateStatus(ctx, sfnc, metav1.ConditionFalse, ConfigurationFailed, err.Error()))
+	}
+
+	detectedInventory, err := r.readExistingInventory(ctx)
+	if err != nil {
+		return requeueNowWithError(err)
+	}
+	scope := secondaryAcceleratorScope(sfnc)
+	inventory := scope.inventory(detectedInventory)
+
+	if err := fecUnsupportedDeviceRequested(sfnc.Spec.PhysicalFunctions, detectedInventory); err != nil {
+		r.log.WithError(err).Info("requested configuration refers to unsupported accelerator")
+		return requeueLaterOrNowIfError(r.updateStatus(ctx, sfnc, metav1.ConditionFalse, ConfigurationUnsupportedDevice, err.Error()))
+	}
+
+	// no drain, as configuration would fail anyway
+	if noAcceleratorsDiscovered(len(sfnc.Spec.PhysicalFunctions), len(detectedInventory.SriovAccelerators)) {
+		msg := noAcceleratorsDiscoveredMessage(len(sfnc.Spec.PhysicalFunctions))
+		r.reportNoAcceleratorsDiscovered(sfnc, sfnc.FindCondition(ConditionConfigured), msg)
+		return requeueDegradedOrNowIfError(r.updateStatus(ctx, sfnc, metav1.ConditionFalse, ConfigurationNoAcceleratorsDiscovered, msg), r.log)
+	}
+
+	if isConfigurationOfNonExistingInventoryRequested(sfnc.Spec.PhysicalFunctions, inventory) {
+		r.log.Info("requested configuration refers to not existing accelerator(s)")
+		return requeueLaterOrNowIfError(r.updateStatus(ctx, sfnc, metav1.ConditionFalse, ConfigurationFailed, "requested configuration refers to not existing accelerator"))
+	}
+
+	bbDevConfigHashes, err := r.bbDevConfigHashes(ctx, fecBBDevConfigRefs(sfnc.Spec.PhysicalFunctions))
+	if err != nil {
+		r.log.WithError(err).Error("failed to read bbDevConfigFrom")
+		return requeueLaterOrNowIfError(r.updateStatus(ctx, sfnc, metav1.ConditionFalse, ConfigurationFailed, err.Error()))
+	}
+
+	// fields which do not affect the hardware are left out, as in case of the primary node config
+	fecSpec := sfnc.Spec
+	fecSpec.ConfigurationDebounce, fecSpec.ForceVfRemoval, fecSpec.PostConfigureSoakSeconds, fecSpec.AdoptExistingConfig = nil, false, 0, false
+	fecSpec.PhysicalFunctions = fecWithoutLogicalNamePrefixes(fecSpec.PhysicalFunctions)
+	fecSpecHash, err := specHash(fecSpec)
+	if err != nil {
+		return requeueNowWithError(err)
+	}
+
+	if !r.isCardUpdateRequired(ctx, sfnc, inventory, fecSpecHash) && !bbDevConfigHashesChanged(bbDevConfigHashes, sfnc.Status.BBDevConfigHashes) {
+		r.log.WithField("nodeConfig", nn).Info("Nothing to do")
+		return requeueLaterOrNowIfError(r.refreshInventory(ctx, sfnc, detectedInventory))
+	}
+
+	return r.configureFecNodeConfig(withAcceleratorScope(ctx, scope), sfnc, inventory, fecSpec, fecSpecHash, bbDevConfigHashes)
+}
+
+// requestsForPrimaryNodeConfig enqueues the primary node config when secondary one changes, as accelerators which
+// are not owned by secondary node configs anymore are left to the primary one
+func (r *NodeConfigReconciler) requestsForPrimaryNodeConfig(o client.Object) []reconcile.Request {
+	if !isSecondaryNodeConfigOf(o, r.nodeNameRef.Name) {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: r.nodeConfigKey()}}
+}
@@ -0,0 +1,203 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/drainhelper"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+// scopeRecordingConfigurer records specs applied by the reconciler together with accelerator scope of the context
+type scopeRecordingConfigurer struct {
+	specs  []sriovv2.SriovFecNodeConfigSpec
+	scopes []acceleratorScope
+}
+
+func (c *scopeRecordingConfigurer) ApplySpec(ctx context.Context, spec sriovv2.SriovFecNodeConfigSpec) error {
+	scope, _ := acceleratorScopeFrom(ctx)
+	c.specs, c.scopes = append(c.specs, spec), append(c.scopes, scope)
+	return nil
+}
+
+func (c *scopeRecordingConfigurer) RestartPfBBConfig(context.Context, sriovv2.PhysicalFunctionConfigExt) error {
+	return nil
+}
+
+var _ = Describe("NodeConfigReconciler.Reconcile with secondary node configs", func() {
+	const n3000, acc100 = "0000:1b:00.0", "0000:f7:00.0"
+
+	var (
+		fakeClient  client.Client
+		nodeNameRef = types.NamespacedName{Name: "worker", Namespace: "testNamespace"}
+		secondary   = types.NamespacedName{Name: "worker-acc100", Namespace: "testNamespace"}
+		configurer  *scopeRecordingConfigurer
+		reconciler  *NodeConfigReconciler
+	)
+
+	pf := func(pciAddress string, vfAmount int) sriovv2.PhysicalFunctionConfigExt {
+		return sriovv2.PhysicalFunctionConfigExt{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, VFDriver: utils.IGB_UIO, VFAmount: vfAmount}
+	}
+
+	reconcile := func(nn types.NamespacedName) *sriovv2.SriovFecNodeConfig {
+		_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: nn})
+		Expect(err).ToNot(HaveOccurred())
+		nc := &sriovv2.SriovFecNodeConfig{}
+		Expect(fakeClient.Get(context.TODO(), nn, nc)).To(Succeed())
+		return nc
+	}
+
+	inventoryAddresses := func(nc *sriovv2.SriovFecNodeConfig) []string {
+		var addresses []string
+		for _, acc := range nc.Status.Inventory.SriovAccelerators {
+			addresses = append(addresses, acc.PCIAddress)
+		}
+		return addresses
+	}
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(sriovv2.AddToScheme(scheme)).To(Succeed())
+		Expect(vrbv1.AddToScheme(scheme)).To(Succeed())
+
+		configPath = "testdata/accelerators.json"
+		VrbconfigPath = "testdata/accelerators_vrb.json"
+		procCmdlineFilePath = "testdata/cmdline_test"
+		sysLockdownFilePath = filepath.Join(testTmpFolder, "lockdown")
+		Expect(os.WriteFile(sysLockdownFilePath, []byte("[none] integrity confidentiality"), 0644)).To(Succeed())
+
+		// both accelerators are configured already, each by its node config
+		getSriovInventory = func(_ context.Context, log *logrus.Logger) (*sriovv2.NodeInventory, error) {
+			return &sriovv2.NodeInventory{SriovAccelerators: []sriovv2.SriovAccelerator{
+				{PCIAddress: n3000, PFDriver: utils.PCI_PF_STUB_DASH, MaxVFs: 8, VFs: []sriovv2.VF{{PCIAddress: "0000:1d:00.0"}}},
+				{PCIAddress: acc100, PFDriver: utils.PCI_PF_STUB_DASH, MaxVFs: 16, VFs: []sriovv2.VF{
+					{PCIAddress: "0000:f7:00.1"}, {PCIAddress: "0000:f7:00.2"}, {PCIAddress: "0000:f7:00.3"}, {PCIAddress: "0000:f7:00.4"},
+				}},
+			}}, nil
+		}
+		VrbgetSriovInventory = func(_ context.Context, log *logrus.Logger) (*vrbv1.NodeInventory, error) {
+			return &vrbv1.NodeInventory{}, nil
+		}
+
+		fakeClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&sriovv2.SriovFecNodeConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace},
+				Spec:       sriovv2.SriovFecNodeConfigSpec{PhysicalFunctions: []sriovv2.PhysicalFunctionConfigExt{pf(n3000, 1)}},
+			},
+			&vrbv1.SriovVrbNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace}},
+			&sriovv2.SriovFecNodeConfig{
+				// spec of the secondary node config was updated since it was applied
+				ObjectMeta: metav1.ObjectMeta{Name: secondary.Name, Namespace: secondary.Namespace, Generation: 2},
+				Spec: sriovv2.SriovFecNodeConfigSpec{
+					NodeName:          nodeNameRef.Name,
+					PhysicalFunctions: []sriovv2.PhysicalFunctionConfigExt{pf(acc100, 4)},
+				},
+			},
+		).Build()
+
+		configurer = &scopeRecordingConfigurer{}
+		var err error
+		reconciler, err = NewNodeConfigReconciler(fakeClient, &drainhelper.FakeDrainer{}, nodeNameRef, configurer, nil,
+			func(context.Context) error { return nil }, record.NewFakeRecorder(10), nil)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		getSriovInventory = GetSriovInventory
+		VrbgetSriovInventory = VrbGetSriovInventory
+		sysLockdownFilePath = "/sys/kernel/security/lockdown"
+	})
+
+	It("configures only accelerators owned by the secondary node config and exposes them in its status", func() {
+		nc := reconcile(secondary)
+
+		Expect(nc.FindCondition(ConditionConfigured).Reason).To(Equal(string(ConfigurationSucceeded)))
+		Expect(configurer.specs).To(HaveLen(1))
+		Expect(configurer.specs[0].PhysicalFunctions).To(ConsistOf(HaveField("PCIAddress", acc100)))
+		Expect(configurer.scopes[0].nodeConfig).To(Equal(secondary))
+		Expect(configurer.scopes[0].owns(acc100)).To(BeTrue())
+		Expect(configurer.scopes[0].owns(n3000)).To(BeFalse())
+		// interrupted configuration is resumed separately from the primary one
+		Expect(configurer.scopes[0].appliedSpecKind()).To(Equal(hitlessUpdateKindFec + "-" + secondary.Name))
+		Expect(inventoryAddresses(nc)).To(ConsistOf(acc100))
+
+		primary := &sriovv2.SriovFecNodeConfig{}
+		Expect(fakeClient.Get(context.TODO(), nodeNameRef, primary)).To(Succeed())
+		Expect(primary.Status.Conditions).To(BeEmpty())
+	})
+
+	It("leaves accelerators owned by secondary node configs out of the primary one", func() {
+		nc := reconcile(nodeNameRef)
+
+		// VFs of ACC100, which are not requested by the primary node config, do not make it outdated
+		Expect(configurer.specs).To(BeEmpty())
+		// primary node config exposes inventory of the whole node
+		Expect(inventoryAddresses(nc)).To(ConsistOf(n3000, acc100))
+
+		nc.Spec.PhysicalFunctions = []sriovv2.PhysicalFunctionConfigExt{pf(n3000, 2)}
+		Expect(fakeClient.Update(context.TODO(), nc)).To(Succeed())
+		nc = reconcile(nodeNameRef)
+
+		Expect(nc.FindCondition(ConditionConfigured).Reason).To(Equal(string(ConfigurationSucceeded)))
+		Expect(configurer.scopes).To(HaveLen(1))
+		Expect(configurer.scopes[0].nodeConfig).To(Equal(nodeNameRef))
+		Expect(configurer.scopes[0].owns(n3000)).To(BeTrue())
+		Expect(configurer.scopes[0].owns(acc100)).To(BeFalse())
+		Expect(configurer.scopes[0].appliedSpecKind()).To(Equal(hitlessUpdateKindFec))
+	})
+
+	It("treats PCI addresses provided without domain or in uppercase as the sysfs ones", func() {
+		nc := &sriovv2.SriovFecNodeConfig{}
+		Expect(fakeClient.Get(context.TODO(), secondary, nc)).To(Succeed())
+		nc.Spec.PhysicalFunctions = []sriovv2.PhysicalFunctionConfigExt{pf("F7:00.0", 4)}
+		Expect(fakeClient.Update(context.TODO(), nc)).To(Succeed())
+
+		nc = reconcile(secondary)
+		Expect(nc.FindCondition(ConditionConfigured).Reason).To(Equal(string(ConfigurationSucceeded)))
+		Expect(configurer.specs[0].PhysicalFunctions).To(ConsistOf(HaveField("PCIAddress", acc100)))
+		Expect(configurer.scopes[0].owns(acc100)).To(BeTrue())
+		Expect(inventoryAddresses(nc)).To(ConsistOf(acc100))
+
+		// the primary node config leaves the accelerator to the secondary one
+		scope, err := reconciler.fecAcceleratorScope(context.TODO(), &sriovv2.SriovFecNodeConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(scope.addresses).To(HaveKey(acc100))
+	})
+
+	It("recognizes secondary node configs of this node only", func() {
+		nodeConfig := func(name, nodeName string) client.Object {
+			return &sriovv2.SriovFecNodeConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: nodeNameRef.Namespace},
+				Spec:       sriovv2.SriovFecNodeConfigSpec{NodeName: nodeName},
+			}
+		}
+
+		Expect(isSecondaryNodeConfigOf(nodeConfig("worker-acc100", "worker"), "worker")).To(BeTrue())
+		Expect(isSecondaryNodeConfigOf(nodeConfig("worker-acc100", ""), "worker")).To(BeFalse())
+		Expect(isSecondaryNodeConfigOf(nodeConfig("worker-acc100", "worker-2"), "worker")).To(BeFalse())
+		Expect(isSecondaryNodeConfigOf(nodeConfig("other-acc100", "worker"), "worker")).To(BeFalse())
+		Expect(isSecondaryNodeConfigOf(&vrbv1.SriovVrbNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: "worker-acc100"}}, "worker")).To(BeFalse())
+		Expect(reconciler.requestsForPrimaryNodeConfig(nodeConfig("worker-acc100", "worker"))).To(
+			ConsistOf(HaveField("NamespacedName", nodeNameRef)))
+		Expect(reconciler.requestsForPrimaryNodeConfig(nodeConfig("worker", ""))).To(BeEmpty())
+	})
+})
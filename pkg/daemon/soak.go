@@ -0,0 +1,51 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/drainhelper"
+)
+
+// soakReportInterval is how often remaining soak time is reported; each report renews the drain lease as well
+var soakReportInterval = 10 * time.Second
+
+// soakMessage is progress reported while soaking, exposed in InProgress condition
+func soakMessage(remaining time.Duration) string {
+	return fmt.Sprintf("soaking, %ds remaining", int(math.Ceil(remaining.Seconds())))
+}
+
+// soak waits given number of seconds after the node was configured, before it is uncordoned. Wait ends early when ctx
+// is done (e.g. on shutdown or lost drain lease) - the node is uncordoned then as well, as after any interrupted work.
+func (r *NodeConfigReconciler) soak(ctx context.Context, seconds int) error {
+	if seconds <= 0 {
+		return nil
+	}
+
+	r.log.WithField("seconds", seconds).Info("soaking before uncordon")
+	// soak is measured in elapsed time, so that clock jumps neither cut it short nor prolong it
+	deadline := daemonClock.Elapsed() + time.Duration(seconds)*time.Second
+	for {
+		remaining := deadline - daemonClock.Elapsed()
+		if remaining <= 0 {
+			r.log.Info("soak finished")
+			return nil
+		}
+		drainhelper.ReportProgress(ctx, soakMessage(remaining))
+
+		wait := soakReportInterval
+		if remaining < wait {
+			wait = remaining
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("soak interrupted, %s - %v", soakMessage(remaining), ctx.Err())
+		case <-daemonClock.After(wait):
+		}
+	}
+}
@@ -0,0 +1,186 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/drainhelper"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("Soak after configuration", func() {
+	var (
+		fakeClient                 client.Client
+		nodeNameRef                = types.NamespacedName{Name: "worker", Namespace: "testNamespace"}
+		drainer                    *drainhelper.FakeDrainer
+		configurations             int
+		originalSoakReportInterval = soakReportInterval
+	)
+
+	reconcile := func(ctx context.Context, soakSeconds int) *metav1.Condition {
+		nc := &sriovv2.SriovFecNodeConfig{}
+		Expect(fakeClient.Get(context.TODO(), nodeNameRef, nc)).To(Succeed())
+		nc.Spec.PostConfigureSoakSeconds = soakSeconds
+		Expect(fakeClient.Update(context.TODO(), nc)).To(Succeed())
+
+		reconciler, err := NewNodeConfigReconciler(fakeClient, drainer, nodeNameRef,
+			testConfigurerProto{configureNodeFunction: func(sriovv2.SriovFecNodeConfigSpec) error {
+				configurations++
+				return nil
+			}}, nil,
+			func(context.Context) error { return nil },
+			record.NewFakeRecorder(10), nil)
+		Expect(err).ToNot(HaveOccurred())
+		_, _ = reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: nodeNameRef})
+
+		Expect(fakeClient.Get(context.TODO(), nodeNameRef, nc)).To(Succeed())
+		return nc.FindCondition(ConditionConfigured)
+	}
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(sriovv2.AddToScheme(scheme)).To(Succeed())
+		Expect(vrbv1.AddToScheme(scheme)).To(Succeed())
+
+		configPath = "testdata/accelerators.json"
+		VrbconfigPath = "testdata/accelerators_vrb.json"
+		procCmdlineFilePath = "testdata/cmdline_test"
+		sysLockdownFilePath = filepath.Join(testTmpFolder, "lockdown")
+		Expect(os.WriteFile(sysLockdownFilePath, []byte("[none] integrity confidentiality"), 0644)).To(Succeed())
+
+		// requested VF is exposed once the node is configured
+		getSriovInventory = func(_ context.Context, log *logrus.Logger) (*sriovv2.NodeInventory, error) {
+			acc := sriovv2.SriovAccelerator{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, MaxVFs: 10}
+			if configurations > 0 {
+				acc.VFs = []sriovv2.VF{{PCIAddress: "0000:14:01.0", Driver: utils.IGB_UIO}}
+			}
+			return &sriovv2.NodeInventory{SriovAccelerators: []sriovv2.SriovAccelerator{acc}}, nil
+		}
+		VrbgetSriovInventory = func(_ context.Context, log *logrus.Logger) (*vrbv1.NodeInventory, error) {
+			return &vrbv1.NodeInventory{}, nil
+		}
+
+		fakeClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&sriovv2.SriovFecNodeConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace},
+				Spec: sriovv2.SriovFecNodeConfigSpec{
+					PhysicalFunctions: []sriovv2.PhysicalFunctionConfigExt{{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, VFDriver: utils.IGB_UIO, VFAmount: 1}},
+				},
+			},
+			&vrbv1.SriovVrbNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace}},
+		).Build()
+		drainer = &drainhelper.FakeDrainer{}
+		configurations = 0
+		soakReportInterval = 200 * time.Millisecond
+	})
+
+	AfterEach(func() {
+		getSriovInventory = GetSriovInventory
+		VrbgetSriovInventory = VrbGetSriovInventory
+		sysLockdownFilePath = "/sys/kernel/security/lockdown"
+		soakReportInterval = originalSoakReportInterval
+	})
+
+	It("does not soak by default", func() {
+		Expect(reconcile(context.TODO(), 0).Reason).To(Equal(string(ConfigurationSucceeded)))
+		Expect(drainer.Runs()).To(HaveLen(1))
+		Expect(drainer.Runs()[0].Uncordoned).To(BeTrue())
+		Expect(drainer.Runs()[0].Steps).ToNot(ContainElement(HavePrefix("soaking")))
+	})
+
+	It("uncordons the node and reports success once soak is over, reporting remaining time meanwhile", func() {
+		started := time.Now()
+		Expect(reconcile(context.TODO(), 1).Reason).To(Equal(string(ConfigurationSucceeded)))
+
+		Expect(time.Since(started)).To(BeNumerically(">=", time.Second))
+		Expect(drainer.Runs()).To(HaveLen(1))
+		Expect(drainer.Runs()[0].Uncordoned).To(BeTrue())
+		// each report renews the drain lease
+		steps := drainer.Runs()[0].Steps
+		Expect(steps[0]).To(Equal("node drained with pod eviction"))
+		Expect(len(steps[1:])).To(BeNumerically(">=", 4))
+		Expect(steps[1:]).To(HaveEach(Equal("soaking, 1s remaining")))
+	})
+
+	It("does not reconfigure the node when only soak is changed", func() {
+		Expect(reconcile(context.TODO(), 0).Reason).To(Equal(string(ConfigurationSucceeded)))
+		Expect(reconcile(context.TODO(), 1).Reason).To(Equal(string(ConfigurationSucceeded)))
+		Expect(configurations).To(Equal(1))
+		Expect(drainer.Runs()).To(HaveLen(1))
+	})
+
+	It("uncordons the node when soak is interrupted by shutdown", func() {
+		ctx, cancel := context.WithTimeout(context.TODO(), 300*time.Millisecond)
+		defer cancel()
+
+		condition := reconcile(ctx, 120)
+
+		Expect(condition.Reason).To(Equal(string(ConfigurationFailed)))
+		Expect(condition.Message).To(HavePrefix("soak interrupted, soaking, 120s remaining"))
+		Expect(drainer.Runs()).To(HaveLen(1))
+		Expect(drainer.Runs()[0].Uncordoned).To(BeTrue())
+	})
+})
+
+var _ = Describe("soak", func() {
+	var (
+		clk                 *fakeClock
+		originalDaemonClock = daemonClock
+	)
+
+	BeforeEach(func() {
+		clk = newFakeClock(time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC))
+		daemonClock = clk
+	})
+
+	AfterEach(func() {
+		daemonClock = originalDaemonClock
+	})
+
+	It("is measured in elapsed time regardless of clock jumps", func() {
+		var mu sync.Mutex
+		var steps []string
+		ctx := drainhelper.WithProgressReporter(context.TODO(), func(step string) {
+			mu.Lock()
+			defer mu.Unlock()
+			steps = append(steps, step)
+		})
+		reconciler := &NodeConfigReconciler{log: utils.NewLogger()}
+		done := make(chan error, 1)
+		go func() { done <- reconciler.soak(ctx, 30) }()
+
+		Eventually(clk.Waiters).Should(Equal(1))
+		clk.Jump(-time.Hour)
+		clk.Step(soakReportInterval)
+		Eventually(clk.Waiters).Should(Equal(1))
+		clk.Jump(24 * time.Hour)
+		clk.Step(soakReportInterval)
+		Eventually(clk.Waiters).Should(Equal(1))
+		Consistently(done).ShouldNot(Receive(), "soak is not cut short by clock stepped forward")
+
+		clk.Step(soakReportInterval)
+		Eventually(done).Should(Receive(BeNil()))
+		mu.Lock()
+		defer mu.Unlock()
+		Expect(steps).To(Equal([]string{"soaking, 30s remaining", "soaking, 20s remaining", "soaking, 10s remaining"}))
+	})
+})
@@ -0,0 +1,45 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// specDebouncer tracks since when the current generation of node config is observed, so that configuration can
+// be postponed until the spec settles
+type specDebouncer struct {
+	mu         sync.Mutex
+	generation int64
+	observedAt time.Time
+}
+
+// remaining returns how long configuration of given generation still has to wait; observing a new generation
+// restarts the wait
+func (d *specDebouncer) remaining(generation int64, debounce *metav1.Duration, now time.Time) time.Duration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if generation != d.generation || d.observedAt.IsZero() {
+		d.generation = generation
+		d.observedAt = now
+	}
+
+	if debounce == nil || debounce.Duration <= 0 {
+		return 0
+	}
+	if remaining := d.observedAt.Add(debounce.Duration).Sub(now); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+func specSettleMessage(remaining time.Duration) string {
+	return fmt.Sprintf("waiting for spec to settle (%ds remaining)", int64(math.Ceil(remaining.Seconds())))
+}
@@ -0,0 +1,166 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("SpecDebounce", func() {
+	Context("specDebouncer", func() {
+		now := time.Now()
+		debounce := &metav1.Duration{Duration: time.Minute}
+
+		It("should not wait when debounce is not set", func() {
+			d := &specDebouncer{}
+			Expect(d.remaining(1, nil, now)).To(BeZero())
+			Expect(d.remaining(2, &metav1.Duration{}, now)).To(BeZero())
+		})
+
+		It("should restart wait when new generation is observed", func() {
+			d := &specDebouncer{}
+			Expect(d.remaining(1, debounce, now)).To(Equal(time.Minute))
+			Expect(d.remaining(1, debounce, now.Add(40*time.Second))).To(Equal(20 * time.Second))
+			Expect(d.remaining(2, debounce, now.Add(50*time.Second))).To(Equal(time.Minute))
+			Expect(d.remaining(2, debounce, now.Add(100*time.Second))).To(Equal(10 * time.Second))
+			Expect(d.remaining(2, debounce, now.Add(110*time.Second))).To(BeZero())
+			Expect(d.remaining(2, debounce, now.Add(200*time.Second))).To(BeZero())
+		})
+
+		It("should report remaining time rounded up to seconds", func() {
+			Expect(specSettleMessage(1500 * time.Millisecond)).To(Equal("waiting for spec to settle (2s remaining)"))
+		})
+	})
+
+	Context("NodeConfigReconciler.Reconcile", func() {
+		var (
+			fakeClient  client.Client
+			nodeNameRef = types.NamespacedName{Name: "worker", Namespace: "testNamespace"}
+			reconciler  *NodeConfigReconciler
+			drained     bool
+		)
+
+		BeforeEach(func() {
+			scheme := runtime.NewScheme()
+			Expect(sriovv2.AddToScheme(scheme)).ToNot(HaveOccurred())
+			Expect(vrbv1.AddToScheme(scheme)).ToNot(HaveOccurred())
+
+			procCmdlineFilePath = "testdata/cmdline_test"
+			sysLockdownFilePath = filepath.Join(testTmpFolder, "lockdown")
+			Expect(os.WriteFile(sysLockdownFilePath, []byte("[none] integrity confidentiality"), 0644)).To(Succeed())
+			drained = false
+
+			getSriovInventory = func(_ context.Context, log *logrus.Logger) (*sriovv2.NodeInventory, error) {
+				return &sriovv2.NodeInventory{
+					SriovAccelerators: []sriovv2.SriovAccelerator{{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, MaxVFs: 10}},
+				}, nil
+			}
+			VrbgetSriovInventory = func(_ context.Context, log *logrus.Logger) (*vrbv1.NodeInventory, error) {
+				return &vrbv1.NodeInventory{}, nil
+			}
+
+			sfnc := &sriovv2.SriovFecNodeConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace, Generation: 1},
+				Spec: sriovv2.SriovFecNodeConfigSpec{
+					PhysicalFunctions:     []sriovv2.PhysicalFunctionConfigExt{{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, VFDriver: utils.IGB_UIO, VFAmount: 1}},
+					ConfigurationDebounce: &metav1.Duration{Duration: time.Minute},
+				},
+			}
+			vrbnc := &vrbv1.SriovVrbNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace}}
+			fakeClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(sfnc, vrbnc).Build()
+
+			reconciler = &NodeConfigReconciler{
+				Client:      fakeClient,
+				log:         utils.NewLogger(),
+				nodeNameRef: nodeNameRef,
+				sriovfecconfigurer: testConfigurerProto{configureNodeFunction: func(sriovv2.SriovFecNodeConfigSpec) error {
+					return nil
+				}},
+				drainerAndExecute: func(_ context.Context, configurer func(ctx context.Context) bool, drain bool) error {
+					drained = true
+					_ = configurer(context.TODO())
+					return nil
+				},
+				restartDevicePlugin: func(context.Context) error { return nil },
+			}
+		})
+
+		AfterEach(func() {
+			getSriovInventory = GetSriovInventory
+			VrbgetSriovInventory = VrbGetSriovInventory
+			sysLockdownFilePath = "/sys/kernel/security/lockdown"
+		})
+
+		configuredCondition := func() *metav1.Condition {
+			sfnc := new(sriovv2.SriovFecNodeConfig)
+			Expect(fakeClient.Get(context.TODO(), nodeNameRef, sfnc)).To(Succeed())
+			return sfnc.FindCondition(ConditionConfigured)
+		}
+
+		It("should configure node only once spec settles", func() {
+			result, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: nodeNameRef})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically("~", time.Minute, time.Second))
+			Expect(drained).To(BeFalse())
+			Expect(configuredCondition().Reason).To(Equal(string(ConfigurationInProgress)))
+			Expect(configuredCondition().Message).To(Equal("waiting for spec to settle (60s remaining)"))
+
+			// further change arrives during the wait
+			sfnc := new(sriovv2.SriovFecNodeConfig)
+			Expect(fakeClient.Get(context.TODO(), nodeNameRef, sfnc)).To(Succeed())
+			sfnc.Spec.PhysicalFunctions[0].VFAmount = 2
+			sfnc.Generation++
+			Expect(fakeClient.Update(context.TODO(), sfnc)).To(Succeed())
+			reconciler.fecSpecDebouncer.observedAt = time.Now().Add(-50 * time.Second)
+
+			result, err = reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: nodeNameRef})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically("~", time.Minute, time.Second))
+			Expect(drained).To(BeFalse())
+
+			reconciler.fecSpecDebouncer.observedAt = time.Now().Add(-time.Minute)
+			_, err = reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: nodeNameRef})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(drained).To(BeTrue())
+			Expect(configuredCondition().Reason).To(Equal(string(ConfigurationSucceeded)))
+			Expect(configuredCondition().ObservedGeneration).To(BeEquivalentTo(2))
+		})
+
+		It("should not reconfigure node when only debounce changes", func() {
+			reconciler.fecSpecDebouncer.observedAt = time.Now().Add(-time.Minute)
+			reconciler.fecSpecDebouncer.generation = 1
+			_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: nodeNameRef})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(drained).To(BeTrue())
+
+			drained = false
+			sfnc := new(sriovv2.SriovFecNodeConfig)
+			Expect(fakeClient.Get(context.TODO(), nodeNameRef, sfnc)).To(Succeed())
+			sfnc.Spec.ConfigurationDebounce = &metav1.Duration{Duration: time.Hour}
+			sfnc.Generation++
+			Expect(fakeClient.Update(context.TODO(), sfnc)).To(Succeed())
+
+			_, err = reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: nodeNameRef})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(drained).To(BeFalse())
+		})
+	})
+})
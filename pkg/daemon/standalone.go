@@ -0,0 +1,521 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	yamlutil "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/yaml"
+
+	fec "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/conditions"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/drainhelper"
+)
+
+// Standalone mode runs single configuration of the node outside of a pod, e.g. in bare-metal bring-up labs. Node
+// configs are kept in memory: they are read from a local file or once from API server, and status written by the
+// reconciler is printed instead of being updated in API server. The node is neither drained nor written to.
+
+var errStandaloneWrite = errors.New("objects other than node configs are not written in standalone mode")
+
+// LoadLocalSpec reads SriovFecNodeConfig and SriovVrbNodeConfig manifests, separated with "---", from YAML file at
+// path. Unknown fields are rejected. Name and namespace of node configs are the ones of the node, as the daemon
+// configures only node configs of its node; status of the manifests is ignored.
+func LoadLocalSpec(path string, nodeNameRef types.NamespacedName) ([]client.Object, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open local spec - %v", err)
+	}
+	defer f.Close()
+	return parseLocalSpec(f, nodeNameRef)
+}
+
+func parseLocalSpec(r io.Reader, nodeNameRef types.NamespacedName) ([]client.Object, error) {
+	reader := yamlutil.NewYAMLReader(bufio.NewReader(r))
+	var nodeConfigs []client.Object
+	kinds := map[string]bool{}
+	for document := 1; ; document++ {
+		data, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read document %d of local spec - %v", document, err)
+		}
+		var content map[string]interface{}
+		if err := yaml.Unmarshal(data, &content); err != nil {
+			return nil, fmt.Errorf("invalid document %d of local spec - %v", document, err)
+		}
+		if len(content) == 0 {
+			continue
+		}
+
+		var typeMeta metav1.TypeMeta
+		if err := yaml.Unmarshal(data, &typeMeta); err != nil {
+			return nil, fmt.Errorf("invalid document %d of local spec - %v", document, err)
+		}
+		var nc client.Object
+		switch typeMeta.GroupVersionKind() {
+		case fec.GroupVersion.WithKind("SriovFecNodeConfig"):
+			nc = &fec.SriovFecNodeConfig{}
+		case vrbv1.GroupVersion.WithKind("SriovVrbNodeConfig"):
+			nc = &vrbv1.SriovVrbNodeConfig{}
+		default:
+			return nil, fmt.Errorf("document %d of local spec is %s %s - only %s SriovFecNodeConfig and %s SriovVrbNodeConfig are supported",
+				document, typeMeta.APIVersion, typeMeta.Kind, fec.GroupVersion, vrbv1.GroupVersion)
+		}
+		if kinds[typeMeta.Kind] {
+			return nil, fmt.Errorf("local spec contains more than one %s", typeMeta.Kind)
+		}
+		kinds[typeMeta.Kind] = true
+		if err := yaml.UnmarshalStrict(data, nc); err != nil {
+			return nil, fmt.Errorf("invalid %s in local spec - %v", typeMeta.Kind, err)
+		}
+
+		nc.SetName(nodeNameRef.Name)
+		nc.SetNamespace(nodeNameRef.Namespace)
+		nc.SetGeneration(1)
+		switch nc := nc.(type) {
+		case *fec.SriovFecNodeConfig:
+			nc.Status = fec.SriovFecNodeConfigStatus{}
+		case *vrbv1.SriovVrbNodeConfig:
+			nc.Status = vrbv1.SriovVrbNodeConfigStatus{}
+		}
+		normalizePCIAddressesOf(nc)
+		nodeConfigs = append(nodeConfigs, nc)
+	}
+	if len(nodeConfigs) == 0 {
+		return nil, errors.New("local spec contains no node config")
+	}
+	return nodeConfigs, nil
+}
+
+// ReadNodeConfigs reads node configs of the node from API server; node configs which do not exist are skipped
+func ReadNodeConfigs(ctx context.Context, c client.Reader, nodeNameRef types.NamespacedName) ([]client.Object, error) {
+	var nodeConfigs []client.Object
+	for _, nc := range []client.Object{&fec.SriovFecNodeConfig{}, &vrbv1.SriovVrbNodeConfig{}} {
+		if err := c.Get(ctx, nodeNameRef, nc); err != nil {
+			if k8serrors.IsNotFound(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read node config - %v", err)
+		}
+		nodeConfigs = append(nodeConfigs, nc)
+	}
+	return nodeConfigs, nil
+}
+
+// standaloneClient keeps node configs in memory, so that the reconciler runs unchanged without writing them to API
+// server. Other objects are read from API server when it is configured and are not found otherwise; they are never
+// written.
+type standaloneClient struct {
+	api    client.Client
+	scheme *runtime.Scheme
+
+	mu          sync.Mutex
+	nodeConfigs map[schema.GroupVersionKind]map[types.NamespacedName]client.Object
+}
+
+// NewStandaloneClient returns client of standalone mode holding given node configs; api is optional
+func NewStandaloneClient(api client.Client, scheme *runtime.Scheme, nodeConfigs ...client.Object) (client.Client, error) {
+	c := &standaloneClient{api: api, scheme: scheme, nodeConfigs: map[schema.GroupVersionKind]map[types.NamespacedName]client.Object{}}
+	for _, nc := range nodeConfigs {
+		if err := c.Create(context.TODO(), nc.DeepCopyObject().(client.Object)); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// nodeConfigGVK returns kind of node config (list), or false for other objects
+func (c *standaloneClient) nodeConfigGVK(obj runtime.Object) (schema.GroupVersionKind, bool) {
+	switch obj.(type) {
+	case *fec.SriovFecNodeConfig, *fec.SriovFecNodeConfigList:
+		return fec.GroupVersion.WithKind("SriovFecNodeConfig"), true
+	case *vrbv1.SriovVrbNodeConfig, *vrbv1.SriovVrbNodeConfigList:
+		return vrbv1.GroupVersion.WithKind("SriovVrbNodeConfig"), true
+	}
+	return schema.GroupVersionKind{}, false
+}
+
+func (c *standaloneClient) notFound(obj runtime.Object, name string) error {
+	gvk, err := apiutil.GVKForObject(obj, c.scheme)
+	if err != nil {
+		return err
+	}
+	return k8serrors.NewNotFound(schema.GroupResource{Group: gvk.Group, Resource: gvk.Kind}, name)
+}
+
+// copyNodeConfig deep copies node config of any kind into node config of the same kind
+func copyNodeConfig(from, to client.Object) {
+	switch to := to.(type) {
+	case *fec.SriovFecNodeConfig:
+		from.(*fec.SriovFecNodeConfig).DeepCopyInto(to)
+	case *vrbv1.SriovVrbNodeConfig:
+		from.(*vrbv1.SriovVrbNodeConfig).DeepCopyInto(to)
+	}
+}
+
+func specOf(nc client.Object) interface{} {
+	switch nc := nc.(type) {
+	case *fec.SriovFecNodeConfig:
+		return nc.Spec
+	case *vrbv1.SriovVrbNodeConfig:
+		return nc.Spec
+	}
+	return nil
+}
+
+func (c *standaloneClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	gvk, ok := c.nodeConfigGVK(obj)
+	if !ok {
+		if c.api == nil {
+			return c.notFound(obj, key.Name)
+		}
+		return c.api.Get(ctx, key, obj, opts...)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stored, ok := c.nodeConfigs[gvk][key]
+	if !ok {
+		return c.notFound(obj, key.Name)
+	}
+	copyNodeConfig(stored, obj)
+	return nil
+}
+
+func (c *standaloneClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	gvk, ok := c.nodeConfigGVK(list)
+	if !ok {
+		if c.api == nil {
+			return nil
+		}
+		return c.api.List(ctx, list, opts...)
+	}
+	listOpts := (&client.ListOptions{}).ApplyOptions(opts)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var items []runtime.Object
+	for key, stored := range c.nodeConfigs[gvk] {
+		if listOpts.Namespace == "" || listOpts.Namespace == key.Namespace {
+			items = append(items, stored.DeepCopyObject())
+		}
+	}
+	return meta.SetList(list, items)
+}
+
+func (c *standaloneClient) Create(_ context.Context, obj client.Object, _ ...client.CreateOption) error {
+	gvk, ok := c.nodeConfigGVK(obj)
+	if !ok {
+		return errStandaloneWrite
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := client.ObjectKeyFromObject(obj)
+	if _, exists := c.nodeConfigs[gvk][key]; exists {
+		return k8serrors.NewAlreadyExists(schema.GroupResource{Group: gvk.Group, Resource: gvk.Kind}, key.Name)
+	}
+	if obj.GetGeneration() == 0 {
+		obj.SetGeneration(1)
+	}
+	if c.nodeConfigs[gvk] == nil {
+		c.nodeConfigs[gvk] = map[types.NamespacedName]client.Object{}
+	}
+	c.nodeConfigs[gvk][key] = obj.DeepCopyObject().(client.Object)
+	return nil
+}
+
+func (c *standaloneClient) Delete(_ context.Context, obj client.Object, _ ...client.DeleteOption) error {
+	gvk, ok := c.nodeConfigGVK(obj)
+	if !ok {
+		return errStandaloneWrite
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := client.ObjectKeyFromObject(obj)
+	if _, exists := c.nodeConfigs[gvk][key]; !exists {
+		return c.notFound(obj, key.Name)
+	}
+	delete(c.nodeConfigs[gvk], key)
+	return nil
+}
+
+// Update replaces stored node config; generation is bumped when its spec changes, as API server does
+func (c *standaloneClient) Update(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
+	return c.store(obj, false)
+}
+
+// Patch of node config is applied as Update, since obj carries the patched object in full
+func (c *standaloneClient) Patch(_ context.Context, obj client.Object, _ client.Patch, _ ...client.PatchOption) error {
+	return c.store(obj, false)
+}
+
+func (c *standaloneClient) DeleteAllOf(context.Context, client.Object, ...client.DeleteAllOfOption) error {
+	return errStandaloneWrite
+}
+
+func (c *standaloneClient) Status() client.StatusWriter {
+	return standaloneStatusWriter{c}
+}
+
+func (c *standaloneClient) Scheme() *runtime.Scheme {
+	return c.scheme
+}
+
+func (c *standaloneClient) RESTMapper() meta.RESTMapper {
+	if c.api == nil {
+		return nil
+	}
+	return c.api.RESTMapper()
+}
+
+// store replaces stored node config with obj; only status is replaced when statusOnly is set
+func (c *standaloneClient) store(obj client.Object, statusOnly bool) error {
+	gvk, ok := c.nodeConfigGVK(obj)
+	if !ok {
+		return errStandaloneWrite
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := client.ObjectKeyFromObject(obj)
+	stored, exists := c.nodeConfigs[gvk][key]
+	if !exists {
+		return c.notFound(obj, key.Name)
+	}
+
+	updated := obj.DeepCopyObject().(client.Object)
+	if statusOnly {
+		updated = stored.DeepCopyObject().(client.Object)
+		switch updated := updated.(type) {
+		case *fec.SriovFecNodeConfig:
+			obj.(*fec.SriovFecNodeConfig).Status.DeepCopyInto(&updated.Status)
+		case *vrbv1.SriovVrbNodeConfig:
+			obj.(*vrbv1.SriovVrbNodeConfig).Status.DeepCopyInto(&updated.Status)
+		}
+	} else if !equality.Semantic.DeepEqual(specOf(stored), specOf(updated)) {
+		updated.SetGeneration(stored.GetGeneration() + 1)
+	} else {
+		updated.SetGeneration(stored.GetGeneration())
+	}
+	c.nodeConfigs[gvk][key] = updated
+	copyNodeConfig(updated, obj)
+	return nil
+}
+
+// standaloneStatusWriter replaces status of node configs kept by standaloneClient
+type standaloneStatusWriter struct {
+	c *standaloneClient
+}
+
+func (w standaloneStatusWriter) Update(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
+	return w.c.store(obj, true)
+}
+
+func (w standaloneStatusWriter) Patch(_ context.Context, obj client.Object, _ client.Patch, _ ...client.PatchOption) error {
+	return w.c.store(obj, true)
+}
+
+// standaloneDrainer executes configuration without cordoning and draining the node
+type standaloneDrainer struct{}
+
+// NewStandaloneDrainer returns drainer of standalone mode, which neither cordons nor drains the node
+func NewStandaloneDrainer() drainhelper.Drainer {
+	return standaloneDrainer{}
+}
+
+func (standaloneDrainer) Run(ctx context.Context, f func(context.Context) bool, _ bool) error {
+	f(ctx)
+	return nil
+}
+
+func (standaloneDrainer) VerifyRescheduling(context.Context) *drainhelper.ReschedulingSummary {
+	return nil
+}
+
+// standaloneRecorder logs events instead of emitting them
+type standaloneRecorder struct {
+	log *logrus.Logger
+}
+
+// NewStandaloneRecorder returns event recorder of standalone mode, which logs events
+func NewStandaloneRecorder(log *logrus.Logger) record.EventRecorder {
+	return standaloneRecorder{log: log}
+}
+
+func (r standaloneRecorder) Event(_ runtime.Object, eventtype, reason, message string) {
+	entry := r.log.WithField("reason", reason)
+	if eventtype == corev1.EventTypeWarning {
+		entry.Warn(message)
+		return
+	}
+	entry.Info(message)
+}
+
+func (r standaloneRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.Event(object, eventtype, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+func (r standaloneRecorder) AnnotatedEventf(object runtime.Object, _ map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.Eventf(object, eventtype, reason, messageFmt, args...)
+}
+
+// RestartDevicePluginInStandalone is RestartDevicePluginFunction of standalone mode; device plugin pod is not
+// restarted, as nothing is written to API server
+func RestartDevicePluginInStandalone(log *logrus.Logger) RestartDevicePluginFunction {
+	return func(context.Context) error {
+		log.Info("device plugin is not restarted in standalone mode - restart it to advertise the configured VFs")
+		return nil
+	}
+}
+
+// standaloneMaxReconciles bounds reconciles performed by RunStandalone; the node is configured within a few of them,
+// e.g. FEC and VRB node configs in one each
+const standaloneMaxReconciles = 10
+
+// RunStandalone configures the node according to its node configs and writes them, with resulting status, to out as
+// YAML documents. Node is reconciled until every node config is configured at its generation or its configuration
+// ended otherwise, at most standaloneMaxReconciles times. Reconcile which changed status of a node config is followed
+// by the next one right away, as the watch of the daemon would do; delay requested by reconcile (e.g. until the spec
+// settles) is waited for otherwise. Failed configuration is not retried; error is returned when it failed, i.e. a node
+// config is not Available, after the node configs are written.
+func (r *NodeConfigReconciler) RunStandalone(ctx context.Context, out io.Writer) error {
+	_, state, err := r.standaloneProgress(ctx)
+	if err != nil {
+		return err
+	}
+	for reconciles := 1; ; reconciles++ {
+		var result ctrl.Result
+		result, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: r.nodeConfigKey()})
+		settled, reconciledState, progressErr := r.standaloneProgress(ctx)
+		if progressErr != nil {
+			return progressErr
+		}
+		if settled {
+			break
+		}
+		if reconciles == standaloneMaxReconciles {
+			if err == nil {
+				err = fmt.Errorf("configuration did not complete within %d reconciles", standaloneMaxReconciles)
+			}
+			break
+		}
+		if reconciledState == state && result.RequeueAfter > 0 {
+			r.log.WithField("after", result.RequeueAfter).Info("waiting for the next reconcile")
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(result.RequeueAfter):
+			}
+		}
+		state = reconciledState
+	}
+
+	var documents [][]byte
+	for _, nc := range []client.Object{&fec.SriovFecNodeConfig{}, &vrbv1.SriovVrbNodeConfig{}} {
+		if getErr := r.Get(ctx, r.nodeConfigKey(), nc); getErr != nil {
+			if k8serrors.IsNotFound(getErr) {
+				continue
+			}
+			return fmt.Errorf("failed to read node config - %v", getErr)
+		}
+		gvk, gvkErr := apiutil.GVKForObject(nc, r.Scheme())
+		if gvkErr != nil {
+			return gvkErr
+		}
+		nc.GetObjectKind().SetGroupVersionKind(gvk)
+		nc.SetManagedFields(nil)
+		data, marshalErr := yaml.Marshal(nc)
+		if marshalErr != nil {
+			return fmt.Errorf("failed to print node config - %v", marshalErr)
+		}
+		documents = append(documents, data)
+
+		if available := meta.FindStatusCondition(standaloneConditionsOf(nc), conditions.TypeAvailable); err == nil &&
+			(available == nil || available.Status != metav1.ConditionTrue) {
+			err = fmt.Errorf("%s is not available - %s", gvk.Kind, standaloneReasonOf(available))
+		}
+	}
+	if _, writeErr := out.Write(bytes.Join(documents, []byte("---\n"))); writeErr != nil {
+		return fmt.Errorf("failed to print node configs - %v", writeErr)
+	}
+	return err
+}
+
+// standaloneProgress tells whether configuration of every node config of the node has ended, i.e. it is not in
+// progress anymore and the Configured condition observed the generation unless it failed. State of the
+// Configured conditions is returned as well, so that progress made by a reconcile is seen.
+func (r *NodeConfigReconciler) standaloneProgress(ctx context.Context) (settled bool, state string, err error) {
+	settled = true
+	for _, nc := range []client.Object{&fec.SriovFecNodeConfig{}, &vrbv1.SriovVrbNodeConfig{}} {
+		if err := r.Get(ctx, r.nodeConfigKey(), nc); err != nil {
+			if k8serrors.IsNotFound(err) {
+				continue
+			}
+			return false, "", fmt.Errorf("failed to read node config - %v", err)
+		}
+		configured := meta.FindStatusCondition(standaloneConditionsOf(nc), ConditionConfigured)
+		if configured == nil {
+			// node config which requests nothing is not configured until the other one changes
+			settled = settled && !standaloneRequestsPFs(nc)
+			continue
+		}
+		state += fmt.Sprintf("%d/%s/%s;", configured.ObservedGeneration, configured.Reason, configured.Message)
+		// failed configuration does not report generation it failed with
+		if configured.Reason == string(ConfigurationInProgress) ||
+			(configured.Status == metav1.ConditionTrue && configured.ObservedGeneration != nc.GetGeneration()) {
+			settled = false
+		}
+	}
+	return settled, state, nil
+}
+
+func standaloneConditionsOf(nc client.Object) []metav1.Condition {
+	switch nc := nc.(type) {
+	case *fec.SriovFecNodeConfig:
+		return nc.Status.Conditions
+	case *vrbv1.SriovVrbNodeConfig:
+		return nc.Status.Conditions
+	}
+	return nil
+}
+
+func standaloneRequestsPFs(nc client.Object) bool {
+	switch nc := nc.(type) {
+	case *fec.SriovFecNodeConfig:
+		return len(nc.Spec.PhysicalFunctions) > 0
+	case *vrbv1.SriovVrbNodeConfig:
+		return len(nc.Spec.PhysicalFunctions) > 0
+	}
+	return false
+}
+
+func standaloneReasonOf(available *metav1.Condition) string {
+	if available == nil {
+		return "configuration was not attempted"
+	}
+	return available.Reason + ": " + available.Message
+}
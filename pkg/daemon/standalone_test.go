@@ -0,0 +1,298 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("Standalone mode", func() {
+	nodeNameRef := types.NamespacedName{Name: "lab-host", Namespace: "testNamespace"}
+
+	const localSpec = `
+apiVersion: sriovfec.intel.com/v2
+kind: SriovFecNodeConfig
+metadata:
+  name: ignored
+spec:
+  physicalFunctions:
+  - pciAddress: 14:00.1
+    pfDriver: pci-pf-stub
+    vfDriver: igb_uio
+    vfAmount: 1
+status:
+  conditions:
+  - type: Configured
+    status: "True"
+    reason: Succeeded
+    message: ignored
+    lastTransitionTime: "2023-05-01T12:00:00Z"
+---
+apiVersion: sriovvrb.intel.com/v1
+kind: SriovVrbNodeConfig
+spec:
+  physicalFunctions:
+  - pciAddress: 0000:F7:00.0
+    pfDriver: vfio-pci
+    vfDriver: vfio-pci
+    vfAmount: 2
+`
+
+	parse := func(spec string) ([]client.Object, error) {
+		return parseLocalSpec(strings.NewReader(spec), nodeNameRef)
+	}
+
+	Context("local spec", func() {
+		It("is read from file", func() {
+			path := filepath.Join(testTmpFolder, "local-spec.yaml")
+			Expect(os.WriteFile(path, []byte(localSpec), 0644)).To(Succeed())
+
+			nodeConfigs, err := LoadLocalSpec(path, nodeNameRef)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(nodeConfigs).To(HaveLen(2))
+
+			_, err = LoadLocalSpec(filepath.Join(testTmpFolder, "missing.yaml"), nodeNameRef)
+			Expect(err).To(MatchError(HavePrefix("failed to open local spec")))
+		})
+
+		It("provides node configs of the node with normalized PCI addresses and no status", func() {
+			nodeConfigs, err := parse(localSpec)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(nodeConfigs).To(HaveLen(2))
+
+			sfnc, ok := nodeConfigs[0].(*sriovv2.SriovFecNodeConfig)
+			Expect(ok).To(BeTrue())
+			Expect(sfnc.Name).To(Equal(nodeNameRef.Name))
+			Expect(sfnc.Namespace).To(Equal(nodeNameRef.Namespace))
+			Expect(sfnc.Generation).To(Equal(int64(1)))
+			Expect(sfnc.Spec.PhysicalFunctions[0].PCIAddress).To(Equal("0000:14:00.1"))
+			Expect(sfnc.Spec.PhysicalFunctions[0].VFAmount).To(Equal(1))
+			Expect(sfnc.Status.Conditions).To(BeEmpty())
+
+			vrbnc, ok := nodeConfigs[1].(*vrbv1.SriovVrbNodeConfig)
+			Expect(ok).To(BeTrue())
+			Expect(vrbnc.Name).To(Equal(nodeNameRef.Name))
+			Expect(vrbnc.Spec.PhysicalFunctions[0].PCIAddress).To(Equal("0000:f7:00.0"))
+		})
+
+		It("rejects unsupported kinds", func() {
+			_, err := parse("apiVersion: v1\nkind: ConfigMap\n")
+			Expect(err).To(MatchError(ContainSubstring("document 1 of local spec is v1 ConfigMap")))
+
+			_, err = parse("apiVersion: sriovfec.intel.com/v2\nkind: SriovFecClusterConfig\n")
+			Expect(err).To(MatchError(ContainSubstring("SriovFecClusterConfig - only")))
+		})
+
+		It("rejects unknown fields", func() {
+			_, err := parse("apiVersion: sriovfec.intel.com/v2\nkind: SriovFecNodeConfig\nspec:\n  physicalFunction: []\n")
+			Expect(err).To(MatchError(And(HavePrefix("invalid SriovFecNodeConfig in local spec"), ContainSubstring("physicalFunction"))))
+		})
+
+		It("rejects invalid YAML", func() {
+			_, err := parse("apiVersion: sriovfec.intel.com/v2\nkind: [SriovFecNodeConfig\n")
+			Expect(err).To(MatchError(HavePrefix("invalid document 1 of local spec")))
+		})
+
+		It("rejects more node configs of the same kind", func() {
+			doc := "apiVersion: sriovfec.intel.com/v2\nkind: SriovFecNodeConfig\n"
+			_, err := parse(doc + "---\n" + doc)
+			Expect(err).To(MatchError("local spec contains more than one SriovFecNodeConfig"))
+		})
+
+		It("rejects spec without node configs", func() {
+			_, err := parse("# nothing to configure\n---\n")
+			Expect(err).To(MatchError("local spec contains no node config"))
+		})
+	})
+
+	Context("run", func() {
+		var (
+			pfBBConfigErr error
+			out           *bytes.Buffer
+			logs          *bytes.Buffer
+		)
+
+		run := func(nodeConfigs ...client.Object) (client.Client, error) {
+			scheme := runtime.NewScheme()
+			Expect(sriovv2.AddToScheme(scheme)).To(Succeed())
+			Expect(vrbv1.AddToScheme(scheme)).To(Succeed())
+			Expect(corev1.AddToScheme(scheme)).To(Succeed())
+
+			c, err := NewStandaloneClient(nil, scheme, nodeConfigs...)
+			Expect(err).ToNot(HaveOccurred())
+			log := logrus.New()
+			log.SetOutput(logs)
+			configurer := pfBBConfigOutcomeConfigurer{&pfBBConfigErr}
+			reconciler, err := NewNodeConfigReconciler(c, NewStandaloneDrainer(), nodeNameRef, configurer, configurer,
+				RestartDevicePluginInStandalone(log), NewStandaloneRecorder(log), nil)
+			Expect(err).ToNot(HaveOccurred())
+			return c, reconciler.RunStandalone(context.TODO(), out)
+		}
+
+		printed := func() (*sriovv2.SriovFecNodeConfig, *vrbv1.SriovVrbNodeConfig) {
+			documents := strings.Split(out.String(), "---\n")
+			Expect(documents).To(HaveLen(2))
+			sfnc, vrbnc := &sriovv2.SriovFecNodeConfig{}, &vrbv1.SriovVrbNodeConfig{}
+			Expect(yaml.UnmarshalStrict([]byte(documents[0]), sfnc)).To(Succeed())
+			Expect(yaml.UnmarshalStrict([]byte(documents[1]), vrbnc)).To(Succeed())
+			return sfnc, vrbnc
+		}
+
+		BeforeEach(func() {
+			configPath = "testdata/accelerators.json"
+			VrbconfigPath = "testdata/accelerators_vrb.json"
+			procCmdlineFilePath = "testdata/cmdline_test"
+			sysLockdownFilePath = filepath.Join(testTmpFolder, "lockdown")
+			Expect(os.WriteFile(sysLockdownFilePath, []byte("[none] integrity confidentiality"), 0644)).To(Succeed())
+
+			getSriovInventory = func(_ context.Context, log *logrus.Logger) (*sriovv2.NodeInventory, error) {
+				return &sriovv2.NodeInventory{
+					SriovAccelerators: []sriovv2.SriovAccelerator{{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, MaxVFs: 10}},
+				}, nil
+			}
+			VrbgetSriovInventory = func(_ context.Context, log *logrus.Logger) (*vrbv1.NodeInventory, error) {
+				return &vrbv1.NodeInventory{}, nil
+			}
+			pfBBConfigErr = nil
+			out, logs = &bytes.Buffer{}, &bytes.Buffer{}
+		})
+
+		AfterEach(func() {
+			getSriovInventory = GetSriovInventory
+			VrbgetSriovInventory = VrbGetSriovInventory
+			sysLockdownFilePath = "/sys/kernel/security/lockdown"
+		})
+
+		It("configures the node once and prints status of node configs", func() {
+			nodeConfigs, err := parse(localSpec)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = run(nodeConfigs[0])
+			Expect(err).ToNot(HaveOccurred())
+
+			sfnc, vrbnc := printed()
+			Expect(sfnc.Kind).To(Equal("SriovFecNodeConfig"))
+			Expect(sfnc.APIVersion).To(Equal(sriovv2.GroupVersion.String()))
+			Expect(sfnc.Name).To(Equal(nodeNameRef.Name))
+			Expect(sfnc.Spec.PhysicalFunctions[0].PCIAddress).To(Equal(pciAddress))
+			configured := sfnc.FindCondition(ConditionConfigured)
+			Expect(configured).ToNot(BeNil())
+			Expect(configured.Reason).To(Equal(string(ConfigurationSucceeded)))
+			Expect(configured.ObservedGeneration).To(Equal(int64(1)))
+			Expect(sfnc.Status.Inventory.SriovAccelerators).To(HaveLen(1))
+
+			Expect(vrbnc.Kind).To(Equal("SriovVrbNodeConfig"), "missing node config is created in memory, as in the cluster")
+			Expect(vrbnc.Spec.PhysicalFunctions).To(BeEmpty())
+		})
+
+		It("configures node configs of both kinds", func() {
+			// inventory reflects configured VFs, so that each node config is configured once
+			getSriovInventory = func(_ context.Context, log *logrus.Logger) (*sriovv2.NodeInventory, error) {
+				return &sriovv2.NodeInventory{
+					SriovAccelerators: []sriovv2.SriovAccelerator{{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, MaxVFs: 10,
+						VFs: []sriovv2.VF{{PCIAddress: "0000:14:00.2", Driver: utils.IGB_UIO}}}},
+				}, nil
+			}
+			VrbgetSriovInventory = func(_ context.Context, log *logrus.Logger) (*vrbv1.NodeInventory, error) {
+				return &vrbv1.NodeInventory{
+					SriovAccelerators: []vrbv1.SriovAccelerator{{PCIAddress: "0000:f7:00.0", PFDriver: utils.VFIO_PCI, MaxVFs: 16,
+						VFs: []vrbv1.VF{{PCIAddress: "0000:f7:00.1", Driver: utils.VFIO_PCI}, {PCIAddress: "0000:f7:00.2", Driver: utils.VFIO_PCI}}}},
+				}, nil
+			}
+			nodeConfigs, err := parse(localSpec)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = run(nodeConfigs...)
+			Expect(err).ToNot(HaveOccurred())
+
+			sfnc, vrbnc := printed()
+			Expect(sfnc.FindCondition(ConditionConfigured).Reason).To(Equal(string(ConfigurationSucceeded)))
+			Expect(vrbnc.Spec.PhysicalFunctions).To(HaveLen(1))
+			configured := vrbnc.FindCondition(ConditionConfigured)
+			Expect(configured).ToNot(BeNil())
+			Expect(configured.Reason).To(Equal(string(ConfigurationSucceeded)))
+			Expect(configured.ObservedGeneration).To(Equal(int64(1)))
+		})
+
+		It("waits for the spec to settle before configuring", func() {
+			_, err := run(&sriovv2.SriovFecNodeConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace, Generation: 1},
+				Spec: sriovv2.SriovFecNodeConfigSpec{
+					ConfigurationDebounce: &metav1.Duration{Duration: 100 * time.Millisecond},
+					PhysicalFunctions:     []sriovv2.PhysicalFunctionConfigExt{{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, VFDriver: utils.IGB_UIO, VFAmount: 1}},
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			sfnc, _ := printed()
+			Expect(sfnc.FindCondition(ConditionConfigured).Reason).To(Equal(string(ConfigurationSucceeded)))
+		})
+
+		It("prints status of failed configuration and returns its error", func() {
+			pfBBConfigErr = errors.New("pf_bb_config failed")
+
+			_, err := run(&sriovv2.SriovFecNodeConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace, Generation: 1},
+				Spec: sriovv2.SriovFecNodeConfigSpec{
+					PhysicalFunctions: []sriovv2.PhysicalFunctionConfigExt{{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, VFDriver: utils.IGB_UIO, VFAmount: 1}},
+				},
+			})
+			Expect(err).To(MatchError(And(HavePrefix("SriovFecNodeConfig is not available - Failed: "), ContainSubstring("pf_bb_config failed"))))
+
+			sfnc, _ := printed()
+			Expect(sfnc.FindCondition(ConditionConfigured).Reason).To(Equal(string(ConfigurationFailed)))
+		})
+
+		It("logs events instead of emitting them", func() {
+			log := logrus.New()
+			log.SetOutput(logs)
+			NewStandaloneRecorder(log).Eventf(&sriovv2.SriovFecNodeConfig{}, corev1.EventTypeWarning, "RebootRequested", "PF %s", pciAddress)
+			Expect(logs.String()).To(And(ContainSubstring("level=warning"), ContainSubstring("reason=RebootRequested"),
+				ContainSubstring("PF "+pciAddress)))
+		})
+
+		It("writes only node configs", func() {
+			nodeConfigs, err := parse(localSpec)
+			Expect(err).ToNot(HaveOccurred())
+			c, err := run(nodeConfigs[0])
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(c.Get(context.TODO(), client.ObjectKey{Name: nodeNameRef.Name}, &corev1.Node{})).
+				To(MatchError(ContainSubstring("not found")))
+			Expect(c.Create(context.TODO(), &corev1.ConfigMap{})).To(MatchError(errStandaloneWrite))
+			Expect(c.Patch(context.TODO(), &corev1.Node{}, client.MergeFrom(&corev1.Node{}))).To(MatchError(errStandaloneWrite))
+
+			sfnc := &sriovv2.SriovFecNodeConfig{}
+			Expect(c.Get(context.TODO(), nodeNameRef, sfnc)).To(Succeed())
+			sfnc.Spec.PhysicalFunctions[0].VFAmount = 2
+			Expect(c.Update(context.TODO(), sfnc)).To(Succeed())
+			Expect(sfnc.Generation).To(Equal(int64(2)), "generation is bumped with spec change")
+
+			list := &sriovv2.SriovFecNodeConfigList{}
+			Expect(c.List(context.TODO(), list, client.InNamespace(nodeNameRef.Namespace))).To(Succeed())
+			Expect(list.Items).To(HaveLen(1))
+			Expect(list.Items[0].Spec.PhysicalFunctions[0].VFAmount).To(Equal(2))
+		})
+	})
+})
@@ -0,0 +1,52 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+const (
+	stateDirEnvVarName = utils.SRIOV_PREFIX + "STATE_DIR"
+	defaultStateDir    = "/var/lib/sriov-fec"
+)
+
+// InitStateDir points the daemon to directory which holds all files it writes (pf_bb_config cfg files, downloaded
+// FFT artifacts), so that it can run with read-only root filesystem. Directory is configured with SRIOV_FEC_STATE_DIR
+// env variable and has to be writable.
+func InitStateDir(log *logrus.Logger) (string, error) {
+	dir := os.Getenv(stateDirEnvVarName)
+	if dir == "" {
+		dir = defaultStateDir
+	}
+
+	if err := ensureWritableDir(dir); err != nil {
+		return "", fmt.Errorf("state directory %s is not writable, mount writable volume (e.g. emptyDir) there or point %s to one - %v",
+			dir, stateDirEnvVarName, err)
+	}
+
+	workdir = dir
+	artifactsFolder = dir
+	log.WithField("stateDir", dir).Info("state directory")
+	return dir, nil
+}
+
+func ensureWritableDir(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	probe, err := os.CreateTemp(dir, ".write-probe-")
+	if err != nil {
+		return err
+	}
+	if err := probe.Close(); err != nil {
+		return err
+	}
+	return os.Remove(probe.Name())
+}
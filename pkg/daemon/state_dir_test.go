@@ -0,0 +1,125 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("StateDir", func() {
+	const pciAddress = "0000:5e:00.7"
+	var (
+		originalWorkdir                  = workdir
+		originalArtifactsFolder          = artifactsFolder
+		originalRunExecCmd               = runExecCmd
+		originalRunPfBBConfigCmd         = runPfBBConfigCmd
+		originalDownloadFile             = downloadFile
+		originalUntarFile                = untarFile
+		originalVrbSupportedAccelerators = VrbsupportedAccelerators
+		stateDir                         string
+		written                          []string
+	)
+
+	BeforeEach(func() {
+		var err error
+		stateDir, err = os.MkdirTemp(testTmpFolder, "state")
+		Expect(err).ToNot(HaveOccurred())
+		written = nil
+	})
+
+	AfterEach(func() {
+		workdir = originalWorkdir
+		artifactsFolder = originalArtifactsFolder
+		runExecCmd = originalRunExecCmd
+		runPfBBConfigCmd = originalRunPfBBConfigCmd
+		downloadFile = originalDownloadFile
+		untarFile = originalUntarFile
+		VrbsupportedAccelerators = originalVrbSupportedAccelerators
+		Expect(os.Unsetenv(stateDirEnvVarName)).To(Succeed())
+	})
+
+	It("should use directory configured with env variable", func() {
+		dir := filepath.Join(stateDir, "nested")
+		Expect(os.Setenv(stateDirEnvVarName, dir)).To(Succeed())
+
+		Expect(InitStateDir(utils.NewLogger())).To(Equal(dir))
+		Expect(workdir).To(Equal(dir))
+		Expect(artifactsFolder).To(Equal(dir))
+		Expect(os.ReadDir(dir)).To(BeEmpty(), "write probe should be removed")
+	})
+
+	It("should fail when directory is not writable", func() {
+		file := filepath.Join(stateDir, "file")
+		Expect(os.WriteFile(file, nil, 0644)).To(Succeed())
+		Expect(os.Setenv(stateDirEnvVarName, filepath.Join(file, "state"))).To(Succeed())
+
+		_, err := InitStateDir(utils.NewLogger())
+		Expect(err).To(MatchError(ContainSubstring("is not writable")))
+		Expect(workdir).To(Equal(originalWorkdir))
+	})
+
+	It("should not write configuration files outside of the state directory", func() {
+		Expect(os.Setenv(stateDirEnvVarName, stateDir)).To(Succeed())
+		_, err := InitStateDir(utils.NewLogger())
+		Expect(err).ToNot(HaveOccurred())
+
+		VrbsupportedAccelerators = utils.AcceleratorDiscoveryConfig{Devices: map[string]string{"57c0": "VRB1"}}
+		runExecCmd = func(_ context.Context, args []string, _ *logrus.Logger) (string, error) {
+			for i, arg := range args {
+				if arg == "-c" || arg == "-f" {
+					written = append(written, args[i+1])
+				}
+			}
+			return "", nil
+		}
+		runPfBBConfigCmd = runExecCmd
+		downloadFile = func(path, _, _ string, _ *http.Client) error {
+			written = append(written, path)
+			return os.WriteFile(path, []byte("fft"), 0644)
+		}
+		untarFile = func(tarFile, destination string, _ *logrus.Logger) (string, error) {
+			fftFile := filepath.Join(destination, "srs_fft_windows_coefficient.bin")
+			written = append(written, fftFile)
+			return fftFile, os.WriteFile(fftFile, []byte("fft"), 0644)
+		}
+
+		pf := &vrbv1.PhysicalFunctionConfigExt{
+			PCIAddress: pciAddress,
+			BBDevConfig: vrbv1.BBDevConfig{VRB1: &vrbv1.VRB1BBDevConfig{
+				ACC100BBDevConfig: vrbv1.ACC100BBDevConfig{
+					NumVfBundles: 1,
+					MaxQueueSize: 1024,
+					Uplink4G:     vrbv1.QueueGroupConfig{NumQueueGroups: 0, NumAqsPerGroups: 16, AqDepthLog2: 4},
+					Downlink4G:   vrbv1.QueueGroupConfig{NumQueueGroups: 0, NumAqsPerGroups: 16, AqDepthLog2: 4},
+					Uplink5G:     vrbv1.QueueGroupConfig{NumQueueGroups: 4, NumAqsPerGroups: 16, AqDepthLog2: 4},
+					Downlink5G:   vrbv1.QueueGroupConfig{NumQueueGroups: 4, NumAqsPerGroups: 16, AqDepthLog2: 4},
+				},
+				QFFT:   vrbv1.QueueGroupConfig{NumQueueGroups: 4, NumAqsPerGroups: 16, AqDepthLog2: 4},
+				FFTLut: vrbv1.FFTLutParam{FftUrl: "http://example.com/fft.tar.gz", FftChecksum: "checksum"},
+			}},
+		}
+		p := &pfBBConfigController{log: utils.NewLogger(), fftUpdater: &fftUpdater{log: utils.NewLogger()}}
+
+		Expect(p.VrbinitializePfBBConfig(context.TODO(), vrbv1.SriovAccelerator{DeviceID: "57c0"}, pf, nil)).To(Succeed())
+		Expect(p.VrbinitializePfBBConfig(context.TODO(), vrbv1.SriovAccelerator{DeviceID: "57c0"}, pf, []byte(validBBDevConfigFile))).To(Succeed())
+
+		Expect(written).ToNot(BeEmpty())
+		for _, path := range written {
+			Expect(strings.HasPrefix(path, stateDir+string(filepath.Separator))).To(BeTrue(), path)
+		}
+		Expect(filepath.Join(stateDir, pciAddress+".ini")).To(BeAnExistingFile())
+		Expect(filepath.Join(originalWorkdir, pciAddress+".ini")).ToNot(BeAnExistingFile())
+	})
+})
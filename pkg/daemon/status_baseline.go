@@ -0,0 +1,62 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// statusBaselines keep node configs as they were last read from or written to API server within single reconcile.
+// Status of node config handed to updateStatus is diffed against its baseline, so that status fields set since it was
+// read (e.g. eviction summary) are patched too, without reading the node config again.
+type statusBaselines struct {
+	lock sync.Mutex
+	// key: see statusBaselineKey
+	objects map[string]client.Object
+}
+
+type statusBaselinesKey struct{}
+
+// withStatusBaselines returns context of reconcile, which node configs read and written within are recorded in
+func withStatusBaselines(ctx context.Context) context.Context {
+	return context.WithValue(ctx, statusBaselinesKey{}, &statusBaselines{objects: map[string]client.Object{}})
+}
+
+// withoutStatusBaselines returns context of work outliving the reconcile, which node configs written within are not
+// recorded for
+func withoutStatusBaselines(ctx context.Context) context.Context {
+	return context.WithValue(ctx, statusBaselinesKey{}, nil)
+}
+
+func statusBaselineKey(o client.Object) string {
+	return fmt.Sprintf("%T/%s", o, client.ObjectKeyFromObject(o))
+}
+
+// recordStatusBaseline records node config as stored by API server; nothing is recorded outside of reconcile
+func recordStatusBaseline(ctx context.Context, o client.Object) {
+	baselines, ok := ctx.Value(statusBaselinesKey{}).(*statusBaselines)
+	if !ok {
+		return
+	}
+	baselines.lock.Lock()
+	defer baselines.lock.Unlock()
+	baselines.objects[statusBaselineKey(o)] = o.DeepCopyObject().(client.Object)
+}
+
+// statusBaselineOf returns copy of the baseline recorded for the node config, or copy of the node config itself when
+// none was recorded
+func statusBaselineOf[T client.Object](ctx context.Context, o T) T {
+	if baselines, ok := ctx.Value(statusBaselinesKey{}).(*statusBaselines); ok {
+		baselines.lock.Lock()
+		defer baselines.lock.Unlock()
+		if baseline, ok := baselines.objects[statusBaselineKey(o)].(T); ok {
+			return baseline.DeepCopyObject().(T)
+		}
+	}
+	return o.DeepCopyObject().(T)
+}
@@ -0,0 +1,89 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+// getCountingClient counts node configs read
+type getCountingClient struct {
+	client.Client
+	gets int
+}
+
+func (c *getCountingClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if _, ok := obj.(*sriovv2.SriovFecNodeConfig); ok {
+		c.gets++
+	}
+	return c.Client.Get(ctx, key, obj, opts...)
+}
+
+var _ = Describe("statusBaselines", func() {
+	var (
+		c          *getCountingClient
+		reconciler *NodeConfigReconciler
+	)
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(sriovv2.AddToScheme(scheme)).To(Succeed())
+		c = &getCountingClient{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&sriovv2.SriovFecNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "default"}},
+		).Build()}
+		reconciler = &NodeConfigReconciler{Client: c, log: utils.NewLogger()}
+	})
+
+	stored := func() *sriovv2.SriovFecNodeConfig {
+		nc := new(sriovv2.SriovFecNodeConfig)
+		Expect(c.Client.Get(context.TODO(), client.ObjectKey{Name: "worker", Namespace: "default"}, nc)).To(Succeed())
+		return nc
+	}
+
+	It("patches status fields set since the node config was read without reading it again", func() {
+		ctx := withStatusBaselines(context.TODO())
+		nc := stored()
+		recordStatusBaseline(ctx, nc)
+
+		nc.Status.EvictionSummary = "1 pods evicted, 1 rescheduled, 0 pending"
+		Expect(reconciler.updateStatus(ctx, nc, metav1.ConditionTrue, ConfigurationSucceeded, "Configured successfully")).To(Succeed())
+
+		Expect(c.gets).To(BeZero())
+		Expect(stored().Status.EvictionSummary).To(Equal("1 pods evicted, 1 rescheduled, 0 pending"))
+	})
+
+	It("diffs against the last status written within the reconcile", func() {
+		ctx := withStatusBaselines(context.TODO())
+		nc := stored()
+		recordStatusBaseline(ctx, nc)
+
+		nc.Status.Warnings = []string{"warning"}
+		Expect(reconciler.updateStatus(ctx, nc, metav1.ConditionFalse, ConfigurationInProgress, "Configuration started")).To(Succeed())
+		nc.Status.Warnings = nil
+		Expect(reconciler.updateStatus(ctx, nc, metav1.ConditionTrue, ConfigurationSucceeded, "Configured successfully")).To(Succeed())
+
+		Expect(stored().Status.Warnings).To(BeEmpty())
+	})
+
+	It("is not recorded outside of reconcile", func() {
+		nc := stored()
+		recordStatusBaseline(context.TODO(), nc)
+		nc.Status.EvictionSummary = "changed"
+		Expect(statusBaselineOf(context.TODO(), nc).Status.EvictionSummary).To(Equal("changed"))
+
+		ctx := withoutStatusBaselines(withStatusBaselines(context.TODO()))
+		recordStatusBaseline(ctx, stored())
+		Expect(statusBaselineOf(ctx, nc).Status.EvictionSummary).To(Equal("changed"))
+	})
+})
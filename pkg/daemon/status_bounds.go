@@ -0,0 +1,171 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	fec "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+)
+
+// Status of node configs is written on every reconcile, lists in it are bounded so that it does not bloat etcd objects
+const (
+	// maxStatusWarnings is number of latest warnings kept in status
+	maxStatusWarnings = 16
+	// maxPFTransitions is number of latest transitions of the Configured condition kept per PF
+	maxPFTransitions = 5
+)
+
+// maxStatusSize is the limit of serialized status in bytes; oldest entries of its lists are trimmed until the status
+// fits, it is replaced by tests
+var maxStatusSize = 256 * 1024
+
+const (
+	statusFieldWarnings      = "warnings"
+	statusFieldPfTransitions = "pfTransitions"
+	statusFieldHookResults   = "hookResults"
+)
+
+var statusEntriesTrimmed = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "sriovfec_status_entries_trimmed_total",
+	Help: `number of entries trimmed from status of node configs to keep it bounded. 'kind' - SriovFecNodeConfig or SriovVrbNodeConfig. 'field' - status field the entries were trimmed from`,
+}, []string{"kind", "field"})
+
+func init() {
+	metrics.Registry.MustRegister(statusEntriesTrimmed)
+}
+
+// recordPFTransition appends transition to history of each PF of the spec, keeping up to maxPFTransitions latest ones;
+// history of PFs which are not in the spec anymore is dropped
+func recordPFTransition[T any](history map[string][]T, pciAddresses []string, transition T) map[string][]T {
+	recorded := make(map[string][]T, len(pciAddresses))
+	for _, pciAddress := range pciAddresses {
+		transitions := append(append([]T(nil), history[pciAddress]...), transition)
+		if len(transitions) > maxPFTransitions {
+			transitions = transitions[len(transitions)-maxPFTransitions:]
+		}
+		recorded[pciAddress] = transitions
+	}
+	if len(recorded) == 0 {
+		return nil
+	}
+	return recorded
+}
+
+// statusTrimmer drops the oldest entry of a status field; false is returned when the field is empty
+type statusTrimmer struct {
+	field      string
+	trimOldest func() bool
+}
+
+// boundStatus keeps up to maxStatusWarnings latest warnings and trims the oldest entries of fields, in order of
+// trimmers, until serialized status fits maxStatusSize. Trimmed entries are counted by statusEntriesTrimmed.
+func boundStatus(log *logrus.Logger, kind string, status interface{}, warnings *[]string, trimmers ...statusTrimmer) {
+	if trimmed := len(*warnings) - maxStatusWarnings; trimmed > 0 {
+		*warnings = (*warnings)[trimmed:]
+		statusEntriesTrimmed.WithLabelValues(kind, statusFieldWarnings).Add(float64(trimmed))
+		log.WithField("kind", kind).WithField("trimmed", trimmed).Info("trimmed oldest warnings of status")
+	}
+
+	trimmed := map[string]int{}
+	for _, trimmer := range trimmers {
+		for statusSize(status) > maxStatusSize && trimmer.trimOldest() {
+			trimmed[trimmer.field]++
+		}
+	}
+	for field, count := range trimmed {
+		statusEntriesTrimmed.WithLabelValues(kind, field).Add(float64(count))
+	}
+	if len(trimmed) > 0 {
+		log.WithField("kind", kind).WithField("trimmed", trimmed).WithField("limit", maxStatusSize).
+			Warn("status exceeds size limit - trimmed its oldest entries")
+	}
+	if size := statusSize(status); size > maxStatusSize {
+		log.WithField("kind", kind).WithField("size", size).WithField("limit", maxStatusSize).
+			Warn("status exceeds size limit after trimming")
+	}
+}
+
+func statusSize(status interface{}) int {
+	serialized, err := json.Marshal(status)
+	if err != nil {
+		return 0
+	}
+	return len(serialized)
+}
+
+// trimOldestEntry drops the first entry of entries, which are ordered oldest first
+func trimOldestEntry[T any](field string, entries *[]T) statusTrimmer {
+	return statusTrimmer{field: field, trimOldest: func() bool {
+		if len(*entries) == 0 {
+			return false
+		}
+		*entries = (*entries)[1:]
+		if len(*entries) == 0 {
+			*entries = nil
+		}
+		return true
+	}}
+}
+
+// trimOldestPFTransition drops the oldest transition of all PFs; ties are broken by PCI address
+func trimOldestPFTransition[T any](history map[string][]T, timeOf func(T) metav1.Time) statusTrimmer {
+	return statusTrimmer{field: statusFieldPfTransitions, trimOldest: func() bool {
+		pciAddresses := make([]string, 0, len(history))
+		for pciAddress := range history {
+			pciAddresses = append(pciAddresses, pciAddress)
+		}
+		sort.Strings(pciAddresses)
+
+		oldest := ""
+		for _, pciAddress := range pciAddresses {
+			if len(history[pciAddress]) == 0 {
+				continue
+			}
+			if oldest == "" || timeOf(history[pciAddress][0]).Time.Before(timeOf(history[oldest][0]).Time) {
+				oldest = pciAddress
+			}
+		}
+		if oldest == "" {
+			return false
+		}
+		if history[oldest] = history[oldest][1:]; len(history[oldest]) == 0 {
+			delete(history, oldest)
+		}
+		return true
+	}}
+}
+
+func fecBoundStatus(log *logrus.Logger, status *fec.SriovFecNodeConfigStatus) {
+	boundStatus(log, "SriovFecNodeConfig", status, &status.Warnings,
+		trimOldestPFTransition(status.PfTransitions, func(t fec.PFTransition) metav1.Time { return t.Time }),
+		trimOldestEntry(statusFieldWarnings, &status.Warnings),
+		trimOldestEntry(statusFieldHookResults, &status.HookResults))
+}
+
+func vrbBoundStatus(log *logrus.Logger, status *vrbv1.SriovVrbNodeConfigStatus) {
+	boundStatus(log, "SriovVrbNodeConfig", status, &status.Warnings,
+		trimOldestPFTransition(status.PfTransitions, func(t vrbv1.PFTransition) metav1.Time { return t.Time }),
+		trimOldestEntry(statusFieldWarnings, &status.Warnings),
+		trimOldestEntry(statusFieldHookResults, &status.HookResults))
+}
+
+// boundStatusOf bounds status of node config which status is about to be written, so that none of the writers can
+// exceed the bounds
+func boundStatusOf(o client.Object) {
+	switch nc := o.(type) {
+	case *fec.SriovFecNodeConfig:
+		fecBoundStatus(log, &nc.Status)
+	case *vrbv1.SriovVrbNodeConfig:
+		vrbBoundStatus(log, &nc.Status)
+	}
+}
@@ -0,0 +1,195 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	fec "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("Status bounds", func() {
+	var (
+		originalMaxStatusSize = maxStatusSize
+		start                 = time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	)
+
+	transition := func(minute int, reason string) fec.PFTransition {
+		return fec.PFTransition{Time: metav1.NewTime(start.Add(time.Duration(minute) * time.Minute)), Reason: reason}
+	}
+	trimmedCount := func(kind, field string) float64 {
+		return testutil.ToFloat64(statusEntriesTrimmed.WithLabelValues(kind, field))
+	}
+
+	AfterEach(func() {
+		maxStatusSize = originalMaxStatusSize
+	})
+
+	Context("recordPFTransition", func() {
+		It("keeps ring of the latest transitions of each PF", func() {
+			var history map[string][]fec.PFTransition
+			for minute := 0; minute < maxPFTransitions+2; minute++ {
+				history = recordPFTransition(history, []string{"0000:f7:00.0"}, transition(minute, fmt.Sprint(minute)))
+			}
+
+			Expect(history["0000:f7:00.0"]).To(HaveLen(maxPFTransitions))
+			Expect(history["0000:f7:00.0"][0]).To(Equal(transition(2, "2")))
+			Expect(history["0000:f7:00.0"][maxPFTransitions-1]).To(Equal(transition(6, "6")))
+		})
+
+		It("drops history of PFs which are not in the spec anymore", func() {
+			history := map[string][]fec.PFTransition{
+				"0000:f7:00.0": {transition(0, "InProgress")},
+				"0000:f8:00.0": {transition(0, "InProgress")},
+			}
+
+			history = recordPFTransition(history, []string{"0000:f8:00.0"}, transition(1, "Succeeded"))
+
+			Expect(history).To(Equal(map[string][]fec.PFTransition{
+				"0000:f8:00.0": {transition(0, "InProgress"), transition(1, "Succeeded")},
+			}))
+			Expect(recordPFTransition(history, nil, transition(2, "InProgress"))).To(BeNil())
+		})
+
+		It("does not modify recorded history", func() {
+			history := map[string][]fec.PFTransition{"0000:f7:00.0": make([]fec.PFTransition, 1, 10)}
+			history["0000:f7:00.0"][0] = transition(0, "InProgress")
+
+			recordPFTransition(history, []string{"0000:f7:00.0"}, transition(1, "Succeeded"))
+
+			Expect(history["0000:f7:00.0"]).To(Equal([]fec.PFTransition{transition(0, "InProgress")}))
+		})
+	})
+
+	Context("fecBoundStatus", func() {
+		It("keeps the latest warnings", func() {
+			before := trimmedCount("SriovFecNodeConfig", statusFieldWarnings)
+			status := &fec.SriovFecNodeConfigStatus{}
+			for i := 0; i < maxStatusWarnings+3; i++ {
+				status.Warnings = append(status.Warnings, fmt.Sprintf("warning %d", i))
+			}
+
+			fecBoundStatus(utils.NewLogger(), status)
+
+			Expect(status.Warnings).To(HaveLen(maxStatusWarnings))
+			Expect(status.Warnings[0]).To(Equal("warning 3"))
+			Expect(trimmedCount("SriovFecNodeConfig", statusFieldWarnings) - before).To(Equal(3.0))
+		})
+
+		It("does not trim status which fits the limit", func() {
+			status := &fec.SriovFecNodeConfigStatus{
+				Warnings:      []string{"warning"},
+				PfTransitions: map[string][]fec.PFTransition{"0000:f7:00.0": {transition(0, "Succeeded")}},
+			}
+			expected := status.DeepCopy()
+
+			fecBoundStatus(utils.NewLogger(), status)
+
+			Expect(status).To(Equal(expected))
+		})
+
+		It("trims the oldest transitions of all PFs first, until status fits the limit", func() {
+			before := trimmedCount("SriovFecNodeConfig", statusFieldPfTransitions)
+			status := &fec.SriovFecNodeConfigStatus{
+				Warnings: []string{"warning"},
+				PfTransitions: map[string][]fec.PFTransition{
+					"0000:f7:00.0": {transition(0, "InProgress"), transition(3, "Succeeded")},
+					"0000:f8:00.0": {transition(1, "InProgress"), transition(2, "Failed")},
+				},
+			}
+			fits := status.DeepCopy()
+			fits.PfTransitions = map[string][]fec.PFTransition{
+				"0000:f7:00.0": {transition(3, "Succeeded")},
+				"0000:f8:00.0": {transition(2, "Failed")},
+			}
+			maxStatusSize = statusSize(fits)
+
+			fecBoundStatus(utils.NewLogger(), status)
+
+			Expect(status).To(Equal(fits))
+			Expect(trimmedCount("SriovFecNodeConfig", statusFieldPfTransitions) - before).To(Equal(2.0))
+		})
+
+		It("trims warnings and then hook results once transitions are trimmed", func() {
+			beforeWarnings := trimmedCount("SriovFecNodeConfig", statusFieldWarnings)
+			beforeHookResults := trimmedCount("SriovFecNodeConfig", statusFieldHookResults)
+			postConfigure := fec.HookResult{Hook: postConfigureHookName, Path: "/bin/true"}
+			status := &fec.SriovFecNodeConfigStatus{
+				Summary:       strings.Repeat("s", 64),
+				Warnings:      []string{"first", "second"},
+				HookResults:   []fec.HookResult{{Hook: preConfigureHookName, Path: "/bin/true"}, postConfigure},
+				PfTransitions: map[string][]fec.PFTransition{"0000:f7:00.0": {transition(0, "Succeeded")}},
+			}
+			maxStatusSize = statusSize(&fec.SriovFecNodeConfigStatus{Summary: status.Summary, HookResults: []fec.HookResult{postConfigure}})
+
+			fecBoundStatus(utils.NewLogger(), status)
+
+			Expect(status.PfTransitions).To(BeEmpty())
+			Expect(status.Warnings).To(BeEmpty())
+			Expect(status.HookResults).To(Equal([]fec.HookResult{postConfigure}))
+			Expect(trimmedCount("SriovFecNodeConfig", statusFieldWarnings) - beforeWarnings).To(Equal(2.0))
+			Expect(trimmedCount("SriovFecNodeConfig", statusFieldHookResults) - beforeHookResults).To(Equal(1.0))
+		})
+
+		It("gives up when nothing is left to trim", func() {
+			status := &fec.SriovFecNodeConfigStatus{Summary: strings.Repeat("s", 64), Warnings: []string{"warning"}}
+			maxStatusSize = 16
+
+			fecBoundStatus(utils.NewLogger(), status)
+
+			Expect(status.Warnings).To(BeEmpty())
+			Expect(status.Summary).To(HaveLen(64))
+		})
+	})
+
+	Context("vrbBoundStatus", func() {
+		It("trims the oldest transitions", func() {
+			before := trimmedCount("SriovVrbNodeConfig", statusFieldPfTransitions)
+			status := &vrbv1.SriovVrbNodeConfigStatus{PfTransitions: map[string][]vrbv1.PFTransition{
+				"0000:f7:00.0": {vrbv1.PFTransition(transition(0, "InProgress")), vrbv1.PFTransition(transition(1, "Succeeded"))},
+			}}
+			fits := status.DeepCopy()
+			fits.PfTransitions["0000:f7:00.0"] = fits.PfTransitions["0000:f7:00.0"][1:]
+			maxStatusSize = statusSize(fits)
+
+			vrbBoundStatus(utils.NewLogger(), status)
+
+			Expect(status).To(Equal(fits))
+			Expect(trimmedCount("SriovVrbNodeConfig", statusFieldPfTransitions) - before).To(Equal(1.0))
+		})
+	})
+
+	Context("patchStatus", func() {
+		It("bounds status written by any writer", func() {
+			scheme := runtime.NewScheme()
+			Expect(fec.AddToScheme(scheme)).To(Succeed())
+			original := &fec.SriovFecNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "default"}}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(original.DeepCopy()).Build()
+
+			updated := original.DeepCopy()
+			for i := 0; i < maxStatusWarnings+1; i++ {
+				updated.Status.Warnings = append(updated.Status.Warnings, fmt.Sprintf("warning %d", i))
+			}
+			Expect(patchStatus(context.TODO(), c, original, updated)).To(BeTrue())
+
+			stored := &fec.SriovFecNodeConfig{}
+			Expect(c.Get(context.TODO(), client.ObjectKeyFromObject(original), stored)).To(Succeed())
+			Expect(stored.Status.Warnings).To(HaveLen(maxStatusWarnings))
+			Expect(stored.Status.Warnings[0]).To(Equal("warning 1"))
+		})
+	})
+})
@@ -0,0 +1,45 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+
+	fec "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/conditions"
+)
+
+// migrateStatus normalizes conditions of node config status written by older versions of the operator and marks it
+// with the current schema version, so that migration is done once per node config
+func (r *NodeConfigReconciler) migrateStatus(ctx context.Context, nc *fec.SriovFecNodeConfig) error {
+	if nc.Status.SchemaVersion >= conditions.CurrentSchemaVersion {
+		return nil
+	}
+
+	original := nc.DeepCopy()
+	nc.Status.Conditions = conditions.MigrateLegacy(nc.Status.Conditions)
+	nc.Status.SchemaVersion = conditions.CurrentSchemaVersion
+	if _, err := patchStatus(ctx, r.Client, original, nc); err != nil {
+		return fmt.Errorf("failed to migrate status of %s - %v", nc.GetName(), err)
+	}
+	r.log.WithField("schemaVersion", nc.Status.SchemaVersion).Info("status of SriovFecNodeConfig migrated")
+	return nil
+}
+
+func (r *NodeConfigReconciler) VrbmigrateStatus(ctx context.Context, nc *vrbv1.SriovVrbNodeConfig) error {
+	if nc.Status.SchemaVersion >= conditions.CurrentSchemaVersion {
+		return nil
+	}
+
+	original := nc.DeepCopy()
+	nc.Status.Conditions = conditions.MigrateLegacy(nc.Status.Conditions)
+	nc.Status.SchemaVersion = conditions.CurrentSchemaVersion
+	if _, err := patchStatus(ctx, r.Client, original, nc); err != nil {
+		return fmt.Errorf("failed to migrate status of %s - %v", nc.GetName(), err)
+	}
+	r.log.WithField("schemaVersion", nc.Status.SchemaVersion).Info("status of SriovVrbNodeConfig migrated")
+	return nil
+}
@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	fec "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/conditions"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("migrateStatus", func() {
+	// status written by an older version of the operator
+	const legacyStatus = `{
+		"conditions": [
+			{"type": "Configured", "status": "True", "reason": "ConfigurationSucceeded", "message": "Configured successfully", "observedGeneration": 4, "lastTransitionTime": "2022-05-10T08:00:00Z"},
+			{"type": "configured", "status": "False", "reason": "ConfigurationInProgress", "message": "Configuration started", "lastTransitionTime": "2022-05-10T07:59:00Z"},
+			{"type": "Flashed", "status": "True", "reason": "Succeeded", "message": "", "lastTransitionTime": "2021-11-02T12:00:00Z"}
+		],
+		"inventory": {"sriovAccelerators": [{"vendorID": "8086", "deviceID": "0d5c", "pciAddress": "0000:f7:00.0", "driver": "vfio-pci", "maxVirtualFunctions": 16, "virtualFunctions": []}]}
+	}`
+
+	var (
+		c          client.Client
+		reconciler *NodeConfigReconciler
+		key        = client.ObjectKey{Name: "worker", Namespace: "default"}
+	)
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(fec.AddToScheme(scheme)).To(Succeed())
+		Expect(vrbv1.AddToScheme(scheme)).To(Succeed())
+
+		sfnc := &fec.SriovFecNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace}}
+		Expect(json.Unmarshal([]byte(legacyStatus), &sfnc.Status)).To(Succeed())
+		vrbnc := &vrbv1.SriovVrbNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace}}
+		Expect(json.Unmarshal([]byte(legacyStatus), &vrbnc.Status)).To(Succeed())
+
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(sfnc, vrbnc).Build()
+		reconciler = &NodeConfigReconciler{Client: c, log: utils.NewLogger()}
+	})
+
+	It("should migrate legacy status of SriovFecNodeConfig once", func() {
+		nc := &fec.SriovFecNodeConfig{}
+		Expect(c.Get(context.TODO(), key, nc)).To(Succeed())
+		Expect(reconciler.migrateStatus(context.TODO(), nc)).To(Succeed())
+
+		stored := &fec.SriovFecNodeConfig{}
+		Expect(c.Get(context.TODO(), key, stored)).To(Succeed())
+		Expect(stored.Status.SchemaVersion).To(Equal(conditions.CurrentSchemaVersion))
+		Expect(stored.Status.Conditions).To(HaveLen(4), "Configured with Available, Progressing and Degraded")
+		configured := meta.FindStatusCondition(stored.Status.Conditions, conditions.TypeConfigured)
+		Expect(configured.Reason).To(Equal(string(ConfigurationSucceeded)))
+		Expect(configured.ObservedGeneration).To(BeEquivalentTo(4))
+		Expect(stored.Status.Inventory.SriovAccelerators).To(HaveLen(1))
+
+		// migrated status is not touched again
+		meta.SetStatusCondition(&stored.Status.Conditions, metav1.Condition{Type: "Flashed", Status: metav1.ConditionTrue, Reason: "Succeeded"})
+		Expect(c.Status().Update(context.TODO(), stored)).To(Succeed())
+		Expect(reconciler.migrateStatus(context.TODO(), stored)).To(Succeed())
+		Expect(c.Get(context.TODO(), key, stored)).To(Succeed())
+		Expect(meta.FindStatusCondition(stored.Status.Conditions, "Flashed")).ToNot(BeNil())
+	})
+
+	It("should migrate legacy status of SriovVrbNodeConfig", func() {
+		nc := &vrbv1.SriovVrbNodeConfig{}
+		Expect(c.Get(context.TODO(), key, nc)).To(Succeed())
+		Expect(reconciler.VrbmigrateStatus(context.TODO(), nc)).To(Succeed())
+
+		stored := &vrbv1.SriovVrbNodeConfig{}
+		Expect(c.Get(context.TODO(), key, stored)).To(Succeed())
+		Expect(stored.Status.SchemaVersion).To(Equal(conditions.CurrentSchemaVersion))
+		Expect(stored.Status.Conditions).To(HaveLen(4), "Configured with Available, Progressing and Degraded")
+		Expect(stored.Status.Conditions[0].Reason).To(Equal(string(ConfigurationSucceeded)))
+	})
+})
@@ -0,0 +1,130 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/conditions"
+)
+
+func statusPatchTestNodeConfig() *sriovv2.SriovFecNodeConfig {
+	nc := &sriovv2.SriovFecNodeConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "default"},
+		Status: sriovv2.SriovFecNodeConfigStatus{
+			Inventory: sriovv2.NodeInventory{
+				SriovAccelerators: []sriovv2.SriovAccelerator{{PCIAddress: "0000:14:00.1", DeviceID: "0d5c", MaxVFs: 16}},
+			},
+			// status reports discovery config in use, which is loaded by other specs
+			DiscoveryConfigHash: discoveryConfigHash,
+		},
+	}
+	meta.SetStatusCondition(&nc.Status.Conditions, metav1.Condition{
+		Type:   ConditionConfigured,
+		Status: metav1.ConditionTrue,
+		Reason: string(ConfigurationSucceeded),
+	})
+	conditions.SetStandard(&nc.Status.Conditions, nc.GetGeneration())
+	nc.Status.Summary = fecStatusSummary(nc)
+	return nc
+}
+
+var _ = Describe("statusPatchData", func() {
+	It("will produce empty patch when nothing has changed", func() {
+		original := statusPatchTestNodeConfig()
+		Expect(statusPatchData(original, original.DeepCopy())).To(MatchJSON(`{}`))
+	})
+
+	It("will contain only conditions when condition has changed", func() {
+		original := statusPatchTestNodeConfig()
+		updated := original.DeepCopy()
+		meta.SetStatusCondition(&updated.Status.Conditions, metav1.Condition{
+			Type:    ConditionConfigured,
+			Status:  metav1.ConditionFalse,
+			Reason:  string(ConfigurationInProgress),
+			Message: "Configuration started",
+		})
+
+		data, err := statusPatchData(original, updated)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(ContainSubstring(`"conditions"`))
+		Expect(data).ToNot(ContainSubstring(`"inventory"`))
+		Expect(data).ToNot(ContainSubstring(`"spec"`))
+	})
+
+	It("will contain only inventory when inventory has changed", func() {
+		original := statusPatchTestNodeConfig()
+		updated := original.DeepCopy()
+		updated.Status.Inventory.SriovAccelerators[0].VFs = []sriovv2.VF{{PCIAddress: "0000:15:00.0"}}
+
+		data, err := statusPatchData(original, updated)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(ContainSubstring(`"inventory"`))
+		Expect(data).ToNot(ContainSubstring(`"conditions"`))
+	})
+})
+
+var _ = Describe("patchStatus", func() {
+	var scheme *runtime.Scheme
+
+	BeforeEach(func() {
+		scheme = runtime.NewScheme()
+		Expect(sriovv2.AddToScheme(scheme)).ToNot(HaveOccurred())
+	})
+
+	It("will skip patch when status has not changed", func() {
+		nc := statusPatchTestNodeConfig()
+		fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(nc).Build()
+		Expect(fakeClient.Get(context.TODO(), client.ObjectKeyFromObject(nc), nc)).To(Succeed())
+
+		patched, err := patchStatus(context.TODO(), fakeClient, nc.DeepCopy(), nc)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(patched).To(BeFalse())
+	})
+
+	It("will patch changed status", func() {
+		nc := statusPatchTestNodeConfig()
+		fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(nc).Build()
+		Expect(fakeClient.Get(context.TODO(), client.ObjectKeyFromObject(nc), nc)).To(Succeed())
+
+		original := nc.DeepCopy()
+		nc.Status.Inventory.SriovAccelerators[0].MaxVFs = 8
+
+		patched, err := patchStatus(context.TODO(), fakeClient, original, nc)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(patched).To(BeTrue())
+
+		res := new(sriovv2.SriovFecNodeConfig)
+		Expect(fakeClient.Get(context.TODO(), client.ObjectKeyFromObject(nc), res)).To(Succeed())
+		Expect(res.Status.Inventory.SriovAccelerators[0].MaxVFs).To(Equal(8))
+		Expect(res.FindCondition(ConditionConfigured)).ToNot(BeNil())
+	})
+})
+
+func BenchmarkStatusPatchData(b *testing.B) {
+	original := statusPatchTestNodeConfig()
+	updated := original.DeepCopy()
+	meta.SetStatusCondition(&updated.Status.Conditions, metav1.Condition{
+		Type:   ConditionConfigured,
+		Status: metav1.ConditionFalse,
+		Reason: string(ConfigurationInProgress),
+	})
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := statusPatchData(original, updated); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	fec "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/conditions"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+// summaryPF is PF requested by the spec, as seen by the status summary
+type summaryPF struct {
+	pciAddress string
+	vfDriver   string
+	vfAmount   int
+	// current tells whether PF was last configured with current generation of the node config
+	current bool
+}
+
+// summaryAccelerator is accelerator found in the inventory, as seen by the status summary
+type summaryAccelerator struct {
+	pciAddress string
+	vfDrivers  []string
+}
+
+// statusSummary renders one-line summary of the node exposed in status.summary, e.g.
+// "2/2 PFs configured, 32 VFs, vfio-pci, last change 2023-06-01T10:00:00Z". PF is counted as configured when it is present in the
+// inventory with requested amount of VFs, all bound to requested VF driver, and it was last configured with current
+// generation of the node config. VF drivers are listed only when there are
+// VFs and the last change is omitted when lastChange is zero. Last change is absolute, so that the summary does not go
+// stale between status writes.
+func statusSummary(pfs []summaryPF, accelerators []summaryAccelerator, lastChange time.Time) string {
+	byAddress := make(map[string]summaryAccelerator, len(accelerators))
+	vfs, drivers := 0, map[string]bool{}
+	for _, acc := range accelerators {
+		byAddress[utils.CanonicalPCIAddress(acc.pciAddress)] = acc
+		vfs += len(acc.vfDrivers)
+		for _, driver := range acc.vfDrivers {
+			drivers[driver] = true
+		}
+	}
+
+	configured := 0
+	for _, pf := range pfs {
+		if acc, ok := byAddress[utils.CanonicalPCIAddress(pf.pciAddress)]; ok && pf.current && vfsBoundTo(acc.vfDrivers, pf.vfDriver, pf.vfAmount) {
+			configured++
+		}
+	}
+
+	parts := []string{fmt.Sprintf("%d/%d PFs configured", configured, len(pfs)), fmt.Sprintf("%d VFs", vfs)}
+	if len(drivers) > 0 {
+		var names []string
+		for driver := range drivers {
+			names = append(names, driver)
+		}
+		sort.Strings(names)
+		parts = append(parts, strings.Join(names, ","))
+	}
+	if !lastChange.IsZero() {
+		parts = append(parts, fmt.Sprintf("last change %s", lastChange.UTC().Format(time.RFC3339)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func vfsBoundTo(vfDrivers []string, driver string, amount int) bool {
+	if len(vfDrivers) != amount {
+		return false
+	}
+	for _, d := range vfDrivers {
+		if d != driver {
+			return false
+		}
+	}
+	return true
+}
+
+func fecStatusSummary(nc *fec.SriovFecNodeConfig) string {
+	var pfs []summaryPF
+	for _, pf := range nc.Spec.PhysicalFunctions {
+		pfs = append(pfs, summaryPF{pciAddress: pf.PCIAddress, vfDriver: pf.VFDriver, vfAmount: pf.VFAmount,
+			current: appliedGenerationsCurrent(nc.Status.AppliedGenerations, []string{pf.PCIAddress}, nc.GetGeneration())})
+	}
+	var accelerators []summaryAccelerator
+	for _, acc := range nc.Status.Inventory.SriovAccelerators {
+		summary := summaryAccelerator{pciAddress: acc.PCIAddress}
+		for _, vf := range acc.VFs {
+			summary.vfDrivers = append(summary.vfDrivers, vf.Driver)
+		}
+		accelerators = append(accelerators, summary)
+	}
+	var lastChange time.Time
+	if condition := nc.FindCondition(ConditionConfigured); condition != nil {
+		lastChange = condition.LastTransitionTime.Time
+	}
+	return statusSummary(pfs, accelerators, lastChange)
+}
+
+func vrbStatusSummary(nc *vrbv1.SriovVrbNodeConfig) string {
+	var pfs []summaryPF
+	for _, pf := range nc.Spec.PhysicalFunctions {
+		pfs = append(pfs, summaryPF{pciAddress: pf.PCIAddress, vfDriver: pf.VFDriver, vfAmount: pf.VFAmount,
+			current: appliedGenerationsCurrent(nc.Status.AppliedGenerations, []string{pf.PCIAddress}, nc.GetGeneration())})
+	}
+	var accelerators []summaryAccelerator
+	for _, acc := range nc.Status.Inventory.SriovAccelerators {
+		summary := summaryAccelerator{pciAddress: acc.PCIAddress}
+		for _, vf := range acc.VFs {
+			summary.vfDrivers = append(summary.vfDrivers, vf.Driver)
+		}
+		accelerators = append(accelerators, summary)
+	}
+	var lastChange time.Time
+	if condition := nc.FindCondition(ConditionConfigured); condition != nil {
+		lastChange = condition.LastTransitionTime.Time
+	}
+	return statusSummary(pfs, accelerators, lastChange)
+}
+
+// summarizeStatusOf refreshes status.summary of node config which status is about to be written
+func summarizeStatusOf(o client.Object) {
+	switch nc := o.(type) {
+	case *fec.SriovFecNodeConfig:
+		nc.Status.Summary = fecStatusSummary(nc)
+	case *vrbv1.SriovVrbNodeConfig:
+		nc.Status.Summary = vrbStatusSummary(nc)
+	}
+}
+
+// stampDiscoveryConfigHash sets hash of discovery config in use into status of node config which status is about to be
+// written; hash is left as is until the config is loaded
+func stampDiscoveryConfigHash(o client.Object) {
+	discoveryConfigHashLock.RLock()
+	defer discoveryConfigHashLock.RUnlock()
+	switch nc := o.(type) {
+	case *fec.SriovFecNodeConfig:
+		if discoveryConfigHash != "" {
+			nc.Status.DiscoveryConfigHash = discoveryConfigHash
+		}
+	case *vrbv1.SriovVrbNodeConfig:
+		if VrbdiscoveryConfigHash != "" {
+			nc.Status.DiscoveryConfigHash = VrbdiscoveryConfigHash
+		}
+	}
+}
+
+// setStandardConditions derives Available, Progressing and Degraded conditions of node config which status is about
+// to be written from the other conditions, see conditions.Standard
+func setStandardConditions(o client.Object) {
+	switch nc := o.(type) {
+	case *fec.SriovFecNodeConfig:
+		conditions.SetStandard(&nc.Status.Conditions, nc.GetGeneration())
+	case *vrbv1.SriovVrbNodeConfig:
+		conditions.SetStandard(&nc.Status.Conditions, nc.GetGeneration())
+	}
+}
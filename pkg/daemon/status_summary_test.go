@@ -0,0 +1,141 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	fec "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("statusSummary", func() {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	vfs := func(driver string, amount int) []string {
+		var drivers []string
+		for i := 0; i < amount; i++ {
+			drivers = append(drivers, driver)
+		}
+		return drivers
+	}
+
+	DescribeTable("renders one line summary",
+		func(pfs []summaryPF, accelerators []summaryAccelerator, lastChange time.Time, expected string) {
+			Expect(statusSummary(pfs, accelerators, lastChange)).To(Equal(expected))
+		},
+		Entry("nothing requested nor found",
+			nil, nil, time.Time{},
+			"0/0 PFs configured, 0 VFs"),
+		Entry("all PFs configured",
+			[]summaryPF{{"0000:f7:00.0", utils.VFIO_PCI, 16, true}, {"0000:f8:00.0", utils.VFIO_PCI, 16, true}},
+			[]summaryAccelerator{{"0000:f7:00.0", vfs(utils.VFIO_PCI, 16)}, {"0000:f8:00.0", vfs(utils.VFIO_PCI, 16)}},
+			now.Add(-2*time.Hour),
+			"2/2 PFs configured, 32 VFs, vfio-pci, last change 2023-06-01T10:00:00Z"),
+		Entry("PF with VFs missing",
+			[]summaryPF{{"0000:f7:00.0", utils.VFIO_PCI, 16, true}, {"0000:f8:00.0", utils.VFIO_PCI, 16, true}},
+			[]summaryAccelerator{{"0000:f7:00.0", vfs(utils.VFIO_PCI, 16)}, {"0000:f8:00.0", nil}},
+			now.Add(-90*time.Second),
+			"1/2 PFs configured, 16 VFs, vfio-pci, last change 2023-06-01T11:58:30Z"),
+		Entry("PF with VFs bound to other driver",
+			[]summaryPF{{"0000:f7:00.0", utils.VFIO_PCI, 2, true}},
+			[]summaryAccelerator{{"0000:f7:00.0", []string{utils.VFIO_PCI, utils.IGB_UIO}}},
+			now.Add(-3*24*time.Hour),
+			"0/1 PFs configured, 2 VFs, igb_uio,vfio-pci, last change 2023-05-29T12:00:00Z"),
+		Entry("requested PF not found",
+			[]summaryPF{{"0000:f7:00.0", utils.VFIO_PCI, 1, true}},
+			nil,
+			now.Add(-5*time.Minute),
+			"0/1 PFs configured, 0 VFs, last change 2023-06-01T11:55:00Z"),
+		Entry("change recorded in other time zone",
+			[]summaryPF{{"0000:f7:00.0", utils.VFIO_PCI, 1, true}},
+			[]summaryAccelerator{{"0000:f7:00.0", vfs(utils.VFIO_PCI, 1)}},
+			now.In(time.FixedZone("CEST", 2*60*60)),
+			"1/1 PFs configured, 1 VFs, vfio-pci, last change 2023-06-01T12:00:00Z"),
+		Entry("PF requested without domain",
+			[]summaryPF{{"f7:00.0", utils.IGB_UIO, 1, true}},
+			[]summaryAccelerator{{"0000:f7:00.0", vfs(utils.IGB_UIO, 1)}},
+			time.Time{},
+			"1/1 PFs configured, 1 VFs, igb_uio"),
+		Entry("PF configured with previous generation",
+			[]summaryPF{{"0000:f7:00.0", utils.VFIO_PCI, 16, true}, {"0000:f8:00.0", utils.VFIO_PCI, 16, false}},
+			[]summaryAccelerator{{"0000:f7:00.0", vfs(utils.VFIO_PCI, 16)}, {"0000:f8:00.0", vfs(utils.VFIO_PCI, 16)}},
+			now.Add(-time.Minute),
+			"1/2 PFs configured, 32 VFs, vfio-pci, last change 2023-06-01T11:59:00Z"),
+		Entry("PF without VFs requested",
+			[]summaryPF{{"0000:f7:00.0", utils.VFIO_PCI, 0, true}},
+			[]summaryAccelerator{{"0000:f7:00.0", nil}},
+			now.Add(-time.Second),
+			"1/1 PFs configured, 0 VFs, last change 2023-06-01T11:59:59Z"),
+	)
+
+	It("is refreshed when status is written", func() {
+		scheme := runtime.NewScheme()
+		Expect(fec.AddToScheme(scheme)).To(Succeed())
+		nc := &fec.SriovFecNodeConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "default"},
+			Spec: fec.SriovFecNodeConfigSpec{
+				PhysicalFunctions: []fec.PhysicalFunctionConfigExt{{PCIAddress: "0000:f7:00.0", VFDriver: utils.VFIO_PCI, VFAmount: 1}},
+			},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(nc).Build()
+
+		original := nc.DeepCopy()
+		nc.Status.Inventory.SriovAccelerators = []fec.SriovAccelerator{{
+			PCIAddress: "0000:f7:00.0", VFs: []fec.VF{{PCIAddress: "0000:f7:00.1", Driver: utils.VFIO_PCI}},
+		}}
+		nc.Status.AppliedGenerations = map[string]int64{"0000:f7:00.0": nc.GetGeneration()}
+		nc.Status.Conditions = []metav1.Condition{{Type: ConditionConfigured, Status: metav1.ConditionTrue,
+			Reason: string(ConfigurationSucceeded), LastTransitionTime: metav1.NewTime(time.Date(2023, 6, 1, 11, 0, 0, 0, time.UTC))}}
+		Expect(patchStatus(context.TODO(), c, original, nc)).To(BeTrue())
+
+		stored := &fec.SriovFecNodeConfig{}
+		Expect(c.Get(context.TODO(), client.ObjectKeyFromObject(nc), stored)).To(Succeed())
+		Expect(stored.Status.Summary).To(Equal("1/1 PFs configured, 1 VFs, vfio-pci, last change 2023-06-01T11:00:00Z"))
+
+		// summary alone does not cause a write
+		Expect(patchStatus(context.TODO(), c, stored.DeepCopy(), stored)).To(BeFalse())
+	})
+})
+
+var _ = Describe("discoveryConfigHash", func() {
+	originalDiscoveryConfigHash := discoveryConfigHash
+
+	AfterEach(func() {
+		discoveryConfigHash = originalDiscoveryConfigHash
+	})
+
+	It("is reported with status writes", func() {
+		scheme := runtime.NewScheme()
+		Expect(fec.AddToScheme(scheme)).To(Succeed())
+		nc := &fec.SriovFecNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "default"}}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(nc).Build()
+		getStored := func() *fec.SriovFecNodeConfig {
+			stored := &fec.SriovFecNodeConfig{}
+			Expect(c.Get(context.TODO(), client.ObjectKeyFromObject(nc), stored)).To(Succeed())
+			return stored
+		}
+
+		discoveryConfigHash = "aaaa"
+		Expect(patchStatus(context.TODO(), c, nc.DeepCopy(), nc)).To(BeTrue())
+		Expect(getStored().Status.DiscoveryConfigHash).To(Equal("aaaa"))
+
+		stored := getStored()
+		Expect(patchStatus(context.TODO(), c, stored.DeepCopy(), stored)).To(BeFalse(), "hash alone does not cause a write until it changes")
+
+		// config is reloaded
+		discoveryConfigHash = "bbbb"
+		stored = getStored()
+		Expect(patchStatus(context.TODO(), c, stored.DeepCopy(), stored)).To(BeTrue())
+		Expect(getStored().Status.DiscoveryConfigHash).To(Equal("bbbb"))
+	})
+})
@@ -0,0 +1,109 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type reconcileIDKey struct{}
+
+// withReconcileID returns context carrying ID of the reconcile, which correlates log entries of single reconcile
+func withReconcileID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, reconcileIDKey{}, id)
+}
+
+// reconcileIDFrom returns ID of the reconcile the context belongs to; empty outside of reconcile
+func reconcileIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(reconcileIDKey{}).(string)
+	return id
+}
+
+// sysfsWriteCounts summarizes writes of single operation type, i.e. sysfs file name (e.g. bind, sriov_numvfs)
+type sysfsWriteCounts struct {
+	Writes   int           `json:"writes"`
+	Failures int           `json:"failures"`
+	Elapsed  time.Duration `json:"elapsed"`
+}
+
+// sysfsAudit records every sysfs write of single configuration, so that exact sequence of writes and their results can
+// be analyzed when configuration fails. It is used only when Debug level is enabled, nil audit records nothing.
+type sysfsAudit struct {
+	log *logrus.Entry
+
+	mu     sync.Mutex
+	counts map[string]*sysfsWriteCounts
+}
+
+type sysfsAuditKey struct{}
+
+// withSysfsAudit returns context which sysfs writes are recorded in, when log enables Debug level; audit is nil otherwise
+func withSysfsAudit(ctx context.Context, log *logrus.Logger) (context.Context, *sysfsAudit) {
+	if !log.IsLevelEnabled(logrus.DebugLevel) {
+		return ctx, nil
+	}
+	audit := &sysfsAudit{
+		log:    log.WithField("reconcileID", reconcileIDFrom(ctx)),
+		counts: map[string]*sysfsWriteCounts{},
+	}
+	return context.WithValue(ctx, sysfsAuditKey{}, audit), audit
+}
+
+func sysfsAuditFrom(ctx context.Context) *sysfsAudit {
+	audit, _ := ctx.Value(sysfsAuditKey{}).(*sysfsAudit)
+	return audit
+}
+
+// record logs write of data to path and counts it under its operation type
+func (a *sysfsAudit) record(path, data string, err error, elapsed time.Duration) {
+	if a == nil {
+		return
+	}
+	operation := filepath.Base(path)
+
+	a.mu.Lock()
+	counts, ok := a.counts[operation]
+	if !ok {
+		counts = &sysfsWriteCounts{}
+		a.counts[operation] = counts
+	}
+	counts.Writes++
+	counts.Elapsed += elapsed
+	if err != nil {
+		counts.Failures++
+	}
+	a.mu.Unlock()
+
+	entry := a.log.WithField("path", path).WithField("value", data).WithField("elapsed", elapsed.String())
+	if err != nil {
+		var errno syscall.Errno
+		if errors.As(err, &errno) {
+			entry = entry.WithField("errno", int(errno))
+		}
+		entry.WithError(err).Debug("sysfs write failed")
+		return
+	}
+	entry.Debug("sysfs write")
+}
+
+// summarize logs single entry with counts of writes per operation type, once configuration is over
+func (a *sysfsAudit) summarize(err error) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	entry := a.log.WithField("operations", a.counts)
+	if err != nil {
+		entry = entry.WithError(err)
+	}
+	entry.Debug("sysfs writes summary")
+}
@@ -0,0 +1,77 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+var _ = Describe("sysfs write audit", func() {
+	var sysfsDir string
+
+	BeforeEach(func() {
+		var err error
+		sysfsDir, err = os.MkdirTemp(testTmpFolder, "sysfs")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(sysfsDir)).To(Succeed())
+	})
+
+	It("should log every write and summary of writes per operation", func() {
+		log, hook := test.NewNullLogger()
+		log.SetLevel(logrus.DebugLevel)
+		ctx, audit := withSysfsAudit(withReconcileID(context.TODO(), "reconcile-1"), log)
+		Expect(audit).ToNot(BeNil())
+
+		numvfs := filepath.Join(sysfsDir, "sriov_numvfs")
+		Expect(writeFileWithTimeout(ctx, numvfs, "0")).To(Succeed())
+		Expect(writeFileWithTimeout(ctx, numvfs, "2")).To(Succeed())
+		missing := filepath.Join(sysfsDir, "missing", "bind")
+		Expect(writeFileWithTimeout(ctx, missing, pciAddress)).ToNot(Succeed())
+		audit.summarize(nil)
+
+		entries := hook.AllEntries()
+		Expect(entries).To(HaveLen(4))
+		Expect(entries[1].Message).To(Equal("sysfs write"))
+		Expect(entries[1].Data).To(And(
+			HaveKeyWithValue("reconcileID", "reconcile-1"),
+			HaveKeyWithValue("path", numvfs),
+			HaveKeyWithValue("value", "2"),
+			HaveKey("elapsed")))
+		Expect(entries[2].Message).To(Equal("sysfs write failed"))
+		Expect(entries[2].Data).To(And(
+			HaveKeyWithValue("path", missing),
+			HaveKeyWithValue("errno", int(syscall.ENOENT)),
+			HaveKey(logrus.ErrorKey)))
+
+		Expect(entries[3].Message).To(Equal("sysfs writes summary"))
+		Expect(entries[3].Data).To(HaveKeyWithValue("reconcileID", "reconcile-1"))
+		operations := entries[3].Data["operations"].(map[string]*sysfsWriteCounts)
+		Expect(operations).To(HaveLen(2))
+		Expect(operations["sriov_numvfs"]).To(And(HaveField("Writes", 2), HaveField("Failures", 0)))
+		Expect(operations["bind"]).To(And(HaveField("Writes", 1), HaveField("Failures", 1)))
+	})
+
+	It("should not audit writes when Debug level is disabled", func() {
+		log, hook := test.NewNullLogger()
+		log.SetLevel(logrus.InfoLevel)
+		ctx, audit := withSysfsAudit(context.TODO(), log)
+		Expect(audit).To(BeNil())
+		Expect(sysfsAuditFrom(ctx)).To(BeNil())
+
+		Expect(writeFileWithTimeout(ctx, filepath.Join(sysfsDir, "sriov_numvfs"), "1")).To(Succeed())
+		audit.summarize(nil)
+		Expect(hook.AllEntries()).To(BeEmpty())
+	})
+})
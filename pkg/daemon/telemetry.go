@@ -132,7 +132,8 @@ func StartTelemetryDaemon(mgr manager.Manager, nodeName string, ns string, direc
 	go getMetrics(nodeName, ns, directClient, log, telemetryGatherer)
 }
 
-func getMetrics(nodeName, namespace string, c client.Client, log *logrus.Logger, telemetryGatherer *telemetryGatherer) {
+// metricGatherInterval returns interval of telemetry updates configured with METRIC_GATHER_INTERVAL env variable
+func metricGatherInterval(log *logrus.Logger) time.Duration {
 	sleepDuration := 15 * time.Second
 	sleepEnv := os.Getenv(utils.SRIOV_PREFIX + "METRIC_GATHER_INTERVAL")
 	if sleepEnv != "" {
@@ -143,6 +144,11 @@ func getMetrics(nodeName, namespace string, c client.Client, log *logrus.Logger,
 			sleepDuration = envDuration
 		}
 	}
+	return sleepDuration
+}
+
+func getMetrics(nodeName, namespace string, c client.Client, log *logrus.Logger, telemetryGatherer *telemetryGatherer) {
+	sleepDuration := metricGatherInterval(log)
 
 	utils.NewLogger().Info("metrics update loop will run every ", sleepDuration)
 	wait.Forever(func() {
@@ -206,9 +212,9 @@ func getTelemetry(pciAddr string, vfs []fec.VF, telemetryGatherer *telemetryGath
 		log.WithError(err).WithField("pciAddr", pciAddr).Error("error occurred during preparation for telemetry loop")
 		return
 	}
-	err = requestTelemetry(pciAddr, log)
+	err = requestTelemetryOfPfBBConfig(pciAddr, log)
 	if err != nil {
-		log.WithError(err).WithField("pciAddr", pciAddr).Error("couldn't request telemetry from socket")
+		log.WithError(err).WithField("pciAddr", pciAddr).Error("couldn't request telemetry from pf_bb_config")
 		return
 	}
 
@@ -218,7 +224,7 @@ func getTelemetry(pciAddr string, vfs []fec.VF, telemetryGatherer *telemetryGath
 		return
 	}
 
-	parseTelemetry(file, vfs, pciAddr, telemetryGatherer, log)
+	parseTelemetry(file, vfs, pciAddr, telemetryGatherer, telemetryParseLogger(pciAddr, file, log))
 }
 
 func VrbgetTelemetry(pciAddr string, vfs []vrbv1.VF, telemetryGatherer *telemetryGatherer, log *logrus.Logger) {
@@ -227,9 +233,9 @@ func VrbgetTelemetry(pciAddr string, vfs []vrbv1.VF, telemetryGatherer *telemetr
 		log.WithError(err).WithField("pciAddr", pciAddr).Error("error occurred during preparation for telemetry loop")
 		return
 	}
-	err = requestTelemetry(pciAddr, log)
+	err = requestTelemetryOfPfBBConfig(pciAddr, log)
 	if err != nil {
-		log.WithError(err).WithField("pciAddr", pciAddr).Error("couldn't request telemetry from socket")
+		log.WithError(err).WithField("pciAddr", pciAddr).Error("couldn't request telemetry from pf_bb_config")
 		return
 	}
 
@@ -239,7 +245,7 @@ func VrbgetTelemetry(pciAddr string, vfs []vrbv1.VF, telemetryGatherer *telemetr
 		return
 	}
 
-	VrbparseTelemetry(file, vfs, pciAddr, telemetryGatherer, log)
+	VrbparseTelemetry(file, vfs, pciAddr, telemetryGatherer, telemetryParseLogger(pciAddr, file, log))
 }
 
 func parseTelemetry(file []byte, vfs []fec.VF, pciAddr string, telemetryGatherer *telemetryGatherer, logger *logrus.Logger) {
@@ -457,7 +463,7 @@ func readFileWithTelemetry(pciAddr string, log *logrus.Logger) ([]byte, error) {
 }
 
 func requestTelemetry(pciAddr string, log *logrus.Logger) error {
-	conn, err := net.Dial("unix", fmt.Sprintf("/tmp/pf_bb_config.%v.sock", pciAddr))
+	conn, err := net.Dial("unix", telemetrySocketPath(pciAddr))
 	if err != nil {
 		log.WithField("pciAddr", pciAddr).WithError(err).Error("failed to open socket")
 		return err
@@ -0,0 +1,160 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// telemetryMechanism is the way pf_bb_config is asked to dump its counters to the response file
+type telemetryMechanism string
+
+const (
+	// socketTelemetry - request is written to telemetry socket of pf_bb_config
+	socketTelemetry telemetryMechanism = "socket"
+	// signalTelemetry - pf_bb_config is sent SIGUSR2; older versions without telemetry socket support only this one
+	signalTelemetry telemetryMechanism = "SIGUSR2"
+)
+
+var (
+	telemetrySocketPath = func(pciAddr string) string {
+		return fmt.Sprintf("/tmp/pf_bb_config.%v.sock", pciAddr)
+	}
+	// pfBBConfigStartupTimeout is time pf_bb_config of unknown version is given to create its telemetry socket; younger
+	// instances without the socket are not signaled, as versions with the socket are terminated by SIGUSR2
+	pfBBConfigStartupTimeout = 30 * time.Second
+
+	telemetryStateLock sync.Mutex
+	// telemetryCapabilities are capabilities of installed pf_bb_config, see DetectPfBBConfigCapabilities; nil when unknown
+	telemetryCapabilities *pfBBConfigCapabilities
+	// telemetryMechanisms are mechanisms last used for PFs by their PCI address, change is logged
+	telemetryMechanisms = map[string]telemetryMechanism{}
+	// telemetryFormats are formats of response files last parsed for PFs by their PCI address, see telemetryFormat
+	telemetryFormats = map[string]string{}
+)
+
+func setTelemetryCapabilities(capabilities *pfBBConfigCapabilities) {
+	telemetryStateLock.Lock()
+	defer telemetryStateLock.Unlock()
+	telemetryCapabilities = capabilities
+}
+
+// detectTelemetryMechanism picks mechanism supported by pf_bb_config serving the PF. When version of pf_bb_config is
+// known, the mechanism follows from it. Otherwise pf_bb_config is asked through telemetry socket if it exists and
+// signaled if it doesn't.
+func detectTelemetryMechanism(pciAddr string) telemetryMechanism {
+	telemetryStateLock.Lock()
+	capabilities := telemetryCapabilities
+	telemetryStateLock.Unlock()
+	if capabilities != nil {
+		if capabilities.telemetrySocket {
+			return socketTelemetry
+		}
+		return signalTelemetry
+	}
+	if _, err := os.Stat(telemetrySocketPath(pciAddr)); err == nil {
+		return socketTelemetry
+	}
+	return signalTelemetry
+}
+
+// requestTelemetryOfPfBBConfig asks pf_bb_config serving the PF to dump its counters to the response file, using
+// mechanism supported by its version
+func requestTelemetryOfPfBBConfig(pciAddr string, log *logrus.Logger) error {
+	mechanism := detectTelemetryMechanism(pciAddr)
+
+	telemetryStateLock.Lock()
+	previous, known := telemetryMechanisms[pciAddr]
+	telemetryMechanisms[pciAddr] = mechanism
+	versionKnown := telemetryCapabilities != nil
+	telemetryStateLock.Unlock()
+	if !known || previous != mechanism {
+		log.WithField("pciAddr", pciAddr).WithField("mechanism", mechanism).Info("requesting pf_bb_config telemetry")
+	}
+
+	if mechanism == socketTelemetry {
+		return requestTelemetry(pciAddr, log)
+	}
+	// pf_bb_config of unknown version may be still starting up, not having created its socket yet
+	minAge := pfBBConfigStartupTimeout
+	if versionKnown {
+		minAge = 0
+	}
+	return requestTelemetryWithSignal(pciAddr, minAge)
+}
+
+// requestTelemetryWithSignal sends SIGUSR2 to pf_bb_config serving the PF; instances running for less than minAge are
+// not signaled
+func requestTelemetryWithSignal(pciAddr string, minAge time.Duration) error {
+	pids, err := findPfBBConfigProcesses(pciAddr)
+	if err != nil {
+		return err
+	}
+	if len(pids) == 0 {
+		return fmt.Errorf("pf_bb_config serving %s is not running", pciAddr)
+	}
+	for _, pid := range pids {
+		if minAge > 0 {
+			age, err := processAge(pid)
+			if err != nil {
+				return fmt.Errorf("failed to get age of pf_bb_config(%d) - %v", pid, err)
+			}
+			if age < minAge {
+				return fmt.Errorf("pf_bb_config(%d) serving %s started %v ago and has no telemetry socket yet - not signaling it",
+					pid, pciAddr, age.Round(time.Second))
+			}
+		}
+		if err := signalProcess(pid, syscall.SIGUSR2); err != nil {
+			return fmt.Errorf("failed to send SIGUSR2 to pf_bb_config(%d) - %v", pid, err)
+		}
+	}
+	return nil
+}
+
+// telemetryFormat identifies format of the response file - names of counters and shape of the device status, with
+// timestamps and values left out
+func telemetryFormat(file []byte) string {
+	var format []string
+	for _, line := range strings.Split(string(file), "\n") {
+		parts := strings.SplitN(line, "INFO:", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		switch {
+		case strings.Contains(parts[1], "counters"):
+			format = append(format, parts[1])
+		case strings.Contains(parts[1], "Device Status:: "):
+			format = append(format, "Device Status:: ")
+		}
+	}
+	return strings.Join(format, "\n")
+}
+
+var discardingLogger = func() *logrus.Logger {
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	return l
+}()
+
+// telemetryParseLogger returns logger parse errors of the response file are reported with. They are logged only when
+// format of the file changed since it was parsed last time, not on each gathering of the telemetry.
+func telemetryParseLogger(pciAddr string, file []byte, log *logrus.Logger) *logrus.Logger {
+	format := telemetryFormat(file)
+
+	telemetryStateLock.Lock()
+	defer telemetryStateLock.Unlock()
+	if previous, known := telemetryFormats[pciAddr]; known && previous == format {
+		return discardingLogger
+	}
+	telemetryFormats[pciAddr] = format
+	return log
+}
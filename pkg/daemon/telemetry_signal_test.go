@@ -0,0 +1,153 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+)
+
+var _ = Describe("pf_bb_config telemetry mechanism", func() {
+	const pciAddress = "0000:14:00.0"
+
+	var (
+		log                 = logrus.New()
+		originalProcPath    = procPath
+		originalOwnProcPath = ownProcPath
+		originalSignal      = signalProcess
+		originalSocketPath  = telemetrySocketPath
+		socketDir           string
+		signals             map[int][]syscall.Signal
+	)
+
+	// startPfBBConfig creates /proc/<pid> entries of pf_bb_config serving the PF, running for given time
+	startPfBBConfig := func(pid int, age time.Duration) {
+		dir := filepath.Join(procPath, strconv.Itoa(pid))
+		Expect(os.MkdirAll(dir, 0755)).To(Succeed())
+		args := []string{"/sriov_workdir/pf_bb_config", "ACC100", "-c", "/sriov_workdir/" + pciAddress + ".ini", "-p", pciAddress}
+		Expect(os.WriteFile(filepath.Join(dir, "cmdline"), []byte(strings.Join(args, "\x00")+"\x00"), 0644)).To(Succeed())
+		// started 1000s after boot, start time is in clock ticks
+		stat := fmt.Sprintf("%d (pf_bb_config) S 1 %d %d 0 -1 4194560 1 0 0 0 0 0 0 0 20 0 1 0 %d 0 0", pid, pid, pid, 1000*clockTicksPerSecond)
+		Expect(os.WriteFile(filepath.Join(dir, "stat"), []byte(stat), 0644)).To(Succeed())
+		uptime := fmt.Sprintf("%.2f 4000.00\n", 1000+age.Seconds())
+		Expect(os.WriteFile(filepath.Join(procPath, "uptime"), []byte(uptime), 0644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+		procPath, err = os.MkdirTemp(testTmpFolder, "proc")
+		Expect(err).ToNot(HaveOccurred())
+		ownProcPath = procPath
+		// unix socket path is limited in length, so it is not nested in testTmpFolder
+		socketDir, err = os.MkdirTemp("", "sock")
+		Expect(err).ToNot(HaveOccurred())
+		telemetrySocketPath = func(pciAddr string) string { return filepath.Join(socketDir, pciAddr+".sock") }
+
+		signals = map[int][]syscall.Signal{}
+		signalProcess = func(pid int, sig syscall.Signal) error {
+			signals[pid] = append(signals[pid], sig)
+			return nil
+		}
+		telemetryCapabilities = nil
+		telemetryMechanisms = map[string]telemetryMechanism{}
+		telemetryFormats = map[string]string{}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(procPath)).To(Succeed())
+		Expect(os.RemoveAll(socketDir)).To(Succeed())
+		procPath = originalProcPath
+		ownProcPath = originalOwnProcPath
+		signalProcess = originalSignal
+		telemetrySocketPath = originalSocketPath
+		telemetryCapabilities = nil
+	})
+
+	It("should use socket when pf_bb_config provides it", func() {
+		listener, err := net.Listen("unix", telemetrySocketPath(pciAddress))
+		Expect(err).ToNot(HaveOccurred())
+		defer listener.Close()
+		startPfBBConfig(42, time.Second)
+
+		Expect(detectTelemetryMechanism(pciAddress)).To(Equal(socketTelemetry))
+		Expect(requestTelemetryOfPfBBConfig(pciAddress, log)).To(Succeed())
+		Expect(signals).To(BeEmpty())
+	})
+
+	It("should signal pf_bb_config without telemetry socket", func() {
+		startPfBBConfig(42, time.Minute)
+
+		Expect(detectTelemetryMechanism(pciAddress)).To(Equal(signalTelemetry))
+		Expect(requestTelemetryOfPfBBConfig(pciAddress, log)).To(Succeed())
+		Expect(signals).To(Equal(map[int][]syscall.Signal{42: {syscall.SIGUSR2}}))
+	})
+
+	It("should not signal pf_bb_config which may not have created telemetry socket yet", func() {
+		startPfBBConfig(42, 5*time.Second)
+
+		Expect(requestTelemetryOfPfBBConfig(pciAddress, log)).
+			To(MatchError("pf_bb_config(42) serving 0000:14:00.0 started 5s ago and has no telemetry socket yet - not signaling it"))
+		Expect(signals).To(BeEmpty())
+	})
+
+	It("should fail when pf_bb_config without telemetry socket is not running", func() {
+		Expect(requestTelemetryOfPfBBConfig(pciAddress, log)).To(MatchError("pf_bb_config serving 0000:14:00.0 is not running"))
+	})
+
+	Context("pf_bb_config of known version", func() {
+		It("should never signal version with telemetry socket", func() {
+			telemetryCapabilities = &pfBBConfigCapabilities{version: "24.03", telemetrySocket: true}
+			startPfBBConfig(42, time.Hour)
+
+			Expect(detectTelemetryMechanism(pciAddress)).To(Equal(socketTelemetry))
+			Expect(requestTelemetryOfPfBBConfig(pciAddress, log)).ToNot(Succeed())
+			Expect(signals).To(BeEmpty())
+		})
+
+		It("should signal version without telemetry socket right after its startup", func() {
+			telemetryCapabilities = &pfBBConfigCapabilities{version: "21.11"}
+			startPfBBConfig(42, time.Second)
+
+			Expect(detectTelemetryMechanism(pciAddress)).To(Equal(signalTelemetry))
+			Expect(requestTelemetryOfPfBBConfig(pciAddress, log)).To(Succeed())
+			Expect(signals).To(Equal(map[int][]syscall.Signal{42: {syscall.SIGUSR2}}))
+		})
+	})
+
+	Context("parse errors", func() {
+		response := func(timestamp, values string) []byte {
+			return []byte(timestamp + ":INFO:5GUL counters: Code Blocks\n" +
+				timestamp + ":INFO:" + values + "\n" +
+				timestamp + ":INFO:Device Status:: 1 VFs\n" +
+				timestamp + ":INFO:-  VF 0 RTE_BBDEV_DEV_CONFIGURED\n")
+		}
+
+		It("should ignore timestamps and values in the format", func() {
+			Expect(telemetryFormat(response("Tue Sep 13 10:49:25 2022", "0 x"))).
+				To(Equal(telemetryFormat(response("Wed Sep 14 11:00:00 2022", "1 2"))))
+			Expect(telemetryFormat(response("Tue Sep 13 10:49:25 2022", "0"))).
+				To(Equal("5GUL counters: Code Blocks\nDevice Status:: "))
+		})
+
+		It("should be logged once per format", func() {
+			Expect(telemetryParseLogger(pciAddress, response("Tue Sep 13 10:49:25 2022", "x"), log)).To(BeIdenticalTo(log))
+			Expect(telemetryParseLogger(pciAddress, response("Tue Sep 13 10:49:40 2022", "y"), log)).To(BeIdenticalTo(discardingLogger))
+			Expect(telemetryParseLogger("0000:15:00.0", response("Tue Sep 13 10:49:40 2022", "y"), log)).To(BeIdenticalTo(log), "format is tracked per PF")
+
+			changed := []byte("Tue Sep 13 10:49:55 2022:INFO:FFT counters: Per Engine\nTue Sep 13 10:49:55 2022:INFO:x\n")
+			Expect(telemetryParseLogger(pciAddress, changed, log)).To(BeIdenticalTo(log))
+			Expect(telemetryParseLogger(pciAddress, changed, log)).To(BeIdenticalTo(discardingLogger))
+		})
+	})
+})
@@ -0,0 +1,43 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"fmt"
+	"path/filepath"
+
+	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+)
+
+// fecUnsupportedDeviceRequested returns error when any of PFs refers to accelerator which is present on the node, but
+// not supported
+func fecUnsupportedDeviceRequested(pfs []sriovv2.PhysicalFunctionConfigExt, inv *sriovv2.NodeInventory) error {
+	for _, pf := range pfs {
+		for _, device := range inv.UnsupportedDevices {
+			if device.PCIAddress == pf.PCIAddress {
+				return unsupportedDeviceError(device.PCIAddress, device.VendorID, device.DeviceID, configPath)
+			}
+		}
+	}
+	return nil
+}
+
+// vrbUnsupportedDeviceRequested returns error when any of PFs refers to accelerator which is present on the node, but
+// not supported
+func vrbUnsupportedDeviceRequested(pfs []vrbv1.PhysicalFunctionConfigExt, inv *vrbv1.NodeInventory) error {
+	for _, pf := range pfs {
+		for _, device := range inv.UnsupportedDevices {
+			if device.PCIAddress == pf.PCIAddress {
+				return unsupportedDeviceError(device.PCIAddress, device.VendorID, device.DeviceID, VrbconfigPath)
+			}
+		}
+	}
+	return nil
+}
+
+func unsupportedDeviceError(pciAddress, vendorID, deviceID, discoveryConfigPath string) error {
+	return fmt.Errorf("accelerator %s (%s:%s) is present but not supported - device ID %s is missing in %s of supported-accelerators ConfigMap",
+		pciAddress, vendorID, deviceID, deviceID, filepath.Base(discoveryConfigPath))
+}
@@ -6,8 +6,8 @@ package daemon
 import (
 	"archive/tar"
 	"compress/gzip"
+	"context"
 	"crypto/sha1"
-	"crypto/tls"
 	"crypto/x509"
 	"encoding/hex"
 	"errors"
@@ -21,28 +21,63 @@ import (
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/faultinjection"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
 )
 
 type resourceNamePredicate struct {
 	predicate.Funcs
 	requiredName string
+	matching     NodeNameMatching
 	log          *logrus.Logger
 }
 
 func (r resourceNamePredicate) Update(e event.UpdateEvent) bool {
-	if e.ObjectNew.GetName() != r.requiredName {
-		r.log.WithField("expected name", r.requiredName).Info("CR intended for another node - ignoring")
+	return r.hasRequiredName(e.ObjectNew)
+}
+
+func (r resourceNamePredicate) Create(e event.CreateEvent) bool {
+	return r.hasRequiredName(e.Object)
+}
+
+func (r resourceNamePredicate) Delete(e event.DeleteEvent) bool {
+	return r.hasRequiredName(e.Object)
+}
+
+func (r resourceNamePredicate) Generic(e event.GenericEvent) bool {
+	return r.hasRequiredName(e.Object)
+}
+
+// controlAnnotationsChangedPredicate passes update events which change annotations controlling the configuration
+// (e.g. cluster-wide emergency stop); changes of other annotations are ignored
+type controlAnnotationsChangedPredicate struct {
+	predicate.Funcs
+	annotations []string
+}
+
+func (p controlAnnotationsChangedPredicate) Update(e event.UpdateEvent) bool {
+	if e.ObjectOld == nil || e.ObjectNew == nil {
 		return false
 	}
-	return true
+	for _, annotation := range p.annotations {
+		if e.ObjectOld.GetAnnotations()[annotation] != e.ObjectNew.GetAnnotations()[annotation] {
+			return true
+		}
+	}
+	return false
 }
 
-func (r resourceNamePredicate) Create(e event.CreateEvent) bool {
-	if e.Object.GetName() != r.requiredName {
-		r.log.WithField("expected name", r.requiredName).Info("CR intended for another node - ignoring")
+// hasRequiredName passes node config named after the node (or matching it by hostname, with hostname matching) and its
+// secondary node configs
+func (r resourceNamePredicate) hasRequiredName(o client.Object) bool {
+	if o == nil || (!r.matching.matches(o.GetName(), r.requiredName) && !isSecondaryNodeConfigOf(o, r.requiredName)) {
+		r.log.WithField("expected name", r.requiredName).Debug("CR intended for another node - ignoring")
 		return false
 	}
 	return true
@@ -50,7 +85,7 @@ func (r resourceNamePredicate) Create(e event.CreateEvent) bool {
 
 // returns result indicating necessity of re-queuing Reconcile after configured resyncPeriod
 func requeueLater() (reconcile.Result, error) {
-	return reconcile.Result{RequeueAfter: resyncPeriod}, nil
+	return reconcile.Result{RequeueAfter: resyncPeriod.Get()}, nil
 }
 
 // returns result indicating necessity of re-queuing Reconcile(...) immediately; non-nil err will be logged by controller
@@ -62,27 +97,40 @@ func requeueNowWithError(e error) (reconcile.Result, error) {
 // immediately - in case when given err is non-nil;
 // on configured schedule, when err is nil
 func requeueLaterOrNowIfError(e error) (reconcile.Result, error) {
-	return reconcile.Result{RequeueAfter: resyncPeriod}, e
+	return reconcile.Result{RequeueAfter: resyncPeriod.Get()}, e
 }
 
 // operator is unable to write to sysfs files if device is currently in use
-// this function is supposed to either write successfully to file or return timeout error
-func writeFileWithTimeout(filename, data string) error {
-	done := make(chan struct{})
-	var err error
+// this function is supposed to either write successfully to file or return timeout error; it also gives up when ctx
+// is done. Writes are recorded in sysfs audit of the context, if any.
+func writeFileWithTimeout(ctx context.Context, filename, data string) (err error) {
+	if audit := sysfsAuditFrom(ctx); audit != nil {
+		started := daemonClock.Elapsed()
+		defer func() { audit.record(filename, data, err, daemonClock.Elapsed()-started) }()
+	}
+
+	if err := faultinjection.Check(faultinjection.SysfsWrite, filepath.Base(filename), filename); err != nil {
+		return &os.PathError{Op: "write", Path: filename, Err: err}
+	}
+
+	writeCtx, cancel := context.WithTimeout(ctx, sysfsWriteTimeout)
+	defer cancel()
 
+	// buffered, so that writer does not block forever when nobody waits for it anymore
+	done := make(chan error, 1)
 	go func() {
-		err = os.WriteFile(filename, []byte(data), os.ModeAppend)
-		done <- struct{}{}
+		done <- os.WriteFile(filename, []byte(data), os.ModeAppend)
 	}()
 
 	select {
-	case <-done:
+	case err := <-done:
 		return err
-	case <-time.After(60 * time.Second):
+	case <-writeCtx.Done():
+		if ctx.Err() != nil {
+			return fmt.Errorf("write to sysfs file %s interrupted - %v", filename, ctx.Err())
+		}
 		return fmt.Errorf("failed to write to sysfs file. Usually it means that device is in use by other process")
 	}
-
 }
 
 func isHardLink(path string) (bool, error) {
@@ -131,21 +179,7 @@ func OpenFileNoLinks(path string, flag int, perm os.FileMode) (*os.File, error)
 }
 
 func NewSecureHttpsClient(cert *x509.Certificate) (*http.Client, error) {
-	certPool, err := x509.SystemCertPool()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get syscerts - %v", err)
-	}
-	certPool.AddCert(cert)
-	transport := &http.Transport{
-		TLSClientConfig: &tls.Config{
-			RootCAs:    certPool,
-			ClientAuth: tls.RequireAndVerifyClientCert,
-		},
-	}
-	httpsClient := http.Client{
-		Transport: transport,
-	}
-	return &httpsClient, nil
+	return utils.NewHTTPClient(utils.NewLogger(), cert)
 }
 
 func verifyChecksum(path, expected string) (bool, error) {
@@ -287,3 +321,48 @@ func tarHandler(fh *tar.Header, nfDst string, tr *tar.Reader) error {
 	}
 	return nil
 }
+
+// statusPatchData builds merge patch containing only fields which differ between original and updated object.
+// Returned patch is empty ("{}") when objects are equal.
+func statusPatchData(original, updated client.Object) ([]byte, error) {
+	return client.MergeFrom(original).Data(updated)
+}
+
+// apiCallTimeout bounds single call to API server made on behalf of reconcile, so that unresponsive API server
+// does not stall the reconcile forever
+var apiCallTimeout = 30 * time.Second
+
+// sysfsWriteTimeout bounds single write to sysfs file
+var sysfsWriteTimeout = 60 * time.Second
+
+// withAPICallTimeout derives context of single API call from the reconcile context
+func withAPICallTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, apiCallTimeout)
+}
+
+// patchStatus sends single status merge patch built by diffing updated object against the original one.
+// Patch is not sent at all when nothing has changed; returned flag reports whether patch was applied.
+func patchStatus(ctx context.Context, c client.StatusClient, original, updated client.Object) (bool, error) {
+	// reloaded discovery config is reported with the first status write after the reload
+	stampDiscoveryConfigHash(updated)
+	setStandardConditions(updated)
+	boundStatusOf(updated)
+	// summary is derived from spec and status only, so that it does not trigger writes on its own
+	summarizeStatusOf(updated)
+	data, err := statusPatchData(original, updated)
+	if err != nil {
+		return false, fmt.Errorf("failed to build status patch - %v", err)
+	}
+	if string(data) == "{}" {
+		return false, nil
+	}
+	ctx, cancel := withAPICallTimeout(ctx)
+	defer cancel()
+	if err := c.Status().Patch(ctx, updated, client.RawPatch(types.MergePatchType, data)); err != nil {
+		return false, err
+	}
+	// patch response carries spec as stored, which is used further by the caller
+	normalizePCIAddressesOf(updated)
+	recordStatusBaseline(ctx, updated)
+	return true, nil
+}
@@ -0,0 +1,221 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+)
+
+// First VF Offset and VF Stride of SR-IOV capability; kernel updates them whenever sriov_numvfs is written
+const sriovOffsetFile, sriovStrideFile = "sriov_offset", "sriov_stride"
+
+func readSriovCapabilityField(pfPCIAddress, file string) (int, error) {
+	content, err := os.ReadFile(filepath.Join(sysBusPciDevices, pfPCIAddress, file))
+	if err != nil {
+		return 0, err
+	}
+	value, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s of PF (%s) - %v", file, pfPCIAddress, err)
+	}
+	return value, nil
+}
+
+// predictVFAddresses computes PCI addresses of VFs of the PF in virtfn order. Routing ID (bus, device, function) of
+// VF n is routing ID of the PF increased by First VF Offset and n times VF Stride; VFs share PF's PCI domain.
+func predictVFAddresses(pfPCIAddress string, numVFs int) ([]string, error) {
+	if numVFs == 0 {
+		return nil, nil
+	}
+
+	var domain, bus, device, function int
+	if _, err := fmt.Sscanf(pfPCIAddress, "%x:%x:%x.%x", &domain, &bus, &device, &function); err != nil {
+		return nil, fmt.Errorf("failed to parse PCI address (%s) - %v", pfPCIAddress, err)
+	}
+	offset, err := readSriovCapabilityField(pfPCIAddress, sriovOffsetFile)
+	if err != nil {
+		return nil, err
+	}
+	stride, err := readSriovCapabilityField(pfPCIAddress, sriovStrideFile)
+	if err != nil {
+		return nil, err
+	}
+
+	routingID := bus<<8 | device<<3 | function
+	var addresses []string
+	for vf := 0; vf < numVFs; vf++ {
+		vfRoutingID := routingID + offset + vf*stride
+		if vfRoutingID > 0xffff {
+			return nil, fmt.Errorf("VF %d of PF (%s) is beyond the last bus", vf, pfPCIAddress)
+		}
+		addresses = append(addresses, fmt.Sprintf("%04x:%02x:%02x.%x", domain, vfRoutingID>>8, vfRoutingID>>3&0x1f, vfRoutingID&0x7))
+	}
+	return addresses, nil
+}
+
+// vfAddressMismatch describes difference between predicted and probed VF addresses; empty when they match
+func vfAddressMismatch(predicted, probed []string) string {
+	isProbed := map[string]bool{}
+	for _, vf := range probed {
+		isProbed[vf] = true
+	}
+
+	var missing, unexpected []string
+	for _, vf := range predicted {
+		if !isProbed[vf] {
+			missing = append(missing, vf)
+		}
+		delete(isProbed, vf)
+	}
+	for vf := range isProbed {
+		unexpected = append(unexpected, vf)
+	}
+	sort.Strings(unexpected)
+
+	var mismatch []string
+	if len(missing) > 0 {
+		mismatch = append(mismatch, "predicted VFs not probed: "+strings.Join(missing, ", "))
+	}
+	if len(unexpected) > 0 {
+		mismatch = append(mismatch, "unexpected VFs probed: "+strings.Join(unexpected, ", "))
+	}
+	return strings.Join(mismatch, "; ")
+}
+
+// setPredictedVFs replaces entry of the PF; entry is removed when PF has no VFs
+func setPredictedVFs(predicted []sriovv2.PredictedVFs, pfPCIAddress string, vfs []string) []sriovv2.PredictedVFs {
+	var updated []sriovv2.PredictedVFs
+	for _, entry := range predicted {
+		if entry.PCIAddress != pfPCIAddress {
+			updated = append(updated, entry)
+		}
+	}
+	if len(vfs) > 0 {
+		updated = append(updated, sriovv2.PredictedVFs{PCIAddress: pfPCIAddress, VFs: vfs})
+	}
+	sort.Slice(updated, func(i, j int) bool { return updated[i].PCIAddress < updated[j].PCIAddress })
+	return updated
+}
+
+// publishPredictedVFs stores predicted addresses of PF's VFs in SriovFecNodeConfig status right after VFs are enabled,
+// so that they are known before the VFs are probed and the device plugin is restarted. It is a best-effort step of
+// the configuration (stepPublishPredictedVFs).
+func (n *NodeConfigurator) publishPredictedVFs(ctx context.Context, pfPCIAddress string, numVFs int) error {
+	vfs, err := predictVFAddresses(pfPCIAddress, numVFs)
+	if err != nil {
+		return err
+	}
+
+	// published in node config being configured, which may be a secondary one
+	key := n.nodeConfigRef()
+	if scope, ok := acceleratorScopeFrom(ctx); ok {
+		key = scope.nodeConfig
+	}
+	original := &sriovv2.SriovFecNodeConfig{}
+	if err := n.Get(ctx, key, original); err != nil {
+		return err
+	}
+	updated := original.DeepCopy()
+	updated.Status.PredictedVFs = setPredictedVFs(original.Status.PredictedVFs, pfPCIAddress, vfs)
+	if _, err := patchStatus(ctx, n.Client, original, updated); err != nil {
+		return err
+	}
+	n.Log.WithField("pf", pfPCIAddress).WithField("vfs", vfs).Info("published predicted VF addresses")
+	return nil
+}
+
+func (n *NodeConfigurator) VrbpublishPredictedVFs(ctx context.Context, pfPCIAddress string, numVFs int) error {
+	vfs, err := predictVFAddresses(pfPCIAddress, numVFs)
+	if err != nil {
+		return err
+	}
+
+	original := &vrbv1.SriovVrbNodeConfig{}
+	if err := n.Get(ctx, n.nodeConfigRef(), original); err != nil {
+		return err
+	}
+	updated := original.DeepCopy()
+	updated.Status.PredictedVFs = toVrbPredictedVFs(setPredictedVFs(fromVrbPredictedVFs(original.Status.PredictedVFs), pfPCIAddress, vfs))
+	if _, err := patchStatus(ctx, n.Client, original, updated); err != nil {
+		return err
+	}
+	n.Log.WithField("pf", pfPCIAddress).WithField("vfs", vfs).Info("published predicted VF addresses")
+	return nil
+}
+
+func fromVrbPredictedVFs(predicted []vrbv1.PredictedVFs) []sriovv2.PredictedVFs {
+	var converted []sriovv2.PredictedVFs
+	for _, entry := range predicted {
+		converted = append(converted, sriovv2.PredictedVFs(entry))
+	}
+	return converted
+}
+
+func toVrbPredictedVFs(predicted []sriovv2.PredictedVFs) []vrbv1.PredictedVFs {
+	var converted []vrbv1.PredictedVFs
+	for _, entry := range predicted {
+		converted = append(converted, vrbv1.PredictedVFs(entry))
+	}
+	return converted
+}
+
+// verifyPredictedVFs compares predicted VF addresses with VFs found in the inventory. Entries of PFs which are not
+// configured with VFs anymore are dropped; entries of PFs which VFs were not probed yet are left unverified.
+func verifyPredictedVFs(predicted []sriovv2.PredictedVFs, configured map[string]bool, probed map[string][]string, log *logrus.Logger) []sriovv2.PredictedVFs {
+	var verified []sriovv2.PredictedVFs
+	for _, entry := range predicted {
+		if !configured[entry.PCIAddress] {
+			continue
+		}
+		entry.Verified, entry.Mismatch = false, ""
+		if vfs := probed[entry.PCIAddress]; len(vfs) > 0 {
+			entry.Mismatch = vfAddressMismatch(entry.VFs, vfs)
+			entry.Verified = entry.Mismatch == ""
+		}
+		if entry.Mismatch != "" {
+			log.WithField("pf", entry.PCIAddress).WithField("mismatch", entry.Mismatch).Warn("probed VFs differ from predicted ones")
+		}
+		verified = append(verified, entry)
+	}
+	return verified
+}
+
+func fecVerifyPredictedVFs(nc *sriovv2.SriovFecNodeConfig, log *logrus.Logger) {
+	configured := map[string]bool{}
+	for _, pf := range nc.Spec.PhysicalFunctions {
+		configured[pf.PCIAddress] = pf.VFAmount > 0
+	}
+	probed := map[string][]string{}
+	for _, acc := range nc.Status.Inventory.SriovAccelerators {
+		for _, vf := range acc.VFs {
+			probed[acc.PCIAddress] = append(probed[acc.PCIAddress], vf.PCIAddress)
+		}
+	}
+	nc.Status.PredictedVFs = verifyPredictedVFs(nc.Status.PredictedVFs, configured, probed, log)
+}
+
+func vrbVerifyPredictedVFs(nc *vrbv1.SriovVrbNodeConfig, log *logrus.Logger) {
+	configured := map[string]bool{}
+	for _, pf := range nc.Spec.PhysicalFunctions {
+		configured[pf.PCIAddress] = pf.VFAmount > 0
+	}
+	probed := map[string][]string{}
+	for _, acc := range nc.Status.Inventory.SriovAccelerators {
+		for _, vf := range acc.VFs {
+			probed[acc.PCIAddress] = append(probed[acc.PCIAddress], vf.PCIAddress)
+		}
+	}
+	nc.Status.PredictedVFs = toVrbPredictedVFs(verifyPredictedVFs(fromVrbPredictedVFs(nc.Status.PredictedVFs), configured, probed, log))
+}
@@ -0,0 +1,109 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("VF address prediction", func() {
+	const pf = "0000:f7:00.0"
+
+	var originalSysBusPciDevices = sysBusPciDevices
+
+	setOffsetAndStride := func(pf, offset, stride string) {
+		Expect(createFiles(filepath.Join(sysBusPciDevices, pf), sriovOffsetFile, sriovStrideFile)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(sysBusPciDevices, pf, sriovOffsetFile), []byte(offset+"\n"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(sysBusPciDevices, pf, sriovStrideFile), []byte(stride+"\n"), 0644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		root, err := os.MkdirTemp(testTmpFolder, "sysfs")
+		Expect(err).ToNot(HaveOccurred())
+		sysBusPciDevices = root
+	})
+
+	AfterEach(func() {
+		sysBusPciDevices = originalSysBusPciDevices
+	})
+
+	It("should compute VF addresses from routing ID of the PF, offset and stride", func() {
+		setOffsetAndStride(pf, "1", "1")
+		Expect(predictVFAddresses(pf, 8)).To(Equal([]string{
+			"0000:f7:00.1", "0000:f7:00.2", "0000:f7:00.3", "0000:f7:00.4",
+			"0000:f7:00.5", "0000:f7:00.6", "0000:f7:00.7", "0000:f7:01.0",
+		}))
+
+		setOffsetAndStride("0001:3e:1f.4", "8", "2")
+		Expect(predictVFAddresses("0001:3e:1f.4", 2)).To(Equal([]string{"0001:3f:00.4", "0001:3f:00.6"}))
+
+		// PF behind VMD
+		setOffsetAndStride("10000:af:00.0", "1", "1")
+		Expect(predictVFAddresses("10000:af:00.0", 2)).To(Equal([]string{"10000:af:00.1", "10000:af:00.2"}))
+
+		Expect(predictVFAddresses(pf, 0)).To(BeEmpty())
+	})
+
+	It("should fail when SR-IOV capability of the PF is not exposed or VFs do not fit the last bus", func() {
+		_, err := predictVFAddresses(pf, 1)
+		Expect(err).To(HaveOccurred())
+
+		setOffsetAndStride("0000:ff:1f.0", "8", "1")
+		_, err = predictVFAddresses("0000:ff:1f.0", 1)
+		Expect(err).To(MatchError("VF 0 of PF (0000:ff:1f.0) is beyond the last bus"))
+	})
+
+	It("should publish predicted VFs in NodeConfig status and verify them against probed VFs", func() {
+		setOffsetAndStride(pf, "1", "1")
+		nodeNameRef := types.NamespacedName{Name: "worker", Namespace: "testNamespace"}
+		scheme := runtime.NewScheme()
+		Expect(sriovv2.AddToScheme(scheme)).To(Succeed())
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&sriovv2.SriovFecNodeConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace},
+			Spec: sriovv2.SriovFecNodeConfigSpec{
+				PhysicalFunctions: []sriovv2.PhysicalFunctionConfigExt{{PCIAddress: pf, VFAmount: 2}},
+			},
+		}).Build()
+
+		configurator := &NodeConfigurator{Client: c, Log: utils.NewLogger(), nodeNameRef: nodeNameRef}
+		Expect(configurator.publishPredictedVFs(context.TODO(), pf, 2)).To(Succeed())
+
+		predicted, err := sriovv2.GetPredictedVFs(context.TODO(), c, nodeNameRef.Namespace, nodeNameRef.Name, pf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(predicted).To(Equal(sriovv2.PredictedVFs{PCIAddress: pf, VFs: []string{"0000:f7:00.1", "0000:f7:00.2"}}))
+		_, err = sriovv2.GetPredictedVFs(context.TODO(), c, nodeNameRef.Namespace, nodeNameRef.Name, "0000:f8:00.0")
+		Expect(err).To(MatchError("VFs of PF 0000:f8:00.0 are not published in SriovFecNodeConfig testNamespace/worker"))
+
+		nc := &sriovv2.SriovFecNodeConfig{}
+		Expect(c.Get(context.TODO(), nodeNameRef, nc)).To(Succeed())
+		nc.Status.Inventory.SriovAccelerators = []sriovv2.SriovAccelerator{{
+			PCIAddress: pf,
+			VFs:        []sriovv2.VF{{PCIAddress: "0000:f7:00.2"}, {PCIAddress: "0000:f7:00.1"}},
+		}}
+		fecVerifyPredictedVFs(nc, utils.NewLogger())
+		Expect(nc.Status.PredictedVFs).To(HaveLen(1))
+		Expect(nc.Status.PredictedVFs[0].Verified).To(BeTrue())
+
+		nc.Status.Inventory.SriovAccelerators[0].VFs = []sriovv2.VF{{PCIAddress: "0000:f7:00.1"}, {PCIAddress: "0000:f7:02.0"}}
+		fecVerifyPredictedVFs(nc, utils.NewLogger())
+		Expect(nc.Status.PredictedVFs[0].Verified).To(BeFalse())
+		Expect(nc.Status.PredictedVFs[0].Mismatch).To(Equal("predicted VFs not probed: 0000:f7:00.2; unexpected VFs probed: 0000:f7:02.0"))
+
+		nc.Spec.PhysicalFunctions[0].VFAmount = 0
+		fecVerifyPredictedVFs(nc, utils.NewLogger())
+		Expect(nc.Status.PredictedVFs).To(BeEmpty(), "PF without VFs is not listed")
+	})
+})
@@ -0,0 +1,48 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// vfTotalMSIXFile is exposed by devices which allow to distribute MSI-X vectors among VFs
+const vfTotalMSIXFile = "sriov_vf_total_msix"
+
+// readVFTotalMSIX returns number of MSI-X vectors available for VFs of PF; false when device does not expose it
+func readVFTotalMSIX(pciAddress string) (int, bool) {
+	content, err := os.ReadFile(filepath.Join(sysBusPciDevices, pciAddress, vfTotalMSIXFile))
+	if err != nil {
+		return 0, false
+	}
+	total, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil {
+		return 0, false
+	}
+	return total, true
+}
+
+// checkVFsMSIXFeasibility verifies, before the node is drained, that requested VFs (by PF's PCI address) can be
+// created. Every VF needs at least one MSI-X vector, so PF which exposes sriov_vf_total_msix cannot have more VFs
+// than vectors. PFs which do not expose sriov_vf_total_msix are not checked.
+func checkVFsMSIXFeasibility(requested map[string]int) error {
+	var pfs []string
+	for pf := range requested {
+		pfs = append(pfs, pf)
+	}
+	sort.Strings(pfs)
+
+	for _, pf := range pfs {
+		if total, ok := readVFTotalMSIX(pf); ok && requested[pf] > total {
+			return fmt.Errorf("requested %d VFs for PF (%s) but device exposes only %d MSI-X vectors for VFs (%s) - reduce vfAmount",
+				requested[pf], pf, total, vfTotalMSIXFile)
+		}
+	}
+	return nil
+}
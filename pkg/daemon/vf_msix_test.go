@@ -0,0 +1,84 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("VFs MSI-X", func() {
+	const (
+		pfWithMSIX    = "0000:f7:00.0"
+		pfWithoutMSIX = "0000:f8:00.0"
+	)
+
+	var (
+		originalSysBusPciDevices = sysBusPciDevices
+		originalGetVFconfigured  = getVFconfigured
+		originalGetVFList        = getVFList
+		limit                    int
+	)
+
+	BeforeEach(func() {
+		root, err := os.MkdirTemp(testTmpFolder, "sysfs")
+		Expect(err).ToNot(HaveOccurred())
+		sysBusPciDevices = root
+		Expect(createFiles(filepath.Join(sysBusPciDevices, pfWithMSIX), vfNumFileDefault, vfTotalMSIXFile)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(sysBusPciDevices, pfWithMSIX, vfTotalMSIXFile), []byte("8\n"), 0644)).To(Succeed())
+		Expect(createFiles(filepath.Join(sysBusPciDevices, pfWithoutMSIX), vfNumFileDefault)).To(Succeed())
+
+		// kernel creates requested amount of VFs, but at most "limit" of them
+		limit = 0
+		getVFconfigured = func(pf string) int {
+			content, _ := os.ReadFile(filepath.Join(sysBusPciDevices, pf, vfNumFileDefault))
+			requested, _ := strconv.Atoi(string(content))
+			if requested > limit {
+				return limit
+			}
+			return requested
+		}
+		getVFList = func(pf string) ([]string, error) {
+			return make([]string, getVFconfigured(pf)), nil
+		}
+	})
+
+	AfterEach(func() {
+		sysBusPciDevices = originalSysBusPciDevices
+		getVFconfigured = originalGetVFconfigured
+		getVFList = originalGetVFList
+	})
+
+	It("should reject requested VFs exceeding MSI-X vectors before configuration", func() {
+		Expect(checkVFsMSIXFeasibility(map[string]int{pfWithMSIX: 8, pfWithoutMSIX: 64})).To(Succeed())
+
+		err := checkVFsMSIXFeasibility(map[string]int{pfWithMSIX: 16})
+		Expect(err).To(MatchError(ContainSubstring("requested 16 VFs for PF (0000:f7:00.0) but device exposes only 8 MSI-X vectors")))
+	})
+
+	It("should fail when kernel creates fewer VFs than requested", func() {
+		nc := &NodeConfigurator{Log: utils.NewLogger()}
+		limit = 8
+		err := nc.changeAmountOfVFs(context.TODO(), utils.PCI_PF_STUB_DASH, pfWithMSIX, 16)
+		Expect(err).To(MatchError(ContainSubstring("kernel created only 8 of 16 requested VFs for PF (0000:f7:00.0)")))
+		Expect(err).To(MatchError(ContainSubstring("device exposes 8 MSI-X vectors")))
+
+		limit = 2
+		err = nc.changeAmountOfVFs(context.TODO(), utils.PCI_PF_STUB_DASH, pfWithoutMSIX, 4)
+		Expect(err).To(MatchError("kernel created only 2 of 4 requested VFs for PF (0000:f8:00.0)"))
+	})
+
+	It("should succeed when kernel creates all requested VFs", func() {
+		nc := &NodeConfigurator{Log: utils.NewLogger()}
+		limit = 8
+		Expect(nc.changeAmountOfVFs(context.TODO(), utils.PCI_PF_STUB_DASH, pfWithMSIX, 8)).To(Succeed())
+	})
+})
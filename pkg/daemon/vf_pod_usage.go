@@ -0,0 +1,211 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	podresourcesv1 "k8s.io/kubelet/pkg/apis/podresources/v1"
+
+	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+)
+
+const (
+	// VFUsageUnknown is reported as user of every VF when pods using VFs could not be determined
+	VFUsageUnknown = "unknown"
+
+	podResourcesTimeout = 10 * time.Second
+)
+
+var (
+	// podResourcesSocket is kubelet pod-resources API socket, mounted from the host into the daemon container
+	podResourcesSocket = "/var/lib/kubelet/pod-resources/kubelet.sock"
+
+	// listDeviceUsers maps IDs of devices allocated to pods to namespace/name of the pods
+	listDeviceUsers = func(ctx context.Context) (map[string]string, error) {
+		pods, err := listPodResources(ctx, podResourcesSocket)
+		if err != nil {
+			return nil, err
+		}
+		return deviceUsers(pods), nil
+	}
+)
+
+// podResourcesEnricher sets UsedBy of VFs allocated (by the device plugin) to pods, as reported by kubelet
+// pod-resources API. When the API is not available, UsedBy of all VFs is set to "unknown" - inventory is not failed.
+type podResourcesEnricher struct {
+	list func(ctx context.Context) ([]*podresourcesv1.PodResources, error)
+	log  *logrus.Logger
+}
+
+func newPodResourcesEnricher(socket string, log *logrus.Logger) podResourcesEnricher {
+	return podResourcesEnricher{
+		list: func(ctx context.Context) ([]*podresourcesv1.PodResources, error) {
+			return listPodResources(ctx, socket)
+		},
+		log: log,
+	}
+}
+
+func (e podResourcesEnricher) Name() string {
+	return "vf-pod-usage"
+}
+
+func (e podResourcesEnricher) Enrich(ctx context.Context, inventory *sriovv2.NodeInventory) error {
+	pods, err := e.list(ctx)
+	if err != nil {
+		e.log.WithError(err).Warn("failed to read pods using VFs - reporting them as unknown")
+	}
+	users := deviceUsers(pods)
+
+	for i := range inventory.SriovAccelerators {
+		vfs := inventory.SriovAccelerators[i].VFs
+		for j := range vfs {
+			if err != nil {
+				vfs[j].UsedBy = VFUsageUnknown
+			} else {
+				vfs[j].UsedBy = users[vfs[j].PCIAddress]
+			}
+		}
+	}
+	return nil
+}
+
+// deviceUsers maps IDs of allocated devices (PCI addresses of VFs) to namespace/name of pods using them
+func deviceUsers(pods []*podresourcesv1.PodResources) map[string]string {
+	users := map[string]string{}
+	for _, pod := range pods {
+		for _, container := range pod.GetContainers() {
+			for _, devices := range container.GetDevices() {
+				for _, id := range devices.GetDeviceIds() {
+					users[id] = pod.GetNamespace() + "/" + pod.GetName()
+				}
+			}
+		}
+	}
+	return users
+}
+
+func listPodResources(ctx context.Context, socket string) ([]*podresourcesv1.PodResources, error) {
+	// dial would be retried until timeout otherwise
+	if _, err := os.Stat(socket); err != nil {
+		return nil, fmt.Errorf("pod-resources socket is not available - %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, podResourcesTimeout)
+	defer cancel()
+
+	conn, err := grpc.DialContext(ctx, "unix://"+socket, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to pod-resources socket %s - %v", socket, err)
+	}
+	defer conn.Close()
+
+	resp, err := podresourcesv1.NewPodResourcesListerClient(conn).List(ctx, &podresourcesv1.ListPodResourcesRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pod resources - %v", err)
+	}
+	return resp.GetPodResources(), nil
+}
+
+func fecVFUsers(inventory *sriovv2.NodeInventory) []string {
+	var users []string
+	for _, acc := range inventory.SriovAccelerators {
+		for _, vf := range acc.VFs {
+			users = append(users, vf.UsedBy)
+		}
+	}
+	return users
+}
+
+func vrbVFUsers(inventory *vrbv1.NodeInventory) []string {
+	var users []string
+	for _, acc := range inventory.SriovAccelerators {
+		for _, vf := range acc.VFs {
+			users = append(users, vf.UsedBy)
+		}
+	}
+	return users
+}
+
+// vfPodDisruptionMessage tells how many pods using VFs are going to be disrupted by reconfiguration; empty when no
+// VF is reported as used
+func vfPodDisruptionMessage(users []string) string {
+	pods := map[string]bool{}
+	for _, user := range users {
+		switch user {
+		case "":
+		case VFUsageUnknown:
+			return "; reconfiguration may disrupt pods using VFs (usage unknown)"
+		default:
+			pods[user] = true
+		}
+	}
+	switch len(pods) {
+	case 0:
+		return ""
+	case 1:
+		return "; reconfiguration will disrupt 1 pod"
+	default:
+		return fmt.Sprintf("; reconfiguration will disrupt %d pods", len(pods))
+	}
+}
+
+func fecVFAddresses(inventory *sriovv2.NodeInventory) []string {
+	var vfs []string
+	for _, acc := range inventory.SriovAccelerators {
+		for _, vf := range acc.VFs {
+			vfs = append(vfs, vf.PCIAddress)
+		}
+	}
+	return vfs
+}
+
+func vrbVFAddresses(inventory *vrbv1.NodeInventory) []string {
+	var vfs []string
+	for _, acc := range inventory.SriovAccelerators {
+		for _, vf := range acc.VFs {
+			vfs = append(vfs, vf.PCIAddress)
+		}
+	}
+	return vfs
+}
+
+// podsUsingVFs returns sorted namespace/name of pods which are allocated any of given VFs, as reported by kubelet
+// pod-resources API; it is queried regardless of VFPodUsage gate. When the API is not available, VFs are assumed to
+// be unused.
+func (r *NodeConfigReconciler) podsUsingVFs(ctx context.Context, vfs []string) []string {
+	users, err := listDeviceUsers(ctx)
+	if err != nil {
+		r.log.WithError(err).Warn("failed to read pods using VFs - assuming VFs are not used")
+		return nil
+	}
+	pods := map[string]bool{}
+	for _, vf := range vfs {
+		if pod, ok := users[vf]; ok {
+			pods[pod] = true
+		}
+	}
+	var sorted []string
+	for pod := range pods {
+		sorted = append(sorted, pod)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// deviceInUseMessage explains why configuration without drain was refused
+func deviceInUseMessage(pods []string) string {
+	return fmt.Sprintf("VFs to be removed or rebound are allocated to running pods (%s) and drain is skipped; "+
+		"set forceVfRemoval to configure anyway", strings.Join(pods, ", "))
+}
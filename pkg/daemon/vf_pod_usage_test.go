@@ -0,0 +1,239 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	podresourcesv1 "k8s.io/kubelet/pkg/apis/podresources/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+type fakePodResourcesServer struct {
+	podresourcesv1.UnimplementedPodResourcesListerServer
+	pods []*podresourcesv1.PodResources
+}
+
+func (s *fakePodResourcesServer) List(context.Context, *podresourcesv1.ListPodResourcesRequest) (*podresourcesv1.ListPodResourcesResponse, error) {
+	return &podresourcesv1.ListPodResourcesResponse{PodResources: s.pods}, nil
+}
+
+func podUsingDevices(namespace, name, resource string, ids ...string) *podresourcesv1.PodResources {
+	return &podresourcesv1.PodResources{
+		Namespace: namespace,
+		Name:      name,
+		Containers: []*podresourcesv1.ContainerResources{
+			{Name: "app", Devices: []*podresourcesv1.ContainerDevices{{ResourceName: resource, DeviceIds: ids}}},
+		},
+	}
+}
+
+var _ = Describe("VFPodUsage", func() {
+	var (
+		log       = utils.NewLogger()
+		inventory *sriovv2.NodeInventory
+	)
+
+	BeforeEach(func() {
+		inventory = &sriovv2.NodeInventory{SriovAccelerators: []sriovv2.SriovAccelerator{{
+			PCIAddress: "0000:f7:00.0",
+			VFs:        []sriovv2.VF{{PCIAddress: "0000:f7:00.1"}, {PCIAddress: "0000:f7:00.2"}, {PCIAddress: "0000:f7:00.3"}},
+		}}}
+	})
+
+	It("should read pods using VFs from pod-resources socket", func() {
+		dir, err := os.MkdirTemp("", "podresources")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		socket := filepath.Join(dir, "kubelet.sock")
+		listener, err := net.Listen("unix", socket)
+		Expect(err).ToNot(HaveOccurred())
+		server := grpc.NewServer()
+		podresourcesv1.RegisterPodResourcesListerServer(server, &fakePodResourcesServer{pods: []*podresourcesv1.PodResources{
+			podUsingDevices("vran", "du-0", "intel.com/intel_fec_acc200", "0000:f7:00.1"),
+			podUsingDevices("vran", "du-1", "intel.com/intel_fec_acc200", "0000:f7:00.3"),
+			podUsingDevices("default", "nic", "intel.com/intel_sriov_netdevice", "0000:18:02.0"),
+		}})
+		go func() { _ = server.Serve(listener) }()
+		defer server.Stop()
+
+		Expect(newPodResourcesEnricher(socket, log).Enrich(context.TODO(), inventory)).To(Succeed())
+		vfs := inventory.SriovAccelerators[0].VFs
+		Expect(vfs[0].UsedBy).To(Equal("vran/du-0"))
+		Expect(vfs[1].UsedBy).To(BeEmpty())
+		Expect(vfs[2].UsedBy).To(Equal("vran/du-1"))
+		Expect(vfPodDisruptionMessage(fecVFUsers(inventory))).To(Equal("; reconfiguration will disrupt 2 pods"))
+	})
+
+	It("should report VFs as used by unknown pods when socket is not mounted", func() {
+		enricher := newPodResourcesEnricher(filepath.Join(os.TempDir(), "missing", "kubelet.sock"), log)
+		Expect(enricher.Enrich(context.TODO(), inventory)).To(Succeed())
+		for _, vf := range inventory.SriovAccelerators[0].VFs {
+			Expect(vf.UsedBy).To(Equal(VFUsageUnknown))
+		}
+		Expect(vfPodDisruptionMessage(fecVFUsers(inventory))).To(ContainSubstring("usage unknown"))
+	})
+
+	It("should report VFs as used by unknown pods when listing fails", func() {
+		enricher := podResourcesEnricher{log: log, list: func(context.Context) ([]*podresourcesv1.PodResources, error) {
+			return nil, errors.New("rpc error: code = Unavailable")
+		}}
+		Expect(enricher.Enrich(context.TODO(), inventory)).To(Succeed())
+		Expect(inventory.SriovAccelerators[0].VFs[0].UsedBy).To(Equal(VFUsageUnknown))
+	})
+
+	It("should count every pod once in disruption message", func() {
+		Expect(vfPodDisruptionMessage(nil)).To(BeEmpty())
+		Expect(vfPodDisruptionMessage([]string{"", ""})).To(BeEmpty())
+		Expect(vfPodDisruptionMessage([]string{"vran/du-0", "vran/du-0", ""})).To(Equal("; reconfiguration will disrupt 1 pod"))
+		Expect(vfPodDisruptionMessage([]string{"vran/du-0", "vran/du-1", "vran/du-2"})).To(Equal("; reconfiguration will disrupt 3 pods"))
+	})
+})
+
+var _ = Describe("NodeConfigReconciler.Reconcile of VFs in use", func() {
+	const usedVF = "0000:15:00.1"
+
+	var (
+		fakeClient  client.Client
+		nodeNameRef = types.NamespacedName{Name: "worker", Namespace: "testNamespace"}
+		reconciler  NodeConfigReconciler
+		configured  bool
+		drained     bool
+
+		originalListDeviceUsers = listDeviceUsers
+	)
+
+	setSpec := func(drainSkip, forceVfRemoval bool) {
+		sfnc := new(sriovv2.SriovFecNodeConfig)
+		Expect(fakeClient.Get(context.TODO(), nodeNameRef, sfnc)).To(Succeed())
+		sfnc.Spec.DrainSkip, sfnc.Spec.ForceVfRemoval = drainSkip, forceVfRemoval
+		Expect(fakeClient.Update(context.TODO(), sfnc)).To(Succeed())
+	}
+
+	reconcile := func() *metav1.Condition {
+		_, err := reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: nodeNameRef})
+		Expect(err).ToNot(HaveOccurred())
+		sfnc := new(sriovv2.SriovFecNodeConfig)
+		Expect(fakeClient.Get(context.TODO(), nodeNameRef, sfnc)).To(Succeed())
+		return sfnc.FindCondition(ConditionConfigured)
+	}
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(sriovv2.AddToScheme(scheme)).ToNot(HaveOccurred())
+		Expect(vrbv1.AddToScheme(scheme)).ToNot(HaveOccurred())
+
+		procCmdlineFilePath = "testdata/cmdline_test"
+		sysLockdownFilePath = filepath.Join(testTmpFolder, "lockdown")
+		Expect(os.WriteFile(sysLockdownFilePath, []byte("[none] integrity confidentiality"), 0644)).To(Succeed())
+		configured, drained = false, false
+
+		// two VFs exist, the spec shrinks them to one
+		getSriovInventory = func(_ context.Context, log *logrus.Logger) (*sriovv2.NodeInventory, error) {
+			vfs := []sriovv2.VF{{PCIAddress: "0000:15:00.0", Driver: utils.IGB_UIO}}
+			if !configured {
+				vfs = append(vfs, sriovv2.VF{PCIAddress: usedVF, Driver: utils.IGB_UIO})
+			}
+			return &sriovv2.NodeInventory{SriovAccelerators: []sriovv2.SriovAccelerator{
+				{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, MaxVFs: 16, VFs: vfs},
+			}}, nil
+		}
+		VrbgetSriovInventory = func(_ context.Context, log *logrus.Logger) (*vrbv1.NodeInventory, error) {
+			return &vrbv1.NodeInventory{}, nil
+		}
+		listDeviceUsers = func(context.Context) (map[string]string, error) {
+			return map[string]string{usedVF: "vran/du-0", "0000:99:00.1": "vran/other"}, nil
+		}
+
+		sfnc := &sriovv2.SriovFecNodeConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace, Generation: 1},
+			Spec: sriovv2.SriovFecNodeConfigSpec{
+				PhysicalFunctions: []sriovv2.PhysicalFunctionConfigExt{{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, VFDriver: utils.IGB_UIO, VFAmount: 1}},
+				DrainSkip:         true,
+			},
+		}
+		vrbnc := &vrbv1.SriovVrbNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace}}
+		fakeClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(sfnc, vrbnc).Build()
+
+		reconciler = NodeConfigReconciler{
+			Client:      fakeClient,
+			log:         utils.NewLogger(),
+			nodeNameRef: nodeNameRef,
+			sriovfecconfigurer: testConfigurerProto{configureNodeFunction: func(sriovv2.SriovFecNodeConfigSpec) error {
+				configured = true
+				return nil
+			}},
+			drainerAndExecute: func(_ context.Context, configurer func(ctx context.Context) bool, drain bool) error {
+				drained = drain
+				_ = configurer(context.TODO())
+				return nil
+			},
+			restartDevicePlugin: func(context.Context) error { return nil },
+		}
+	})
+
+	AfterEach(func() {
+		getSriovInventory = GetSriovInventory
+		VrbgetSriovInventory = VrbGetSriovInventory
+		listDeviceUsers = originalListDeviceUsers
+		sysLockdownFilePath = "/sys/kernel/security/lockdown"
+	})
+
+	It("refuses configuration without drain when VFs are allocated to pods", func() {
+		condition := reconcile()
+
+		Expect(configured).To(BeFalse())
+		Expect(condition.Reason).To(Equal(string(ConfigurationDeviceInUse)))
+		Expect(condition.Message).To(Equal("VFs to be removed or rebound are allocated to running pods (vran/du-0) and " +
+			"drain is skipped; set forceVfRemoval to configure anyway"))
+	})
+
+	It("configures node without drain when VF removal is forced", func() {
+		setSpec(true, true)
+
+		Expect(reconcile().Reason).To(Equal(string(ConfigurationSucceeded)))
+		Expect(configured).To(BeTrue())
+		Expect(drained).To(BeFalse())
+	})
+
+	It("configures node once pods release VFs", func() {
+		Expect(reconcile().Reason).To(Equal(string(ConfigurationDeviceInUse)))
+
+		listDeviceUsers = func(context.Context) (map[string]string, error) { return map[string]string{}, nil }
+
+		Expect(reconcile().Reason).To(Equal(string(ConfigurationSucceeded)))
+		Expect(configured).To(BeTrue())
+	})
+
+	It("does not check VFs in use when node is drained", func() {
+		setSpec(false, false)
+
+		Expect(reconcile().Reason).To(Equal(string(ConfigurationSucceeded)))
+		Expect(drained).To(BeTrue())
+	})
+
+	It("does not refuse configuration when pods using VFs cannot be determined", func() {
+		listDeviceUsers = func(context.Context) (map[string]string, error) { return nil, errors.New("socket not mounted") }
+
+		Expect(reconcile().Reason).To(Equal(string(ConfigurationSucceeded)))
+	})
+})
@@ -0,0 +1,44 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	fec "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+// newerWriterDeferral returns reason of refusing to act on node config written by newer version of the operator than
+// the daemon's one, e.g. during rollback of the operator; its spec may carry fields the daemon does not know, so it
+// would be applied only partially. Empty reason is returned when the node config can be acted on, or when the refusal
+// is overridden with IgnoreWriterVersionAnnotation.
+func (r *NodeConfigReconciler) newerWriterDeferral(nc client.Object) string {
+	writtenByAnnotation, ignoreAnnotation := fec.WrittenByVersionAnnotation, fec.IgnoreWriterVersionAnnotation
+	if _, ok := nc.(*vrbv1.SriovVrbNodeConfig); ok {
+		writtenByAnnotation, ignoreAnnotation = vrbv1.WrittenByVersionAnnotation, vrbv1.IgnoreWriterVersionAnnotation
+	}
+
+	written := nc.GetAnnotations()[writtenByAnnotation]
+	newer, err := utils.IsNewerOperatorVersion(written, utils.OperatorVersion)
+	if err != nil {
+		r.log.WithError(err).WithField("annotation", writtenByAnnotation).Warn("cannot compare operator version which wrote node config - acting on it")
+		return ""
+	}
+	if !newer {
+		return ""
+	}
+
+	log := r.log.WithField("nodeConfig", nc.GetName()).WithField("writtenBy", written).WithField("version", utils.OperatorVersion)
+	if nc.GetAnnotations()[ignoreAnnotation] == "true" {
+		log.Warn("node config written by newer operator version - acting on it, as forced with annotation")
+		return ""
+	}
+	log.Info("node config written by newer operator version - refusing to act")
+	return fmt.Sprintf("written by newer operator version %s (daemon is %s), refusing to act; annotate with %s=true to force",
+		written, utils.OperatorVersion, ignoreAnnotation)
+}
@@ -0,0 +1,134 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sriovv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/drainhelper"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+)
+
+var _ = Describe("NodeConfigReconciler.Reconcile of node config written by newer operator", func() {
+	var (
+		nodeNameRef                  = types.NamespacedName{Name: "worker", Namespace: "testNamespace"}
+		configured                   bool
+		originalGetSriovInventory    = getSriovInventory
+		originalVrbGetSriovInventory = VrbgetSriovInventory
+		originalOperatorVersion      = utils.OperatorVersion
+	)
+
+	reconcile := func(annotations, vrbAnnotations map[string]string) (*metav1.Condition, *metav1.Condition) {
+		scheme := runtime.NewScheme()
+		Expect(sriovv2.AddToScheme(scheme)).To(Succeed())
+		Expect(vrbv1.AddToScheme(scheme)).To(Succeed())
+
+		fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&sriovv2.SriovFecNodeConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace, Annotations: annotations},
+				Spec: sriovv2.SriovFecNodeConfigSpec{
+					PhysicalFunctions: []sriovv2.PhysicalFunctionConfigExt{{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, VFDriver: utils.IGB_UIO, VFAmount: 1}},
+				},
+			},
+			&vrbv1.SriovVrbNodeConfig{ObjectMeta: metav1.ObjectMeta{Name: nodeNameRef.Name, Namespace: nodeNameRef.Namespace, Annotations: vrbAnnotations}},
+		).Build()
+
+		reconciler, err := NewNodeConfigReconciler(fakeClient, &drainhelper.FakeDrainer{}, nodeNameRef,
+			testConfigurerProto{configureNodeFunction: func(sriovv2.SriovFecNodeConfigSpec) error {
+				configured = true
+				return nil
+			}}, nil,
+			func(context.Context) error { return nil },
+			record.NewFakeRecorder(10), nil)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = reconciler.Reconcile(context.TODO(), ctrl.Request{NamespacedName: nodeNameRef})
+		Expect(err).ToNot(HaveOccurred())
+
+		nc := &sriovv2.SriovFecNodeConfig{}
+		Expect(fakeClient.Get(context.TODO(), nodeNameRef, nc)).To(Succeed())
+		vrbnc := &vrbv1.SriovVrbNodeConfig{}
+		Expect(fakeClient.Get(context.TODO(), client.ObjectKeyFromObject(nc), vrbnc)).To(Succeed())
+		return nc.FindCondition(ConditionConfigured), vrbnc.FindCondition(ConditionConfigured)
+	}
+
+	BeforeEach(func() {
+		utils.OperatorVersion = "2.8.0"
+		configPath = "testdata/accelerators.json"
+		VrbconfigPath = "testdata/accelerators_vrb.json"
+		procCmdlineFilePath = "testdata/cmdline_test"
+		sysLockdownFilePath = filepath.Join(testTmpFolder, "lockdown")
+		Expect(os.WriteFile(sysLockdownFilePath, []byte("[none] integrity confidentiality"), 0644)).To(Succeed())
+
+		getSriovInventory = func(_ context.Context, log *logrus.Logger) (*sriovv2.NodeInventory, error) {
+			return &sriovv2.NodeInventory{
+				SriovAccelerators: []sriovv2.SriovAccelerator{{PCIAddress: pciAddress, PFDriver: utils.PCI_PF_STUB_DASH, MaxVFs: 10}},
+			}, nil
+		}
+		VrbgetSriovInventory = func(_ context.Context, log *logrus.Logger) (*vrbv1.NodeInventory, error) {
+			return &vrbv1.NodeInventory{}, nil
+		}
+		configured = false
+	})
+
+	AfterEach(func() {
+		getSriovInventory = originalGetSriovInventory
+		VrbgetSriovInventory = originalVrbGetSriovInventory
+		sysLockdownFilePath = "/sys/kernel/security/lockdown"
+		utils.OperatorVersion = originalOperatorVersion
+	})
+
+	It("defers configuration of both node configs", func() {
+		fecCondition, vrbCondition := reconcile(map[string]string{sriovv2.WrittenByVersionAnnotation: "99.0.0"},
+			map[string]string{vrbv1.WrittenByVersionAnnotation: "99.0.0"})
+
+		Expect(configured).To(BeFalse())
+		Expect(fecCondition.Reason).To(Equal(string(ConfigurationDeferred)))
+		Expect(fecCondition.Message).To(Equal("written by newer operator version 99.0.0 (daemon is " + utils.OperatorVersion +
+			"), refusing to act; annotate with sriovfec.intel.com/ignore-writer-version=true to force"))
+		Expect(vrbCondition.Reason).To(Equal(string(ConfigurationDeferred)))
+	})
+
+	It("defers configuration of both node configs when only one of them is written by newer operator", func() {
+		fecCondition, _ := reconcile(nil, map[string]string{vrbv1.WrittenByVersionAnnotation: "99.0.0"})
+
+		Expect(configured).To(BeFalse())
+		Expect(fecCondition).To(BeNil())
+	})
+
+	It("configures the node when forced", func() {
+		reconcile(map[string]string{
+			sriovv2.WrittenByVersionAnnotation:    "99.0.0",
+			sriovv2.IgnoreWriterVersionAnnotation: "true",
+		}, map[string]string{
+			vrbv1.WrittenByVersionAnnotation:    "99.0.0",
+			vrbv1.IgnoreWriterVersionAnnotation: "true",
+		})
+		Expect(configured).To(BeTrue())
+	})
+
+	It("configures node config written by the same or older operator", func() {
+		reconcile(map[string]string{sriovv2.WrittenByVersionAnnotation: utils.OperatorVersion},
+			map[string]string{vrbv1.WrittenByVersionAnnotation: "2.7.0"})
+		Expect(configured).To(BeTrue())
+
+		configured = false
+		reconcile(nil, nil)
+		Expect(configured).To(BeTrue())
+	})
+})
@@ -0,0 +1,138 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package integration
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	sriovfecv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/daemon"
+)
+
+const clusterConfigName = "config"
+
+// configuredCondition returns Configured condition of the NodeConfig, together with its generation
+func configuredCondition(nodeName string) (metav1.Condition, int64) {
+	nc := nodeConfig(nodeName)
+	if condition := nc.FindCondition(daemon.ConditionConfigured); condition != nil {
+		return *condition, nc.GetGeneration()
+	}
+	return metav1.Condition{}, nc.GetGeneration()
+}
+
+// expectConfiguredWith checks that the node was configured with reason, at generation of its NodeConfig
+func expectConfiguredWith(nodeName string, reason string) {
+	EventuallyWithOffset(1, func() error {
+		condition, generation := configuredCondition(nodeName)
+		if condition.Reason != reason {
+			return fmt.Errorf("node %s is %s (%s), expected %s", nodeName, condition.Reason, condition.Message, reason)
+		}
+		if reason == string(daemon.ConfigurationSucceeded) && condition.ObservedGeneration != generation {
+			return fmt.Errorf("node %s succeeded at generation %d, expected %d", nodeName, condition.ObservedGeneration, generation)
+		}
+		return nil
+	}, "30s", "250ms").Should(Succeed())
+}
+
+var _ = Describe("Status propagation", func() {
+	var cc *sriovfecv2.SriovFecClusterConfig
+
+	BeforeEach(func() {
+		cc = &sriovfecv2.SriovFecClusterConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: clusterConfigName, Namespace: namespace},
+			Spec: sriovfecv2.SriovFecClusterConfigSpec{
+				AcceleratorSelector: sriovfecv2.AcceleratorSelector{PCIAddress: pciAddress},
+				PhysicalFunction: sriovfecv2.PhysicalFunctionConfig{
+					PFDriver: utils.PCI_PF_STUB_DASH,
+					VFDriver: utils.VFIO_PCI,
+					VFAmount: 2,
+				},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		for _, configurer := range configurers {
+			configurer.SetErr(nil)
+		}
+		Expect(k8sClient.Delete(ctx, cc)).To(Succeed())
+		syncClusterConfigs(clusterConfigName)
+		for _, nodeName := range nodeNames {
+			Eventually(func() []sriovfecv2.PhysicalFunctionConfigExt {
+				return nodeConfig(nodeName).Spec.PhysicalFunctions
+			}).Should(BeEmpty())
+			expectConfiguredWith(nodeName, string(daemon.ConfigurationSucceeded))
+		}
+	})
+
+	It("stamps NodeConfigs, reports their configuration and aggregates it into ClusterConfig", func() {
+		configurers["worker-2"].SetErr(fmt.Errorf("pf_bb_config failed"))
+		Expect(k8sClient.Create(ctx, cc)).To(Succeed())
+		syncClusterConfigs(clusterConfigName)
+
+		By("stamping NodeConfigs of all accelerated nodes")
+		for _, nodeName := range nodeNames {
+			Expect(nodeConfig(nodeName).Spec.PhysicalFunctions).To(ConsistOf(sriovfecv2.PhysicalFunctionConfigExt{
+				PCIAddress: pciAddress,
+				PFDriver:   utils.PCI_PF_STUB_DASH,
+				VFDriver:   utils.VFIO_PCI,
+				VFAmount:   2,
+			}))
+		}
+
+		By("reporting configuration of each node by its daemon")
+		expectConfiguredWith("worker-1", string(daemon.ConfigurationSucceeded))
+		expectConfiguredWith("worker-2", string(daemon.ConfigurationFailed))
+		condition, _ := configuredCondition("worker-2")
+		Expect(condition.Message).To(ContainSubstring("pf_bb_config failed"))
+		Expect(configurers["worker-1"].LastApplied().PhysicalFunctions).To(HaveLen(1))
+
+		By("aggregating nodes into ClusterConfig status")
+		syncClusterConfigs(clusterConfigName)
+		Eventually(func() *sriovfecv2.VFCapacity {
+			return clusterConfig(clusterConfigName).Status.VFCapacity
+		}, "10s").Should(And(Not(BeNil()), WithTransform(func(c *sriovfecv2.VFCapacity) int { return c.TotalVFs }, Equal(4))))
+		Expect(clusterConfig(clusterConfigName).Status.NodeDecisions).To(BeEmpty())
+	})
+
+	It("re-stamps and reconfigures nodes when ClusterConfig changes", func() {
+		configurers["worker-2"].SetErr(fmt.Errorf("pf_bb_config failed"))
+		Expect(k8sClient.Create(ctx, cc)).To(Succeed())
+		syncClusterConfigs(clusterConfigName)
+		expectConfiguredWith("worker-1", string(daemon.ConfigurationSucceeded))
+		expectConfiguredWith("worker-2", string(daemon.ConfigurationFailed))
+
+		By("changing VF driver and narrowing ClusterConfig to worker-1")
+		configurers["worker-2"].SetErr(nil)
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(cc), cc)).To(Succeed())
+		cc.Spec.PhysicalFunction.VFDriver = utils.IGB_UIO
+		cc.Spec.NodeSelector = map[string]string{"kubernetes.io/hostname": "worker-1"}
+		Expect(k8sClient.Update(ctx, cc)).To(Succeed())
+		syncClusterConfigs(clusterConfigName)
+
+		By("reconfiguring worker-1 with the changed spec")
+		Expect(nodeConfig("worker-1").Spec.PhysicalFunctions).To(ConsistOf(
+			WithTransform(func(pf sriovfecv2.PhysicalFunctionConfigExt) string { return pf.VFDriver }, Equal(utils.IGB_UIO))))
+		expectConfiguredWith("worker-1", string(daemon.ConfigurationSucceeded))
+		Expect(configurers["worker-1"].LastApplied().PhysicalFunctions).To(ConsistOf(
+			WithTransform(func(pf sriovfecv2.PhysicalFunctionConfigExt) string { return pf.VFDriver }, Equal(utils.IGB_UIO))))
+
+		By("cleaning up worker-2 which is not selected anymore")
+		Expect(nodeConfig("worker-2").Spec.PhysicalFunctions).To(BeEmpty())
+		expectConfiguredWith("worker-2", string(daemon.ConfigurationSucceeded))
+		Expect(configurers["worker-2"].LastApplied().PhysicalFunctions).To(BeEmpty())
+
+		By("explaining in ClusterConfig status why worker-2 is not selected")
+		Expect(clusterConfig(clusterConfigName).Status.NodeDecisions).To(ConsistOf(sriovfecv2.NodeDecision{
+			NodeName: "worker-2",
+			Reason:   "label selector did not match",
+		}))
+	})
+})
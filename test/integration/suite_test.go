@@ -0,0 +1,264 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2023 Intel Corporation
+
+package integration
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/controller-runtime/pkg/envtest/printer"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	sriovfecv2 "github.com/smart-edge-open/sriov-fec-operator/api/sriovfec/v2"
+	vrbv1 "github.com/smart-edge-open/sriov-fec-operator/api/sriovvrb/v1"
+	"github.com/smart-edge-open/sriov-fec-operator/controllers/sriovfec"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/drainhelper"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/common/utils"
+	"github.com/smart-edge-open/sriov-fec-operator/pkg/daemon"
+)
+
+// The suite runs the cluster controller and daemons of several nodes against single API server, so that handoff
+// between them (stamping of NodeConfigs, status reported by daemons, aggregation into ClusterConfigs) is tested
+// together. Accelerators are faked: daemons discover the same inventory and configure them with fakeConfigurer.
+
+const (
+	namespace  = "default"
+	pciAddress = "0000:14:00.0"
+)
+
+var (
+	cfg          *rest.Config
+	k8sClient    client.Client
+	testEnv      *envtest.Environment
+	ctx          context.Context
+	cancel       context.CancelFunc
+	restoreSeams func()
+	tmpFolder    string
+
+	clusterReconciler *sriovfec.SriovFecClusterConfigReconciler
+	// configurers of the nodes, by node name
+	configurers = map[string]*fakeConfigurer{}
+	nodeNames   = []string{"worker-1", "worker-2"}
+)
+
+func TestStatusPropagation(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecsWithDefaultAndCustomReporters(t,
+		"Status Propagation Suite",
+		[]Reporter{printer.NewlineReporter{}})
+}
+
+var _ = BeforeSuite(func(done Done) {
+	logf.SetLogger(logr.New(utils.NewLogWrapper()))
+	By("bootstrapping test environment")
+	testEnv = &envtest.Environment{
+		CRDDirectoryPaths: []string{filepath.Join("..", "..", "config", "crd", "bases")},
+	}
+
+	var err error
+	cfg, err = testEnv.Start()
+	Expect(err).NotTo(HaveOccurred())
+	Expect(cfg).NotTo(BeNil())
+
+	Expect(sriovfecv2.AddToScheme(scheme.Scheme)).To(Succeed())
+	Expect(vrbv1.AddToScheme(scheme.Scheme)).To(Succeed())
+
+	k8sClient, err = client.New(cfg, client.Options{Scheme: scheme.Scheme})
+	Expect(err).NotTo(HaveOccurred())
+
+	tmpFolder, err = os.MkdirTemp("", "status_propagation")
+	Expect(err).NotTo(HaveOccurred())
+	lockdown := filepath.Join(tmpFolder, "lockdown")
+	Expect(os.WriteFile(lockdown, []byte("[none] integrity confidentiality"), 0644)).To(Succeed())
+	restoreSeams = daemon.ReplaceHostSeams(daemon.HostSeams{
+		DiscoveryConfigPath:    filepath.Join("..", "..", "pkg", "daemon", "testdata", "accelerators.json"),
+		VrbDiscoveryConfigPath: filepath.Join("..", "..", "pkg", "daemon", "testdata", "accelerators_vrb.json"),
+		KernelCmdlinePath:      filepath.Join("..", "..", "pkg", "daemon", "testdata", "cmdline_test"),
+		LockdownPath:           lockdown,
+		Inventory:              fakeInventory,
+		VrbInventory: func(context.Context, *logrus.Logger) (*vrbv1.NodeInventory, error) {
+			return &vrbv1.NodeInventory{}, nil
+		},
+	})
+
+	sriovfec.NAMESPACE = namespace
+	clusterReconciler = &sriovfec.SriovFecClusterConfigReconciler{
+		Client:             k8sClient,
+		Log:                utils.NewLogger(),
+		VFCapacityDebounce: 1,
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	for _, nodeName := range nodeNames {
+		createNode(nodeName)
+		configurers[nodeName] = startDaemon(nodeName)
+	}
+
+	By("waiting for daemons to report inventory")
+	for _, nodeName := range nodeNames {
+		Eventually(func() []sriovfecv2.SriovAccelerator {
+			return nodeConfig(nodeName).Status.Inventory.SriovAccelerators
+		}, "30s").Should(HaveLen(1))
+	}
+	close(done)
+}, 120)
+
+var _ = AfterSuite(func() {
+	By("tearing down the test environment")
+	if cancel != nil {
+		cancel()
+	}
+	if restoreSeams != nil {
+		restoreSeams()
+	}
+	Expect(os.RemoveAll(tmpFolder)).To(Succeed())
+	Expect(testEnv.Stop()).To(Succeed())
+})
+
+// fakeInventory is reported by daemons of all nodes: single ACC100 with 2 VFs
+func fakeInventory(context.Context, *logrus.Logger) (*sriovfecv2.NodeInventory, error) {
+	return &sriovfecv2.NodeInventory{
+		SriovAccelerators: []sriovfecv2.SriovAccelerator{{
+			VendorID:   "8086",
+			DeviceID:   "0d5c",
+			PCIAddress: pciAddress,
+			PFDriver:   utils.PCI_PF_STUB_DASH,
+			MaxVFs:     16,
+			VFs: []sriovfecv2.VF{
+				{PCIAddress: "0000:14:00.1", Driver: utils.VFIO_PCI, DeviceID: "0d5d"},
+				{PCIAddress: "0000:14:00.2", Driver: utils.VFIO_PCI, DeviceID: "0d5d"},
+			},
+		}},
+	}, nil
+}
+
+// createNode creates accelerated node with running daemon pod, as the cluster controller expects it
+func createNode(name string) {
+	Expect(k8sClient.Create(ctx, &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+		Name:   name,
+		Labels: map[string]string{"fpga.intel.com/intel-accelerator-present": "", "kubernetes.io/hostname": name},
+	}})).To(Succeed())
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sriov-fec-daemonset-" + name,
+			Namespace: namespace,
+			Labels:    map[string]string{"app": "sriov-fec-daemonset"},
+		},
+		Spec: corev1.PodSpec{
+			NodeName:   name,
+			Containers: []corev1.Container{{Name: "sriov-fec-daemon", Image: "sriov-fec-daemon"}},
+		},
+	}
+	Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+	pod.Status.Phase = corev1.PodRunning
+	Expect(k8sClient.Status().Update(ctx, pod)).To(Succeed())
+}
+
+// startDaemon runs daemon of the node with its own manager, as in daemon pod; accelerators are configured with returned
+// fakeConfigurer and the node is drained with FakeDrainer
+func startDaemon(nodeName string) *fakeConfigurer {
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+		Scheme:                 scheme.Scheme,
+		Namespace:              namespace,
+		MetricsBindAddress:     "0",
+		HealthProbeBindAddress: "0",
+	})
+	Expect(err).NotTo(HaveOccurred())
+
+	configurer := &fakeConfigurer{}
+	reconciler, err := daemon.NewNodeConfigReconciler(mgr.GetClient(), &drainhelper.FakeDrainer{},
+		types.NamespacedName{Namespace: namespace, Name: nodeName}, configurer, configurer,
+		func(context.Context) error { return nil }, mgr.GetEventRecorderFor("sriov-fec-daemon"), nil)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(reconciler.SetupWithManager(mgr)).To(Succeed())
+	Expect(reconciler.VrbSetupWithManager(mgr)).To(Succeed())
+	Expect(reconciler.CreateEmptyNodeConfigIfNeeded(ctx, k8sClient)).To(Succeed())
+	Expect(reconciler.VrbCreateEmptyNodeConfigIfNeeded(ctx, k8sClient)).To(Succeed())
+
+	go func() {
+		defer GinkgoRecover()
+		Expect(mgr.Start(ctx)).To(Succeed())
+	}()
+	return configurer
+}
+
+// fakeConfigurer stands for accelerators of the node; configuration fails while error is set
+type fakeConfigurer struct {
+	mu      sync.Mutex
+	err     error
+	applied []sriovfecv2.SriovFecNodeConfigSpec
+}
+
+func (f *fakeConfigurer) ApplySpec(_ context.Context, spec sriovfecv2.SriovFecNodeConfigSpec) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.applied = append(f.applied, *spec.DeepCopy())
+	return f.err
+}
+
+func (f *fakeConfigurer) RestartPfBBConfig(context.Context, sriovfecv2.PhysicalFunctionConfigExt) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.err
+}
+
+func (f *fakeConfigurer) VrbApplySpec(context.Context, vrbv1.SriovVrbNodeConfigSpec) error {
+	return nil
+}
+
+func (f *fakeConfigurer) VrbRestartPfBBConfig(context.Context, vrbv1.PhysicalFunctionConfigExt) error {
+	return nil
+}
+
+func (f *fakeConfigurer) SetErr(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err = err
+}
+
+// LastApplied returns spec the node was configured with last; nil when it was not configured yet
+func (f *fakeConfigurer) LastApplied() *sriovfecv2.SriovFecNodeConfigSpec {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.applied) == 0 {
+		return nil
+	}
+	return f.applied[len(f.applied)-1].DeepCopy()
+}
+
+func nodeConfig(name string) *sriovfecv2.SriovFecNodeConfig {
+	nc := &sriovfecv2.SriovFecNodeConfig{}
+	ExpectWithOffset(1, k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, nc)).To(Succeed())
+	return nc
+}
+
+func clusterConfig(name string) *sriovfecv2.SriovFecClusterConfig {
+	cc := &sriovfecv2.SriovFecClusterConfig{}
+	ExpectWithOffset(1, k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, cc)).To(Succeed())
+	return cc
+}
+
+// syncClusterConfigs runs reconcile of the cluster controller, as its periodic resync would; the controller does not
+// watch NodeConfigs and resyncs once a minute, which is too slow for the suite
+func syncClusterConfigs(name string) {
+	_, err := clusterReconciler.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: namespace, Name: name}})
+	ExpectWithOffset(1, err).NotTo(HaveOccurred())
+}